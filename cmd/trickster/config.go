@@ -19,6 +19,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"sync"
@@ -30,6 +31,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/cache/registration"
 	"github.com/tricksterproxy/trickster/pkg/cache/types"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/config/reload"
 	ro "github.com/tricksterproxy/trickster/pkg/config/reload/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/handlers"
 	th "github.com/tricksterproxy/trickster/pkg/proxy/handlers"
@@ -38,6 +40,7 @@ import (
 	tr "github.com/tricksterproxy/trickster/pkg/tracing/registration"
 	"github.com/tricksterproxy/trickster/pkg/util/log"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/md5"
 	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 )
 
@@ -55,9 +58,19 @@ func runConfig(oldConf *config.Config, wg *sync.WaitGroup, log *log.Logger,
 
 	// load the config
 	conf, flags, err := config.Load(runtime.ApplicationName, runtime.ApplicationVersion, args)
+
+	// if it's a -validate-config command, report the validation result and exit without
+	// starting any listeners or caches
+	if flags != nil && flags.ValidateConfig && !flags.PrintVersion {
+		if err == nil {
+			err = validateConfig(conf)
+		}
+		os.Exit(reportValidation(conf, err, os.Stdout, os.Stderr))
+	}
+
 	if err != nil {
 		fmt.Println("\nERROR: Could not load configuration:", err.Error())
-		if flags != nil && !flags.ValidateConfig {
+		if flags != nil {
 			PrintUsage()
 		}
 		handleStartupIssue("", nil, nil, errorsFatal)
@@ -70,15 +83,15 @@ func runConfig(oldConf *config.Config, wg *sync.WaitGroup, log *log.Logger,
 		os.Exit(0)
 	}
 
+	for _, w := range conf.LoaderWarnings {
+		fmt.Println(w)
+	}
+
 	err = validateConfig(conf)
 	if err != nil {
 		handleStartupIssue("ERROR: Could not load configuration: "+err.Error(),
 			nil, nil, errorsFatal)
 	}
-	if flags.ValidateConfig {
-		fmt.Println("Trickster configuration validation succeeded.")
-		os.Exit(0)
-	}
 
 	return applyConfig(conf, oldConf, wg, log, oldCaches, args, errorsFatal)
 
@@ -102,10 +115,18 @@ func applyConfig(conf, oldConf *config.Config, wg *sync.WaitGroup, log *log.Logg
 
 	log = applyLoggingConfig(conf, oldConf, log)
 
+	conf.LoaderWarnings = append(conf.LoaderWarnings, conf.CacheKeyEncodingWarnings(oldConf)...)
 	for _, w := range conf.LoaderWarnings {
 		log.Warn(w, tl.Pairs{})
 	}
 
+	// on a reload, log what changed versus the running config
+	if oldConf != nil {
+		for _, d := range conf.Diff(oldConf) {
+			log.Info("config changed", tl.Pairs{"change": d})
+		}
+	}
+
 	//Register Tracing Configurations
 	tracers, err := tr.RegisterAll(conf, log, false)
 	if err != nil {
@@ -128,14 +149,37 @@ func applyConfig(conf, oldConf *config.Config, wg *sync.WaitGroup, log *log.Logg
 		return err
 	}
 
-	applyListenerConfigs(conf, oldConf, router, http.HandlerFunc(rh), log, tracers)
+	// the root handler is registered after the origins, so that any origin serving '/' takes precedence
+	if conf.Frontend.RootHandlerResponse != nil {
+		router.HandleFunc("/", th.RootHandleFunc(conf)).Methods(http.MethodGet, http.MethodHead)
+	}
+
+	// the listeners are not yet bound at startup, so readiness checks fail during the delay
+	if oldConf == nil {
+		awaitStartup(conf, caches, log)
+	}
+
+	applyListenerConfigs(conf, oldConf, router, http.HandlerFunc(rh), caches, log, tracers)
 
 	metrics.LastReloadSuccessfulTimestamp.Set(float64(time.Now().Unix()))
 	metrics.LastReloadSuccessful.Set(1)
+	if conf.Logging != nil && conf.Logging.LogResolvedConfig {
+		// conf.String() redacts secrets before the config is serialized
+		cs := conf.String()
+		log.Info("resolved configuration",
+			tl.Pairs{"configHash": md5.Checksum(cs), "config": cs})
+	}
 	// add Config Reload HUP Signal Monitor
+	reload.SetRunning(conf, log, caches)
 	if oldConf != nil && oldConf.Resources != nil {
 		oldConf.Resources.QuitChan <- true // this signals the old hup monitor goroutine to exit
 	}
+	// the new config's replica pools have their own health checks
+	if oldConf != nil {
+		for _, o := range oldConf.Origins {
+			o.Pool.Stop()
+		}
+	}
 	startHupMonitor(conf, wg, log, caches, args)
 
 	return nil
@@ -220,14 +264,18 @@ func applyCachingConfig(c, oc *config.Config, logger *log.Logger,
 				continue
 			}
 
-			// if we got to this point, the cache won't be used, so lets close it
+			// if we got to this point, the cache won't be used, so connect its replacement
+			// before closing it. requests served under the old config may still hold the old
+			// cache, so it is closed once they drain, and its own in-flight operations complete
+			caches[k] = registration.NewCache(k, v, logger)
 			go func() {
 				time.Sleep(time.Second * time.Duration(c.ReloadConfig.DrainTimeoutSecs))
 				w.Close()
 			}()
+			continue
 		}
 
-		// the newly-named cache is not in the old config or couldn't be reused, so make it anew
+		// the newly-named cache is not in the old config, so make it anew
 		caches[k] = registration.NewCache(k, v, logger)
 	}
 	return caches
@@ -280,11 +328,24 @@ func handleStartupIssue(event string, detail log.Pairs, logger *log.Logger, exit
 	}
 }
 
-func validateConfig(conf *config.Config) error {
-
-	for _, w := range conf.LoaderWarnings {
-		fmt.Println(w)
+// reportValidation prints the result of a -validate-config run, with any error and all loader
+// warnings written to stderr, and returns the process exit code
+func reportValidation(conf *config.Config, err error, stdout, stderr io.Writer) int {
+	if conf != nil {
+		for _, w := range conf.LoaderWarnings {
+			fmt.Fprintln(stderr, "WARNING:", w)
+		}
 	}
+	if err != nil {
+		fmt.Fprintln(stderr, "ERROR: Trickster configuration validation failed:", err.Error())
+		return 1
+	}
+	fmt.Fprintf(stdout, "Trickster configuration validation succeeded: %d origins, %d caches, %d rules\n",
+		len(conf.Origins), len(conf.Caches), len(conf.Rules))
+	return 0
+}
+
+func validateConfig(conf *config.Config) error {
 
 	var caches = make(map[string]cache.Cache)
 	for k := range conf.Caches {
@@ -309,11 +370,24 @@ func validateConfig(conf *config.Config) error {
 	}
 
 	if conf.Frontend.ServeTLS && conf.Frontend.TLSListenPort > 0 {
-		_, err = conf.TLSCertConfig()
+		_, err = conf.FrontendTLSConfig(conf.Frontend)
 		if err != nil {
 			return err
 		}
 	}
 
+	for _, fc := range conf.Frontends {
+		if fc.ServeTLS && fc.TLSListenPort > 0 {
+			_, err = conf.FrontendTLSConfig(fc)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if _, err = conf.MetricsTLSConfig(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+)
+
+func TestReportValidation(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-type", "rpc", "-origin-url", "http://tricksterproxy.io"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.LoaderWarnings = []string{"test warning"}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if code := reportValidation(conf, nil, stdout, stderr); code != 0 {
+		t.Errorf("expected %d got %d", 0, code)
+	}
+	if !strings.Contains(stdout.String(), "1 origins, 1 caches, 0 rules") {
+		t.Errorf("expected summary got %s", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "test warning") {
+		t.Errorf("expected warning got %s", stderr.String())
+	}
+
+	stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	if code := reportValidation(nil, errors.New("test error"), stdout, stderr); code != 1 {
+		t.Errorf("expected %d got %d", 1, code)
+	}
+	if !strings.Contains(stderr.String(), "test error") || stdout.Len() != 0 {
+		t.Errorf("expected error on stderr only, got stdout %s stderr %s", stdout.String(), stderr.String())
+	}
+}
@@ -19,9 +19,12 @@ package main
 import (
 	"crypto/tls"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
 	ph "github.com/tricksterproxy/trickster/pkg/proxy/handlers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/listener"
 	ttls "github.com/tricksterproxy/trickster/pkg/proxy/tls"
@@ -30,12 +33,14 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/util/log"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+	"github.com/tricksterproxy/trickster/pkg/util/middleware"
+	str "github.com/tricksterproxy/trickster/pkg/util/strings"
 )
 
 var lg = listener.NewListenerGroup()
 
 func applyListenerConfigs(conf, oldConf *config.Config,
-	router, reloadHandler http.Handler, log *log.Logger,
+	router, reloadHandler http.Handler, caches map[string]cache.Cache, log *log.Logger,
 	tracers tracing.Tracers) {
 
 	var err error
@@ -45,15 +50,23 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 		return
 	}
 
+	// the main frontend's basic auth also protects the config and reload handlers of the
+	// reload listener, while its other handlers are protected by the admin auth token
+	fr := middleware.ACL(conf.Frontend.ACL, middleware.BasicAuth(conf.Frontend.BasicAuth,
+		d.DefaultFrontendName, middleware.WithJWTAuth(conf.Frontend.JWTAuth, log, router)))
+	configHandler := middleware.BasicAuth(conf.Frontend.BasicAuth, d.DefaultFrontendName,
+		http.HandlerFunc(ph.ConfigHandleFunc(conf)))
+	reloadHandler = middleware.BasicAuth(conf.Frontend.BasicAuth, d.DefaultFrontendName, reloadHandler)
+
 	adminRouter := http.NewServeMux()
 	adminRouter.Handle(conf.ReloadConfig.HandlerPath, reloadHandler)
 
 	// No changes in frontend config
 	if oldConf != nil && oldConf.Frontend != nil &&
 		oldConf.Frontend.Equal(conf.Frontend) {
-		lg.UpdateFrontendRouters(router, adminRouter)
+		lg.UpdateFrontendRouters(fr, adminRouter)
 		if ttls.OptionsChanged(conf, oldConf) {
-			tlsConfig, _ = conf.TLSCertConfig()
+			tlsConfig, _ = conf.FrontendTLSConfig(conf.Frontend)
 			l := lg.Get("tlsListener")
 			if l != nil {
 				cs := l.CertSwapper()
@@ -71,6 +84,8 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 		return
 	}
 
+	applyNamedFrontendListeners(conf, oldConf, router, log)
+
 	hasOldFC := oldConf != nil && oldConf.Frontend != nil
 	hasOldMC := oldConf != nil && oldConf.Metrics != nil
 	hasOldRC := oldConf != nil && oldConf.ReloadConfig != nil
@@ -82,9 +97,10 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 	if conf.Frontend.ServeTLS && conf.Frontend.TLSListenPort > 0 && (!hasOldFC ||
 		!oldConf.Frontend.ServeTLS ||
 		(oldConf.Frontend.TLSListenAddress != conf.Frontend.TLSListenAddress ||
-			oldConf.Frontend.TLSListenPort != conf.Frontend.TLSListenPort)) {
+			oldConf.Frontend.TLSListenPort != conf.Frontend.TLSListenPort ||
+			!frontendTLSEqual(oldConf.Frontend, conf.Frontend))) {
 		lg.DrainAndClose("tlsListener", drainTimeout)
-		tlsConfig, err = conf.TLSCertConfig()
+		tlsConfig, err = conf.FrontendTLSConfig(conf.Frontend)
 		if err != nil {
 			log.Error("unable to start tls listener due to certificate error", tl.Pairs{"detail": err})
 		} else {
@@ -92,7 +108,7 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 			tracerFlusherSet = true
 			go lg.StartListener("tlsListener",
 				conf.Frontend.TLSListenAddress, conf.Frontend.TLSListenPort,
-				conf.Frontend.ConnectionsLimit, tlsConfig, router, wg, tracers, true,
+				conf.Frontend.ConnectionsLimit, tlsConfig, fr, wg, tracers, true,
 				time.Duration(conf.ReloadConfig.DrainTimeoutSecs)*time.Second, log)
 		}
 	} else if !conf.Frontend.ServeTLS && hasOldFC && oldConf.Frontend.ServeTLS {
@@ -100,7 +116,7 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 		// the TLS listener port needs to be stopped
 		lg.DrainAndClose("tlsListener", drainTimeout)
 	} else if conf.Frontend.ServeTLS && ttls.OptionsChanged(conf, oldConf) {
-		tlsConfig, _ = conf.TLSCertConfig()
+		tlsConfig, _ = conf.FrontendTLSConfig(conf.Frontend)
 		if err != nil {
 			log.Error("unable to update tls config to certificate error", tl.Pairs{"detail": err})
 			return
@@ -117,38 +133,46 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 	// if the plaintext HTTP port is configured, then set up the http listener instance
 	if conf.Frontend.ListenPort > 0 && (!hasOldFC ||
 		(oldConf.Frontend.ListenAddress != conf.Frontend.ListenAddress ||
-			oldConf.Frontend.ListenPort != conf.Frontend.ListenPort)) {
+			oldConf.Frontend.ListenPort != conf.Frontend.ListenPort ||
+			oldConf.Frontend.EnableH2C != conf.Frontend.EnableH2C)) {
 		lg.DrainAndClose("httpListener", drainTimeout)
 		wg.Add(1)
 		var t2 tracing.Tracers
 		if !tracerFlusherSet {
 			t2 = tracers
 		}
-		go lg.StartListener("httpListener",
-			conf.Frontend.ListenAddress, conf.Frontend.ListenPort,
-			conf.Frontend.ConnectionsLimit, nil, router, wg, t2, true, 0, log)
+		if conf.Frontend.EnableH2C {
+			go lg.StartH2CListener("httpListener",
+				conf.Frontend.ListenAddress, conf.Frontend.ListenPort,
+				conf.Frontend.ConnectionsLimit, fr, wg, t2, true, 0, log)
+		} else {
+			go lg.StartListener("httpListener",
+				conf.Frontend.ListenAddress, conf.Frontend.ListenPort,
+				conf.Frontend.ConnectionsLimit, nil, fr, wg, t2, true, 0, log)
+		}
 	}
 
 	// if the Metrics HTTP port is configured, then set up the http listener instance
 	if conf.Metrics != nil && conf.Metrics.ListenPort > 0 &&
 		(!hasOldMC || (conf.Metrics.ListenAddress != oldConf.Metrics.ListenAddress ||
-			conf.Metrics.ListenPort != oldConf.Metrics.ListenPort)) {
+			conf.Metrics.ListenPort != oldConf.Metrics.ListenPort ||
+			!metricsTLSEqual(oldConf.Metrics, conf.Metrics))) {
 		lg.DrainAndClose("metricsListener", 0)
-		mr := http.NewServeMux()
-		mr.Handle("/metrics", metrics.Handler())
-		mr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
+		mr := newMetricsRouter(conf, fr)
 		if conf.Main.PprofServer == "both" || conf.Main.PprofServer == "metrics" {
 			routing.RegisterPprofRoutes("metrics", mr, log)
 		}
-		wg.Add(1)
-		go lg.StartListener("metricsListener",
-			conf.Metrics.ListenAddress, conf.Metrics.ListenPort,
-			conf.Frontend.ConnectionsLimit, nil, mr, wg, nil, true, 0, log)
+		tlsConfig, err = conf.MetricsTLSConfig()
+		if err != nil {
+			log.Error("unable to start metrics listener due to certificate error", tl.Pairs{"detail": err})
+		} else {
+			wg.Add(1)
+			go lg.StartListener("metricsListener",
+				conf.Metrics.ListenAddress, conf.Metrics.ListenPort,
+				conf.Frontend.ConnectionsLimit, tlsConfig, mr, wg, nil, true, 0, log)
+		}
 	} else {
-		mr := http.NewServeMux()
-		mr.Handle("/metrics", metrics.Handler())
-		mr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
-		lg.UpdateRouter("metricsListener", mr)
+		lg.UpdateRouter("metricsListener", newMetricsRouter(conf, fr))
 	}
 
 	// if the Reload HTTP port is configured, then set up the http listener instance
@@ -158,7 +182,11 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 		wg.Add(1)
 		lg.DrainAndClose("reloadListener", time.Millisecond*500)
 		mr := http.NewServeMux()
-		mr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
+		mr.Handle(conf.Main.ConfigHandlerPath, configHandler)
+		mr.HandleFunc(conf.Main.RouteDebugHandlerPath, ph.RouteDebugHandleFunc(conf))
+		mr.HandleFunc(conf.Main.CacheExportHandlerPath, ph.CacheExportHandleFunc(conf, caches))
+		mr.HandleFunc(conf.Main.CacheImportHandlerPath, ph.CacheImportHandleFunc(conf, caches))
+		mr.HandleFunc(conf.Main.MaintenanceHandlerPath, ph.MaintenanceHandleFunc(conf))
 		mr.Handle(conf.ReloadConfig.HandlerPath, reloadHandler)
 		if conf.Main.PprofServer == "both" || conf.Main.PprofServer == "reload" {
 			routing.RegisterPprofRoutes("reload", mr, log)
@@ -168,8 +196,117 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 			conf.Frontend.ConnectionsLimit, nil, mr, wg, nil, true, 0, log)
 	} else {
 		mr := http.NewServeMux()
-		mr.HandleFunc(conf.Main.ConfigHandlerPath, ph.ConfigHandleFunc(conf))
+		mr.Handle(conf.Main.ConfigHandlerPath, configHandler)
+		mr.HandleFunc(conf.Main.RouteDebugHandlerPath, ph.RouteDebugHandleFunc(conf))
+		mr.HandleFunc(conf.Main.CacheExportHandlerPath, ph.CacheExportHandleFunc(conf, caches))
+		mr.HandleFunc(conf.Main.CacheImportHandlerPath, ph.CacheImportHandleFunc(conf, caches))
+		mr.HandleFunc(conf.Main.MaintenanceHandlerPath, ph.MaintenanceHandleFunc(conf))
 		mr.Handle(conf.ReloadConfig.HandlerPath, reloadHandler)
 		lg.UpdateRouter("reloadListener", mr)
 	}
 }
+
+// applyNamedFrontendListeners starts, restarts, updates or stops the listeners of the
+// named frontends, so that they reflect the provided config
+func applyNamedFrontendListeners(conf, oldConf *config.Config, router http.Handler, log *log.Logger) {
+
+	drainTimeout := time.Duration(conf.ReloadConfig.DrainTimeoutSecs) * time.Second
+
+	var old map[string]*config.FrontendConfig
+	if oldConf != nil {
+		old = oldConf.Frontends
+	}
+
+	// the listeners of any frontends that were removed from the config are stopped
+	for k := range old {
+		if _, ok := conf.Frontends[k]; !ok {
+			lg.DrainAndClose(frontendListenerName(k, false), drainTimeout)
+			lg.DrainAndClose(frontendListenerName(k, true), drainTimeout)
+		}
+	}
+
+	for k, fc := range conf.Frontends {
+
+		fr := middleware.WithFrontendName(k, middleware.ACL(fc.ACL, middleware.BasicAuth(fc.BasicAuth, k,
+			middleware.WithJWTAuth(fc.JWTAuth, log, router))))
+		hn, tn := frontendListenerName(k, false), frontendListenerName(k, true)
+
+		// No changes in the frontend config, so the listeners only need the new router
+		if ofc, ok := old[k]; ok && ofc.Equal(fc) {
+			lg.UpdateRouter(hn, fr)
+			lg.UpdateRouter(tn, fr)
+			if fc.ServeTLS && fc.TLSFullChainCertPath == "" && ttls.OptionsChanged(conf, oldConf) {
+				if tlsConfig, err := conf.FrontendTLSConfig(fc); err == nil {
+					if l := lg.Get(tn); l != nil && l.CertSwapper() != nil {
+						l.CertSwapper().SetCerts(tlsConfig.Certificates)
+					}
+				}
+			}
+			continue
+		}
+
+		lg.DrainAndClose(hn, drainTimeout)
+		lg.DrainAndClose(tn, drainTimeout)
+
+		if fc.ServeTLS && fc.TLSListenPort > 0 {
+			tlsConfig, err := conf.FrontendTLSConfig(fc)
+			if err != nil {
+				log.Error("unable to start tls listener due to certificate error",
+					tl.Pairs{"frontendName": k, "detail": err})
+			} else {
+				wg.Add(1)
+				go lg.StartListener(tn, fc.TLSListenAddress, fc.TLSListenPort,
+					fc.ConnectionsLimit, tlsConfig, fr, wg, nil, true, drainTimeout, log)
+			}
+		}
+
+		if fc.ListenPort > 0 {
+			wg.Add(1)
+			if fc.EnableH2C {
+				go lg.StartH2CListener(hn, fc.ListenAddress, fc.ListenPort,
+					fc.ConnectionsLimit, fr, wg, nil, true, 0, log)
+			} else {
+				go lg.StartListener(hn, fc.ListenAddress, fc.ListenPort,
+					fc.ConnectionsLimit, nil, fr, wg, nil, true, 0, log)
+			}
+		}
+	}
+}
+
+func frontendListenerName(name string, isTLS bool) string {
+	if isTLS {
+		return "frontend." + name + ".tlsListener"
+	}
+	return "frontend." + name + ".httpListener"
+}
+
+// newMetricsRouter returns the router for the metrics listener. When the metrics listener serves TLS,
+// it is a dedicated operational interface, so the ping and health handlers are also served there
+func newMetricsRouter(conf *config.Config, router http.Handler) *http.ServeMux {
+	mr := http.NewServeMux()
+	mr.Handle("/metrics", metrics.Handler())
+	mr.Handle(conf.Main.ConfigHandlerPath, middleware.BasicAuth(conf.Frontend.BasicAuth,
+		d.DefaultFrontendName, http.HandlerFunc(ph.ConfigHandleFunc(conf))))
+	mr.HandleFunc(conf.Main.RouteDebugHandlerPath, ph.RouteDebugHandleFunc(conf))
+	if conf.Metrics.ServeTLS {
+		mr.Handle(conf.Main.PingHandlerPath, router)
+		mr.Handle(strings.TrimSuffix(conf.Main.HealthHandlerPath, "/")+"/", router)
+	}
+	return mr
+}
+
+// metricsTLSEqual returns true if the metrics listeners' TLS certificate configurations are identical
+func metricsTLSEqual(mc1, mc2 *config.MetricsConfig) bool {
+	return mc1.TLSFullChainCertPath == mc2.TLSFullChainCertPath &&
+		mc1.TLSPrivateKeyPath == mc2.TLSPrivateKeyPath &&
+		mc1.TLSClientCACertPath == mc2.TLSClientCACertPath
+}
+
+// frontendTLSEqual returns true if the frontends' own TLS certificate configurations are identical
+func frontendTLSEqual(fc1, fc2 *config.FrontendConfig) bool {
+	return fc1.TLSFullChainCertPath == fc2.TLSFullChainCertPath &&
+		fc1.TLSPrivateKeyPath == fc2.TLSPrivateKeyPath &&
+		fc1.TLSClientCACertPath == fc2.TLSClientCACertPath &&
+		str.Equal(fc1.ClientCAPaths, fc2.ClientCAPaths) &&
+		fc1.RequireClientCert == fc2.RequireClientCert
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/config/reload"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 )
 
@@ -43,16 +44,30 @@ func startHupMonitor(conf *config.Config, wg *sync.WaitGroup, log *tl.Logger,
 		for {
 			select {
 			case <-hups:
-				conf.Main.ReloaderLock.Lock()
-				if conf.IsStale() {
-					log.Warn("configuration reload starting now", tl.Pairs{"source": "sighup"})
-					err := runConfig(conf, wg, log, caches, args, false)
-					if err == nil {
-						conf.Main.ReloaderLock.Unlock()
-						return // runConfig will start a new HupMonitor in place of this one
+				var reloaded bool
+				rc := conf
+				queue := conf.ReloadConfig != nil && conf.ReloadConfig.QueueReloads
+				ran := reload.Serialize(queue, func() {
+					// a reload that completed while this one was queued has replaced conf,
+					// so this one is applied over the config that is now running
+					var rlog *tl.Logger
+					var rcaches map[string]cache.Cache
+					rc, rlog, rcaches = reload.Running(conf, log, caches)
+					rc.Main.ReloaderLock.Lock()
+					defer rc.Main.ReloaderLock.Unlock()
+					if rc.IsStale() {
+						rlog.Warn("configuration reload starting now", tl.Pairs{"source": "sighup"})
+						reloaded = runConfig(rc, wg, rlog, rcaches, args, false) == nil
 					}
+				})
+				if reloaded || rc != conf {
+					return // a newer config's HupMonitor runs in place of this one
+				}
+				if !ran {
+					log.Warn("configuration reload skipped: a reload is already in progress",
+						tl.Pairs{"source": "sighup"})
+					continue
 				}
-				conf.Main.ReloaderLock.Unlock()
 				log.Warn("configuration NOT reloaded", tl.Pairs{})
 			case <-conf.Resources.QuitChan:
 				return
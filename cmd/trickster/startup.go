@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// startupProbeKey is the cache key retrieved to check that a cache is responding
+const startupProbeKey = "trickster.startup.probe"
+
+const (
+	startupInitialBackoff = 250 * time.Millisecond
+	startupMaxBackoff     = 5 * time.Second
+	startupDialTimeout    = 2 * time.Second
+)
+
+// dependencyCheck checks that a cache or origin is responding
+type dependencyCheck struct {
+	name  string
+	check func() error
+}
+
+// awaitStartup delays the initial binding of the listeners per the main config. With
+// startup_dependency_checks, it returns once every cache and origin responds, retrying
+// with exponential backoff, or once startup_delay_secs elapses; otherwise it waits for
+// startup_delay_secs
+func awaitStartup(conf *config.Config, caches map[string]cache.Cache, log *tl.Logger) {
+	if conf.Main == nil {
+		return
+	}
+	delay := time.Duration(conf.Main.StartupDelaySecs) * time.Second
+	if !conf.Main.StartupDependencyChecks {
+		if delay > 0 {
+			log.Info("delaying startup", tl.Pairs{"startupDelaySecs": conf.Main.StartupDelaySecs})
+			time.Sleep(delay)
+		}
+		return
+	}
+
+	checks := dependencyChecks(conf, caches)
+	deadline := time.Now().Add(delay)
+	backoff := startupInitialBackoff
+	for {
+		pending := checks[:0:0]
+		for _, c := range checks {
+			if err := c.check(); err != nil {
+				log.Info("startup dependency not ready",
+					tl.Pairs{"dependency": c.name, "detail": err.Error()})
+				pending = append(pending, c)
+			}
+		}
+		checks = pending
+		if len(checks) == 0 {
+			return
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			names := make([]string, len(checks))
+			for i, c := range checks {
+				names[i] = c.name
+			}
+			log.Warn("startup dependencies not ready after startup_delay_secs, starting anyway",
+				tl.Pairs{"dependencies": names})
+			return
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > startupMaxBackoff {
+			backoff = startupMaxBackoff
+		}
+	}
+}
+
+// dependencyChecks returns a check for each of the caches and origins that Trickster depends
+// on, in name order. A cache is responding when a retrieval completes, even as a miss, and an
+// origin is responding when it accepts a connection
+func dependencyChecks(conf *config.Config, caches map[string]cache.Cache) []dependencyCheck {
+	var checks []dependencyCheck
+	for k, c := range caches {
+		c := c
+		checks = append(checks, dependencyCheck{name: "cache " + k, check: func() error {
+			_, _, err := c.Retrieve(startupProbeKey, false)
+			if err == cache.ErrKNF {
+				return nil
+			}
+			return err
+		}})
+	}
+	for k, o := range conf.Origins {
+		hosts := []string{originAddr(o.Scheme, o.Host)}
+		if o.Pool != nil {
+			hosts = hosts[:0]
+			for _, b := range o.Pool.Backends() {
+				hosts = append(hosts, originAddr(b.URL.Scheme, b.URL.Host))
+			}
+		}
+		for _, h := range hosts {
+			if h == "" {
+				continue
+			}
+			h := h
+			checks = append(checks, dependencyCheck{name: fmt.Sprintf("origin %s (%s)", k, h),
+				check: func() error {
+					conn, err := net.DialTimeout("tcp", h, startupDialTimeout)
+					if err != nil {
+						return err
+					}
+					return conn.Close()
+				}})
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+	return checks
+}
+
+// originAddr returns the host:port address of an origin host, using the default port of the
+// scheme when the host does not include one
+func originAddr(scheme, host string) string {
+	if host == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	if scheme == "https" {
+		return net.JoinHostPort(host, "443")
+	}
+	return net.JoinHostPort(host, "80")
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/registration"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+func TestAwaitStartup(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-type", "rpc", "-origin-url", "http://" + l.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Main.StartupDependencyChecks = true
+	conf.Main.StartupDelaySecs = 5
+	log := tl.ConsoleLogger("error")
+	caches := map[string]cache.Cache{
+		"default": registration.NewCache("default", conf.Caches["default"], log),
+	}
+
+	checks := dependencyChecks(conf, caches)
+	if len(checks) != 2 || !strings.HasPrefix(checks[0].name, "cache default") ||
+		!strings.HasPrefix(checks[1].name, "origin default") {
+		t.Fatalf("unexpected dependency checks %v", checks)
+	}
+
+	// all dependencies respond, so startup is not delayed
+	start := time.Now()
+	awaitStartup(conf, caches, log)
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expected startup without delay, took %s", d)
+	}
+
+	// the origin is no longer accepting connections, so startup waits for startup_delay_secs
+	addr := l.Addr().String()
+	l.Close()
+	conf.Origins["default"].Host = addr
+	conf.Main.StartupDelaySecs = 1
+	start = time.Now()
+	awaitStartup(conf, caches, log)
+	if d := time.Since(start); d < time.Second || d > 3*time.Second {
+		t.Errorf("expected startup delay of %s, took %s", time.Second, d)
+	}
+}
+
+func TestOriginAddr(t *testing.T) {
+	tests := []struct {
+		scheme, host, expected string
+	}{
+		{"http", "example.com", "example.com:80"},
+		{"https", "example.com", "example.com:443"},
+		{"http", "example.com:9090", "example.com:9090"},
+		{"http", "", ""},
+	}
+	for _, test := range tests {
+		if a := originAddr(test.scheme, test.host); a != test.expected {
+			t.Errorf("expected %s got %s", test.expected, a)
+		}
+	}
+}
@@ -228,6 +228,11 @@ func (c *Cache) BulkRemove(cacheKeys []string) {
 	wg.Wait()
 }
 
+// ObjectExpirations returns the expiration of each object in the cache, keyed by cache key
+func (c *Cache) ObjectExpirations() map[string]time.Time {
+	return c.Index.Expirations()
+}
+
 // Close closes the Cache
 func (c *Cache) Close() error {
 	if c.Index != nil {
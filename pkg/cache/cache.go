@@ -62,6 +62,14 @@ type MemoryCache interface {
 	SetLocker(locks.NamedLocker)
 }
 
+// IndexedCache is the interface for a cache that maintains an index of its objects
+// This offers an additional method for listing the cached objects, e.g., for export
+type IndexedCache interface {
+	Cache
+	// ObjectExpirations returns the expiration of each object in the cache, keyed by cache key
+	ObjectExpirations() map[string]time.Time
+}
+
 // ReferenceObject defines an interface for a cache object possessing the ability to report
 // the approximate comprehensive byte size of its members, to assist with cache size management
 type ReferenceObject interface {
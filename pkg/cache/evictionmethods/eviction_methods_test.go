@@ -39,3 +39,23 @@ func TestTEMString(t *testing.T) {
 	}
 
 }
+
+func TestIndexEvictionPolicyString(t *testing.T) {
+
+	tests := []struct {
+		p        IndexEvictionPolicy
+		expected string
+	}{
+		{IndexEvictionPolicyLRU, "lru"},
+		{IndexEvictionPolicyLFU, "lfu"},
+		{IndexEvictionPolicyFIFO, "fifo"},
+		{IndexEvictionPolicy(9), "9"},
+	}
+
+	for _, test := range tests {
+		if test.p.String() != test.expected {
+			t.Errorf("expected %s got %s", test.expected, test.p.String())
+		}
+	}
+
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package evictionmethods
+
+import "strconv"
+
+// IndexEvictionPolicy enumerates the methodologies a Cache Index uses to select
+// objects for eviction when the cache exceeds its maximum size
+type IndexEvictionPolicy int
+
+const (
+	// IndexEvictionPolicyLRU indicates that the least-recently-accessed objects are evicted first
+	IndexEvictionPolicyLRU = IndexEvictionPolicy(iota)
+	// IndexEvictionPolicyLFU indicates that the least-frequently-accessed objects are evicted first,
+	// with ties broken by evicting the least-recently-accessed object
+	IndexEvictionPolicyLFU
+	// IndexEvictionPolicyFIFO indicates that the least-recently-written objects are evicted first,
+	// regardless of how often or recently they are accessed
+	IndexEvictionPolicyFIFO
+)
+
+// IndexEvictionPolicyNames is a map of IndexEvictionPolicies keyed by string name
+var IndexEvictionPolicyNames = map[string]IndexEvictionPolicy{
+	"lru":  IndexEvictionPolicyLRU,
+	"lfu":  IndexEvictionPolicyLFU,
+	"fifo": IndexEvictionPolicyFIFO,
+}
+
+// IndexEvictionPolicyValues is a map of IndexEvictionPolicies valued by string name
+var IndexEvictionPolicyValues = make(map[IndexEvictionPolicy]string)
+
+func init() {
+	for k, v := range IndexEvictionPolicyNames {
+		IndexEvictionPolicyValues[v] = k
+	}
+}
+
+func (p IndexEvictionPolicy) String() string {
+	if v, ok := IndexEvictionPolicyValues[p]; ok {
+		return v
+	}
+	return strconv.Itoa(int(p))
+}
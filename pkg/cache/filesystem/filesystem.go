@@ -43,6 +43,9 @@ type Cache struct {
 	Logger     *log.Logger
 	locker     locks.NamedLocker
 	lockPrefix string
+	// openFiles is a semaphore bounding the number of concurrently open cache files
+	// when Filesystem.MaxOpenFiles is set
+	openFiles chan struct{}
 }
 
 // Locker returns the cache's locker
@@ -68,6 +71,9 @@ func (c *Cache) Connect() error {
 		return err
 	}
 	c.lockPrefix = c.Name + ".file."
+	if c.Config.Filesystem.MaxOpenFiles > 0 {
+		c.openFiles = make(chan struct{}, c.Config.Filesystem.MaxOpenFiles)
+	}
 
 	// Load Index here and pass bytes as param2
 	indexData, _, _ := c.retrieve(index.IndexKey, false, false)
@@ -106,7 +112,9 @@ func (c *Cache) store(cacheKey string, data []byte, ttl time.Duration, updateInd
 	nl, _ := c.locker.Acquire(c.lockPrefix + cacheKey)
 
 	o := &index.Object{Key: cacheKey, Value: data, Expiration: time.Now().Add(ttl)}
+	c.acquireFile()
 	err := ioutil.WriteFile(dataFile, o.ToBytes(), os.FileMode(0777))
+	c.releaseFile()
 	if err != nil {
 		nl.Release()
 		return err
@@ -130,7 +138,9 @@ func (c *Cache) retrieve(cacheKey string, allowExpired bool, atime bool) ([]byte
 	dataFile := c.getFileName(cacheKey)
 
 	nl, _ := c.locker.RAcquire(c.lockPrefix + cacheKey)
+	c.acquireFile()
 	data, err := ioutil.ReadFile(dataFile)
+	c.releaseFile()
 	nl.RRelease()
 
 	if err != nil {
@@ -180,7 +190,9 @@ func (c *Cache) Remove(cacheKey string) {
 
 func (c *Cache) remove(cacheKey string, isBulk bool) {
 	nl, _ := c.locker.Acquire(c.lockPrefix + cacheKey)
+	c.acquireFile()
 	err := os.Remove(c.getFileName(cacheKey))
+	c.releaseFile()
 	nl.Release()
 	if err == nil && !isBulk {
 		go c.Index.RemoveObject(cacheKey)
@@ -202,6 +214,11 @@ func (c *Cache) BulkRemove(cacheKeys []string) {
 	wg.Wait()
 }
 
+// ObjectExpirations returns the expiration of each object in the cache, keyed by cache key
+func (c *Cache) ObjectExpirations() map[string]time.Time {
+	return c.Index.Expirations()
+}
+
 // Close is not used for Cache
 func (c *Cache) Close() error {
 	if c.Index != nil {
@@ -210,6 +227,20 @@ func (c *Cache) Close() error {
 	return nil
 }
 
+// acquireFile blocks until a file operation is permitted under Filesystem.MaxOpenFiles
+func (c *Cache) acquireFile() {
+	if c.openFiles != nil {
+		c.openFiles <- struct{}{}
+	}
+}
+
+// releaseFile permits the next file operation waiting in acquireFile
+func (c *Cache) releaseFile() {
+	if c.openFiles != nil {
+		<-c.openFiles
+	}
+}
+
 func (c *Cache) getFileName(cacheKey string) string {
 	prefix := strings.Replace(c.Config.Filesystem.CachePath+"/"+cacheKey+".", "//", "/", 1)
 	return prefix + "data"
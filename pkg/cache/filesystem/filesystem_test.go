@@ -148,6 +148,51 @@ func TestFilesystemCache_Store(t *testing.T) {
 
 }
 
+func TestFilesystemCache_MaxOpenFiles(t *testing.T) {
+
+	cacheConfig := newCacheConfig(t)
+	cacheConfig.Filesystem.MaxOpenFiles = 1
+	defer os.RemoveAll(cacheConfig.Filesystem.CachePath)
+	fc := Cache{Config: &cacheConfig, Logger: tl.ConsoleLogger("error"), locker: locks.NewNamedLocker()}
+
+	err := fc.Connect()
+	if err != nil {
+		t.Error(err)
+	}
+	if cap(fc.openFiles) != 1 {
+		t.Errorf("expected %d got %d", 1, cap(fc.openFiles))
+	}
+
+	// occupy the only open file slot, so the store must wait for it
+	fc.acquireFile()
+	done := make(chan error, 1)
+	go func() {
+		done <- fc.Store(cacheKey, []byte("data"), time.Duration(60)*time.Second)
+	}()
+
+	select {
+	case <-done:
+		t.Error("expected store to wait for an open file slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	fc.releaseFile()
+	if err = <-done; err != nil {
+		t.Error(err)
+	}
+
+	data, ls, err := fc.Retrieve(cacheKey, false)
+	if err != nil {
+		t.Error(err)
+	}
+	if ls != status.LookupStatusHit || string(data) != "data" {
+		t.Errorf("expected %s got %s", "data", string(data))
+	}
+	if len(fc.openFiles) != 0 {
+		t.Errorf("expected %d got %d", 0, len(fc.openFiles))
+	}
+}
+
 func BenchmarkCache_Store(b *testing.B) {
 	fc := storeBenchmark(b)
 	defer fc.Close()
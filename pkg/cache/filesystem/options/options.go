@@ -24,6 +24,9 @@ import (
 type Options struct {
 	// CachePath represents the path on disk where our cache will live
 	CachePath string `toml:"cache_path"`
+	// MaxOpenFiles, when greater than 0, limits the number of cache files that may be open at once,
+	// so that file descriptor usage stays bounded under heavy concurrency
+	MaxOpenFiles int `toml:"max_open_files,omitzero"`
 }
 
 // NewOptions returns a new Filesystem Options Reference with default values set
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	"github.com/tricksterproxy/trickster/pkg/cache/index/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/metrics"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
@@ -57,6 +58,8 @@ type Index struct {
 	reaperExited  bool
 
 	mtx sync.Mutex
+	// reapIOMtx serializes throttled bulk removals, so overlapping reaps do not exceed the I/O limit
+	reapIOMtx sync.Mutex
 }
 
 // Close is called to signal the index to shut down any subroutines
@@ -81,6 +84,8 @@ type Object struct {
 	LastWrite time.Time `msg:"lastwrite"`
 	// LastAccess is the time the object was last Accessed
 	LastAccess time.Time `msg:"lastaccess"`
+	// AccessCount is the number of times the object has been Accessed since it was first Written
+	AccessCount int64 `msg:"accesscount"`
 	// Size the size of the Object in bytes
 	Size int64 `msg:"size"`
 	// Value is the value of the Object stored in the Cache
@@ -151,11 +156,12 @@ func (idx *Index) UpdateOptions(o *options.Options) {
 	idx.mtx.Unlock()
 }
 
-// UpdateObjectAccessTime updates the LastAccess for the object with the provided key
+// UpdateObjectAccessTime updates the LastAccess and AccessCount for the object with the provided key
 func (idx *Index) UpdateObjectAccessTime(key string) {
 	idx.mtx.Lock()
 	if _, ok := idx.Objects[key]; ok {
 		idx.Objects[key].LastAccess = time.Now()
+		idx.Objects[key].AccessCount++
 	}
 	idx.mtx.Unlock()
 
@@ -193,6 +199,8 @@ func (idx *Index) UpdateObject(obj *Object) {
 
 	if o, ok := idx.Objects[key]; ok {
 		atomic.AddInt64(&idx.CacheSize, obj.Size-o.Size)
+		// rewriting an object does not reset how often it has been accessed
+		obj.AccessCount = o.AccessCount
 	} else {
 		atomic.AddInt64(&idx.CacheSize, obj.Size)
 		atomic.AddInt64(&idx.ObjectCount, 1)
@@ -251,6 +259,17 @@ func (idx *Index) GetExpiration(cacheKey string) time.Time {
 	return time.Time{}
 }
 
+// Expirations returns the expiration of each object in the cache index, keyed by cache key
+func (idx *Index) Expirations() map[string]time.Time {
+	idx.mtx.Lock()
+	out := make(map[string]time.Time, len(idx.Objects))
+	for k, o := range idx.Objects {
+		out[k] = o.Expiration
+	}
+	idx.mtx.Unlock()
+	return out
+}
+
 // flusher periodically calls the cache's index flush func that writes the cache index to disk
 func (idx *Index) flusher(log *tl.Logger) {
 	var lastFlush time.Time
@@ -286,10 +305,52 @@ func (idx *Index) reaper(log *tl.Logger) {
 	idx.reaperExited = true
 }
 
+// bulkRemove removes the provided keys from the cache. When the options limit the reaper's
+// concurrent I/O, the keys are removed in sequential batches, pausing between each batch
+func (idx *Index) bulkRemove(keys []string, o *options.Options) {
+	n := o.ReapMaxConcurrentIO
+	if n <= 0 || len(keys) <= n {
+		idx.bulkRemoveFunc(keys)
+		return
+	}
+	idx.reapIOMtx.Lock()
+	defer idx.reapIOMtx.Unlock()
+	for i := 0; i < len(keys); i += n {
+		if i > 0 && o.ReapIOPause > 0 {
+			time.Sleep(o.ReapIOPause)
+		}
+		j := i + n
+		if j > len(keys) {
+			j = len(keys)
+		}
+		idx.bulkRemoveFunc(keys[i:j])
+	}
+}
+
 type objectsAtime []*Object
 
+// objectsAccessCount sorts objects by ascending AccessCount, then by ascending LastAccess
+type objectsAccessCount []*Object
+
+// objectsWtime sorts objects by ascending LastWrite
+type objectsWtime []*Object
+
+// sortEvictionCandidates orders the objects so that those to be evicted first,
+// according to the provided policy, are at the front of the list
+func sortEvictionCandidates(objects []*Object, policy evictionmethods.IndexEvictionPolicy) {
+	switch policy {
+	case evictionmethods.IndexEvictionPolicyLFU:
+		sort.Sort(objectsAccessCount(objects))
+	case evictionmethods.IndexEvictionPolicyFIFO:
+		sort.Sort(objectsWtime(objects))
+	default:
+		sort.Sort(objectsAtime(objects))
+	}
+}
+
 // reap makes a single iteration through the cache index to to find and remove expired elements
-// and evict least-recently-accessed elements to maintain the Maximum allowed Cache Size
+// and evict elements, selected according to the configured eviction policy, to maintain the
+// Maximum allowed Cache Size
 func (idx *Index) reap(log *tl.Logger) {
 
 	idx.mtx.Lock()
@@ -315,7 +376,7 @@ func (idx *Index) reap(log *tl.Logger) {
 
 	if len(removals) > 0 {
 		metrics.ObserveCacheEvent(idx.name, idx.cacheType, "eviction", "ttl")
-		go idx.bulkRemoveFunc(removals)
+		go idx.bulkRemove(removals, idx.options)
 		idx.RemoveObjects(removals, true)
 		cacheChanged = true
 	}
@@ -333,9 +394,9 @@ func (idx *Index) reap(log *tl.Logger) {
 			return
 		}
 
-		log.Debug("max cache size reached. evicting least-recently-accessed records",
+		log.Debug("max cache size reached. evicting records",
 			tl.Pairs{
-				"reason":         evictionType,
+				"reason": evictionType, "evictionPolicy": idx.options.EvictionPolicy.String(),
 				"cacheSizeBytes": idx.CacheSize, "maxSizeBytes": idx.options.MaxSizeBytes,
 				"cacheSizeObjects": idx.ObjectCount, "maxSizeObjects": idx.options.MaxSizeObjects,
 			},
@@ -343,7 +404,7 @@ func (idx *Index) reap(log *tl.Logger) {
 
 		removals = make([]string, 0)
 
-		sort.Sort(remainders)
+		sortEvictionCandidates(remainders, idx.options.EvictionPolicy)
 
 		i := 0
 		j := len(remainders)
@@ -374,7 +435,9 @@ func (idx *Index) reap(log *tl.Logger) {
 
 		if len(removals) > 0 {
 			metrics.ObserveCacheEvent(idx.name, idx.cacheType, "eviction", evictionType)
-			go idx.bulkRemoveFunc(removals)
+			metrics.ObserveCacheEvictions(idx.name, idx.cacheType,
+				idx.options.EvictionPolicy.String(), float64(len(removals)))
+			go idx.bulkRemove(removals, idx.options)
 			idx.RemoveObjects(removals, true)
 			cacheChanged = true
 		}
@@ -406,3 +469,37 @@ func (o objectsAtime) Less(i, j int) bool {
 func (o objectsAtime) Swap(i, j int) {
 	o[i], o[j] = o[j], o[i]
 }
+
+// Len returns the length of the list of objects
+func (o objectsAccessCount) Len() int {
+	return len(o)
+}
+
+// Less returns true if i has been accessed less often than j,
+// or equally as often but less recently
+func (o objectsAccessCount) Less(i, j int) bool {
+	if o[i].AccessCount == o[j].AccessCount {
+		return o[i].LastAccess.Before(o[j].LastAccess)
+	}
+	return o[i].AccessCount < o[j].AccessCount
+}
+
+// Swap modifies the list of objects by swapping the values in indexes i and j
+func (o objectsAccessCount) Swap(i, j int) {
+	o[i], o[j] = o[j], o[i]
+}
+
+// Len returns the length of the list of objects
+func (o objectsWtime) Len() int {
+	return len(o)
+}
+
+// Less returns true if i was written before j
+func (o objectsWtime) Less(i, j int) bool {
+	return o[i].LastWrite.Before(o[j].LastWrite)
+}
+
+// Swap modifies the list of objects by swapping the values in indexes i and j
+func (o objectsWtime) Swap(i, j int) {
+	o[i], o[j] = o[j], o[i]
+}
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	io "github.com/tricksterproxy/trickster/pkg/cache/index/options"
 	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
@@ -276,6 +277,106 @@ func TestSort(t *testing.T) {
 
 }
 
+func TestSortEvictionCandidates(t *testing.T) {
+
+	newObjects := func() []*Object {
+		return []*Object{
+			{Key: "1", LastAccess: time.Unix(1, 0), LastWrite: time.Unix(3, 0), AccessCount: 5},
+			{Key: "2", LastAccess: time.Unix(2, 0), LastWrite: time.Unix(1, 0), AccessCount: 1},
+			{Key: "3", LastAccess: time.Unix(3, 0), LastWrite: time.Unix(2, 0), AccessCount: 1},
+		}
+	}
+
+	tests := []struct {
+		policy   evictionmethods.IndexEvictionPolicy
+		expected []string
+	}{
+		{evictionmethods.IndexEvictionPolicyLRU, []string{"1", "2", "3"}},
+		{evictionmethods.IndexEvictionPolicyLFU, []string{"2", "3", "1"}},
+		{evictionmethods.IndexEvictionPolicyFIFO, []string{"2", "3", "1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.policy.String(), func(t *testing.T) {
+			o := newObjects()
+			sortEvictionCandidates(o, test.policy)
+			for i, k := range test.expected {
+				if o[i].Key != k {
+					t.Errorf("expected %s got %s at index %d", k, o[i].Key, i)
+				}
+			}
+		})
+	}
+
+}
+
+func TestReapLFU(t *testing.T) {
+
+	o := &io.Options{ReapInterval: time.Second * time.Duration(10),
+		FlushInterval:  time.Second * time.Duration(10),
+		MaxSizeObjects: 2, EvictionPolicy: evictionmethods.IndexEvictionPolicyLFU}
+
+	idx := NewIndex("test", "test", nil, o, testBulkRemoveFunc, fakeFlusherFunc, testLogger)
+
+	idx.UpdateObject(&Object{Key: "test.1", Value: []byte("test_value")})
+	idx.UpdateObject(&Object{Key: "test.2", Value: []byte("test_value")})
+	idx.UpdateObject(&Object{Key: "test.3", Value: []byte("test_value")})
+
+	// test.1 is the oldest and least-recently-accessed object,
+	// but the most frequently accessed, so LFU should retain it
+	idx.UpdateObjectAccessTime("test.1")
+	idx.UpdateObjectAccessTime("test.1")
+	idx.UpdateObjectAccessTime("test.3")
+
+	// rewriting an object should not reset its access count
+	idx.UpdateObject(&Object{Key: "test.1", Value: []byte("test_value")})
+	if idx.Objects["test.1"].AccessCount != 2 {
+		t.Errorf("expected %d got %d", 2, idx.Objects["test.1"].AccessCount)
+	}
+
+	idx.reap(testLogger)
+
+	if _, ok := idx.Objects["test.1"]; !ok {
+		t.Errorf("expected key %s to be present", "test.1")
+	}
+
+	if _, ok := idx.Objects["test.2"]; ok {
+		t.Errorf("expected key %s to be missing", "test.2")
+	}
+
+}
+
+func TestBulkRemoveBatches(t *testing.T) {
+
+	var batches [][]string
+	idx := &Index{bulkRemoveFunc: func(keys []string) {
+		batches = append(batches, keys)
+	}}
+
+	keys := []string{"1", "2", "3", "4", "5"}
+
+	idx.bulkRemove(keys, &io.Options{})
+	if len(batches) != 1 {
+		t.Errorf("expected %d got %d", 1, len(batches))
+	}
+
+	batches = nil
+	start := time.Now()
+	idx.bulkRemove(keys, &io.Options{ReapMaxConcurrentIO: 2, ReapIOPause: time.Millisecond * 10})
+	if len(batches) != 3 {
+		t.Fatalf("expected %d got %d", 3, len(batches))
+	}
+	for i, n := range []int{2, 2, 1} {
+		if len(batches[i]) != n {
+			t.Errorf("expected %d got %d for batch %d", n, len(batches[i]), i)
+		}
+	}
+	// two pauses occur between the three batches
+	if time.Since(start) < time.Millisecond*20 {
+		t.Error("expected the reaper to pause between batches")
+	}
+}
+
 func TestUpdateObjectTTL(t *testing.T) {
 
 	cacheKey := "test-ttl-key"
@@ -333,3 +434,21 @@ func TestRemoveObjects(t *testing.T) {
 		t.Error("key should not be in map")
 	}
 }
+
+func TestExpirations(t *testing.T) {
+
+	exp := time.Now().Add(time.Hour)
+	cacheConfig := &co.Options{CacheType: "test",
+		Index: &io.Options{ReapInterval: time.Second * time.Duration(10),
+			FlushInterval: time.Second * time.Duration(10)}}
+	idx := NewIndex("test", "test", nil, cacheConfig.Index, testBulkRemoveFunc, fakeFlusherFunc, testLogger)
+	idx.UpdateObject(&Object{Key: "test-key", Value: []byte("test_value"), Expiration: exp})
+
+	m := idx.Expirations()
+	if len(m) != 1 {
+		t.Errorf("expected %d got %d", 1, len(m))
+	}
+	if !m["test-key"].Equal(exp) {
+		t.Errorf("expected %s got %s", exp, m["test-key"])
+	}
+}
@@ -19,6 +19,7 @@ package options
 import (
 	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
 )
 
@@ -40,9 +41,22 @@ type Options struct {
 	// MaxSizeBackoffObjects indicates how far under max_size_objects the cache size must
 	// be to complete object-size-based eviction exercise.
 	MaxSizeBackoffObjects int64 `toml:"max_size_backoff_objects"`
+	// ReapMaxConcurrentIO, when greater than 0, limits the number of objects the reaper removes
+	// from the cache concurrently, by removing them in sequential batches of this size
+	ReapMaxConcurrentIO int `toml:"reap_max_concurrent_io"`
+	// ReapIOPauseMS is the time in milliseconds the reaper waits between removal batches
+	// when ReapMaxConcurrentIO is set
+	ReapIOPauseMS int `toml:"reap_io_pause_ms"`
+	// EvictionPolicyName selects which objects the Index evicts first when the cache
+	// exceeds its maximum size. Options are 'lru', 'lfu' and 'fifo'
+	EvictionPolicyName string `toml:"eviction_policy"`
+
+	// EvictionPolicy is the parsed value of EvictionPolicyName
+	EvictionPolicy evictionmethods.IndexEvictionPolicy `toml:"-"`
 
 	ReapInterval  time.Duration `toml:"-"`
 	FlushInterval time.Duration `toml:"-"`
+	ReapIOPause   time.Duration `toml:"-"`
 }
 
 // NewOptions returns a new Cache Index Options Reference with default values set
@@ -54,6 +68,8 @@ func NewOptions() *Options {
 		MaxSizeBackoffBytes:   d.DefaultMaxSizeBackoffBytes,
 		MaxSizeObjects:        d.DefaultMaxSizeObjects,
 		MaxSizeBackoffObjects: d.DefaultMaxSizeBackoffObjects,
+		EvictionPolicyName:    d.DefaultCacheIndexEvictionPolicyName,
+		EvictionPolicy:        d.DefaultCacheIndexEvictionPolicy,
 	}
 }
 
@@ -70,5 +86,8 @@ func (o *Options) Equal(o2 *Options) bool {
 		o.MaxSizeBytes == o2.MaxSizeBytes &&
 		o.MaxSizeBackoffBytes == o2.MaxSizeBackoffBytes &&
 		o.MaxSizeObjects == o2.MaxSizeObjects &&
-		o.MaxSizeBackoffObjects == o2.MaxSizeBackoffObjects
+		o.MaxSizeBackoffObjects == o2.MaxSizeBackoffObjects &&
+		o.EvictionPolicy == o2.EvictionPolicy &&
+		o.ReapMaxConcurrentIO == o2.ReapMaxConcurrentIO &&
+		o.ReapIOPauseMS == o2.ReapIOPauseMS
 }
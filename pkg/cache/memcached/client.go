@@ -0,0 +1,316 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memcached
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	mo "github.com/tricksterproxy/trickster/pkg/cache/memcached/options"
+)
+
+var (
+	errCacheMiss = errors.New("memcached: cache miss")
+	errNoServers = errors.New("memcached: no servers configured")
+)
+
+var (
+	crlf            = []byte("\r\n")
+	resultEnd       = []byte("END\r\n")
+	resultStored    = []byte("STORED\r\n")
+	resultDeleted   = []byte("DELETED\r\n")
+	resultTouched   = []byte("TOUCHED\r\n")
+	resultNotFound  = []byte("NOT_FOUND\r\n")
+	resultValue     = []byte("VALUE ")
+	resultVersion   = []byte("VERSION ")
+	resultClientErr = []byte("CLIENT_ERROR ")
+	resultServerErr = []byte("SERVER_ERROR ")
+	resultError     = []byte("ERROR\r\n")
+)
+
+// serverError is a SERVER_ERROR response returned by a memcached server, such as when
+// an object is too large to store. The connection remains usable after a serverError
+type serverError string
+
+func (e serverError) Error() string {
+	return "memcached: " + string(e)
+}
+
+// client is a memcached text protocol client that distributes keys across
+// its servers and maintains a pool of idle connections to each
+type client struct {
+	servers     []*server
+	maxIdle     int
+	timeout     time.Duration
+	dialTimeout time.Duration
+}
+
+// server is a memcached server endpoint and its idle connections
+type server struct {
+	addr string
+	mtx  sync.Mutex
+	idle []*conn
+}
+
+// conn is a connection to a memcached server
+type conn struct {
+	nc net.Conn
+	rw *bufio.ReadWriter
+}
+
+func newClient(o *mo.Options) *client {
+	c := &client{
+		servers:     make([]*server, len(o.Servers)),
+		maxIdle:     o.MaxIdleConns,
+		timeout:     durationFromMS(o.TimeoutMS),
+		dialTimeout: durationFromMS(o.DialTimeoutMS),
+	}
+	for i, addr := range o.Servers {
+		c.servers[i] = &server{addr: addr}
+	}
+	return c
+}
+
+// pick returns the server responsible for the key
+func (c *client) pick(key string) (*server, error) {
+	switch len(c.servers) {
+	case 0:
+		return nil, errNoServers
+	case 1:
+		return c.servers[0], nil
+	}
+	return c.servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(c.servers))], nil
+}
+
+func (c *client) getConn(s *server) (*conn, error) {
+	s.mtx.Lock()
+	if n := len(s.idle); n > 0 {
+		cn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mtx.Unlock()
+		return cn, nil
+	}
+	s.mtx.Unlock()
+	nc, err := net.DialTimeout("tcp", s.addr, c.dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{nc: nc, rw: bufio.NewReadWriter(bufio.NewReader(nc), bufio.NewWriter(nc))}, nil
+}
+
+func (c *client) putConn(s *server, cn *conn) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if len(s.idle) >= c.maxIdle {
+		cn.nc.Close()
+		return
+	}
+	s.idle = append(s.idle, cn)
+}
+
+// withConn runs f on a connection to the server. The connection is returned to the pool
+// unless f fails with an error that leaves the connection in an unknown state
+func (c *client) withConn(s *server, f func(*conn) error) error {
+	cn, err := c.getConn(s)
+	if err != nil {
+		return err
+	}
+	if c.timeout > 0 {
+		cn.nc.SetDeadline(time.Now().Add(c.timeout))
+	}
+	err = f(cn)
+	switch err.(type) {
+	case nil, serverError:
+		c.putConn(s, cn)
+	default:
+		if err == errCacheMiss {
+			c.putConn(s, cn)
+		} else {
+			cn.nc.Close()
+		}
+	}
+	return err
+}
+
+// withKeyConn runs f on a connection to the server responsible for the key
+func (c *client) withKeyConn(key string, f func(*conn) error) error {
+	s, err := c.pick(key)
+	if err != nil {
+		return err
+	}
+	return c.withConn(s, f)
+}
+
+// ping verifies that each server is reachable and responding to commands
+func (c *client) ping() error {
+	if len(c.servers) == 0 {
+		return errNoServers
+	}
+	for _, s := range c.servers {
+		err := c.withConn(s, func(cn *conn) error {
+			line, err := cn.command("version\r\n")
+			if err != nil {
+				return err
+			}
+			if !bytes.HasPrefix(line, resultVersion) {
+				return fmt.Errorf("memcached: unexpected response to version: %q", line)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("could not connect to memcached server %s: %s", s.addr, err.Error())
+		}
+	}
+	return nil
+}
+
+func (c *client) get(key string) ([]byte, error) {
+	var data []byte
+	err := c.withKeyConn(key, func(cn *conn) error {
+		line, err := cn.command("get %s\r\n", key)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(line, resultEnd) {
+			return errCacheMiss
+		}
+		if !bytes.HasPrefix(line, resultValue) {
+			return fmt.Errorf("memcached: unexpected response to get: %q", line)
+		}
+		// VALUE <key> <flags> <bytes>
+		f := bytes.Fields(line)
+		if len(f) < 4 {
+			return fmt.Errorf("memcached: malformed value line: %q", line)
+		}
+		size, err := strconv.Atoi(string(f[3]))
+		if err != nil {
+			return fmt.Errorf("memcached: malformed value line: %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(cn.rw, buf); err != nil {
+			return err
+		}
+		if !bytes.HasSuffix(buf, crlf) {
+			return errors.New("memcached: corrupt value in get response")
+		}
+		data = buf[:size]
+		line, err = cn.rw.ReadSlice('\n')
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(line, resultEnd) {
+			return fmt.Errorf("memcached: unexpected response to get: %q", line)
+		}
+		return nil
+	})
+	return data, err
+}
+
+func (c *client) set(key string, value []byte, exp int64) error {
+	return c.withKeyConn(key, func(cn *conn) error {
+		if _, err := fmt.Fprintf(cn.rw, "set %s 0 %d %d\r\n", key, exp, len(value)); err != nil {
+			return err
+		}
+		if _, err := cn.rw.Write(value); err != nil {
+			return err
+		}
+		line, err := cn.command("\r\n")
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(line, resultStored) {
+			return fmt.Errorf("memcached: unexpected response to set: %q", line)
+		}
+		return nil
+	})
+}
+
+func (c *client) delete(key string) error {
+	return c.withKeyConn(key, func(cn *conn) error {
+		line, err := cn.command("delete %s\r\n", key)
+		if err != nil {
+			return err
+		}
+		switch {
+		case bytes.Equal(line, resultDeleted):
+			return nil
+		case bytes.Equal(line, resultNotFound):
+			return errCacheMiss
+		}
+		return fmt.Errorf("memcached: unexpected response to delete: %q", line)
+	})
+}
+
+func (c *client) touch(key string, exp int64) error {
+	return c.withKeyConn(key, func(cn *conn) error {
+		line, err := cn.command("touch %s %d\r\n", key, exp)
+		if err != nil {
+			return err
+		}
+		switch {
+		case bytes.Equal(line, resultTouched):
+			return nil
+		case bytes.Equal(line, resultNotFound):
+			return errCacheMiss
+		}
+		return fmt.Errorf("memcached: unexpected response to touch: %q", line)
+	})
+}
+
+// close closes all idle connections
+func (c *client) close() error {
+	for _, s := range c.servers {
+		s.mtx.Lock()
+		for _, cn := range s.idle {
+			cn.nc.Close()
+		}
+		s.idle = nil
+		s.mtx.Unlock()
+	}
+	return nil
+}
+
+// command writes the formatted command to the connection, flushes it and returns
+// the first line of the response. CLIENT_ERROR and ERROR responses are returned as errors
+// that close the connection, since the server may not have consumed the full command
+func (cn *conn) command(format string, args ...interface{}) ([]byte, error) {
+	if _, err := fmt.Fprintf(cn.rw, format, args...); err != nil {
+		return nil, err
+	}
+	if err := cn.rw.Flush(); err != nil {
+		return nil, err
+	}
+	line, err := cn.rw.ReadSlice('\n')
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(line, resultServerErr):
+		return nil, serverError(bytes.TrimSpace(line))
+	case bytes.HasPrefix(line, resultClientErr), bytes.Equal(line, resultError):
+		return nil, fmt.Errorf("memcached: %s", bytes.TrimSpace(line))
+	}
+	return line, nil
+}
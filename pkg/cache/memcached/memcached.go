@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package memcached is the memcached implementation of the Trickster Cache
+package memcached
+
+import (
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/metrics"
+	"github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/md5"
+)
+
+// Memcached is the string "memcached"
+const Memcached = "memcached"
+
+// maxRelativeExpiration is the longest expiration that memcached interprets as a number of
+// seconds from now. Larger expiration values are interpreted as absolute Unix timestamps
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+// maxKeyLength is the maximum length of a memcached key
+const maxKeyLength = 250
+
+// Cache represents a memcached cache object that conforms to the Cache interface
+type Cache struct {
+	Name   string
+	Config *options.Options
+	Logger *tl.Logger
+	locker locks.NamedLocker
+
+	client *client
+}
+
+// Locker returns the cache's locker
+func (c *Cache) Locker() locks.NamedLocker {
+	return c.locker
+}
+
+// SetLocker sets the cache's locker
+func (c *Cache) SetLocker(l locks.NamedLocker) {
+	c.locker = l
+}
+
+// Configuration returns the Configuration for the Cache object
+func (c *Cache) Configuration() *options.Options {
+	return c.Config
+}
+
+// Connect connects to the configured memcached servers, and returns an error
+// if any of them cannot be reached
+func (c *Cache) Connect() error {
+	c.Logger.Info("connecting to memcached",
+		tl.Pairs{"servers": strings.Join(c.Config.Memcached.Servers, ",")})
+	c.client = newClient(c.Config.Memcached)
+	return c.client.ping()
+}
+
+// Store places the the data into the memcached Cache using the provided Key and TTL
+func (c *Cache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	metrics.ObserveCacheOperation(c.Name, c.Config.CacheType, "set", "none", float64(len(data)))
+	c.Logger.Debug("memcached cache store", tl.Pairs{"key": cacheKey})
+	return c.client.set(storageKey(cacheKey), data, expiration(ttl, time.Now()))
+}
+
+// Retrieve gets data from the memcached Cache using the provided Key
+// because memcached manages Object Expiration internally, allowExpired is not used.
+func (c *Cache) Retrieve(cacheKey string, allowExpired bool) ([]byte, status.LookupStatus, error) {
+	data, err := c.client.get(storageKey(cacheKey))
+
+	if err == nil {
+		c.Logger.Debug("memcached cache retrieve", tl.Pairs{"key": cacheKey})
+		metrics.ObserveCacheOperation(c.Name, c.Config.CacheType, "get", "hit", float64(len(data)))
+		return data, status.LookupStatusHit, nil
+	}
+
+	if err == errCacheMiss {
+		c.Logger.Debug("memcached cache miss", tl.Pairs{"key": cacheKey})
+		metrics.ObserveCacheMiss(cacheKey, c.Name, c.Config.CacheType)
+		return nil, status.LookupStatusKeyMiss, cache.ErrKNF
+	}
+
+	c.Logger.Debug("memcached cache retrieve failed", tl.Pairs{"key": cacheKey, "reason": err.Error()})
+	metrics.ObserveCacheMiss(cacheKey, c.Name, c.Config.CacheType)
+	return nil, status.LookupStatusError, err
+}
+
+// Remove removes an object in cache, if present
+func (c *Cache) Remove(cacheKey string) {
+	c.Logger.Debug("memcached cache remove", tl.Pairs{"key": cacheKey})
+	c.client.delete(storageKey(cacheKey))
+	metrics.ObserveCacheDel(c.Name, c.Config.CacheType, 0)
+}
+
+// SetTTL updates the TTL for the provided cache object
+func (c *Cache) SetTTL(cacheKey string, ttl time.Duration) {
+	c.client.touch(storageKey(cacheKey), expiration(ttl, time.Now()))
+}
+
+// BulkRemove removes a list of objects from the cache
+func (c *Cache) BulkRemove(cacheKeys []string) {
+	c.Logger.Debug("memcached cache bulk remove", tl.Pairs{})
+	for _, k := range cacheKeys {
+		c.client.delete(storageKey(k))
+	}
+	metrics.ObserveCacheDel(c.Name, c.Config.CacheType, float64(len(cacheKeys)))
+}
+
+// Close closes the idle connections to the memcached servers
+func (c *Cache) Close() error {
+	c.Logger.Info("closing memcached connections", tl.Pairs{})
+	if c.client == nil {
+		return nil
+	}
+	return c.client.close()
+}
+
+// expiration returns the memcached expiration value for the TTL. TTLs of up to 30 days are
+// provided as a number of seconds, rounded up so that sub-second TTLs do not become 0, which
+// memcached treats as never expiring. Longer TTLs are provided as an absolute Unix timestamp.
+// A TTL of 0 or less does not expire.
+func expiration(ttl time.Duration, now time.Time) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	secs := int64((ttl + time.Second - 1) / time.Second)
+	if ttl <= maxRelativeExpiration {
+		return secs
+	}
+	return now.Unix() + secs
+}
+
+// storageKey returns the cache key, or its digest if the key is not a legal memcached key
+// because it is too long or contains whitespace or control characters
+func storageKey(key string) string {
+	if len(key) > maxKeyLength {
+		return md5.Checksum(key)
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return md5.Checksum(key)
+		}
+	}
+	return key
+}
+
+func durationFromMS(input int) time.Duration {
+	return time.Duration(int64(input)) * time.Millisecond
+}
@@ -0,0 +1,293 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package memcached
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	mo "github.com/tricksterproxy/trickster/pkg/cache/memcached/options"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+const cacheKey = `cacheKey`
+
+// fakeServer is a minimal in-process memcached server for testing
+type fakeServer struct {
+	ln    net.Listener
+	mtx   sync.Mutex
+	items map[string][]byte
+	exps  map[string]int64
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln, items: make(map[string][]byte), exps: make(map[string]int64)}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nc)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) expiration(key string) int64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.exps[key]
+}
+
+func (s *fakeServer) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			fmt.Fprint(nc, "ERROR\r\n")
+			continue
+		}
+		s.mtx.Lock()
+		switch {
+		case f[0] == "version":
+			fmt.Fprint(nc, "VERSION 1.6.9\r\n")
+		case f[0] == "get" && len(f) == 2:
+			if v, ok := s.items[f[1]]; ok {
+				fmt.Fprintf(nc, "VALUE %s 0 %d\r\n%s\r\n", f[1], len(v), v)
+			}
+			fmt.Fprint(nc, "END\r\n")
+		case f[0] == "set" && len(f) == 5:
+			size, _ := strconv.Atoi(f[4])
+			buf := make([]byte, size+2)
+			if _, err = io.ReadFull(r, buf); err != nil {
+				s.mtx.Unlock()
+				return
+			}
+			exp, _ := strconv.ParseInt(f[3], 10, 64)
+			s.items[f[1]] = buf[:size]
+			s.exps[f[1]] = exp
+			fmt.Fprint(nc, "STORED\r\n")
+		case f[0] == "delete" && len(f) == 2:
+			if _, ok := s.items[f[1]]; ok {
+				delete(s.items, f[1])
+				fmt.Fprint(nc, "DELETED\r\n")
+			} else {
+				fmt.Fprint(nc, "NOT_FOUND\r\n")
+			}
+		case f[0] == "touch" && len(f) == 3:
+			if _, ok := s.items[f[1]]; ok {
+				s.exps[f[1]], _ = strconv.ParseInt(f[2], 10, 64)
+				fmt.Fprint(nc, "TOUCHED\r\n")
+			} else {
+				fmt.Fprint(nc, "NOT_FOUND\r\n")
+			}
+		default:
+			fmt.Fprint(nc, "ERROR\r\n")
+		}
+		s.mtx.Unlock()
+	}
+}
+
+func setupMemcachedCache(t *testing.T) (*Cache, *fakeServer) {
+	s := newFakeServer(t)
+	cacheConfig := &co.Options{CacheType: "memcached",
+		Memcached: &mo.Options{Servers: []string{s.addr()}, MaxIdleConns: 2,
+			TimeoutMS: 1000, DialTimeoutMS: 1000}}
+	mc := &Cache{Name: "test", Config: cacheConfig, Logger: tl.ConsoleLogger("error")}
+	if err := mc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return mc, s
+}
+
+func TestConfiguration(t *testing.T) {
+	mc, s := setupMemcachedCache(t)
+	defer s.ln.Close()
+	cfg := mc.Configuration()
+	if cfg.CacheType != Memcached {
+		t.Errorf("expected %s got %s", Memcached, cfg.CacheType)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	mc, s := setupMemcachedCache(t)
+	s.ln.Close()
+	mc.client.close()
+	if err := mc.Connect(); err == nil {
+		t.Error("expected connection error")
+	}
+
+	mc.Config.Memcached.Servers = nil
+	if err := mc.Connect(); err != errNoServers {
+		t.Errorf("expected %v got %v", errNoServers, err)
+	}
+}
+
+func TestStoreAndRetrieve(t *testing.T) {
+	mc, s := setupMemcachedCache(t)
+	defer s.ln.Close()
+	defer mc.Close()
+
+	_, ls, err := mc.Retrieve(cacheKey, false)
+	if err != cache.ErrKNF {
+		t.Errorf("expected %v got %v", cache.ErrKNF, err)
+	}
+	if ls != status.LookupStatusKeyMiss {
+		t.Errorf("expected %s got %s", status.LookupStatusKeyMiss, ls)
+	}
+
+	if err = mc.Store(cacheKey, []byte("data\r\nEND\r\n"), 60*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	data, ls, err := mc.Retrieve(cacheKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "data\r\nEND\r\n" {
+		t.Errorf("unexpected data %q", data)
+	}
+	if ls != status.LookupStatusHit {
+		t.Errorf("expected %s got %s", status.LookupStatusHit, ls)
+	}
+	if v := s.expiration(cacheKey); v != 60 {
+		t.Errorf("expected %d got %d", 60, v)
+	}
+
+	mc.SetTTL(cacheKey, 120*time.Second)
+	if v := s.expiration(cacheKey); v != 120 {
+		t.Errorf("expected %d got %d", 120, v)
+	}
+
+	mc.Remove(cacheKey)
+	if _, _, err = mc.Retrieve(cacheKey, false); err != cache.ErrKNF {
+		t.Errorf("expected %v got %v", cache.ErrKNF, err)
+	}
+}
+
+func TestBulkRemove(t *testing.T) {
+	mc, s := setupMemcachedCache(t)
+	defer s.ln.Close()
+	defer mc.Close()
+
+	keys := []string{"key1", "key2", "key3"}
+	for _, k := range keys {
+		if err := mc.Store(k, []byte("data"), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mc.BulkRemove(keys[:2])
+	for i, k := range keys {
+		_, _, err := mc.Retrieve(k, false)
+		if i < 2 && err != cache.ErrKNF {
+			t.Errorf("expected %v got %v", cache.ErrKNF, err)
+		} else if i == 2 && err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestRetrieveError(t *testing.T) {
+	mc, s := setupMemcachedCache(t)
+	s.ln.Close()
+	mc.client.close()
+	_, ls, err := mc.Retrieve(cacheKey, false)
+	if err == nil {
+		t.Error("expected error")
+	}
+	if ls != status.LookupStatusError {
+		t.Errorf("expected %s got %s", status.LookupStatusError, ls)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	tests := []struct {
+		ttl      time.Duration
+		expected int64
+	}{
+		{0, 0},
+		{-1 * time.Second, 0},
+		{500 * time.Millisecond, 1},
+		{time.Minute, 60},
+		{maxRelativeExpiration, 2592000},
+		{maxRelativeExpiration + time.Second, 1600000000 + 2592001},
+	}
+	for _, test := range tests {
+		if v := expiration(test.ttl, now); v != test.expected {
+			t.Errorf("ttl %s: expected %d got %d", test.ttl, test.expected, v)
+		}
+	}
+}
+
+func TestStorageKey(t *testing.T) {
+	if v := storageKey(cacheKey); v != cacheKey {
+		t.Errorf("expected %s got %s", cacheKey, v)
+	}
+	for _, k := range []string{"cache key", "cache\nkey", strings.Repeat("k", maxKeyLength+1)} {
+		if v := storageKey(k); len(v) != 32 {
+			t.Errorf("expected digest for %q got %s", k, v)
+		}
+	}
+}
+
+func TestServerDistribution(t *testing.T) {
+	s1, s2 := newFakeServer(t), newFakeServer(t)
+	defer s1.ln.Close()
+	defer s2.ln.Close()
+	c := newClient(&mo.Options{Servers: []string{s1.addr(), s2.addr()}, MaxIdleConns: 1})
+	if err := c.ping(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := c.set("key"+strconv.Itoa(i), []byte("data"), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s1.items) == 0 || len(s2.items) == 0 {
+		t.Errorf("expected keys on both servers, got %d and %d", len(s1.items), len(s2.items))
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := c.get("key" + strconv.Itoa(i)); err != nil {
+			t.Error(err)
+		}
+	}
+	c.close()
+}
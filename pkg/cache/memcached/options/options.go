@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+)
+
+// Options is a collection of Configurations for Connecting to memcached
+type Options struct {
+	// Servers is the list of FQDN:port or IP:port memcached server endpoints. Keys are
+	// distributed across the servers by their hash
+	Servers []string `toml:"servers"`
+	// MaxIdleConns is the maximum number of idle connections kept open to each server
+	MaxIdleConns int `toml:"max_idle_conns"`
+	// TimeoutMS is the timeout for socket reads and writes.
+	// If reached, operations will fail with a timeout instead of blocking.
+	TimeoutMS int `toml:"timeout_ms"`
+	// DialTimeoutMS is the timeout for establishing new connections.
+	DialTimeoutMS int `toml:"dial_timeout_ms"`
+}
+
+// Equal returns true if all values in the Options references are identical
+func (o *Options) Equal(o2 *Options) bool {
+	if o2 == nil {
+		return false
+	}
+	if len(o.Servers) != len(o2.Servers) {
+		return false
+	}
+	for i := range o.Servers {
+		if o.Servers[i] != o2.Servers[i] {
+			return false
+		}
+	}
+	return o.MaxIdleConns == o2.MaxIdleConns &&
+		o.TimeoutMS == o2.TimeoutMS &&
+		o.DialTimeoutMS == o2.DialTimeoutMS
+}
+
+// NewOptions returns a new memcached Options Reference with default values set
+func NewOptions() *Options {
+	return &Options{
+		Servers:       []string{d.DefaultMemcachedServer},
+		MaxIdleConns:  d.DefaultMemcachedMaxIdleConns,
+		TimeoutMS:     d.DefaultMemcachedTimeoutMS,
+		DialTimeoutMS: d.DefaultMemcachedDialTimeoutMS,
+	}
+}
@@ -51,6 +51,11 @@ func ObserveCacheEvent(cache, cacheType, event, reason string) {
 	metrics.CacheEvents.WithLabelValues(cache, cacheType, event, reason).Inc()
 }
 
+// ObserveCacheEvictions increments the count of objects evicted by the cache index's eviction policy
+func ObserveCacheEvictions(cache, cacheType, policy string, count float64) {
+	metrics.CacheEvictions.WithLabelValues(cache, cacheType, policy).Add(count)
+}
+
 // ObserveCacheSizeChange adjust counters and gauges as the cache size changes due to object operations
 func ObserveCacheSizeChange(cache, cacheType string, byteCount, objectCount int64) {
 	metrics.CacheObjects.WithLabelValues(cache, cacheType).Set(float64(objectCount))
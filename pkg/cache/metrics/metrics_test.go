@@ -53,6 +53,10 @@ func TestObserveCacheEvent(t *testing.T) {
 	ObserveCacheEvent(testCacheName, testCacheType, "test", "test")
 }
 
+func TestObserveCacheEvictions(t *testing.T) {
+	ObserveCacheEvictions(testCacheName, testCacheType, "lru", 1)
+}
+
 func TestObserveCacheSizeChange(t *testing.T) {
 	ObserveCacheSizeChange(testCacheName, testCacheType, 0, 0)
 }
@@ -21,21 +21,41 @@ import (
 	bbolt "github.com/tricksterproxy/trickster/pkg/cache/bbolt/options"
 	filesystem "github.com/tricksterproxy/trickster/pkg/cache/filesystem/options"
 	index "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+	memcached "github.com/tricksterproxy/trickster/pkg/cache/memcached/options"
 	redis "github.com/tricksterproxy/trickster/pkg/cache/redis/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/serialization"
 	"github.com/tricksterproxy/trickster/pkg/cache/types"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	"github.com/tricksterproxy/trickster/pkg/util/compress/zstd"
 )
 
 // Options is a collection of defining the Trickster Caching Behavior
 type Options struct {
 	// Name is the Name of the cache, taken from the Key in the Caches map[string]*CacheConfig
 	Name string `toml:"-"`
-	// Type represents the type of cache that we wish to use: "boltdb", "memory", "filesystem", or "redis"
+	// Type represents the type of cache that we wish to use: "boltdb", "memory", "filesystem", "redis" or "memcached"
 	CacheType string `toml:"cache_type"`
+	// SerializationFormat is the format used to serialize objects stored in the cache:
+	// "msgpack", "json", or "gob". It does not apply to memory caches
+	SerializationFormat string `toml:"serialization_format"`
+	// CompressionMinSizeBytes is the minimum serialized size of an object for it to be compressed
+	// when stored in the cache. Smaller objects are stored uncompressed. It does not apply to memory caches
+	CompressionMinSizeBytes int `toml:"compression_min_size_bytes"`
+	// MaxKeyLengthBytes, when greater than 0, is the maximum length of a cache key. Longer keys
+	// are replaced with their fixed-length digest
+	MaxKeyLengthBytes int `toml:"max_key_length_bytes"`
+	// CompressionDictionaryPath is the path to a trained zstd dictionary file. When provided, compressible
+	// objects are compressed with zstd using the dictionary instead of snappy. It does not apply to memory caches
+	CompressionDictionaryPath string `toml:"compression_dictionary_path"`
+	// VerifyChecksums, when true, stores a checksum with each object written to the cache and verifies
+	// it when the object is retrieved, treating a mismatch as a cache miss. It does not apply to memory caches
+	VerifyChecksums bool `toml:"verify_checksums"`
 	// Index provides options for the Cache Index
 	Index *index.Options `toml:"index"`
 	// Redis provides options for Redis caching
 	Redis *redis.Options `toml:"redis"`
+	// Memcached provides options for memcached caching
+	Memcached *memcached.Options `toml:"memcached"`
 	// Filesystem provides options for Filesystem caching
 	Filesystem *filesystem.Options `toml:"filesystem"`
 	// BBolt provides options for BBolt caching
@@ -48,19 +68,28 @@ type Options struct {
 	// CacheTypeID represents the internal constant for the provided CacheType string
 	// and is automatically populated at startup
 	CacheTypeID types.CacheType `toml:"-"`
+	// SerializationFormatID represents the internal constant for the provided SerializationFormat string
+	// and is automatically populated at startup
+	SerializationFormatID serialization.Format `toml:"-"`
+	// CompressionDictionary is the zstd dictionary loaded from CompressionDictionaryPath,
+	// or nil when no valid dictionary is configured
+	CompressionDictionary *zstd.Dictionary `toml:"-"`
 }
 
 // NewOptions will return a pointer to an OriginConfig with the default configuration settings
 func NewOptions() *Options {
 
 	return &Options{
-		CacheType:   d.DefaultCacheType,
-		CacheTypeID: d.DefaultCacheTypeID,
-		Redis:       redis.NewOptions(),
-		Filesystem:  filesystem.NewOptions(),
-		BBolt:       bbolt.NewOptions(),
-		Badger:      badger.NewOptions(),
-		Index:       index.NewOptions(),
+		CacheType:             d.DefaultCacheType,
+		CacheTypeID:           d.DefaultCacheTypeID,
+		SerializationFormat:   d.DefaultSerializationFormat,
+		SerializationFormatID: d.DefaultSerializationFormatID,
+		Redis:                 redis.NewOptions(),
+		Memcached:             memcached.NewOptions(),
+		Filesystem:            filesystem.NewOptions(),
+		BBolt:                 bbolt.NewOptions(),
+		Badger:                badger.NewOptions(),
+		Index:                 index.NewOptions(),
 	}
 }
 
@@ -71,7 +100,16 @@ func (cc *Options) Clone() *Options {
 	c.Name = cc.Name
 	c.CacheType = cc.CacheType
 	c.CacheTypeID = cc.CacheTypeID
-
+	c.SerializationFormat = cc.SerializationFormat
+	c.SerializationFormatID = cc.SerializationFormatID
+	c.CompressionMinSizeBytes = cc.CompressionMinSizeBytes
+	c.MaxKeyLengthBytes = cc.MaxKeyLengthBytes
+	c.CompressionDictionaryPath = cc.CompressionDictionaryPath
+	c.CompressionDictionary = cc.CompressionDictionary
+	c.VerifyChecksums = cc.VerifyChecksums
+
+	c.Index.EvictionPolicy = cc.Index.EvictionPolicy
+	c.Index.EvictionPolicyName = cc.Index.EvictionPolicyName
 	c.Index.FlushInterval = cc.Index.FlushInterval
 	c.Index.FlushIntervalSecs = cc.Index.FlushIntervalSecs
 	c.Index.MaxSizeBackoffBytes = cc.Index.MaxSizeBackoffBytes
@@ -79,12 +117,16 @@ func (cc *Options) Clone() *Options {
 	c.Index.MaxSizeBytes = cc.Index.MaxSizeBytes
 	c.Index.MaxSizeObjects = cc.Index.MaxSizeObjects
 	c.Index.ReapInterval = cc.Index.ReapInterval
+	c.Index.ReapIOPause = cc.Index.ReapIOPause
+	c.Index.ReapIOPauseMS = cc.Index.ReapIOPauseMS
+	c.Index.ReapMaxConcurrentIO = cc.Index.ReapMaxConcurrentIO
 	c.Index.ReapIntervalSecs = cc.Index.ReapIntervalSecs
 
 	c.Badger.Directory = cc.Badger.Directory
 	c.Badger.ValueDirectory = cc.Badger.ValueDirectory
 
 	c.Filesystem.CachePath = cc.Filesystem.CachePath
+	c.Filesystem.MaxOpenFiles = cc.Filesystem.MaxOpenFiles
 
 	c.BBolt.Bucket = cc.BBolt.Bucket
 	c.BBolt.Filename = cc.BBolt.Filename
@@ -102,12 +144,19 @@ func (cc *Options) Clone() *Options {
 	c.Redis.MinIdleConns = cc.Redis.MinIdleConns
 	c.Redis.MinRetryBackoffMS = cc.Redis.MinRetryBackoffMS
 	c.Redis.Password = cc.Redis.Password
+	c.Redis.PasswordFile = cc.Redis.PasswordFile
 	c.Redis.PoolSize = cc.Redis.PoolSize
 	c.Redis.PoolTimeoutMS = cc.Redis.PoolTimeoutMS
 	c.Redis.Protocol = cc.Redis.Protocol
 	c.Redis.ReadTimeoutMS = cc.Redis.ReadTimeoutMS
 	c.Redis.SentinelMaster = cc.Redis.SentinelMaster
 	c.Redis.WriteTimeoutMS = cc.Redis.WriteTimeoutMS
+	c.Redis.DrainTimeoutMS = cc.Redis.DrainTimeoutMS
+
+	c.Memcached.Servers = cc.Memcached.Servers
+	c.Memcached.MaxIdleConns = cc.Memcached.MaxIdleConns
+	c.Memcached.TimeoutMS = cc.Memcached.TimeoutMS
+	c.Memcached.DialTimeoutMS = cc.Memcached.DialTimeoutMS
 
 	return c
 
@@ -123,6 +172,13 @@ func (cc *Options) Equal(cc2 *Options) bool {
 
 	return cc.Name == cc2.Name &&
 		cc.CacheType == cc2.CacheType &&
-		cc.CacheTypeID == cc2.CacheTypeID
+		cc.CacheTypeID == cc2.CacheTypeID &&
+		cc.SerializationFormatID == cc2.SerializationFormatID &&
+		cc.CompressionMinSizeBytes == cc2.CompressionMinSizeBytes &&
+		cc.MaxKeyLengthBytes == cc2.MaxKeyLengthBytes &&
+		cc.CompressionDictionaryPath == cc2.CompressionDictionaryPath &&
+		cc.VerifyChecksums == cc2.VerifyChecksums &&
+		(cc.Redis == cc2.Redis || (cc.Redis != nil && cc.Redis.Equal(cc2.Redis))) &&
+		(cc.Memcached == cc2.Memcached || (cc.Memcached != nil && cc.Memcached.Equal(cc2.Memcached)))
 
 }
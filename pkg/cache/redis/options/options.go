@@ -32,6 +32,9 @@ type Options struct {
 	Endpoints []string `toml:"endpoints"`
 	// Password can be set when using password protected redis instance.
 	Password string `toml:"password"`
+	// PasswordFile is the path to a file containing the password, which is read into Password
+	// when the config is loaded or reloaded. It cannot be used together with Password
+	PasswordFile string `toml:"password_file"`
 	// SentinelMaster should be set when using Redis Sentinel to indicate the Master Node
 	SentinelMaster string `toml:"sentinel_master"`
 	// DB is the Database to be selected after connecting to the server.
@@ -64,6 +67,44 @@ type Options struct {
 	IdleTimeoutMS int `toml:"idle_timeout_ms"`
 	// IdleCheckFrequencyMS is the frequency of idle checks made by idle connections reaper.
 	IdleCheckFrequencyMS int `toml:"idle_check_frequency_ms"`
+	// DrainTimeoutMS is the maximum amount of time to wait for in-flight operations to complete
+	// before closing the client, such as when it is replaced during a config reload.
+	DrainTimeoutMS int `toml:"drain_timeout_ms"`
+}
+
+// Equal returns true if all values in the Options references are identical
+func (o *Options) Equal(o2 *Options) bool {
+	if o2 == nil {
+		return false
+	}
+	if len(o.Endpoints) != len(o2.Endpoints) {
+		return false
+	}
+	for i := range o.Endpoints {
+		if o.Endpoints[i] != o2.Endpoints[i] {
+			return false
+		}
+	}
+	return o.ClientType == o2.ClientType &&
+		o.Protocol == o2.Protocol &&
+		o.Endpoint == o2.Endpoint &&
+		o.Password == o2.Password &&
+		o.PasswordFile == o2.PasswordFile &&
+		o.SentinelMaster == o2.SentinelMaster &&
+		o.DB == o2.DB &&
+		o.MaxRetries == o2.MaxRetries &&
+		o.MinRetryBackoffMS == o2.MinRetryBackoffMS &&
+		o.MaxRetryBackoffMS == o2.MaxRetryBackoffMS &&
+		o.DialTimeoutMS == o2.DialTimeoutMS &&
+		o.ReadTimeoutMS == o2.ReadTimeoutMS &&
+		o.WriteTimeoutMS == o2.WriteTimeoutMS &&
+		o.PoolSize == o2.PoolSize &&
+		o.MinIdleConns == o2.MinIdleConns &&
+		o.MaxConnAgeMS == o2.MaxConnAgeMS &&
+		o.PoolTimeoutMS == o2.PoolTimeoutMS &&
+		o.IdleTimeoutMS == o2.IdleTimeoutMS &&
+		o.IdleCheckFrequencyMS == o2.IdleCheckFrequencyMS &&
+		o.DrainTimeoutMS == o2.DrainTimeoutMS
 }
 
 // NewOptions returns a new Redis Options Reference with default values set
@@ -73,5 +114,7 @@ func NewOptions() *Options {
 		Protocol:   d.DefaultRedisProtocol,
 		Endpoint:   d.DefaultRedisEndpoint,
 		Endpoints:  []string{d.DefaultRedisEndpoint},
+
+		DrainTimeoutMS: d.DefaultRedisDrainTimeoutMS,
 	}
 }
@@ -24,3 +24,23 @@ func TestNewOptions(t *testing.T) {
 		t.Error("expected non-nil options")
 	}
 }
+
+func TestEqual(t *testing.T) {
+	o := NewOptions()
+	o2 := NewOptions()
+	if !o.Equal(o2) {
+		t.Error("expected true")
+	}
+	if o.Equal(nil) {
+		t.Error("expected false")
+	}
+	o2.Endpoint = "redis2:6379"
+	if o.Equal(o2) {
+		t.Error("expected false")
+	}
+	o2 = NewOptions()
+	o2.Endpoints = []string{"redis2:6379"}
+	if o.Equal(o2) {
+		t.Error("expected false")
+	}
+}
@@ -19,6 +19,7 @@
 package redis
 
 import (
+	"sync/atomic"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -34,6 +35,9 @@ import (
 // Redis is the string "redis"
 const Redis = "redis"
 
+// drainPollInterval is how often Close checks for the completion of in-flight operations
+const drainPollInterval = 10 * time.Millisecond
+
 // Cache represents a redis cache object that conforms to the Cache interface
 type Cache struct {
 	Name   string
@@ -43,6 +47,9 @@ type Cache struct {
 
 	client redis.Cmdable
 	closer func() error
+
+	// inflight is the number of operations currently using the client
+	inflight int32
 }
 
 // Locker returns the cache's locker
@@ -96,6 +103,8 @@ func (c *Cache) Connect() error {
 
 // Store places the the data into the Redis Cache using the provided Key and TTL
 func (c *Cache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	c.beginOp()
+	defer c.endOp()
 	metrics.ObserveCacheOperation(c.Name, c.Config.CacheType, "set", "none", float64(len(data)))
 	c.Logger.Debug("redis cache store", tl.Pairs{"key": cacheKey})
 	return c.client.Set(cacheKey, data, ttl).Err()
@@ -104,6 +113,8 @@ func (c *Cache) Store(cacheKey string, data []byte, ttl time.Duration) error {
 // Retrieve gets data from the Redis Cache using the provided Key
 // because Redis manages Object Expiration internally, allowExpired is not used.
 func (c *Cache) Retrieve(cacheKey string, allowExpired bool) ([]byte, status.LookupStatus, error) {
+	c.beginOp()
+	defer c.endOp()
 	res, err := c.client.Get(cacheKey).Result()
 
 	if err == nil {
@@ -126,6 +137,8 @@ func (c *Cache) Retrieve(cacheKey string, allowExpired bool) ([]byte, status.Loo
 
 // Remove removes an object in cache, if present
 func (c *Cache) Remove(cacheKey string) {
+	c.beginOp()
+	defer c.endOp()
 	c.Logger.Debug("redis cache remove", tl.Pairs{"key": cacheKey})
 	c.client.Del(cacheKey)
 	metrics.ObserveCacheDel(c.Name, c.Config.CacheType, 0)
@@ -133,22 +146,50 @@ func (c *Cache) Remove(cacheKey string) {
 
 // SetTTL updates the TTL for the provided cache object
 func (c *Cache) SetTTL(cacheKey string, ttl time.Duration) {
+	c.beginOp()
+	defer c.endOp()
 	c.client.Expire(cacheKey, ttl)
 }
 
 // BulkRemove removes a list of objects from the cache. noLock is not used for Redis
 func (c *Cache) BulkRemove(cacheKeys []string) {
+	c.beginOp()
+	defer c.endOp()
 	c.Logger.Debug("redis cache bulk remove", tl.Pairs{})
 	c.client.Del(cacheKeys...)
 	metrics.ObserveCacheDel(c.Name, c.Config.CacheType, float64(len(cacheKeys)))
 }
 
-// Close disconnects from the Redis Cache
+// Close disconnects from the Redis Cache, after waiting up to the configured
+// drain timeout for any in-flight operations to complete
 func (c *Cache) Close() error {
+	c.drain()
 	c.Logger.Info("closing redis connection", tl.Pairs{})
 	return c.closer()
 }
 
+func (c *Cache) beginOp() {
+	atomic.AddInt32(&c.inflight, 1)
+}
+
+func (c *Cache) endOp() {
+	atomic.AddInt32(&c.inflight, -1)
+}
+
+// drain blocks until there are no in-flight operations, or the drain timeout has elapsed
+func (c *Cache) drain() {
+	timeout := durationFromMS(c.Config.Redis.DrainTimeoutMS)
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt32(&c.inflight) > 0 {
+		if !time.Now().Before(deadline) {
+			c.Logger.Warn("redis drain timeout exceeded; closing connection with operations in flight",
+				tl.Pairs{"inflight": atomic.LoadInt32(&c.inflight), "drainTimeoutMS": c.Config.Redis.DrainTimeoutMS})
+			return
+		}
+		time.Sleep(drainPollInterval)
+	}
+}
+
 func durationFromMS(input int) time.Duration {
 	return time.Duration(int64(input)) * time.Millisecond
 }
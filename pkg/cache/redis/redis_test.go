@@ -383,6 +383,52 @@ func TestRedisCache_Close(t *testing.T) {
 	}
 }
 
+func TestRedisCache_CloseDrain(t *testing.T) {
+	rc, close := setupRedisCache(clientTypeStandard)
+	defer close()
+	rc.Config.Redis.DrainTimeoutMS = 1000
+
+	err := rc.Connect()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// an in-flight operation should delay the close until it completes
+	rc.beginOp()
+	closed := make(chan error)
+	go func() {
+		closed <- rc.Close()
+	}()
+	select {
+	case <-closed:
+		t.Fatal("expected close to wait for the in-flight operation")
+	case <-time.After(50 * time.Millisecond):
+	}
+	rc.endOp()
+	select {
+	case err = <-closed:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected close to complete after the in-flight operation")
+	}
+
+	// an operation that never completes should not prevent the close beyond the drain timeout
+	rc, close2 := setupRedisCache(clientTypeStandard)
+	defer close2()
+	rc.Config.Redis.DrainTimeoutMS = 20
+	err = rc.Connect()
+	if err != nil {
+		t.Error(err)
+	}
+	rc.beginOp()
+	err = rc.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
 func TestCache_Remove(t *testing.T) {
 
 	rc, close := setupRedisCache(clientTypeStandard)
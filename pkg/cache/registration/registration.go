@@ -23,6 +23,7 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/cache/badger"
 	"github.com/tricksterproxy/trickster/pkg/cache/bbolt"
 	"github.com/tricksterproxy/trickster/pkg/cache/filesystem"
+	"github.com/tricksterproxy/trickster/pkg/cache/memcached"
 	"github.com/tricksterproxy/trickster/pkg/cache/memory"
 	"github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/redis"
@@ -37,6 +38,7 @@ const (
 	ctRedis      = "redis"
 	ctBBolt      = "bbolt"
 	ctBadger     = "badger"
+	ctMemcached  = "memcached"
 )
 
 // Caches maintains a list of active caches
@@ -84,12 +86,17 @@ func NewCache(cacheName string, cfg *options.Options, logger *tl.Logger) cache.C
 		c = &bbolt.Cache{Name: cacheName, Config: cfg, Logger: logger}
 	case ctBadger:
 		c = &badger.Cache{Name: cacheName, Config: cfg, Logger: logger}
+	case ctMemcached:
+		c = &memcached.Cache{Name: cacheName, Config: cfg, Logger: logger}
 	default:
 		// Default to MemoryCache
 		c = &memory.Cache{Name: cacheName, Config: cfg, Logger: logger}
 	}
 
 	c.SetLocker(locks.NewNamedLocker())
-	c.Connect()
+	if err := c.Connect(); err != nil {
+		logger.Error("cache connection failed",
+			tl.Pairs{"cacheName": cacheName, "cacheType": cfg.CacheType, "detail": err.Error()})
+	}
 	return c
 }
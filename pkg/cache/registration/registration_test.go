@@ -25,6 +25,7 @@ import (
 	bbo "github.com/tricksterproxy/trickster/pkg/cache/bbolt/options"
 	flo "github.com/tricksterproxy/trickster/pkg/cache/filesystem/options"
 	io "github.com/tricksterproxy/trickster/pkg/cache/index/options"
+	mco "github.com/tricksterproxy/trickster/pkg/cache/memcached/options"
 	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	ro "github.com/tricksterproxy/trickster/pkg/cache/redis/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/types"
@@ -102,6 +103,7 @@ func newCacheConfig(t *testing.T, cacheType string) *co.Options {
 	return &co.Options{
 		CacheType:  cacheType,
 		Redis:      &ro.Options{Protocol: "tcp", Endpoint: "redis:6379", Endpoints: []string{"redis:6379"}},
+		Memcached:  &mco.Options{Servers: []string{"memcached:11211"}, DialTimeoutMS: 100},
 		Filesystem: &flo.Options{CachePath: fd},
 		BBolt:      &bbo.Options{Filename: "/tmp/test.db", Bucket: "trickster_test"},
 		Badger:     &bao.Options{Directory: bd, ValueDirectory: bd},
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package serialization enumerates the formats used to serialize objects stored in a cache
+package serialization
+
+import "strconv"
+
+// Format enumerates the serialization formats for cached objects
+type Format int
+
+const (
+	// FormatMsgPack indicates objects are serialized using MessagePack
+	FormatMsgPack = Format(iota)
+	// FormatJSON indicates objects are serialized using JSON
+	FormatJSON
+	// FormatGob indicates objects are serialized using Go's encoding/gob
+	FormatGob
+)
+
+// Names is a map of serialization formats keyed by name
+var Names = map[string]Format{
+	"msgpack": FormatMsgPack,
+	"json":    FormatJSON,
+	"gob":     FormatGob,
+}
+
+// Values is a map of serialization formats keyed by internal id
+var Values = make(map[Format]string)
+
+func init() {
+	for k, v := range Names {
+		Values[v] = k
+	}
+}
+
+func (f Format) String() string {
+	if v, ok := Values[f]; ok {
+		return v
+	}
+	return strconv.Itoa(int(f))
+}
@@ -0,0 +1,36 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package serialization
+
+import (
+	"testing"
+)
+
+func TestFormatString(t *testing.T) {
+
+	t1 := FormatJSON
+	var t2 Format = 30
+
+	if t1.String() != "json" {
+		t.Errorf("expected %s got %s", "json", t1.String())
+	}
+
+	if t2.String() != "30" {
+		t.Errorf("expected %s got %s", "30", t2.String())
+	}
+
+}
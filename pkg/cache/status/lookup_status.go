@@ -79,6 +79,35 @@ var cacheLookupStatusValues = map[LookupStatus]string{
 	LookupStatusError:            "error",
 }
 
+// Cache Results are coarse groupings of lookup statuses, suitable for use as metrics labels
+const (
+	// CacheResultHit indicates the response was served entirely from the cache
+	CacheResultHit = "hit"
+	// CacheResultPartial indicates the response was served partially from the cache
+	CacheResultPartial = "partial"
+	// CacheResultMiss indicates the response was fetched from the origin and cached
+	CacheResultMiss = "miss"
+	// CacheResultProxy indicates the response was proxied from the origin without using the cache
+	CacheResultProxy = "proxy"
+)
+
+// CacheResult returns the Cache Result grouping for the named lookup status
+func CacheResult(name string) string {
+	s, ok := cacheLookupStatusNames[name]
+	if !ok {
+		return CacheResultProxy
+	}
+	switch s {
+	case LookupStatusHit, LookupStatusRevalidated, LookupStatusNegativeCacheHit:
+		return CacheResultHit
+	case LookupStatusPartialHit:
+		return CacheResultPartial
+	case LookupStatusKeyMiss, LookupStatusRangeMiss:
+		return CacheResultMiss
+	}
+	return CacheResultProxy
+}
+
 func (s LookupStatus) String() string {
 	if v, ok := cacheLookupStatusValues[s]; ok {
 		return v
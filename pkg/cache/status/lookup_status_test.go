@@ -37,3 +37,22 @@ func TestLookupStatusString(t *testing.T) {
 		t.Errorf("expected %s got %s", "99", t3.String())
 	}
 }
+
+func TestCacheResult(t *testing.T) {
+	tests := map[string]string{
+		"hit":        CacheResultHit,
+		"rhit":       CacheResultHit,
+		"nchit":      CacheResultHit,
+		"phit":       CacheResultPartial,
+		"kmiss":      CacheResultMiss,
+		"rmiss":      CacheResultMiss,
+		"proxy-only": CacheResultProxy,
+		"proxy-hit":  CacheResultProxy,
+		"":           CacheResultProxy,
+	}
+	for name, expected := range tests {
+		if v := CacheResult(name); v != expected {
+			t.Errorf("expected %s got %s for %s", expected, v, name)
+		}
+	}
+}
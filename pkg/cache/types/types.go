@@ -32,6 +32,8 @@ const (
 	CacheTypeBbolt
 	// CacheTypeBadgerDB indicates a BadgerDB cache
 	CacheTypeBadgerDB
+	// CacheTypeMemcached indicates a memcached cache
+	CacheTypeMemcached
 )
 
 // Names is a map of cache types keyed by name
@@ -41,6 +43,7 @@ var Names = map[string]CacheType{
 	"redis":      CacheTypeRedis,
 	"bbolt":      CacheTypeBbolt,
 	"badger":     CacheTypeBadgerDB,
+	"memcached":  CacheTypeMemcached,
 }
 
 // Values is a map of cache types keyed by internal id
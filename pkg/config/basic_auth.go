@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuthConfig is a collection of configurations for the HTTP Basic Authentication of requests
+// to a frontend's listeners
+type BasicAuthConfig struct {
+	// Users is a map of usernames to their bcrypt password hashes
+	Users map[string]string `toml:"users"`
+	// HtpasswdFile is the path of an htpasswd file of users with bcrypt password hashes. Its users
+	// are merged with Users, and take precedence over them
+	HtpasswdFile string `toml:"htpasswd_file"`
+	// Realm is the realm presented to clients in the WWW-Authenticate header; default is 'trickster'
+	Realm string `toml:"realm"`
+	// PublicPing, when true, serves the ping handler path without authentication
+	PublicPing bool `toml:"public_ping"`
+	// PublicHealth, when true, serves the health handler paths without authentication
+	PublicHealth bool `toml:"public_health"`
+	// PublicConfig, when true, serves the config handler path without authentication
+	PublicConfig bool `toml:"public_config"`
+	// PublicReload, when true, serves the config reload handler path without authentication
+	PublicReload bool `toml:"public_reload"`
+
+	// Credentials is the map of usernames to bcrypt password hashes from Users and HtpasswdFile
+	Credentials map[string][]byte `toml:"-"`
+	// PublicPaths is the list of handler paths served without authentication. Paths ending
+	// in a slash also match the paths beneath them
+	PublicPaths []string `toml:"-"`
+}
+
+// Clone returns an exact copy of a BasicAuthConfig
+func (ba *BasicAuthConfig) Clone() *BasicAuthConfig {
+	if ba == nil {
+		return nil
+	}
+	ba2 := *ba
+	if ba.Users != nil {
+		ba2.Users = make(map[string]string, len(ba.Users))
+		for k, v := range ba.Users {
+			ba2.Users[k] = v
+		}
+	}
+	if ba.Credentials != nil {
+		ba2.Credentials = make(map[string][]byte, len(ba.Credentials))
+		for k, v := range ba.Credentials {
+			ba2.Credentials[k] = v
+		}
+	}
+	if ba.PublicPaths != nil {
+		ba2.PublicPaths = make([]string, len(ba.PublicPaths))
+		copy(ba2.PublicPaths, ba.PublicPaths)
+	}
+	return &ba2
+}
+
+// redactBasicAuthUsers replaces the password hashes of the frontend's basic auth users
+func redactBasicAuthUsers(fc *FrontendConfig) {
+	if fc == nil || fc.BasicAuth == nil {
+		return
+	}
+	for k := range fc.BasicAuth.Users {
+		fc.BasicAuth.Users[k] = "*****"
+	}
+}
+
+// processBasicAuthConfig loads the credentials of a frontend's basic_auth config, and resolves
+// the management handler paths it serves without authentication
+func (c *Config) processBasicAuthConfig(section string, ba *BasicAuthConfig) error {
+	if ba == nil {
+		return nil
+	}
+	ba.Credentials = make(map[string][]byte, len(ba.Users))
+	for u, h := range ba.Users {
+		if _, err := bcrypt.Cost([]byte(h)); err != nil {
+			return fmt.Errorf("invalid bcrypt hash provided for user [%s] in %s basic_auth config", u, section)
+		}
+		ba.Credentials[u] = []byte(h)
+	}
+	if ba.HtpasswdFile != "" {
+		s, err := c.readSecretFile(ba.HtpasswdFile)
+		if err != nil {
+			return fmt.Errorf("unable to read htpasswd_file [%s] in %s basic_auth config: %v",
+				ba.HtpasswdFile, section, err)
+		}
+		if err = parseHtpasswd(s, ba.Credentials); err != nil {
+			return fmt.Errorf("invalid htpasswd_file [%s] in %s basic_auth config: %v",
+				ba.HtpasswdFile, section, err)
+		}
+	}
+	if len(ba.Credentials) == 0 {
+		return fmt.Errorf("no users provided in %s basic_auth config", section)
+	}
+	if ba.Realm == "" {
+		ba.Realm = d.DefaultBasicAuthRealm
+	}
+
+	ba.PublicPaths = nil
+	if ba.PublicPing && c.Main != nil {
+		ba.PublicPaths = append(ba.PublicPaths, c.Main.PingHandlerPath)
+	}
+	if ba.PublicHealth && c.Main != nil {
+		hp := strings.TrimSuffix(c.Main.HealthHandlerPath, "/")
+		ba.PublicPaths = append(ba.PublicPaths, hp, hp+"/")
+	}
+	if ba.PublicConfig && c.Main != nil {
+		ba.PublicPaths = append(ba.PublicPaths, c.Main.ConfigHandlerPath)
+	}
+	if ba.PublicReload && c.ReloadConfig != nil {
+		ba.PublicPaths = append(ba.PublicPaths, c.ReloadConfig.HandlerPath)
+	}
+	return nil
+}
+
+// parseHtpasswd adds the users of an htpasswd file to creds. Each line provides a username
+// and bcrypt password hash, separated by a colon. Blank lines and comments are skipped
+func parseHtpasswd(s string, creds map[string][]byte) error {
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i < 1 {
+			return fmt.Errorf("line %d is not of the form username:hash", n)
+		}
+		h := []byte(line[i+1:])
+		if _, err := bcrypt.Cost(h); err != nil {
+			return fmt.Errorf("line %d does not provide a bcrypt hash", n)
+		}
+		creds[line[:i]] = h
+	}
+	return sc.Err()
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestProcessBasicAuthConfig(t *testing.T) {
+
+	h1, _ := bcrypt.GenerateFromPassword([]byte("pass1"), bcrypt.MinCost)
+	h2, _ := bcrypt.GenerateFromPassword([]byte("pass2"), bcrypt.MinCost)
+
+	dir, err := ioutil.TempDir("", "trickster-basic-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "htpasswd")
+	if err = ioutil.WriteFile(path, []byte("# users\nuser2:"+string(h2)+"\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, toml := emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "[frontend]",
+		"[frontend]\n  [frontend.basic_auth]\n  htpasswd_file = '"+path+"'\n  public_health = true"+
+			"\n  public_reload = true\n    [frontend.basic_auth.users]\n    user1 = '"+string(h1)+"'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ba := c.Frontend.BasicAuth
+	if len(ba.Credentials) != 2 {
+		t.Errorf("expected %d got %d", 2, len(ba.Credentials))
+	}
+	if ba.Realm != d.DefaultBasicAuthRealm {
+		t.Errorf("expected %s got %s", d.DefaultBasicAuthRealm, ba.Realm)
+	}
+	expected := []string{"/trickster/health", "/trickster/health/", "/trickster/config/reload"}
+	if strings.Join(ba.PublicPaths, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v got %v", expected, ba.PublicPaths)
+	}
+
+	// the password hashes are redacted from the printed config
+	if s := c.String(); strings.Contains(s, string(h1)) {
+		t.Error("expected redacted password hash")
+	}
+	if c.Frontend.BasicAuth.Users["user1"] != string(h1) {
+		t.Error("expected unmodified password hash")
+	}
+
+	ba2 := ba.Clone()
+	ba2.Credentials["user3"] = h1
+	if _, ok := ba.Credentials["user3"]; ok {
+		t.Error("expected cloned credentials")
+	}
+
+	tests := []struct {
+		section, expected string
+	}{
+		{"[frontend.basic_auth]\n  realm = 'test'", "no users provided"},
+		{"[frontend.basic_auth.users]\n  user1 = 'plaintext'", "invalid bcrypt hash provided for user [user1]"},
+		{"[frontend.basic_auth]\n  htpasswd_file = '" + filepath.Join(dir, "missing") + "'", "unable to read htpasswd_file"},
+	}
+
+	for _, test := range tests {
+		c, _ = emptyTestConfig()
+		err = c.loadTOMLConfig(strings.Replace(toml, "[frontend]", "[frontend]\n  "+test.section, 1), &Flags{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected error %s got %v", test.expected, err)
+		}
+	}
+}
+
+func TestParseHtpasswd(t *testing.T) {
+
+	h, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+	creds := make(map[string][]byte)
+	if err := parseHtpasswd("user:"+string(h), creds); err != nil {
+		t.Fatal(err)
+	}
+	if string(creds["user"]) != string(h) {
+		t.Errorf("expected %s got %s", string(h), string(creds["user"]))
+	}
+
+	tests := []string{
+		"user",
+		":" + string(h),
+		"user:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+	}
+	for _, test := range tests {
+		if err := parseHtpasswd(test, creds); err == nil {
+			t.Errorf("expected error for %s", test)
+		}
+	}
+}
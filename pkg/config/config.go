@@ -24,26 +24,40 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	cache "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/serialization"
 	"github.com/tricksterproxy/trickster/pkg/cache/types"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
 	reload "github.com/tricksterproxy/trickster/pkg/config/reload/options"
+	ao "github.com/tricksterproxy/trickster/pkg/proxy/acl/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	origins "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	rule "github.com/tricksterproxy/trickster/pkg/proxy/origins/rule/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	"github.com/tricksterproxy/trickster/pkg/proxy/pool"
 	rewriter "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
 	rwopts "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter/options"
+	so "github.com/tricksterproxy/trickster/pkg/proxy/signing/options"
 	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/transform"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	tracing "github.com/tricksterproxy/trickster/pkg/tracing/options"
+	"github.com/tricksterproxy/trickster/pkg/util/compress/zstd"
+	"github.com/tricksterproxy/trickster/pkg/util/md5"
+	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
 
 	"github.com/BurntSushi/toml"
 )
@@ -58,6 +72,8 @@ type Config struct {
 	Caches map[string]*cache.Options `toml:"caches"`
 	// ProxyServer is provides configurations about the Proxy Front End
 	Frontend *FrontendConfig `toml:"frontend"`
+	// Frontends is a map of additional named FrontendConfigs, each with its own listeners
+	Frontends map[string]*FrontendConfig `toml:"frontends"`
 	// Logging provides configurations that affect logging behavior
 	Logging *LoggingConfig `toml:"logging"`
 	// Metrics provides configurations for collecting Metrics about the application
@@ -82,6 +98,8 @@ type Config struct {
 	providedOriginType string
 
 	LoaderWarnings []string `toml:"-"`
+	// LoadTimings holds the duration of each phase of the most recent config load
+	LoadTimings []LoadPhaseTiming `toml:"-"`
 }
 
 // MainConfig is a collection of general configuration values.
@@ -92,8 +110,22 @@ type MainConfig struct {
 	ConfigHandlerPath string `toml:"config_handler_path"`
 	// PingHandlerPath provides the path to register the Ping Handler for checking that Trickster is running
 	PingHandlerPath string `toml:"ping_handler_path"`
+	// RouteDebugHandlerPath provides the path to register the Route Debug Handler for testing
+	// which configured origin path would match a given request
+	RouteDebugHandlerPath string `toml:"route_debug_handler_path"`
 	// ReloadHandlerPath provides the path to register the Config Reload Handler
 	ReloadHandlerPath string `toml:"reload_handler_path"`
+	// CacheExportHandlerPath provides the path to register the Cache Export Handler
+	CacheExportHandlerPath string `toml:"cache_export_handler_path"`
+	// CacheImportHandlerPath provides the path to register the Cache Import Handler
+	CacheImportHandlerPath string `toml:"cache_import_handler_path"`
+	// MaintenanceHandlerPath provides the path to register the Maintenance Handler, which
+	// places origins in, or removes them from, maintenance mode at runtime
+	MaintenanceHandlerPath string `toml:"maintenance_handler_path"`
+	// AdminAuthToken is the bearer token that requests to the admin endpoints, such as the
+	// Cache Export, Cache Import and Maintenance Handlers, must provide. When empty, those
+	// endpoints are disabled
+	AdminAuthToken string `toml:"admin_auth_token"`
 	// HeatlHandlerPath provides the base Health Check Handler path
 	HealthHandlerPath string `toml:"health_handler_path"`
 	// PprofServer provides the name of the http listener that will host the pprof debugging routes
@@ -102,14 +134,33 @@ type MainConfig struct {
 	// ServerName represents the server name that is conveyed in Via headers to upstream origins
 	// defaults to os.Hostname
 	ServerName string `toml:"server_name"`
+	// MaxOrigins limits the number of origins that may be defined in the configuration.
+	// A value of 0 means unlimited
+	MaxOrigins int `toml:"max_origins"`
+	// MaxPathsPerOrigin limits the number of paths that may be defined for any single origin.
+	// A value of 0 means unlimited
+	MaxPathsPerOrigin int `toml:"max_paths_per_origin"`
+	// MaxNegativeCacheTTLSecs limits the TTL of every entry in every negative cache config.
+	// A value of 0 means unlimited
+	MaxNegativeCacheTTLSecs int `toml:"max_negative_cache_ttl_secs"`
+	// StartupDelaySecs delays the initial binding of the listeners at startup. When
+	// StartupDependencyChecks is true, it is the longest listeners are delayed while waiting
+	// for the configured caches and origins to respond
+	StartupDelaySecs int `toml:"startup_delay_secs"`
+	// StartupDependencyChecks, when true, delays the initial binding of the listeners until
+	// the configured caches and origins respond, or StartupDelaySecs elapses
+	StartupDependencyChecks bool `toml:"startup_dependency_checks"`
 
 	// ReloaderLock is used to lock the config for reloading
 	ReloaderLock sync.Mutex `toml:"-"`
 
 	configFilePath      string
+	configFormat        string
 	configLastModified  time.Time
 	configRateLimitTime time.Time
 	stalenessCheckLock  sync.Mutex
+	// secretFiles maps the secret files read by the config to their last modified dates
+	secretFiles map[string]time.Time
 }
 
 // FrontendConfig is a collection of configurations for the main http frontend for the application
@@ -124,18 +175,71 @@ type FrontendConfig struct {
 	TLSListenPort int `toml:"tls_listen_port"`
 	// ConnectionsLimit indicates how many concurrent front end connections trickster will handle at any time
 	ConnectionsLimit int `toml:"connections_limit"`
-
-	// ServeTLS indicates whether to listen and serve on the TLS port, meaning
+	// EnableH2C, when true, allows clients of the plaintext http listener to use HTTP/2 without TLS (h2c),
+	// such as gRPC clients of grpc_passthrough origins
+	EnableH2C bool `toml:"enable_h2c"`
+	// RootHandlerResponse, when set, provides a static response served for GET requests to '/'
+	// that are not otherwise handled by an origin
+	RootHandlerResponse *RootHandlerResponseConfig `toml:"root_handler_response"`
+	// TLSFullChainCertPath specifies the path of the file containing the concatenated server
+	// certification and the intermediate certification served by this frontend's tls listener.
+	// When set, it is served instead of the certificates provided by the origin configurations
+	TLSFullChainCertPath string `toml:"tls_full_chain_cert_path"`
+	// TLSPrivateKeyPath specifies the path of the private key file for TLSFullChainCertPath
+	TLSPrivateKeyPath string `toml:"tls_private_key_path"`
+	// TLSClientCACertPath specifies the path of a file containing the Certificate Authorities used to
+	// verify client certificates. When set, this frontend's tls listener requires mutual TLS
+	TLSClientCACertPath string `toml:"tls_client_ca_cert_path"`
+	// ClientCAPaths is the list of paths of files containing Certificate Authorities used to verify the
+	// client certificates presented to this frontend's tls listener, in addition to TLSClientCACertPath
+	ClientCAPaths []string `toml:"client_ca_paths"`
+	// RequireClientCert, when true, requires clients of this frontend's tls listener to present a
+	// certificate verified by ClientCAPaths. Otherwise, client certificates are verified when presented
+	RequireClientCert bool `toml:"require_client_cert"`
+	// BasicAuth, when set, requires HTTP Basic Authentication of requests to this frontend's listeners
+	BasicAuth *BasicAuthConfig `toml:"basic_auth"`
+	// JWTAuth, when set, requires a valid JWT bearer token on requests to the origins served by
+	// this frontend's listeners
+	JWTAuth *JWTAuthConfig `toml:"jwt_auth"`
+	// ACL, when set, restricts the client addresses from which this frontend's listeners accept requests
+	ACL *ao.Options `toml:"acl"`
+
+	// ServeTLS indicates whether to listen and serve on the TLS port, meaning this frontend or
 	// at least one origin configuration has a valid certificate and key file configured.
 	ServeTLS bool `toml:"-"`
 }
 
+// RootHandlerResponseConfig is a collection of configurations for the static response served at the root path
+type RootHandlerResponseConfig struct {
+	// StatusCode is the HTTP status code of the response; default is 200
+	StatusCode int `toml:"status_code"`
+	// Body is the body of the response
+	Body string `toml:"body"`
+	// ContentType is the value of the response's Content-Type header; default is text/plain
+	ContentType string `toml:"content_type"`
+}
+
+// Clone returns an exact copy of a RootHandlerResponseConfig
+func (rc *RootHandlerResponseConfig) Clone() *RootHandlerResponseConfig {
+	if rc == nil {
+		return nil
+	}
+	return &RootHandlerResponseConfig{
+		StatusCode:  rc.StatusCode,
+		Body:        rc.Body,
+		ContentType: rc.ContentType,
+	}
+}
+
 // LoggingConfig is a collection of Logging configurations
 type LoggingConfig struct {
 	// LogFile provides the filepath to the instances's logfile. Set as empty string to Log to Console
 	LogFile string `toml:"log_file"`
 	// LogLevel provides the most granular level (e.g., DEBUG, INFO, ERROR) to log
 	LogLevel string `toml:"log_level"`
+	// LogResolvedConfig, when true, logs the full resolved configuration (with secrets redacted)
+	// at INFO level once at startup and after each successful reload
+	LogResolvedConfig bool `toml:"log_resolved_config"`
 }
 
 // MetricsConfig is a collection of Metrics Collection configurations
@@ -144,6 +248,16 @@ type MetricsConfig struct {
 	ListenAddress string `toml:"listen_address"`
 	// ListenPort is TCP Port from which the Application Metrics are available for pulling at /metrics
 	ListenPort int `toml:"listen_port"`
+	// TLSFullChainCertPath is the path to the certificate served by the metrics listener. When provided,
+	// the metrics listener only serves TLS, and also serves the ping and health handlers
+	TLSFullChainCertPath string `toml:"tls_full_chain_cert_path"`
+	// TLSPrivateKeyPath is the path to the private key of TLSFullChainCertPath
+	TLSPrivateKeyPath string `toml:"tls_private_key_path"`
+	// TLSClientCACertPath is the path to a CA bundle used to require and verify client certificates
+	TLSClientCACertPath string `toml:"tls_client_ca_cert_path"`
+
+	// ServeTLS indicates whether the metrics listener serves TLS, and is populated at startup
+	ServeTLS bool `toml:"-"`
 }
 
 // Resources is a collection of values used by configs at runtime that are not part of the config itself
@@ -176,12 +290,16 @@ func NewConfig() *Config {
 			LogLevel: d.DefaultLogLevel,
 		},
 		Main: &MainConfig{
-			ConfigHandlerPath: d.DefaultConfigHandlerPath,
-			PingHandlerPath:   d.DefaultPingHandlerPath,
-			ReloadHandlerPath: d.DefaultReloadHandlerPath,
-			HealthHandlerPath: d.DefaultHealthHandlerPath,
-			PprofServer:       d.DefaultPprofServerName,
-			ServerName:        hn,
+			ConfigHandlerPath:      d.DefaultConfigHandlerPath,
+			PingHandlerPath:        d.DefaultPingHandlerPath,
+			RouteDebugHandlerPath:  d.DefaultRouteDebugHandlerPath,
+			ReloadHandlerPath:      d.DefaultReloadHandlerPath,
+			CacheExportHandlerPath: d.DefaultCacheExportHandlerPath,
+			CacheImportHandlerPath: d.DefaultCacheImportHandlerPath,
+			MaintenanceHandlerPath: d.DefaultMaintenanceHandlerPath,
+			HealthHandlerPath:      d.DefaultHealthHandlerPath,
+			PprofServer:            d.DefaultPprofServerName,
+			ServerName:             hn,
 		},
 		Metrics: &MetricsConfig{
 			ListenPort: d.DefaultMetricsListenPort,
@@ -221,11 +339,27 @@ func (c *Config) loadFile(flags *Flags) error {
 		c.setDefaults(&toml.MetaData{})
 		return err
 	}
+	if configFormat(flags.ConfigPath) == formatYAML {
+		return c.loadYAMLConfig(string(b), flags)
+	}
 	return c.loadTOMLConfig(string(b), flags)
 }
 
 // loadTOMLConfig loads application configuration from a TOML-formatted byte slice.
+// Environment variable references in string values are expanded, and any included
+// config files are merged into the document, before decoding.
 func (c *Config) loadTOMLConfig(tml string, flags *Flags) error {
+	tml, warnings := interpolateEnv(tml)
+	c.LoaderWarnings = append(c.LoaderWarnings, warnings...)
+	var path string
+	if flags != nil {
+		path = flags.ConfigPath
+	}
+	tml, err := c.resolveIncludes(tml, path)
+	if err != nil {
+		c.setDefaults(&toml.MetaData{})
+		return err
+	}
 	md, err := toml.Decode(tml, c)
 	if err != nil {
 		c.setDefaults(&toml.MetaData{})
@@ -234,6 +368,7 @@ func (c *Config) loadTOMLConfig(tml string, flags *Flags) error {
 	err = c.setDefaults(&md)
 	if err == nil {
 		c.Main.configFilePath = flags.ConfigPath
+		c.Main.configFormat = formatTOML
 		c.Main.configLastModified = c.CheckFileLastModified()
 	}
 	return err
@@ -244,50 +379,91 @@ func (c *Config) CheckFileLastModified() time.Time {
 	if c.Main == nil || c.Main.configFilePath == "" {
 		return time.Time{}
 	}
-	file, err := os.Stat(c.Main.configFilePath)
-	if err != nil {
-		return time.Time{}
-	}
-	return file.ModTime()
+	return fileLastModified(c.Main.configFilePath)
 }
 
-func (c *Config) setDefaults(metadata *toml.MetaData) error {
+func (c *Config) setDefaults(md *toml.MetaData) error {
 
-	c.Resources.metadata = metadata
+	c.Resources.metadata = md
+	metadata := newConfigMetadata(md)
 
 	var err error
+	lt := newLoadTimer()
+
+	c.processDeprecatedFields(metadata)
 
 	if err = c.processPprofConfig(); err != nil {
 		return err
 	}
 
+	if c.Main.StartupDelaySecs < 0 {
+		return fmt.Errorf("invalid startup_delay_secs [%d] provided in main config",
+			c.Main.StartupDelaySecs)
+	}
+
+	lt.reset()
 	if c.RequestRewriters != nil {
 		if c.CompiledRewriters, err = rewriter.ProcessConfigs(c.RequestRewriters); err != nil {
 			return err
 		}
+		c.processMethodTransformWarnings()
 	}
+	lt.mark("rewriters")
 
 	if err = c.processOriginConfigs(metadata); err != nil {
 		return err
 	}
+	lt.mark("origins")
+
+	if err = c.processFrontendConfig(); err != nil {
+		return err
+	}
+	lt.mark("frontend")
 
-	tracing.ProcessTracingOptions(c.TracingConfigs, metadata)
+	tracing.ProcessTracingOptions(c.TracingConfigs, md)
+	lt.mark("tracing")
 
 	if err = c.processCachingConfigs(metadata); err != nil {
 		return err
 	}
+	lt.mark("caches")
 
 	if err = c.validateConfigMappings(); err != nil {
 		return err
 	}
 
+	c.processSharedCacheNamespaces()
+
 	if err = c.validateTLSConfigs(); err != nil {
 		return err
 	}
 
+	if err = c.validateListenerPorts(); err != nil {
+		return err
+	}
+	lt.mark("validation")
+
+	c.LoadTimings = lt.finish()
+
 	return nil
 }
 
+// isHTTPToken returns true if s is a non-empty RFC 7230 token, as required of HTTP method names
+func isHTTPToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // ErrInvalidPprofServerName returns an error for invalid pprof server name
 var ErrInvalidPprofServerName = errors.New("invalid pprof server name")
 
@@ -302,7 +478,168 @@ func (c *Config) processPprofConfig() error {
 	return ErrInvalidPprofServerName
 }
 
+// processMethodTransformWarnings warns for each rewriter method transformation
+// that turns a safe HTTP method into an unsafe one
+func (c *Config) processMethodTransformWarnings() {
+	names := make([]string, 0, len(c.CompiledRewriters))
+	for k := range c.CompiledRewriters {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		for _, t := range rewriter.UnsafeMethodTransforms(c.CompiledRewriters[k]) {
+			c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+				"request rewriter [%s] transforms the method %s, which may modify or delete upstream resources",
+				k, t))
+		}
+	}
+}
+
+// processNegativeCacheTTLs clamps each negative cache entry's TTL to the configured
+// max_negative_cache_ttl_secs, and warns for each entry that is clamped
+func (c *Config) processNegativeCacheTTLs() error {
+	if c.Main == nil || c.Main.MaxNegativeCacheTTLSecs == 0 {
+		return nil
+	}
+	maxTTL := c.Main.MaxNegativeCacheTTLSecs
+	if maxTTL < 0 {
+		return fmt.Errorf("invalid max_negative_cache_ttl_secs [%d]", maxTTL)
+	}
+	names := make([]string, 0, len(c.NegativeCacheConfigs))
+	for k := range c.NegativeCacheConfigs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		nc := c.NegativeCacheConfigs[k]
+		codes := make([]string, 0, len(nc))
+		for code := range nc {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			if nc[code] > maxTTL {
+				c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+					"negative cache TTL [%d] for code [%s] in negative cache config [%s] "+
+						"is clamped to max_negative_cache_ttl_secs [%d]", nc[code], code, k, maxTTL))
+				nc[code] = maxTTL
+			}
+		}
+	}
+	return nil
+}
+
+// processSharedCacheNamespaces applies each origin's shared cache namespace as its cache key prefix,
+// and warns when origins sharing a namespace have settings that would prevent or corrupt sharing
+func (c *Config) processSharedCacheNamespaces() {
+	namespaces := make(map[string][]string)
+	for k, oc := range c.Origins {
+		if oc.SharedCacheNamespace == "" {
+			continue
+		}
+		if oc.CacheKeyPrefix != "" && oc.CacheKeyPrefix != oc.SharedCacheNamespace {
+			c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+				"cache_key_prefix [%s] is overridden by shared_cache_namespace [%s] in origin config [%s]",
+				oc.CacheKeyPrefix, oc.SharedCacheNamespace, k))
+		}
+		oc.CacheKeyPrefix = oc.SharedCacheNamespace
+		namespaces[oc.SharedCacheNamespace] = append(namespaces[oc.SharedCacheNamespace], k)
+	}
+
+	for ns, names := range namespaces {
+		sort.Strings(names)
+		first := c.Origins[names[0]]
+		for _, k := range names[1:] {
+			oc := c.Origins[k]
+			if oc.CacheName != first.CacheName {
+				c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+					"origin configs [%s] and [%s] use shared_cache_namespace [%s] with different caches, "+
+						"so cache entries will not be shared", names[0], k, ns))
+			}
+			for _, s := range cacheKeyMismatches(first, oc) {
+				c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+					"origin configs [%s] and [%s] use shared_cache_namespace [%s] with different %s, "+
+						"so shared cache entries may be served for non-equivalent requests", names[0], k, ns, s))
+			}
+		}
+	}
+}
+
+// cacheKeyMismatches returns the names of the settings affecting cache key derivation
+// that differ between the two origins
+func cacheKeyMismatches(o1, o2 *origins.Options) []string {
+	out := make([]string, 0)
+	if o1.OriginType != o2.OriginType {
+		out = append(out, "origin_type")
+	}
+	if o1.OriginURL != o2.OriginURL {
+		out = append(out, "origin_url")
+	}
+	if o1.CacheIdentityRewriterName != o2.CacheIdentityRewriterName {
+		out = append(out, "cache_identity_rewriter_name")
+	}
+	if o1.CacheKeyEncoding != o2.CacheKeyEncoding {
+		out = append(out, "cache_key_encoding")
+	}
+	if o1.IncludeHostInCacheKey != o2.IncludeHostInCacheKey {
+		out = append(out, "include_host_in_cache_key")
+	}
+	if o1.IncludeSchemeInCacheKey != o2.IncludeSchemeInCacheKey {
+		out = append(out, "include_scheme_in_cache_key")
+	}
+	if o1.CanonicalizeCacheKeyHeaders != o2.CanonicalizeCacheKeyHeaders {
+		out = append(out, "canonicalize_cache_key_headers")
+	}
+	if !ts.Equal(o1.CacheKeyHeaders, o2.CacheKeyHeaders) {
+		out = append(out, "cache_key_headers")
+	}
+	if !ts.Equal(o1.CacheKeyCookies, o2.CacheKeyCookies) {
+		out = append(out, "cache_key_cookies")
+	}
+	if !ts.Equal(o1.ClaimCacheKeyHeaders, o2.ClaimCacheKeyHeaders) {
+		out = append(out, "jwt_auth claim_headers")
+	}
+	if o1.CacheKeyFromAuthHash != o2.CacheKeyFromAuthHash || (o1.CacheKeyFromAuthHash &&
+		!strings.EqualFold(o1.CacheKeyAuthHeader, o2.CacheKeyAuthHeader)) {
+		out = append(out, "cache_key_from_auth_hash")
+	}
+	if o1.MaxCacheKeyComponents != o2.MaxCacheKeyComponents || (o1.MaxCacheKeyComponents > 0 &&
+		o1.CacheKeyComponentsPolicy != o2.CacheKeyComponentsPolicy) {
+		out = append(out, "max_cache_key_components")
+	}
+	if o1.DuplicateParamPolicy != o2.DuplicateParamPolicy {
+		out = append(out, "duplicate_param_policy")
+	}
+	// the prefix is part of the key path unless it is stripped from the cache key
+	if o1.StripPathPrefixFromCacheKey != o2.StripPathPrefixFromCacheKey ||
+		(!o1.StripPathPrefixFromCacheKey && o1.StripPathPrefix != o2.StripPathPrefix) {
+		out = append(out, "strip_path_prefix")
+	}
+
+	// only paths configured in both origins can be compared
+	keys := make([]string, 0, len(o1.Paths))
+	for k := range o1.Paths {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p1 := o1.Paths[k]
+		p2, ok := o2.Paths[k]
+		if !ok {
+			continue
+		}
+		if !ts.Equal(p1.CacheKeyParams, p2.CacheKeyParams) ||
+			!ts.Equal(p1.CacheKeyHeaders, p2.CacheKeyHeaders) ||
+			!ts.Equal(p1.CacheKeyCookies, p2.CacheKeyCookies) ||
+			!ts.Equal(p1.CacheKeyFormFields, p2.CacheKeyFormFields) {
+			out = append(out, fmt.Sprintf("cache key settings for path [%s]", k))
+		}
+	}
+	return out
+}
+
 func (c *Config) validateTLSConfigs() error {
+	var originsServeTLS bool
 	for _, oc := range c.Origins {
 		if oc.TLS != nil {
 			b, err := oc.TLS.Validate()
@@ -310,26 +647,282 @@ func (c *Config) validateTLSConfigs() error {
 				return err
 			}
 			if b {
-				c.Frontend.ServeTLS = true
+				originsServeTLS = true
 			}
 		}
 	}
+
+	if err := c.Frontend.validateTLS(d.DefaultFrontendName, originsServeTLS); err != nil {
+		return err
+	}
+	for k, fc := range c.Frontends {
+		if err := fc.validateTLS(k, originsServeTLS); err != nil {
+			return err
+		}
+	}
+
+	if c.Metrics != nil {
+		serveTLS, err := validateTLSFiles("metrics config", c.Metrics.TLSFullChainCertPath,
+			c.Metrics.TLSPrivateKeyPath, c.Metrics.TLSClientCACertPath)
+		if err != nil {
+			return err
+		}
+		c.Metrics.ServeTLS = serveTLS
+	}
 	return nil
 }
 
-var pathMembers = []string{"path", "match_type", "handler", "methods", "cache_key_params",
-	"cache_key_headers", "default_ttl_secs", "request_headers", "response_headers",
+// validateTLS ensures the frontend's own TLS files are readable and determines whether
+// the frontend will serve TLS, using either its own certificate or those of the origins
+func (fc *FrontendConfig) validateTLS(name string, originsServeTLS bool) error {
+	hasCert, err := validateTLSFiles(fmt.Sprintf("frontend config [%s]", name),
+		fc.TLSFullChainCertPath, fc.TLSPrivateKeyPath, fc.TLSClientCACertPath)
+	if err != nil {
+		return err
+	}
+	fc.ServeTLS = hasCert || fc.ServeTLS || originsServeTLS
+	if len(fc.ClientCAPaths) == 0 && !fc.RequireClientCert {
+		return nil
+	}
+	if !fc.ServeTLS {
+		return fmt.Errorf("client_ca_paths and require_client_cert require a tls listener in frontend config [%s]",
+			name)
+	}
+	if fc.RequireClientCert && len(fc.ClientCAPaths) == 0 && fc.TLSClientCACertPath == "" {
+		return fmt.Errorf("require_client_cert requires client_ca_paths in frontend config [%s]", name)
+	}
+	for _, path := range fc.ClientCAPaths {
+		if _, err := ioutil.ReadFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateTLSFiles ensures a listener's certificate, private key and optional client CA paths
+// are coherent and readable, and returns true if a certificate is configured
+func validateTLSFiles(section, certPath, keyPath, clientCAPath string) (bool, error) {
+	if certPath == "" && keyPath == "" {
+		if clientCAPath != "" {
+			return false, fmt.Errorf("tls_client_ca_cert_path requires tls_full_chain_cert_path in %s",
+				section)
+		}
+		return false, nil
+	}
+	if certPath == "" || keyPath == "" {
+		return false, fmt.Errorf("tls_full_chain_cert_path and tls_private_key_path are both required in %s",
+			section)
+	}
+	for _, path := range []string{certPath, keyPath, clientCAPath} {
+		if path == "" {
+			continue
+		}
+		if _, err := ioutil.ReadFile(path); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// validateListenerPorts ensures that no two configured listeners, across all frontends,
+// metrics and reloading, are bound to the same address and port
+func (c *Config) validateListenerPorts() error {
+
+	type binding struct {
+		name, address string
+		port          int
+	}
+
+	bindings := make([]binding, 0, 4+(len(c.Frontends)*2))
+	addFrontend := func(name string, fc *FrontendConfig) {
+		if fc == nil {
+			return
+		}
+		bindings = append(bindings, binding{"frontend " + name, fc.ListenAddress, fc.ListenPort})
+		if fc.ServeTLS {
+			bindings = append(bindings, binding{"frontend " + name + " tls", fc.TLSListenAddress, fc.TLSListenPort})
+		}
+	}
+
+	addFrontend(d.DefaultFrontendName, c.Frontend)
+	for k, fc := range c.Frontends {
+		addFrontend(k, fc)
+	}
+	if c.Metrics != nil {
+		bindings = append(bindings, binding{"metrics", c.Metrics.ListenAddress, c.Metrics.ListenPort})
+	}
+	if c.ReloadConfig != nil {
+		bindings = append(bindings, binding{"reloading", c.ReloadConfig.ListenAddress, c.ReloadConfig.ListenPort})
+	}
+
+	for i, b1 := range bindings {
+		if b1.port < 1 {
+			continue
+		}
+		for _, b2 := range bindings[i+1:] {
+			if b1.port == b2.port && (b1.address == b2.address || isWildcardAddress(b1.address) ||
+				isWildcardAddress(b2.address)) {
+				return fmt.Errorf("listen port [%d] is configured for both %s and %s", b1.port, b1.name, b2.name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateCookieNames returns an error for the first cookie name that is empty or includes
+// characters that are not permitted in a cookie name
+func validateCookieNames(names []string) error {
+	for _, n := range names {
+		if n == "" || strings.ContainsAny(n, " \t\r\n=;,\"()<>@:/[]?{}\\") {
+			return fmt.Errorf("invalid cache_key_cookies name [%s]", n)
+		}
+	}
+	return nil
+}
+
+// canonicalHeaderNames returns the list of header names in canonical form, with duplicates removed
+func canonicalHeaderNames(names []string) []string {
+	if len(names) == 0 {
+		return names
+	}
+	out := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+	for _, n := range names {
+		n = http.CanonicalHeaderKey(n)
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func isWildcardAddress(address string) bool {
+	return address == "" || address == "0.0.0.0" || address == "::"
+}
+
+var pathMembers = []string{"path", "match_type", "match_priority", "handler", "methods", "cache_key_params",
+	"cache_key_headers", "cache_key_cookies", "replace_cache_key_headers", "default_ttl_secs", "request_headers", "response_headers",
 	"response_headers", "response_code", "response_body", "no_metrics", "collapsed_forwarding",
-	"req_rewriter_name",
+	"req_rewriter_name", "cache_disabled", "response_transform", "upgradeable",
+}
+
+func (c *Config) processFrontendConfig() error {
+	if c.Frontend == nil {
+		return nil
+	}
+	if err := c.processBasicAuthConfig("frontend", c.Frontend.BasicAuth); err != nil {
+		return err
+	}
+	if err := processJWTAuthConfig("frontend", c.Frontend.JWTAuth); err != nil {
+		return err
+	}
+	if c.Frontend.ACL != nil {
+		if err := c.Frontend.ACL.Validate(); err != nil {
+			return fmt.Errorf("invalid acl config in frontend: %s", err.Error())
+		}
+	}
+	for k, fc := range c.Frontends {
+		if fc == nil {
+			continue
+		}
+		if err := c.processBasicAuthConfig("frontends."+k, fc.BasicAuth); err != nil {
+			return err
+		}
+		if err := processJWTAuthConfig("frontends."+k, fc.JWTAuth); err != nil {
+			return err
+		}
+		if fc.ACL != nil {
+			if err := fc.ACL.Validate(); err != nil {
+				return fmt.Errorf("invalid acl config in frontends.%s: %s", k, err.Error())
+			}
+		}
+	}
+	if c.Frontend.RootHandlerResponse == nil {
+		return nil
+	}
+	rc := c.Frontend.RootHandlerResponse
+	if rc.StatusCode == 0 {
+		rc.StatusCode = http.StatusOK
+	} else if rc.StatusCode < 100 || rc.StatusCode > 599 {
+		return fmt.Errorf("invalid root_handler_response status_code: %d", rc.StatusCode)
+	}
+	if rc.ContentType == "" {
+		rc.ContentType = headers.ValueTextPlain
+	}
+	return nil
 }
 
 func (c *Config) validateConfigMappings() error {
+
+	if c.Main != nil && c.Main.MaxOrigins > 0 && len(c.Origins) > c.Main.MaxOrigins {
+		return fmt.Errorf("too many origins configured: %d exceeds max_origins of %d",
+			len(c.Origins), c.Main.MaxOrigins)
+	}
+
+	if _, ok := c.Frontends[d.DefaultFrontendName]; ok {
+		return fmt.Errorf("invalid frontend name [%s]: the name is reserved for the [frontend] section",
+			d.DefaultFrontendName)
+	}
+
 	for k, oc := range c.Origins {
 
 		if err := origins.ValidateOriginName(k); err != nil {
 			return err
 		}
 
+		if oc.CacheIdentityRewriterName != "" {
+			ri, ok := c.CompiledRewriters[oc.CacheIdentityRewriterName]
+			if !ok {
+				return fmt.Errorf("invalid cache identity rewriter name [%s] provided in origin config [%s]",
+					oc.CacheIdentityRewriterName, k)
+			}
+			oc.CacheIdentityRewriter = ri
+		}
+
+		if oc.CacheValidationRewriterName != "" {
+			ri, ok := c.CompiledRewriters[oc.CacheValidationRewriterName]
+			if !ok {
+				return fmt.Errorf("invalid cache validation rewriter name [%s] provided in origin config [%s]",
+					oc.CacheValidationRewriterName, k)
+			}
+			oc.CacheValidationRewriter = ri
+		}
+
+		if oc.CoalescingKeyRewriterName != "" {
+			ri, ok := c.CompiledRewriters[oc.CoalescingKeyRewriterName]
+			if !ok {
+				return fmt.Errorf("invalid coalescing key rewriter name [%s] provided in origin config [%s]",
+					oc.CoalescingKeyRewriterName, k)
+			}
+			oc.CoalescingKeyRewriter = ri
+		}
+
+		if len(oc.FailoverOrigins) > 0 {
+			oc.FailoverOriginConfigs = make([]*origins.Options, 0, len(oc.FailoverOrigins))
+			for _, fn := range oc.FailoverOrigins {
+				fo, ok := c.Origins[fn]
+				if !ok || fn == k || fo.OriginType == "rule" {
+					return fmt.Errorf("invalid failover origin name [%s] provided in origin config [%s]", fn, k)
+				}
+				oc.FailoverOriginConfigs = append(oc.FailoverOriginConfigs, fo)
+			}
+		}
+
+		for _, fn := range oc.FrontendNames {
+			if _, ok := c.Frontends[fn]; !ok && fn != d.DefaultFrontendName {
+				return fmt.Errorf("invalid frontend name [%s] provided in origin config [%s]", fn, k)
+			}
+		}
+		// claim headers partition the origin's responses, such as by tenant, so they must be in the
+		// cache key; otherwise a response cached for one token would be served to another
+		oc.ClaimCacheKeyHeaders = c.jwtClaimHeaders(oc)
+
+		if c.Main != nil && c.Main.MaxPathsPerOrigin > 0 && len(oc.Paths) > c.Main.MaxPathsPerOrigin {
+			return fmt.Errorf("too many paths configured for origin [%s]: %d exceeds max_paths_per_origin of %d",
+				k, len(oc.Paths), c.Main.MaxPathsPerOrigin)
+		}
+
 		if oc.OriginType == "rule" {
 			// Rule Type Validations
 			r, ok := c.Rules[oc.RuleName]
@@ -338,16 +931,32 @@ func (c *Config) validateConfigMappings() error {
 			}
 			r.Name = oc.RuleName
 			oc.RuleOptions = r
-		} else // non-Rule Type Validations
-		if _, ok := c.Caches[oc.CacheName]; !ok {
-			return fmt.Errorf("invalid cache name [%s] provided in origin config [%s]", oc.CacheName, k)
+		} else { // non-Rule Type Validations
+			if _, ok := c.Caches[oc.CacheName]; !ok {
+				return fmt.Errorf("invalid cache name [%s] provided in origin config [%s]", oc.CacheName, k)
+			}
+			if oc.NegativeCacheBackendName != "" {
+				if _, ok := c.Caches[oc.NegativeCacheBackendName]; !ok {
+					return fmt.Errorf("invalid negative cache backend name [%s] provided in origin config [%s]",
+						oc.NegativeCacheBackendName, k)
+				}
+			}
+			// memory caches store objects by reference, so their bodies are never compressed
+			if oc.CacheCompression != "" && oc.CacheCompression != origins.CacheCompressionNone {
+				for _, cn := range []string{oc.CacheName, oc.NegativeCacheBackendName} {
+					if cc, ok := c.Caches[cn]; ok && cc.CacheTypeID == types.CacheTypeMemory {
+						return fmt.Errorf("invalid cache_compression [%s] provided in origin config [%s]: "+
+							"cache [%s] is a memory cache", oc.CacheCompression, k, cn)
+					}
+				}
+			}
 		}
 
 	}
 	return nil
 }
 
-func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
+func (c *Config) processOriginConfigs(metadata *configMetadata) error {
 
 	if metadata == nil {
 		return errors.New("invalid config metadata")
@@ -355,11 +964,27 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 
 	c.activeCaches = make(map[string]bool)
 
+	if err := c.processNegativeCacheTTLs(); err != nil {
+		return err
+	}
+
 	for k, v := range c.Origins {
 
 		oc := origins.NewOptions()
 		oc.Name = k
 
+		if metadata.IsDefined("origins", k, "cache_identity_rewriter_name") {
+			oc.CacheIdentityRewriterName = v.CacheIdentityRewriterName
+		}
+
+		if metadata.IsDefined("origins", k, "cache_validation_rewriter_name") {
+			oc.CacheValidationRewriterName = v.CacheValidationRewriterName
+		}
+
+		if metadata.IsDefined("origins", k, "coalescing_key_rewriter_name") {
+			oc.CoalescingKeyRewriterName = v.CoalescingKeyRewriterName
+		}
+
 		if metadata.IsDefined("origins", k, "req_rewriter_name") && v.ReqRewriterName != "" {
 			oc.ReqRewriterName = v.ReqRewriterName
 			ri, ok := c.CompiledRewriters[oc.ReqRewriterName]
@@ -371,7 +996,8 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 		}
 
 		if metadata.IsDefined("origins", k, "origin_type") {
-			oc.OriginType = v.OriginType
+			// origin types are validated when routes are registered, and are case-insensitive
+			oc.OriginType = strings.ToLower(v.OriginType)
 		}
 
 		if metadata.IsDefined("origins", k, "rule_name") {
@@ -382,6 +1008,18 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.PathRoutingDisabled = v.PathRoutingDisabled
 		}
 
+		if metadata.IsDefined("origins", k, "strip_path_prefix") {
+			// normalize to a leading slash and no trailing slash, so '/' strips nothing
+			oc.StripPathPrefix = strings.TrimRight(v.StripPathPrefix, "/")
+			if oc.StripPathPrefix != "" && !strings.HasPrefix(oc.StripPathPrefix, "/") {
+				oc.StripPathPrefix = "/" + oc.StripPathPrefix
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "strip_path_prefix_from_cache_key") {
+			oc.StripPathPrefixFromCacheKey = v.StripPathPrefixFromCacheKey
+		}
+
 		if metadata.IsDefined("origins", k, "hosts") && v != nil {
 			oc.Hosts = make([]string, len(v.Hosts))
 			copy(oc.Hosts, v.Hosts)
@@ -399,10 +1037,42 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.ForwardedHeaders = v.ForwardedHeaders
 		}
 
+		if metadata.IsDefined("origins", k, "trailing_slash_policy") {
+			tsp := strings.ToLower(v.TrailingSlashPolicy)
+			switch tsp {
+			case origins.TrailingSlashPolicyStrict, origins.TrailingSlashPolicyIgnore,
+				origins.TrailingSlashPolicyRedirect:
+				oc.TrailingSlashPolicy = tsp
+			default:
+				return fmt.Errorf("invalid trailing_slash_policy [%s] provided in origin config [%s]",
+					v.TrailingSlashPolicy, k)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "duplicate_param_policy") {
+			dpp := strings.ToLower(v.DuplicateParamPolicy)
+			switch dpp {
+			case origins.DuplicateParamPolicyAll, origins.DuplicateParamPolicyFirst,
+				origins.DuplicateParamPolicyLast:
+				oc.DuplicateParamPolicy = dpp
+			default:
+				return fmt.Errorf("invalid duplicate_param_policy [%s] provided in origin config [%s]",
+					v.DuplicateParamPolicy, k)
+			}
+		}
+
 		if metadata.IsDefined("origins", k, "require_tls") {
 			oc.RequireTLS = v.RequireTLS
 		}
 
+		if metadata.IsDefined("origins", k, "frontend_names") {
+			oc.FrontendNames = v.FrontendNames
+		}
+
+		if metadata.IsDefined("origins", k, "jwt_auth_disabled") {
+			oc.JWTAuthDisabled = v.JWTAuthDisabled
+		}
+
 		if metadata.IsDefined("origins", k, "cache_name") {
 			oc.CacheName = v.CacheName
 		}
@@ -412,22 +1082,230 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.CacheKeyPrefix = v.CacheKeyPrefix
 		}
 
-		if metadata.IsDefined("origins", k, "origin_url") {
-			oc.OriginURL = v.OriginURL
+		if metadata.IsDefined("origins", k, "shared_cache_namespace") {
+			oc.SharedCacheNamespace = v.SharedCacheNamespace
+		}
+
+		if metadata.IsDefined("origins", k, "origin_url") {
+			oc.OriginURL = v.OriginURL
+		}
+
+		if metadata.IsDefined("origins", k, "origin_urls") {
+			if metadata.IsDefined("origins", k, "origin_url") {
+				return fmt.Errorf("only one of origin_url or origin_urls may be provided in origin config [%s]", k)
+			}
+			if len(v.OriginURLs) == 0 {
+				return fmt.Errorf("empty origin_urls provided in origin config [%s]", k)
+			}
+			for _, s := range v.OriginURLs {
+				if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+					return fmt.Errorf("invalid origin_urls [%s] provided in origin config [%s]", s, k)
+				}
+			}
+			oc.OriginURLs = v.OriginURLs
+		}
+
+		if metadata.IsDefined("origins", k, "load_balancing") {
+			lb := strings.ToLower(v.LoadBalancing)
+			switch lb {
+			case pool.RoundRobin, pool.Random:
+			default:
+				return fmt.Errorf("invalid load_balancing [%s] provided in origin config [%s]",
+					v.LoadBalancing, k)
+			}
+			oc.LoadBalancing = lb
+		}
+
+		if oc.OriginType == "grpc_passthrough" {
+			// gRPC requires HTTP/2, which Trickster negotiates with an https origin over TLS,
+			// and speaks to an http origin in cleartext (h2c)
+			if u := strings.ToLower(oc.OriginURL); !strings.HasPrefix(u, "https://") &&
+				!strings.HasPrefix(u, "http://") {
+				return fmt.Errorf("grpc_passthrough origin [%s] requires an http or https origin_url", k)
+			}
+			// gRPC method paths can't be prefixed with the origin name, so routing is by Host (:authority)
+			if len(oc.Hosts) == 0 && !oc.IsDefault {
+				return fmt.Errorf("grpc_passthrough origin [%s] requires hosts or is_default", k)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "compressable_types") {
+			oc.CompressableTypeList = v.CompressableTypeList
+		}
+
+		if metadata.IsDefined("origins", k, "cache_compression") {
+			c := strings.ToLower(v.CacheCompression)
+			switch c {
+			case origins.CacheCompressionNone, origins.CacheCompressionGzip, origins.CacheCompressionZstd:
+			default:
+				return fmt.Errorf("invalid cache_compression [%s] provided in origin config [%s]",
+					v.CacheCompression, k)
+			}
+			oc.CacheCompression = c
+		}
+
+		if metadata.IsDefined("origins", k, "brotli_quality") {
+			if v.BrotliQuality < 0 || v.BrotliQuality > 11 {
+				return fmt.Errorf("invalid brotli_quality [%d] provided in origin config [%s]",
+					v.BrotliQuality, k)
+			}
+			oc.BrotliQuality = v.BrotliQuality
+		}
+
+		if metadata.IsDefined("origins", k, "cache_key_encoding") {
+			e := strings.ToLower(v.CacheKeyEncoding)
+			switch e {
+			case origins.CacheKeyEncodingHex, origins.CacheKeyEncodingBase64URL, origins.CacheKeyEncodingBase32:
+			default:
+				return fmt.Errorf("invalid cache_key_encoding [%s] provided in origin config [%s]",
+					v.CacheKeyEncoding, k)
+			}
+			oc.CacheKeyEncoding = e
+		}
+
+		if metadata.IsDefined("origins", k, "async_cache_write") {
+			oc.AsyncCacheWrite = v.AsyncCacheWrite
+		}
+
+		if metadata.IsDefined("origins", k, "max_async_cache_writes") {
+			if v.MaxAsyncCacheWrites < 1 {
+				return fmt.Errorf("invalid max_async_cache_writes [%d] provided in origin config [%s]",
+					v.MaxAsyncCacheWrites, k)
+			}
+			oc.MaxAsyncCacheWrites = v.MaxAsyncCacheWrites
+		}
+
+		if metadata.IsDefined("origins", k, "max_cache_writes_per_sec") {
+			if v.MaxCacheWritesPerSec < 0 {
+				return fmt.Errorf("invalid max_cache_writes_per_sec [%d] provided in origin config [%s]",
+					v.MaxCacheWritesPerSec, k)
+			}
+			oc.MaxCacheWritesPerSec = v.MaxCacheWritesPerSec
+		}
+
+		if metadata.IsDefined("origins", k, "timeout_secs") {
+			oc.TimeoutSecs = v.TimeoutSecs
+		}
+
+		if metadata.IsDefined("origins", k, "timeout_response_code") {
+			if http.StatusText(v.TimeoutResponseCode) == "" {
+				return fmt.Errorf("invalid timeout_response_code [%d] provided in origin config [%s]",
+					v.TimeoutResponseCode, k)
+			}
+			oc.TimeoutResponseCode = v.TimeoutResponseCode
+		}
+
+		if metadata.IsDefined("origins", k, "timeout_response_body") {
+			oc.TimeoutResponseBody = v.TimeoutResponseBody
+		}
+
+		if metadata.IsDefined("origins", k, "timeout_response_content_type") {
+			oc.TimeoutResponseContentType = v.TimeoutResponseContentType
+		}
+
+		if metadata.IsDefined("origins", k, "retry_max_attempts") {
+			if v.RetryMaxAttempts < 0 {
+				return fmt.Errorf("invalid retry_max_attempts [%d] provided in origin config [%s]",
+					v.RetryMaxAttempts, k)
+			}
+			oc.RetryMaxAttempts = v.RetryMaxAttempts
+		}
+
+		if metadata.IsDefined("origins", k, "retry_initial_backoff_ms") {
+			if v.RetryInitialBackoffMS < 0 {
+				return fmt.Errorf("invalid retry_initial_backoff_ms [%d] provided in origin config [%s]",
+					v.RetryInitialBackoffMS, k)
+			}
+			oc.RetryInitialBackoffMS = v.RetryInitialBackoffMS
+		}
+
+		if metadata.IsDefined("origins", k, "retry_max_backoff_ms") {
+			if v.RetryMaxBackoffMS < 0 {
+				return fmt.Errorf("invalid retry_max_backoff_ms [%d] provided in origin config [%s]",
+					v.RetryMaxBackoffMS, k)
+			}
+			oc.RetryMaxBackoffMS = v.RetryMaxBackoffMS
+		}
+
+		if oc.RetryMaxBackoffMS < oc.RetryInitialBackoffMS {
+			return fmt.Errorf("invalid retry_max_backoff_ms [%d] provided in origin config [%s]: "+
+				"must not be less than retry_initial_backoff_ms [%d]",
+				oc.RetryMaxBackoffMS, k, oc.RetryInitialBackoffMS)
+		}
+
+		if metadata.IsDefined("origins", k, "retry_status_codes") {
+			for _, code := range v.RetryStatusCodes {
+				if http.StatusText(code) == "" {
+					return fmt.Errorf("invalid retry_status_codes [%d] provided in origin config [%s]",
+						code, k)
+				}
+			}
+			oc.RetryStatusCodes = v.RetryStatusCodes
+		}
+
+		if metadata.IsDefined("origins", k, "failover_origins") {
+			oc.FailoverOrigins = v.FailoverOrigins
+		}
+
+		if metadata.IsDefined("origins", k, "last_resort_response") && v.LastResortResponse != nil {
+			lr := v.LastResortResponse.Clone()
+			if lr.Code == 0 {
+				lr.Code = http.StatusOK
+			} else if lr.Code < 200 || http.StatusText(lr.Code) == "" {
+				return fmt.Errorf("invalid last_resort_response code [%d] provided in origin config [%s]",
+					lr.Code, k)
+			}
+			oc.LastResortResponse = lr
+		}
+
+		if metadata.IsDefined("origins", k, "breaker_error_threshold") {
+			if v.BreakerErrorThreshold < 1 {
+				return fmt.Errorf("invalid breaker_error_threshold [%d] provided in origin config [%s]",
+					v.BreakerErrorThreshold, k)
+			}
+			oc.BreakerErrorThreshold = v.BreakerErrorThreshold
 		}
 
-		if metadata.IsDefined("origins", k, "compressable_types") {
-			oc.CompressableTypeList = v.CompressableTypeList
+		if metadata.IsDefined("origins", k, "breaker_open_duration_secs") {
+			if v.BreakerOpenDurationSecs < 1 {
+				return fmt.Errorf("invalid breaker_open_duration_secs [%d] provided in origin config [%s]",
+					v.BreakerOpenDurationSecs, k)
+			}
+			oc.BreakerOpenDurationSecs = v.BreakerOpenDurationSecs
 		}
 
-		if metadata.IsDefined("origins", k, "timeout_secs") {
-			oc.TimeoutSecs = v.TimeoutSecs
+		if metadata.IsDefined("origins", k, "breaker_half_open_requests") {
+			if v.BreakerHalfOpenRequests < 1 {
+				return fmt.Errorf("invalid breaker_half_open_requests [%d] provided in origin config [%s]",
+					v.BreakerHalfOpenRequests, k)
+			}
+			oc.BreakerHalfOpenRequests = v.BreakerHalfOpenRequests
 		}
 
 		if metadata.IsDefined("origins", k, "max_idle_conns") {
 			oc.MaxIdleConns = v.MaxIdleConns
 		}
 
+		if metadata.IsDefined("origins", k, "enable_http2_upstream") {
+			oc.EnableHTTP2Upstream = v.EnableHTTP2Upstream
+		}
+
+		if metadata.IsDefined("origins", k, "max_concurrent_tls_handshakes") {
+			if v.MaxConcurrentTLSHandshakes < 0 {
+				return fmt.Errorf("invalid max_concurrent_tls_handshakes [%d] provided in origin config [%s]",
+					v.MaxConcurrentTLSHandshakes, k)
+			}
+			oc.MaxConcurrentTLSHandshakes = v.MaxConcurrentTLSHandshakes
+		}
+
+		if metadata.IsDefined("origins", k, "max_concurrent_upstream_requests") {
+			if v.MaxConcurrentUpstreamRequests < 0 {
+				return fmt.Errorf("invalid max_concurrent_upstream_requests [%d] provided in origin config [%s]",
+					v.MaxConcurrentUpstreamRequests, k)
+			}
+			oc.MaxConcurrentUpstreamRequests = v.MaxConcurrentUpstreamRequests
+		}
+
 		if metadata.IsDefined("origins", k, "keep_alive_timeout_secs") {
 			oc.KeepAliveTimeoutSecs = v.KeepAliveTimeoutSecs
 		}
@@ -463,6 +1341,26 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.BackfillToleranceSecs = v.BackfillToleranceSecs
 		}
 
+		if metadata.IsDefined("origins", k, "default_path_methods") {
+			for _, m := range v.DefaultPathMethods {
+				if !isHTTPToken(m) {
+					return fmt.Errorf("invalid default_path_methods value [%s] provided in origin config [%s]",
+						m, k)
+				}
+			}
+			oc.DefaultPathMethods = v.DefaultPathMethods
+		}
+
+		if metadata.IsDefined("origins", k, "cacheable_methods") {
+			for _, m := range v.CacheableMethods {
+				if !isHTTPToken(m) {
+					return fmt.Errorf("invalid cacheable_methods value [%s] provided in origin config [%s]",
+						m, k)
+				}
+			}
+			oc.CacheableMethods = v.CacheableMethods
+		}
+
 		if metadata.IsDefined("origins", k, "paths") {
 			var j = 0
 			for l, p := range v.Paths {
@@ -476,7 +1374,12 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 					p.ReqRewriter = ri
 				}
 				if len(p.Methods) == 0 {
-					p.Methods = []string{http.MethodGet, http.MethodHead}
+					if len(oc.DefaultPathMethods) > 0 {
+						p.Methods = make([]string, len(oc.DefaultPathMethods))
+						copy(p.Methods, oc.DefaultPathMethods)
+					} else {
+						p.Methods = []string{http.MethodGet, http.MethodHead}
+					}
 				}
 				p.Custom = make([]string, 0)
 				for _, pm := range pathMembers {
@@ -497,6 +1400,14 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 				} else {
 					p.CollapsedForwardingType = forwarding.CFTypeBasic
 				}
+				if metadata.IsDefined("origins", k, "paths", l, "response_transform") {
+					t, ok := transform.Get(p.ResponseTransform)
+					if !ok {
+						return fmt.Errorf("invalid response_transform [%s] in path %s of origin config %s",
+							p.ResponseTransform, l, k)
+					}
+					p.ResponseTransformer = t
+				}
 				if mt, ok := matching.Names[strings.ToLower(p.MatchTypeName)]; ok {
 					p.MatchType = mt
 					p.MatchTypeName = p.MatchType.String()
@@ -504,6 +1415,19 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 					p.MatchType = matching.PathMatchTypeExact
 					p.MatchTypeName = p.MatchType.String()
 				}
+				if p.MatchPriority < 0 {
+					return fmt.Errorf("invalid match_priority [%d] in path %s of origin config %s",
+						p.MatchPriority, l, k)
+				}
+				if p.MatchType == matching.PathMatchTypeRegex {
+					// the pattern is anchored so that it must match the entire request path
+					re, err := regexp.Compile("^(?:" + p.Path + ")$")
+					if err != nil {
+						return fmt.Errorf("invalid regex path [%s] in path %s of origin config %s: %v",
+							p.Path, l, k, err)
+					}
+					p.PathRegexp = re
+				}
 				oc.Paths[p.Path+"-"+strings.Join(p.Methods, "-")] = p
 				j++
 			}
@@ -513,6 +1437,22 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.NegativeCacheName = v.NegativeCacheName
 		}
 
+		if metadata.IsDefined("origins", k, "negative_cache_backend_name") {
+			oc.NegativeCacheBackendName = v.NegativeCacheBackendName
+		}
+
+		if metadata.IsDefined("origins", k, "negative_cache_revalidate") {
+			oc.NegativeCacheRevalidate = v.NegativeCacheRevalidate
+		}
+
+		if metadata.IsDefined("origins", k, "negative_cache_revalidate_interval_ms") {
+			if v.NegativeCacheRevalidateIntervalMS < 1 {
+				return fmt.Errorf("invalid negative_cache_revalidate_interval_ms [%d] provided in origin config [%s]",
+					v.NegativeCacheRevalidateIntervalMS, k)
+			}
+			oc.NegativeCacheRevalidateIntervalMS = v.NegativeCacheRevalidateIntervalMS
+		}
+
 		if metadata.IsDefined("origins", k, "tracing_name") {
 			oc.TracingConfigName = v.TracingConfigName
 		}
@@ -533,14 +1473,61 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.HealthCheckHeaders = v.HealthCheckHeaders
 		}
 
+		if metadata.IsDefined("origins", k, "health_check_interval_secs") {
+			if v.HealthCheckIntervalSecs < 0 {
+				return fmt.Errorf("invalid health_check_interval_secs [%d] provided in origin config [%s]",
+					v.HealthCheckIntervalSecs, k)
+			}
+			oc.HealthCheckIntervalSecs = v.HealthCheckIntervalSecs
+		}
+
+		if metadata.IsDefined("origins", k, "health_check_quorum") {
+			q := pool.Quorum(strings.ToLower(string(v.HealthCheckQuorum)))
+			// the default quorum is a no-op for single-URL origins, and is present in
+			// any config serialized by String(), so it does not require origin_urls
+			if len(oc.OriginURLs) == 0 && q != pool.QuorumAny {
+				return fmt.Errorf("health_check_quorum requires origin_urls in origin config [%s]", k)
+			}
+			if len(oc.OriginURLs) > 0 {
+				if _, err := q.Size(len(oc.OriginURLs)); err != nil {
+					return fmt.Errorf("invalid health_check_quorum [%s] provided in origin config [%s]: "+
+						"must be 'all', 'any' or an integer from 1 to %d", v.HealthCheckQuorum, k, len(oc.OriginURLs))
+				}
+			}
+			oc.HealthCheckQuorum = q
+		}
+
 		if metadata.IsDefined("origins", k, "max_object_size_bytes") {
 			oc.MaxObjectSizeBytes = v.MaxObjectSizeBytes
 		}
 
+		if metadata.IsDefined("origins", k, "streaming_threshold_bytes") {
+			if v.StreamingThresholdBytes < 0 {
+				return fmt.Errorf("invalid streaming_threshold_bytes [%d] provided in origin config [%s]",
+					v.StreamingThresholdBytes, k)
+			}
+			oc.StreamingThresholdBytes = v.StreamingThresholdBytes
+		}
+
 		if metadata.IsDefined("origins", k, "revalidation_factor") {
 			oc.RevalidationFactor = v.RevalidationFactor
 		}
 
+		if metadata.IsDefined("origins", k, "ttl_as_range_fraction") {
+			if v.TTLAsRangeFraction < 0 {
+				return fmt.Errorf("invalid ttl_as_range_fraction [%f] provided in origin config [%s]", v.TTLAsRangeFraction, k)
+			}
+			oc.TTLAsRangeFraction = v.TTLAsRangeFraction
+		}
+
+		if metadata.IsDefined("origins", k, "ttl_as_range_fraction_min_secs") {
+			if v.TTLAsRangeFractionMinSecs < 0 {
+				return fmt.Errorf("invalid ttl_as_range_fraction_min_secs [%d] provided in origin config [%s]",
+					v.TTLAsRangeFractionMinSecs, k)
+			}
+			oc.TTLAsRangeFractionMinSecs = v.TTLAsRangeFractionMinSecs
+		}
+
 		if metadata.IsDefined("origins", k, "multipart_ranges_disabled") {
 			oc.MultipartRangesDisabled = v.MultipartRangesDisabled
 		}
@@ -549,6 +1536,256 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 			oc.DearticulateUpstreamRanges = v.DearticulateUpstreamRanges
 		}
 
+		if metadata.IsDefined("origins", k, "cache_byte_ranges") {
+			oc.CacheByteRanges = v.CacheByteRanges
+		}
+
+		if metadata.IsDefined("origins", k, "byte_range_reassembly_policy") {
+			brp := strings.ToLower(v.ByteRangeReassemblyPolicy)
+			switch brp {
+			case origins.ByteRangeReassemblyPolicyMerge, origins.ByteRangeReassemblyPolicyReplace:
+				oc.ByteRangeReassemblyPolicy = brp
+			default:
+				return fmt.Errorf("invalid byte_range_reassembly_policy [%s] provided in origin config [%s]",
+					v.ByteRangeReassemblyPolicy, k)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "emit_age_header") {
+			oc.EmitAgeHeader = v.EmitAgeHeader
+		}
+
+		if metadata.IsDefined("origins", k, "emit_stale_warning") {
+			oc.EmitStaleWarning = v.EmitStaleWarning
+		}
+
+		if metadata.IsDefined("origins", k, "emit_server_timing") {
+			oc.EmitServerTiming = v.EmitServerTiming
+		}
+
+		if metadata.IsDefined("origins", k, "downstream_cache_control") {
+			if strings.ContainsAny(v.DownstreamCacheControl, "\r\n") {
+				return fmt.Errorf("invalid downstream_cache_control [%s] provided in origin config [%s]",
+					v.DownstreamCacheControl, k)
+			}
+			oc.DownstreamCacheControl = v.DownstreamCacheControl
+		}
+
+		if metadata.IsDefined("origins", k, "strip_hop_by_hop_headers") {
+			oc.StripHopByHopHeaders = v.StripHopByHopHeaders
+		}
+
+		if metadata.IsDefined("origins", k, "default_upstream_content_type") {
+			if _, _, err := mime.ParseMediaType(v.DefaultUpstreamContentType); err != nil &&
+				v.DefaultUpstreamContentType != "" {
+				return fmt.Errorf("invalid default_upstream_content_type [%s] provided in origin config [%s]",
+					v.DefaultUpstreamContentType, k)
+			}
+			oc.DefaultUpstreamContentType = v.DefaultUpstreamContentType
+		}
+
+		if metadata.IsDefined("origins", k, "sniff_content_type") {
+			oc.SniffContentType = v.SniffContentType
+		}
+
+		if metadata.IsDefined("origins", k, "handle_100_continue") {
+			h := strings.ToLower(v.Handle100Continue)
+			switch h {
+			case origins.Handle100ContinueForward, origins.Handle100ContinueRespond,
+				origins.Handle100ContinueStrip:
+				oc.Handle100Continue = h
+			default:
+				return fmt.Errorf("invalid handle_100_continue [%s] provided in origin config [%s]",
+					v.Handle100Continue, k)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "max_100_continue_body_bytes") {
+			if v.Max100ContinueBodyBytes < 1 {
+				return fmt.Errorf("invalid max_100_continue_body_bytes [%d] provided in origin config [%s]",
+					v.Max100ContinueBodyBytes, k)
+			}
+			oc.Max100ContinueBodyBytes = v.Max100ContinueBodyBytes
+		}
+
+		if metadata.IsDefined("origins", k, "include_host_in_cache_key") {
+			oc.IncludeHostInCacheKey = v.IncludeHostInCacheKey
+		}
+
+		if metadata.IsDefined("origins", k, "include_scheme_in_cache_key") {
+			oc.IncludeSchemeInCacheKey = v.IncludeSchemeInCacheKey
+		}
+
+		if metadata.IsDefined("origins", k, "canonicalize_cache_key_headers") {
+			oc.CanonicalizeCacheKeyHeaders = v.CanonicalizeCacheKeyHeaders
+		}
+		if metadata.IsDefined("origins", k, "cache_key_from_auth_hash") {
+			oc.CacheKeyFromAuthHash = v.CacheKeyFromAuthHash
+		}
+
+		if metadata.IsDefined("origins", k, "cache_key_auth_header") && v.CacheKeyAuthHeader != "" {
+			oc.CacheKeyAuthHeader = http.CanonicalHeaderKey(v.CacheKeyAuthHeader)
+		}
+
+		if metadata.IsDefined("origins", k, "cache_key_headers") {
+			oc.CacheKeyHeaders = ts.Unique(v.CacheKeyHeaders)
+		}
+
+		if metadata.IsDefined("origins", k, "cache_key_cookies") {
+			oc.CacheKeyCookies = ts.Unique(v.CacheKeyCookies)
+		}
+		if err := validateCookieNames(oc.CacheKeyCookies); err != nil {
+			return fmt.Errorf("%s provided in origin config [%s]", err.Error(), k)
+		}
+		for l, p := range oc.Paths {
+			if err := validateCookieNames(p.CacheKeyCookies); err != nil {
+				return fmt.Errorf("%s provided in path %s of origin config [%s]", err.Error(), l, k)
+			}
+		}
+
+		if oc.CanonicalizeCacheKeyHeaders {
+			oc.CacheKeyHeaders = canonicalHeaderNames(oc.CacheKeyHeaders)
+			for _, p := range oc.Paths {
+				p.CacheKeyHeaders = canonicalHeaderNames(p.CacheKeyHeaders)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "max_cache_key_components") {
+			if v.MaxCacheKeyComponents < 0 {
+				return fmt.Errorf("invalid max_cache_key_components [%d] provided in origin config [%s]",
+					v.MaxCacheKeyComponents, k)
+			}
+			oc.MaxCacheKeyComponents = v.MaxCacheKeyComponents
+		}
+
+		if metadata.IsDefined("origins", k, "cache_key_components_policy") {
+			p := strings.ToLower(v.CacheKeyComponentsPolicy)
+			switch p {
+			case origins.CacheKeyComponentsPolicyReject, origins.CacheKeyComponentsPolicyTruncate:
+				oc.CacheKeyComponentsPolicy = p
+			default:
+				return fmt.Errorf("invalid cache_key_components_policy [%s] provided in origin config [%s]",
+					v.CacheKeyComponentsPolicy, k)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "max_distinct_query_shapes") {
+			if v.MaxDistinctQueryShapes < 0 {
+				return fmt.Errorf("invalid max_distinct_query_shapes [%d] provided in origin config [%s]",
+					v.MaxDistinctQueryShapes, k)
+			}
+			oc.MaxDistinctQueryShapes = v.MaxDistinctQueryShapes
+		}
+
+		if metadata.IsDefined("origins", k, "max_collapsed_waiters") {
+			if v.MaxCollapsedWaiters < 0 {
+				return fmt.Errorf("invalid max_collapsed_waiters [%d] provided in origin config [%s]",
+					v.MaxCollapsedWaiters, k)
+			}
+			oc.MaxCollapsedWaiters = v.MaxCollapsedWaiters
+		}
+
+		if metadata.IsDefined("origins", k, "collapsed_waiters_policy") {
+			p := strings.ToLower(v.CollapsedWaitersPolicy)
+			switch p {
+			case origins.CollapsedWaitersPolicyProxy, origins.CollapsedWaitersPolicyReject:
+				oc.CollapsedWaitersPolicy = p
+			default:
+				return fmt.Errorf("invalid collapsed_waiters_policy [%s] provided in origin config [%s]",
+					v.CollapsedWaitersPolicy, k)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "collapsed_forwarding_timeout_ms") {
+			if v.CollapsedForwardingTimeoutMS < 0 {
+				return fmt.Errorf("invalid collapsed_forwarding_timeout_ms [%d] provided in origin config [%s]",
+					v.CollapsedForwardingTimeoutMS, k)
+			}
+			oc.CollapsedForwardingTimeoutMS = v.CollapsedForwardingTimeoutMS
+		}
+
+		if metadata.IsDefined("origins", k, "collapsed_forwarding_timeout_policy") {
+			p := strings.ToLower(v.CollapsedForwardingTimeoutPolicy)
+			switch p {
+			case origins.CollapsedWaitersPolicyProxy, origins.CollapsedWaitersPolicyReject:
+				oc.CollapsedForwardingTimeoutPolicy = p
+			default:
+				return fmt.Errorf("invalid collapsed_forwarding_timeout_policy [%s] provided in origin config [%s]",
+					v.CollapsedForwardingTimeoutPolicy, k)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "idempotency_key_header") {
+			oc.IdempotencyKeyHeader = v.IdempotencyKeyHeader
+		}
+
+		if metadata.IsDefined("origins", k, "idempotency_window_secs") {
+			if v.IdempotencyWindowSecs <= 0 {
+				return fmt.Errorf("invalid idempotency_window_secs [%d] provided in origin config [%s]",
+					v.IdempotencyWindowSecs, k)
+			}
+			oc.IdempotencyWindowSecs = v.IdempotencyWindowSecs
+		}
+
+		if metadata.IsDefined("origins", k, "max_response_data_points") {
+			if v.MaxResponseDataPoints < 0 {
+				return fmt.Errorf("invalid max_response_data_points [%d] provided in origin config [%s]",
+					v.MaxResponseDataPoints, k)
+			}
+			oc.MaxResponseDataPoints = v.MaxResponseDataPoints
+		}
+
+		if metadata.IsDefined("origins", k, "max_response_data_points_policy") {
+			p := strings.ToLower(v.MaxResponseDataPointsPolicy)
+			if _, ok := timeseries.DownsampleMethodNames[p]; !ok && p != origins.MaxResponseDataPointsPolicyReject {
+				return fmt.Errorf("invalid max_response_data_points_policy [%s] provided in origin config [%s]",
+					v.MaxResponseDataPointsPolicy, k)
+			}
+			oc.MaxResponseDataPointsPolicy = p
+		}
+
+		if metadata.IsDefined("origins", k, "max_query_range_secs") {
+			if v.MaxQueryRangeSecs < 0 {
+				return fmt.Errorf("invalid max_query_range_secs [%d] provided in origin config [%s]",
+					v.MaxQueryRangeSecs, k)
+			}
+			oc.MaxQueryRangeSecs = v.MaxQueryRangeSecs
+		}
+
+		if metadata.IsDefined("origins", k, "range_guard_response_body") {
+			oc.RangeGuardResponseBody = v.RangeGuardResponseBody
+		}
+
+		if metadata.IsDefined("origins", k, "range_guard_response_format") {
+			f := strings.ToLower(v.RangeGuardResponseFormat)
+			switch f {
+			case origins.RangeGuardResponseFormatText, origins.RangeGuardResponseFormatJSON:
+			default:
+				return fmt.Errorf("invalid range_guard_response_format [%s] provided in origin config [%s]",
+					v.RangeGuardResponseFormat, k)
+			}
+			oc.RangeGuardResponseFormat = f
+		}
+
+		if metadata.IsDefined("origins", k, "conditional_request_policy") {
+			p := strings.ToLower(v.ConditionalRequestPolicy)
+			switch p {
+			case origins.ConditionalRequestPolicyForward, origins.ConditionalRequestPolicyStripOnMiss:
+				oc.ConditionalRequestPolicy = p
+			default:
+				return fmt.Errorf("invalid conditional_request_policy [%s] provided in origin config [%s]",
+					v.ConditionalRequestPolicy, k)
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "maintenance_mode") {
+			oc.MaintenanceMode = v.MaintenanceMode
+		}
+
+		if metadata.IsDefined("origins", k, "honor_client_max_age") {
+			oc.HonorClientMaxAge = v.HonorClientMaxAge
+		}
+
 		if metadata.IsDefined("origins", k, "tls") {
 			oc.TLS = &to.Options{
 				InsecureSkipVerify:        v.TLS.InsecureSkipVerify,
@@ -558,6 +1795,61 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 				ClientCertPath:            v.TLS.ClientCertPath,
 				ClientKeyPath:             v.TLS.ClientKeyPath,
 			}
+			if metadata.IsDefined("origins", k, "tls", "server_name") {
+				if v.TLS.ServerName != "" && !to.ValidServerName(v.TLS.ServerName) {
+					return fmt.Errorf("invalid tls server_name [%s] provided in origin config [%s]",
+						v.TLS.ServerName, k)
+				}
+				oc.TLS.ServerName = v.TLS.ServerName
+			}
+			if metadata.IsDefined("origins", k, "tls", "password_file") && v.TLS.PasswordFile != "" {
+				p, err := c.readSecretFile(v.TLS.PasswordFile)
+				if err != nil {
+					return fmt.Errorf("invalid tls password_file provided in origin config [%s]: %s", k, err.Error())
+				}
+				oc.TLS.PasswordFile = v.TLS.PasswordFile
+				oc.TLS.Password = p
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "request_signing") && v.RequestSigning != nil {
+			rs := so.NewOptions()
+			if metadata.IsDefined("origins", k, "request_signing", "algorithm") {
+				rs.Algorithm = v.RequestSigning.Algorithm
+			}
+			if metadata.IsDefined("origins", k, "request_signing", "header_name") {
+				rs.HeaderName = v.RequestSigning.HeaderName
+			}
+			if metadata.IsDefined("origins", k, "request_signing", "timestamp_header_name") {
+				rs.TimestampHeaderName = v.RequestSigning.TimestampHeaderName
+			}
+			if metadata.IsDefined("origins", k, "request_signing", "signed_elements") {
+				rs.SignedElements = v.RequestSigning.SignedElements
+			}
+			rs.Secret = v.RequestSigning.Secret
+			rs.SecretFile = v.RequestSigning.SecretFile
+			if err := rs.Validate(); err != nil {
+				return fmt.Errorf("invalid request_signing config in origin [%s]: %s", k, err.Error())
+			}
+			oc.RequestSigning = rs
+		}
+
+		if metadata.IsDefined("origins", k, "acl") && v.ACL != nil {
+			oc.ACL = v.ACL.Clone()
+			if err := oc.ACL.Validate(); err != nil {
+				return fmt.Errorf("invalid acl config in origin [%s]: %s", k, err.Error())
+			}
+		}
+
+		if metadata.IsDefined("origins", k, "client_max_age_acl") && v.ClientMaxAgeACL != nil {
+			oc.ClientMaxAgeACL = v.ClientMaxAgeACL.Clone()
+			if err := oc.ClientMaxAgeACL.Validate(); err != nil {
+				return fmt.Errorf("invalid client_max_age_acl config in origin [%s]: %s", k, err.Error())
+			}
+		}
+
+		if oc.HonorClientMaxAge && (oc.ClientMaxAgeACL == nil || len(oc.ClientMaxAgeACL.AllowNetworks) == 0) {
+			return fmt.Errorf("honor_client_max_age in origin [%s] requires a client_max_age_acl with an allow list", k)
 		}
 
 		c.Origins[k] = oc
@@ -565,7 +1857,7 @@ func (c *Config) processOriginConfigs(metadata *toml.MetaData) error {
 	return nil
 }
 
-func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
+func (c *Config) processCachingConfigs(metadata *configMetadata) error {
 
 	// setCachingDefaults assumes that processOriginConfigs was just ran
 
@@ -587,6 +1879,52 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 			}
 		}
 
+		if metadata.IsDefined("caches", k, "serialization_format") {
+			sf := strings.ToLower(v.SerializationFormat)
+			n, ok := serialization.Names[sf]
+			if !ok {
+				return fmt.Errorf("invalid serialization_format [%s] provided in cache config [%s]",
+					v.SerializationFormat, k)
+			}
+			cc.SerializationFormat = sf
+			cc.SerializationFormatID = n
+		}
+
+		if metadata.IsDefined("caches", k, "compression_min_size_bytes") {
+			if v.CompressionMinSizeBytes < 0 {
+				return fmt.Errorf("invalid compression_min_size_bytes [%d] provided in cache config [%s]",
+					v.CompressionMinSizeBytes, k)
+			}
+			cc.CompressionMinSizeBytes = v.CompressionMinSizeBytes
+		}
+
+		if metadata.IsDefined("caches", k, "max_key_length_bytes") {
+			// keys are hashed down to a digest, so the limit can be no shorter than one
+			if v.MaxKeyLengthBytes != 0 && v.MaxKeyLengthBytes < md5.ChecksumLength {
+				return fmt.Errorf("invalid max_key_length_bytes [%d] provided in cache config [%s]: must be 0 or at least %d",
+					v.MaxKeyLengthBytes, k, md5.ChecksumLength)
+			}
+			cc.MaxKeyLengthBytes = v.MaxKeyLengthBytes
+		}
+
+		if metadata.IsDefined("caches", k, "compression_dictionary_path") {
+			cc.CompressionDictionaryPath = v.CompressionDictionaryPath
+			// an unusable dictionary is not fatal, since objects can still be compressed without it
+			if b, err := ioutil.ReadFile(cc.CompressionDictionaryPath); err != nil {
+				c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+					"could not read compression_dictionary_path for cache config [%s], using compression without a dictionary: %s",
+					k, err.Error()))
+			} else if cc.CompressionDictionary, err = zstd.NewDictionary(b); err != nil {
+				c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+					"invalid compression dictionary for cache config [%s], using compression without a dictionary: %s",
+					k, err.Error()))
+			}
+		}
+
+		if metadata.IsDefined("caches", k, "verify_checksums") {
+			cc.VerifyChecksums = v.VerifyChecksums
+		}
+
 		if metadata.IsDefined("caches", k, "index", "reap_interval_secs") {
 			cc.Index.ReapIntervalSecs = v.Index.ReapIntervalSecs
 		}
@@ -619,6 +1957,32 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 			return errors.New("MaxSizeBackoffObjects can't be larger than MaxSizeObjects")
 		}
 
+		if metadata.IsDefined("caches", k, "index", "reap_max_concurrent_io") {
+			if v.Index.ReapMaxConcurrentIO < 0 {
+				return fmt.Errorf("invalid reap_max_concurrent_io [%d] provided in cache config [%s]",
+					v.Index.ReapMaxConcurrentIO, k)
+			}
+			cc.Index.ReapMaxConcurrentIO = v.Index.ReapMaxConcurrentIO
+		}
+
+		if metadata.IsDefined("caches", k, "index", "reap_io_pause_ms") {
+			if v.Index.ReapIOPauseMS < 0 {
+				return fmt.Errorf("invalid reap_io_pause_ms [%d] provided in cache config [%s]",
+					v.Index.ReapIOPauseMS, k)
+			}
+			cc.Index.ReapIOPauseMS = v.Index.ReapIOPauseMS
+		}
+
+		if metadata.IsDefined("caches", k, "index", "eviction_policy") {
+			cc.Index.EvictionPolicyName = strings.ToLower(v.Index.EvictionPolicyName)
+			p, ok := evictionmethods.IndexEvictionPolicyNames[cc.Index.EvictionPolicyName]
+			if !ok {
+				return fmt.Errorf("invalid eviction_policy [%s] provided in cache config [%s]",
+					v.Index.EvictionPolicyName, k)
+			}
+			cc.Index.EvictionPolicy = p
+		}
+
 		if cc.CacheTypeID == types.CacheTypeRedis {
 
 			var hasEndpoint, hasEndpoints bool
@@ -663,6 +2027,18 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 				cc.Redis.Password = v.Redis.Password
 			}
 
+			if metadata.IsDefined("caches", k, "redis", "password_file") && v.Redis.PasswordFile != "" {
+				if v.Redis.Password != "" {
+					return fmt.Errorf("redis password and password_file cannot both be provided in cache config [%s]", k)
+				}
+				cc.Redis.PasswordFile = v.Redis.PasswordFile
+				p, err := c.readSecretFile(v.Redis.PasswordFile)
+				if err != nil {
+					return fmt.Errorf("invalid redis password_file provided in cache config [%s]: %s", k, err.Error())
+				}
+				cc.Redis.Password = p
+			}
+
 			if metadata.IsDefined("caches", k, "redis", "db") {
 				cc.Redis.DB = v.Redis.DB
 			}
@@ -714,12 +2090,62 @@ func (c *Config) processCachingConfigs(metadata *toml.MetaData) error {
 			if metadata.IsDefined("caches", k, "redis", "idle_check_frequency_ms") {
 				cc.Redis.IdleCheckFrequencyMS = v.Redis.IdleCheckFrequencyMS
 			}
+
+			if metadata.IsDefined("caches", k, "redis", "drain_timeout_ms") {
+				if v.Redis.DrainTimeoutMS < 0 {
+					return fmt.Errorf("invalid redis drain_timeout_ms [%d] provided in cache config [%s]",
+						v.Redis.DrainTimeoutMS, k)
+				}
+				cc.Redis.DrainTimeoutMS = v.Redis.DrainTimeoutMS
+			}
+		}
+
+		if cc.CacheTypeID == types.CacheTypeMemcached {
+
+			if metadata.IsDefined("caches", k, "memcached", "servers") {
+				if len(v.Memcached.Servers) == 0 {
+					return fmt.Errorf("no memcached servers provided in cache config [%s]", k)
+				}
+				cc.Memcached.Servers = v.Memcached.Servers
+			}
+
+			if metadata.IsDefined("caches", k, "memcached", "max_idle_conns") {
+				if v.Memcached.MaxIdleConns < 0 {
+					return fmt.Errorf("invalid memcached max_idle_conns [%d] provided in cache config [%s]",
+						v.Memcached.MaxIdleConns, k)
+				}
+				cc.Memcached.MaxIdleConns = v.Memcached.MaxIdleConns
+			}
+
+			if metadata.IsDefined("caches", k, "memcached", "timeout_ms") {
+				if v.Memcached.TimeoutMS < 0 {
+					return fmt.Errorf("invalid memcached timeout_ms [%d] provided in cache config [%s]",
+						v.Memcached.TimeoutMS, k)
+				}
+				cc.Memcached.TimeoutMS = v.Memcached.TimeoutMS
+			}
+
+			if metadata.IsDefined("caches", k, "memcached", "dial_timeout_ms") {
+				if v.Memcached.DialTimeoutMS < 0 {
+					return fmt.Errorf("invalid memcached dial_timeout_ms [%d] provided in cache config [%s]",
+						v.Memcached.DialTimeoutMS, k)
+				}
+				cc.Memcached.DialTimeoutMS = v.Memcached.DialTimeoutMS
+			}
 		}
 
 		if metadata.IsDefined("caches", k, "filesystem", "cache_path") {
 			cc.Filesystem.CachePath = v.Filesystem.CachePath
 		}
 
+		if metadata.IsDefined("caches", k, "filesystem", "max_open_files") {
+			if v.Filesystem.MaxOpenFiles < 1 {
+				return fmt.Errorf("invalid filesystem max_open_files [%d] provided in cache config [%s]",
+					v.Filesystem.MaxOpenFiles, k)
+			}
+			cc.Filesystem.MaxOpenFiles = v.Filesystem.MaxOpenFiles
+		}
+
 		if metadata.IsDefined("caches", k, "bbolt", "filename") {
 			cc.BBolt.Filename = v.BBolt.Filename
 		}
@@ -751,27 +2177,65 @@ func (c *Config) Clone() *Config {
 	nc.Main.ConfigHandlerPath = c.Main.ConfigHandlerPath
 	nc.Main.InstanceID = c.Main.InstanceID
 	nc.Main.PingHandlerPath = c.Main.PingHandlerPath
+	nc.Main.RouteDebugHandlerPath = c.Main.RouteDebugHandlerPath
 	nc.Main.ReloadHandlerPath = c.Main.ReloadHandlerPath
+	nc.Main.CacheExportHandlerPath = c.Main.CacheExportHandlerPath
+	nc.Main.CacheImportHandlerPath = c.Main.CacheImportHandlerPath
+	nc.Main.MaintenanceHandlerPath = c.Main.MaintenanceHandlerPath
+	nc.Main.AdminAuthToken = c.Main.AdminAuthToken
 	nc.Main.HealthHandlerPath = c.Main.HealthHandlerPath
 	nc.Main.PprofServer = c.Main.PprofServer
 	nc.Main.ServerName = c.Main.ServerName
+	nc.Main.MaxOrigins = c.Main.MaxOrigins
+	nc.Main.MaxPathsPerOrigin = c.Main.MaxPathsPerOrigin
+	nc.Main.MaxNegativeCacheTTLSecs = c.Main.MaxNegativeCacheTTLSecs
+	nc.Main.StartupDelaySecs = c.Main.StartupDelaySecs
+	nc.Main.StartupDependencyChecks = c.Main.StartupDependencyChecks
 
 	nc.Main.configFilePath = c.Main.configFilePath
+	nc.Main.configFormat = c.Main.configFormat
 	nc.Main.configLastModified = c.Main.configLastModified
 	nc.Main.configRateLimitTime = c.Main.configRateLimitTime
+	if c.Main.secretFiles != nil {
+		nc.Main.secretFiles = make(map[string]time.Time, len(c.Main.secretFiles))
+		for k, v := range c.Main.secretFiles {
+			nc.Main.secretFiles[k] = v
+		}
+	}
 
 	nc.Logging.LogFile = c.Logging.LogFile
 	nc.Logging.LogLevel = c.Logging.LogLevel
+	nc.Logging.LogResolvedConfig = c.Logging.LogResolvedConfig
 
 	nc.Metrics.ListenAddress = c.Metrics.ListenAddress
 	nc.Metrics.ListenPort = c.Metrics.ListenPort
+	nc.Metrics.TLSFullChainCertPath = c.Metrics.TLSFullChainCertPath
+	nc.Metrics.TLSPrivateKeyPath = c.Metrics.TLSPrivateKeyPath
+	nc.Metrics.TLSClientCACertPath = c.Metrics.TLSClientCACertPath
+	nc.Metrics.ServeTLS = c.Metrics.ServeTLS
 
 	nc.Frontend.ListenAddress = c.Frontend.ListenAddress
 	nc.Frontend.ListenPort = c.Frontend.ListenPort
 	nc.Frontend.TLSListenAddress = c.Frontend.TLSListenAddress
 	nc.Frontend.TLSListenPort = c.Frontend.TLSListenPort
 	nc.Frontend.ConnectionsLimit = c.Frontend.ConnectionsLimit
+	nc.Frontend.TLSFullChainCertPath = c.Frontend.TLSFullChainCertPath
+	nc.Frontend.TLSPrivateKeyPath = c.Frontend.TLSPrivateKeyPath
+	nc.Frontend.TLSClientCACertPath = c.Frontend.TLSClientCACertPath
+	nc.Frontend.ClientCAPaths = ts.CloneList(c.Frontend.ClientCAPaths)
+	nc.Frontend.RequireClientCert = c.Frontend.RequireClientCert
 	nc.Frontend.ServeTLS = c.Frontend.ServeTLS
+	nc.Frontend.RootHandlerResponse = c.Frontend.RootHandlerResponse.Clone()
+	nc.Frontend.BasicAuth = c.Frontend.BasicAuth.Clone()
+	nc.Frontend.JWTAuth = c.Frontend.JWTAuth.Clone()
+	nc.Frontend.ACL = c.Frontend.ACL.Clone()
+
+	if c.Frontends != nil {
+		nc.Frontends = make(map[string]*FrontendConfig, len(c.Frontends))
+		for k, v := range c.Frontends {
+			nc.Frontends[k] = v.Clone()
+		}
+	}
 
 	nc.Resources = &Resources{
 		QuitChan: make(chan bool, 1),
@@ -807,6 +2271,11 @@ func (c *Config) Clone() *Config {
 		}
 	}
 
+	if c.LoadTimings != nil {
+		nc.LoadTimings = make([]LoadPhaseTiming, len(c.LoadTimings))
+		copy(nc.LoadTimings, c.LoadTimings)
+	}
+
 	return nc
 }
 
@@ -827,14 +2296,56 @@ func (c *Config) IsStale() bool {
 
 	c.Main.configRateLimitTime =
 		time.Now().Add(time.Second * time.Duration(c.ReloadConfig.RateLimitSecs))
+
+	// secret files are typically rotated by atomic replacement, so are not debounced
+	if c.secretFilesModified() {
+		return true
+	}
+
 	t := c.CheckFileLastModified()
-	if t.IsZero() {
+	if t.IsZero() || t == c.Main.configLastModified {
+		return false
+	}
+
+	// the file may still be being written by a non-atomic editor, so it is
+	// only considered stale once its modification time has settled
+	if c.ReloadConfig.DebounceMS > 0 {
+		d := time.Millisecond * time.Duration(c.ReloadConfig.DebounceMS)
+		for i := 0; i < maxDebounceChecks; i++ {
+			time.Sleep(d)
+			t2 := c.CheckFileLastModified()
+			if t2 == t {
+				return !t.IsZero()
+			}
+			t = t2
+		}
 		return false
 	}
-	return t != c.Main.configLastModified
+	return true
 }
 
+// maxDebounceChecks limits how many times IsStale waits for a modified config file to settle
+const maxDebounceChecks = 10
+
 func (c *Config) String() string {
+	cp := c.redacted()
+
+	var buf bytes.Buffer
+	e := toml.NewEncoder(&buf)
+	e.Encode(cp)
+
+	// a config loaded from YAML is printed as YAML
+	if c.Main != nil && c.Main.configFormat == formatYAML {
+		if s, err := tomlToYAML(buf.String()); err == nil {
+			return s
+		}
+	}
+	return buf.String()
+}
+
+// redacted returns a copy of the config that is safe to serialize, with sensitive
+// values such as credentials masked
+func (c *Config) redacted() *Config {
 	cp := c.Clone()
 
 	// the toml library will panic if the Handler is assigned,
@@ -851,6 +2362,11 @@ func (c *Config) String() string {
 			// also strip out potentially sensitive headers
 			hideAuthorizationCredentials(v.HealthCheckHeaders)
 
+			// and the request signing secret
+			if v.RequestSigning != nil && v.RequestSigning.Secret != "" {
+				v.RequestSigning.Secret = "*****"
+			}
+
 			if v.Paths != nil {
 				for _, p := range v.Paths {
 					hideAuthorizationCredentials(p.RequestHeaders)
@@ -860,17 +2376,29 @@ func (c *Config) String() string {
 		}
 	}
 
-	// strip Redis password
+	if cp.Main.AdminAuthToken != "" {
+		cp.Main.AdminAuthToken = "*****"
+	}
+
+	// and the password hashes of the frontends' basic auth users
+	redactBasicAuthUsers(cp.Frontend)
+	for _, fc := range cp.Frontends {
+		redactBasicAuthUsers(fc)
+	}
+
+	// strip Redis password, which is omitted entirely when it was read from a password_file
 	for k, v := range cp.Caches {
-		if v != nil && cp.Caches[k].Redis.Password != "" {
+		if v == nil {
+			continue
+		}
+		if cp.Caches[k].Redis.PasswordFile != "" {
+			cp.Caches[k].Redis.Password = ""
+		} else if cp.Caches[k].Redis.Password != "" {
 			cp.Caches[k].Redis.Password = "*****"
 		}
 	}
 
-	var buf bytes.Buffer
-	e := toml.NewEncoder(&buf)
-	e.Encode(cp)
-	return buf.String()
+	return cp
 }
 
 // ConfigFilePath returns the file path from which this configuration is based
@@ -881,9 +2409,28 @@ func (c *Config) ConfigFilePath() string {
 	return ""
 }
 
+// Clone returns an exact copy of a FrontendConfig
+func (fc *FrontendConfig) Clone() *FrontendConfig {
+	fc2 := *fc
+	fc2.RootHandlerResponse = fc.RootHandlerResponse.Clone()
+	fc2.BasicAuth = fc.BasicAuth.Clone()
+	fc2.JWTAuth = fc.JWTAuth.Clone()
+	fc2.ACL = fc.ACL.Clone()
+	fc2.ClientCAPaths = ts.CloneList(fc.ClientCAPaths)
+	return &fc2
+}
+
 // Equal returns true if the FrontendConfigs are identical in value.
 func (fc *FrontendConfig) Equal(fc2 *FrontendConfig) bool {
-	return *fc == *fc2
+	// the root handler response, basic auth and jwt auth are applied by the router rather
+	// than the listener, so they are excluded from the comparison
+	f1, f2 := *fc, *fc2
+	f1.RootHandlerResponse, f2.RootHandlerResponse = nil, nil
+	f1.BasicAuth, f2.BasicAuth = nil, nil
+	f1.JWTAuth, f2.JWTAuth = nil, nil
+	f1.ACL, f2.ACL = nil, nil
+	f1.ClientCAPaths, f2.ClientCAPaths = nil, nil
+	return reflect.DeepEqual(f1, f2) && ts.Equal(fc.ClientCAPaths, fc2.ClientCAPaths)
 }
 
 var sensitiveCredentials = map[string]bool{headers.NameAuthorization: true}
@@ -24,13 +24,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	rule "github.com/tricksterproxy/trickster/pkg/proxy/origins/rule/options"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	rwo "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter/options"
+	so "github.com/tricksterproxy/trickster/pkg/proxy/signing/options"
 	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
 )
 
 const emptyFilePath = "../../testdata/test.empty.conf"
@@ -91,6 +94,39 @@ func TestString(t *testing.T) {
 	}
 }
 
+func TestStringRedactsAdminAuthToken(t *testing.T) {
+	c1 := NewConfig()
+	c1.Main.AdminAuthToken = "plaintext-token"
+	s := c1.String()
+	if strings.Contains(s, "plaintext-token") || !strings.Contains(s, `admin_auth_token = "*****"`) {
+		t.Errorf("missing admin auth token mask: %s", "*****")
+	}
+	if c1.Main.AdminAuthToken != "plaintext-token" {
+		t.Errorf("expected %s got %s", "plaintext-token", c1.Main.AdminAuthToken)
+	}
+}
+
+func TestStringRedactsSigningSecret(t *testing.T) {
+	c1 := NewConfig()
+	c1.Origins["default"].RequestSigning = &so.Options{Secret: "plaintext-secret"}
+	s := c1.String()
+	if strings.Contains(s, "plaintext-secret") {
+		t.Error("expected request signing secret to be redacted")
+	}
+}
+
+func TestCloneLogResolvedConfig(t *testing.T) {
+	c1 := NewConfig()
+	if c1.Logging.LogResolvedConfig {
+		t.Error("expected log_resolved_config to default to false")
+	}
+	c1.Logging.LogResolvedConfig = true
+	c2 := c1.Clone()
+	if !c2.Logging.LogResolvedConfig {
+		t.Error("expected log_resolved_config to be cloned")
+	}
+}
+
 func TestHideAuthorizationCredentials(t *testing.T) {
 	hdrs := map[string]string{headers.NameAuthorization: "Basic SomeHash"}
 	hideAuthorizationCredentials(hdrs)
@@ -131,6 +167,24 @@ func TestCheckFileLastModified(t *testing.T) {
 	}
 }
 
+func TestStartupDelayConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig("[main]\nstartup_delay_secs = 30\nstartup_dependency_checks = true\n"+toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Main.StartupDelaySecs != 30 || !c.Main.StartupDependencyChecks {
+		t.Errorf("expected %d %t got %d %t", 30, true, c.Main.StartupDelaySecs, c.Main.StartupDependencyChecks)
+	}
+
+	c, toml = emptyTestConfig()
+	err = c.loadTOMLConfig("[main]\nstartup_delay_secs = -1\n"+toml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid startup_delay_secs") {
+		t.Error("expected error for invalid startup_delay_secs")
+	}
+}
+
 func TestProcessPprofConfig(t *testing.T) {
 
 	c := NewConfig()
@@ -172,6 +226,38 @@ func TestSetDefaults(t *testing.T) {
 	}
 }
 
+func TestLoadTimings(t *testing.T) {
+
+	if NewConfig().LoadTimingsString() != "" {
+		t.Error("expected empty load timings string")
+	}
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"rewriters", "origins", "frontend", "tracing", "caches", "validation", "total"}
+	if len(c.LoadTimings) != len(expected) {
+		t.Fatalf("expected %d load timings got %d", len(expected), len(c.LoadTimings))
+	}
+	for i, v := range expected {
+		if c.LoadTimings[i].Phase != v {
+			t.Errorf("expected phase %s got %s", v, c.LoadTimings[i].Phase)
+		}
+	}
+
+	c2 := c.Clone()
+	if len(c2.LoadTimings) != len(expected) {
+		t.Errorf("expected %d cloned load timings got %d", len(expected), len(c2.LoadTimings))
+	}
+
+	if !strings.Contains(c.LoadTimingsString(), "#   total: ") {
+		t.Errorf("missing total in load timings string:\n%s", c.LoadTimingsString())
+	}
+}
+
 func TestValidateConfigMappings(t *testing.T) {
 
 	c, toml := emptyTestConfig()
@@ -206,6 +292,260 @@ func TestValidateConfigMappings(t *testing.T) {
 
 }
 
+func TestValidateConfigMappingsNegativeCacheBackend(t *testing.T) {
+
+	c, _ := emptyTestConfig()
+	c.Origins["test"].NegativeCacheBackendName = "invalid"
+	err := c.validateConfigMappings()
+	if err == nil || !strings.Contains(err.Error(), "invalid negative cache backend name") {
+		t.Error("expected error for invalid negative cache backend name")
+	}
+
+	c.Origins["test"].NegativeCacheBackendName = c.Origins["test"].CacheName
+	err = c.validateConfigMappings()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateConfigMappingsFailoverOrigins(t *testing.T) {
+
+	c, _ := emptyTestConfig()
+	oc := c.Origins["test"]
+	fo := oc.Clone()
+	fo.Name = "standby"
+	c.Origins["standby"] = fo
+
+	oc.FailoverOrigins = []string{"standby"}
+	err := c.validateConfigMappings()
+	if err != nil {
+		t.Error(err)
+	}
+	if len(oc.FailoverOriginConfigs) != 1 || oc.FailoverOriginConfigs[0] != fo {
+		t.Error("expected failover origin config reference")
+	}
+
+	for _, name := range []string{"invalid", "test"} {
+		oc.FailoverOrigins = []string{name}
+		err = c.validateConfigMappings()
+		if err == nil || !strings.Contains(err.Error(), "invalid failover origin name") {
+			t.Errorf("expected error for invalid failover origin name %s", name)
+		}
+	}
+}
+
+func TestProcessFrontendConfig(t *testing.T) {
+
+	c := NewConfig()
+	err := c.processFrontendConfig()
+	if err != nil {
+		t.Error(err)
+	}
+
+	c.Frontend.RootHandlerResponse = &RootHandlerResponseConfig{}
+	err = c.processFrontendConfig()
+	if err != nil {
+		t.Error(err)
+	}
+
+	rc := c.Frontend.RootHandlerResponse
+	if rc.StatusCode != 200 {
+		t.Errorf("expected %d got %d", 200, rc.StatusCode)
+	}
+
+	if rc.ContentType != headers.ValueTextPlain {
+		t.Errorf("expected %s got %s", headers.ValueTextPlain, rc.ContentType)
+	}
+
+	rc.StatusCode = 1000
+	err = c.processFrontendConfig()
+	if err == nil {
+		t.Error("expected error for invalid status code")
+	}
+
+}
+
+func TestProcessFrontendsConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[caches]",
+		"[frontends]\n    [frontends.internal]\n    listen_port = 9480\n\n[caches]", 1)
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    frontend_names = [ 'internal' ]", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fc, ok := c.Frontends["internal"]; !ok || fc.ListenPort != 9480 {
+		t.Error("expected internal frontend")
+	}
+
+	c2 := c.Clone()
+	if fc, ok := c2.Frontends["internal"]; !ok || !fc.Equal(c.Frontends["internal"]) ||
+		fc == c.Frontends["internal"] {
+		t.Error("expected cloned internal frontend")
+	}
+	if len(c2.Origins["test"].FrontendNames) != 1 {
+		t.Error("expected cloned frontend names")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "listen_port = 9480",
+		"listen_port = 8481", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "listen port [8481] is configured for both") {
+		t.Error("expected error for colliding listen ports")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "listen_port = 9480",
+		"listen_port = 8481\n    listen_address = '127.0.0.1'", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for listen port colliding with a wildcard address")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "[ 'internal' ]", "[ 'external' ]", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid frontend name [external]") {
+		t.Error("expected error for invalid frontend name")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "[frontends.internal]", "[frontends.default]", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Error("expected error for reserved frontend name")
+	}
+}
+
+func TestValidateConfigMappingsCacheIdentityRewriter(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[caches]",
+		"[request_rewriters]\n    [request_rewriters.identity]\n    instructions = [ [ 'param', 'delete', 'session' ] ]\n\n[caches]", 1)
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_identity_rewriter_name = 'identity'", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Origins["test"].CacheIdentityRewriter) != 1 {
+		t.Error("expected cache identity rewriter")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "cache_identity_rewriter_name = 'identity'",
+		"cache_identity_rewriter_name = 'invalid'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid cache identity rewriter name") {
+		t.Error("expected error for invalid cache identity rewriter name")
+	}
+}
+
+func TestValidateConfigMappingsCacheValidationRewriter(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[caches]",
+		"[request_rewriters]\n    [request_rewriters.validation]\n    instructions = [ [ 'header', 'set', 'X-Schema-Version', '2' ] ]\n\n[caches]", 1)
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_validation_rewriter_name = 'validation'", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Origins["test"].CacheValidationRewriter) != 1 {
+		t.Error("expected cache validation rewriter")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "cache_validation_rewriter_name = 'validation'",
+		"cache_validation_rewriter_name = 'invalid'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid cache validation rewriter name") {
+		t.Error("expected error for invalid cache validation rewriter name")
+	}
+}
+
+func TestValidateConfigMappingsCoalescingKeyRewriter(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[caches]",
+		"[request_rewriters]\n    [request_rewriters.coalesce]\n    instructions = [ [ 'header', 'delete', 'X-Trace-Id' ] ]\n\n[caches]", 1)
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    coalescing_key_rewriter_name = 'coalesce'", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Origins["test"].CoalescingKeyRewriter) != 1 {
+		t.Error("expected coalescing key rewriter")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "coalescing_key_rewriter_name = 'coalesce'",
+		"coalescing_key_rewriter_name = 'invalid'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid coalescing key rewriter name [invalid]") {
+		t.Error("expected error for invalid coalescing key rewriter name")
+	}
+}
+
+func TestProcessMethodTransformWarnings(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[caches]",
+		"[request_rewriters]\n    [request_rewriters.legacy]\n    instructions = [ [ 'method', 'transform', 'GET', 'POST' ] ]\n\n[caches]", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.LoaderWarnings) != 0 {
+		t.Errorf("expected no loader warnings got %v", c.LoaderWarnings)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'POST'", "'DELETE'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.LoaderWarnings) != 1 || !strings.Contains(c.LoaderWarnings[0], "GET to DELETE") {
+		t.Errorf("expected unsafe method transform warning got %v", c.LoaderWarnings)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'POST'", "'PO ST'", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid method")
+	}
+}
+
+func TestValidateConfigMappingsLimits(t *testing.T) {
+
+	c, _ := emptyTestConfig()
+	c.Origins["test2"] = c.Origins["test"].Clone()
+
+	c.Main.MaxOrigins = 1
+	err := c.validateConfigMappings()
+	if err == nil || !strings.Contains(err.Error(), "too many origins") {
+		t.Error("expected error for too many origins")
+	}
+
+	c.Main.MaxOrigins = 2
+	err = c.validateConfigMappings()
+	if err != nil {
+		t.Error(err)
+	}
+
+	c.Origins["test"].Paths = map[string]*po.Options{"a": {}, "b": {}}
+	c.Main.MaxPathsPerOrigin = 1
+	err = c.validateConfigMappings()
+	if err == nil || !strings.Contains(err.Error(), "too many paths") {
+		t.Error("expected error for too many paths")
+	}
+
+}
+
 const testRule = `
 [rules]
   [rules.example]
@@ -293,64 +633,1700 @@ func TestProcessOriginConfigs(t *testing.T) {
 
 }
 
-func TestLoadTOMLConfig(t *testing.T) {
+func TestProcessOriginConfigsResponseTransform(t *testing.T) {
 
-	c := NewConfig()
-	err := c.loadTOMLConfig("[[", nil)
-	if err == nil || !strings.Contains(err.Error(), "unexpected end of table name") {
-		t.Error("expected toml parsing error")
+	const paths = `
+	[origins.test.paths.xml]
+	  path = '/xml'
+	  response_transform = '%s'
+`
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(toml+fmt.Sprintf(paths, "XML2JSON"), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range c.Origins["test"].Paths {
+		if p.ResponseTransformer == nil || p.ResponseTransformer.Name != "xml2json" {
+			t.Errorf("expected xml2json transformer for path %s", p.Path)
+		}
 	}
 
-	c, tml := emptyTestConfig()
-	err = c.loadTOMLConfig(tml, &Flags{})
+	c, toml = emptyTestConfig()
+	err = c.loadTOMLConfig(toml+fmt.Sprintf(paths, "invalid"), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid response_transform [invalid]") {
+		t.Errorf("expected error for invalid response_transform got %v", err)
+	}
+}
+
+func TestProcessOriginConfigsRegexPath(t *testing.T) {
+
+	const paths = `
+	[origins.test.paths.tenant]
+	  path = '%s'
+	  match_type = 'regex'
+`
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(toml+fmt.Sprintf(paths, "/tenants/(?P<tenant>[^/]+)/query"), &Flags{})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
+	}
+	for _, p := range c.Origins["test"].Paths {
+		if p.PathRegexp == nil || !p.PathRegexp.MatchString("/tenants/acme/query") ||
+			p.PathRegexp.MatchString("/tenants/acme/query/extra") {
+			t.Errorf("expected anchored regexp for path %s", p.Path)
+		}
+	}
+
+	c, toml = emptyTestConfig()
+	err = c.loadTOMLConfig(toml+fmt.Sprintf(paths, "/tenants/([^/]+/query"), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid regex path [/tenants/([^/]+/query]") {
+		t.Errorf("expected error for invalid regex path got %v", err)
 	}
 }
 
-func TestIsStale(t *testing.T) {
+func TestProcessOriginConfigsMatchPriority(t *testing.T) {
 
-	testFile := fmt.Sprintf("/tmp/trickster_test_config.%d.conf", time.Now().UnixNano())
-	_, tml := emptyTestConfig()
+	const paths = `
+	[origins.test.paths.metrics]
+	  path = '/metrics'
+	  match_priority = %d
+`
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(toml+fmt.Sprintf(paths, 10), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range c.Origins["test"].Paths {
+		if p.MatchPriority != 10 {
+			t.Errorf("expected %d got %d", 10, p.MatchPriority)
+		}
+		if ts.IndexOfString(p.Custom, "match_priority") < 0 {
+			t.Errorf("expected match_priority in custom path settings, got %v", p.Custom)
+		}
+	}
 
-	err := ioutil.WriteFile(testFile, []byte(tml), 0666)
+	c, toml = emptyTestConfig()
+	err = c.loadTOMLConfig(toml+fmt.Sprintf(paths, -1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid match_priority [-1]") {
+		t.Errorf("expected error for invalid match_priority got %v", err)
+	}
+}
+
+func TestProcessOriginConfigsUpgradeable(t *testing.T) {
+
+	const paths = `
+	[origins.test.paths.stream]
+	  path = '/stream'
+	  upgradeable = true
+`
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(toml+paths, &Flags{})
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	defer os.Remove(testFile)
+	for _, p := range c.Origins["test"].Paths {
+		if !p.Upgradeable {
+			t.Errorf("expected %t got %t", true, p.Upgradeable)
+		}
+		if ts.IndexOfString(p.Custom, "upgradeable") < 0 {
+			t.Errorf("expected upgradeable in custom path settings, got %v", p.Custom)
+		}
+	}
+}
 
-	c, _, _ := Load("testing", "testing", []string{"-config", testFile})
-	c.ReloadConfig.RateLimitSecs = 0
+func TestProcessRequestSigning(t *testing.T) {
 
-	if c.IsStale() {
-		t.Error("expected non-stale config")
+	c, _ := emptyTestConfig()
+	toml := strings.Replace(c.String(), "[origins.test.paths]",
+		"[origins.test.request_signing]\n    algorithm = 'sha512'\n    secret = 'trickster'\n\n[origins.test.paths]", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	temp := c.Main.configFilePath
-	c.Main.configFilePath = testFile + ".invalid"
-	if c.IsStale() {
-		t.Error("expected non-stale config")
+	rs := c.Origins["test"].RequestSigning
+	if rs == nil || rs.Algorithm != "sha512" || string(rs.Key) != "trickster" {
+		t.Error("expected request signing options to be processed")
 	}
-	c.Main.configFilePath = temp
 
-	time.Sleep(time.Millisecond * 10)
+	toml = strings.Replace(toml, "algorithm = 'sha512'", "algorithm = 'md5'", 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid signing algorithm")
+	}
+}
 
-	err = ioutil.WriteFile(testFile, []byte(tml), 0666)
+func TestProcessGRPCPassthroughConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'grpc_passthrough'", 1)
+
+	// an http origin_url is proxied with cleartext HTTP/2 (h2c)
+	err := c.loadTOMLConfig(toml, &Flags{})
 	if err != nil {
 		t.Error(err)
 	}
 
-	time.Sleep(time.Millisecond * 10)
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_url = 'http://1'", "origin_url = 'ftp://1'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "requires an http or https origin_url") {
+		t.Error("expected error for non-http origin_url")
+	}
 
-	c.ReloadConfig = nil
-	if !c.IsStale() {
-		t.Error("expected stale config")
+	toml = strings.Replace(toml, "origin_url = 'http://1'", "origin_url = 'https://1'", 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Error(err)
 	}
 
-	time.Sleep(time.Millisecond * 10)
+	toml = strings.Replace(toml, "origin_url = 'https://1'", "origin_url = 'https://1'\n    is_default = false", 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "requires hosts") {
+		t.Error("expected error for missing hosts")
+	}
+}
 
-	if c.IsStale() {
-		t.Error("expected non-stale config")
+func TestProcessByteRangeConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_byte_ranges = false\n    byte_range_reassembly_policy = 'Replace'", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oc := c.Origins["test"]
+	if oc.CacheByteRanges {
+		t.Error("expected cache_byte_ranges to be false")
+	}
+	if oc.ByteRangeReassemblyPolicy != "replace" {
+		t.Errorf("expected %s got %s", "replace", oc.ByteRangeReassemblyPolicy)
+	}
+
+	toml = strings.Replace(toml, "'Replace'", "'append'", 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid byte_range_reassembly_policy") {
+		t.Error("expected error for invalid byte_range_reassembly_policy")
+	}
+}
+
+const testDefaultPathMethods = `
+	[origins.test.paths]
+	  [origins.test.paths.query]
+	  path = '/query'
+	  [origins.test.paths.status]
+	  path = '/status'
+	  methods = [ 'GET' ]
+`
+
+func TestProcessDefaultPathMethodsConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    default_path_methods = [ 'POST', 'PUT' ]", 1) + testDefaultPathMethods
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oc := c.Origins["test"]
+	if _, ok := oc.Paths["/query-POST-PUT"]; !ok {
+		t.Error("expected /query path to use the origin's default_path_methods")
+	}
+	if _, ok := oc.Paths["/status-GET"]; !ok {
+		t.Error("expected /status path to use its own methods")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'PUT'", "'P U T'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid default_path_methods") {
+		t.Error("expected error for invalid default_path_methods")
+	}
+}
+
+func TestProcessDownstreamCacheControlConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    downstream_cache_control = 'public, max-age=30'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].DownstreamCacheControl; v != "public, max-age=30" {
+		t.Errorf("expected %s got %s", "public, max-age=30", v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'public, max-age=30'", `"public\nX-Injected: 1"`, 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid downstream_cache_control") {
+		t.Error("expected error for invalid downstream_cache_control")
+	}
+}
+
+func TestProcessCacheableMethodsConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cacheable_methods = [ 'GET', 'HEAD', 'POST' ]", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].CacheableMethods; len(v) != 3 {
+		t.Errorf("expected %d got %d", 3, len(v))
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'POST'", "'PO(ST'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid cacheable_methods") {
+		t.Error("expected error for invalid cacheable_methods")
+	}
+}
+
+func TestProcessHandle100ContinueConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    handle_100_continue = 'Respond'", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Origins["test"].Handle100Continue != "respond" {
+		t.Errorf("expected %s got %s", "respond", c.Origins["test"].Handle100Continue)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'Respond'", "'ignore'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid handle_100_continue") {
+		t.Error("expected error for invalid handle_100_continue")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'Respond'",
+		"'respond'\n    max_100_continue_body_bytes = 1024", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Origins["test"].Max100ContinueBodyBytes != 1024 {
+		t.Errorf("expected %d got %d", 1024, c.Origins["test"].Max100ContinueBodyBytes)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'Respond'",
+		"'respond'\n    max_100_continue_body_bytes = 0", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_100_continue_body_bytes") {
+		t.Error("expected error for invalid max_100_continue_body_bytes")
+	}
+}
+
+func TestProcessMaxCacheKeyComponentsConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_cache_key_components = 10\n    cache_key_components_policy = 'Truncate'", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.MaxCacheKeyComponents != 10 {
+		t.Errorf("expected %d got %d", 10, oc.MaxCacheKeyComponents)
+	}
+	if oc.CacheKeyComponentsPolicy != "truncate" {
+		t.Errorf("expected %s got %s", "truncate", oc.CacheKeyComponentsPolicy)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'Truncate'", "'drop'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid cache_key_components_policy") {
+		t.Error("expected error for invalid cache_key_components_policy")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "= 10", "= -1", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_cache_key_components") {
+		t.Error("expected error for invalid max_cache_key_components")
+	}
+}
+
+func TestProcessMaxDistinctQueryShapesConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_distinct_query_shapes = 500", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Origins["test"].MaxDistinctQueryShapes; n != 500 {
+		t.Errorf("expected %d got %d", 500, n)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "= 500", "= -1", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_distinct_query_shapes") {
+		t.Error("expected error for invalid max_distinct_query_shapes")
+	}
+}
+
+func TestProcessCanonicalizeCacheKeyHeadersConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_url = 'http://1'", `origin_url = 'http://1'
+        [origins.test.paths.root]
+        path = '/'
+        cache_key_headers = [ 'x-test-header', 'X-Test-Header', 'accept-encoding' ]`, 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if !oc.CanonicalizeCacheKeyHeaders {
+		t.Error("expected cache key headers to be canonicalized by default")
+	}
+	expected := []string{"X-Test-Header", "Accept-Encoding"}
+	if v := oc.Paths["/-GET-HEAD"].CacheKeyHeaders; !ts.Equal(v, expected) {
+		t.Errorf("expected %v got %v", expected, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    canonicalize_cache_key_headers = false", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].Paths["/-GET-HEAD"].CacheKeyHeaders; len(v) != 3 {
+		t.Errorf("expected %d got %d", 3, len(v))
+	}
+}
+
+func TestProcessCacheKeyHeadersConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", `origin_type = 'test'
+    cache_key_headers = [ 'x-tenant', 'X-Tenant', 'x-region' ]`, 1)
+	toml = strings.Replace(toml, "origin_url = 'http://1'", `origin_url = 'http://1'
+        [origins.test.paths.root]
+        path = '/'
+        cache_key_headers = [ 'X-Api-Version' ]
+        replace_cache_key_headers = true`, 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	expected := []string{"X-Tenant", "X-Region"}
+	if !ts.Equal(oc.CacheKeyHeaders, expected) {
+		t.Errorf("expected %v got %v", expected, oc.CacheKeyHeaders)
+	}
+	p := oc.Paths["/-GET-HEAD"]
+	if !p.ReplaceCacheKeyHeaders {
+		t.Error("expected replace_cache_key_headers to be true")
+	}
+	if ts.IndexOfString(p.Custom, "replace_cache_key_headers") < 0 {
+		t.Errorf("expected replace_cache_key_headers in custom path settings, got %v", p.Custom)
+	}
+
+	if oc2 := oc.Clone(); !ts.Equal(oc2.CacheKeyHeaders, expected) {
+		t.Errorf("expected %v got %v", expected, oc2.CacheKeyHeaders)
+	}
+}
+
+func TestProcessCacheKeyCookiesConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", `origin_type = 'test'
+    cache_key_cookies = [ 'locale', 'locale', 'theme' ]`, 1)
+	toml = strings.Replace(toml, "origin_url = 'http://1'", `origin_url = 'http://1'
+        [origins.test.paths.root]
+        path = '/'
+        cache_key_cookies = [ 'region' ]`, 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	expected := []string{"locale", "theme"}
+	if !ts.Equal(oc.CacheKeyCookies, expected) {
+		t.Errorf("expected %v got %v", expected, oc.CacheKeyCookies)
+	}
+	p := oc.Paths["/-GET-HEAD"]
+	if ts.IndexOfString(p.Custom, "cache_key_cookies") < 0 {
+		t.Errorf("expected cache_key_cookies in custom path settings, got %v", p.Custom)
+	}
+
+	if oc2 := oc.Clone(); !ts.Equal(oc2.CacheKeyCookies, expected) {
+		t.Errorf("expected %v got %v", expected, oc2.CacheKeyCookies)
+	}
+
+	c, toml = emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", `origin_type = 'test'
+    cache_key_cookies = [ 'locale=en' ]`, 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid cache_key_cookies name [locale=en]") {
+		t.Errorf("expected error for invalid cache_key_cookies got %v", err)
+	}
+}
+
+func TestProcessDefaultUpstreamContentTypeConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", `origin_type = 'test'
+    default_upstream_content_type = 'application/json'
+    sniff_content_type = true`, 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.DefaultUpstreamContentType != "application/json" {
+		t.Errorf("expected %s got %s", "application/json", oc.DefaultUpstreamContentType)
+	}
+	if !oc.SniffContentType {
+		t.Error("expected sniff_content_type to be true")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'application/json'", "'application/'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid default_upstream_content_type") {
+		t.Error("expected error for invalid default_upstream_content_type")
+	}
+}
+
+func TestProcessMaxCollapsedWaitersConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_collapsed_waiters = 10\n    collapsed_waiters_policy = 'Reject'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.MaxCollapsedWaiters != 10 {
+		t.Errorf("expected %d got %d", 10, oc.MaxCollapsedWaiters)
+	}
+	if oc.CollapsedWaitersPolicy != oo.CollapsedWaitersPolicyReject {
+		t.Errorf("expected %s got %s", oo.CollapsedWaitersPolicyReject, oc.CollapsedWaitersPolicy)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'Reject'", "'wait'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid collapsed_waiters_policy") {
+		t.Error("expected error for invalid collapsed_waiters_policy")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "= 10", "= -1", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_collapsed_waiters") {
+		t.Error("expected error for invalid max_collapsed_waiters")
+	}
+}
+
+func TestProcessEnableHTTP2UpstreamConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    enable_http2_upstream = true", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Origins["test"].EnableHTTP2Upstream {
+		t.Errorf("expected %t got %t", true, c.Origins["test"].EnableHTTP2Upstream)
+	}
+}
+
+func TestProcessMaintenanceModeConfig(t *testing.T) {
+
+	testFile := fmt.Sprintf("/tmp/trickster_test_config.%d.conf", time.Now().UnixNano())
+	_, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    maintenance_mode = true", 1)
+	err := ioutil.WriteFile(testFile, []byte(toml), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(testFile)
+
+	c, _, err := Load("testing", "testing", []string{"-config", testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if !oc.MaintenanceMode || !oc.InMaintenance() {
+		t.Errorf("expected maintenance mode, got %t %t", oc.MaintenanceMode, oc.InMaintenance())
+	}
+
+	oc.SetMaintenance(false)
+	if oc.InMaintenance() {
+		t.Error("expected maintenance mode to be cleared")
+	}
+
+	// reloading the config reapplies maintenance_mode
+	c, _, err = Load("testing", "testing", []string{"-config", testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Origins["test"].InMaintenance() {
+		t.Error("expected maintenance mode after reload")
+	}
+}
+
+func TestProcessConditionalRequestPolicyConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    conditional_request_policy = 'Strip-On-Miss'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].ConditionalRequestPolicy; v != oo.ConditionalRequestPolicyStripOnMiss {
+		t.Errorf("expected %s got %s", oo.ConditionalRequestPolicyStripOnMiss, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "'Strip-On-Miss'", "'strip'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid conditional_request_policy") {
+		t.Error("expected error for invalid conditional_request_policy")
+	}
+}
+
+func TestProcessNegativeCacheRevalidateConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if c.Origins["test"].NegativeCacheRevalidate {
+		t.Error("expected negative_cache_revalidate to default to false")
+	}
+
+	c, _ = emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    negative_cache_revalidate = true", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Origins["test"].NegativeCacheRevalidate {
+		t.Error("expected negative_cache_revalidate to be true")
+	}
+	if c.Origins["test"].NegativeCacheRevalidateInterval != time.Second {
+		t.Errorf("expected %s got %s", time.Second, c.Origins["test"].NegativeCacheRevalidateInterval)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "negative_cache_revalidate = true",
+		"negative_cache_revalidate = true\n    negative_cache_revalidate_interval_ms = 250", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Origins["test"].NegativeCacheRevalidateIntervalMS != 250 {
+		t.Errorf("expected %d got %d", 250, c.Origins["test"].NegativeCacheRevalidateIntervalMS)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "negative_cache_revalidate = true",
+		"negative_cache_revalidate = true\n    negative_cache_revalidate_interval_ms = 0", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid negative_cache_revalidate_interval_ms") {
+		t.Error("expected error for invalid negative_cache_revalidate_interval_ms")
+	}
+}
+
+func TestProcessIdempotencyConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].IdempotencyWindowSecs; v != d.DefaultIdempotencyWindowSecs {
+		t.Errorf("expected %d got %d", d.DefaultIdempotencyWindowSecs, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    idempotency_key_header = 'Idempotency-Key'\n    idempotency_window_secs = 600", 1),
+		&Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.IdempotencyKeyHeader != "Idempotency-Key" {
+		t.Errorf("expected %s got %s", "Idempotency-Key", oc.IdempotencyKeyHeader)
+	}
+	if oc.IdempotencyWindowSecs != 600 {
+		t.Errorf("expected %d got %d", 600, oc.IdempotencyWindowSecs)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    idempotency_window_secs = 0", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid idempotency_window_secs")
+	}
+}
+
+func TestProcessMaxResponseDataPointsConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].MaxResponseDataPointsPolicy; v != d.DefaultMaxResponseDataPointsPolicy {
+		t.Errorf("expected %s got %s", d.DefaultMaxResponseDataPointsPolicy, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_response_data_points = 11000\n    max_response_data_points_policy = 'Average'", 1),
+		&Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.MaxResponseDataPoints != 11000 {
+		t.Errorf("expected %d got %d", 11000, oc.MaxResponseDataPoints)
+	}
+	if oc.MaxResponseDataPointsPolicy != "average" {
+		t.Errorf("expected %s got %s", "average", oc.MaxResponseDataPointsPolicy)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_response_data_points = -1", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid max_response_data_points")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_response_data_points_policy = 'sample'", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid max_response_data_points_policy")
+	}
+}
+
+func TestProcessMaxQueryRangeConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].RangeGuardResponseFormat; v != d.DefaultRangeGuardResponseFormat {
+		t.Errorf("expected %s got %s", d.DefaultRangeGuardResponseFormat, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_query_range_secs = 86400\n    range_guard_response_format = 'JSON'"+
+			"\n    range_guard_response_body = 'limited to {max_range}'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.MaxQueryRangeSecs != 86400 {
+		t.Errorf("expected %d got %d", 86400, oc.MaxQueryRangeSecs)
+	}
+	if oc.RangeGuardResponseFormat != "json" {
+		t.Errorf("expected %s got %s", "json", oc.RangeGuardResponseFormat)
+	}
+	if oc.RangeGuardResponseBody != "limited to {max_range}" {
+		t.Errorf("expected %s got %s", "limited to {max_range}", oc.RangeGuardResponseBody)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_query_range_secs = -1", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid max_query_range_secs")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    range_guard_response_format = 'xml'", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid range_guard_response_format")
+	}
+}
+
+func TestProcessOriginTypeConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'VictoriaMetrics'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].OriginType; v != "victoriametrics" {
+		t.Errorf("expected %s got %s", "victoriametrics", v)
+	}
+}
+
+func TestProcessCacheCompressionConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].CacheCompression; v != d.DefaultCacheCompression {
+		t.Errorf("expected %s got %s", d.DefaultCacheCompression, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_compression = 'ZSTD'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "is a memory cache") {
+		t.Error("expected error for cache_compression with a memory cache")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_name = 'test'\n    cache_compression = 'ZSTD'", 1),
+		"[caches.test]", "[caches.test]\n    cache_type = 'redis'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].CacheCompression; v != "zstd" {
+		t.Errorf("expected %s got %s", "zstd", v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_compression = 'brotli'", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid cache_compression")
+	}
+}
+
+func TestProcessBrotliQualityConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].BrotliQuality; v != d.DefaultBrotliQuality {
+		t.Errorf("expected %d got %d", d.DefaultBrotliQuality, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    brotli_quality = 0", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].BrotliQuality; v != 0 {
+		t.Errorf("expected %d got %d", 0, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    brotli_quality = 12", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid brotli_quality")
+	}
+}
+
+func TestProcessLastResortResponseConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if c.Origins["test"].LastResortResponse != nil {
+		t.Error("expected nil last_resort_response")
+	}
+
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    last_resort_response = { body = '[]', headers = { 'Content-Type' = 'application/json' } }",
+		1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lr := c.Origins["test"].LastResortResponse
+	if lr == nil || lr.Code != 200 || lr.Body != "[]" || lr.Headers["Content-Type"] != "application/json" {
+		t.Fatalf("unexpected last_resort_response %v", lr)
+	}
+	if lr2 := c.Origins["test"].Clone().LastResortResponse; lr2 == lr || lr2.Body != lr.Body {
+		t.Error("expected cloned last_resort_response")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    last_resort_response = { code = 99 }", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid last_resort_response code [99]") {
+		t.Errorf("expected error for invalid last_resort_response code, got %v", err)
+	}
+}
+
+func TestProcessACLConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(strings.Replace(toml, "[frontend]",
+		"[frontend]\n  [frontend.acl]\n  allow = ['10.0.0.0/8']\n  trusted_proxies = ['10.0.0.10']", 1),
+		"origin_type = 'test'", "origin_type = 'test'\n    acl = { deny = ['10.1.0.0/16'] }", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acl := c.Frontend.ACL; acl == nil || len(acl.AllowNetworks) != 1 || len(acl.TrustedProxyNetworks) != 1 {
+		t.Errorf("unexpected frontend acl %v", acl)
+	}
+	acl := c.Origins["test"].ACL
+	if acl == nil || len(acl.DenyNetworks) != 1 || acl.DenyNetworks[0].String() != "10.1.0.0/16" {
+		t.Fatalf("unexpected origin acl %v", acl)
+	}
+	if acl2 := c.Origins["test"].Clone().ACL; acl2 == acl || len(acl2.DenyNetworks) != 1 {
+		t.Error("expected cloned origin acl")
+	}
+
+	// frontends differing only by their acl are equal, so their listeners are not restarted
+	fc := c.Frontend.Clone()
+	fc.ACL.Allow = nil
+	if !fc.Equal(c.Frontend) {
+		t.Error("expected equal frontend configs")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "[frontend]",
+		"[frontend]\n  [frontend.acl]\n  deny = ['10.0.0.0/33']", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid acl config in frontend: invalid deny cidr [10.0.0.0/33]") {
+		t.Errorf("expected error for invalid frontend acl, got %v", err)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    acl = { allow = ['internal'] }", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid acl config in origin [test]: invalid allow cidr [internal]") {
+		t.Errorf("expected error for invalid origin acl, got %v", err)
+	}
+}
+
+func TestProcessClientMaxAgeACLConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    honor_client_max_age = true\n    client_max_age_acl = { allow = ['10.0.0.0/8'] }", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acl := c.Origins["test"].ClientMaxAgeACL; acl == nil || len(acl.AllowNetworks) != 1 {
+		t.Errorf("unexpected client_max_age_acl %v", acl)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    honor_client_max_age = true", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "requires a client_max_age_acl") {
+		t.Errorf("expected error for missing client_max_age_acl, got %v", err)
+	}
+}
+
+func TestProcessDuplicateParamPolicyConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].DuplicateParamPolicy; v != d.DefaultDuplicateParamPolicy {
+		t.Errorf("expected %s got %s", d.DefaultDuplicateParamPolicy, v)
+	}
+
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    duplicate_param_policy = 'Last'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].DuplicateParamPolicy; v != "last" {
+		t.Errorf("expected %s got %s", "last", v)
+	}
+	if v := c.Origins["test"].Clone().DuplicateParamPolicy; v != "last" {
+		t.Errorf("expected %s got %s", "last", v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    duplicate_param_policy = 'random'", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid duplicate_param_policy [random]") {
+		t.Errorf("expected error for invalid duplicate_param_policy, got %v", err)
+	}
+}
+
+func TestProcessCacheKeyEncodingConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].CacheKeyEncoding; v != d.DefaultCacheKeyEncoding {
+		t.Errorf("expected %s got %s", d.DefaultCacheKeyEncoding, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_key_encoding = 'Base32'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].CacheKeyEncoding; v != "base32" {
+		t.Errorf("expected %s got %s", "base32", v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_key_encoding = 'base58'", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid cache_key_encoding")
+	}
+}
+
+func TestProcessAsyncCacheWriteConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    async_cache_write = true\n    max_async_cache_writes = 8", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if !oc.AsyncCacheWrite {
+		t.Error("expected async_cache_write to be true")
+	}
+	if oc.MaxAsyncCacheWrites != 8 {
+		t.Errorf("expected %d got %d", 8, oc.MaxAsyncCacheWrites)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_async_cache_writes = 0", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid max_async_cache_writes")
+	}
+}
+
+func TestProcessOriginURLsConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	tml := strings.Replace(toml, "    origin_url = 'http://1'\n", "", 1)
+	err := c.loadTOMLConfig(strings.Replace(tml, "origin_type = 'test'",
+		"origin_type = 'test'\n    origin_urls = [ 'http://1', 'http://2' ]\n    health_check_interval_secs = 5", 1),
+		&Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if len(oc.OriginURLs) != 2 {
+		t.Errorf("expected %d got %d", 2, len(oc.OriginURLs))
+	}
+	if oc.LoadBalancing != d.DefaultLoadBalancing {
+		t.Errorf("expected %s got %s", d.DefaultLoadBalancing, oc.LoadBalancing)
+	}
+	if oc.HealthCheckIntervalSecs != 5 {
+		t.Errorf("expected %d got %d", 5, oc.HealthCheckIntervalSecs)
+	}
+	if oc.HealthCheckQuorum != d.DefaultHealthCheckQuorum {
+		t.Errorf("expected %s got %s", d.DefaultHealthCheckQuorum, oc.HealthCheckQuorum)
+	}
+
+	// integer quorums may be provided as a TOML integer or string
+	for _, q := range []string{"2", "'2'", "'ALL'"} {
+		c, _ = emptyTestConfig()
+		err = c.loadTOMLConfig(strings.Replace(tml, "origin_type = 'test'",
+			"origin_type = 'test'\n    origin_urls = [ 'http://1', 'http://2' ]\n    health_check_quorum = "+q, 1),
+			&Flags{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := strings.ToLower(strings.Trim(q, "'"))
+		if v := c.Origins["test"].HealthCheckQuorum; string(v) != expected {
+			t.Errorf("expected %s got %s", expected, v)
+		}
+	}
+
+	tests := []struct {
+		toml, expected string
+	}{
+		{strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    origin_urls = [ 'http://2' ]", 1),
+			"only one of origin_url or origin_urls"},
+		{strings.Replace(tml, "origin_type = 'test'", "origin_type = 'test'\n    origin_urls = [ ]", 1),
+			"empty origin_urls"},
+		{strings.Replace(tml, "origin_type = 'test'", "origin_type = 'test'\n    origin_urls = [ 'p1:9090' ]", 1),
+			"invalid origin_urls"},
+		{strings.Replace(tml, "origin_type = 'test'", "origin_type = 'test'\n    load_balancing = 'least_conn'", 1),
+			"invalid load_balancing"},
+		{strings.Replace(tml, "origin_type = 'test'", "origin_type = 'test'\n    health_check_interval_secs = -1", 1),
+			"invalid health_check_interval_secs"},
+		{strings.Replace(tml, "origin_type = 'test'",
+			"origin_type = 'test'\n    origin_urls = [ 'http://1', 'http://2' ]\n    health_check_quorum = 3", 1),
+			"invalid health_check_quorum"},
+		{strings.Replace(tml, "origin_type = 'test'",
+			"origin_type = 'test'\n    origin_urls = [ 'http://1', 'http://2' ]\n    health_check_quorum = 'most'", 1),
+			"invalid health_check_quorum"},
+		{strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    health_check_quorum = 'all'", 1),
+			"health_check_quorum requires origin_urls"},
+	}
+	for _, test := range tests {
+		c, _ = emptyTestConfig()
+		err = c.loadTOMLConfig(test.toml, &Flags{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected error %s got %v", test.expected, err)
+		}
+	}
+}
+
+func TestProcessRetryConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    retry_max_attempts = 3\n    retry_initial_backoff_ms = 50"+
+			"\n    retry_max_backoff_ms = 400\n    retry_status_codes = [ 503 ]", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.RetryMaxAttempts != 3 {
+		t.Errorf("expected %d got %d", 3, oc.RetryMaxAttempts)
+	}
+	if oc.RetryInitialBackoffMS != 50 {
+		t.Errorf("expected %d got %d", 50, oc.RetryInitialBackoffMS)
+	}
+	if oc.RetryMaxBackoffMS != 400 {
+		t.Errorf("expected %d got %d", 400, oc.RetryMaxBackoffMS)
+	}
+	if len(oc.RetryStatusCodes) != 1 || oc.RetryStatusCodes[0] != 503 {
+		t.Errorf("expected %v got %v", []int{503}, oc.RetryStatusCodes)
+	}
+
+	tests := []string{
+		"retry_max_attempts = -1",
+		"retry_initial_backoff_ms = -1",
+		"retry_max_backoff_ms = -1",
+		"retry_initial_backoff_ms = 500\n    retry_max_backoff_ms = 100",
+		"retry_status_codes = [ 503, 1000 ]",
+	}
+	for _, test := range tests {
+		c, _ = emptyTestConfig()
+		err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+			"origin_type = 'test'\n    "+test, 1), &Flags{})
+		if err == nil {
+			t.Errorf("expected error for %s", test)
+		}
+	}
+}
+
+func TestProcessBreakerConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    breaker_error_threshold = 5\n    breaker_open_duration_secs = 10", 1),
+		&Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.BreakerErrorThreshold != 5 {
+		t.Errorf("expected %d got %d", 5, oc.BreakerErrorThreshold)
+	}
+	if oc.BreakerOpenDurationSecs != 10 {
+		t.Errorf("expected %d got %d", 10, oc.BreakerOpenDurationSecs)
+	}
+	if oc.BreakerHalfOpenRequests != d.DefaultBreakerHalfOpenRequests {
+		t.Errorf("expected %d got %d", d.DefaultBreakerHalfOpenRequests, oc.BreakerHalfOpenRequests)
+	}
+
+	tests := []string{
+		"breaker_error_threshold = -1",
+		"breaker_error_threshold = 0",
+		"breaker_open_duration_secs = 0",
+		"breaker_half_open_requests = -1",
+	}
+	for _, test := range tests {
+		c, _ = emptyTestConfig()
+		err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+			"origin_type = 'test'\n    "+test, 1), &Flags{})
+		if err == nil {
+			t.Errorf("expected error for %s", test)
+		}
+	}
+}
+
+func TestProcessCollapsedForwardingTimeoutConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].CollapsedForwardingTimeoutPolicy; v != d.DefaultCollapsedForwardingTimeoutPolicy {
+		t.Errorf("expected %s got %s", d.DefaultCollapsedForwardingTimeoutPolicy, v)
+	}
+
+	c, _ = emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    collapsed_forwarding_timeout_ms = 250\n    collapsed_forwarding_timeout_policy = 'Reject'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if oc.CollapsedForwardingTimeoutMS != 250 {
+		t.Errorf("expected %d got %d", 250, oc.CollapsedForwardingTimeoutMS)
+	}
+	if oc.CollapsedForwardingTimeoutPolicy != oo.CollapsedWaitersPolicyReject {
+		t.Errorf("expected %s got %s", oo.CollapsedWaitersPolicyReject, oc.CollapsedForwardingTimeoutPolicy)
+	}
+
+	c, toml = emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    collapsed_forwarding_timeout_ms = -1", 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid collapsed_forwarding_timeout_ms")
+	}
+
+	c, toml = emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    collapsed_forwarding_timeout_policy = 'invalid'", 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid collapsed_forwarding_timeout_policy")
+	}
+}
+
+func TestProcessTLSServerNameConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_url = 'http://1'",
+		"origin_url = 'http://1'\n    [origins.test.tls]\n    server_name = 'origin.example.com'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].TLS.ServerName; v != "origin.example.com" {
+		t.Errorf("expected %s got %s", "origin.example.com", v)
+	}
+
+	for _, name := range []string{"127.0.0.1", "-bad.example.com", "bad_name.example.com", "a..b"} {
+		c, toml = emptyTestConfig()
+		toml = strings.Replace(toml, "origin_url = 'http://1'",
+			"origin_url = 'http://1'\n    [origins.test.tls]\n    server_name = '"+name+"'", 1)
+		err = c.loadTOMLConfig(toml, &Flags{})
+		if err == nil {
+			t.Errorf("expected error for invalid tls server_name %s", name)
+		}
+	}
+}
+
+func TestProcessCacheKeyFromAuthHashConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if v := c.Origins["test"].CacheKeyAuthHeader; v != headers.NameAuthorization {
+		t.Errorf("expected %s got %s", headers.NameAuthorization, v)
+	}
+
+	c, _ = emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_key_from_auth_hash = true\n    cache_key_auth_header = 'x-tenant-token'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["test"]
+	if !oc.CacheKeyFromAuthHash {
+		t.Error("expected cache_key_from_auth_hash to be true")
+	}
+	if oc.CacheKeyAuthHeader != "X-Tenant-Token" {
+		t.Errorf("expected %s got %s", "X-Tenant-Token", oc.CacheKeyAuthHeader)
+	}
+}
+
+func TestProcessStripPathPrefixConfig(t *testing.T) {
+
+	tests := []struct {
+		prefix, expected string
+	}{
+		{"'/prometheus'", "/prometheus"},
+		{"'prometheus/'", "/prometheus"},
+		{"'/'", ""},
+	}
+
+	for _, test := range tests {
+		c, toml := emptyTestConfig()
+		toml = strings.Replace(toml, "origin_type = 'test'",
+			"origin_type = 'test'\n    strip_path_prefix = "+test.prefix, 1)
+		err := c.loadTOMLConfig(toml, &Flags{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := c.Origins["test"].StripPathPrefix; v != test.expected {
+			t.Errorf("expected %s got %s", test.expected, v)
+		}
+	}
+}
+
+func TestProcessSharedCacheNamespaces(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_url = 'http://1'", `origin_url = 'http://1'
+    shared_cache_namespace = 'backend'
+    [origins.test2]
+    origin_type = 'test'
+    origin_url = 'http://1'
+    shared_cache_namespace = 'backend'`, 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Origins["test"].CacheKeyPrefix != "backend" || c.Origins["test2"].CacheKeyPrefix != "backend" {
+		t.Error("expected shared cache namespace to be used as cache key prefix")
+	}
+	if len(c.LoaderWarnings) != 0 {
+		t.Errorf("expected no loader warnings got %v", c.LoaderWarnings)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "[origins.test2]",
+		"[origins.test2]\n    include_host_in_cache_key = true\n    cache_key_prefix = 'other'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one warning for the overridden cache_key_prefix and one for the key setting mismatch
+	if len(c.LoaderWarnings) != 2 {
+		t.Errorf("expected 2 loader warnings got %v", c.LoaderWarnings)
+	}
+	if c.Origins["test2"].CacheKeyPrefix != "backend" {
+		t.Errorf("expected %s got %s", "backend", c.Origins["test2"].CacheKeyPrefix)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "[origins.test2]",
+		"[origins.test2]\n    duplicate_param_policy = 'first'\n    strip_path_prefix = '/p'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.LoaderWarnings) != 2 ||
+		!strings.Contains(c.LoaderWarnings[0], "duplicate_param_policy") ||
+		!strings.Contains(c.LoaderWarnings[1], "strip_path_prefix") {
+		t.Errorf("expected a duplicate_param_policy and strip_path_prefix warning got %v", c.LoaderWarnings)
+	}
+}
+
+func TestProcessNegativeCacheTTLs(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[frontend]", `[main]
+max_negative_cache_ttl_secs = 30
+
+[negative_caches]
+    [negative_caches.default]
+    404 = 60
+    500 = 10
+
+    [negative_caches.other]
+    502 = 3600
+
+[frontend]`, 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.NegativeCacheConfigs["default"]["404"]; v != 30 {
+		t.Errorf("expected %d got %d", 30, v)
+	}
+	if v := c.NegativeCacheConfigs["default"]["500"]; v != 10 {
+		t.Errorf("expected %d got %d", 10, v)
+	}
+	if v := c.NegativeCacheConfigs["other"]["502"]; v != 30 {
+		t.Errorf("expected %d got %d", 30, v)
+	}
+	if len(c.LoaderWarnings) != 2 {
+		t.Errorf("expected 2 loader warnings got %v", c.LoaderWarnings)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "max_negative_cache_ttl_secs = 30",
+		"max_negative_cache_ttl_secs = -1", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_negative_cache_ttl_secs") {
+		t.Error("expected error for invalid max_negative_cache_ttl_secs")
+	}
+}
+
+func TestProcessMaxConcurrentUpstreamRequestsConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_concurrent_upstream_requests = 8", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].MaxConcurrentUpstreamRequests; v != 8 {
+		t.Errorf("expected %d got %d", 8, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "max_concurrent_upstream_requests = 8",
+		"max_concurrent_upstream_requests = -1", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_concurrent_upstream_requests") {
+		t.Error("expected error for invalid max_concurrent_upstream_requests")
+	}
+}
+
+func TestProcessMaxCacheWritesPerSecConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_cache_writes_per_sec = 100", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].MaxCacheWritesPerSec; v != 100 {
+		t.Errorf("expected %d got %d", 100, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "max_cache_writes_per_sec = 100",
+		"max_cache_writes_per_sec = -1", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_cache_writes_per_sec") {
+		t.Error("expected error for invalid max_cache_writes_per_sec")
+	}
+}
+
+func TestProcessMaxConcurrentTLSHandshakesConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    max_concurrent_tls_handshakes = 4", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].MaxConcurrentTLSHandshakes; v != 4 {
+		t.Errorf("expected %d got %d", 4, v)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "max_concurrent_tls_handshakes = 4",
+		"max_concurrent_tls_handshakes = -1", 1), &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_concurrent_tls_handshakes") {
+		t.Error("expected error for invalid max_concurrent_tls_handshakes")
+	}
+}
+
+func TestProcessTimeoutResponseConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    timeout_response_code = 504\n    timeout_response_body = '{}'", 1)
+
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oc := c.Origins["test"]
+	if oc.TimeoutResponseCode != 504 || oc.TimeoutResponseBody != "{}" {
+		t.Error("expected timeout response options to be processed")
+	}
+
+	toml = strings.Replace(toml, "timeout_response_code = 504", "timeout_response_code = 999", 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid timeout_response_code") {
+		t.Error("expected error for invalid timeout_response_code")
+	}
+}
+
+func TestProcessCachingConfigsMaxKeyLength(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[caches.test]", "[caches.test]\n    max_key_length_bytes = 16", 1)
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid max_key_length_bytes") {
+		t.Error("expected error for invalid max_key_length_bytes")
+	}
+
+	c, _ = emptyTestConfig()
+	toml = strings.Replace(toml, "max_key_length_bytes = 16", "max_key_length_bytes = 128", 1)
+	err = c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Caches["test"].MaxKeyLengthBytes != 128 {
+		t.Errorf("expected %d got %d", 128, c.Caches["test"].MaxKeyLengthBytes)
+	}
+}
+
+func TestProcessCachingConfigsCompressionDictionary(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[caches.test]",
+		"[caches.test]\n    compression_dictionary_path = '../../testdata/zstd_test.dict'", 1)
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Caches["test"].CompressionDictionary == nil {
+		t.Error("expected compression dictionary to be loaded")
+	}
+	if len(c.LoaderWarnings) != 0 {
+		t.Errorf("expected no loader warnings got %v", c.LoaderWarnings)
+	}
+
+	// invalid or missing dictionaries fall back to compression without a dictionary
+	for _, path := range []string{"../../testdata/gzip_test.txt.gz", "../../testdata/nonexistent.dict"} {
+		c, _ = emptyTestConfig()
+		err = c.loadTOMLConfig(strings.Replace(toml, "../../testdata/zstd_test.dict", path, 1), &Flags{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Caches["test"].CompressionDictionary != nil {
+			t.Errorf("expected nil compression dictionary for %s", path)
+		}
+		if len(c.LoaderWarnings) != 1 {
+			t.Errorf("expected 1 loader warning for %s got %d", path, len(c.LoaderWarnings))
+		}
+	}
+}
+
+func TestProcessCachingConfigsVerifyChecksums(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "[caches.test]", "[caches.test]\n    verify_checksums = true", 1)
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Caches["test"].VerifyChecksums {
+		t.Error("expected checksum verification to be enabled")
+	}
+}
+
+func TestProcessCachingConfigsEvictionPolicy(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Caches["test"].Index.EvictionPolicy != evictionmethods.IndexEvictionPolicyLRU {
+		t.Errorf("expected %s got %s", "lru", c.Caches["test"].Index.EvictionPolicy)
+	}
+
+	c, _ = emptyTestConfig()
+	tml := strings.Replace(toml, "[caches.test.index]", "[caches.test.index]\n        eviction_policy = 'LFU'", 1)
+	err = c.loadTOMLConfig(tml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Caches["test"].Index.EvictionPolicy != evictionmethods.IndexEvictionPolicyLFU {
+		t.Errorf("expected %s got %s", "lfu", c.Caches["test"].Index.EvictionPolicy)
+	}
+
+	c, _ = emptyTestConfig()
+	tml = strings.Replace(toml, "[caches.test.index]", "[caches.test.index]\n        eviction_policy = 'mru'", 1)
+	err = c.loadTOMLConfig(tml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid eviction_policy [mru]") {
+		t.Errorf("expected invalid eviction_policy error, got %v", err)
+	}
+}
+
+func TestProcessCachingConfigsReapIO(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	tml := strings.Replace(toml, "[caches.test.index]",
+		"[caches.test.index]\n        reap_max_concurrent_io = 8\n        reap_io_pause_ms = 50", 1)
+	err := c.loadTOMLConfig(tml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Caches["test"].Index.ReapMaxConcurrentIO; v != 8 {
+		t.Errorf("expected %d got %d", 8, v)
+	}
+	if v := c.Caches["test"].Index.ReapIOPauseMS; v != 50 {
+		t.Errorf("expected %d got %d", 50, v)
+	}
+
+	for _, opt := range []string{"reap_max_concurrent_io", "reap_io_pause_ms"} {
+		c, _ = emptyTestConfig()
+		tml = strings.Replace(toml, "[caches.test.index]", "[caches.test.index]\n        "+opt+" = -1", 1)
+		err = c.loadTOMLConfig(tml, &Flags{})
+		if err == nil || !strings.Contains(err.Error(), "invalid "+opt) {
+			t.Errorf("expected invalid %s error, got %v", opt, err)
+		}
+	}
+}
+
+func TestProcessCachingConfigsMaxOpenFiles(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	tml := strings.Replace(toml, "[caches.test.filesystem]",
+		"[caches.test.filesystem]\n        max_open_files = 64", 1)
+	err := c.loadTOMLConfig(tml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Caches["test"].Filesystem.MaxOpenFiles; v != 64 {
+		t.Errorf("expected %d got %d", 64, v)
+	}
+
+	c, _ = emptyTestConfig()
+	tml = strings.Replace(toml, "[caches.test.filesystem]",
+		"[caches.test.filesystem]\n        max_open_files = 0", 1)
+	err = c.loadTOMLConfig(tml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid filesystem max_open_files") {
+		t.Errorf("expected invalid max_open_files error, got %v", err)
+	}
+}
+
+func TestProcessCachingConfigsRedisDrainTimeout(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	toml = strings.Replace(toml, "[caches.test.index]", "cache_type = 'redis'\n        [caches.test.index]", 1)
+	if c.Caches["default"].Redis.DrainTimeoutMS != d.DefaultRedisDrainTimeoutMS {
+		t.Errorf("expected %d got %d", d.DefaultRedisDrainTimeoutMS, c.Caches["default"].Redis.DrainTimeoutMS)
+	}
+
+	tml := strings.Replace(toml, "[caches.test.redis]",
+		"[caches.test.redis]\n        drain_timeout_ms = 250", 1)
+	err := c.loadTOMLConfig(tml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Caches["test"].Redis.DrainTimeoutMS; v != 250 {
+		t.Errorf("expected %d got %d", 250, v)
+	}
+
+	c, _ = emptyTestConfig()
+	tml = strings.Replace(toml, "[caches.test.redis]",
+		"[caches.test.redis]\n        drain_timeout_ms = -1", 1)
+	err = c.loadTOMLConfig(tml, &Flags{})
+	if err == nil || !strings.Contains(err.Error(), "invalid redis drain_timeout_ms") {
+		t.Errorf("expected invalid drain_timeout_ms error, got %v", err)
+	}
+}
+
+func TestProcessCachingConfigsMemcached(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	mc := "cache_type = 'memcached'\n        [caches.test.memcached]\n        %s\n        [caches.test.index]"
+
+	tml := strings.Replace(toml, "[caches.test.index]", fmt.Sprintf(mc,
+		"servers = ['mc1:11211', 'mc2:11211']\n        max_idle_conns = 4\n        timeout_ms = 100\n        dial_timeout_ms = 200"), 1)
+	err := c.loadTOMLConfig(tml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := c.Caches["test"].Memcached
+	if len(o.Servers) != 2 || o.Servers[1] != "mc2:11211" {
+		t.Errorf("unexpected servers %v", o.Servers)
+	}
+	if o.MaxIdleConns != 4 || o.TimeoutMS != 100 || o.DialTimeoutMS != 200 {
+		t.Errorf("unexpected options %v", o)
+	}
+	if !c.Caches["test"].Clone().Equal(c.Caches["test"]) {
+		t.Error("expected equal clone")
+	}
+
+	tests := []struct {
+		setting, expected string
+	}{
+		{"servers = []", "no memcached servers"},
+		{"max_idle_conns = -1", "invalid memcached max_idle_conns"},
+		{"timeout_ms = -1", "invalid memcached timeout_ms"},
+		{"dial_timeout_ms = -1", "invalid memcached dial_timeout_ms"},
+	}
+	for _, test := range tests {
+		c, _ = emptyTestConfig()
+		err = c.loadTOMLConfig(strings.Replace(toml, "[caches.test.index]",
+			fmt.Sprintf(mc, test.setting), 1), &Flags{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected %s error, got %v", test.expected, err)
+		}
+	}
+}
+
+func TestLoadTOMLConfig(t *testing.T) {
+
+	c := NewConfig()
+	err := c.loadTOMLConfig("[[", nil)
+	if err == nil || !strings.Contains(err.Error(), "unexpected end of table name") {
+		t.Error("expected toml parsing error")
+	}
+
+	c, tml := emptyTestConfig()
+	err = c.loadTOMLConfig(tml, &Flags{})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIsStale(t *testing.T) {
+
+	testFile := fmt.Sprintf("/tmp/trickster_test_config.%d.conf", time.Now().UnixNano())
+	_, tml := emptyTestConfig()
+
+	err := ioutil.WriteFile(testFile, []byte(tml), 0666)
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.Remove(testFile)
+
+	c, _, _ := Load("testing", "testing", []string{"-config", testFile})
+	c.ReloadConfig.RateLimitSecs = 0
+
+	if c.IsStale() {
+		t.Error("expected non-stale config")
+	}
+
+	temp := c.Main.configFilePath
+	c.Main.configFilePath = testFile + ".invalid"
+	if c.IsStale() {
+		t.Error("expected non-stale config")
+	}
+	c.Main.configFilePath = temp
+
+	time.Sleep(time.Millisecond * 10)
+
+	err = ioutil.WriteFile(testFile, []byte(tml), 0666)
+	if err != nil {
+		t.Error(err)
+	}
+
+	time.Sleep(time.Millisecond * 10)
+
+	c.ReloadConfig = nil
+	if !c.IsStale() {
+		t.Error("expected stale config")
+	}
+
+	time.Sleep(time.Millisecond * 10)
+
+	if c.IsStale() {
+		t.Error("expected non-stale config")
+	}
+}
+
+func TestIsStaleDebounce(t *testing.T) {
+
+	testFile := fmt.Sprintf("/tmp/trickster_test_config.%d.conf", time.Now().UnixNano())
+	_, tml := emptyTestConfig()
+
+	err := ioutil.WriteFile(testFile, []byte(tml), 0666)
+	if err != nil {
+		t.Error(err)
+	}
+	defer os.Remove(testFile)
+
+	c, _, _ := Load("testing", "testing", []string{"-config", testFile})
+	c.ReloadConfig.RateLimitSecs = 0
+	c.ReloadConfig.DebounceMS = 20
+
+	// simulate an editor that is still writing the file throughout the debounce checks
+	mt := time.Now()
+	done := make(chan bool)
+	go func() {
+		for i := 1; i <= 60; i++ {
+			os.Chtimes(testFile, mt, mt.Add(time.Second*time.Duration(i)))
+			time.Sleep(time.Millisecond * 5)
+		}
+		close(done)
+	}()
+
+	time.Sleep(time.Millisecond * 10)
+	if c.IsStale() {
+		t.Error("expected non-stale config while the file is being modified")
+	}
+	<-done
+
+	if !c.IsStale() {
+		t.Error("expected stale config")
 	}
 }
 
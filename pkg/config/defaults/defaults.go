@@ -18,6 +18,7 @@ package defaults
 
 import (
 	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
+	"github.com/tricksterproxy/trickster/pkg/cache/serialization"
 	"github.com/tricksterproxy/trickster/pkg/cache/types"
 )
 
@@ -54,6 +55,8 @@ const (
 	DefaultDrainTimeoutSecs = 30
 	// DefaultRateLimitSecs is the default Rate Limit time for Config Reloads
 	DefaultRateLimitSecs = 3
+	// DefaultReloadDebounceMS is the default time to wait for a modified config file to settle before reloading it
+	DefaultReloadDebounceMS = 100
 
 	// DefaultTracerType is the default distributed tracer exporter implementation
 	DefaultTracerType = "none"
@@ -67,6 +70,12 @@ const (
 	// and should align with DefaultCacheType
 	DefaultCacheTypeID = types.CacheTypeMemory
 
+	// DefaultSerializationFormat is the default format used to serialize objects stored in a cache
+	DefaultSerializationFormat = "msgpack"
+	// DefaultSerializationFormatID is the default serialization format ID for any defined cache
+	// and should align with DefaultSerializationFormat
+	DefaultSerializationFormatID = serialization.FormatMsgPack
+
 	// DefaultTimeseriesTTLSecs is the default Cache TTL for Time Series Objects
 	DefaultTimeseriesTTLSecs = 21600
 	// DefaultFastForwardTTLSecs is the default Cache TTL for Time Series Fast Forward Objects
@@ -75,12 +84,26 @@ const (
 	DefaultMaxTTLSecs = 86400
 	// DefaultRevalidationFactor is the default Cache Object Freshness Lifetime to TTL multiplier
 	DefaultRevalidationFactor = 2
+	// DefaultTTLAsRangeFractionMinSecs is the default minimum TTL when ttl_as_range_fraction is used
+	DefaultTTLAsRangeFractionMinSecs = 15
 	// DefaultRedisClientType is the default Redis Client Type
 	DefaultRedisClientType = "standard"
 	// DefaultRedisProtocol is the default Redis Client protocol
 	DefaultRedisProtocol = "tcp"
 	// DefaultRedisEndpoint is the default Redis Client endpoint
 	DefaultRedisEndpoint = "redis:6379"
+	// DefaultRedisDrainTimeoutMS is the default time to wait for in-flight Redis operations
+	// to complete before closing a client that is replaced by a config reload
+	DefaultRedisDrainTimeoutMS = 5000
+	// DefaultMemcachedServer is the default memcached server endpoint
+	DefaultMemcachedServer = "memcached:11211"
+	// DefaultMemcachedMaxIdleConns is the default maximum number of idle connections
+	// kept open to each memcached server
+	DefaultMemcachedMaxIdleConns = 2
+	// DefaultMemcachedTimeoutMS is the default timeout for memcached socket reads and writes
+	DefaultMemcachedTimeoutMS = 500
+	// DefaultMemcachedDialTimeoutMS is the default timeout for establishing memcached connections
+	DefaultMemcachedDialTimeoutMS = 1000
 	// DefaultBBoltFile is the default bbolt Cache filename
 	DefaultBBoltFile = "trickster.db"
 	// DefaultBBoltBucket is the default bbolt Cache bucket name
@@ -97,6 +120,10 @@ const (
 	DefaultMaxSizeObjects = 0
 	// DefaultMaxSizeBackoffObjects is the default Max Cache Backoff Object Count
 	DefaultMaxSizeBackoffObjects = 100
+	// DefaultCacheIndexEvictionPolicy is the default Cache Index Eviction Policy
+	DefaultCacheIndexEvictionPolicy = evictionmethods.IndexEvictionPolicyLRU
+	// DefaultCacheIndexEvictionPolicyName is the default Cache Index Eviction Policy name
+	DefaultCacheIndexEvictionPolicyName = "lru"
 	// DefaultMaxObjectSizeBytes is the default Max Size of any Cache Object
 	DefaultMaxObjectSizeBytes = 524288
 	// DefaultOriginTRF is the default Timeseries Retention Factor for Time Series-based Origins
@@ -105,8 +132,25 @@ const (
 	DefaultOriginTEM = evictionmethods.EvictionMethodOldest
 	// DefaultOriginTEMName is the default Timeseries Eviction Method name for Time Series-based Origins
 	DefaultOriginTEMName = "oldest"
+	// DefaultFrontendName is the name of the main frontend configured in the [frontend] section
+	DefaultFrontendName = "default"
 	// DefaultOriginTimeoutSecs is the default Upstream Request Timeout for Origins
 	DefaultOriginTimeoutSecs = 180
+	// DefaultTimeoutResponseCode is the default status code returned downstream when an upstream request times out
+	DefaultTimeoutResponseCode = 502
+	// DefaultTimeoutResponseContentType is the default Content-Type of a custom timeout response body
+	DefaultTimeoutResponseContentType = "text/plain; charset=utf-8"
+	// DefaultRetryInitialBackoffMS is the default wait before the first upstream request retry
+	DefaultRetryInitialBackoffMS = 100
+	// DefaultNegativeCacheRevalidateIntervalMS is the default minimum time between background
+	// revalidations of the same negatively-cached object
+	DefaultNegativeCacheRevalidateIntervalMS = 1000
+	// DefaultRetryMaxBackoffMS is the default maximum wait between upstream request retries
+	DefaultRetryMaxBackoffMS = 2000
+	// DefaultBreakerOpenDurationSecs is the default time an origin's circuit breaker remains open
+	DefaultBreakerOpenDurationSecs = 30
+	// DefaultBreakerHalfOpenRequests is the default number of probe requests that close a circuit breaker
+	DefaultBreakerHalfOpenRequests = 1
 	// DefaultOriginCacheName is the default Cache Name for Origins
 	DefaultOriginCacheName = "default"
 	// DefaultOriginNegativeCacheName is the default Negative Cache Name for Origins
@@ -125,12 +169,32 @@ const (
 	DefaultHealthCheckQuery = "-"
 	// DefaultHealthCheckVerb is the default value (noop) for Origins' Health Check Verb
 	DefaultHealthCheckVerb = "-"
+	// DefaultHealthCheckIntervalSecs is the default interval between health checks of Origins' replica URLs
+	DefaultHealthCheckIntervalSecs = 10
+	// DefaultHealthCheckQuorum is the default number of Origins' replica URLs that must be healthy
+	// for the Origin to be healthy
+	DefaultHealthCheckQuorum = "any"
+	// DefaultLoadBalancing is the default policy for selecting among Origins' replica URLs
+	DefaultLoadBalancing = "round_robin"
 	// DefaultConfigHandlerPath is the default value for the Trickster Config Printout Handler path
 	DefaultConfigHandlerPath = "/trickster/config"
 	// DefaultPingHandlerPath is the default value for the Trickster Config Ping Handler path
 	DefaultPingHandlerPath = "/trickster/ping"
+	// DefaultRouteDebugHandlerPath is the default value for the Trickster Route Debug Handler path
+	DefaultRouteDebugHandlerPath = "/trickster/routes"
 	// DefaultReloadHandlerPath defines the default path for the Reload Handler
 	DefaultReloadHandlerPath = "/trickster/config/reload"
+	// DefaultBasicAuthRealm is the default realm presented to clients by a frontend's HTTP Basic Authentication
+	DefaultBasicAuthRealm = "trickster"
+	// DefaultJWKSRefreshSecs is the default number of seconds a frontend's JSON Web Key Set
+	// is used before it is fetched again
+	DefaultJWKSRefreshSecs = 300
+	// DefaultCacheExportHandlerPath defines the default path for the Cache Export Handler
+	DefaultCacheExportHandlerPath = "/trickster/cache/export"
+	// DefaultCacheImportHandlerPath defines the default path for the Cache Import Handler
+	DefaultCacheImportHandlerPath = "/trickster/cache/import"
+	// DefaultMaintenanceHandlerPath defines the default path for the Maintenance Handler
+	DefaultMaintenanceHandlerPath = "/trickster/maintenance"
 	// DefaultHealthHandlerPath defines the default path for the Health Handler
 	DefaultHealthHandlerPath = "/trickster/health"
 	// DefaultMaxRuleExecutions is the default value for the number of allowed Rule executions per Request
@@ -139,8 +203,69 @@ const (
 	DefaultPprofServerName = "both"
 	// DefaultForwardedHeaders defines which class of 'Forwarded' headers are attached to upstream requests
 	DefaultForwardedHeaders = "standard"
+	// DefaultEmitAgeHeader defines whether an Age header is attached to responses served from cache
+	DefaultEmitAgeHeader = true
+	// DefaultEmitStaleWarning defines whether a Warning header is attached to responses served stale
+	DefaultEmitStaleWarning = true
+	// DefaultStripHopByHopHeaders defines whether hop-by-hop headers are stripped from proxied messages
+	DefaultStripHopByHopHeaders = true
+	// DefaultHandle100Continue defines how requests with an Expect: 100-continue header are proxied
+	DefaultHandle100Continue = "forward"
+	// DefaultMax100ContinueBodyBytes is the largest request body buffered to answer a 100-continue locally
+	DefaultMax100ContinueBodyBytes = 10485760
+	// DefaultCacheKeyComponentsPolicy defines how requests exceeding max_cache_key_components are handled
+	DefaultCacheKeyComponentsPolicy = "reject"
+	// DefaultCacheCompression defines the codec used to compress response bodies written to the cache
+	DefaultCacheCompression = "none"
+	// DefaultBrotliQuality defines the brotli quality level used to compress responses to clients,
+	// which favors speed, as responses are compressed as they are served
+	DefaultBrotliQuality = 4
+	// DefaultCacheKeyEncoding defines the encoding of the digest of derived cache keys
+	DefaultCacheKeyEncoding = "hex"
+	// DefaultMaxAsyncCacheWrites is the default limit of background cache writes in progress per origin
+	DefaultMaxAsyncCacheWrites = 64
+	// DefaultConditionalRequestPolicy defines how conditional requests that miss the cache are proxied
+	DefaultConditionalRequestPolicy = "forward"
+	// DefaultCollapsedWaitersPolicy defines how requests exceeding max_collapsed_waiters are handled
+	DefaultCollapsedWaitersPolicy = "proxy"
+	// DefaultCollapsedForwardingTimeoutPolicy defines how requests exceeding collapsed_forwarding_timeout_ms
+	// are handled
+	DefaultCollapsedForwardingTimeoutPolicy = "proxy"
+	// DefaultIdempotencyWindowSecs defines how long responses to requests with an idempotency key are replayed
+	DefaultIdempotencyWindowSecs = 86400
+	// DefaultMaxResponseDataPointsPolicy defines how timeseries responses exceeding
+	// max_response_data_points are handled
+	DefaultMaxResponseDataPointsPolicy = "lttb"
+	// DefaultRangeGuardResponseFormat defines how responses to timeseries requests exceeding
+	// max_query_range_secs are formatted
+	DefaultRangeGuardResponseFormat = "text"
+	// DefaultCanonicalizeCacheKeyHeaders defines whether cache key header names are canonicalized
+	DefaultCanonicalizeCacheKeyHeaders = true
+	// DefaultCacheKeyAuthHeader defines the header hashed into the cache key when cache_key_from_auth_hash is true
+	DefaultCacheKeyAuthHeader = "Authorization"
+	// DefaultTrailingSlashPolicy defines how request paths with a trailing slash are handled
+	DefaultTrailingSlashPolicy = "strict"
+	// DefaultDuplicateParamPolicy defines which values of a repeated query parameter are used
+	DefaultDuplicateParamPolicy = "all"
+	// DefaultCacheByteRanges defines whether partial content responses are cached
+	DefaultCacheByteRanges = true
+	// DefaultByteRangeReassemblyPolicy defines how fetched byte ranges are combined with cached ranges
+	DefaultByteRangeReassemblyPolicy = "merge"
+
+	// DefaultSigningAlgorithm is the default hashing algorithm for upstream request signing
+	DefaultSigningAlgorithm = "sha256"
+	// DefaultSigningHeaderName is the default request header name for upstream request signatures
+	DefaultSigningHeaderName = "X-Trickster-Signature"
+	// DefaultSigningTimestampHeaderName is the default request header name for upstream request signing timestamps
+	DefaultSigningTimestampHeaderName = "X-Trickster-Signature-Timestamp"
 )
 
+// DefaultRetryStatusCodes returns the list of upstream response status codes that are retried
+// when an origin enables retries
+func DefaultRetryStatusCodes() []int {
+	return []int{502, 503, 504}
+}
+
 // DefaultCompressableTypes returns a list of types that Trickster should compress before caching
 func DefaultCompressableTypes() []string {
 	return []string{
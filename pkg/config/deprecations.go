@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// deprecatedField maps a renamed config field to the field that replaced it. Both fields must
+// remain in the config structs, with the same type, until the deprecated name is removed
+type deprecatedField struct {
+	// section is the dotted path of the table holding the fields, where "*" matches each
+	// named entry of a section, such as "origins.*"
+	section string
+	// deprecated is the former name of the field
+	deprecated string
+	// current is the name of the field that replaced it
+	current string
+}
+
+// deprecatedFields is the registry of renamed config fields
+var deprecatedFields = []deprecatedField{
+	{section: "origins.*", deprecated: "value_retention_factor", current: "timeseries_retention_factor"},
+}
+
+// configMetadata is the metadata of a decoded config, which also reports the current fields
+// that were set from their deprecated names as defined
+type configMetadata struct {
+	*toml.MetaData
+	forwarded map[string]bool
+}
+
+// newConfigMetadata returns the configMetadata of the decoded config, or nil if md is nil
+func newConfigMetadata(md *toml.MetaData) *configMetadata {
+	if md == nil {
+		return nil
+	}
+	return &configMetadata{MetaData: md, forwarded: make(map[string]bool)}
+}
+
+// IsDefined returns true if the key, or a field forwarded to it, was set in the config
+func (m *configMetadata) IsDefined(key ...string) bool {
+	return m.MetaData.IsDefined(key...) || m.forwarded[strings.Join(key, ".")]
+}
+
+// forward marks the key, and the tables holding it, as defined
+func (m *configMetadata) forward(key []string) {
+	for i := range key {
+		m.forwarded[strings.Join(key[:i+1], ".")] = true
+	}
+}
+
+// processDeprecatedFields copies the value of each deprecated field that is set in the config
+// forward to its replacement, and warns to use the replacement instead. A deprecated field is
+// ignored when its replacement is also set
+func (c *Config) processDeprecatedFields(metadata *configMetadata) {
+	if metadata == nil {
+		return
+	}
+	for _, df := range deprecatedFields {
+		for _, t := range configTables(reflect.ValueOf(c).Elem(), nil, strings.Split(df.section, ".")) {
+			dk := append(append([]string{}, t.key...), df.deprecated)
+			if !metadata.IsDefined(dk...) {
+				continue
+			}
+			ck := append(append([]string{}, t.key...), df.current)
+			if metadata.IsDefined(ck...) {
+				c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+					"deprecated config field [%s] is ignored, since [%s] is also provided",
+					strings.Join(dk, "."), strings.Join(ck, ".")))
+				continue
+			}
+			from, to := tomlField(t.value, df.deprecated), tomlField(t.value, df.current)
+			if !from.IsValid() || !to.IsValid() || !to.CanSet() || from.Type() != to.Type() {
+				continue
+			}
+			to.Set(from)
+			metadata.forward(ck)
+			c.LoaderWarnings = append(c.LoaderWarnings, fmt.Sprintf(
+				"config field [%s] is deprecated; use [%s] instead",
+				strings.Join(dk, "."), strings.Join(ck, ".")))
+		}
+	}
+}
+
+// configTable is a decoded config struct and the key of its table
+type configTable struct {
+	key   []string
+	value reflect.Value
+}
+
+// configTables returns the structs at the path beneath v, which is a struct reached by key
+func configTables(v reflect.Value, key, path []string) []configTable {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if len(path) == 0 {
+		if v.Kind() != reflect.Struct {
+			return nil
+		}
+		return []configTable{{key: key, value: v}}
+	}
+	if path[0] == "*" {
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		names := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			names = append(names, k.String())
+		}
+		sort.Strings(names)
+		var out []configTable
+		for _, k := range names {
+			out = append(out, configTables(v.MapIndex(reflect.ValueOf(k)),
+				append(append([]string{}, key...), k), path[1:])...)
+		}
+		return out
+	}
+	f := tomlField(v, path[0])
+	if !f.IsValid() {
+		return nil
+	}
+	return configTables(f, append(append([]string{}, key...), path[0]), path[1:])
+}
+
+// tomlField returns the field of the struct with the toml name
+func tomlField(v reflect.Value, name string) reflect.Value {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("toml"), ",")[0] == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcessDeprecatedFields(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    value_retention_factor = 2048", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].TimeseriesRetentionFactor; v != 2048 {
+		t.Errorf("expected %d got %d", 2048, v)
+	}
+	const expected = "config field [origins.test.value_retention_factor] is deprecated; " +
+		"use [origins.test.timeseries_retention_factor] instead"
+	if !hasWarning(c.LoaderWarnings, expected) {
+		t.Errorf("expected warning %s got %v", expected, c.LoaderWarnings)
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    value_retention_factor = 2048\n    timeseries_retention_factor = 512", 1),
+		&Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].TimeseriesRetentionFactor; v != 512 {
+		t.Errorf("expected %d got %d", 512, v)
+	}
+	const ignored = "deprecated config field [origins.test.value_retention_factor] is ignored, " +
+		"since [origins.test.timeseries_retention_factor] is also provided"
+	if !hasWarning(c.LoaderWarnings, ignored) {
+		t.Errorf("expected warning %s got %v", ignored, c.LoaderWarnings)
+	}
+
+	c, _ = emptyTestConfig()
+	if err = c.loadTOMLConfig(toml, &Flags{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range c.LoaderWarnings {
+		if strings.Contains(w, "deprecated") {
+			t.Errorf("unexpected warning %s", w)
+		}
+	}
+}
+
+func TestConfigTables(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	if err := c.loadTOMLConfig(toml, &Flags{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tables := configTables(reflect.ValueOf(c).Elem(), nil, []string{"origins", "*"})
+	if len(tables) != 1 || strings.Join(tables[0].key, ".") != "origins.test" {
+		t.Errorf("unexpected tables %v", tables)
+	}
+	if tables = configTables(reflect.ValueOf(c).Elem(), nil, []string{"frontend"}); len(tables) != 1 {
+		t.Errorf("expected %d got %d", 1, len(tables))
+	}
+	if tables = configTables(reflect.ValueOf(c).Elem(), nil, []string{"invalid", "*"}); len(tables) != 0 {
+		t.Errorf("expected %d got %d", 0, len(tables))
+	}
+}
+
+func hasWarning(warnings []string, expected string) bool {
+	for _, w := range warnings {
+		if w == expected {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Diff returns a human-readable list of the origins, caches, rules and frontends that were
+// added, removed or changed in c versus old, such as `origin "prod": timeout_secs 30 -> 60`.
+// Sensitive values are redacted as they are by String().
+func (c *Config) Diff(old *Config) []string {
+	nc, oc := c.redacted(), &Config{}
+	if old != nil {
+		oc = old.redacted()
+	}
+
+	var out []string
+	out = append(out, diffSection("origin", mapEntries(oc.Origins), mapEntries(nc.Origins))...)
+	out = append(out, diffSection("cache", mapEntries(oc.Caches), mapEntries(nc.Caches))...)
+	out = append(out, diffSection("rule", mapEntries(oc.Rules), mapEntries(nc.Rules))...)
+	out = append(out, diffEntry("frontend", oc.Frontend, nc.Frontend)...)
+	out = append(out, diffSection("frontend", mapEntries(oc.Frontends), mapEntries(nc.Frontends))...)
+	return out
+}
+
+// CacheKeyEncodingWarnings returns a warning for each origin whose cache_key_encoding differs in
+// c versus old, since the origin's existing cache entries will no longer be hit and must age out
+func (c *Config) CacheKeyEncodingWarnings(old *Config) []string {
+	if old == nil {
+		return nil
+	}
+	var out []string
+	for k, oc := range c.Origins {
+		prev, ok := old.Origins[k]
+		if !ok || prev.CacheKeyEncoding == oc.CacheKeyEncoding {
+			continue
+		}
+		out = append(out, fmt.Sprintf("cache_key_encoding for origin config [%s] changed from [%s] to [%s]; "+
+			"existing cache entries will not be hit and will age out", k, prev.CacheKeyEncoding, oc.CacheKeyEncoding))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// mapEntries returns the values of a map of config sections, keyed by their string names
+func mapEntries(m interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		return out
+	}
+	for _, k := range v.MapKeys() {
+		out[k.String()] = v.MapIndex(k).Interface()
+	}
+	return out
+}
+
+// diffSection compares the named entries of a config section, in name order
+func diffSection(kind string, old, cur map[string]interface{}) []string {
+	names := make(map[string]bool, len(old)+len(cur))
+	for k := range old {
+		names[k] = true
+	}
+	for k := range cur {
+		names[k] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for k := range names {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var out []string
+	for _, k := range sorted {
+		label := fmt.Sprintf("%s %q", kind, k)
+		o, inOld := old[k]
+		n, inCur := cur[k]
+		switch {
+		case !inOld:
+			out = append(out, label+": added")
+		case !inCur:
+			out = append(out, label+": removed")
+		default:
+			out = append(out, diffEntry(label, o, n)...)
+		}
+	}
+	return out
+}
+
+// diffEntry compares the serialized settings of two versions of a config section
+func diffEntry(label string, old, cur interface{}) []string {
+	o, n := flattenSection(old), flattenSection(cur)
+	keys := make(map[string]bool, len(o)+len(n))
+	for k := range o {
+		keys[k] = true
+	}
+	for k := range n {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var out []string
+	for _, k := range sorted {
+		ov, inOld := o[k]
+		nv, inCur := n[k]
+		switch {
+		case !inOld:
+			out = append(out, fmt.Sprintf("%s: %s added %s", label, k, nv))
+		case !inCur:
+			out = append(out, fmt.Sprintf("%s: %s removed", label, k))
+		case ov != nv:
+			out = append(out, fmt.Sprintf("%s: %s %s -> %s", label, k, ov, nv))
+		}
+	}
+	return out
+}
+
+// flattenSection serializes a config section and returns its settings as a map of
+// dotted setting names to their formatted values
+func flattenSection(v interface{}) map[string]string {
+	out := make(map[string]string)
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return out
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return out
+	}
+	m := make(map[string]interface{})
+	if _, err := toml.Decode(buf.String(), &m); err != nil {
+		return out
+	}
+	flattenInto(out, "", m)
+	return out
+}
+
+func flattenInto(out map[string]string, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		if t, ok := v.(map[string]interface{}); ok {
+			flattenInto(out, name, t)
+			continue
+		}
+		s := fmt.Sprintf("%v", v)
+		if s == "" {
+			s = `""`
+		}
+		out[name] = s
+	}
+}
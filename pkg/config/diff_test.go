@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func TestDiff(t *testing.T) {
+
+	old, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    timeout_secs = 30", 1)
+
+	c1, _ := emptyTestConfig()
+	if err := c1.loadTOMLConfig(toml, &Flags{}); err != nil {
+		t.Fatal(err)
+	}
+	c2, _ := emptyTestConfig()
+	if err := c2.loadTOMLConfig(toml, &Flags{}); err != nil {
+		t.Fatal(err)
+	}
+	if d := c2.Diff(c1); len(d) != 0 {
+		t.Errorf("expected no differences got %v", d)
+	}
+
+	c2, _ = emptyTestConfig()
+	tml := strings.Replace(toml, "timeout_secs = 30", "timeout_secs = 60", 1)
+	tml = strings.Replace(tml, "[caches]", "[caches]\n    [caches.other]\n", 1)
+	tml = strings.Replace(tml, "[metrics]",
+		"    [origins.test2]\n    origin_type = 'test'\n    origin_url = 'http://2'\n    cache_name = 'other'\n\n[metrics]", 1)
+	tml = strings.Replace(tml, "[frontend]", "[frontend]\nlisten_port = 9999", 1)
+	if err := c2.loadTOMLConfig(tml, &Flags{}); err != nil {
+		t.Fatal(err)
+	}
+	c2.Origins["test"].HealthCheckHeaders = map[string]string{headers.NameAuthorization: "secret"}
+	c2.Caches["default"].Redis.Password = "secret"
+	delete(c2.Origins, "default")
+
+	d := c2.Diff(c1)
+	expected := []string{
+		`origin "test": timeout_secs 30 -> 60`,
+		`cache "other": added`,
+		`origin "test2": added`,
+		`frontend: listen_port 8480 -> 9999`,
+	}
+	s := strings.Join(d, "\n")
+	for _, e := range expected {
+		if !strings.Contains(s, e) {
+			t.Errorf("expected %s in diff %v", e, d)
+		}
+	}
+	if strings.Contains(s, "secret") {
+		t.Errorf("expected sensitive values to be redacted in diff %v", d)
+	}
+
+	d = c1.Diff(c2)
+	if !strings.Contains(strings.Join(d, "\n"), `cache "other": removed`) {
+		t.Errorf("expected removed cache in diff %v", d)
+	}
+
+	d = old.Diff(nil)
+	if len(d) == 0 || !strings.Contains(strings.Join(d, "\n"), `origin "test": added`) {
+		t.Errorf("expected added origin in diff %v", d)
+	}
+}
+
+func TestCacheKeyEncodingWarnings(t *testing.T) {
+
+	c1, toml := emptyTestConfig()
+	if w := c1.CacheKeyEncodingWarnings(nil); len(w) != 0 {
+		t.Errorf("expected no warnings got %v", w)
+	}
+
+	c2, _ := emptyTestConfig()
+	if w := c2.CacheKeyEncodingWarnings(c1); len(w) != 0 {
+		t.Errorf("expected no warnings got %v", w)
+	}
+
+	err := c2.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    cache_key_encoding = 'base64url'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := c2.CacheKeyEncodingWarnings(c1)
+	if len(w) != 1 || !strings.Contains(w[0], "changed from [hex] to [base64url]") {
+		t.Errorf("expected cache_key_encoding warning got %v", w)
+	}
+}
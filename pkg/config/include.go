@@ -0,0 +1,206 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// includeKey is the top-level config key that lists additional config files to load
+const includeKey = "include"
+
+// resolveIncludes returns the TOML document tml, with the files listed in its top-level
+// include directive merged into it. Include paths are relative to the directory of the
+// file at path, and may be glob patterns. Files are merged in order after the including
+// document, so later files override earlier ones, at the level of each named section entry
+// (e.g., each origin or cache), and included files may include further files. If tml has
+// no include directive, it is returned unmodified.
+func (c *Config) resolveIncludes(tml, path string) (string, error) {
+	m := make(map[string]interface{})
+	if _, err := toml.Decode(tml, &m); err != nil {
+		return "", err
+	}
+	if _, ok := m[includeKey]; !ok {
+		return tml, nil
+	}
+
+	r := &includeResolver{c: c, sources: make(map[string]map[string]string)}
+	merged := make(map[string]interface{})
+	if err := r.merge(merged, m, path, nil); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(merged); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// includeResolver merges a config document and its includes
+type includeResolver struct {
+	c *Config
+	// sources maps each section name and entry name to the file that defined it
+	sources map[string]map[string]string
+}
+
+// merge merges the decoded document m, loaded from path, and then its includes, into dst.
+// stack is the chain of files that included path, and is used to detect include cycles.
+func (r *includeResolver) merge(dst, m map[string]interface{}, path string,
+	stack []string) error {
+
+	abs := path
+	if path != "" {
+		var err error
+		if abs, err = filepath.Abs(path); err != nil {
+			return err
+		}
+		for _, p := range stack {
+			if p == abs {
+				return fmt.Errorf("config include cycle detected: %s",
+					strings.Join(append(stack, abs), " -> "))
+			}
+		}
+	}
+	stack = append(stack, abs)
+
+	includes, err := includePaths(m[includeKey], path)
+	if err != nil {
+		return err
+	}
+	delete(m, includeKey)
+
+	r.mergeSections(dst, m, path)
+
+	for _, p := range includes {
+		im, err := r.loadInclude(p)
+		if err != nil {
+			return err
+		}
+		if err = r.merge(dst, im, p, stack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeSections merges the top-level keys of m into dst. Tables are merged entry by entry,
+// so an entry in m replaces the entry of the same name in dst, and other values are replaced.
+func (r *includeResolver) mergeSections(dst, m map[string]interface{}, path string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := m[k]
+		t, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dt, ok := dst[k].(map[string]interface{})
+		if !ok {
+			dt = make(map[string]interface{})
+			dst[k] = dt
+		}
+		if _, ok = r.sources[k]; !ok {
+			r.sources[k] = make(map[string]string)
+		}
+		names := make([]string, 0, len(t))
+		for name := range t {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prev, ok := r.sources[k][name]; ok && k == "origins" {
+				r.c.LoaderWarnings = append(r.c.LoaderWarnings, fmt.Sprintf(
+					"origin config [%s] in [%s] overrides the origin config of the same name in [%s]",
+					name, path, prev))
+			}
+			r.sources[k][name] = path
+			dt[name] = t[name]
+		}
+	}
+}
+
+// loadInclude reads and decodes the included config file at path, which may be TOML or YAML
+func (r *includeResolver) loadInclude(path string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load included config file: %s", err.Error())
+	}
+	tml := string(b)
+	if configFormat(path) == formatYAML {
+		if tml, err = yamlToTOML(tml); err != nil {
+			return nil, fmt.Errorf("invalid included config file %s: %s", path, err.Error())
+		}
+	}
+	tml, warnings := interpolateEnv(tml)
+	r.c.LoaderWarnings = append(r.c.LoaderWarnings, warnings...)
+	m := make(map[string]interface{})
+	if _, err := toml.Decode(tml, &m); err != nil {
+		return nil, fmt.Errorf("invalid included config file %s: %s", path, err.Error())
+	}
+	return m, nil
+}
+
+// includePaths returns the files matched by the include directive value v, relative
+// to the directory of the file at path, in the order they are listed in the directive
+func includePaths(v interface{}, path string) ([]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	l, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid %s directive in %s: expected a list of paths", includeKey, path)
+	}
+	dir := filepath.Dir(path)
+	out := make([]string, 0, len(l))
+	for _, x := range l {
+		p, ok := x.(string)
+		if !ok || p == "" {
+			return nil, fmt.Errorf("invalid %s path [%v] in %s", includeKey, x, path)
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		if !hasGlobMeta(p) {
+			out = append(out, p)
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s path [%s] in %s: %s", includeKey, p, path, err.Error())
+		}
+		// filepath.Glob returns matches in lexical order
+		out = append(out, matches...)
+	}
+	return out, nil
+}
+
+// hasGlobMeta returns true if the path contains any glob pattern characters
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, `*?[`)
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeIncludeTestFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "trickster-include")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+
+	dir := writeIncludeTestFiles(t, map[string]string{
+		"main.toml": `include = ["origins/*.toml", "caches.toml"]
+
+[main]
+server_name = 'main'
+
+[origins]
+    [origins.shared]
+    origin_type = 'rpc'
+    origin_url = 'http://main'
+`,
+		"origins/a.toml": `[origins]
+    [origins.a]
+    origin_type = 'rpc'
+    origin_url = 'http://a'
+    cache_name = 'fs'
+`,
+		"origins/b.toml": `[origins]
+    [origins.shared]
+    origin_type = 'rpc'
+    origin_url = 'http://b'
+`,
+		"caches.toml": `[caches]
+    [caches.fs]
+    cache_type = 'memory'
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	conf, _, err := Load("trickster", "test", []string{"-config", filepath.Join(dir, "main.toml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Main.ServerName != "main" {
+		t.Errorf("expected %s got %s", "main", conf.Main.ServerName)
+	}
+	if len(conf.Origins) != 2 {
+		t.Errorf("expected %d origins got %d", 2, len(conf.Origins))
+	}
+	if oc, ok := conf.Origins["a"]; !ok || oc.CacheName != "fs" {
+		t.Error("expected origin from included file")
+	}
+	if oc, ok := conf.Origins["shared"]; !ok || oc.OriginURL != "http://b" {
+		t.Error("expected later included file to override origin")
+	}
+	if _, ok := conf.Caches["fs"]; !ok {
+		t.Error("expected cache from included file")
+	}
+	var found bool
+	for _, w := range conf.LoaderWarnings {
+		if strings.Contains(w, "origin config [shared]") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected duplicate origin warning got %v", conf.LoaderWarnings)
+	}
+}
+
+func TestLoadConfigIncludesErrors(t *testing.T) {
+
+	dir := writeIncludeTestFiles(t, map[string]string{
+		"a.toml":       "include = ['b.toml']\n",
+		"b.toml":       "include = ['a.toml']\n",
+		"invalid.toml": "include = 'a.toml'\n",
+		"missing.toml": "include = ['nonexistent.toml']\n",
+	})
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		file, expected string
+	}{
+		{"a.toml", "config include cycle detected"},
+		{"invalid.toml", "expected a list of paths"},
+		{"missing.toml", "unable to load included config file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			_, _, err := Load("trickster", "test", []string{"-config", filepath.Join(dir, test.file)})
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("expected error containing %s got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestResolveIncludesNoDirective(t *testing.T) {
+	c := NewConfig()
+	const tml = "[main]\nserver_name = 'test'\n"
+	s, err := c.resolveIncludes(tml, "")
+	if err != nil {
+		t.Error(err)
+	}
+	if s != tml {
+		t.Errorf("expected %s got %s", tml, s)
+	}
+}
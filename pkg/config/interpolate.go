@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// tomlStringType identifies the kind of TOML string being scanned by interpolateEnv
+type tomlStringType int
+
+const (
+	tomlNotString tomlStringType = iota
+	tomlBasicString
+	tomlLiteralString
+	tomlMultilineBasicString
+	tomlMultilineLiteralString
+)
+
+// interpolateEnv expands ${VAR} and ${VAR:-default} tokens that appear within string values
+// of the raw TOML document, and unescapes $$ as a literal $. Tokens outside of strings, such
+// as in comments, are left as-is. A warning is returned for each token whose variable is not
+// set and that provides no default.
+func interpolateEnv(tml string) (string, []string) {
+
+	if !strings.Contains(tml, "$") {
+		return tml, nil
+	}
+
+	var warnings []string
+	var sb strings.Builder
+	sb.Grow(len(tml))
+
+	st := tomlNotString
+	for i := 0; i < len(tml); i++ {
+		ch := tml[i]
+		switch st {
+		case tomlNotString:
+			switch {
+			case ch == '#':
+				// copy comments through to the end of the line
+				j := strings.IndexByte(tml[i:], '\n')
+				if j < 0 {
+					j = len(tml) - i
+				}
+				sb.WriteString(tml[i : i+j])
+				i += j - 1
+				continue
+			case strings.HasPrefix(tml[i:], `"""`):
+				st = tomlMultilineBasicString
+				sb.WriteString(`"""`)
+				i += 2
+				continue
+			case strings.HasPrefix(tml[i:], `'''`):
+				st = tomlMultilineLiteralString
+				sb.WriteString(`'''`)
+				i += 2
+				continue
+			case ch == '"':
+				st = tomlBasicString
+			case ch == '\'':
+				st = tomlLiteralString
+			}
+			sb.WriteByte(ch)
+			continue
+		case tomlBasicString, tomlMultilineBasicString:
+			if ch == '\\' && i+1 < len(tml) {
+				// escape sequences are copied verbatim, so \$ is not interpolated
+				sb.WriteString(tml[i : i+2])
+				i++
+				continue
+			}
+			if st == tomlBasicString && (ch == '"' || ch == '\n') {
+				st = tomlNotString
+			} else if st == tomlMultilineBasicString && strings.HasPrefix(tml[i:], `"""`) {
+				st = tomlNotString
+				sb.WriteString(`"""`)
+				i += 2
+				continue
+			}
+		case tomlLiteralString, tomlMultilineLiteralString:
+			if st == tomlLiteralString && (ch == '\'' || ch == '\n') {
+				st = tomlNotString
+			} else if st == tomlMultilineLiteralString && strings.HasPrefix(tml[i:], `'''`) {
+				st = tomlNotString
+				sb.WriteString(`'''`)
+				i += 2
+				continue
+			}
+		}
+
+		if ch != '$' || st == tomlNotString {
+			sb.WriteByte(ch)
+			continue
+		}
+
+		if strings.HasPrefix(tml[i:], "$$") {
+			sb.WriteByte('$')
+			i++
+			continue
+		}
+
+		n, def, hasDef, l := parseEnvToken(tml[i:])
+		if l == 0 {
+			sb.WriteByte(ch)
+			continue
+		}
+		i += l - 1
+
+		v, ok := os.LookupEnv(n)
+		if hasDef && v == "" {
+			// the default is raw TOML string content, so it is not escaped
+			sb.WriteString(def)
+			continue
+		}
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf(
+				"environment variable [%s] referenced in config is not set", n))
+			continue
+		}
+		if st == tomlBasicString || st == tomlMultilineBasicString {
+			v = escapeTOMLBasicString(v)
+		}
+		sb.WriteString(v)
+	}
+
+	return sb.String(), warnings
+}
+
+// parseEnvToken parses a ${VAR} or ${VAR:-default} token at the beginning of s, and returns
+// the variable name, the default value if present, and the length of the token. A length of
+// 0 is returned if s does not begin with a valid token.
+func parseEnvToken(s string) (string, string, bool, int) {
+	if !strings.HasPrefix(s, "${") {
+		return "", "", false, 0
+	}
+	end := strings.IndexAny(s, "}\n")
+	if end < 0 || s[end] != '}' {
+		return "", "", false, 0
+	}
+	body := s[2:end]
+	name, def, hasDef := body, "", false
+	if j := strings.Index(body, ":-"); j >= 0 {
+		name, def, hasDef = body[:j], body[j+2:], true
+	}
+	if !isEnvVarName(name) {
+		return "", "", false, 0
+	}
+	return name, def, hasDef, end + 1
+}
+
+// isEnvVarName returns true if s is a valid environment variable name
+func isEnvVarName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// escapeTOMLBasicString escapes s for inclusion in a TOML basic string
+func escapeTOMLBasicString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s)
+}
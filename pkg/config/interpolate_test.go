@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateEnv(t *testing.T) {
+
+	os.Setenv("TRK_TEST_URL", "http://prometheus:9090")
+	os.Setenv("TRK_TEST_QUOTED", `a"b\c`)
+	os.Setenv("TRK_TEST_EMPTY", "")
+	os.Unsetenv("TRK_TEST_UNSET")
+	defer func() {
+		os.Unsetenv("TRK_TEST_URL")
+		os.Unsetenv("TRK_TEST_QUOTED")
+		os.Unsetenv("TRK_TEST_EMPTY")
+	}()
+
+	tests := []struct {
+		input, expected string
+		warnings        int
+	}{
+		{`origin_url = "${TRK_TEST_URL}"`, `origin_url = "http://prometheus:9090"`, 0},
+		{`origin_url = '${TRK_TEST_URL}/api'`, `origin_url = 'http://prometheus:9090/api'`, 0},
+		{`x = """${TRK_TEST_URL}"""`, `x = """http://prometheus:9090"""`, 0},
+		{`x = '''${TRK_TEST_URL}'''`, `x = '''http://prometheus:9090'''`, 0},
+		{`x = "${TRK_TEST_QUOTED}"`, `x = "a\"b\\c"`, 0},
+		{`x = "${TRK_TEST_UNSET:-fallback}"`, `x = "fallback"`, 0},
+		{`x = "${TRK_TEST_EMPTY:-fallback}"`, `x = "fallback"`, 0},
+		{`x = "${TRK_TEST_EMPTY}"`, `x = ""`, 0},
+		{`x = "${TRK_TEST_UNSET}"`, `x = ""`, 1},
+		{`x = "$${TRK_TEST_URL}"`, `x = "${TRK_TEST_URL}"`, 0},
+		{`x = "\${TRK_TEST_URL}"`, `x = "\${TRK_TEST_URL}"`, 0},
+		{`x = "$TRK_TEST_URL ${not valid} ${unterminated"`, `x = "$TRK_TEST_URL ${not valid} ${unterminated"`, 0},
+		{"# ${TRK_TEST_URL}\nx = 1", "# ${TRK_TEST_URL}\nx = 1", 0},
+		{`x = "a" # "${TRK_TEST_URL}"`, `x = "a" # "${TRK_TEST_URL}"`, 0},
+		{`x = "\"${TRK_TEST_URL}"`, `x = "\"http://prometheus:9090"`, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			s, w := interpolateEnv(test.input)
+			if s != test.expected {
+				t.Errorf("expected %s got %s", test.expected, s)
+			}
+			if len(w) != test.warnings {
+				t.Errorf("expected %d warnings got %v", test.warnings, w)
+			}
+		})
+	}
+}
+
+func TestLoadTOMLConfigInterpolateEnv(t *testing.T) {
+
+	os.Setenv("TRK_TEST_URL", "http://2")
+	defer os.Unsetenv("TRK_TEST_URL")
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_url = 'http://1'", `origin_url = "${TRK_TEST_URL}"
+    cache_key_prefix = "${TRK_TEST_UNSET}"`, 1)
+	err := c.loadTOMLConfig(toml, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Origins["test"].OriginURL != "http://2" {
+		t.Errorf("expected %s got %s", "http://2", c.Origins["test"].OriginURL)
+	}
+	if len(c.LoaderWarnings) != 1 ||
+		!strings.Contains(c.LoaderWarnings[0], "[TRK_TEST_UNSET]") {
+		t.Errorf("expected unset variable warning got %v", c.LoaderWarnings)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	origins "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// JWTAuthConfig is a collection of configurations for the validation of JWT bearer tokens
+// on requests to the origins served by a frontend's listeners
+type JWTAuthConfig struct {
+	// JWKSURL is the URL of the JSON Web Key Set whose keys sign the tokens
+	JWKSURL string `toml:"jwks_url"`
+	// JWKSRefreshSecs is how long the key set is used before it is fetched again; default is 300
+	JWKSRefreshSecs int `toml:"jwks_refresh_secs"`
+	// LeewaySecs is the clock skew tolerated when checking the expiration and not before times of tokens
+	LeewaySecs int `toml:"leeway_secs"`
+	// Issuers is the list of accepted token issuers. When empty, any issuer is accepted
+	Issuers []string `toml:"issuers"`
+	// Audiences is the list of accepted token audiences. When empty, any audience is accepted
+	Audiences []string `toml:"audiences"`
+	// RequiredClaims is a map of the claims each token must provide to their required values.
+	// Claims mapped to an empty string must be present, but may have any value
+	RequiredClaims map[string]string `toml:"required_claims"`
+	// ClaimHeaders is a map of token claims to the names of the headers that pass their values
+	// to the origin. Any such headers provided by the client are removed
+	ClaimHeaders map[string]string `toml:"claim_headers"`
+
+	// JWKSRefreshInterval is the time.Duration representation of JWKSRefreshSecs
+	JWKSRefreshInterval time.Duration `toml:"-"`
+	// Leeway is the time.Duration representation of LeewaySecs
+	Leeway time.Duration `toml:"-"`
+}
+
+// Clone returns an exact copy of a JWTAuthConfig
+func (ja *JWTAuthConfig) Clone() *JWTAuthConfig {
+	if ja == nil {
+		return nil
+	}
+	ja2 := *ja
+	if ja.Issuers != nil {
+		ja2.Issuers = make([]string, len(ja.Issuers))
+		copy(ja2.Issuers, ja.Issuers)
+	}
+	if ja.Audiences != nil {
+		ja2.Audiences = make([]string, len(ja.Audiences))
+		copy(ja2.Audiences, ja.Audiences)
+	}
+	if ja.RequiredClaims != nil {
+		ja2.RequiredClaims = make(map[string]string, len(ja.RequiredClaims))
+		for k, v := range ja.RequiredClaims {
+			ja2.RequiredClaims[k] = v
+		}
+	}
+	if ja.ClaimHeaders != nil {
+		ja2.ClaimHeaders = make(map[string]string, len(ja.ClaimHeaders))
+		for k, v := range ja.ClaimHeaders {
+			ja2.ClaimHeaders[k] = v
+		}
+	}
+	return &ja2
+}
+
+// processJWTAuthConfig validates a frontend's jwt_auth config and sets its defaults
+func processJWTAuthConfig(section string, ja *JWTAuthConfig) error {
+	if ja == nil {
+		return nil
+	}
+	u, err := url.Parse(ja.JWKSURL)
+	if ja.JWKSURL == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid jwks_url [%s] provided in %s jwt_auth config", ja.JWKSURL, section)
+	}
+	if ja.JWKSRefreshSecs < 0 {
+		return fmt.Errorf("invalid jwks_refresh_secs [%d] provided in %s jwt_auth config",
+			ja.JWKSRefreshSecs, section)
+	}
+	if ja.JWKSRefreshSecs == 0 {
+		ja.JWKSRefreshSecs = d.DefaultJWKSRefreshSecs
+	}
+	if ja.LeewaySecs < 0 {
+		return fmt.Errorf("invalid leeway_secs [%d] provided in %s jwt_auth config", ja.LeewaySecs, section)
+	}
+	for k, v := range ja.ClaimHeaders {
+		if k == "" || strings.TrimSpace(v) == "" || strings.ContainsAny(v, " \t\r\n:") {
+			return fmt.Errorf("invalid claim_headers entry [%s = %s] provided in %s jwt_auth config",
+				k, v, section)
+		}
+		ja.ClaimHeaders[k] = http.CanonicalHeaderKey(v)
+	}
+	ja.JWKSRefreshInterval = time.Duration(ja.JWKSRefreshSecs) * time.Second
+	ja.Leeway = time.Duration(ja.LeewaySecs) * time.Second
+	return nil
+}
+
+// jwtClaimHeaders returns the sorted names of the claim headers that the frontends serving the
+// origin with a jwt_auth config set on its requests
+func (c *Config) jwtClaimHeaders(oc *origins.Options) []string {
+	if oc.JWTAuthDisabled {
+		return nil
+	}
+	fcs := make([]*FrontendConfig, 0, len(c.Frontends)+1)
+	if len(oc.FrontendNames) == 0 {
+		fcs = append(fcs, c.Frontend)
+		for _, fc := range c.Frontends {
+			fcs = append(fcs, fc)
+		}
+	} else {
+		for _, fn := range oc.FrontendNames {
+			if fn == d.DefaultFrontendName {
+				fcs = append(fcs, c.Frontend)
+			} else {
+				fcs = append(fcs, c.Frontends[fn])
+			}
+		}
+	}
+	var h []string
+	for _, fc := range fcs {
+		if fc == nil || fc.JWTAuth == nil {
+			continue
+		}
+		for _, v := range fc.JWTAuth.ClaimHeaders {
+			h = append(h, v)
+		}
+	}
+	if len(h) == 0 {
+		return nil
+	}
+	h = ts.Unique(h)
+	sort.Strings(h)
+	return h
+}
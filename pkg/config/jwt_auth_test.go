@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+func TestProcessJWTAuthConfig(t *testing.T) {
+
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "[frontend]",
+		"[frontend]\n  [frontend.jwt_auth]\n  jwks_url = 'https://issuer.example.com/.well-known/jwks.json'"+
+			"\n  leeway_secs = 30\n  audiences = ['trickster']"+
+			"\n    [frontend.jwt_auth.claim_headers]\n    tenant = 'x-tenant-id'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ja := c.Frontend.JWTAuth
+	if ja.JWKSRefreshSecs != d.DefaultJWKSRefreshSecs {
+		t.Errorf("expected %d got %d", d.DefaultJWKSRefreshSecs, ja.JWKSRefreshSecs)
+	}
+	if ja.JWKSRefreshInterval != time.Duration(d.DefaultJWKSRefreshSecs)*time.Second {
+		t.Errorf("expected %d got %s", d.DefaultJWKSRefreshSecs, ja.JWKSRefreshInterval)
+	}
+	if ja.Leeway != 30*time.Second {
+		t.Errorf("expected %s got %s", 30*time.Second, ja.Leeway)
+	}
+	if ja.ClaimHeaders["tenant"] != "X-Tenant-Id" {
+		t.Errorf("expected %s got %s", "X-Tenant-Id", ja.ClaimHeaders["tenant"])
+	}
+	if h := c.Origins["test"].ClaimCacheKeyHeaders; !ts.Equal(h, []string{"X-Tenant-Id"}) {
+		t.Errorf("expected %v got %v", []string{"X-Tenant-Id"}, h)
+	}
+
+	ja2 := ja.Clone()
+	ja2.ClaimHeaders["sub"] = "X-User"
+	ja2.Audiences[0] = "other"
+	if _, ok := ja.ClaimHeaders["sub"]; ok || ja.Audiences[0] != "trickster" {
+		t.Error("expected cloned jwt auth config")
+	}
+
+	tests := []struct {
+		section, expected string
+	}{
+		{"[frontend.jwt_auth]\n  jwks_refresh_secs = 60", "invalid jwks_url []"},
+		{"[frontend.jwt_auth]\n  jwks_url = 'file:///jwks.json'", "invalid jwks_url [file:///jwks.json]"},
+		{"[frontend.jwt_auth]\n  jwks_url = 'http://issuer/'\n  jwks_refresh_secs = -1", "invalid jwks_refresh_secs [-1]"},
+		{"[frontend.jwt_auth]\n  jwks_url = 'http://issuer/'\n  leeway_secs = -1", "invalid leeway_secs [-1]"},
+		{"[frontend.jwt_auth]\n  jwks_url = 'http://issuer/'\n  [frontend.jwt_auth.claim_headers]\n  sub = 'X User'",
+			"invalid claim_headers entry [sub = X User]"},
+	}
+
+	for _, test := range tests {
+		c, _ = emptyTestConfig()
+		err = c.loadTOMLConfig(strings.Replace(toml, "[frontend]", "[frontend]\n  "+test.section, 1), &Flags{})
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected error %s got %v", test.expected, err)
+		}
+	}
+}
+
+func TestProcessJWTAuthDisabledConfig(t *testing.T) {
+	c, toml := emptyTestConfig()
+	err := c.loadTOMLConfig(strings.Replace(toml, "origin_type = 'test'",
+		"origin_type = 'test'\n    jwt_auth_disabled = true", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Origins["test"].JWTAuthDisabled {
+		t.Error("expected jwt auth disabled")
+	}
+}
+
+func TestJWTClaimHeaders(t *testing.T) {
+
+	c := NewConfig()
+	c.Frontend.JWTAuth = &JWTAuthConfig{ClaimHeaders: map[string]string{"tenant": "X-Tenant-Id"}}
+	c.Frontends = map[string]*FrontendConfig{
+		"internal": {JWTAuth: &JWTAuthConfig{ClaimHeaders: map[string]string{"org": "X-Org", "tenant": "X-Tenant-Id"}}},
+		"public":   {},
+	}
+	oc := c.Origins["default"]
+
+	tests := []struct {
+		frontendNames []string
+		disabled      bool
+		expected      []string
+	}{
+		{nil, false, []string{"X-Org", "X-Tenant-Id"}},
+		{[]string{"internal"}, false, []string{"X-Org", "X-Tenant-Id"}},
+		{[]string{"default"}, false, []string{"X-Tenant-Id"}},
+		{[]string{"public"}, false, nil},
+		{nil, true, nil},
+	}
+
+	for i, test := range tests {
+		oc.FrontendNames = test.frontendNames
+		oc.JWTAuthDisabled = test.disabled
+		if h := c.jwtClaimHeaders(oc); !ts.Equal(h, test.expected) {
+			t.Errorf("test %d: expected %v got %v", i, test.expected, h)
+		}
+	}
+}
@@ -23,6 +23,11 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/breaker"
+	origins "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/pool"
+	"github.com/tricksterproxy/trickster/pkg/proxy/queryshape"
 )
 
 // Load returns the Application Configuration, starting with a default config,
@@ -62,7 +67,7 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 		}
 		// If the user has configured their own origins, and one of them is not "default"
 		// then Trickster will not use the auto-created default origin
-		if d.OriginURL == "" {
+		if d.OriginURL == "" && len(d.OriginURLs) == 0 {
 			delete(c.Origins, "default")
 		}
 
@@ -93,29 +98,67 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 			return nil, flags, fmt.Errorf(`missing origin-type for origin "%s"`, k)
 		}
 
-		if o.OriginType != "rule" && o.OriginURL == "" {
+		if o.OriginType != "rule" && o.OriginURL == "" && len(o.OriginURLs) == 0 {
 			return nil, flags, fmt.Errorf(`missing origin-url for origin "%s"`, k)
 		}
 
-		url, err := url.Parse(o.OriginURL)
-		if err != nil {
+		var u *url.URL
+		var err error
+		if len(o.OriginURLs) > 0 {
+			// requests are built for the first replica, and rebased onto the replica selected by the pool
+			urls := make([]*url.URL, len(o.OriginURLs))
+			for i, s := range o.OriginURLs {
+				if urls[i], err = parseOriginURL(s); err != nil {
+					return nil, flags, err
+				}
+			}
+			u = urls[0]
+			o.Pool = pool.New(urls, o.LoadBalancing)
+		} else if u, err = parseOriginURL(o.OriginURL); err != nil {
 			return nil, flags, err
 		}
 
-		if strings.HasSuffix(url.Path, "/") {
-			url.Path = url.Path[0 : len(url.Path)-1]
-		}
-
 		o.Name = k
-		o.Scheme = url.Scheme
-		o.Host = url.Host
-		o.PathPrefix = url.Path
+		o.Scheme = u.Scheme
+		o.Host = u.Host
+		o.PathPrefix = u.Path
 		o.Timeout = time.Duration(o.TimeoutSecs) * time.Second
+		o.CollapsedForwardingTimeout = time.Duration(o.CollapsedForwardingTimeoutMS) * time.Millisecond
+		o.RetryInitialBackoff = time.Duration(o.RetryInitialBackoffMS) * time.Millisecond
+		o.NegativeCacheRevalidateInterval = time.Duration(o.NegativeCacheRevalidateIntervalMS) * time.Millisecond
+		o.RetryMaxBackoff = time.Duration(o.RetryMaxBackoffMS) * time.Millisecond
 		o.BackfillTolerance = time.Duration(o.BackfillToleranceSecs) * time.Second
 		o.TimeseriesRetention = time.Duration(o.TimeseriesRetentionFactor)
 		o.TimeseriesTTL = time.Duration(o.TimeseriesTTLSecs) * time.Second
 		o.FastForwardTTL = time.Duration(o.FastForwardTTLSecs) * time.Second
 		o.MaxTTL = time.Duration(o.MaxTTLSecs) * time.Second
+		o.TTLAsRangeFractionMin = time.Duration(o.TTLAsRangeFractionMinSecs) * time.Second
+		o.IdempotencyWindow = time.Duration(o.IdempotencyWindowSecs) * time.Second
+		o.MaxQueryRange = time.Duration(o.MaxQueryRangeSecs) * time.Second
+
+		o.SetMaintenance(o.MaintenanceMode)
+
+		if o.BreakerErrorThreshold > 0 {
+			o.Breaker = breaker.New(o.BreakerErrorThreshold,
+				time.Duration(o.BreakerOpenDurationSecs)*time.Second, o.BreakerHalfOpenRequests)
+		}
+
+		if o.MaxDistinctQueryShapes > 0 {
+			// a shape that is idle for the timeseries ttl no longer has live cache entries
+			o.QueryShapes = queryshape.New(o.MaxDistinctQueryShapes, o.TimeseriesTTL)
+		}
+
+		if o.MaxConcurrentUpstreamRequests > 0 {
+			o.UpstreamRequestSlots = origins.NewSlots(o.MaxConcurrentUpstreamRequests)
+		}
+
+		if o.MaxCacheWritesPerSec > 0 {
+			o.CacheWriteLimiter = origins.NewRateLimiter(o.MaxCacheWritesPerSec)
+		}
+
+		if o.AsyncCacheWrite {
+			o.AsyncCacheWriteSlots = origins.NewSlots(o.MaxAsyncCacheWrites)
+		}
 
 		if o.CompressableTypeList != nil {
 			o.CompressableTypes = make(map[string]bool)
@@ -156,7 +199,18 @@ func Load(applicationName string, applicationVersion string, arguments []string)
 	for _, c := range c.Caches {
 		c.Index.FlushInterval = time.Duration(c.Index.FlushIntervalSecs) * time.Second
 		c.Index.ReapInterval = time.Duration(c.Index.ReapIntervalSecs) * time.Second
+		c.Index.ReapIOPause = time.Duration(c.Index.ReapIOPauseMS) * time.Millisecond
 	}
 
 	return c, flags, nil
 }
+
+// parseOriginURL parses an origin's upstream URL, removing any trailing slash from its path
+func parseOriginURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u, nil
+}
@@ -19,6 +19,7 @@ package config
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -701,3 +702,34 @@ func TestLoadEmptyArgs(t *testing.T) {
 		t.Error("expected error: no valid origins configured")
 	}
 }
+
+func TestLoadConfigurationOriginURLs(t *testing.T) {
+
+	dir := writeIncludeTestFiles(t, map[string]string{
+		"main.toml": `[origins]
+    [origins.pool]
+    origin_type = 'rpc'
+    origin_urls = [ 'http://p1:9090/prefix/', 'https://p2:9090/prefix' ]
+    load_balancing = 'Random'
+`,
+	})
+	defer os.RemoveAll(dir)
+
+	c, _, err := Load("trickster-test", "0", []string{"-config", filepath.Join(dir, "main.toml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oc := c.Origins["pool"]
+	if oc.Scheme != "http" || oc.Host != "p1:9090" || oc.PathPrefix != "/prefix" {
+		t.Errorf("expected %s got %s://%s%s", "http://p1:9090/prefix", oc.Scheme, oc.Host, oc.PathPrefix)
+	}
+	if oc.LoadBalancing != "random" {
+		t.Errorf("expected %s got %s", "random", oc.LoadBalancing)
+	}
+	if oc.Pool == nil || len(oc.Pool.Backends()) != 2 {
+		t.Fatal("expected pool of 2 backends")
+	}
+	if u := oc.Pool.Backends()[1].URL.String(); u != "https://p2:9090/prefix" {
+		t.Errorf("expected %s got %s", "https://p2:9090/prefix", u)
+	}
+}
@@ -35,6 +35,12 @@ type Options struct {
 	// This prevents a bad actor from stating the config file with millions of concurrent requets
 	// The rate limit does not apply to SIGHUP-based reload requests
 	RateLimitSecs int `toml:"rate_limit_secs"`
+	// QueueReloads, when true, queues a single pending reload when one is requested while another
+	// is in progress, rather than skipping it. Additional requests made while one is queued are skipped
+	QueueReloads bool `toml:"queue_reloads"`
+	// DebounceMS provides the duration to wait after detecting a config file modification before
+	// checking that its last modified time is unchanged, so that partially-written files are not loaded
+	DebounceMS int `toml:"debounce_ms"`
 }
 
 // NewOptions returns a new Options references with Default Values set
@@ -45,5 +51,6 @@ func NewOptions() *Options {
 		HandlerPath:      defaults.DefaultReloadHandlerPath,
 		DrainTimeoutSecs: defaults.DefaultDrainTimeoutSecs,
 		RateLimitSecs:    defaults.DefaultRateLimitSecs,
+		DebounceMS:       defaults.DefaultReloadDebounceMS,
 	}
 }
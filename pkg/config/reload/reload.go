@@ -23,9 +23,70 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/config"
 	"github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 )
 
 // ReloaderFunc describes a function that loads and applies a Trickster config at startup,
 // or gracefully over an existing running Config
 type ReloaderFunc func(oldConf *config.Config, wg *sync.WaitGroup, log *log.Logger,
 	caches map[string]cache.Cache, args []string, errorsFatal bool) error
+
+// running and queued are process-wide semaphores, since each reload replaces the
+// running Config, and with it the Config-level ReloaderLock
+var running = make(chan struct{}, 1)
+var queued = make(chan struct{}, 1)
+
+// current is the running Config and the resources it was applied with. A reload that was
+// queued behind another is applied over current rather than the Config it was requested
+// against, since that Config has since been replaced
+var current struct {
+	sync.Mutex
+	conf   *config.Config
+	log    *log.Logger
+	caches map[string]cache.Cache
+}
+
+// SetRunning records conf, and the logger and caches it was applied with, as the running Config
+func SetRunning(conf *config.Config, log *log.Logger, caches map[string]cache.Cache) {
+	current.Lock()
+	current.conf, current.log, current.caches = conf, log, caches
+	current.Unlock()
+}
+
+// Running returns the running Config and the logger and caches it was applied with. When no
+// running Config has been recorded, the provided values are returned
+func Running(conf *config.Config, log *log.Logger,
+	caches map[string]cache.Cache) (*config.Config, *log.Logger, map[string]cache.Cache) {
+	current.Lock()
+	defer current.Unlock()
+	if current.conf == nil {
+		return conf, log, caches
+	}
+	return current.conf, current.log, current.caches
+}
+
+// Serialize runs f only if no other reload is in progress, and reports whether f was run.
+// When queue is true and a reload is in progress, f is queued to run once the in-progress
+// reload completes, unless another reload is already queued. Skipped reloads are counted
+// in the reloads skipped metric
+func Serialize(queue bool, f func()) bool {
+	select {
+	case running <- struct{}{}:
+	default:
+		if !queue {
+			metrics.ReloadsSkipped.Inc()
+			return false
+		}
+		select {
+		case queued <- struct{}{}:
+		default:
+			metrics.ReloadsSkipped.Inc()
+			return false
+		}
+		running <- struct{}{}
+		<-queued
+	}
+	defer func() { <-running }()
+	f()
+	return true
+}
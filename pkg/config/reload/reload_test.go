@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reload
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+)
+
+func TestSerialize(t *testing.T) {
+
+	if !Serialize(false, func() {}) {
+		t.Error("expected reload to run")
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Serialize(false, func() {
+			close(started)
+			<-release
+		})
+	}()
+	<-started
+
+	// a reload is in progress, so an unqueued reload is skipped
+	if Serialize(false, func() { t.Error("unexpected reload") }) {
+		t.Error("expected reload to be skipped")
+	}
+
+	// a queued reload runs once the in-progress reload completes
+	var queuedRan bool
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if !Serialize(true, func() { queuedRan = true }) {
+			t.Error("expected queued reload to run")
+		}
+	}()
+
+	// wait for the reload to be queued, after which any further reload is skipped
+	for len(queued) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if Serialize(true, func() { t.Error("unexpected reload") }) {
+		t.Error("expected reload to be skipped")
+	}
+
+	close(release)
+	wg.Wait()
+	if !queuedRan {
+		t.Error("expected queued reload to run")
+	}
+}
+
+func TestRunning(t *testing.T) {
+
+	requested := config.NewConfig()
+	if c, _, _ := Running(requested, nil, nil); c != requested {
+		t.Error("expected the requested config when no running config is recorded")
+	}
+
+	running := config.NewConfig()
+	SetRunning(running, nil, nil)
+	defer SetRunning(nil, nil, nil)
+	if c, _, _ := Running(requested, nil, nil); c != running {
+		t.Error("expected the running config")
+	}
+}
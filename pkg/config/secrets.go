@@ -0,0 +1,64 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// readSecretFile returns the contents of the secret file at path, with trailing whitespace
+// removed, and records the file's last modified date so that IsStale detects its rotation
+func (c *Config) readSecretFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if c.Main != nil {
+		if c.Main.secretFiles == nil {
+			c.Main.secretFiles = make(map[string]time.Time)
+		}
+		c.Main.secretFiles[path] = fileLastModified(path)
+	}
+	return strings.TrimRight(string(b), " \t\r\n"), nil
+}
+
+// secretFilesModified returns true if any secret file read by the config has been modified
+// or removed since it was read
+func (c *Config) secretFilesModified() bool {
+	if c.Main == nil {
+		return false
+	}
+	for path, t := range c.Main.secretFiles {
+		if fileLastModified(path) != t {
+			return true
+		}
+	}
+	return false
+}
+
+// fileLastModified returns the last modified date of the file at path, or the zero time
+// if the file cannot be read
+func fileLastModified(path string) time.Time {
+	file, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return file.ModTime()
+}
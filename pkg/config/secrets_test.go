@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedisPasswordFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "trickster-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "redis-password")
+	if err = ioutil.WriteFile(path, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, toml := emptyTestConfig()
+	toml = strings.Replace(toml, "origin_type = 'test'", "origin_type = 'test'\n    cache_name = 'test'", 1)
+	toml = strings.Replace(toml, "[caches.test.index]", "cache_type = 'redis'\n        [caches.test.index]", 1)
+	err = c.loadTOMLConfig(strings.Replace(toml, "[caches.test.redis]",
+		"[caches.test.redis]\n        password_file = '"+path+"'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Caches["test"].Redis.Password; v != "s3cret" {
+		t.Errorf("expected %s got %s", "s3cret", v)
+	}
+	if c.secretFilesModified() {
+		t.Error("expected unmodified secret files")
+	}
+
+	// rotating the secret file makes the config stale
+	later := time.Now().Add(time.Minute)
+	if err = os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !c.secretFilesModified() {
+		t.Error("expected modified secret files")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "[caches.test.redis]",
+		"[caches.test.redis]\n        password = 'x'\n        password_file = '"+path+"'", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for both password and password_file")
+	}
+
+	c, _ = emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "[caches.test.redis]",
+		"[caches.test.redis]\n        password_file = '"+filepath.Join(dir, "missing")+"'", 1), &Flags{})
+	if err == nil {
+		t.Error("expected error for missing password_file")
+	}
+}
+
+func TestTLSPasswordFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "trickster-secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tls-password")
+	if err = ioutil.WriteFile(path, []byte("s3cret \r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, toml := emptyTestConfig()
+	err = c.loadTOMLConfig(strings.Replace(toml, "origin_url = 'http://1'",
+		"origin_url = 'http://1'\n    [origins.test.tls]\n    password_file = '"+path+"'", 1), &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := c.Origins["test"].TLS.Password; v != "s3cret" {
+		t.Errorf("expected %s got %s", "s3cret", v)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// LoadPhaseTiming is the time spent in a single phase of loading the configuration
+type LoadPhaseTiming struct {
+	Phase    string
+	Duration time.Duration
+}
+
+// loadTimer records the duration of consecutive config load phases
+type loadTimer struct {
+	start   time.Time
+	last    time.Time
+	timings []LoadPhaseTiming
+}
+
+func newLoadTimer() *loadTimer {
+	now := time.Now()
+	return &loadTimer{start: now, last: now, timings: make([]LoadPhaseTiming, 0, 8)}
+}
+
+// reset excludes the time since the previous mark from the next phase
+func (lt *loadTimer) reset() {
+	lt.last = time.Now()
+}
+
+// mark records the time elapsed since the previous mark as the named phase
+func (lt *loadTimer) mark(phase string) {
+	now := time.Now()
+	lt.observe(phase, now.Sub(lt.last))
+	lt.last = now
+}
+
+// finish records the total load duration and returns all recorded timings
+func (lt *loadTimer) finish() []LoadPhaseTiming {
+	lt.observe("total", time.Since(lt.start))
+	return lt.timings
+}
+
+func (lt *loadTimer) observe(phase string, d time.Duration) {
+	lt.timings = append(lt.timings, LoadPhaseTiming{Phase: phase, Duration: d})
+	metrics.ConfigLoadPhaseDuration.WithLabelValues(phase).Observe(d.Seconds())
+}
+
+// LoadTimingsString returns the phase timings of the most recent config load as TOML comments
+func (c *Config) LoadTimingsString() string {
+	if len(c.LoadTimings) == 0 {
+		return ""
+	}
+	sb := &strings.Builder{}
+	sb.WriteString("\n# config load phase timings\n")
+	for _, t := range c.LoadTimings {
+		sb.WriteString(fmt.Sprintf("#   %s: %s\n", t.Phase, t.Duration))
+	}
+	return sb.String()
+}
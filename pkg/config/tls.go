@@ -18,6 +18,9 @@ package config
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
 
 	origins "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 )
@@ -53,3 +56,81 @@ func (c *Config) TLSCertConfig() (*tls.Config, error) {
 	return tlsConfig, nil
 
 }
+
+// FrontendTLSConfig returns the crypto/tls configuration object for the provided frontend's
+// tls listener. When the frontend has its own certificate configured, only that certificate is
+// served, and client certificates are verified if a client CA is configured. Otherwise, the
+// name-bound certs derived from the origin configs are served. Client certificates are also
+// verified against the frontend's client_ca_paths, and required when require_client_cert is set
+func (c *Config) FrontendTLSConfig(fc *FrontendConfig) (*tls.Config, error) {
+	if fc == nil || !fc.ServeTLS {
+		return nil, nil
+	}
+	var tlsConfig *tls.Config
+	var err error
+	if fc.TLSFullChainCertPath == "" {
+		tlsConfig, err = c.TLSCertConfig()
+	} else {
+		tlsConfig, err = listenerTLSConfig(fc.TLSFullChainCertPath, fc.TLSPrivateKeyPath, fc.TLSClientCACertPath)
+	}
+	if err != nil || tlsConfig == nil || len(fc.ClientCAPaths) == 0 {
+		return tlsConfig, err
+	}
+	pool := tlsConfig.ClientCAs
+	if pool == nil {
+		pool = x509.NewCertPool()
+	}
+	for _, path := range fc.ClientCAPaths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.New("no valid certificates found in client_ca_paths " + path)
+		}
+	}
+	tlsConfig.ClientCAs = pool
+	if fc.RequireClientCert || fc.TLSClientCACertPath != "" {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// MetricsTLSConfig returns the crypto/tls configuration object for the metrics listener,
+// or nil if the metrics listener does not serve TLS
+func (c *Config) MetricsTLSConfig() (*tls.Config, error) {
+	if c.Metrics == nil || !c.Metrics.ServeTLS {
+		return nil, nil
+	}
+	return listenerTLSConfig(c.Metrics.TLSFullChainCertPath, c.Metrics.TLSPrivateKeyPath,
+		c.Metrics.TLSClientCACertPath)
+}
+
+// listenerTLSConfig returns a crypto/tls configuration object serving the provided certificate,
+// which verifies client certificates when a client CA is provided
+func listenerTLSConfig(certPath, keyPath, clientCAPath string) (*tls.Config, error) {
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"h2"}}
+
+	if clientCAPath != "" {
+		b, err := ioutil.ReadFile(clientCAPath)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.New("no valid certificates found in tls_client_ca_cert_path " +
+				clientCAPath)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
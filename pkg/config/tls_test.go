@@ -17,6 +17,7 @@
 package config
 
 import (
+	"crypto/tls"
 	"testing"
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
@@ -94,6 +95,180 @@ func TestTLSCertConfig(t *testing.T) {
 
 }
 
+func TestFrontendTLSConfig(t *testing.T) {
+
+	config := NewConfig()
+
+	fc := &FrontendConfig{}
+	n, err := config.FrontendTLSConfig(fc)
+	if n != nil || err != nil {
+		t.Error("expected nil config and error for frontend not serving tls")
+	}
+
+	tls01, closer01, err01 := tlsConfig("")
+	if closer01 != nil {
+		defer closer01()
+	}
+	if err01 != nil {
+		t.Fatal(err01)
+	}
+
+	fc.TLSFullChainCertPath = tls01.FullChainCertPath
+	fc.TLSPrivateKeyPath = tls01.PrivateKeyPath
+	if err = fc.validateTLS("test", false); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err = config.FrontendTLSConfig(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Certificates) != 1 || n.ClientCAs != nil {
+		t.Error("expected frontend certificate without client verification")
+	}
+
+	// the test cert is self-signed, so it can serve as its own client CA
+	fc.TLSClientCACertPath = tls01.FullChainCertPath
+	n, err = config.FrontendTLSConfig(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ClientCAs == nil || n.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Error("expected client certificate verification")
+	}
+
+	fc.TLSClientCACertPath = tls01.PrivateKeyPath
+	_, err = config.FrontendTLSConfig(fc)
+	if err == nil {
+		t.Error("expected error for invalid client CA file")
+	}
+
+	fc.TLSPrivateKeyPath = ""
+	if err = fc.validateTLS("test", false); err == nil {
+		t.Error("expected error for missing private key path")
+	}
+}
+
+func TestFrontendTLSConfigClientCAPaths(t *testing.T) {
+
+	config := NewConfig()
+
+	tls01, closer01, err01 := tlsConfig("")
+	if closer01 != nil {
+		defer closer01()
+	}
+	if err01 != nil {
+		t.Fatal(err01)
+	}
+
+	fc := &FrontendConfig{RequireClientCert: true}
+	if err := fc.validateTLS("test", false); err == nil {
+		t.Error("expected error for require_client_cert without a tls listener")
+	}
+
+	fc.TLSFullChainCertPath = tls01.FullChainCertPath
+	fc.TLSPrivateKeyPath = tls01.PrivateKeyPath
+	if err := fc.validateTLS("test", false); err == nil {
+		t.Error("expected error for require_client_cert without client_ca_paths")
+	}
+
+	fc.ClientCAPaths = []string{tls01.FullChainCertPath + ".invalid"}
+	if err := fc.validateTLS("test", false); err == nil {
+		t.Error("expected error for unreadable client_ca_paths")
+	}
+
+	// the test cert is self-signed, so it can serve as its own client CA
+	fc.ClientCAPaths = []string{tls01.FullChainCertPath}
+	if err := fc.validateTLS("test", false); err != nil {
+		t.Fatal(err)
+	}
+	n, err := config.FrontendTLSConfig(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ClientCAs == nil || n.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Error("expected required client certificate verification")
+	}
+
+	fc.RequireClientCert = false
+	n, err = config.FrontendTLSConfig(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ClientCAs == nil || n.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Error("expected optional client certificate verification")
+	}
+
+	// frontends serving the origins' certificates also verify client certificates
+	config.Frontend.ServeTLS = true
+	config.Origins["default"].TLS = tls01
+	fc.TLSFullChainCertPath, fc.TLSPrivateKeyPath = "", ""
+	if err := fc.validateTLS("test", true); err != nil {
+		t.Fatal(err)
+	}
+	n, err = config.FrontendTLSConfig(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ClientCAs == nil || n.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Error("expected optional client certificate verification")
+	}
+
+	fc.ClientCAPaths = []string{tls01.PrivateKeyPath}
+	if _, err = config.FrontendTLSConfig(fc); err == nil {
+		t.Error("expected error for invalid client_ca_paths file")
+	}
+}
+
+func TestMetricsTLSConfig(t *testing.T) {
+
+	config := NewConfig()
+
+	n, err := config.MetricsTLSConfig()
+	if n != nil || err != nil {
+		t.Error("expected nil config and error for metrics not serving tls")
+	}
+
+	tls01, closer01, err01 := tlsConfig("")
+	if closer01 != nil {
+		defer closer01()
+	}
+	if err01 != nil {
+		t.Fatal(err01)
+	}
+
+	config.Metrics.TLSFullChainCertPath = tls01.FullChainCertPath
+	config.Metrics.TLSPrivateKeyPath = tls01.PrivateKeyPath
+	if err = config.validateTLSConfigs(); err != nil {
+		t.Fatal(err)
+	}
+	if !config.Metrics.ServeTLS {
+		t.Error("expected metrics listener to serve tls")
+	}
+
+	n, err = config.MetricsTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Certificates) != 1 || n.ClientCAs != nil {
+		t.Error("expected metrics certificate without client verification")
+	}
+
+	config.Metrics.TLSClientCACertPath = tls01.FullChainCertPath
+	n, err = config.MetricsTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.ClientCAs == nil || n.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Error("expected client certificate verification")
+	}
+
+	config.Metrics.TLSPrivateKeyPath = ""
+	if err = config.validateTLSConfigs(); err == nil {
+		t.Error("expected error for missing private key path")
+	}
+}
+
 func tlsConfig(condition string) (*options.Options, func(), error) {
 
 	kf, cf, closer, err := tlstest.GetTestKeyAndCertFiles(condition)
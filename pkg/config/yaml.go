@@ -0,0 +1,186 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Config file formats, as determined by the config file's extension
+const (
+	formatTOML = "toml"
+	formatYAML = "yaml"
+)
+
+// configFormat returns the format of the config file at path: yaml for
+// .yaml and .yml files, and toml otherwise
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return formatYAML
+	}
+	return formatTOML
+}
+
+// loadYAMLConfig loads application configuration from a YAML-formatted document. The
+// document is translated to TOML and loaded by loadTOMLConfig, so that the same struct
+// tags apply to both formats, and the keys present in the YAML document are reported by
+// the TOML metadata exactly as if the configuration had been provided as TOML.
+func (c *Config) loadYAMLConfig(yml string, flags *Flags) error {
+	tml, err := yamlToTOML(yml)
+	if err != nil {
+		c.setDefaults(&toml.MetaData{})
+		return err
+	}
+	err = c.loadTOMLConfig(tml, flags)
+	if err == nil {
+		c.Main.configFormat = formatYAML
+	}
+	return err
+}
+
+// yamlToTOML translates a YAML document into an equivalent TOML document
+func yamlToTOML(yml string) (string, error) {
+	m := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(yml), &m); err != nil {
+		return "", err
+	}
+	v, err := normalizeYAML(m, configType)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// tomlToYAML translates a TOML document into an equivalent YAML document
+func tomlToYAML(tml string) (string, error) {
+	m := make(map[string]interface{})
+	if _, err := toml.Decode(tml, &m); err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// configType is the type into which translated YAML documents are ultimately decoded
+var configType = reflect.TypeOf((*Config)(nil)).Elem()
+
+// normalizeYAML converts the generic maps produced by the YAML decoder, whose keys may be
+// of any type (e.g., the integer status codes of a negative cache config), into maps with
+// string keys that can be encoded as TOML. Keys with null values are treated as absent.
+// Since YAML, unlike TOML, does not distinguish 1 from 1.0, or 1 from '1', scalars are
+// converted to the kind of the field t into which they will be decoded, when known.
+func normalizeYAML(v interface{}, t reflect.Type) (interface{}, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, v2 := range x {
+			if v2 == nil {
+				continue
+			}
+			key := fmt.Sprint(k)
+			n, err := normalizeYAML(v2, memberType(t, key))
+			if err != nil {
+				return nil, err
+			}
+			m[key] = n
+		}
+		return m, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, v2 := range x {
+			if v2 == nil {
+				continue
+			}
+			n, err := normalizeYAML(v2, memberType(t, k))
+			if err != nil {
+				return nil, err
+			}
+			m[k] = n
+		}
+		return m, nil
+	case []interface{}:
+		var et reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			et = t.Elem()
+		}
+		l := make([]interface{}, 0, len(x))
+		for _, v2 := range x {
+			if v2 == nil {
+				return nil, fmt.Errorf("invalid null list value in yaml config")
+			}
+			n, err := normalizeYAML(v2, et)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, n)
+		}
+		return l, nil
+	case int:
+		if t != nil {
+			switch t.Kind() {
+			case reflect.Float32, reflect.Float64:
+				return float64(x), nil
+			case reflect.String:
+				return fmt.Sprint(x), nil
+			}
+		}
+	case float64, bool:
+		if t != nil && t.Kind() == reflect.String {
+			return fmt.Sprint(x), nil
+		}
+	}
+	return v, nil
+}
+
+// memberType returns the type of the member of t that is decoded from key,
+// or nil if it is not known
+func memberType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("toml"), ",")[0]
+			if name == key || (name == "" && strings.EqualFold(f.Name, key)) {
+				return f.Type
+			}
+		}
+	}
+	return nil
+}
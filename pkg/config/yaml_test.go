@@ -0,0 +1,219 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tlstest "github.com/tricksterproxy/trickster/pkg/util/testing/tls"
+)
+
+const testYAML = `
+main:
+  instance_id: 3
+origins:
+  test:
+    origin_type: test
+    origin_url: http://1
+    timeout_secs: 90
+    revalidation_factor: 2
+    cache_key_prefix: 123
+    negative_cache:
+      404: 10
+    paths:
+      root:
+        path: /
+        match_type: prefix
+        handler: proxycache
+        methods: [ GET, POST ]
+        cache_key_params: [ query ]
+    tls:
+      insecure_skip_verify: true
+      server_name:
+`
+
+const testYAMLRoundTrip = `
+origins:
+  test:
+    origin_type: test
+    origin_url: http://1
+    revalidation_factor: 2
+    negative_cache:
+      404: 10
+`
+
+func TestConfigFormat(t *testing.T) {
+	tests := map[string]string{
+		"trickster.conf": formatTOML,
+		"trickster.toml": formatTOML,
+		"trickster.yaml": formatYAML,
+		"TRICKSTER.YML":  formatYAML,
+		"trickster":      formatTOML,
+	}
+	for path, expected := range tests {
+		if v := configFormat(path); v != expected {
+			t.Errorf("expected %s for %s got %s", expected, path, v)
+		}
+	}
+}
+
+func TestLoadYAMLConfig(t *testing.T) {
+
+	c := NewConfig()
+	err := c.loadYAMLConfig(testYAML, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oc, ok := c.Origins["test"]
+	if !ok {
+		t.Fatal("expected test origin")
+	}
+	if c.Main.InstanceID != 3 {
+		t.Errorf("expected %d got %d", 3, c.Main.InstanceID)
+	}
+	if oc.TimeoutSecs != 90 {
+		t.Errorf("expected %d got %d", 90, oc.TimeoutSecs)
+	}
+	if oc.RevalidationFactor != 2 {
+		t.Errorf("expected %f got %f", 2.0, oc.RevalidationFactor)
+	}
+	if oc.CacheKeyPrefix != "123" {
+		t.Errorf("expected %s got %s", "123", oc.CacheKeyPrefix)
+	}
+	if v := oc.NegativeCacheName; v != "default" {
+		t.Errorf("expected %s got %s", "default", v)
+	}
+	if _, ok := c.NegativeCacheConfigs["default"]; !ok {
+		t.Error("expected default negative cache config")
+	}
+	if !oc.TLS.InsecureSkipVerify || oc.TLS.ServerName != "" {
+		t.Error("expected tls options to be loaded")
+	}
+	p, ok := oc.Paths["/-GET-POST"]
+	if !ok {
+		t.Fatal("expected root path")
+	}
+	if p.HandlerName != "proxycache" || len(p.CacheKeyParams) != 1 {
+		t.Error("expected root path options to be loaded")
+	}
+	// only the keys present in the yaml document are customized
+	if len(p.Custom) != 5 {
+		t.Errorf("expected %d got %d: %v", 5, len(p.Custom), p.Custom)
+	}
+	if c.Main.configFormat != formatYAML {
+		t.Errorf("expected %s got %s", formatYAML, c.Main.configFormat)
+	}
+
+	// String prints a yaml-loaded config as yaml, which loads to the same config
+	c = NewConfig()
+	err = c.loadYAMLConfig(testYAMLRoundTrip, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.String()
+	c2 := NewConfig()
+	err = c2.loadYAMLConfig(s, &Flags{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2 := c2.String(); s2 != s {
+		t.Errorf("expected round-tripped config to match:\n%s\n%s", s, s2)
+	}
+
+	c = NewConfig()
+	err = c.loadYAMLConfig("origins: [", &Flags{})
+	if err == nil {
+		t.Error("expected error for invalid yaml")
+	}
+
+	c = NewConfig()
+	err = c.loadYAMLConfig("origins:\n  test:\n    hosts: [ a, null ]\n", &Flags{})
+	if err == nil {
+		t.Error("expected error for null list value")
+	}
+}
+
+func TestLoadYAMLConfigFile(t *testing.T) {
+
+	kb, cb, _ := tlstest.GetTestKeyAndCert(false)
+	const certfile = "../../testdata/test.02.cert.pem"
+	const keyfile = "../../testdata/test.02.key.pem"
+	err := ioutil.WriteFile(certfile, cb, 0600)
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer os.Remove(certfile)
+	}
+	err = ioutil.WriteFile(keyfile, kb, 0600)
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer os.Remove(keyfile)
+	}
+
+	const tomlPath = "../../testdata/test.full.02.conf"
+	b, err := ioutil.ReadFile(tomlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yml, err := tomlToYAML(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "trickster-yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	yamlPath := filepath.Join(dir, "test.full.yaml")
+	err = ioutil.WriteFile(yamlPath, []byte(yml), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1, _, err := Load("trickster-test", "0", []string{"-config", tomlPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, _, err := Load("trickster-test", "0", []string{"-config", yamlPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c2.Origins["test"].Timeout != c1.Origins["test"].Timeout ||
+		c2.Origins["test"].Timeout == 0 {
+		t.Errorf("expected %s got %s", c1.Origins["test"].Timeout, c2.Origins["test"].Timeout)
+	}
+	if c2.Caches["test"].Index.ReapInterval != 4*time.Second {
+		t.Errorf("expected %s got %s", 4*time.Second, c2.Caches["test"].Index.ReapInterval)
+	}
+
+	// the yaml config loads identically to the toml config it was translated from
+	expected, err := tomlToYAML(c1.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := c2.String(); s != expected {
+		t.Errorf("expected yaml config to match toml config:\n%s\n%s", expected, s)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package acl evaluates the CIDR-based access control of requests
+package acl
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/acl/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// Permits returns true if the client of the request is permitted by the Options. Deny rules
+// take precedence over Allow rules, and an empty Allow list permits any client not denied
+func Permits(o *options.Options, r *http.Request) bool {
+	if o == nil {
+		return true
+	}
+	ip := ClientIP(r, o.TrustedProxyNetworks)
+	if ip == nil {
+		return len(o.AllowNetworks) == 0 && len(o.DenyNetworks) == 0
+	}
+	if contains(o.DenyNetworks, ip) {
+		return false
+	}
+	return len(o.AllowNetworks) == 0 || contains(o.AllowNetworks, ip)
+}
+
+// ClientIP returns the IP address of the request's client. When the request's remote address
+// is a trusted proxy, its X-Forwarded-For header is walked from the nearest hop, and the first
+// address that is not a trusted proxy is the client
+func ClientIP(r *http.Request, trusted []*net.IPNet) net.IP {
+	ip := parseAddress(r.RemoteAddr)
+	if ip == nil || len(trusted) == 0 || !contains(trusted, ip) {
+		return ip
+	}
+	var hops []string
+	for _, v := range r.Header[headers.NameXForwardedFor] {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseAddress(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !contains(trusted, ip) {
+			break
+		}
+	}
+	return ip
+}
+
+// parseAddress returns the IP address of a host or host:port string
+func parseAddress(s string) net.IP {
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return nil
+}
+
+func contains(networks []*net.IPNet, ip net.IP) bool {
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
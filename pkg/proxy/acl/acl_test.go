@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package acl
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/acl/options"
+)
+
+func TestClientIP(t *testing.T) {
+
+	o := &options.Options{TrustedProxies: []string{"10.0.0.0/24"}}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remoteAddr string
+		xff        []string
+		trusted    bool
+		expected   string
+	}{
+		// X-Forwarded-For is ignored without trusted proxies
+		{"192.168.0.5:4000", []string{"1.2.3.4"}, false, "192.168.0.5"},
+		// and from remote addresses that are not trusted proxies
+		{"192.168.0.5:4000", []string{"1.2.3.4"}, true, "192.168.0.5"},
+		{"10.0.0.1:4000", nil, true, "10.0.0.1"},
+		{"10.0.0.1:4000", []string{"1.2.3.4"}, true, "1.2.3.4"},
+		// a client-provided hop cannot mask the address seen by the trusted proxy
+		{"10.0.0.1:4000", []string{"6.6.6.6, 1.2.3.4"}, true, "1.2.3.4"},
+		{"10.0.0.1:4000", []string{"1.2.3.4, 10.0.0.2", "10.0.0.3"}, true, "1.2.3.4"},
+		{"10.0.0.1:4000", []string{"10.0.0.2"}, true, "10.0.0.2"},
+		{"10.0.0.1:4000", []string{"1.2.3.4, garbage, 10.0.0.2"}, true, "10.0.0.2"},
+		{"[::1]:4000", nil, false, "::1"},
+	}
+
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+		r.RemoteAddr = test.remoteAddr
+		for _, v := range test.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		var trusted = o.TrustedProxyNetworks
+		if !test.trusted {
+			trusted = nil
+		}
+		if ip := ClientIP(r, trusted); ip.String() != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, ip)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+	r.RemoteAddr = "invalid"
+	if ip := ClientIP(r, nil); ip != nil {
+		t.Errorf("expected nil got %s", ip)
+	}
+}
+
+func TestPermits(t *testing.T) {
+
+	if !Permits(nil, httptest.NewRequest("GET", "http://127.0.0.1/", nil)) {
+		t.Error("expected permitted request without an acl")
+	}
+
+	o := &options.Options{
+		Allow: []string{"10.0.0.0/8"},
+		Deny:  []string{"10.1.0.0/16"},
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	denyOnly := &options.Options{Deny: []string{"10.1.0.0/16"}}
+	if err := denyOnly.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		o          *options.Options
+		remoteAddr string
+		expected   bool
+	}{
+		{o, "10.2.0.1:4000", true},
+		{o, "10.1.0.1:4000", false},
+		{o, "192.168.0.1:4000", false},
+		{o, "invalid", false},
+		{denyOnly, "192.168.0.1:4000", true},
+		{denyOnly, "10.1.0.1:4000", false},
+		{&options.Options{}, "invalid", true},
+	}
+
+	for i, test := range tests {
+		r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+		r.RemoteAddr = test.remoteAddr
+		if v := Permits(test.o, r); v != test.expected {
+			t.Errorf("test %d: expected %t got %t", i, test.expected, v)
+		}
+	}
+}
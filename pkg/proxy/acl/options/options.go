@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Options is a collection of configurations for the CIDR-based access control of requests
+type Options struct {
+	// Allow is the list of CIDRs (or IP addresses) from which requests are accepted.
+	// When empty, requests from any address not denied are accepted
+	Allow []string `toml:"allow"`
+	// Deny is the list of CIDRs (or IP addresses) from which requests are rejected,
+	// and takes precedence over Allow
+	Deny []string `toml:"deny"`
+	// TrustedProxies is the list of CIDRs (or IP addresses) of the proxies whose X-Forwarded-For
+	// header identifies the client. When empty, X-Forwarded-For is ignored
+	TrustedProxies []string `toml:"trusted_proxies"`
+
+	// AllowNetworks is the parsed representation of Allow
+	AllowNetworks []*net.IPNet `toml:"-"`
+	// DenyNetworks is the parsed representation of Deny
+	DenyNetworks []*net.IPNet `toml:"-"`
+	// TrustedProxyNetworks is the parsed representation of TrustedProxies
+	TrustedProxyNetworks []*net.IPNet `toml:"-"`
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	return &Options{
+		Allow:                cloneStrings(o.Allow),
+		Deny:                 cloneStrings(o.Deny),
+		TrustedProxies:       cloneStrings(o.TrustedProxies),
+		AllowNetworks:        cloneNetworks(o.AllowNetworks),
+		DenyNetworks:         cloneNetworks(o.DenyNetworks),
+		TrustedProxyNetworks: cloneNetworks(o.TrustedProxyNetworks),
+	}
+}
+
+// Validate verifies the Options and parses their CIDRs
+func (o *Options) Validate() error {
+	var err error
+	if o.AllowNetworks, err = parseNetworks("allow", o.Allow); err != nil {
+		return err
+	}
+	if o.DenyNetworks, err = parseNetworks("deny", o.Deny); err != nil {
+		return err
+	}
+	o.TrustedProxyNetworks, err = parseNetworks("trusted_proxies", o.TrustedProxies)
+	return err
+}
+
+// parseNetworks parses a list of CIDRs, where a bare IP address is a network of one address
+func parseNetworks(name string, list []string) ([]*net.IPNet, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	out := make([]*net.IPNet, 0, len(list))
+	for _, v := range list {
+		s := strings.TrimSpace(v)
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid %s cidr [%s]", name, v)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s cidr [%s]", name, v)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	out := make([]string, len(s))
+	copy(out, s)
+	return out
+}
+
+func cloneNetworks(n []*net.IPNet) []*net.IPNet {
+	if n == nil {
+		return nil
+	}
+	out := make([]*net.IPNet, len(n))
+	for i, v := range n {
+		out[i] = &net.IPNet{IP: append(net.IP(nil), v.IP...), Mask: append(net.IPMask(nil), v.Mask...)}
+	}
+	return out
+}
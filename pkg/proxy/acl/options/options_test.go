@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+
+	o := &Options{
+		Allow:          []string{"10.0.0.0/8", "192.168.1.1"},
+		Deny:           []string{"fd00::/8", "::1"},
+		TrustedProxies: []string{" 10.0.0.10 "},
+	}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(o.AllowNetworks) != 2 || o.AllowNetworks[1].String() != "192.168.1.1/32" {
+		t.Errorf("unexpected allow networks %v", o.AllowNetworks)
+	}
+	if len(o.DenyNetworks) != 2 || o.DenyNetworks[1].String() != "::1/128" {
+		t.Errorf("unexpected deny networks %v", o.DenyNetworks)
+	}
+	if len(o.TrustedProxyNetworks) != 1 || o.TrustedProxyNetworks[0].String() != "10.0.0.10/32" {
+		t.Errorf("unexpected trusted proxy networks %v", o.TrustedProxyNetworks)
+	}
+
+	tests := []struct {
+		o        *Options
+		expected string
+	}{
+		{&Options{Allow: []string{"10.0.0.0/33"}}, "invalid allow cidr [10.0.0.0/33]"},
+		{&Options{Deny: []string{"example.com"}}, "invalid deny cidr [example.com]"},
+		{&Options{TrustedProxies: []string{""}}, "invalid trusted_proxies cidr []"},
+	}
+	for _, test := range tests {
+		err := test.o.Validate()
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("expected error %s got %v", test.expected, err)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+
+	var o *Options
+	if o.Clone() != nil {
+		t.Error("expected nil clone")
+	}
+
+	o = &Options{Allow: []string{"10.0.0.0/8"}}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	o2 := o.Clone()
+	o2.Allow[0] = "172.16.0.0/12"
+	o2.AllowNetworks[0].IP[0] = 172
+	if o.Allow[0] != "10.0.0.0/8" || o.AllowNetworks[0].String() != "10.0.0.0/8" {
+		t.Error("expected cloned options")
+	}
+}
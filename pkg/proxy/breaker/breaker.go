@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package breaker provides a circuit breaker that sheds load on failing origins
+package breaker
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the state of a Breaker
+type State int
+
+const (
+	// StateClosed indicates requests are sent upstream
+	StateClosed = State(iota)
+	// StateOpen indicates requests fail immediately without being sent upstream
+	StateOpen
+	// StateHalfOpen indicates a limited number of probe requests are sent upstream to
+	// determine whether the Breaker should close
+	StateHalfOpen
+)
+
+var stateNames = map[State]string{
+	StateClosed:   "closed",
+	StateOpen:     "open",
+	StateHalfOpen: "half-open",
+}
+
+func (s State) String() string {
+	return stateNames[s]
+}
+
+// Breaker is a circuit breaker that opens after a threshold of consecutive failures, and
+// allows a limited number of probe requests once it has been open for the open duration
+type Breaker struct {
+	threshold    int
+	openDuration time.Duration
+	probes       int
+
+	mtx       sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	inFlight  int
+	successes int
+	now       func() time.Time
+}
+
+// New returns a closed Breaker that opens after threshold consecutive failures, and then
+// allows probes concurrent requests after each openDuration until they all succeed
+func New(threshold int, openDuration time.Duration, probes int) *Breaker {
+	return &Breaker{threshold: threshold, openDuration: openDuration, probes: probes,
+		now: time.Now}
+}
+
+// State returns the current State of the Breaker. A nil Breaker is always closed
+func (b *Breaker) State() State {
+	if b == nil {
+		return StateClosed
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.state
+}
+
+// Allow returns true if a request may be sent upstream. Each allowed request must be
+// followed by a call to Record or Cancel. A nil Breaker allows all requests
+func (b *Breaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.state == StateOpen {
+		if b.now().Sub(b.openedAt) < b.openDuration {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.inFlight = 0
+		b.successes = 0
+	}
+	if b.state == StateHalfOpen {
+		if b.inFlight+b.successes >= b.probes {
+			return false
+		}
+		b.inFlight++
+	}
+	return true
+}
+
+// Record records the outcome of an allowed request
+func (b *Breaker) Record(success bool) {
+	if b == nil {
+		return
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	switch b.state {
+	case StateClosed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	case StateHalfOpen:
+		if b.inFlight > 0 {
+			b.inFlight--
+		}
+		if !success {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.probes {
+			b.state = StateClosed
+			b.failures = 0
+		}
+	}
+}
+
+// Cancel releases an allowed request that completed without a conclusive outcome, such as
+// one canceled by the client
+func (b *Breaker) Cancel() {
+	if b == nil {
+		return
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.state == StateHalfOpen && b.inFlight > 0 {
+		b.inFlight--
+	}
+}
+
+func (b *Breaker) open() {
+	b.state = StateOpen
+	b.openedAt = b.now()
+	b.failures = 0
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package breaker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+
+	now := time.Unix(1577836800, 0)
+	b := New(2, time.Second, 2)
+	b.now = func() time.Time { return now }
+
+	if !b.Allow() {
+		t.Fatal("expected closed breaker to allow")
+	}
+	b.Record(false)
+	b.Record(true)
+	b.Record(false)
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+	b.Record(false)
+	if b.State() != StateOpen {
+		t.Errorf("expected %s got %s", StateOpen, b.State())
+	}
+	if b.Allow() {
+		t.Error("expected open breaker to reject")
+	}
+
+	now = now.Add(time.Second)
+	if !b.Allow() || !b.Allow() {
+		t.Fatal("expected half-open breaker to allow 2 probes")
+	}
+	if b.State() != StateHalfOpen {
+		t.Errorf("expected %s got %s", StateHalfOpen, b.State())
+	}
+	if b.Allow() {
+		t.Error("expected half-open breaker to reject a third probe")
+	}
+	b.Cancel()
+	if !b.Allow() {
+		t.Error("expected half-open breaker to allow a probe after cancel")
+	}
+	b.Record(true)
+	if b.State() != StateHalfOpen {
+		t.Errorf("expected %s got %s", StateHalfOpen, b.State())
+	}
+	b.Record(false)
+	if b.State() != StateOpen {
+		t.Errorf("expected %s got %s", StateOpen, b.State())
+	}
+
+	now = now.Add(time.Second)
+	b.Allow()
+	b.Allow()
+	b.Record(true)
+	b.Record(true)
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	if !b.Allow() {
+		t.Error("expected nil breaker to allow")
+	}
+	b.Record(false)
+	b.Cancel()
+	if b.State() != StateClosed {
+		t.Errorf("expected %s got %s", StateClosed, b.State())
+	}
+}
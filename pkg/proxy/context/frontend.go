@@ -0,0 +1,37 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+
+	"github.com/tricksterproxy/trickster/pkg/config/defaults"
+)
+
+// WithFrontendName returns a copy of the provided context that also includes
+// the name of the frontend listener that accepted the request
+func WithFrontendName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, frontendKey, name)
+}
+
+// FrontendName returns the name of the frontend listener that accepted the request
+func FrontendName(ctx context.Context) string {
+	if name, ok := ctx.Value(frontendKey).(string); ok && name != "" {
+		return name
+	}
+	return defaults.DefaultFrontendName
+}
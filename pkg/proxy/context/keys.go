@@ -22,4 +22,6 @@ const (
 	resourcesKey contextKey = iota
 	hopsKey
 	healthCheckKey
+	frontendKey
+	pathMatchKey
 )
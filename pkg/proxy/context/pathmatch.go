@@ -0,0 +1,36 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+)
+
+// WithPathMatch returns a copy of the provided context that also includes the groups
+// captured by the regex path config that matched the request, keyed by index and name
+func WithPathMatch(ctx context.Context, groups map[string]string) context.Context {
+	return context.WithValue(ctx, pathMatchKey, groups)
+}
+
+// PathMatch returns the groups captured by the regex path config that matched the request,
+// or nil if the request was not matched by a regex path config
+func PathMatch(ctx context.Context) map[string]string {
+	if groups, ok := ctx.Value(pathMatchKey).(map[string]string); ok {
+		return groups
+	}
+	return nil
+}
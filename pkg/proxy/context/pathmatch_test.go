@@ -0,0 +1,35 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPathMatch(t *testing.T) {
+
+	ctx := context.Background()
+	if groups := PathMatch(ctx); groups != nil {
+		t.Errorf("expected nil got %v", groups)
+	}
+
+	ctx = WithPathMatch(ctx, map[string]string{"1": "a", "tenant": "a"})
+	if v := PathMatch(ctx)["tenant"]; v != "a" {
+		t.Errorf("expected %s got %s", "a", v)
+	}
+}
@@ -17,15 +17,18 @@
 package engines
 
 import (
+	"crypto/x509"
 	"net/http"
+	"strings"
 
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/clientcert"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 )
 
 func logUpstreamRequest(log *tl.Logger, originName, originType, handlerName, method,
-	path, userAgent string, responseCode, size int, requestDuration float64) {
+	path, userAgent string, responseCode, size int, requestDuration float64, clientCert *x509.Certificate) {
 	log.Debug("upstream request",
-		tl.Pairs{
+		withClientCert(tl.Pairs{
 			"originName":  originName,
 			"originType":  originType,
 			"handlerName": handlerName,
@@ -35,15 +38,24 @@ func logUpstreamRequest(log *tl.Logger, originName, originType, handlerName, met
 			"code":        responseCode,
 			"size":        size,
 			"durationMS":  int(requestDuration * 1000),
-		})
+		}, clientCert))
 }
 
 func logDownstreamRequest(log *tl.Logger, r *http.Request) {
 	log.Debug("downtream request",
-		tl.Pairs{
+		withClientCert(tl.Pairs{
 			"uri":       r.RequestURI,
 			"method":    r.Method,
 			"userAgent": r.UserAgent(),
 			"clientIP":  r.RemoteAddr,
-		})
+		}, clientcert.Verified(r)))
+}
+
+// withClientCert adds the identity of the verified client certificate, if any, to the pairs
+func withClientCert(pairs tl.Pairs, c *x509.Certificate) tl.Pairs {
+	if c != nil {
+		pairs["clientCertCN"] = c.Subject.CommonName
+		pairs["clientCertSANs"] = strings.Join(clientcert.AltNames(c), ",")
+	}
+	return pairs
 }
@@ -33,7 +33,7 @@ func TestLogUpstreamRequest(t *testing.T) {
 	conf.Logging = &config.LoggingConfig{LogFile: fileName, LogLevel: "debug"}
 	log := tl.New(conf)
 	logUpstreamRequest(log, "testOrigin", "testType", "testHandler", "testMethod",
-		"testPath", "testUserAgent", 200, 0, 1.0)
+		"testPath", "testUserAgent", 200, 0, 1.0, nil)
 	if _, err := os.Stat(fileName); err != nil {
 		t.Errorf(err.Error())
 	}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/breaker"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// doWithBreaker sends r to the origin per doWithRetry, unless the origin's circuit breaker is
+// open, in which case ErrCircuitOpen is returned without dialing the upstream. The outcome of
+// each request that is sent, after any retries, is recorded by the breaker
+func doWithBreaker(r *http.Request, oc *oo.Options, logger *log.Logger) (*http.Response, error) {
+	b := oc.Breaker
+	if b == nil {
+		return doWithRetry(r, oc, logger)
+	}
+
+	prev := b.State()
+	if !b.Allow() {
+		return nil, errors.ErrCircuitOpen
+	}
+	observeBreaker(oc, prev, logger)
+
+	prev = b.State()
+	resp, err := doWithRetry(r, oc, logger)
+	if r.Context().Err() != nil || err == errors.ErrUpstreamConcurrencyLimit {
+		// the client went away, or the request was not sent, which says nothing about
+		// the health of the upstream
+		b.Cancel()
+	} else {
+		b.Record(!isFailoverResponse(resp, err))
+	}
+	observeBreaker(oc, prev, logger)
+	return resp, err
+}
+
+// observeBreaker updates the breaker state metric for the origin, and logs the change if the
+// origin's breaker is no longer in the prev state
+func observeBreaker(oc *oo.Options, prev breaker.State, logger *log.Logger) {
+	s := oc.Breaker.State()
+	metrics.ProxyBreakerState.WithLabelValues(oc.Name, oc.OriginType).Set(float64(s))
+	if s == prev {
+		return
+	}
+	pairs := log.Pairs{"originName": oc.Name, "from": prev.String(), "to": s.String()}
+	if s == breaker.StateOpen {
+		logger.Warn("circuit breaker opened", pairs)
+		return
+	}
+	logger.Info("circuit breaker state changed", pairs)
+}
+
+// breakerOpenStatus is the Status of the responses generated for requests that are rejected
+// by an open circuit breaker, which distinguishes them from 503s received from the upstream
+const breakerOpenStatus = "503 Service Unavailable (circuit breaker open)"
+
+// isBreakerOpenResponse returns true if resp was generated because the origin's circuit
+// breaker rejected the request, rather than received from the upstream. This includes
+// requests rejected while the breaker is half-open and all of its probe requests are in use
+func isBreakerOpenResponse(resp *http.Response) bool {
+	return resp != nil && resp.StatusCode == http.StatusServiceUnavailable &&
+		resp.Status == breakerOpenStatus
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/mockster/pkg/mocks/byterange"
+	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
+	"github.com/tricksterproxy/trickster/pkg/proxy/breaker"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestDoProxyBreaker(t *testing.T) {
+
+	s, count := newRetryTestServer(5, http.StatusBadGateway)
+	defer s.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s.URL, 0)
+	oc := request.GetResources(r).OriginConfig
+	oc.Breaker = breaker.New(2, time.Hour, 1)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		DoProxy(w, r, true)
+		expected := http.StatusBadGateway
+		if i == 2 {
+			expected = http.StatusServiceUnavailable
+		}
+		if w.Result().StatusCode != expected {
+			t.Errorf("expected %d got %d", expected, w.Result().StatusCode)
+		}
+	}
+	// the breaker opens after 2 failures, so the third request is not sent upstream
+	if *count != 2 {
+		t.Errorf("expected %d got %d", 2, *count)
+	}
+	if oc.Breaker.State() != breaker.StateOpen {
+		t.Errorf("expected %s got %s", breaker.StateOpen, oc.Breaker.State())
+	}
+}
+
+func TestObjectProxyCacheBreakerOpenServesStale(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPCRange(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.PathConfig.ResponseHeaders = map[string]string{
+		headers.NameCacheControl: headers.ValueMaxAge + "=1",
+		headers.NameETag:         "test-etag",
+	}
+	rsc.OriginConfig.RevalidationFactor = 2
+
+	_, e := testFetchOPC(r, http.StatusOK, byterange.Body, map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	time.Sleep(1010 * time.Millisecond)
+
+	b := breaker.New(1, time.Hour, 1)
+	b.Allow()
+	b.Record(false)
+	rsc.OriginConfig.Breaker = b
+
+	w, e := testFetchOPC(r, http.StatusOK, byterange.Body, map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	expected := headers.ValueWarningStale + ", " + headers.ValueWarningRevalidationFailed
+	if v := w.Result().Header.Get(headers.NameWarning); v != expected {
+		t.Errorf("expected %s got %s", expected, v)
+	}
+
+	rsc.OriginConfig.EmitStaleWarning = false
+	w, e = testFetchOPC(r, http.StatusOK, byterange.Body, map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if v := w.Result().Header.Get(headers.NameWarning); v != "" {
+		t.Errorf("expected no warning got %s", v)
+	}
+}
+
+func TestObjectProxyCacheBreakerHalfOpenServesStale(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPCRange(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.PathConfig.ResponseHeaders = map[string]string{
+		headers.NameCacheControl: headers.ValueMaxAge + "=1",
+		headers.NameETag:         "test-etag",
+	}
+	rsc.OriginConfig.RevalidationFactor = 2
+
+	_, e := testFetchOPC(r, http.StatusOK, byterange.Body, map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	time.Sleep(1010 * time.Millisecond)
+
+	// the breaker is half-open, with its only probe request in flight
+	b := breaker.New(1, time.Nanosecond, 1)
+	b.Allow()
+	b.Record(false)
+	time.Sleep(time.Millisecond)
+	b.Allow()
+	rsc.OriginConfig.Breaker = b
+
+	_, e = testFetchOPC(r, http.StatusOK, byterange.Body, map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if b.State() != breaker.StateHalfOpen {
+		t.Errorf("expected %s got %s", breaker.StateHalfOpen, b.State())
+	}
+}
+
+func TestDeltaProxyCacheBreakerOpenServesStale(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	rsc.CacheConfig.CacheType = "test"
+	oc.FastForwardDisable = true
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+	extn := timeseries.Extent{Start: normalizeTime(extr.Start, step), End: normalizeTime(extr.End, step)}
+	expected, _, _ := mockprom.GetTimeSeriesData(queryReturnsOKNoLatency, extn.Start, extn.End, step)
+
+	setQuery := func(e timeseries.Extent) {
+		r.URL.Path = "/prometheus/api/v1/query_range"
+		r.URL.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s", int(step.Seconds()),
+			e.Start.Unix(), e.End.Unix(), queryReturnsOKNoLatency)
+	}
+
+	setQuery(extr)
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	if err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "kmiss"}); err != nil {
+		t.Error(err)
+	}
+	if v := resp.Header.Get(headers.NameWarning); v != "" {
+		t.Errorf("expected no warning got %s", v)
+	}
+
+	b := breaker.New(1, time.Hour, 1)
+	b.Allow()
+	b.Record(false)
+	oc.Breaker = b
+
+	// the cached data is served without the range that could not be fetched
+	setQuery(timeseries.Extent{Start: extr.Start, End: extr.End.Add(time.Hour)})
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+	bodyBytes, _ := ioutil.ReadAll(resp.Body)
+	if err = testStringMatch(string(bodyBytes), expected); err != nil {
+		t.Error(err)
+	}
+	if err = testStatusCodeMatch(resp.StatusCode, http.StatusOK); err != nil {
+		t.Error(err)
+	}
+	if err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "hit"}); err != nil {
+		t.Error(err)
+	}
+	if v := resp.Header.Get(headers.NameWarning); v != headers.ValueWarningStale {
+		t.Errorf("expected %s got %s", headers.ValueWarningStale, v)
+	}
+
+	// when none of the requested range is cached, the rejection is returned
+	setQuery(timeseries.Extent{Start: extr.End.Add(2 * time.Hour), End: extr.End.Add(3 * time.Hour)})
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	if err = testStatusCodeMatch(w.Result().StatusCode, http.StatusServiceUnavailable); err != nil {
+		t.Error(err)
+	}
+}
@@ -17,20 +17,33 @@
 package engines
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
 	"mime"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
+	cm "github.com/tricksterproxy/trickster/pkg/cache/metrics"
+	"github.com/tricksterproxy/trickster/pkg/cache/serialization"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
 	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/ranges/byterange"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
+	"github.com/tricksterproxy/trickster/pkg/util/compress/gzip"
+	"github.com/tricksterproxy/trickster/pkg/util/compress/zstd"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 
 	"github.com/golang/snappy"
 	"go.opentelemetry.io/otel/api/kv"
@@ -89,23 +102,46 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 			return d, lookupStatus, nr, err
 		}
 
-		var inflate bool
-		// check and remove compression bit
+		var inflate, dictionary bool
+		format := serialization.FormatMsgPack
+		// check and remove the encoding header byte
 		if len(bytes) > 0 {
-			if bytes[0] == 1 {
-				inflate = true
-			}
+			hasChecksum := bytes[0]&encodingChecksumFlag == encodingChecksumFlag
+			format, inflate, dictionary = parseEncodingHeader(bytes[0])
 			bytes = bytes[1:]
+			if hasChecksum {
+				if bytes, err = verifyChecksum(bytes); err != nil {
+					rsc.Logger.Warn("cache object failed checksum verification",
+						tl.Pairs{"cacheKey": key, "detail": err.Error()})
+					cm.ObserveCacheEvent(c.Configuration().Name, c.Configuration().CacheType,
+						"checksum", "mismatch")
+					tspan.SetAttributes(rsc.Tracer, span,
+						kv.String("cache.status", status.LookupStatusKeyMiss.String()))
+					return d, status.LookupStatusKeyMiss, ranges, cache.ErrKNF
+				}
+			}
 		}
 
 		if inflate {
 			rsc.Logger.Debug("decompressing cached data", tl.Pairs{"cacheKey": key})
-			b, err := snappy.Decode(nil, bytes)
+			var b []byte
+			if dictionary {
+				if dict := c.Configuration().CompressionDictionary; dict != nil {
+					b, err = dict.Inflate(bytes)
+				} else {
+					err = errMissingCompressionDictionary
+				}
+			} else {
+				b, err = snappy.Decode(nil, bytes)
+			}
 			if err == nil {
 				bytes = b
+			} else {
+				rsc.Logger.Debug("error decompressing cached data",
+					tl.Pairs{"cacheKey": key, "detail": err.Error()})
 			}
 		}
-		_, err = d.UnmarshalMsg(bytes)
+		err = unmarshalDocument(d, bytes, format)
 		if err != nil {
 			rsc.Logger.Error("error unmarshaling cache document", tl.Pairs{
 				"cacheKey": key,
@@ -115,6 +151,22 @@ func QueryCache(ctx context.Context, c cache.Cache, key string,
 			return d, status.LookupStatusKeyMiss, ranges, err
 		}
 
+		// the body encoding is recorded with each object, so that objects written under
+		// a different cache_compression setting still decode correctly
+		if d.BodyEncoding != "" {
+			var b []byte
+			b, err = decodeBody(d.BodyEncoding, d.Body)
+			if err != nil {
+				rsc.Logger.Error("error decompressing cached body", tl.Pairs{
+					"cacheKey": key,
+					"detail":   err.Error(),
+				})
+				tspan.SetAttributes(rsc.Tracer, span, kv.String("cache.status", status.LookupStatusKeyMiss.String()))
+				return d, status.LookupStatusKeyMiss, ranges, err
+			}
+			d.Body, d.BodyEncoding = b, ""
+		}
+
 	}
 
 	var delta byterange.Ranges
@@ -155,12 +207,31 @@ func stripConditionalHeaders(h http.Header) {
 	h.Del(headers.NameIfModifiedSince)
 }
 
-// WriteCache writes an HTTPDocument to the cache
+// WriteCache writes an HTTPDocument to the cache. When the origin's cache write rate limit is
+// exceeded, the document is not written, and no error is returned
 func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 	ttl time.Duration, compressTypes map[string]bool) error {
+	return writeCache(ctx, c, key, d, ttl, compressTypes, true)
+}
+
+// writeCache writes an HTTPDocument to the cache, subject to the origin's cache write rate
+// limit when limited is true
+func writeCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
+	ttl time.Duration, compressTypes map[string]bool, limited bool) error {
 
 	rsc := tc.Resources(ctx).(*request.Resources)
 
+	if oc := rsc.OriginConfig; limited && oc != nil && !oc.CacheWriteLimiter.Allow() {
+		metrics.ProxyCacheWritesThrottled.WithLabelValues(oc.Name, oc.OriginType).Inc()
+		rsc.Logger.Debug("cache write rate limit exceeded",
+			tl.Pairs{"originName": oc.Name, "cacheKey": key})
+		return nil
+	}
+
+	if oc := rsc.OriginConfig; oc != nil {
+		metrics.ProxyAppliedTTL.WithLabelValues(oc.Name, oc.OriginType).Observe(ttl.Seconds())
+	}
+
 	ctx, span := tspan.NewChildSpan(ctx, rsc.Tracer, "WriteCache")
 	if span != nil {
 		defer span.End()
@@ -172,6 +243,10 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 	h.Del(headers.NameTransferEncoding)
 	h.Del(headers.NameContentRange)
 	h.Del(headers.NameTricksterResult)
+	if rsc.OriginConfig != nil && rsc.OriginConfig.EmitServerTiming {
+		// timings describe the request that fetched the object, not later requests served from cache
+		h.Del(headers.NameServerTiming)
+	}
 	ce := h.Get(headers.NameContentEncoding)
 	d.headerLock.Unlock()
 
@@ -207,8 +282,29 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 		return mc.StoreReference(key, d, ttl)
 	}
 
+	// compress the body per the origin's cache_compression setting, unless the
+	// upstream response is already content-encoded, or the body is smaller than
+	// the cache's compression threshold
+	sd := d
+	if rsc.OriginConfig != nil && rsc.OriginConfig.CacheCompression != "" &&
+		rsc.OriginConfig.CacheCompression != oo.CacheCompressionNone &&
+		(ce == "" || ce == "identity") && len(d.Body) > 0 &&
+		len(d.Body) >= c.Configuration().CompressionMinSizeBytes {
+		if sd, err = bodyEncodedDocument(d, rsc.OriginConfig.CacheCompression); err != nil {
+			rsc.Logger.Error("error compressing cache document body", tl.Pairs{
+				"cacheKey": key,
+				"detail":   err.Error(),
+			})
+			sd = d
+		} else {
+			// the body is already compressed, so it is not compressed again by the cache
+			compress = false
+		}
+	}
+
 	// for non-memory, we have to seralize the document to a byte slice to store
-	bytes, err = d.MarshalMsg(nil)
+	format := c.Configuration().SerializationFormatID
+	bytes, err = marshalDocument(sd, format)
 	if err != nil {
 		rsc.Logger.Error("error marshaling cache document", tl.Pairs{
 			"cacheKey": key,
@@ -216,12 +312,27 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 		})
 	}
 
+	// objects smaller than the cache's compression threshold are stored uncompressed
+	if compress && len(bytes) < c.Configuration().CompressionMinSizeBytes {
+		compress = false
+	}
+
+	var hdr byte
 	if compress {
 		rsc.Logger.Debug("compressing cache data", tl.Pairs{"cacheKey": key})
-		bytes = append([]byte{1}, snappy.Encode(nil, bytes)...)
+		if dict := c.Configuration().CompressionDictionary; dict != nil {
+			hdr, bytes = encodingHeader(format, true, true), dict.Deflate(bytes)
+		} else {
+			hdr, bytes = encodingHeader(format, true, false), snappy.Encode(nil, bytes)
+		}
 	} else {
-		bytes = append([]byte{0}, bytes...)
+		hdr = encodingHeader(format, false, false)
+	}
+	if c.Configuration().VerifyChecksums {
+		hdr |= encodingChecksumFlag
+		bytes = prependChecksum(bytes)
 	}
+	bytes = append([]byte{hdr}, bytes...)
 
 	err = c.Store(key, bytes, ttl)
 	if err != nil {
@@ -245,6 +356,126 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 
 }
 
+// encodingDictionaryFlag is the encoding header bit indicating that a compressed object was
+// compressed with the cache's zstd dictionary rather than with snappy
+const encodingDictionaryFlag = 1 << 7
+
+// encodingChecksumFlag is the encoding header bit indicating that the serialized object is
+// prefixed with a CRC-32 checksum of the remaining bytes
+const encodingChecksumFlag = 1 << 6
+
+// bodyEncodedDocument returns a copy of d for serialization, with its Body compressed with the codec
+func bodyEncodedDocument(d *HTTPDocument, codec string) (*HTTPDocument, error) {
+	b, err := encodeBody(codec, d.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPDocument{
+		StatusCode:       d.StatusCode,
+		Status:           d.Status,
+		Headers:          d.Headers,
+		Body:             b,
+		ContentLength:    d.ContentLength,
+		ContentType:      d.ContentType,
+		CachingPolicy:    d.CachingPolicy,
+		Ranges:           d.Ranges,
+		StoredRangeParts: d.StoredRangeParts,
+		BodyEncoding:     codec,
+	}, nil
+}
+
+// encodeBody returns b compressed with the cache compression codec
+func encodeBody(codec string, b []byte) ([]byte, error) {
+	switch codec {
+	case oo.CacheCompressionGzip:
+		return gzip.Deflate(b)
+	case oo.CacheCompressionZstd:
+		return zstd.Deflate(b), nil
+	}
+	return nil, fmt.Errorf("unknown cache compression codec: %s", codec)
+}
+
+// decodeBody returns b decompressed with the cache compression codec
+func decodeBody(codec string, b []byte) ([]byte, error) {
+	switch codec {
+	case oo.CacheCompressionGzip:
+		return gzip.Inflate(b)
+	case oo.CacheCompressionZstd:
+		return zstd.Inflate(b)
+	}
+	return nil, fmt.Errorf("unknown cache compression codec: %s", codec)
+}
+
+var errMissingCompressionDictionary = errors.New("object requires a compression dictionary")
+
+var errChecksumMismatch = errors.New("checksum mismatch")
+
+// prependChecksum returns b prefixed with its big-endian CRC-32 (IEEE) checksum
+func prependChecksum(b []byte) []byte {
+	out := make([]byte, 4, len(b)+4)
+	binary.BigEndian.PutUint32(out, crc32.ChecksumIEEE(b))
+	return append(out, b...)
+}
+
+// verifyChecksum verifies the CRC-32 checksum prefixing b, and returns the remaining bytes
+func verifyChecksum(b []byte) ([]byte, error) {
+	if len(b) < 4 {
+		return nil, errChecksumMismatch
+	}
+	if binary.BigEndian.Uint32(b[:4]) != crc32.ChecksumIEEE(b[4:]) {
+		return nil, errChecksumMismatch
+	}
+	return b[4:], nil
+}
+
+// encodingHeader returns the header byte that prefixes serialized cache objects. The lowest bit
+// indicates compression, the highest bit indicates zstd dictionary rather than snappy compression,
+// the next highest bit indicates a checksum, and the remaining bits indicate the serialization
+// format. Since MessagePack is format 0, objects written before the format was configurable
+// decode as MessagePack.
+func encodingHeader(format serialization.Format, compressed, dictionary bool) byte {
+	b := byte(format) << 1
+	if compressed {
+		b |= 1
+		if dictionary {
+			b |= encodingDictionaryFlag
+		}
+	}
+	return b
+}
+
+// parseEncodingHeader returns the serialization format, compression flag and
+// dictionary compression flag from a header byte
+func parseEncodingHeader(b byte) (serialization.Format, bool, bool) {
+	return serialization.Format((b &^ (encodingDictionaryFlag | encodingChecksumFlag)) >> 1), b&1 == 1,
+		b&encodingDictionaryFlag == encodingDictionaryFlag
+}
+
+func marshalDocument(d *HTTPDocument, format serialization.Format) ([]byte, error) {
+	switch format {
+	case serialization.FormatJSON:
+		return json.Marshal(d)
+	case serialization.FormatGob:
+		buf := &bytes.Buffer{}
+		err := gob.NewEncoder(buf).Encode(d)
+		return buf.Bytes(), err
+	}
+	return d.MarshalMsg(nil)
+}
+
+func unmarshalDocument(d *HTTPDocument, b []byte, format serialization.Format) error {
+	switch format {
+	case serialization.FormatMsgPack:
+		_, err := d.UnmarshalMsg(b)
+		return err
+	case serialization.FormatJSON:
+		return json.Unmarshal(b, d)
+	case serialization.FormatGob:
+		return gob.NewDecoder(bytes.NewReader(b)).Decode(d)
+	}
+	return fmt.Errorf("unknown cache serialization format: %s", format)
+}
+
 // DocumentFromHTTPResponse returns an HTTPDocument from the provided HTTP Response and Body
 func DocumentFromHTTPResponse(resp *http.Response, body []byte, cp *CachingPolicy, log *tl.Logger) *HTTPDocument {
 	d := &HTTPDocument{}
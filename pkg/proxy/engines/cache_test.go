@@ -31,14 +31,21 @@ import (
 	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/cache/registration"
 	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
+	"github.com/tricksterproxy/trickster/pkg/cache/serialization"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
 	"github.com/tricksterproxy/trickster/pkg/config"
 	"github.com/tricksterproxy/trickster/pkg/locks"
 	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/ranges/byterange"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/util/compress/zstd"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 const testRangeBody = "This is a test file, to see how the byte range requests work.\n"
@@ -53,6 +60,66 @@ func newRangeRequestTestServer() *httptest.Server {
 	return s
 }
 
+func TestEncodingHeader(t *testing.T) {
+
+	// legacy headers (0 and 1) must decode as uncompressed and compressed msgpack
+	for b, expected := range []bool{false, true} {
+		f, c, d := parseEncodingHeader(byte(b))
+		if f != serialization.FormatMsgPack {
+			t.Errorf("expected %s got %s", serialization.FormatMsgPack, f)
+		}
+		if c != expected {
+			t.Errorf("expected %t got %t", expected, c)
+		}
+		if d {
+			t.Error("expected legacy header to not use a compression dictionary")
+		}
+	}
+
+	for _, f := range []serialization.Format{serialization.FormatJSON, serialization.FormatGob} {
+		for _, c := range []bool{false, true} {
+			f2, c2, d2 := parseEncodingHeader(encodingHeader(f, c, c))
+			if f2 != f || c2 != c || d2 != c {
+				t.Errorf("expected %s/%t/%t got %s/%t/%t", f, c, c, f2, c2, d2)
+			}
+		}
+	}
+}
+
+func TestMarshalDocumentFormats(t *testing.T) {
+
+	d := &HTTPDocument{
+		StatusCode:    200,
+		Headers:       map[string][]string{headers.NameContentType: {headers.ValueTextPlain}},
+		Body:          []byte(testRangeBody),
+		ContentType:   headers.ValueTextPlain,
+		CachingPolicy: &CachingPolicy{ETag: "test", FreshnessLifetime: 60},
+	}
+
+	for f := range serialization.Values {
+		b, err := marshalDocument(d, f)
+		if err != nil {
+			t.Error(err)
+		}
+		d2 := &HTTPDocument{}
+		err = unmarshalDocument(d2, b, f)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(d2.Body) != testRangeBody {
+			t.Errorf("%s: expected %s got %s", f, testRangeBody, string(d2.Body))
+		}
+		if d2.CachingPolicy == nil || d2.CachingPolicy.ETag != "test" {
+			t.Errorf("%s: expected caching policy to be decoded", f)
+		}
+	}
+
+	err := unmarshalDocument(&HTTPDocument{}, []byte{}, serialization.Format(30))
+	if err == nil {
+		t.Error("expected error for unknown serialization format")
+	}
+}
+
 func TestInvalidContentRange(t *testing.T) {
 	_, _, err := byterange.ParseContentRangeHeader("blah")
 	if err == nil {
@@ -90,6 +157,75 @@ func TestMultiPartByteRange(t *testing.T) {
 	}
 }
 
+func TestWriteCacheRateLimit(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-origin-type", "test"})
+	if err != nil {
+		t.Errorf("Could not load configuration: %s", err.Error())
+	}
+	caches := cr.LoadCachesFromConfig(conf, testLogger)
+	cache := caches["default"]
+
+	oc := conf.Origins["default"]
+	oc.CacheWriteLimiter = oo.NewRateLimiter(1)
+
+	ctx := context.Background()
+	ctx = tc.WithResources(ctx, &request.Resources{OriginConfig: oc, Tracer: tu.NewTestTracer(),
+		Logger: testLogger})
+
+	for i, key := range []string{"testKey1", "testKey2"} {
+		resp := &http.Response{StatusCode: 200, Header: make(http.Header)}
+		d := DocumentFromHTTPResponse(resp, []byte("test"), nil, testLogger)
+		err = WriteCache(ctx, cache, key, d, time.Duration(60)*time.Second, nil)
+		if err != nil {
+			t.Errorf("write %d: %v", i, err)
+		}
+	}
+
+	if _, ls, _, _ := QueryCache(ctx, cache, "testKey1", nil); ls != status.LookupStatusHit {
+		t.Errorf("expected %s got %s", status.LookupStatusHit, ls)
+	}
+	// the second write exceeded the rate, so it was not cached
+	if _, ls, _, _ := QueryCache(ctx, cache, "testKey2", nil); ls != status.LookupStatusKeyMiss {
+		t.Errorf("expected %s got %s", status.LookupStatusKeyMiss, ls)
+	}
+}
+
+func TestWriteCacheAppliedTTL(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-origin-type", "test"})
+	if err != nil {
+		t.Errorf("Could not load configuration: %s", err.Error())
+	}
+	caches := cr.LoadCachesFromConfig(conf, testLogger)
+	cache := caches["default"]
+
+	oc := conf.Origins["default"]
+	oc.Name = "test-applied-ttl"
+
+	ctx := context.Background()
+	ctx = tc.WithResources(ctx, &request.Resources{OriginConfig: oc, Tracer: tu.NewTestTracer(),
+		Logger: testLogger})
+
+	for i, ttl := range []time.Duration{30 * time.Second, 90 * time.Second} {
+		resp := &http.Response{StatusCode: 200, Header: make(http.Header)}
+		d := DocumentFromHTTPResponse(resp, []byte("test"), nil, testLogger)
+		if err = WriteCache(ctx, cache, "testKey"+strconv.Itoa(i), d, ttl, nil); err != nil {
+			t.Errorf("write %d: %v", i, err)
+		}
+	}
+
+	m := &dto.Metric{}
+	metrics.ProxyAppliedTTL.WithLabelValues(oc.Name, oc.OriginType).(prometheus.Histogram).Write(m)
+	h := m.GetHistogram()
+	if v := h.GetSampleCount(); v != 2 {
+		t.Errorf("expected %d got %d", 2, v)
+	}
+	if v := h.GetSampleSum(); v != 120 {
+		t.Errorf("expected %d got %f", 120, v)
+	}
+}
+
 func TestCacheHitRangeRequest(t *testing.T) {
 	expected := "is a "
 	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-origin-type", "test"})
@@ -439,6 +575,267 @@ func TestQueryCache(t *testing.T) {
 
 }
 
+func TestWriteCacheCompressionMinSize(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-origin-type", "test"})
+	if err != nil {
+		t.Errorf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := cr.LoadCachesFromConfig(conf, testLogger)
+	cache, ok := caches["default"]
+	if !ok {
+		t.Error("could not load cache")
+	}
+	// make the cache not appear to be a memory cache, so objects are serialized
+	cache.Configuration().CacheType = "test"
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{headers.NameContentType: {headers.ValueTextPlain}}}
+	d := DocumentFromHTTPResponse(resp, []byte(testRangeBody), nil, testLogger)
+	ctx := context.Background()
+	ctx = tc.WithResources(ctx, &request.Resources{OriginConfig: conf.Origins["default"],
+		Tracer: tu.NewTestTracer(), Logger: testLogger})
+
+	tests := []struct {
+		minSize    int
+		compressed bool
+	}{
+		{0, true},
+		{1 << 20, false},
+	}
+
+	for _, test := range tests {
+		cache.Configuration().CompressionMinSizeBytes = test.minSize
+		err = WriteCache(ctx, cache, "testKey", d, time.Duration(60)*time.Second, map[string]bool{"text/plain": true})
+		if err != nil {
+			t.Error(err)
+		}
+		b, _, err := cache.Retrieve("testKey", false)
+		if err != nil {
+			t.Error(err)
+		}
+		if _, c, _ := parseEncodingHeader(b[0]); c != test.compressed {
+			t.Errorf("expected %t got %t", test.compressed, c)
+		}
+	}
+}
+
+func TestWriteCacheCompressionDictionary(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-origin-type", "test"})
+	if err != nil {
+		t.Errorf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := cr.LoadCachesFromConfig(conf, testLogger)
+	cache, ok := caches["default"]
+	if !ok {
+		t.Error("could not load cache")
+	}
+	// make the cache not appear to be a memory cache, so objects are serialized
+	cache.Configuration().CacheType = "test"
+
+	b, err := ioutil.ReadFile("../../../testdata/zstd_test.dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict, err := zstd.NewDictionary(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Configuration().CompressionDictionary = dict
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{headers.NameContentType: {headers.ValueTextPlain}}}
+	d := DocumentFromHTTPResponse(resp, []byte(testRangeBody), nil, testLogger)
+	ctx := context.Background()
+	ctx = tc.WithResources(ctx, &request.Resources{OriginConfig: conf.Origins["default"],
+		Tracer: tu.NewTestTracer(), Logger: testLogger})
+
+	err = WriteCache(ctx, cache, "testKey", d, time.Duration(60)*time.Second, map[string]bool{"text/plain": true})
+	if err != nil {
+		t.Error(err)
+	}
+	b, _, err = cache.Retrieve("testKey", false)
+	if err != nil {
+		t.Error(err)
+	}
+	if _, c, dc := parseEncodingHeader(b[0]); !c || !dc {
+		t.Errorf("expected dictionary compression got %t/%t", c, dc)
+	}
+
+	d2, _, _, err := QueryCache(ctx, cache, "testKey", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(d2.Body) != testRangeBody {
+		t.Errorf("expected %s got %s", testRangeBody, string(d2.Body))
+	}
+
+	// objects compressed with a dictionary are misses once the dictionary is removed
+	cache.Configuration().CompressionDictionary = nil
+	_, _, _, err = QueryCache(ctx, cache, "testKey", nil)
+	if err == nil {
+		t.Error("expected error for missing compression dictionary")
+	}
+}
+
+func TestWriteCacheVerifyChecksums(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-origin-type", "test"})
+	if err != nil {
+		t.Errorf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := cr.LoadCachesFromConfig(conf, testLogger)
+	cache, ok := caches["default"]
+	if !ok {
+		t.Error("could not load cache")
+	}
+	// make the cache not appear to be a memory cache, so objects are serialized
+	cache.Configuration().CacheType = "test"
+	cache.Configuration().VerifyChecksums = true
+
+	resp := &http.Response{StatusCode: 200, Header: http.Header{headers.NameContentType: {headers.ValueTextPlain}}}
+	d := DocumentFromHTTPResponse(resp, []byte(testRangeBody), nil, testLogger)
+	ctx := context.Background()
+	ctx = tc.WithResources(ctx, &request.Resources{OriginConfig: conf.Origins["default"],
+		Tracer: tu.NewTestTracer(), Logger: testLogger})
+
+	err = WriteCache(ctx, cache, "testKey", d, time.Duration(60)*time.Second, map[string]bool{"text/plain": true})
+	if err != nil {
+		t.Error(err)
+	}
+	b, _, err := cache.Retrieve("testKey", false)
+	if err != nil {
+		t.Error(err)
+	}
+	if b[0]&encodingChecksumFlag != encodingChecksumFlag {
+		t.Error("expected checksum flag in encoding header")
+	}
+	if f, c, _ := parseEncodingHeader(b[0]); f != cache.Configuration().SerializationFormatID || !c {
+		t.Errorf("unexpected encoding header %d", b[0])
+	}
+
+	d2, st, _, err := QueryCache(ctx, cache, "testKey", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if st != status.LookupStatusHit || string(d2.Body) != testRangeBody {
+		t.Errorf("expected %s got %s", testRangeBody, string(d2.Body))
+	}
+
+	// corrupt the stored object, which should then be a miss
+	b[len(b)-1] ^= 0xff
+	cache.Store("testKey", b, time.Duration(60)*time.Second)
+	_, st, _, err = QueryCache(ctx, cache, "testKey", nil)
+	if st != status.LookupStatusKeyMiss || err == nil {
+		t.Errorf("expected %s got %s", status.LookupStatusKeyMiss, st)
+	}
+}
+
+func TestWriteCacheOriginCompression(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url", "http://1", "-origin-type", "test"})
+	if err != nil {
+		t.Errorf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := cr.LoadCachesFromConfig(conf, testLogger)
+	cache, ok := caches["default"]
+	if !ok {
+		t.Error("could not load cache")
+	}
+	// make the cache not appear to be a memory cache, so objects are serialized
+	cache.Configuration().CacheType = "test"
+
+	oc := conf.Origins["default"]
+	resp := &http.Response{StatusCode: 200, Header: http.Header{headers.NameContentType: {headers.ValueTextPlain}}}
+	d := DocumentFromHTTPResponse(resp, []byte(testRangeBody), nil, testLogger)
+	ctx := context.Background()
+	ctx = tc.WithResources(ctx, &request.Resources{OriginConfig: oc,
+		Tracer: tu.NewTestTracer(), Logger: testLogger})
+
+	tests := []struct {
+		codec, expected string
+	}{
+		{oo.CacheCompressionNone, ""},
+		{oo.CacheCompressionGzip, oo.CacheCompressionGzip},
+		{oo.CacheCompressionZstd, oo.CacheCompressionZstd},
+	}
+
+	for _, test := range tests {
+		oc.CacheCompression = test.codec
+		err = WriteCache(ctx, cache, "testKey", d, time.Duration(60)*time.Second, map[string]bool{"text/plain": true})
+		if err != nil {
+			t.Error(err)
+		}
+		if string(d.Body) != testRangeBody {
+			t.Errorf("expected unmodified document body got %s", string(d.Body))
+		}
+		b, _, err := cache.Retrieve("testKey", false)
+		if err != nil {
+			t.Error(err)
+		}
+		f, c, _ := parseEncodingHeader(b[0])
+		if c != (test.expected == "") {
+			t.Errorf("expected cache compression %t got %t", test.expected == "", c)
+		}
+		if !c {
+			sd := &HTTPDocument{}
+			if err = unmarshalDocument(sd, b[1:], f); err != nil {
+				t.Error(err)
+			}
+			if sd.BodyEncoding != test.expected {
+				t.Errorf("expected %s got %s", test.expected, sd.BodyEncoding)
+			}
+		}
+
+		// objects decode per their recorded codec, regardless of the current setting
+		oc.CacheCompression = oo.CacheCompressionNone
+		d2, st, _, err := QueryCache(ctx, cache, "testKey", nil)
+		if err != nil {
+			t.Error(err)
+		}
+		if st != status.LookupStatusHit || string(d2.Body) != testRangeBody {
+			t.Errorf("expected %s got %s", testRangeBody, string(d2.Body))
+		}
+		if d2.BodyEncoding != "" {
+			t.Errorf("expected empty body encoding got %s", d2.BodyEncoding)
+		}
+	}
+
+	// bodies smaller than the cache's compression threshold are not compressed with the codec
+	cache.Configuration().CompressionMinSizeBytes = 1 << 20
+	oc.CacheCompression = oo.CacheCompressionGzip
+	err = WriteCache(ctx, cache, "testKey", d, time.Duration(60)*time.Second, map[string]bool{"text/plain": true})
+	if err != nil {
+		t.Error(err)
+	}
+	b, _, err := cache.Retrieve("testKey", false)
+	if err != nil {
+		t.Error(err)
+	}
+	f, _, _ := parseEncodingHeader(b[0])
+	sd := &HTTPDocument{}
+	if err = unmarshalDocument(sd, b[1:], f); err != nil {
+		t.Error(err)
+	}
+	if sd.BodyEncoding != "" {
+		t.Errorf("expected empty body encoding got %s", sd.BodyEncoding)
+	}
+	cache.Configuration().CompressionMinSizeBytes = 0
+
+	// objects with an unknown codec are misses
+	sd = &HTTPDocument{Body: []byte("test"), BodyEncoding: "invalid"}
+	b, _ = marshalDocument(sd, cache.Configuration().SerializationFormatID)
+	cache.Store("testKey", append([]byte{encodingHeader(cache.Configuration().SerializationFormatID,
+		false, false)}, b...), time.Duration(60)*time.Second)
+	_, st, _, err := QueryCache(ctx, cache, "testKey", nil)
+	if st != status.LookupStatusKeyMiss || err == nil {
+		t.Errorf("expected %s got %s", status.LookupStatusKeyMiss, st)
+	}
+}
+
 // Mock Cache for testing error conditions
 type testCache struct {
 	configuration *co.Options
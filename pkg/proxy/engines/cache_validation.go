@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"net/http"
+	"reflect"
+
+	cm "github.com/tricksterproxy/trickster/pkg/cache/metrics"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// isCacheObjectValid returns false if the rewriter would modify a copy of r bearing the
+// headers of the cached object, or fails on a reference missing from them. The rewriter
+// thus describes the state of a valid object, such as a required version header value
+func isCacheObjectValid(r *http.Request, d *HTTPDocument, ri rewriter.RewriteInstructions) bool {
+	r2 := r.Clone(r.Context())
+	r2.Header = d.SafeHeaderClone()
+	method, u, h := r2.Method, r2.URL.String(), r2.Header.Clone()
+	if err := ri.Execute(r2); err != nil {
+		return false
+	}
+	return r2.Method == method && r2.URL.String() == u && reflect.DeepEqual(r2.Header, h)
+}
+
+// validateCacheObject returns false if the cached object fails the origin's cache validation
+// rewriter, and must be fetched anew from the origin
+func (pr *proxyRequest) validateCacheObject() bool {
+	rsc := request.GetResources(pr.Request)
+	oc := rsc.OriginConfig
+	if oc == nil || len(oc.CacheValidationRewriter) == 0 || pr.cacheDocument == nil {
+		return true
+	}
+	if isCacheObjectValid(pr.Request, pr.cacheDocument, oc.CacheValidationRewriter) {
+		return true
+	}
+	pr.Logger.Debug("cache object failed validation",
+		tl.Pairs{"cacheKey": pr.key, "rewriter": oc.CacheValidationRewriterName})
+	if rsc.CacheClient != nil {
+		cc := rsc.CacheClient.Configuration()
+		cm.ObserveCacheEvent(cc.Name, cc.CacheType, "validation", "invalid")
+	}
+	return false
+}
@@ -31,31 +31,31 @@ import (
 
 // CachingPolicy defines the attributes for determining the cachability of an HTTP object
 type CachingPolicy struct {
-	IsFresh              bool `msg:"is_fresh"`
-	NoCache              bool `msg:"nocache"`
-	NoTransform          bool `msg:"notransform"`
-	CanRevalidate        bool `msg:"can_revalidate"`
-	MustRevalidate       bool `msg:"must_revalidate"`
-	IsNegativeCache      bool `msg:"is_negative_cache"`
-	IsClientConditional  bool `msg:"-"`
-	IsClientFresh        bool `msg:"-"`
-	HasIfModifiedSince   bool `msg:"-"`
-	HasIfUnmodifiedSince bool `msg:"-"`
-	HasIfNoneMatch       bool `msg:"-"`
-	IfNoneMatchResult    bool `msg:"-"`
-
-	FreshnessLifetime int `msg:"freshness_lifetime"`
-
-	LastModified time.Time `msg:"last_modified"`
-	Expires      time.Time `msg:"expires"`
-	Date         time.Time `msg:"date"`
-	LocalDate    time.Time `msg:"local_date"`
-
-	ETag string `msg:"etag"`
-
-	IfNoneMatchValue      string    `msg:"-"`
-	IfModifiedSinceTime   time.Time `msg:"-"`
-	IfUnmodifiedSinceTime time.Time `msg:"-"`
+	IsFresh              bool `msg:"is_fresh" json:"is_fresh"`
+	NoCache              bool `msg:"nocache" json:"nocache"`
+	NoTransform          bool `msg:"notransform" json:"notransform"`
+	CanRevalidate        bool `msg:"can_revalidate" json:"can_revalidate"`
+	MustRevalidate       bool `msg:"must_revalidate" json:"must_revalidate"`
+	IsNegativeCache      bool `msg:"is_negative_cache" json:"is_negative_cache"`
+	IsClientConditional  bool `msg:"-" json:"-"`
+	IsClientFresh        bool `msg:"-" json:"-"`
+	HasIfModifiedSince   bool `msg:"-" json:"-"`
+	HasIfUnmodifiedSince bool `msg:"-" json:"-"`
+	HasIfNoneMatch       bool `msg:"-" json:"-"`
+	IfNoneMatchResult    bool `msg:"-" json:"-"`
+
+	FreshnessLifetime int `msg:"freshness_lifetime" json:"freshness_lifetime"`
+
+	LastModified time.Time `msg:"last_modified" json:"last_modified"`
+	Expires      time.Time `msg:"expires" json:"expires"`
+	Date         time.Time `msg:"date" json:"date"`
+	LocalDate    time.Time `msg:"local_date" json:"local_date"`
+
+	ETag string `msg:"etag" json:"etag"`
+
+	IfNoneMatchValue      string    `msg:"-" json:"-"`
+	IfModifiedSinceTime   time.Time `msg:"-" json:"-"`
+	IfUnmodifiedSinceTime time.Time `msg:"-" json:"-"`
 }
 
 // Clone returns an exact copy of the Caching Policy
@@ -324,6 +324,23 @@ func hasPragmaNoCache(h http.Header) bool {
 	return false
 }
 
+// GetClientMaxAge returns the max-age directive value from the request's Cache-Control header,
+// and true if a valid max-age directive was present
+func GetClientMaxAge(h http.Header) (time.Duration, bool) {
+	v := h.Get(headers.NameCacheControl)
+	if v == "" {
+		return 0, false
+	}
+	for _, d := range strings.Split(strings.Replace(strings.ToLower(v), " ", "", -1), ",") {
+		if i := strings.Index(d, "="); i > 0 && d[:i] == headers.ValueMaxAge {
+			if secs, err := strconv.Atoi(d[i+1:]); err == nil && secs >= 0 {
+				return time.Duration(secs) * time.Second, true
+			}
+		}
+	}
+	return 0, false
+}
+
 // GetRequestCachingPolicy examines HTTP request headers for caching headers
 // and true if the corresponding response is OK to cache
 func GetRequestCachingPolicy(h http.Header) *CachingPolicy {
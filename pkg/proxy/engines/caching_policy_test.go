@@ -314,3 +314,32 @@ func TestCheckIfNoneMatch(t *testing.T) {
 	}
 
 }
+
+func TestGetClientMaxAge(t *testing.T) {
+
+	tests := []struct {
+		cc       string
+		expected time.Duration
+		ok       bool
+	}{
+		{"", 0, false},
+		{"no-store", 0, false},
+		{"max-age=30", 30 * time.Second, true},
+		{"no-transform, Max-Age=0", 0, true},
+		{"max-age=x", 0, false},
+		{"max-age=-5", 0, false},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			h := http.Header{}
+			if test.cc != "" {
+				h.Set(headers.NameCacheControl, test.cc)
+			}
+			d, ok := GetClientMaxAge(h)
+			if d != test.expected || ok != test.ok {
+				t.Errorf("expected %s/%t got %s/%t", test.expected, test.ok, d, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/locks"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// collapsedWaiters counts, by cache key, the requests waiting on a collapsed-forwarding fetch
+var collapsedWaiters = &waiterCounts{counts: make(map[string]int)}
+
+type waiterCounts struct {
+	mtx    sync.Mutex
+	counts map[string]int
+}
+
+// join increments the number of waiters for key, unless it would exceed max
+func (wc *waiterCounts) join(key string, max int) bool {
+	wc.mtx.Lock()
+	defer wc.mtx.Unlock()
+	if wc.counts[key] >= max {
+		return false
+	}
+	wc.counts[key]++
+	return true
+}
+
+// leave decrements the number of waiters for key
+func (wc *waiterCounts) leave(key string) {
+	wc.mtx.Lock()
+	defer wc.mtx.Unlock()
+	if wc.counts[key] <= 1 {
+		delete(wc.counts, key)
+		return
+	}
+	wc.counts[key]--
+}
+
+// joinCollapsedWaiters registers a request as waiting on the collapsed-forwarding fetch for key.
+// It returns false if the origin's max_collapsed_waiters would be exceeded; otherwise the returned
+// func must be called once the request is no longer waiting
+func joinCollapsedWaiters(oc *oo.Options, key string) (bool, func()) {
+	if oc == nil || oc.MaxCollapsedWaiters <= 0 {
+		return true, func() {}
+	}
+	if !collapsedWaiters.join(key, oc.MaxCollapsedWaiters) {
+		return false, nil
+	}
+	g := metrics.ProxyCollapsedWaiters.WithLabelValues(oc.Name, oc.OriginType)
+	g.Inc()
+	return true, func() {
+		g.Dec()
+		collapsedWaiters.leave(key)
+	}
+}
+
+// acquireCollapsedReadLock acquires the read lock for key, which blocks while another request holds
+// the write lock to fetch the object. If the origin's collapsed_forwarding_timeout_ms elapses first,
+// it returns false and the read lock is released as soon as it is eventually acquired
+func acquireCollapsedReadLock(oc *oo.Options, locker locks.NamedLocker,
+	key string) (locks.NamedLock, bool) {
+	if oc == nil || oc.CollapsedForwardingTimeout <= 0 {
+		nl, _ := locker.RAcquire(key)
+		return nl, true
+	}
+	ch := make(chan locks.NamedLock, 1)
+	go func() {
+		nl, _ := locker.RAcquire(key)
+		ch <- nl
+	}()
+	t := time.NewTimer(oc.CollapsedForwardingTimeout)
+	select {
+	case nl := <-ch:
+		t.Stop()
+		return nl, true
+	case <-t.C:
+		metrics.ProxyCollapsedTimeouts.WithLabelValues(oc.Name, oc.OriginType).Inc()
+		go func() {
+			if nl := <-ch; nl != nil {
+				nl.RRelease()
+			}
+		}()
+		return nil, false
+	}
+}
+
+// respondCollapsedWaitersExceeded writes a 503 Service Unavailable to w for a request that exceeded
+// the origin's max_collapsed_waiters or collapsed_forwarding_timeout_ms, and returns the response
+func respondCollapsedWaitersExceeded(w io.Writer, r *http.Request) *http.Response {
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Request: r, Header: make(http.Header)}
+	Respond(w, resp.StatusCode, resp.Header, nil)
+	return resp
+}
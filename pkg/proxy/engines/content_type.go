@@ -0,0 +1,62 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+// sniffLen is the number of bytes of a response body used to detect its Content-Type
+const sniffLen = 512
+
+// sniffedBody is an upstream response body that has been partially read to detect its Content-Type
+type sniffedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// applyDefaultContentType sets the Content-Type of an upstream response that does not include one,
+// per the origin's sniff_content_type and default_upstream_content_type options. Responses that
+// have no body, and encoded responses, which cannot be sniffed, are only given the default
+func applyDefaultContentType(resp *http.Response, oc *oo.Options) {
+	if resp == nil || resp.Header.Get(headers.NameContentType) != "" ||
+		(oc.DefaultUpstreamContentType == "" && !oc.SniffContentType) ||
+		resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return
+	}
+
+	ct := oc.DefaultUpstreamContentType
+	if oc.SniffContentType && resp.Body != nil && resp.Header.Get(headers.NameContentEncoding) == "" {
+		br := bufio.NewReaderSize(resp.Body, sniffLen)
+		if b, _ := br.Peek(sniffLen); len(b) > 0 {
+			// the generic type indicates the body was not recognized
+			if st := http.DetectContentType(b); st != "application/octet-stream" || ct == "" {
+				ct = st
+			}
+		}
+		resp.Body = &sniffedBody{Reader: br, Closer: resp.Body}
+	}
+
+	if ct != "" {
+		resp.Header.Set(headers.NameContentType, ct)
+	}
+}
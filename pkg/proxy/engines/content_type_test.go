@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+func TestApplyDefaultContentType(t *testing.T) {
+
+	const html = "<!DOCTYPE html><html><body>test</body></html>"
+
+	tests := []struct {
+		defaultType string
+		sniff       bool
+		code        int
+		header      http.Header
+		body        string
+		expected    string
+	}{
+		// no options configured
+		{"", false, http.StatusOK, http.Header{}, html, ""},
+		// the upstream's Content-Type is retained
+		{"application/json", true, http.StatusOK,
+			http.Header{headers.NameContentType: []string{headers.ValueTextPlain}}, html, headers.ValueTextPlain},
+		{"application/json", false, http.StatusOK, http.Header{}, html, "application/json"},
+		{"", true, http.StatusOK, http.Header{}, html, "text/html; charset=utf-8"},
+		{"application/json", true, http.StatusOK, http.Header{}, html, "text/html; charset=utf-8"},
+		// unrecognized bodies use the default, when set
+		{"application/json", true, http.StatusOK, http.Header{}, "\x00\x01\x02", "application/json"},
+		{"", true, http.StatusOK, http.Header{}, "\x00\x01\x02", "application/octet-stream"},
+		// empty and encoded bodies are not sniffed
+		{"", true, http.StatusOK, http.Header{}, "", ""},
+		{"application/json", true, http.StatusOK,
+			http.Header{headers.NameContentEncoding: []string{"gzip"}}, html, "application/json"},
+		// responses without a body are unchanged
+		{"application/json", true, http.StatusNotModified, http.Header{}, "", ""},
+	}
+
+	for i, test := range tests {
+		resp := &http.Response{StatusCode: test.code, Header: test.header,
+			Body: ioutil.NopCloser(strings.NewReader(test.body))}
+		applyDefaultContentType(resp, &oo.Options{DefaultUpstreamContentType: test.defaultType,
+			SniffContentType: test.sniff})
+		if v := resp.Header.Get(headers.NameContentType); v != test.expected {
+			t.Errorf("test %d: expected %s got %s", i, test.expected, v)
+		}
+		// the full body remains readable after sniffing
+		if b, _ := ioutil.ReadAll(resp.Body); string(b) != test.body {
+			t.Errorf("test %d: expected body %s got %s", i, test.body, string(b))
+		}
+	}
+}
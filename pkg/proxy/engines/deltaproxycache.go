@@ -18,6 +18,7 @@ package engines
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"sync"
 	"time"
@@ -30,6 +31,8 @@ import (
 	tpe "github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/queryshape"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
@@ -57,6 +60,11 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	r = r.WithContext(ctx)
 
 	pc := rsc.PathConfig
+	if (pc != nil && pc.CacheDisabled) || !oc.IsCacheableMethod(r.Method) {
+		DoProxy(w, r, true)
+		return
+	}
+
 	cache := rsc.CacheClient
 	cc := rsc.CacheConfig
 	locker := cache.Locker()
@@ -70,7 +78,16 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if requested := trq.Extent.End.Sub(trq.Extent.Start); exceedsMaxQueryRange(requested, oc) {
+		rh, body := rangeGuardResponse(requested, oc)
+		recordDPCResult(r, status.LookupStatusProxyOnly, http.StatusBadRequest, r.URL.Path, "",
+			0, nil, rh)
+		Respond(w, http.StatusBadRequest, rh, body)
+		return
+	}
+
 	var cacheStatus status.LookupStatus
+	var cachedDate time.Time
 
 	pr := newProxyRequest(r, w)
 	trq.FastForwardDisable = oc.FastForwardDisable || trq.FastForwardDisable
@@ -106,8 +123,51 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	client.SetExtent(pr.upstreamRequest, trq, &trq.Extent)
-	key := oc.CacheKeyPrefix + ".dpc." + pr.DeriveCacheKey(trq.TemplateURL, "")
-	pr.cacheLock, _ = locker.RAcquire(key)
+	key := limitKeyLength(oc.CacheKeyPrefix+".dpc."+pr.DeriveCacheKey(trq.TemplateURL, ""), rsc.CacheConfig)
+	if pr.keyRejected {
+		pr.Logger.Debug("cache key components exceed limit, proxying without caching",
+			tl.Pairs{"maxCacheKeyComponents": oc.MaxCacheKeyComponents})
+		DoProxy(w, r, true)
+		return
+	}
+
+	if oc.QueryShapes != nil {
+		admitted := oc.QueryShapes.Admit(queryshape.Normalize(trq.Statement))
+		metrics.ProxyDistinctQueryShapes.WithLabelValues(oc.Name, oc.OriginType).
+			Set(float64(oc.QueryShapes.Len()))
+		if !admitted {
+			pr.Logger.Debug("max distinct query shapes exceeded, proxying without caching",
+				tl.Pairs{"maxDistinctQueryShapes": oc.MaxDistinctQueryShapes})
+			DoProxy(w, r, true)
+			return
+		}
+	}
+
+	// the read lock blocks while another request holds the write lock to fetch the timeseries
+	ok, done := joinCollapsedWaiters(oc, key)
+	if !ok {
+		pr.Logger.Debug("max collapsed waiters exceeded",
+			tl.Pairs{"maxCollapsedWaiters": oc.MaxCollapsedWaiters, "policy": oc.CollapsedWaitersPolicy})
+		if oc.CollapsedWaitersPolicy == oo.CollapsedWaitersPolicyReject {
+			respondCollapsedWaitersExceeded(w, r)
+			return
+		}
+		DoProxy(w, r, true)
+		return
+	}
+	pr.cacheLock, ok = acquireCollapsedReadLock(oc, locker, key)
+	done()
+	if !ok {
+		pr.Logger.Debug("collapsed forwarding timeout exceeded",
+			tl.Pairs{"collapsedForwardingTimeoutMS": oc.CollapsedForwardingTimeoutMS,
+				"policy": oc.CollapsedForwardingTimeoutPolicy})
+		if oc.CollapsedForwardingTimeoutPolicy == oo.CollapsedWaitersPolicyReject {
+			respondCollapsedWaitersExceeded(w, r)
+			return
+		}
+		DoProxy(w, r, true)
+		return
+	}
 
 	// this is used to determine if Fast Forward should be activated for this request
 	normalizedNow := &timeseries.TimeRangeQuery{
@@ -119,6 +179,8 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	var cts timeseries.Timeseries
 	var doc *HTTPDocument
 	var elapsed time.Duration
+	// cacheLookupTime and upstreamTime are reported in the Server-Timing header
+	var cacheLookupTime, upstreamTime time.Duration
 
 	coReq := GetRequestCachingPolicy(r.Header)
 	if coReq.NoCache {
@@ -131,6 +193,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		cacheStatus = status.LookupStatusPurge
 		go cache.Remove(key)
 		cts, doc, elapsed, err = fetchTimeseries(pr, trq, client)
+		upstreamTime = elapsed
 		if err != nil {
 			pr.cacheLock.RRelease()
 			h := doc.SafeHeaderClone()
@@ -140,9 +203,13 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 			return // fetchTimeseries logs the error
 		}
 	} else {
+		lookupStart := time.Now()
 		doc, cacheStatus, _, err = QueryCache(ctx, cache, key, nil)
+		cacheLookupTime = time.Since(lookupStart)
 		if cacheStatus == status.LookupStatusKeyMiss && err == tc.ErrKNF {
+			pr.stripConditionalHeadersOnMiss()
 			cts, doc, elapsed, err = fetchTimeseries(pr, trq, client)
+			upstreamTime = elapsed
 			if err != nil {
 				pr.cacheLock.RRelease()
 				h := doc.SafeHeaderClone()
@@ -166,7 +233,9 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 				pr.Logger.Error("cache object unmarshaling failed",
 					tl.Pairs{"key": key, "originName": client.Name(), "detail": err.Error()})
 				go cache.Remove(key)
+				pr.stripConditionalHeadersOnMiss()
 				cts, doc, elapsed, err = fetchTimeseries(pr, trq, client)
+				upstreamTime = elapsed
 				if err != nil {
 					pr.cacheLock.RRelease()
 					h := doc.SafeHeaderClone()
@@ -203,6 +272,9 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 					}
 				}
 				cacheStatus = status.LookupStatusPartialHit
+				if doc.CachingPolicy != nil {
+					cachedDate = doc.CachingPolicy.LocalDate
+				}
 			}
 		}
 	}
@@ -290,17 +362,21 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		dpStatus["extentsFetched"] = missRanges.String()
 	}
 
+	fetchStart := time.Now()
 	// maintain a list of timeseries to merge into the main timeseries
 	mts := make([]timeseries.Timeseries, 0, len(missRanges))
 	wg := sync.WaitGroup{}
 	appendLock := sync.Mutex{}
 	uncachedValueCount := 0
+	// breakerResp is set when the origin's circuit breaker rejects the fetch of a missing range
+	var breakerResp *http.Response
 
 	// iterate each time range that the client needs and fetch from the upstream origin
 	for i := range missRanges {
 		wg.Add(1)
 		// This fetches the gaps from the origin and adds their datasets to the merge list
-		go func(e *timeseries.Extent, rq *proxyRequest) {
+		e, rq := &missRanges[i], pr.Clone()
+		goTracked(oc, func() {
 			defer wg.Done()
 			rq.upstreamRequest = rq.WithContext(tctx.WithResources(
 				trace.ContextWithSpan(context.Background(), span),
@@ -314,6 +390,12 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 			}
 
 			body, resp, _ := rq.Fetch()
+			if isBreakerOpenResponse(resp) {
+				appendLock.Lock()
+				breakerResp = resp
+				appendLock.Unlock()
+				return
+			}
 			if resp.StatusCode == http.StatusOK && len(body) > 0 {
 				nts, err := client.UnmarshalTimeseries(body)
 				if err != nil {
@@ -331,7 +413,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 				mts = append(mts, nts)
 				appendLock.Unlock()
 			}
-		}(&missRanges[i], pr.Clone())
+		})
 	}
 
 	var hasFastForwardData bool
@@ -341,7 +423,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	if (!trq.FastForwardDisable) &&
 		(trq.Extent.End.Equal(normalizedNow.Extent.End)) {
 		wg.Add(1)
-		go func() {
+		goTracked(oc, func() {
 			defer wg.Done()
 			_, span := tspan.NewChildSpan(ctx, rsc.Tracer, "FetchFastForward")
 			if span != nil {
@@ -368,7 +450,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 			} else {
 				ffStatus = "err"
 			}
-		}()
+		})
 	}
 
 	wg.Wait()
@@ -379,13 +461,16 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		elapsed = time.Since(now)
 		cts.Merge(true, mts...)
 	}
+	if len(missRanges) > 0 {
+		upstreamTime = time.Since(fetchStart)
+	}
 
 	// cts is the cacheable time series, rts is the user's response timeseries
 	rts := cts.Clone()
 
 	if writeLock != nil {
 		// if the mutex is still locked, it means we need to write the time series to cache
-		go func() {
+		goTracked(oc, func() {
 			defer writeLock.Release()
 			// Crop the Cache Object down to the Sample Size or Age Retention Policy and the
 			// Backfill Tolerance before storing to cache
@@ -398,6 +483,10 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 			// Don't cache datasets with empty extents
 			// (everything was cropped so there is nothing to cache)
 			if len(cts.Extents()) > 0 {
+				// the stored date is that of the oldest cached data, so it is kept across merges
+				if doc.CachingPolicy == nil {
+					doc.CachingPolicy = &CachingPolicy{LocalDate: time.Now()}
+				}
 				if cc.CacheType == "memory" {
 					doc.timeseries = cts
 				} else {
@@ -411,7 +500,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 					}
 					doc.Body = cdata
 				}
-				if err := WriteCache(ctx, cache, key, doc, oc.TimeseriesTTL, oc.CompressableTypes); err != nil {
+				if err := WriteCache(ctx, cache, key, doc, timeseriesTTL(oc, trq.Extent), oc.CompressableTypes); err != nil {
 					pr.Logger.Error("error writing object to cache",
 						tl.Pairs{
 							"originName": oc.Name,
@@ -422,7 +511,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 					)
 				}
 			}
-		}()
+		})
 	}
 
 	// if it was a cache key miss, there is no need to undergo Crop since the extents are identical
@@ -441,6 +530,19 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 			oc.OriginType, "cached", r.URL.Path).Add(float64(cachedValueCount))
 	}
 
+	// while the origin's circuit breaker rejects requests, the stale cached data is served
+	// without the missing ranges, unless none of the requested range is cached
+	if breakerResp != nil && uncachedValueCount == 0 {
+		if cachedValueCount == 0 {
+			h := breakerResp.Header
+			recordDPCResult(r, status.LookupStatusProxyError, breakerResp.StatusCode, r.URL.Path,
+				ffStatus, elapsed.Seconds(), missRanges, h)
+			Respond(w, breakerResp.StatusCode, h, nil)
+			return
+		}
+		cacheStatus = status.LookupStatusHit
+	}
+
 	// Merge Fast Forward data if present. This must be done after the Downstream Crop since
 	// the cropped extent was normalized to stepboundaries and would remove fast forward data
 	// If the fast forward data point is older (e.g. cached) than the last datapoint in the
@@ -449,6 +551,16 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 		ffts.Extents()[0].Start.Truncate(time.Second).After(normalizedNow.Extent.End) {
 		rts.Merge(false, ffts)
 	}
+
+	if !limitResponseDataPoints(rts, oc) {
+		rh := http.Header{headers.NameContentType: []string{headers.ValueTextPlain}}
+		recordDPCResult(r, cacheStatus, http.StatusBadRequest, r.URL.Path, ffStatus,
+			elapsed.Seconds(), missRanges, rh)
+		Respond(w, http.StatusBadRequest, rh, []byte(fmt.Sprintf(
+			"timeseries response exceeds the max_response_data_points of %d", oc.MaxResponseDataPoints)))
+		return
+	}
+
 	rts.SetExtents(nil) // so they are not included in the client response json
 	rts.SetStep(0)
 	rdata, err := client.MarshalTimeseries(rts)
@@ -458,10 +570,55 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
 	// Respond to the user. Using the response headers from a Delta Response,
 	// so as to not map conflict with cacheData on WriteCache
 	logDeltaRoutine(pr.Logger, dpStatus)
+	if oc.EmitServerTiming {
+		setServerTimingHeader(rh, cacheLookupTime, upstreamTime)
+	}
+	setAgeHeader(rh, oc, cacheStatus, cachedDate)
+	// the cached data is served without the ranges the circuit breaker kept from being fetched
+	if breakerResp != nil {
+		setStaleWarning(rh, oc, false)
+	}
 	recordDPCResult(r, cacheStatus, sc, r.URL.Path, ffStatus, elapsed.Seconds(), missRanges, rh)
 	Respond(w, sc, rh, rdata)
 }
 
+// timeseriesTTL returns the cache TTL for a timeseries object covering the provided extent.
+// When the origin has a TTLAsRangeFraction, the TTL is the fraction of the extent's duration,
+// floored by TTLAsRangeFractionMin and capped by MaxTTL; otherwise it is TimeseriesTTL
+func timeseriesTTL(oc *oo.Options, e timeseries.Extent) time.Duration {
+	if oc.TTLAsRangeFraction <= 0 {
+		return oc.TimeseriesTTL
+	}
+	ttl := time.Duration(float64(e.End.Sub(e.Start)) * oc.TTLAsRangeFraction)
+	if ttl < oc.TTLAsRangeFractionMin {
+		ttl = oc.TTLAsRangeFractionMin
+	}
+	if oc.MaxTTL > 0 && ttl > oc.MaxTTL {
+		ttl = oc.MaxTTL
+	}
+	return ttl
+}
+
+// limitResponseDataPoints downsamples a timeseries having more values than the origin's
+// MaxResponseDataPoints, per its MaxResponseDataPointsPolicy. It returns false if the
+// response is to be rejected instead, including when the timeseries does not support
+// downsampling, or has too many series to downsample to the limit
+func limitResponseDataPoints(ts timeseries.Timeseries, oc *oo.Options) bool {
+	if oc.MaxResponseDataPoints <= 0 || ts.ValueCount() <= oc.MaxResponseDataPoints {
+		return true
+	}
+	method, ok := timeseries.DownsampleMethodNames[oc.MaxResponseDataPointsPolicy]
+	ds, canDownsample := ts.(timeseries.Downsampler)
+	sc := ts.SeriesCount()
+	if !ok || !canDownsample || sc == 0 || oc.MaxResponseDataPoints/sc < 2 {
+		metrics.ProxyLimitedResponses.WithLabelValues(oc.Name, oc.OriginType, "rejected").Inc()
+		return false
+	}
+	ds.Downsample(oc.MaxResponseDataPoints/sc, method)
+	metrics.ProxyLimitedResponses.WithLabelValues(oc.Name, oc.OriginType, "downsampled").Inc()
+	return true
+}
+
 func logDeltaRoutine(log *tl.Logger, p tl.Pairs) { log.Debug("delta routine completed", p) }
 
 func fetchTimeseries(pr *proxyRequest, trq *timeseries.TimeRangeQuery,
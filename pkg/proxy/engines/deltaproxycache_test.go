@@ -21,14 +21,20 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	mockprom "github.com/tricksterproxy/mockster/pkg/mocks/prometheus"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/queryshape"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+
+	"github.com/prometheus/common/model"
 )
 
 // test queries
@@ -123,6 +129,10 @@ func TestDeltaProxyCacheRequestMissThenHit(t *testing.T) {
 		t.Error(err)
 	}
 
+	if v := resp.Header.Get(headers.NameAge); v != "" {
+		t.Errorf("expected no Age header on a miss, got %s", v)
+	}
+
 	// Give time for the object to be written to cache in a separate goroutine from response
 	time.Sleep(time.Millisecond * 10)
 
@@ -151,6 +161,10 @@ func TestDeltaProxyCacheRequestMissThenHit(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+
+	if v := resp.Header.Get(headers.NameAge); v != "0" {
+		t.Errorf("expected %s got %s", "0", v)
+	}
 }
 
 func TestDeltaProxyCacheRequestAllItemsTooNew(t *testing.T) {
@@ -1583,3 +1597,235 @@ func TestDeltaProxyCacheRequestFFTTLBiggerThanStep(t *testing.T) {
 	}
 
 }
+
+func TestTimeseriesTTL(t *testing.T) {
+
+	oc := oo.NewOptions()
+	oc.TimeseriesTTL = 6 * time.Hour
+	oc.MaxTTL = 24 * time.Hour
+	oc.TTLAsRangeFractionMin = 30 * time.Second
+
+	now := time.Now()
+	tests := []struct {
+		fraction float64
+		extent   timeseries.Extent
+		expected time.Duration
+	}{
+		{0, timeseries.Extent{Start: now.Add(-1 * time.Hour), End: now}, 6 * time.Hour},
+		{0.1, timeseries.Extent{Start: now.Add(-6 * time.Hour), End: now}, 36 * time.Minute},
+		{0.1, timeseries.Extent{Start: now.Add(-1 * time.Minute), End: now}, 30 * time.Second},
+		{2, timeseries.Extent{Start: now.Add(-48 * time.Hour), End: now}, 24 * time.Hour},
+	}
+
+	for i, test := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			oc.TTLAsRangeFraction = test.fraction
+			if ttl := timeseriesTTL(oc, test.extent); ttl != test.expected {
+				t.Errorf("expected %s got %s", test.expected, ttl)
+			}
+		})
+	}
+}
+
+// downsamplingEnvelope is a MatrixEnvelope that records how it was downsampled
+type downsamplingEnvelope struct {
+	*MatrixEnvelope
+	sz     int
+	method timeseries.DownsampleMethod
+}
+
+func (de *downsamplingEnvelope) Downsample(sz int, method timeseries.DownsampleMethod) {
+	de.sz, de.method = sz, method
+}
+
+func TestLimitResponseDataPoints(t *testing.T) {
+
+	newEnvelope := func(series, values int) *MatrixEnvelope {
+		me := &MatrixEnvelope{Data: MatrixData{ResultType: "matrix"}}
+		for i := 0; i < series; i++ {
+			s := &model.SampleStream{Metric: model.Metric{"__name__": model.LabelValue(strconv.Itoa(i))}}
+			for j := 0; j < values; j++ {
+				s.Values = append(s.Values, model.SamplePair{Timestamp: model.Time(j * 1000), Value: 1})
+			}
+			me.Data.Result = append(me.Data.Result, s)
+		}
+		return me
+	}
+
+	oc := oo.NewOptions()
+	oc.Name = "test"
+	oc.OriginType = "test"
+
+	// no limit
+	if !limitResponseDataPoints(newEnvelope(2, 100), oc) {
+		t.Error("expected true")
+	}
+
+	oc.MaxResponseDataPoints = 50
+	de := &downsamplingEnvelope{MatrixEnvelope: newEnvelope(2, 100)}
+	if !limitResponseDataPoints(de, oc) {
+		t.Error("expected true")
+	}
+	if de.sz != 25 || de.method != timeseries.DownsampleLTTB {
+		t.Errorf("expected %d %d got %d %d", 25, timeseries.DownsampleLTTB, de.sz, de.method)
+	}
+
+	oc.MaxResponseDataPointsPolicy = "average"
+	de = &downsamplingEnvelope{MatrixEnvelope: newEnvelope(2, 100)}
+	if !limitResponseDataPoints(de, oc) || de.method != timeseries.DownsampleAverage {
+		t.Error("expected average downsampling")
+	}
+
+	// too many series to downsample
+	if limitResponseDataPoints(&downsamplingEnvelope{MatrixEnvelope: newEnvelope(30, 10)}, oc) {
+		t.Error("expected false")
+	}
+
+	// timeseries that do not support downsampling are rejected
+	if limitResponseDataPoints(newEnvelope(2, 100), oc) {
+		t.Error("expected false")
+	}
+
+	oc.MaxResponseDataPointsPolicy = oo.MaxResponseDataPointsPolicyReject
+	if limitResponseDataPoints(&downsamplingEnvelope{MatrixEnvelope: newEnvelope(2, 100)}, oc) {
+		t.Error("expected false")
+	}
+}
+
+func TestDeltaProxyCacheRequestMaxResponseDataPoints(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.MaxResponseDataPoints = 10
+	oc.MaxResponseDataPointsPolicy = oo.MaxResponseDataPointsPolicyReject
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusBadRequest)
+	if err != nil {
+		t.Error(err)
+	}
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(string(bodyBytes), "max_response_data_points") {
+		t.Errorf("unexpected body %s", string(bodyBytes))
+	}
+}
+
+func TestDeltaProxyCacheRequestMaxQueryRange(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.MaxQueryRange = time.Duration(6) * time.Hour
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusBadRequest)
+	if err != nil {
+		t.Error(err)
+	}
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	expected := "requested range of 18h exceeds the maximum range of 6h allowed for this origin"
+	if string(bodyBytes) != expected {
+		t.Errorf("expected %s got %s", expected, string(bodyBytes))
+	}
+
+	// requests within the range are served
+	w = httptest.NewRecorder()
+	oc.MaxQueryRange = time.Duration(24) * time.Hour
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeltaProxyCacheRequestMaxDistinctQueryShapes(t *testing.T) {
+
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	client := rsc.OriginClient.(*TestClient)
+	oc := rsc.OriginConfig
+	oc.FastForwardDisable = true
+	oc.MaxDistinctQueryShapes = 1
+	oc.QueryShapes = queryshape.New(1, time.Hour)
+	oc.QueryShapes.Admit("other_query{job=?}")
+
+	step := time.Duration(300) * time.Second
+	end := time.Now().Add(-time.Duration(12) * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	// the query is of a new shape beyond the limit, so it is proxied without caching
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "proxy-only"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	oc.QueryShapes = queryshape.New(1, time.Hour)
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	err = testResultHeaderPartMatch(w.Result().Header, map[string]string{"status": "kmiss"})
+	if err != nil {
+		t.Error(err)
+	}
+	if n := oc.QueryShapes.Len(); n != 1 {
+		t.Errorf("expected %d got %d", 1, n)
+	}
+}
@@ -36,18 +36,21 @@ import (
 
 // HTTPDocument represents a full HTTP Response/Cache Document with unbuffered body
 type HTTPDocument struct {
-	StatusCode    int                 `msg:"status_code"`
-	Status        string              `msg:"status"`
-	Headers       map[string][]string `msg:"headers"`
-	Body          []byte              `msg:"body"`
-	ContentLength int64               `msg:"content_length"`
-	ContentType   string              `msg:"content_type"`
-	CachingPolicy *CachingPolicy      `msg:"caching_policy"`
+	StatusCode    int                 `msg:"status_code" json:"status_code"`
+	Status        string              `msg:"status" json:"status"`
+	Headers       map[string][]string `msg:"headers" json:"headers"`
+	Body          []byte              `msg:"body" json:"body"`
+	ContentLength int64               `msg:"content_length" json:"content_length"`
+	ContentType   string              `msg:"content_type" json:"content_type"`
+	CachingPolicy *CachingPolicy      `msg:"caching_policy" json:"caching_policy"`
 	// Ranges is the list of Byte Ranges contained in the body of this document
-	Ranges     byterange.Ranges              `msg:"ranges"`
-	RangeParts byterange.MultipartByteRanges `msg:"-"`
+	Ranges     byterange.Ranges              `msg:"ranges" json:"ranges"`
+	RangeParts byterange.MultipartByteRanges `msg:"-" json:"-"`
 	// StoredRangeParts is a version of RangeParts that can be exported to MessagePack
-	StoredRangeParts map[string]*byterange.MultipartByteRange `msg:"range_parts"`
+	StoredRangeParts map[string]*byterange.MultipartByteRange `msg:"range_parts" json:"range_parts"`
+	// BodyEncoding is the codec that the stored Body was compressed with per the origin's
+	// cache_compression setting, or empty if the Body is stored uncompressed
+	BodyEncoding string `msg:"body_encoding" json:"body_encoding"`
 
 	rangePartsLoaded bool
 	isFulfillment    bool
@@ -163,6 +163,11 @@ func (z *HTTPDocument) DecodeMsg(dc *msgp.Reader) (err error) {
 				}
 				z.StoredRangeParts[za0004] = za0005
 			}
+		case "body_encoding":
+			z.BodyEncoding, err = dc.ReadString()
+			if err != nil {
+				return
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -177,7 +182,7 @@ func (z *HTTPDocument) DecodeMsg(dc *msgp.Reader) (err error) {
 func (z *HTTPDocument) EncodeMsg(en *msgp.Writer) (err error) {
 	// map header, size 9
 	// write "status_code"
-	err = en.Append(0x89, 0xab, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x5f, 0x63, 0x6f, 0x64, 0x65)
+	err = en.Append(0x8a, 0xab, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x5f, 0x63, 0x6f, 0x64, 0x65)
 	if err != nil {
 		return
 	}
@@ -297,6 +302,15 @@ func (z *HTTPDocument) EncodeMsg(en *msgp.Writer) (err error) {
 			}
 		}
 	}
+	// write "body_encoding"
+	err = en.Append(0xad, 0x62, 0x6f, 0x64, 0x79, 0x5f, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.BodyEncoding)
+	if err != nil {
+		return
+	}
 	return
 }
 
@@ -305,7 +319,7 @@ func (z *HTTPDocument) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
 	// map header, size 9
 	// string "status_code"
-	o = append(o, 0x89, 0xab, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x5f, 0x63, 0x6f, 0x64, 0x65)
+	o = append(o, 0x8a, 0xab, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x5f, 0x63, 0x6f, 0x64, 0x65)
 	o = msgp.AppendInt(o, z.StatusCode)
 	// string "status"
 	o = append(o, 0xa6, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73)
@@ -359,6 +373,9 @@ func (z *HTTPDocument) MarshalMsg(b []byte) (o []byte, err error) {
 			}
 		}
 	}
+	// string "body_encoding"
+	o = append(o, 0xad, 0x62, 0x6f, 0x64, 0x79, 0x5f, 0x65, 0x6e, 0x63, 0x6f, 0x64, 0x69, 0x6e, 0x67)
+	o = msgp.AppendString(o, z.BodyEncoding)
 	return
 }
 
@@ -501,6 +518,11 @@ func (z *HTTPDocument) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				}
 				z.StoredRangeParts[za0004] = za0005
 			}
+		case "body_encoding":
+			z.BodyEncoding, bts, err = msgp.ReadStringBytes(bts)
+			if err != nil {
+				return
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -542,5 +564,6 @@ func (z *HTTPDocument) Msgsize() (s int) {
 			}
 		}
 	}
+	s += 14 + msgp.StringPrefixSize + len(z.BodyEncoding)
 	return
 }
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/signing"
+	"github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// isFailoverResponse returns true if the upstream response warrants failing over to the
+// origin's failover origins
+func isFailoverResponse(resp *http.Response, err error) bool {
+	return err != nil || resp == nil || resp.StatusCode >= http.StatusInternalServerError
+}
+
+// failoverRequest returns a copy of r, which was prepared for the origin oc, that is
+// addressed to the failover origin fo, or nil if the request body cannot be reissued
+func failoverRequest(r *http.Request, oc, fo *oo.Options) *http.Request {
+	r2 := retryRequest(r)
+	if r2 == nil {
+		return nil
+	}
+	rebaseURL(r2.URL, oc.PathPrefix, fo.Scheme, fo.Host, fo.PathPrefix)
+	if fo.RequestSigning != nil {
+		signing.SignRequest(r2, fo.RequestSigning, time.Now())
+	}
+	return r2
+}
+
+// rebaseURL readdresses u, which has the path prefix fromPrefix, to the provided scheme, host and
+// path prefix
+func rebaseURL(u *url.URL, fromPrefix, scheme, host, pathPrefix string) {
+	u.Scheme = scheme
+	u.Host = host
+	u.Path = pathPrefix + strings.TrimPrefix(u.Path, fromPrefix)
+	u.RawPath = ""
+}
+
+// doUpstream sends r to the origin, with retries per the origin's retry options, unless its
+// circuit breaker is open. If the origin still fails with a connection error or a 5xx
+// response, or its circuit breaker is open, idempotent requests are reissued against each
+// of the origin's failover origins in order, until one succeeds or all have failed, in
+// which case the final failure is returned. Since only the upstream
+// request is reissued, the response is cached under the key of the requested origin
+func doUpstream(r *http.Request, oc *oo.Options, logger *log.Logger) (*http.Response, error) {
+
+	resp, err := doWithBreaker(r, oc, logger)
+	if len(oc.FailoverOriginConfigs) == 0 || !isRetryableMethod(r.Method) ||
+		!isFailoverResponse(resp, err) {
+		return resp, err
+	}
+
+	servedBy := oc.Name
+	for _, fo := range oc.FailoverOriginConfigs {
+		if r.Context().Err() != nil || fo.HTTPClient == nil {
+			break
+		}
+		r2 := failoverRequest(r, oc, fo)
+		if r2 == nil {
+			break
+		}
+		pairs := log.Pairs{"originName": oc.Name, "failoverOriginName": fo.Name,
+			"url": r.URL.String()}
+		if err != nil {
+			pairs["detail"] = err.Error()
+		} else {
+			pairs["statusCode"] = resp.StatusCode
+		}
+		logger.Warn("failing over upstream request", pairs)
+
+		discardResponse(resp)
+		resp, err = doWithBreaker(r2, fo, logger)
+		servedBy = fo.Name
+		if !isFailoverResponse(resp, err) {
+			break
+		}
+	}
+	metrics.ProxyFailoverResponses.WithLabelValues(oc.Name, oc.OriginType, servedBy).Inc()
+	return resp, err
+}
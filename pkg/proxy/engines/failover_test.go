@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+)
+
+func failoverTestOrigin(t *testing.T, oc *oo.Options, name, u string) *oo.Options {
+	fo := oc.Clone()
+	fo.Name = name
+	fo.RetryMaxAttempts = 0
+	pu, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fo.Scheme = pu.Scheme
+	fo.Host = pu.Host
+	fo.HTTPClient = http.DefaultClient
+	return fo
+}
+
+func TestDoProxyFailover(t *testing.T) {
+
+	s, count := newRetryTestServer(5, http.StatusServiceUnavailable)
+	defer s.Close()
+	s2, count2 := newRetryTestServer(5, http.StatusBadGateway)
+	defer s2.Close()
+	s3, count3 := newRetryTestServer(0, http.StatusOK)
+	defer s3.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s.URL+"/api/v1/query?query=up", 1)
+	oc := request.GetResources(r).OriginConfig
+	oc.FailoverOriginConfigs = []*oo.Options{
+		failoverTestOrigin(t, oc, "standby1", s2.URL),
+		failoverTestOrigin(t, oc, "standby2", s3.URL),
+	}
+
+	w := httptest.NewRecorder()
+	DoProxy(w, r, true)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	if string(b) != "ok" {
+		t.Errorf("expected %s got %s", "ok", string(b))
+	}
+	// the primary is retried once, and the failover origins are tried in order
+	if *count != 2 || *count2 != 1 || *count3 != 1 {
+		t.Errorf("expected [2 1 1] got [%d %d %d]", *count, *count2, *count3)
+	}
+}
+
+func TestDoProxyFailoverNotNeeded(t *testing.T) {
+
+	s, count := newRetryTestServer(0, http.StatusOK)
+	defer s.Close()
+	s2, count2 := newRetryTestServer(0, http.StatusOK)
+	defer s2.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s.URL, 0)
+	oc := request.GetResources(r).OriginConfig
+	oc.FailoverOriginConfigs = []*oo.Options{failoverTestOrigin(t, oc, "standby", s2.URL)}
+
+	DoProxy(httptest.NewRecorder(), r, true)
+	if *count != 1 || *count2 != 0 {
+		t.Errorf("expected [1 0] got [%d %d]", *count, *count2)
+	}
+
+	// non-idempotent requests are not failed over
+	s3, count3 := newRetryTestServer(1, http.StatusServiceUnavailable)
+	defer s3.Close()
+	r = retryTestRequest(t, http.MethodPost, s3.URL, 0)
+	oc = request.GetResources(r).OriginConfig
+	oc.FailoverOriginConfigs = []*oo.Options{failoverTestOrigin(t, oc, "standby", s2.URL)}
+
+	w := httptest.NewRecorder()
+	DoProxy(w, r, true)
+	if w.Result().StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Result().StatusCode)
+	}
+	if *count3 != 1 || *count2 != 0 {
+		t.Errorf("expected [1 0] got [%d %d]", *count3, *count2)
+	}
+}
+
+func TestFailoverRequest(t *testing.T) {
+
+	r := retryTestRequest(t, http.MethodGet, "http://primary/prefix/api/v1/query?query=up", 0)
+	oc := request.GetResources(r).OriginConfig
+	oc.PathPrefix = "/prefix"
+	fo := failoverTestOrigin(t, oc, "standby", "https://standby:9090")
+	fo.PathPrefix = "/other"
+
+	r2 := failoverRequest(r, oc, fo)
+	const expected = "https://standby:9090/other/api/v1/query?query=up"
+	if r2.URL.String() != expected {
+		t.Errorf("expected %s got %s", expected, r2.URL.String())
+	}
+	if r.URL.Host != "primary" {
+		t.Errorf("expected %s got %s", "primary", r.URL.Host)
+	}
+}
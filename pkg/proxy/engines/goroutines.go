@@ -0,0 +1,37 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// goTracked runs f in a new goroutine, which is counted in the origin's
+// active goroutines gauge for as long as it is running
+func goTracked(oc *oo.Options, f func()) {
+	if oc == nil {
+		go f()
+		return
+	}
+	g := metrics.ProxyActiveGoroutines.WithLabelValues(oc.Name, oc.OriginType)
+	g.Inc()
+	go func() {
+		defer g.Dec()
+		f()
+	}()
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"sync"
+	"testing"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestGoTracked(t *testing.T) {
+
+	oc := oo.NewOptions()
+	oc.Name = "test-go-tracked"
+	oc.OriginType = "test"
+
+	g := metrics.ProxyActiveGoroutines.WithLabelValues(oc.Name, oc.OriginType)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	release := make(chan bool)
+	goTracked(oc, func() {
+		defer wg.Done()
+		<-release
+	})
+
+	m := &dto.Metric{}
+	g.Write(m)
+	if v := m.GetGauge().GetValue(); v != 1 {
+		t.Errorf("expected %d got %f", 1, v)
+	}
+
+	close(release)
+	wg.Wait()
+
+	wg.Add(1)
+	goTracked(nil, func() { wg.Done() })
+	wg.Wait()
+}
@@ -18,9 +18,11 @@ package engines
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"io/ioutil"
 	"math"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -28,11 +30,14 @@ import (
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/params"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/signing"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
@@ -52,9 +57,19 @@ const HTTPBlockSize = 32 * 1024
 // DoProxy proxies an inbound request to its corresponding upstream origin with no caching features
 func DoProxy(w io.Writer, r *http.Request, closeResponse bool) *http.Response {
 
+	if isUpgradeRequest(w, r) {
+		return doUpgradeProxy(w, r)
+	}
+
 	rsc := request.GetResources(r)
 	oc := rsc.OriginConfig
 
+	if rsc.CacheClient != nil {
+		if key := idempotencyCacheKey(r, oc); key != "" {
+			return doIdempotentProxy(w, r, key)
+		}
+	}
+
 	start := time.Now()
 
 	_, span := tspan.NewChildSpan(r.Context(), rsc.Tracer, "ProxyRequest")
@@ -79,7 +94,7 @@ func DoProxy(w io.Writer, r *http.Request, closeResponse bool) *http.Response {
 		}
 	} else {
 		pr := newProxyRequest(r, w)
-		key := oc.CacheKeyPrefix + "." + pr.DeriveCacheKey(nil, "")
+		key := pr.deriveCoalescingKey(oc.CacheKeyPrefix + "." + pr.DeriveCacheKey(nil, ""))
 		result, ok := reqs.Load(key)
 		if !ok {
 			var contentLength int64
@@ -93,14 +108,14 @@ func DoProxy(w io.Writer, r *http.Request, closeResponse bool) *http.Response {
 				// Blocks until server completes
 				grClose := reader != nil && closeResponse
 				closeResponse = false
-				go func() {
+				goTracked(oc, func() {
 					io.Copy(pcf, reader)
 					pcf.Close()
 					reqs.Delete(key)
 					if grClose {
 						reader.Close()
 					}
-				}()
+				})
 				pcf.AddClient(writer)
 			}
 		} else {
@@ -151,6 +166,19 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 
 	var rc io.ReadCloser
 
+	if oc.StripHopByHopHeaders {
+		headers.StripHopByHopHeaders(r.Header)
+	}
+
+	if err := handleExpectContinue(r, oc.Handle100Continue, oc.Max100ContinueBodyBytes); err != nil {
+		rsc.Logger.Error("error reading request body", log.Pairs{"url": r.URL.String(), "detail": err.Error()})
+		code := http.StatusBadRequest
+		if err == errors.ErrRequestBodyTooLarge {
+			code = http.StatusRequestEntityTooLarge
+		}
+		return nil, &http.Response{StatusCode: code, Request: r, Header: make(http.Header)}, 0
+	}
+
 	headers.AddForwardingHeaders(r, oc.ForwardedHeaders)
 
 	if pc != nil {
@@ -160,6 +188,10 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 		params.SetRequestValues(r, qp)
 	}
 
+	if oc.RequestSigning != nil {
+		signing.SignRequest(r, oc.RequestSigning, time.Now())
+	}
+
 	r.Close = false
 	r.RequestURI = ""
 
@@ -178,13 +210,31 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 	// clear the Host header before proxying or it will be forwarded upstream
 	r.Host = ""
 
-	resp, err := oc.HTTPClient.Do(r)
+	resp, err := doUpstream(r, oc, rsc.Logger)
 	if err != nil {
 		rsc.Logger.Error("error downloading url", log.Pairs{"url": r.URL.String(), "detail": err.Error()})
 		// if there is an err and the response is nil, the server could not be reached
-		// so make a 502 for the downstream response
+		// so make a 502 for the downstream response, or the configured timeout response
+		var contentLength int64
 		if resp == nil {
-			resp = &http.Response{StatusCode: http.StatusBadGateway, Request: r, Header: make(http.Header)}
+			if err == errors.ErrCircuitOpen {
+				resp = &http.Response{StatusCode: http.StatusServiceUnavailable,
+					Status: breakerOpenStatus, Request: r, Header: make(http.Header)}
+			} else if err == errors.ErrUpstreamConcurrencyLimit {
+				resp = &http.Response{StatusCode: http.StatusServiceUnavailable, Request: r,
+					Header: make(http.Header)}
+			} else if isTimeout(err) {
+				resp = &http.Response{StatusCode: oc.TimeoutResponseCode, Request: r, Header: make(http.Header)}
+				if oc.TimeoutResponseBody != "" {
+					contentLength = int64(len(oc.TimeoutResponseBody))
+					rc = ioutil.NopCloser(strings.NewReader(oc.TimeoutResponseBody))
+					resp.Header.Set(headers.NameContentType, oc.TimeoutResponseContentType)
+					resp.Header.Set(headers.NameContentLength, strconv.FormatInt(contentLength, 10))
+					resp.ContentLength = contentLength
+				}
+			} else {
+				resp = &http.Response{StatusCode: http.StatusBadGateway, Request: r, Header: make(http.Header)}
+			}
 		}
 
 		if pc != nil {
@@ -200,7 +250,7 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 			)
 			doSpan.SetStatus(tracing.HTTPToCode(resp.StatusCode), "")
 		}
-		return nil, resp, 0
+		return rc, resp, contentLength
 	}
 
 	originalLen := int64(-1)
@@ -232,6 +282,12 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 	hasCustomResponseBody := false
 	resp.Header.Del(headers.NameContentLength)
 
+	if oc.StripHopByHopHeaders {
+		headers.StripHopByHopHeaders(resp.Header)
+	}
+
+	applyDefaultContentType(resp, oc)
+
 	if pc != nil {
 		headers.UpdateHeaders(resp.Header, pc.ResponseHeaders)
 		hasCustomResponseBody = pc.HasCustomResponseBody
@@ -282,3 +338,45 @@ func recordResults(r *http.Request, engine string, cacheStatus status.LookupStat
 	}
 	headers.SetResultsHeader(header, engine, status, ffStatus, extents)
 }
+
+// handleExpectContinue prepares a request with an Expect: 100-continue header to be proxied
+// according to the origin's handle_100_continue mode. In respond mode, a body larger than
+// maxBytes is not buffered, and errors.ErrRequestBodyTooLarge is returned
+func handleExpectContinue(r *http.Request, mode string, maxBytes int) error {
+	if mode == oo.Handle100ContinueForward || r.Header.Get(headers.NameExpect) == "" {
+		return nil
+	}
+	hasContinue := strings.EqualFold(r.Header.Get(headers.NameExpect), headers.Value100Continue)
+	r.Header.Del(headers.NameExpect)
+	if mode != oo.Handle100ContinueRespond || !hasContinue || r.Body == nil {
+		return nil
+	}
+	// reading the body causes the server to answer the client's 100-continue, so the
+	// buffered body can be proxied to an origin that does not support 100-continue
+	if r.ContentLength > int64(maxBytes) {
+		r.Body.Close()
+		return errors.ErrRequestBodyTooLarge
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+	if len(b) > maxBytes {
+		return errors.ErrRequestBodyTooLarge
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	r.ContentLength = int64(len(b))
+	return nil
+}
+
+// isTimeout returns true if the error is the result of an upstream request timing out
+func isTimeout(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	if ne, ok := err.(net.Error); ok {
+		return ne.Timeout()
+	}
+	return false
+}
@@ -21,13 +21,17 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/config"
 	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
@@ -128,6 +132,151 @@ func TestProxyRequestBadGateway(t *testing.T) {
 
 }
 
+func TestProxyRequestTimeout(t *testing.T) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(250 * time.Millisecond)
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url",
+		es.URL, "-origin-type", "test", "-log-level", "debug"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	oc := conf.Origins["default"]
+	oc.HTTPClient = &http.Client{Timeout: 10 * time.Millisecond}
+
+	doRequest := func() *http.Response {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", es.URL, nil)
+		r = r.WithContext(tc.WithResources(r.Context(),
+			request.NewResources(oc, nil, nil, nil, nil, tu.NewTestTracer(), testLogger)))
+		DoProxy(w, r, true)
+		return w.Result()
+	}
+
+	// the default timeout response is a 502 with no body
+	resp := doRequest()
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusBadGateway)
+	if err != nil {
+		t.Error(err)
+	}
+
+	const body = `{"status":"error","error":"upstream request timed out"}`
+	oc.TimeoutResponseCode = http.StatusGatewayTimeout
+	oc.TimeoutResponseBody = body
+	oc.TimeoutResponseContentType = headers.ValueApplicationJSON
+
+	resp = doRequest()
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusGatewayTimeout)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if ct := resp.Header.Get(headers.NameContentType); ct != headers.ValueApplicationJSON {
+		t.Errorf("expected %s got %s", headers.ValueApplicationJSON, ct)
+	}
+
+	b, _ := ioutil.ReadAll(resp.Body)
+	err = testStringMatch(string(b), body)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestProxyRequestExpectContinue(t *testing.T) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.Header.Get(headers.NameExpect) + ":" + string(b)))
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-url",
+		es.URL, "-origin-type", "test", "-log-level", "debug"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	oc := conf.Origins["default"]
+	oc.HTTPClient = http.DefaultClient
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{oo.Handle100ContinueForward, "100-continue:body"},
+		{oo.Handle100ContinueRespond, ":body"},
+		{oo.Handle100ContinueStrip, ":body"},
+	}
+
+	for _, test := range tests {
+		oc.Handle100Continue = test.mode
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, es.URL, bytes.NewReader([]byte("body")))
+		r.Header.Set(headers.NameExpect, headers.Value100Continue)
+		r = r.WithContext(tc.WithResources(r.Context(),
+			request.NewResources(oc, nil, nil, nil, nil, nil, testLogger)))
+		DoProxy(w, r, true)
+		resp := w.Result()
+		b, _ := ioutil.ReadAll(resp.Body)
+		err = testStringMatch(string(b), test.expected)
+		if err != nil {
+			t.Errorf("%s: %s", test.mode, err.Error())
+		}
+	}
+
+	// a body larger than max_100_continue_body_bytes is not proxied
+	oc.Handle100Continue = oo.Handle100ContinueRespond
+	oc.Max100ContinueBodyBytes = 2
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, es.URL, bytes.NewReader([]byte("body")))
+	r.Header.Set(headers.NameExpect, headers.Value100Continue)
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(oc, nil, nil, nil, nil, nil, testLogger)))
+	DoProxy(w, r, true)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected %d got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestHandleExpectContinueReadError(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "http://0/", ioutil.NopCloser(iotest.ErrReader(errTest)))
+	r.Header.Set(headers.NameExpect, headers.Value100Continue)
+	if err := handleExpectContinue(r, oo.Handle100ContinueRespond, 1024); err != errTest {
+		t.Errorf("expected %v got %v", errTest, err)
+	}
+}
+
+func TestHandleExpectContinueTooLarge(t *testing.T) {
+
+	// a declared Content-Length over the limit is rejected before reading the body
+	r := httptest.NewRequest(http.MethodPost, "http://0/", bytes.NewReader([]byte("too large")))
+	r.Header.Set(headers.NameExpect, headers.Value100Continue)
+	if err := handleExpectContinue(r, oo.Handle100ContinueRespond, 4); err != errors.ErrRequestBodyTooLarge {
+		t.Errorf("expected %v got %v", errors.ErrRequestBodyTooLarge, err)
+	}
+
+	// a body of unknown length is read no further than the limit
+	r = httptest.NewRequest(http.MethodPost, "http://0/", ioutil.NopCloser(strings.NewReader("too large")))
+	r.ContentLength = -1
+	r.Header.Set(headers.NameExpect, headers.Value100Continue)
+	if err := handleExpectContinue(r, oo.Handle100ContinueRespond, 4); err != errors.ErrRequestBodyTooLarge {
+		t.Errorf("expected %v got %v", errors.ErrRequestBodyTooLarge, err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "http://0/", ioutil.NopCloser(strings.NewReader("body")))
+	r.ContentLength = -1
+	r.Header.Set(headers.NameExpect, headers.Value100Continue)
+	if err := handleExpectContinue(r, oo.Handle100ContinueRespond, 4); err != nil {
+		t.Error(err)
+	}
+	if r.ContentLength != 4 {
+		t.Errorf("expected %d got %d", 4, r.ContentLength)
+	}
+}
+
 func TestClockOffsetWarning(t *testing.T) {
 
 	handler := func(w http.ResponseWriter, r *http.Request) {
@@ -303,3 +452,89 @@ func TestPrepareFetchReaderErr(t *testing.T) {
 		t.Errorf("expected 0 got %d", i)
 	}
 }
+
+func TestDoProxyIdempotencyKey(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusCreated, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.IdempotencyKeyHeader = "Idempotency-Key"
+	r.Method = http.MethodPost
+	r.Header.Set("Idempotency-Key", "abc")
+
+	// the first request is proxied, and its retries are replayed from the cache
+	expected := []string{"proxy-only", "hit", "hit"}
+	for _, st := range expected {
+		w := httptest.NewRecorder()
+		DoProxy(w, r, true)
+		resp := w.Result()
+		if err = testStatusCodeMatch(resp.StatusCode, http.StatusCreated); err != nil {
+			t.Error(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		if err = testStringMatch(string(b), "test"); err != nil {
+			t.Error(err)
+		}
+		if err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": st}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// a different key is proxied
+	r.Header.Set("Idempotency-Key", "def")
+	w := httptest.NewRecorder()
+	DoProxy(w, r, true)
+	if err = testResultHeaderPartMatch(w.Result().Header,
+		map[string]string{"status": "proxy-only"}); err != nil {
+		t.Error(err)
+	}
+
+	// a reused key is only replayed for the same caller, query string and body
+	var body string
+	tests := []func(){
+		func() { r.Header.Set(headers.NameAuthorization, "Bearer other") },
+		func() { r.URL.RawQuery = "other=1" },
+		func() { body = "other" },
+	}
+	for i, f := range tests {
+		f()
+		for _, st := range []string{"proxy-only", "hit"} {
+			r.Body = ioutil.NopCloser(bytes.NewBufferString(body))
+			w = httptest.NewRecorder()
+			DoProxy(w, r, true)
+			if err = testResultHeaderPartMatch(w.Result().Header,
+				map[string]string{"status": st}); err != nil {
+				t.Errorf("test %d: %s", i, err)
+			}
+		}
+	}
+}
+
+func TestDoProxyIdempotencyKeyWriteRateLimit(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusCreated, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	// the limiter's only token is already spent, so rate limited writes are dropped
+	rsc.OriginConfig.CacheWriteLimiter = oo.NewRateLimiter(1)
+	rsc.OriginConfig.CacheWriteLimiter.Allow()
+
+	rsc.OriginConfig.IdempotencyKeyHeader = "Idempotency-Key"
+	r.Method = http.MethodPost
+	r.Header.Set("Idempotency-Key", "abc")
+
+	for _, st := range []string{"proxy-only", "hit"} {
+		w := httptest.NewRecorder()
+		DoProxy(w, r, true)
+		if err = testResultHeaderPartMatch(w.Result().Header,
+			map[string]string{"status": st}); err != nil {
+			t.Error(err)
+		}
+	}
+}
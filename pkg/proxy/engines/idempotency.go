@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/md5"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// isIdempotencyKeyMethod returns true if requests using the method are deduplicated
+// by their idempotency key
+func isIdempotencyKeyMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// hasIdempotencyKey returns true if r carries the origin's idempotency key header
+func hasIdempotencyKey(r *http.Request, oc *oo.Options) bool {
+	return oc != nil && oc.IdempotencyKeyHeader != "" && isIdempotencyKeyMethod(r.Method) &&
+		r.Header.Get(oc.IdempotencyKeyHeader) != ""
+}
+
+// idempotencyCacheKey returns the cache key under which the response to r is stored for
+// replay, or an empty string if r does not carry the origin's idempotency key header. Besides
+// the key, it covers the caller's identity, the query string and the request body, so that a
+// reused key only replays the response to the same caller's identical request
+func idempotencyCacheKey(r *http.Request, oc *oo.Options) string {
+	if !hasIdempotencyKey(r, oc) {
+		return ""
+	}
+	var body []byte
+	if r.Body != nil {
+		body, _ = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return oc.CacheKeyPrefix + ".idempotency." + md5.Checksum(r.Method+"."+r.URL.Path+"."+
+		r.URL.RawQuery+"."+r.Header.Get(oc.IdempotencyKeyHeader)+
+		identityHash(r.Header, oc.CacheKeyAuthHeader)+"."+md5.Checksum(string(body)))
+}
+
+// doIdempotentProxy replays the cached response for the idempotency key of r, if present, and
+// otherwise proxies r upstream and caches the response for the origin's idempotency window.
+// The key is locked throughout, so concurrent retries wait for the first request to complete
+func doIdempotentProxy(w io.Writer, r *http.Request, key string) *http.Response {
+
+	rsc := request.GetResources(r)
+	oc := rsc.OriginConfig
+	cc := rsc.CacheClient
+
+	start := time.Now()
+
+	nl, _ := cc.Locker().Acquire(key)
+	defer nl.Release()
+
+	d, lookupStatus, _, err := QueryCache(r.Context(), cc, key, nil)
+	if err == nil && lookupStatus == status.LookupStatusHit {
+		metrics.ProxyIdempotentReplays.WithLabelValues(oc.Name, oc.OriginType).Inc()
+		resp := &http.Response{StatusCode: d.StatusCode, Status: d.Status, Request: r,
+			Header: d.SafeHeaderClone()}
+		recordResults(r, "HTTPProxy", status.LookupStatusHit, resp.StatusCode,
+			r.URL.Path, "", time.Since(start).Seconds(), nil, resp.Header)
+		Respond(w, resp.StatusCode, resp.Header, d.Body)
+		return resp
+	}
+
+	reader, resp, _ := PrepareFetchReader(r)
+	var body []byte
+	var readErr error
+	// server errors are not replayed, so that retries can reach the upstream
+	storable := resp.StatusCode < http.StatusInternalServerError
+	if reader != nil {
+		defer reader.Close()
+		body, readErr = ioutil.ReadAll(io.LimitReader(reader, int64(oc.MaxObjectSizeBytes)+1))
+		if readErr != nil || len(body) > oc.MaxObjectSizeBytes {
+			storable = false
+		}
+	}
+	cacheStatusCode := setStatusHeader(resp.StatusCode, resp.Header)
+	if storable {
+		d := DocumentFromHTTPResponse(resp, body, nil, rsc.Logger)
+		// the write is not rate limited, since a dropped record would let a retry be
+		// proxied upstream and the write be repeated
+		if err = writeCache(r.Context(), cc, key, d, oc.IdempotencyWindow,
+			oc.CompressableTypes, false); err != nil {
+			rsc.Logger.Error("error writing idempotent response to cache",
+				tl.Pairs{"cacheKey": key, "detail": err.Error()})
+		}
+	}
+
+	writer := PrepareResponseWriter(w, resp.StatusCode, resp.Header)
+	if writer != nil {
+		writer.Write(body)
+		if reader != nil && readErr == nil {
+			// stream the remainder of any response that exceeded max_object_size_bytes
+			io.Copy(writer, reader)
+		}
+	}
+
+	recordResults(r, "HTTPProxy", cacheStatusCode, resp.StatusCode,
+		r.URL.Path, "", time.Since(start).Seconds(), nil, resp.Header)
+	return resp
+}
@@ -18,28 +18,100 @@ package engines
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"sort"
 	"strconv"
 	"strings"
 
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/params"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	"github.com/tricksterproxy/trickster/pkg/util/md5"
 )
 
-// DeriveCacheKey calculates a query-specific keyname based on the prometheus query in the user request
+// cacheKeyHeaderValue returns the value of the named header. When canonicalize is true, headers
+// stored under non-canonical names (e.g., by request rewriters) are also matched regardless of casing
+func cacheKeyHeaderValue(h http.Header, name string, canonicalize bool) string {
+	v := h.Get(name)
+	if v != "" || !canonicalize {
+		return v
+	}
+	for k, vals := range h {
+		if len(vals) > 0 && strings.EqualFold(k, name) {
+			return vals[0]
+		}
+	}
+	return ""
+}
+
+// identityHash returns a cache key component containing a SHA-256 hash of the named identity header,
+// so that the cache is partitioned by client identity without the header's raw value entering the key
+func identityHash(h http.Header, name string) string {
+	if name == "" {
+		name = headers.NameAuthorization
+	}
+	v := h.Get(name)
+	if v == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(v))
+	return ".identity." + hex.EncodeToString(sum[:])
+}
+
+// DeriveCacheKey calculates a query-specific keyname based on the prometheus query in the user request,
+// encoded per the origin's cache_key_encoding
 func (pr *proxyRequest) DeriveCacheKey(templateURL *url.URL, extra string) string {
+	k := pr.deriveCacheKey(templateURL, extra)
+	if rsc := request.GetResources(pr.Request); rsc != nil && rsc.OriginConfig != nil {
+		return encodeCacheKey(k, rsc.OriginConfig.CacheKeyEncoding)
+	}
+	return k
+}
+
+// encodeCacheKey re-encodes the hex digest k with the provided Cache Key Encoding. Keys that are not
+// hex digests, such as those returned by some custom KeyHashers, are returned unmodified
+func encodeCacheKey(k, encoding string) string {
+	if encoding == "" || encoding == oo.CacheKeyEncodingHex {
+		return k
+	}
+	b, err := hex.DecodeString(k)
+	if err != nil {
+		return k
+	}
+	switch encoding {
+	case oo.CacheKeyEncodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(b)
+	case oo.CacheKeyEncodingBase32:
+		return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	}
+	return k
+}
+
+func (pr *proxyRequest) deriveCacheKey(templateURL *url.URL, extra string) string {
 
 	rsc := request.GetResources(pr.Request)
 	pc := rsc.PathConfig
 
+	// the identity hash is carried in extra, so it applies to every key derivation method below,
+	// except for the cache identity rewriter, which hashes the identity of the rewritten request
+	baseExtra := extra
+	hashIdentity := rsc.OriginConfig != nil && rsc.OriginConfig.CacheKeyFromAuthHash
+	if hashIdentity {
+		extra += identityHash(pr.Request.Header, rsc.OriginConfig.CacheKeyAuthHeader)
+	}
+
 	if pc == nil {
 		return md5.Checksum(pr.URL.Path + extra)
 	}
@@ -56,46 +128,88 @@ func (pr *proxyRequest) DeriveCacheKey(templateURL *url.URL, extra string) strin
 	}
 
 	var b []byte
+	var isBody bool
 	if templateURL != nil {
 		qp = templateURL.Query()
 	} else {
 		var s string
-		qp, s, _ = params.GetRequestValues(r)
+		qp, s, isBody = params.GetRequestValues(r)
 		b = []byte(s)
 	}
 
+	if oc := rsc.OriginConfig; oc != nil && len(oc.CacheIdentityRewriter) > 0 {
+		return deriveIdentityCacheKey(r, qp, string(b), isBody, oc, baseExtra)
+	}
+
 	if pc.KeyHasher != nil && len(pc.KeyHasher) == 1 {
 		var k string
-		k, r.Body = pc.KeyHasher[0](r.URL.Path, qp, r.Header, r.Body, extra)
+		k, r.Body = pc.KeyHasher[0](cacheKeyPath(rsc.OriginConfig, r.URL.Path), qp, r.Header, r.Body, extra)
 		return k
 	}
 
-	vals := make([]string, 0, (len(pc.CacheKeyParams) + len(pc.CacheKeyHeaders) + len(pc.CacheKeyFormFields)*2))
+	vals := make([]string, 0, (len(pc.CacheKeyParams) + len(pc.CacheKeyHeaders) + len(pc.CacheKeyCookies) +
+		len(pc.CacheKeyFormFields)*2))
 
-	if v := r.Header.Get(headers.NameAuthorization); v != "" {
+	if v := r.Header.Get(headers.NameAuthorization); v != "" && !hashIdentity {
 		vals = append(vals, fmt.Sprintf("%s.%s.", headers.NameAuthorization, v))
 	}
 
 	// Append the http method to the slice for creating the derived cache key
 	vals = append(vals, fmt.Sprintf("%s.%s.", "method", r.Method))
 
+	if oc := rsc.OriginConfig; oc != nil {
+		if oc.IncludeHostInCacheKey {
+			vals = append(vals, fmt.Sprintf("%s.%s.", "host", strings.ToLower(pr.Request.Host)))
+		}
+		if oc.IncludeSchemeInCacheKey {
+			scheme := "http"
+			if pr.Request.TLS != nil {
+				scheme = "https"
+			}
+			vals = append(vals, fmt.Sprintf("%s.%s.", "scheme", scheme))
+		}
+	}
+
+	// params, headers and cookies are collected separately so their combined count can be limited
+	comps := make([]string, 0, len(pc.CacheKeyParams)+len(pc.CacheKeyHeaders)+len(pc.CacheKeyCookies))
+
 	if len(pc.CacheKeyParams) == 1 && pc.CacheKeyParams[0] == "*" {
-		for p := range qp {
-			vals = append(vals, fmt.Sprintf("%s.%s.", p, qp.Get(p)))
+		for p, v := range qp {
+			comps = append(comps, paramComponents(p, v)...)
 		}
 	} else {
 		for _, p := range pc.CacheKeyParams {
-			if v := qp.Get(p); v != "" {
-				vals = append(vals, fmt.Sprintf("%s.%s.", p, v))
-			}
+			comps = append(comps, paramComponents(p, nonEmpty(qp[p]))...)
 		}
 	}
 
+	canonicalize := rsc.OriginConfig != nil && rsc.OriginConfig.CanonicalizeCacheKeyHeaders
 	for _, p := range pc.CacheKeyHeaders {
-		if v := r.Header.Get(p); v != "" {
-			vals = append(vals, fmt.Sprintf("%s.%s.", p, v))
+		if v := cacheKeyHeaderValue(r.Header, p, canonicalize); v != "" {
+			if canonicalize {
+				p = http.CanonicalHeaderKey(p)
+			}
+			comps = append(comps, fmt.Sprintf("%s.%s.", p, v))
+		}
+	}
+
+	// cookie names are prefixed so they cannot collide with a param or header of the same name
+	for _, p := range pc.CacheKeyCookies {
+		if c, err := r.Cookie(p); err == nil && c.Value != "" {
+			comps = append(comps, fmt.Sprintf("cookie:%s.%s.", p, c.Value))
+		}
+	}
+
+	if oc := rsc.OriginConfig; oc != nil && oc.MaxCacheKeyComponents > 0 &&
+		len(comps) > oc.MaxCacheKeyComponents {
+		if oc.CacheKeyComponentsPolicy == oo.CacheKeyComponentsPolicyTruncate {
+			sort.Strings(comps)
+			comps = comps[:oc.MaxCacheKeyComponents]
+		} else {
+			pr.keyRejected = true
 		}
 	}
+	vals = append(vals, comps...)
 
 	if methods.HasBody(r.Method) && pc.CacheKeyFormFields != nil && len(pc.CacheKeyFormFields) > 0 {
 		ct := r.Header.Get(headers.NameContentType)
@@ -131,7 +245,117 @@ func (pr *proxyRequest) DeriveCacheKey(templateURL *url.URL, extra string) strin
 	}
 
 	sort.Strings(vals)
-	return md5.Checksum(pr.URL.Path + "." + strings.Join(vals, "") + extra)
+	return md5.Checksum(cacheKeyPath(rsc.OriginConfig, pr.URL.Path) + "." + strings.Join(vals, "") + extra)
+}
+
+// paramComponents returns the cache key components for the values of a query parameter. Each
+// value of a repeated parameter is a separate component, indexed to preserve their order
+func paramComponents(p string, vals []string) []string {
+	switch len(vals) {
+	case 0:
+		return nil
+	case 1:
+		return []string{fmt.Sprintf("%s.%s.", p, vals[0])}
+	}
+	out := make([]string, len(vals))
+	for i, v := range vals {
+		out[i] = fmt.Sprintf("%s[%d].%s.", p, i, v)
+	}
+	return out
+}
+
+// nonEmpty returns the non-empty values of the list
+func nonEmpty(vals []string) []string {
+	out := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// cacheKeyPath returns the request path used in the cache key. When the origin strips a path
+// prefix before proxying, the prefix is restored unless it is configured to be stripped from the key
+func cacheKeyPath(oc *oo.Options, path string) string {
+	if oc == nil || oc.StripPathPrefix == "" || oc.StripPathPrefixFromCacheKey {
+		return path
+	}
+	return oc.StripPathPrefix + path
+}
+
+// deriveIdentityCacheKey calculates the key from the method, canonicalized URL and identity of a
+// copy of the request, after it has been processed by the origin's cache identity rewriter. Form
+// values are moved into the copy's query so they can be rewritten, while a digest of any other
+// request body is included as is
+func deriveIdentityCacheKey(r *http.Request, qp url.Values, body string, isBody bool,
+	oc *oo.Options, extra string) string {
+
+	hasOtherBody := isBody && len(qp) == 0
+	r2 := r.Clone(r.Context())
+	if !hasOtherBody {
+		r2.URL.RawQuery = qp.Encode()
+	}
+	oc.CacheIdentityRewriter.Execute(r2)
+
+	// re-encoding the query sorts the parameters by name
+	u := *r2.URL
+	u.RawQuery = u.Query().Encode()
+
+	k := r2.Method + "." + u.String()
+	if hasOtherBody && body != "" {
+		k += ".body." + md5.Checksum(body)
+	}
+	if oc.CacheKeyFromAuthHash {
+		k += identityHash(r2.Header, oc.CacheKeyAuthHeader)
+	} else if v := r2.Header.Get(headers.NameAuthorization); v != "" {
+		k += "." + headers.NameAuthorization + "." + v
+	}
+	return md5.Checksum(k + extra)
+}
+
+// deriveCoalescingKey returns the key under which concurrent requests share a progressive collapsed
+// forwarding fetch. This is the provided cache key, unless the origin has a coalescing key rewriter,
+// in which case the key is calculated from the method, canonicalized URL and Authorization header of
+// a copy of the request, after it has been processed by the rewriter. As with the cache identity
+// rewriter, form values are moved into the copy's query so they can be rewritten, while other
+// request bodies are included as is
+func (pr *proxyRequest) deriveCoalescingKey(cacheKey string) string {
+	rsc := request.GetResources(pr.Request)
+	if rsc == nil || rsc.OriginConfig == nil || len(rsc.OriginConfig.CoalescingKeyRewriter) == 0 {
+		return cacheKey
+	}
+	oc := rsc.OriginConfig
+
+	r := pr.Request
+	if pr.upstreamRequest != nil {
+		r = pr.upstreamRequest
+	}
+	qp, body, isBody := params.GetRequestValues(r)
+	r2 := r.Clone(r.Context())
+	r2.URL.RawQuery = qp.Encode()
+	oc.CoalescingKeyRewriter.Execute(r2)
+
+	// re-encoding the query sorts the parameters by name
+	u := *r2.URL
+	u.RawQuery = u.Query().Encode()
+
+	k := r2.Method + "." + u.String()
+	if isBody && len(qp) == 0 {
+		k += "." + body
+	}
+	if v := r2.Header.Get(headers.NameAuthorization); v != "" {
+		k += "." + headers.NameAuthorization + "." + v
+	}
+	return oc.CacheKeyPrefix + ".coalesce." + md5.Checksum(k)
+}
+
+// limitKeyLength returns the key, or its digest when it is longer than the cache's MaxKeyLengthBytes
+func limitKeyLength(key string, cc *co.Options) string {
+	if cc == nil || cc.MaxKeyLengthBytes <= 0 || len(key) <= cc.MaxKeyLengthBytes {
+		return key
+	}
+	return md5.Checksum(key)
 }
 
 func deepSearch(document map[string]interface{}, key string) (string, error) {
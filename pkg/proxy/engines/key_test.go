@@ -26,15 +26,20 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/key"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
 	ct "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	rwo "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter/options"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/md5"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
 )
 
@@ -243,6 +248,507 @@ func TestDeriveCacheKeyAuthHeader(t *testing.T) {
 
 }
 
+func TestDeriveCacheKeyFromAuthHash(t *testing.T) {
+
+	oc := &oo.Options{
+		CacheKeyFromAuthHash: true,
+		CacheKeyAuthHeader:   "X-Tenant-Token",
+		Paths: map[string]*po.Options{
+			"root": {
+				Path:           "/",
+				CacheKeyParams: []string{"query"},
+			},
+		},
+	}
+
+	key := func(token string) string {
+		r := httptest.NewRequest("GET", "http://127.0.0.1/?query=12345", nil)
+		r = r.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(oc, oc.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		if token != "" {
+			r.Header.Set("X-Tenant-Token", token)
+		}
+		return newProxyRequest(r, nil).DeriveCacheKey(nil, "")
+	}
+
+	k1, k2, k3 := key("tenant-1"), key("tenant-2"), key("")
+	if k1 == k2 || k1 == k3 {
+		t.Error("expected distinct cache keys for distinct identities")
+	}
+	if k1 != key("tenant-1") {
+		t.Error("expected the same cache key for the same identity")
+	}
+}
+
+func TestIdentityHash(t *testing.T) {
+
+	h := http.Header{}
+	if v := identityHash(h, ""); v != "" {
+		t.Errorf("expected empty string got %s", v)
+	}
+
+	h.Set(headers.NameAuthorization, "Bearer secret-token")
+	v := identityHash(h, "")
+	if strings.Contains(v, "secret-token") {
+		t.Error("expected the raw identity to be excluded from the hash")
+	}
+	// sha256("Bearer secret-token")
+	const expected = ".identity.86e774bff90b0fe5f8ec00ffd9e579c653705ca16285d09d992ae2bfb58bc308"
+	if v != expected {
+		t.Errorf("expected %s got %s", expected, v)
+	}
+}
+
+func TestDeriveCacheKeyCanonicalHeaders(t *testing.T) {
+
+	client := &TestClient{
+		config: &oo.Options{
+			CanonicalizeCacheKeyHeaders: true,
+			Paths: map[string]*po.Options{
+				"root": {
+					Path:            "/",
+					CacheKeyHeaders: []string{"x-test-header"},
+				},
+			},
+		},
+	}
+
+	newRequest := func(name string) *proxyRequest {
+		tr := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(client.Configuration(), client.Configuration().Paths["root"],
+				nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		// assign directly to the map to bypass canonicalization by http.Header
+		tr.Header[name] = []string{"test"}
+		return newProxyRequest(tr, nil)
+	}
+
+	ck := newRequest("X-Test-Header").DeriveCacheKey(nil, "")
+	client.config.Paths["root"].CacheKeyHeaders = []string{"X-Test-Header"}
+	if ck2 := newRequest("x-test-header").DeriveCacheKey(nil, ""); ck2 != ck {
+		t.Errorf("expected %s got %s", ck, ck2)
+	}
+
+	client.config.CanonicalizeCacheKeyHeaders = false
+	if ck2 := newRequest("x-test-header").DeriveCacheKey(nil, ""); ck2 == ck {
+		t.Errorf("expected cache key other than %s", ck)
+	}
+}
+
+func TestDeriveCacheKeyCookies(t *testing.T) {
+
+	client := &TestClient{
+		config: &oo.Options{
+			Paths: map[string]*po.Options{
+				"root": {
+					Path:            "/",
+					CacheKeyCookies: []string{"locale"},
+				},
+			},
+		},
+	}
+
+	newRequest := func(cookies ...*http.Cookie) *proxyRequest {
+		tr := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(client.Configuration(), client.Configuration().Paths["root"],
+				nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		for _, c := range cookies {
+			tr.AddCookie(c)
+		}
+		return newProxyRequest(tr, nil)
+	}
+
+	ck := newRequest(&http.Cookie{Name: "locale", Value: "en-US"}).DeriveCacheKey(nil, "")
+
+	// cookies that are not listed do not affect the cache key
+	if ck2 := newRequest(&http.Cookie{Name: "locale", Value: "en-US"},
+		&http.Cookie{Name: "session", Value: "12345"}).DeriveCacheKey(nil, ""); ck2 != ck {
+		t.Errorf("expected %s got %s", ck, ck2)
+	}
+
+	if ck2 := newRequest(&http.Cookie{Name: "locale", Value: "fr-FR"}).DeriveCacheKey(nil, ""); ck2 == ck {
+		t.Errorf("expected cache key other than %s", ck)
+	}
+
+	if ck2 := newRequest().DeriveCacheKey(nil, ""); ck2 == ck {
+		t.Errorf("expected cache key other than %s", ck)
+	}
+}
+
+func TestDeriveCacheKeyMaxComponents(t *testing.T) {
+
+	client := &TestClient{
+		config: &oo.Options{
+			MaxCacheKeyComponents: 3,
+			Paths: map[string]*po.Options{
+				"root": {
+					Path:            "/",
+					CacheKeyParams:  []string{"query", "step", "time"},
+					CacheKeyHeaders: []string{"X-Test-Header"},
+				},
+			},
+		},
+	}
+
+	newRequest := func(tm, header string) *proxyRequest {
+		tr := httptest.NewRequest("GET", "http://127.0.0.1/?query=12345&step=300&time="+tm, nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(client.Configuration(), client.Configuration().Paths["root"],
+				nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		if header != "" {
+			tr.Header.Add("X-Test-Header", header)
+		}
+		return newProxyRequest(tr, nil)
+	}
+
+	// requests within the limit are unaffected
+	pr := newRequest("0", "")
+	pr.DeriveCacheKey(nil, "")
+	if pr.keyRejected {
+		t.Error("expected key to not be rejected")
+	}
+
+	// the reject policy flags requests exceeding the limit
+	client.config.CacheKeyComponentsPolicy = oo.CacheKeyComponentsPolicyReject
+	pr = newRequest("0", "test")
+	pr.DeriveCacheKey(nil, "")
+	if !pr.keyRejected {
+		t.Error("expected key to be rejected")
+	}
+
+	// the truncate policy keeps the first components in sorted order, which drops the time param
+	client.config.CacheKeyComponentsPolicy = oo.CacheKeyComponentsPolicyTruncate
+	pr = newRequest("0", "test")
+	ck := pr.DeriveCacheKey(nil, "")
+	if pr.keyRejected {
+		t.Error("expected key to not be rejected")
+	}
+	ck2 := newRequest("1", "test").DeriveCacheKey(nil, "")
+	if ck2 != ck {
+		t.Errorf("expected %s got %s", ck, ck2)
+	}
+}
+
+func TestDeriveCacheKeyRepeatedParams(t *testing.T) {
+
+	cfg := &oo.Options{
+		Paths: map[string]*po.Options{
+			"root": {Path: "/", CacheKeyParams: []string{"q"}},
+		},
+	}
+
+	key := func(url string) string {
+		tr := httptest.NewRequest("GET", url, nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		return newProxyRequest(tr, nil).DeriveCacheKey(nil, "")
+	}
+
+	for _, params := range []string{"q", "*"} {
+		cfg.Paths["root"].CacheKeyParams = []string{params}
+		ab, a := key("http://127.0.0.1/?q=a&q=b"), key("http://127.0.0.1/?q=a")
+		if ab == a {
+			t.Errorf("%s: expected repeated param values to be included in the cache key", params)
+		}
+		if ba := key("http://127.0.0.1/?q=b&q=a"); ba == ab {
+			t.Errorf("%s: expected the order of repeated param values to be included in the cache key", params)
+		}
+	}
+
+	cfg.Paths["root"].CacheKeyParams = []string{"q"}
+	if key("http://127.0.0.1/?q=a&q=") != key("http://127.0.0.1/?q=a") {
+		t.Error("expected empty param values to be excluded from the cache key")
+	}
+}
+
+func TestDeriveCacheKeyHostAndScheme(t *testing.T) {
+
+	cfg := &oo.Options{
+		Paths: map[string]*po.Options{
+			"root": {
+				Path:           "/",
+				CacheKeyParams: []string{"query"},
+			},
+		},
+	}
+
+	key := func(u string) string {
+		tr := httptest.NewRequest("GET", u, nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		return newProxyRequest(tr, nil).DeriveCacheKey(nil, "")
+	}
+
+	const u1 = "http://one.example.com/?query=12345"
+	const u2 = "http://two.example.com/?query=12345"
+	const u3 = "https://one.example.com/?query=12345"
+
+	if key(u1) != key(u2) || key(u1) != key(u3) {
+		t.Error("expected cache keys to match when host and scheme are not included")
+	}
+
+	cfg.IncludeHostInCacheKey = true
+	if key(u1) == key(u2) {
+		t.Error("expected cache keys to differ by host")
+	}
+	if key(u1) != key(u3) {
+		t.Error("expected cache keys to match when scheme is not included")
+	}
+
+	cfg.IncludeSchemeInCacheKey = true
+	if key(u1) == key(u3) {
+		t.Error("expected cache keys to differ by scheme")
+	}
+}
+
+func TestDeriveCacheKeyIdentityRewriter(t *testing.T) {
+
+	cfg := &oo.Options{
+		Paths: map[string]*po.Options{
+			"root": {
+				Path:           "/",
+				CacheKeyParams: []string{"query"},
+			},
+		},
+	}
+
+	key := func(u string) string {
+		tr := httptest.NewRequest("GET", u, nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		return newProxyRequest(tr, nil).DeriveCacheKey(nil, "")
+	}
+
+	const u1 = "http://0/?query=12345&b=1&a=2&session=abc"
+	const u2 = "http://0/?a=2&query=12345&b=1&session=xyz"
+	const u3 = "http://0/?query=12345&b=2&a=2"
+
+	// without the rewriter, only the query param is in the key
+	if key(u1) != key(u3) {
+		t.Error("expected cache keys to match")
+	}
+
+	ri, err := rewriter.ProcessConfigs(map[string]*rwo.Options{"identity": {
+		Instructions: rwo.RewriteList{[]string{"param", "delete", "session"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.CacheIdentityRewriter = ri["identity"]
+
+	// with the rewriter, all remaining params are in the key, regardless of order
+	if key(u1) != key(u2) {
+		t.Error("expected cache keys to match after the session param is removed")
+	}
+	if key(u1) == key(u3) {
+		t.Error("expected cache keys to differ by param b")
+	}
+}
+
+func TestDeriveCacheKeyIdentityRewriterBodyAndAuth(t *testing.T) {
+
+	cfg := &oo.Options{
+		Paths: map[string]*po.Options{
+			"root": {
+				Path: "/",
+			},
+		},
+	}
+
+	key := func(body, auth string) string {
+		tr := httptest.NewRequest(http.MethodPost, "http://0/api?a=1", strings.NewReader(body))
+		tr.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+		if auth != "" {
+			tr.Header.Set(headers.NameAuthorization, auth)
+		}
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		return newProxyRequest(tr, nil).DeriveCacheKey(nil, "")
+	}
+
+	ri, err := rewriter.ProcessConfigs(map[string]*rwo.Options{
+		"identity": {Instructions: rwo.RewriteList{[]string{"param", "delete", "session"}}},
+		"noauth":   {Instructions: rwo.RewriteList{[]string{"header", "delete", "Authorization"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.CacheIdentityRewriter = ri["identity"]
+
+	// request bodies that are not forms are included in the key
+	if key(`{"query":"up"}`, "") == key(`{"query":"down"}`, "") {
+		t.Error("expected cache keys to differ by body")
+	}
+	if key(`{"query":"up"}`, "a") == key(`{"query":"up"}`, "b") {
+		t.Error("expected cache keys to differ by authorization")
+	}
+
+	// the identity is that of the rewritten request
+	cfg.CacheIdentityRewriter = ri["noauth"]
+	if key(`{"query":"up"}`, "a") != key(`{"query":"up"}`, "b") {
+		t.Error("expected cache keys to match after the authorization header is removed")
+	}
+
+	// the identity is hashed when cache_key_from_auth_hash is set
+	cfg.CacheIdentityRewriter = ri["identity"]
+	cfg.CacheKeyFromAuthHash = true
+	ka := key(`{"query":"up"}`, "a")
+	if ka == key(`{"query":"up"}`, "b") {
+		t.Error("expected cache keys to differ by authorization hash")
+	}
+	cfg.CacheKeyFromAuthHash = false
+	if ka == key(`{"query":"up"}`, "a") {
+		t.Error("expected the hashed identity to differ from the raw credential")
+	}
+}
+
+func TestDeriveCoalescingKey(t *testing.T) {
+
+	cfg := &oo.Options{
+		CacheKeyPrefix: "test",
+		Paths: map[string]*po.Options{
+			"root": {
+				Path:           "/",
+				CacheKeyParams: []string{"query", "session"},
+			},
+		},
+	}
+
+	newRequest := func(method, u, body, auth string) *proxyRequest {
+		tr := httptest.NewRequest(method, u, strings.NewReader(body))
+		if body != "" {
+			tr.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+		}
+		if auth != "" {
+			tr.Header.Set(headers.NameAuthorization, auth)
+		}
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		return newProxyRequest(tr, nil)
+	}
+	key := func(method, u, body, auth string) string {
+		pr := newRequest(method, u, body, auth)
+		return pr.deriveCoalescingKey(pr.DeriveCacheKey(nil, ""))
+	}
+
+	const u1 = "http://0/?query=12345&session=abc"
+	const u2 = "http://0/?session=xyz&query=12345"
+
+	// without the rewriter, requests are coalesced by their cache key
+	pr := newRequest(http.MethodGet, u1, "", "")
+	if ck := pr.DeriveCacheKey(nil, ""); pr.deriveCoalescingKey(ck) != ck {
+		t.Error("expected the coalescing key to be the cache key")
+	}
+	if key(http.MethodGet, u1, "", "") == key(http.MethodGet, u2, "", "") {
+		t.Error("expected coalescing keys to differ by session")
+	}
+
+	ri, err := rewriter.ProcessConfigs(map[string]*rwo.Options{"coalesce": {
+		Instructions: rwo.RewriteList{[]string{"param", "delete", "session"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.CoalescingKeyRewriter = ri["coalesce"]
+
+	// the session param is removed from the coalescing key, but not from the cache key
+	if key(http.MethodGet, u1, "", "") != key(http.MethodGet, u2, "", "") {
+		t.Error("expected coalescing keys to match after the session param is removed")
+	}
+	if newRequest(http.MethodGet, u1, "", "").DeriveCacheKey(nil, "") ==
+		newRequest(http.MethodGet, u2, "", "").DeriveCacheKey(nil, "") {
+		t.Error("expected cache keys to differ by session")
+	}
+	if key(http.MethodGet, u1, "", "Bearer a") == key(http.MethodGet, u1, "", "Bearer b") {
+		t.Error("expected coalescing keys to differ by authorization")
+	}
+	if k := key(http.MethodGet, u1, "", ""); !strings.HasPrefix(k, "test.coalesce.") {
+		t.Errorf("unexpected coalescing key %s", k)
+	}
+
+	// request bodies are in the coalescing key, and remain readable afterward
+	if key(http.MethodPost, "http://0/", "query=1&session=abc", "") !=
+		key(http.MethodPost, "http://0/", "session=xyz&query=1", "") {
+		t.Error("expected coalescing keys to match after the session param is removed")
+	}
+	if key(http.MethodPost, "http://0/", "query=1", "") == key(http.MethodPost, "http://0/", "query=2", "") {
+		t.Error("expected coalescing keys to differ by body")
+	}
+	pr = newRequest(http.MethodPost, "http://0/", `{"query":1}`, "")
+	pr.upstreamRequest.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+	k1 := pr.deriveCoalescingKey("")
+	pr = newRequest(http.MethodPost, "http://0/", `{"query":2}`, "")
+	pr.upstreamRequest.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+	if k1 == pr.deriveCoalescingKey("") {
+		t.Error("expected coalescing keys to differ by json body")
+	}
+	pr = newRequest(http.MethodPost, "http://0/", "query=1", "")
+	pr.deriveCoalescingKey("")
+	if b, _ := ioutil.ReadAll(pr.upstreamRequest.Body); string(b) != "query=1" {
+		t.Errorf("expected body %s got %s", "query=1", string(b))
+	}
+}
+
+func TestDeriveCacheKeyEncoding(t *testing.T) {
+
+	cfg := &oo.Options{
+		Paths: map[string]*po.Options{
+			"root": {
+				Path:           "/",
+				CacheKeyParams: []string{"query", "step", "time"},
+			},
+		},
+	}
+
+	tests := []struct {
+		encoding, expected string
+	}{
+		{"", "52dc11456c84506d3444e53ee4c99777"},
+		{oo.CacheKeyEncodingHex, "52dc11456c84506d3444e53ee4c99777"},
+		{oo.CacheKeyEncodingBase64URL, "UtwRRWyEUG00ROU-5MmXdw"},
+		{oo.CacheKeyEncodingBase32, "KLOBCRLMQRIG2NCE4U7OJSMXO4"},
+	}
+
+	for _, test := range tests {
+		cfg.CacheKeyEncoding = test.encoding
+		tr := httptest.NewRequest("GET", "http://127.0.0.1/?query=12345&start=0&end=0&step=300&time=0", nil)
+		tr = tr.WithContext(ct.WithResources(context.Background(),
+			request.NewResources(cfg, cfg.Paths["root"], nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+		pr := newProxyRequest(tr, nil)
+		if ck := pr.DeriveCacheKey(nil, "extra"); ck != test.expected {
+			t.Errorf("expected %s got %s", test.expected, ck)
+		}
+	}
+
+	// keys that are not hex digests are not re-encoded
+	if k := encodeCacheKey("custom-key", oo.CacheKeyEncodingBase32); k != "custom-key" {
+		t.Errorf("expected %s got %s", "custom-key", k)
+	}
+}
+
+func TestLimitKeyLength(t *testing.T) {
+
+	const key = "trickster.opc.0123456789abcdef0123456789abcdef"
+
+	if k := limitKeyLength(key, nil); k != key {
+		t.Errorf("expected %s got %s", key, k)
+	}
+
+	cc := co.NewOptions()
+	if k := limitKeyLength(key, cc); k != key {
+		t.Errorf("expected %s got %s", key, k)
+	}
+
+	cc.MaxKeyLengthBytes = len(key)
+	if k := limitKeyLength(key, cc); k != key {
+		t.Errorf("expected %s got %s", key, k)
+	}
+
+	cc.MaxKeyLengthBytes = 32
+	if k := limitKeyLength(key, cc); k != md5.Checksum(key) {
+		t.Errorf("expected %s got %s", md5.Checksum(key), k)
+	}
+}
+
 func TestDeriveCacheKeyNoPathConfig(t *testing.T) {
 
 	client := &TestClient{
@@ -282,3 +788,20 @@ func TestDeriveCacheKeyNilURL(t *testing.T) {
 		t.Errorf("unexpected cache key: %s", k)
 	}
 }
+
+func TestCacheKeyPath(t *testing.T) {
+
+	if p := cacheKeyPath(nil, "/query"); p != "/query" {
+		t.Errorf("expected %s got %s", "/query", p)
+	}
+
+	oc := &oo.Options{StripPathPrefix: "/prometheus"}
+	if p := cacheKeyPath(oc, "/query"); p != "/prometheus/query" {
+		t.Errorf("expected %s got %s", "/prometheus/query", p)
+	}
+
+	oc.StripPathPrefixFromCacheKey = true
+	if p := cacheKeyPath(oc, "/query"); p != "/query" {
+		t.Errorf("expected %s got %s", "/query", p)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// negativeRevalidations tracks, by cache key, the negatively-cached objects that have
+// been revalidated within the last negative_cache_revalidate_interval_ms of their origin
+var negativeRevalidations sync.Map
+
+// revalidateNegativeCache starts a background revalidation of the negatively-cached object
+// served by the request, when the origin enables negative_cache_revalidate
+func (pr *proxyRequest) revalidateNegativeCache() {
+
+	rsc := request.GetResources(pr.Request)
+	if rsc == nil || rsc.OriginConfig == nil || !rsc.OriginConfig.NegativeCacheRevalidate ||
+		pr.upstreamRequest == nil {
+		return
+	}
+
+	key := pr.key
+	if _, ok := negativeRevalidations.LoadOrStore(key, true); ok {
+		return
+	}
+
+	req := request.SetResources(pr.upstreamRequest.Clone(context.Background()), rsc)
+	// the client's conditional headers would only be evaluated against the upstream's object
+	stripConditionalHeaders(req.Header)
+
+	interval := rsc.OriginConfig.NegativeCacheRevalidateInterval
+	goTracked(rsc.OriginConfig, func() {
+		revalidateNegativeCacheEntry(req, negativeCacheClient(rsc), key, pr.Logger)
+		time.AfterFunc(interval, func() { negativeRevalidations.Delete(key) })
+	})
+}
+
+// revalidateNegativeCacheEntry requests the object from the upstream, and removes the negatively-cached
+// entry stored under key if the upstream responds successfully. It returns true if the entry was removed
+func revalidateNegativeCacheEntry(req *http.Request, cc cache.Cache, key string, log *tl.Logger) bool {
+
+	reader, resp, _ := PrepareFetchReader(req)
+	if reader != nil {
+		io.Copy(ioutil.Discard, reader)
+		reader.Close()
+	}
+
+	// an error response, whether or not it is the negatively-cached status,
+	// does not indicate the object has recovered, so the entry is kept
+	if resp == nil || resp.StatusCode >= http.StatusBadRequest {
+		return false
+	}
+
+	log.Debug("negatively-cached object revalidated successfully, removing from cache",
+		tl.Pairs{"key": key, "statusCode": resp.StatusCode})
+	cc.Remove(key)
+	return true
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+)
+
+func TestRevalidateNegativeCacheEntry(t *testing.T) {
+
+	const key = "test-negative-key"
+
+	tests := []struct {
+		code    int
+		removed bool
+	}{
+		{http.StatusNotFound, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusOK, true},
+	}
+
+	for _, test := range tests {
+		ts, _, r, rsc, err := setupTestHarnessOPC("", "test", test.code, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cc := rsc.CacheClient
+		cc.Store(key, []byte("negative"), time.Minute)
+
+		if removed := revalidateNegativeCacheEntry(r, cc, key, testLogger); removed != test.removed {
+			t.Errorf("expected %t got %t for status %d", test.removed, removed, test.code)
+		}
+
+		_, _, err = cc.Retrieve(key, false)
+		if test.removed && err != cache.ErrKNF {
+			t.Errorf("expected %v got %v", cache.ErrKNF, err)
+		} else if !test.removed && err != nil {
+			t.Error(err)
+		}
+
+		cc.Remove(key)
+		ts.Close()
+	}
+}
+
+func TestRevalidateNegativeCacheOnce(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusNotFound, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.NegativeCacheRevalidate = true
+	rsc.OriginConfig.NegativeCacheRevalidateInterval = 10 * time.Millisecond
+	pr := newProxyRequest(r, nil)
+	pr.key = "test-negative-once"
+
+	pr.revalidateNegativeCache()
+	if _, ok := negativeRevalidations.Load(pr.key); !ok {
+		t.Error("expected revalidation to be tracked")
+	}
+
+	// the object may be revalidated again once the interval elapses
+	for i := 0; i < 100; i++ {
+		if _, ok := negativeRevalidations.Load(pr.key); !ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("expected revalidation tracking to expire after the interval")
+}
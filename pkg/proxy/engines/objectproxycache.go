@@ -25,13 +25,16 @@ import (
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/proxy/acl"
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
 	"github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 
 	"go.opentelemetry.io/otel/api/kv"
 	"go.opentelemetry.io/otel/api/trace"
@@ -126,8 +129,18 @@ func handleCachePartialHit(pr *proxyRequest) error {
 
 func confirmTrueCacheHit(pr *proxyRequest) (bool, error) {
 
+	if pr.cacheDocument.CachingPolicy != nil {
+		pr.cachedDate = pr.cacheDocument.CachingPolicy.LocalDate
+	}
 	pr.cachingPolicy.Merge(pr.cacheDocument.CachingPolicy)
 
+	// an object failing validation is fetched in full, since a conditional
+	// revalidation would only confirm the cached object
+	if !pr.validateCacheObject() {
+		pr.cacheStatus = status.LookupStatusKeyMiss
+		return false, handleCacheKeyMiss(pr)
+	}
+
 	if (!pr.checkCacheFreshness()) && (pr.cachingPolicy.CanRevalidate) {
 		return false, handleCacheRevalidation(pr)
 	}
@@ -215,7 +228,15 @@ func handleCacheRevalidationResponse(pr *proxyRequest) error {
 	}
 
 	pr.revalidation = RevalStatusFailed
+
+	// while the origin's circuit breaker rejects requests, the stale cached object is served
+	if isBreakerOpenResponse(pr.upstreamResponse) {
+		pr.cacheStatus = status.LookupStatusHit
+		return handleTrueCacheHit(pr)
+	}
+
 	pr.cacheStatus = status.LookupStatusKeyMiss
+	pr.transformResponse()
 	return handleAllWrites(pr)
 }
 
@@ -228,6 +249,7 @@ func handleTrueCacheHit(pr *proxyRequest) error {
 
 	if pr.cachingPolicy.IsNegativeCache {
 		pr.cacheStatus = status.LookupStatusNegativeCacheHit
+		pr.revalidateNegativeCache()
 	}
 
 	pr.upstreamResponse = &http.Response{StatusCode: d.StatusCode, Request: pr.Request,
@@ -255,9 +277,10 @@ func handleCacheKeyMiss(pr *proxyRequest) error {
 	rsc := request.GetResources(pr.Request)
 	pc := rsc.PathConfig
 
-	// if a we're using PCF, handle that separately
+	// if a we're using PCF, handle that separately. PCF streams the upstream response to
+	// clients as it arrives, so it is not used on paths with a response transform
 	if !methods.HasBody(pr.Method) && !pr.wantsRanges && pc != nil &&
-		pc.CollapsedForwardingType == forwarding.CFTypeProgressive {
+		pc.CollapsedForwardingType == forwarding.CFTypeProgressive && pc.ResponseTransformer == nil {
 		if err := handlePCF(pr); err != errors.ErrPCFContentLength {
 			// if err is nil, or something else, we'll proceed.
 			return err
@@ -266,6 +289,8 @@ func handleCacheKeyMiss(pr *proxyRequest) error {
 
 	pr.prepareUpstreamRequests()
 	handleUpstreamTransactions(pr)
+	pr.bufferResponse()
+	pr.transformResponse()
 	return handleAllWrites(pr)
 }
 
@@ -282,7 +307,7 @@ func handlePCF(pr *proxyRequest) error {
 	oc := rsc.OriginConfig
 
 	pr.isPCF = true
-	pcfResult, pcfExists := reqs.Load(pr.key)
+	pcfResult, pcfExists := reqs.Load(pr.coalescingKey)
 	// a PCF session is in progress for this URL, join this client to it.
 	if pcfExists {
 		pr.cacheLock.Release()
@@ -301,8 +326,11 @@ func handlePCF(pr *proxyRequest) error {
 		defer span.End()
 	}
 	pr.upstreamRequest = pr.upstreamRequest.WithContext(ctx)
+	pr.stripConditionalHeadersOnMiss()
 
+	start := time.Now()
 	reader, resp, contentLength := PrepareFetchReader(pr.upstreamRequest)
+	pr.upstreamTime += time.Since(start)
 	pr.upstreamResponse = resp
 
 	pr.writeResponseHeader()
@@ -310,14 +338,14 @@ func handlePCF(pr *proxyRequest) error {
 	// Check if we know the content length and if it is less than our max object size.
 	if contentLength > 0 && contentLength < int64(oc.MaxObjectSizeBytes) {
 		pcf := NewPCF(resp, contentLength)
-		reqs.Store(pr.key, pcf)
+		reqs.Store(pr.coalescingKey, pcf)
 		// Blocks until server completes
 
 		pr.cachingPolicy.Merge(GetResponseCachingPolicy(pr.upstreamResponse.StatusCode,
 			rsc.OriginConfig.NegativeCache, pr.upstreamResponse.Header))
 		pr.determineCacheability()
 
-		go func() {
+		goTracked(rsc.OriginConfig, func() {
 			var dest io.Writer = pcf
 			if pr.writeToCache {
 				pr.cacheBuffer = &bytes.Buffer{}
@@ -325,8 +353,8 @@ func handlePCF(pr *proxyRequest) error {
 			}
 			io.Copy(dest, reader)
 			pcf.Close()
-			reqs.Delete(pr.key)
-		}()
+			reqs.Delete(pr.coalescingKey)
+		})
 
 		pcf.AddClient(pr.responseWriter)
 
@@ -347,6 +375,9 @@ func handleAllWrites(pr *proxyRequest) error {
 				d.Body = pr.cacheBuffer.Bytes()
 			}
 		}
+		if oc := request.GetResources(pr.Request).OriginConfig; oc.AsyncCacheWrite && pr.storeAsync() {
+			return nil
+		}
 		pr.store()
 	}
 	return nil
@@ -374,12 +405,35 @@ func init() {
 	}
 }
 
+// replacesCachedRanges returns true if the request partially matches the cached ranges of an
+// object whose origin is configured to replace, rather than merge, cached byte ranges
+func replacesCachedRanges(pr *proxyRequest) bool {
+	rsc := request.GetResources(pr.Request)
+	return rsc.OriginConfig.ByteRangeReassemblyPolicy == oo.ByteRangeReassemblyPolicyReplace &&
+		(pr.cacheStatus == status.LookupStatusPartialHit || pr.cacheStatus == status.LookupStatusRangeMiss)
+}
+
+// negativeCacheClient returns the cache used to store negatively-cached responses,
+// which is the primary cache unless the origin configures a separate negative cache backend
+func negativeCacheClient(rsc *request.Resources) cache.Cache {
+	if rsc.NegativeCacheClient != nil {
+		return rsc.NegativeCacheClient
+	}
+	return rsc.CacheClient
+}
+
 func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, status.LookupStatus) {
 
 	rsc := request.GetResources(r)
 	oc := rsc.OriginConfig
 	cc := rsc.CacheClient
 
+	// requests with an idempotency key are deduplicated by DoProxy rather than cached
+	if (rsc.PathConfig != nil && rsc.PathConfig.CacheDisabled) || !oc.IsCacheableMethod(r.Method) ||
+		hasIdempotencyKey(r, oc) {
+		return nil, status.LookupStatusProxyOnly
+	}
+
 	pr := newProxyRequest(r, w)
 
 	_, span := tspan.NewChildSpan(r.Context(), rsc.Tracer, "ObjectProxyCacheRequest")
@@ -391,11 +445,21 @@ func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, sta
 	pr.parseRequestRanges()
 
 	pr.cachingPolicy = GetRequestCachingPolicy(pr.Header)
+	if oc.HonorClientMaxAge && oc.ClientMaxAgeACL != nil && acl.Permits(oc.ClientMaxAgeACL, pr.Request) {
+		pr.clientMaxAge, pr.hasClientMaxAge = GetClientMaxAge(pr.Header)
+	}
+
+	pr.key = limitKeyLength(oc.CacheKeyPrefix+".opc."+pr.DeriveCacheKey(nil, ""), rsc.CacheConfig)
+	if pr.keyRejected {
+		pr.Logger.Debug("cache key components exceed limit, proxying without caching",
+			log.Pairs{"maxCacheKeyComponents": oc.MaxCacheKeyComponents})
+		return nil, status.LookupStatusProxyOnly
+	}
 
-	pr.key = oc.CacheKeyPrefix + ".opc." + pr.DeriveCacheKey(nil, "")
+	pr.coalescingKey = pr.deriveCoalescingKey(pr.key)
 
 	// if a PCF entry exists, or the client requested no-cache for this object, proxy out to it
-	pcfResult, pcfExists := reqs.Load(pr.key)
+	pcfResult, pcfExists := reqs.Load(pr.coalescingKey)
 	pr.isPCF = !methods.HasBody(pr.Method) && pcfExists && !pr.wantsRanges
 
 	if pr.isPCF || pr.cachingPolicy.NoCache {
@@ -403,25 +467,52 @@ func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, sta
 			cc.Remove(pr.key)
 			return nil, status.LookupStatusProxyOnly
 		}
+		ok, done := joinCollapsedWaiters(oc, pr.coalescingKey)
+		if !ok {
+			return collapsedWaitersExceeded(pr, w)
+		}
 		pcf := pcfResult.(ProgressiveCollapseForwarder)
 		pr.upstreamResponse = pcf.GetResp()
 		writer := PrepareResponseWriter(w, pr.upstreamResponse.StatusCode, pr.upstreamResponse.Header)
 		pcf.AddClient(writer)
+		done()
 		return pr.upstreamResponse, status.LookupStatusProxyHit
 	}
 
 	pr.cachingPolicy.ParseClientConditionals()
 
 	if !rsc.NoLock {
-		pr.cacheLock, _ = cc.Locker().RAcquire(pr.key)
+		// the read lock blocks while another request holds the write lock to fetch the object
+		ok, done := joinCollapsedWaiters(oc, pr.key)
+		if !ok {
+			return collapsedWaitersExceeded(pr, w)
+		}
+		pr.cacheLock, ok = acquireCollapsedReadLock(oc, cc.Locker(), pr.key)
+		done()
+		if !ok {
+			return collapsedForwardingTimedOut(pr, w)
+		}
 		pr.hasReadLock = true
 	}
 
 	var err error
+	lookupStart := time.Now()
 	pr.cacheDocument, pr.cacheStatus, pr.neededRanges, err =
 		QueryCache(pr.upstreamRequest.Context(), cc, pr.key, pr.wantedRanges)
+	if nc := negativeCacheClient(rsc); err == cache.ErrKNF && nc != cc {
+		// the object may have been negatively cached in the separate negative cache backend
+		pr.cacheDocument, pr.cacheStatus, pr.neededRanges, err =
+			QueryCache(pr.upstreamRequest.Context(), nc, pr.key, pr.wantedRanges)
+	}
+	pr.cacheLookupTime = time.Since(lookupStart)
 	if err == nil || err == cache.ErrKNF {
-		if f, ok := cacheResponseHandlers[pr.cacheStatus]; ok {
+		if replacesCachedRanges(pr) {
+			// the cached ranges won't be reassembled with the needed ranges, so fetch all
+			// of the wanted ranges, which will replace the cached ranges when stored
+			pr.cacheDocument = nil
+			pr.neededRanges = pr.wantedRanges
+			handleCacheKeyMiss(pr)
+		} else if f, ok := cacheResponseHandlers[pr.cacheStatus]; ok {
 			f(pr)
 		} else {
 			pr.Logger.Warn("unhandled cache lookup response", log.Pairs{"lookupStatus": pr.cacheStatus})
@@ -448,12 +539,45 @@ func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, sta
 	pr.elapsed = time.Since(pr.started)
 	el := float64(pr.elapsed.Milliseconds()) / 1000.0
 	recordOPCResult(pr, pr.cacheStatus, pr.upstreamResponse.StatusCode, r.URL.Path, el, pr.upstreamResponse.Header)
+	if pr.wantsRanges {
+		metrics.ProxyByteRangeRequests.WithLabelValues(oc.Name, oc.OriginType, pr.cacheStatus.String()).Inc()
+	}
 
 	return pr.upstreamResponse, pr.cacheStatus
 }
 
+// collapsedWaitersExceeded handles a request that would exceed the origin's max_collapsed_waiters,
+// by either proxying it independently or rejecting it, per the origin's collapsed_waiters_policy
+func collapsedWaitersExceeded(pr *proxyRequest, w io.Writer) (*http.Response, status.LookupStatus) {
+	oc := request.GetResources(pr.Request).OriginConfig
+	pr.Logger.Debug("max collapsed waiters exceeded",
+		log.Pairs{"maxCollapsedWaiters": oc.MaxCollapsedWaiters, "policy": oc.CollapsedWaitersPolicy})
+	if oc.CollapsedWaitersPolicy == oo.CollapsedWaitersPolicyReject {
+		return respondCollapsedWaitersExceeded(w, pr.Request), status.LookupStatusProxyError
+	}
+	return nil, status.LookupStatusProxyOnly
+}
+
+// collapsedForwardingTimedOut handles a request that waited longer than the origin's
+// collapsed_forwarding_timeout_ms on a collapsed-forwarding fetch, by either proxying it
+// independently or rejecting it, per the origin's collapsed_forwarding_timeout_policy
+func collapsedForwardingTimedOut(pr *proxyRequest, w io.Writer) (*http.Response, status.LookupStatus) {
+	oc := request.GetResources(pr.Request).OriginConfig
+	pr.Logger.Debug("collapsed forwarding timeout exceeded",
+		log.Pairs{"collapsedForwardingTimeoutMS": oc.CollapsedForwardingTimeoutMS,
+			"policy": oc.CollapsedForwardingTimeoutPolicy})
+	if oc.CollapsedForwardingTimeoutPolicy == oo.CollapsedWaitersPolicyReject {
+		return respondCollapsedWaitersExceeded(w, pr.Request), status.LookupStatusProxyError
+	}
+	return nil, status.LookupStatusProxyOnly
+}
+
 // ObjectProxyCacheRequest provides a Basic HTTP Reverse Proxy/Cache
 func ObjectProxyCacheRequest(w http.ResponseWriter, r *http.Request) {
+	if isUpgradeRequest(w, r) {
+		doUpgradeProxy(w, r)
+		return
+	}
 	_, cacheStatus := fetchViaObjectProxyCache(w, r)
 	if cacheStatus == status.LookupStatusProxyOnly {
 		DoProxy(w, r, true)
@@ -27,14 +27,22 @@ import (
 	"time"
 
 	"github.com/tricksterproxy/mockster/pkg/mocks/byterange"
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
 	"github.com/tricksterproxy/trickster/pkg/locks"
+	ao "github.com/tricksterproxy/trickster/pkg/proxy/acl/options"
 	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	rwo "github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/transform"
 	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
 )
 
@@ -158,6 +166,206 @@ func TestObjectProxyCacheRequest(t *testing.T) {
 
 }
 
+func TestObjectProxyCacheValidationRewriter(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60", "X-Schema-Version": "1"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	ri, err := rewriter.ProcessConfigs(map[string]*rwo.Options{
+		"v1": {Instructions: rwo.RewriteList{[]string{"header", "set", "X-Schema-Version", "1"}}},
+		"v2": {Instructions: rwo.RewriteList{[]string{"header", "set", "X-Schema-Version", "2"}}},
+		"missing": {OnMissingReference: "error",
+			Instructions: rwo.RewriteList{[]string{"header", "set", "X-Schema", "${header:X-Schema-Name}"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oc := rsc.OriginConfig
+	oc.CacheValidationRewriter = ri["v1"]
+
+	tests := []struct {
+		rewriter, expected string
+	}{
+		{"v1", "kmiss"},
+		{"v1", "hit"},
+		{"v2", "kmiss"},
+		{"v2", "kmiss"},
+		{"v1", "hit"},
+		{"missing", "kmiss"},
+	}
+
+	for _, test := range tests {
+		oc.CacheValidationRewriter = ri[test.rewriter]
+		_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": test.expected})
+		for _, err = range e {
+			t.Errorf("%s: %s", test.rewriter, err)
+		}
+	}
+}
+
+func TestObjectProxyCacheAsyncWrite(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	oc := rsc.OriginConfig
+	oc.AsyncCacheWrite = true
+	oc.AsyncCacheWriteSlots = oo.NewSlots(1)
+
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// the write lock is held until the background write completes, so this waits on it
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if n := oc.AsyncCacheWriteSlots.InUse(); n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+
+	// when no slot is available, the object is written before the response completes
+	r.URL.Path = "/other"
+	oc.AsyncCacheWriteSlots.TryAcquire()
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
+func TestObjectProxyCacheAgeHeader(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60", headers.NameAge: "5"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	w, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if v := w.Header().Get(headers.NameAge); v != "5" {
+		t.Errorf("expected %s got %s", "5", v)
+	}
+
+	w, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if v := w.Header().Get(headers.NameAge); v != "5" {
+		t.Errorf("expected %s got %s", "5", v)
+	}
+
+	rsc.OriginConfig.EmitAgeHeader = false
+	w, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	// when disabled, the stored upstream Age header is passed through as-is
+	if v := w.Header().Get(headers.NameAge); v != "5" {
+		t.Errorf("expected %s got %s", "5", v)
+	}
+}
+
+func TestObjectProxyCacheHonorClientMaxAge(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// the client max-age is ignored unless the origin is configured to honor it
+	r.Header.Set(headers.NameCacheControl, "max-age=0")
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// the client max-age is also ignored for clients outside of the client_max_age_acl
+	rsc.OriginConfig.HonorClientMaxAge = true
+	rsc.OriginConfig.ClientMaxAgeACL = &ao.Options{Allow: []string{"10.0.0.0/8"}}
+	rsc.OriginConfig.ClientMaxAgeACL.Validate()
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	r.RemoteAddr = "10.0.0.1:12345"
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	r.Header.Set(headers.NameCacheControl, "max-age=30")
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
+func TestSetAgeHeader(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	pr := newProxyRequest(r, httptest.NewRecorder())
+	pr.upstreamResponse = &http.Response{Header: http.Header{headers.NameAge: []string{"5"}}}
+	pr.cacheStatus = status.LookupStatusHit
+	pr.cachedDate = time.Now().Add(-10 * time.Second)
+
+	pr.setAgeHeader()
+	if v := pr.upstreamResponse.Header.Get(headers.NameAge); v != "15" {
+		t.Errorf("expected %s got %s", "15", v)
+	}
+
+	pr.cacheStatus = status.LookupStatusRevalidated
+	pr.setAgeHeader()
+	if v := pr.upstreamResponse.Header.Get(headers.NameAge); v != "0" {
+		t.Errorf("expected %s got %s", "0", v)
+	}
+
+	pr.cacheStatus = status.LookupStatusKeyMiss
+	pr.upstreamResponse.Header.Set(headers.NameAge, "5")
+	pr.setAgeHeader()
+	if v := pr.upstreamResponse.Header.Get(headers.NameAge); v != "5" {
+		t.Errorf("expected %s got %s", "5", v)
+	}
+
+	rsc.OriginConfig.EmitAgeHeader = false
+	pr.cacheStatus = status.LookupStatusHit
+	pr.setAgeHeader()
+	if v := pr.upstreamResponse.Header.Get(headers.NameAge); v != "5" {
+		t.Errorf("expected %s got %s", "5", v)
+	}
+}
+
 func TestObjectProxyCachePartialHit(t *testing.T) {
 
 	ts, _, r, rsc, err := setupTestHarnessOPCRange(nil)
@@ -234,6 +442,281 @@ func TestObjectProxyCachePartialHit(t *testing.T) {
 	}
 }
 
+func TestObjectProxyCacheServerTiming(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.EmitServerTiming = true
+
+	w, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	st := w.Header().Get(headers.NameServerTiming)
+	if !strings.HasPrefix(st, "cache;dur=") || !strings.Contains(st, ", upstream;dur=") {
+		t.Errorf("unexpected Server-Timing header: %s", st)
+	}
+
+	// a cache hit has no upstream fetch
+	w, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	st = w.Header().Get(headers.NameServerTiming)
+	if !strings.HasPrefix(st, "cache;dur=") || strings.Contains(st, "upstream") {
+		t.Errorf("unexpected Server-Timing header: %s", st)
+	}
+}
+
+func TestObjectProxyCacheMaxCollapsedWaiters(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.MaxCollapsedWaiters = 1
+	rsc.OriginConfig.CollapsedWaitersPolicy = oo.CollapsedWaitersPolicyProxy
+
+	// occupy the only waiter slot for the request's cache key
+	pr := newProxyRequest(r, nil)
+	key := limitKeyLength(rsc.OriginConfig.CacheKeyPrefix+".opc."+pr.DeriveCacheKey(nil, ""), rsc.CacheConfig)
+	if !collapsedWaiters.join(key, 1) {
+		t.Fatal("expected to join waiters")
+	}
+
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "proxy-only"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	rsc.OriginConfig.CollapsedWaitersPolicy = oo.CollapsedWaitersPolicyReject
+	_, e = testFetchOPC(r, http.StatusServiceUnavailable, "", nil)
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// once the slot is released, requests are served from the cache again
+	collapsedWaiters.leave(key)
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if _, ok := collapsedWaiters.counts[key]; ok {
+		t.Error("expected waiter count to be removed")
+	}
+}
+
+func TestObjectProxyCacheDisabledPath(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.PathConfig.CacheDisabled = true
+
+	// a cacheable response is proxied on every request and never written to the cache
+	for i := 0; i < 2; i++ {
+		_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "proxy-only"})
+		for _, err = range e {
+			t.Error(err)
+		}
+	}
+
+	rsc.PathConfig.CacheDisabled = false
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
+func TestObjectProxyCacheResponseTransform(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60", "Content-Type": "application/xml"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", `<a><b>1</b></a>`, http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.PathConfig.ResponseTransformer, _ = transform.Get("xml2json")
+
+	// the converted response is cached, and served converted on the subsequent hit
+	w, e := testFetchOPC(r, http.StatusOK, `{"a":{"b":"1"}}`, map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if ct := w.Header().Get(headers.NameContentType); ct != "application/json" {
+		t.Errorf("expected %s got %s", "application/json", ct)
+	}
+	w, e = testFetchOPC(r, http.StatusOK, `{"a":{"b":"1"}}`, map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	if ct := w.Header().Get(headers.NameContentType); ct != "application/json" {
+		t.Errorf("expected %s got %s", "application/json", ct)
+	}
+}
+
+func TestObjectProxyCacheResponseTransformTooLarge(t *testing.T) {
+
+	body := `<a><b>1</b></a>`
+	hdrs := map[string]string{"Cache-Control": "max-age=60", "Content-Type": "application/xml"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", body, http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.PathConfig.ResponseTransformer, _ = transform.Get("xml2json")
+	rsc.OriginConfig.MaxObjectSizeBytes = 4
+
+	// bodies larger than max_object_size_bytes are passed through unconverted
+	_, e := testFetchOPC(r, http.StatusOK, body, nil)
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
+func TestObjectProxyCacheUncacheableMethod(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.CacheableMethods = []string{http.MethodHead}
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "proxy-only"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	rsc.OriginConfig.CacheableMethods = []string{http.MethodGet, http.MethodHead}
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
+func TestObjectProxyCacheCollapsedForwardingTimeout(t *testing.T) {
+
+	hdrs := map[string]string{"Cache-Control": "max-age=60"}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.CollapsedForwardingTimeout = 10 * time.Millisecond
+	rsc.OriginConfig.CollapsedForwardingTimeoutPolicy = oo.CollapsedWaitersPolicyProxy
+
+	// hold the write lock for the request's cache key, as a stuck leader fetch would
+	pr := newProxyRequest(r, nil)
+	key := limitKeyLength(rsc.OriginConfig.CacheKeyPrefix+".opc."+pr.DeriveCacheKey(nil, ""), rsc.CacheConfig)
+	nl, _ := rsc.CacheClient.Locker().Acquire(key)
+
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "proxy-only"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	rsc.OriginConfig.CollapsedForwardingTimeoutPolicy = oo.CollapsedWaitersPolicyReject
+	_, e = testFetchOPC(r, http.StatusServiceUnavailable, "", nil)
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// once the leader releases the lock, requests are served from the cache again
+	nl.Release()
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
+func TestSetServerTimingHeader(t *testing.T) {
+	h := http.Header{headers.NameServerTiming: {"db;dur=53"}}
+	setServerTimingHeader(h, 1500*time.Microsecond, 0)
+	if v := h[headers.NameServerTiming]; len(v) != 2 || v[1] != "cache;dur=1.500" {
+		t.Errorf("unexpected Server-Timing header: %v", v)
+	}
+	h = http.Header{}
+	setServerTimingHeader(h, 0, 0)
+	if _, ok := h[headers.NameServerTiming]; ok {
+		t.Error("expected no Server-Timing header")
+	}
+}
+
+func TestObjectProxyCacheByteRangesDisabled(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPCRange(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.CacheByteRanges = false
+
+	r.Header.Set(headers.NameRange, "bytes=0-10")
+	expectedBody, err := getExpectedRangeBody(r, "")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the partial response should not have been cached, so both requests are key misses
+	for i := 0; i < 2; i++ {
+		_, e := testFetchOPC(r, http.StatusPartialContent, expectedBody, map[string]string{"status": "kmiss"})
+		for _, err = range e {
+			t.Error(err)
+		}
+	}
+}
+
+func TestObjectProxyCacheByteRangeReassemblyReplace(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPCRange(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.ByteRangeReassemblyPolicy = oo.ByteRangeReassemblyPolicyReplace
+
+	tests := []struct {
+		rng, status string
+	}{
+		{"bytes=0-10", "kmiss"},
+		{"bytes=5-15", "phit"},
+		{"bytes=5-15", "hit"},
+		// 0-4 was replaced by 5-15 and is no longer cached
+		{"bytes=0-4", "rmiss"},
+	}
+
+	for i, test := range tests {
+		r.Header.Set(headers.NameRange, test.rng)
+		expectedBody, err := getExpectedRangeBody(r, "")
+		if err != nil {
+			t.Error(err)
+		}
+		_, e := testFetchOPC(r, http.StatusPartialContent, expectedBody, map[string]string{"status": test.status})
+		for _, err = range e {
+			t.Errorf("test %d: %v", i, err)
+		}
+	}
+}
+
 func TestFullArticuation(t *testing.T) {
 
 	ts, _, r, rsc, err := setupTestHarnessOPCRange(nil)
@@ -824,6 +1307,68 @@ func TestObjectProxyCacheRequestNegativeCache(t *testing.T) {
 	}
 }
 
+func TestObjectProxyCacheRequestNegativeCacheBackend(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusNotFound, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	defer ts.Close()
+
+	nc := cr.NewCache("negative", co.NewOptions(), testLogger)
+	if err := nc.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+
+	pc := po.NewOptions()
+	cfg := rsc.OriginConfig
+	cfg.Paths = map[string]*po.Options{
+		"/": pc,
+	}
+	cfg.NegativeCache[404] = time.Second * 30
+	rsc2 := request.NewResources(cfg, pc, rsc.CacheConfig, rsc.CacheClient, rsc.OriginClient, nil, rsc.Logger)
+	rsc2.NegativeCacheClient = nc
+	r = r.WithContext(tc.WithResources(r.Context(), rsc2))
+
+	_, e := testFetchOPC(r, http.StatusNotFound, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// the negatively-cached object should be served from the negative cache backend
+	_, e = testFetchOPC(r, http.StatusNotFound, "test", map[string]string{"status": "nchit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	// and should not have been written to the primary cache
+	key := cfg.CacheKeyPrefix + ".opc." + newProxyRequest(r, nil).DeriveCacheKey(nil, "")
+	if _, _, err := rsc.CacheClient.Retrieve(key, false); err != cache.ErrKNF {
+		t.Errorf("expected %v got %v", cache.ErrKNF, err)
+	}
+	if _, _, err := nc.Retrieve(key, false); err != nil {
+		t.Error(err)
+	}
+
+	// a negatively-cached response removes any object held for the key by the primary cache,
+	// which would otherwise shadow the negative cache entry
+	if err := rsc.CacheClient.Store(key, []byte("positive"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	pr := newProxyRequest(r, nil)
+	pr.key = key
+	pr.writeToCache = true
+	pr.cacheDocument = &HTTPDocument{StatusCode: http.StatusNotFound}
+	pr.cachingPolicy = &CachingPolicy{IsNegativeCache: true}
+	if cc, _, ok := pr.prepareStore(); !ok || cc != nc {
+		t.Error("expected the negative cache backend")
+	}
+	if _, _, err := rsc.CacheClient.Retrieve(key, false); err != cache.ErrKNF {
+		t.Errorf("expected %v got %v", cache.ErrKNF, err)
+	}
+}
+
 func TestHandleCacheRevalidation(t *testing.T) {
 
 	ts, _, r, _, err := setupTestHarnessOPC("", "test", http.StatusNotFound, nil)
@@ -1191,3 +1736,26 @@ func TestRerunRequest(t *testing.T) {
 		t.Error("expected true")
 	}
 }
+
+func TestObjectProxyCacheIdempotencyKey(t *testing.T) {
+
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	rsc.OriginConfig.IdempotencyKeyHeader = "Idempotency-Key"
+	r.Method = http.MethodPost
+	r.Header.Set("Idempotency-Key", "abc")
+
+	// a write routed to a caching handler is deduplicated rather than cached
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "proxy-only"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "hit"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
@@ -22,16 +22,21 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
 	"github.com/tricksterproxy/trickster/pkg/locks"
 	tctx "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/ranges/byterange"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/clientcert"
 	tspan "github.com/tricksterproxy/trickster/pkg/tracing/span"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 
@@ -62,10 +67,19 @@ type proxyRequest struct {
 	cacheLock     locks.NamedLock
 	mapLock       *sync.Mutex
 
-	key         string
-	started     time.Time
-	elapsed     time.Duration
-	cacheStatus status.LookupStatus
+	key string
+	// coalescingKey is the key under which concurrent requests share a progressive
+	// collapsed forwarding fetch
+	coalescingKey string
+	started       time.Time
+	cachedDate    time.Time
+	clientMaxAge  time.Duration
+	elapsed       time.Duration
+	cacheStatus   status.LookupStatus
+
+	// cacheLookupTime and upstreamTime are reported in the Server-Timing header
+	cacheLookupTime time.Duration
+	upstreamTime    time.Duration
 
 	wantedRanges byterange.Ranges
 	neededRanges byterange.Ranges
@@ -81,6 +95,7 @@ type proxyRequest struct {
 
 	Logger            *tl.Logger
 	isPCF             bool
+	hasClientMaxAge   bool
 	writeToCache      bool
 	hasWriteLock      bool
 	hasReadLock       bool
@@ -88,6 +103,9 @@ type proxyRequest struct {
 	wantsRanges       bool
 	isPartialResponse bool
 	wasReconstituted  bool
+	// keyRejected indicates the request exceeded the origin's max_cache_key_components
+	// under the reject policy, and must not be cached
+	keyRejected bool
 }
 
 // newProxyRequest accepts the original inbound HTTP Request and Response
@@ -128,6 +146,7 @@ func (pr *proxyRequest) Clone() *proxyRequest {
 		Logger:             pr.Logger,
 		cacheDocument:      pr.cacheDocument,
 		key:                pr.key,
+		coalescingKey:      pr.coalescingKey,
 		cacheStatus:        pr.cacheStatus,
 		writeToCache:       pr.writeToCache,
 		wantsRanges:        pr.wantsRanges,
@@ -174,7 +193,8 @@ func (pr *proxyRequest) Fetch() ([]byte, *http.Response, time.Duration) {
 	elapsed := time.Since(start) // includes any time required to decompress the document for deserialization
 
 	go logUpstreamRequest(pr.Logger, oc.Name, oc.OriginType, handlerName,
-		pr.Method, pr.URL.String(), pr.UserAgent(), resp.StatusCode, len(body), elapsed.Seconds())
+		pr.Method, pr.URL.String(), pr.UserAgent(), resp.StatusCode, len(body), elapsed.Seconds(),
+		clientcert.Verified(pr.Request))
 
 	return body, resp, elapsed
 }
@@ -274,12 +294,13 @@ func (pr *proxyRequest) prepareUpstreamRequests() {
 func (pr *proxyRequest) makeUpstreamRequests() error {
 
 	wg := sync.WaitGroup{}
+	start := time.Now()
 
 	rsc := request.GetResources(pr.Request)
 
 	if pr.revalidationRequest != nil {
 		wg.Add(1)
-		go func() {
+		goTracked(rsc.OriginConfig, func() {
 			req := pr.revalidationRequest
 			_, span := tspan.NewChildSpan(req.Context(), rsc.Tracer, "FetchRevalidation")
 			if span != nil {
@@ -293,7 +314,7 @@ func (pr *proxyRequest) makeUpstreamRequests() error {
 			}
 			pr.revalidationReader, pr.revalidationResponse, _ = PrepareFetchReader(pr.revalidationRequest)
 			wg.Done()
-		}()
+		})
 	}
 
 	if pr.originRequests != nil && len(pr.originRequests) > 0 {
@@ -301,7 +322,8 @@ func (pr *proxyRequest) makeUpstreamRequests() error {
 		pr.originReaders = make([]io.ReadCloser, len(pr.originRequests))
 		for i := range pr.originRequests {
 			wg.Add(1)
-			go func(j int) {
+			j := i
+			goTracked(rsc.OriginConfig, func() {
 				req := pr.originRequests[j]
 				_, span := tspan.NewChildSpan(req.Context(), rsc.Tracer, "Fetch")
 				if span != nil {
@@ -315,11 +337,12 @@ func (pr *proxyRequest) makeUpstreamRequests() error {
 				}
 				pr.originReaders[j], pr.originResponses[j], _ = PrepareFetchReader(req)
 				wg.Done()
-			}(i)
+			})
 		}
 	}
 
 	wg.Wait()
+	pr.upstreamTime += time.Since(start)
 
 	return nil
 }
@@ -330,6 +353,10 @@ func (pr *proxyRequest) checkCacheFreshness() bool {
 		return false
 	}
 	cp.IsFresh = !cp.LocalDate.Add(time.Duration(cp.FreshnessLifetime) * time.Second).Before(time.Now())
+	// if the client requested a max-age, the object is stale once it is older than that
+	if cp.IsFresh && pr.hasClientMaxAge && time.Since(cp.LocalDate) > pr.clientMaxAge {
+		cp.IsFresh = false
+	}
 	return cp.IsFresh
 }
 
@@ -344,7 +371,9 @@ func (pr *proxyRequest) parseRequestRanges() bool {
 
 	// if the client shouldn't support multipart ranges, force a full range
 	rsc := request.GetResources(pr.Request)
-	if rsc.OriginConfig.MultipartRangesDisabled && len(pr.wantedRanges) > 1 {
+	// and responses on paths with a response transform are always converted in full
+	if (rsc.OriginConfig.MultipartRangesDisabled && len(pr.wantedRanges) > 1) ||
+		(rsc.PathConfig != nil && rsc.PathConfig.ResponseTransformer != nil) {
 		pr.upstreamRequest.Header.Del(headers.NameRange)
 		pr.wantsRanges = false
 		pr.wantedRanges = nil
@@ -360,8 +389,107 @@ func (pr *proxyRequest) stripConditionalHeaders() {
 	}
 }
 
+// stripConditionalHeadersOnMiss removes the client's conditional headers from the upstream request
+// for an object that is not in the cache, when the origin's conditional_request_policy is strip-on-miss
+func (pr *proxyRequest) stripConditionalHeadersOnMiss() {
+	if rsc := request.GetResources(pr.Request); rsc != nil && rsc.OriginConfig != nil &&
+		rsc.OriginConfig.ConditionalRequestPolicy == oo.ConditionalRequestPolicyStripOnMiss {
+		stripConditionalHeaders(pr.upstreamRequest.Header)
+	}
+}
+
 func (pr *proxyRequest) writeResponseHeader() {
 	headers.SetResultsHeader(pr.upstreamResponse.Header, "ObjectProxyCache", pr.cacheStatus.String(), "", nil)
+	pr.setAgeHeader()
+	if rsc := request.GetResources(pr.Request); rsc != nil && rsc.OriginConfig != nil {
+		if rsc.OriginConfig.EmitServerTiming {
+			setServerTimingHeader(pr.upstreamResponse.Header, pr.cacheLookupTime, pr.upstreamTime)
+		}
+		// a cached object is only served after a failed revalidation when it is served stale
+		if pr.revalidation == RevalStatusFailed && pr.cacheStatus == status.LookupStatusHit {
+			setStaleWarning(pr.upstreamResponse.Header, rsc.OriginConfig, true)
+		}
+	}
+}
+
+// setStaleWarning adds the Warning header to h for a response served from stale cached data,
+// including the Revalidation Failed warning when the cached data could not be revalidated
+func setStaleWarning(h http.Header, oc *oo.Options, revalidationFailed bool) {
+	if oc == nil || !oc.EmitStaleWarning {
+		return
+	}
+	// the warnings are sent as a single list, since only the first value of a header is relayed
+	if revalidationFailed {
+		h.Set(headers.NameWarning, headers.ValueWarningStale+", "+headers.ValueWarningRevalidationFailed)
+		return
+	}
+	h.Set(headers.NameWarning, headers.ValueWarningStale)
+}
+
+// setServerTimingHeader appends the cache lookup and upstream fetch durations to the Server-Timing
+// header, preserving any timings provided by the origin. Phases with no duration did not occur.
+func setServerTimingHeader(h http.Header, cacheLookup, upstream time.Duration) {
+	parts := make([]string, 0, 2)
+	if cacheLookup > 0 {
+		parts = append(parts, serverTimingMetric("cache", cacheLookup))
+	}
+	if upstream > 0 {
+		parts = append(parts, serverTimingMetric("upstream", upstream))
+	}
+	if len(parts) > 0 {
+		h.Add(headers.NameServerTiming, strings.Join(parts, ", "))
+	}
+}
+
+func serverTimingMetric(name string, d time.Duration) string {
+	return name + ";dur=" + strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// setAgeHeader sets the Age header on responses that were served (in whole or in part) from cache.
+// The age is that of the cached object when it was stored, plus the time it has resided in cache.
+// For partial hits, the cached portion is the oldest part of the response, so its age is used.
+func (pr *proxyRequest) setAgeHeader() {
+	rsc := request.GetResources(pr.Request)
+	if rsc == nil {
+		return
+	}
+	setAgeHeader(pr.upstreamResponse.Header, rsc.OriginConfig, pr.cacheStatus, pr.cachedDate)
+}
+
+// setAgeHeader sets the Age header in h for a response with the provided cache status, whose
+// cached portion was stored at cachedDate
+func setAgeHeader(h http.Header, oc *oo.Options, cacheStatus status.LookupStatus, cachedDate time.Time) {
+
+	if oc == nil || !oc.EmitAgeHeader {
+		return
+	}
+
+	switch cacheStatus {
+	case status.LookupStatusHit, status.LookupStatusPartialHit,
+		status.LookupStatusNegativeCacheHit:
+	case status.LookupStatusRevalidated:
+		// the object was just validated against the origin, so the cached
+		// age does not carry over
+		h.Set(headers.NameAge, "0")
+		return
+	default:
+		return
+	}
+
+	if cachedDate.IsZero() {
+		return
+	}
+
+	var age int64
+	if v := h.Get(headers.NameAge); v != "" {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil && i > 0 {
+			age = i
+		}
+	}
+	if d := int64(time.Since(cachedDate).Seconds()); d > 0 {
+		age += d
+	}
+	h.Set(headers.NameAge, strconv.FormatInt(age, 10))
 }
 
 func (pr *proxyRequest) setBodyWriter() {
@@ -390,11 +518,83 @@ func (pr *proxyRequest) setBodyWriter() {
 	}
 }
 
+// bufferResponse fully reads an upstream response whose declared Content-Length is within the
+// origin's streaming threshold before any of it is written downstream, so that a failed read
+// results in a 502 rather than a truncated response. Other responses remain streamed
+func (pr *proxyRequest) bufferResponse() {
+	rsc := request.GetResources(pr.Request)
+	resp := pr.upstreamResponse
+	if rsc.OriginConfig == nil || rsc.OriginConfig.StreamingThresholdBytes <= 0 ||
+		resp == nil || pr.upstreamReader == nil || resp.ContentLength < 0 ||
+		resp.ContentLength > int64(rsc.OriginConfig.StreamingThresholdBytes) {
+		return
+	}
+	b, err := ioutil.ReadAll(pr.upstreamReader)
+	if err != nil {
+		pr.Logger.Error("error reading upstream response", tl.Pairs{"cacheKey": pr.key, "detail": err.Error()})
+		resp.StatusCode = http.StatusBadGateway
+		pr.writeToCache = false
+		b = nil
+	}
+	pr.upstreamReader = bytes.NewReader(b)
+}
+
+// transformResponse applies the path's response transform, if any, to a complete upstream
+// response of an accepted Content-Type, so that the converted form is what gets cached.
+// Bodies larger than the origin's max_object_size_bytes are passed through unconverted
+func (pr *proxyRequest) transformResponse() {
+	rsc := request.GetResources(pr.Request)
+	resp := pr.upstreamResponse
+	if rsc.PathConfig == nil || rsc.PathConfig.ResponseTransformer == nil || rsc.OriginConfig == nil ||
+		resp == nil || pr.upstreamReader == nil || resp.StatusCode != http.StatusOK {
+		return
+	}
+	t := rsc.PathConfig.ResponseTransformer
+	if !t.Accepts(resp.Header.Get(headers.NameContentType)) ||
+		resp.Header.Get(headers.NameContentEncoding) != "" {
+		return
+	}
+	limit := int64(rsc.OriginConfig.MaxObjectSizeBytes)
+	if resp.ContentLength > limit {
+		return
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(pr.upstreamReader, limit+1))
+	if err != nil {
+		pr.Logger.Error("error reading upstream response", tl.Pairs{"cacheKey": pr.key, "detail": err.Error()})
+		resp.StatusCode = http.StatusBadGateway
+		pr.writeToCache = false
+		pr.upstreamReader = bytes.NewReader(nil)
+		return
+	}
+	if int64(len(b)) > limit {
+		pr.upstreamReader = io.MultiReader(bytes.NewReader(b), pr.upstreamReader)
+		return
+	}
+	out, err := t.Transform(b)
+	if err != nil {
+		// the client receives the unconverted response, which must not be cached as converted
+		pr.Logger.Warn("unable to transform upstream response",
+			tl.Pairs{"cacheKey": pr.key, "transform": t.Name, "detail": err.Error()})
+		pr.writeToCache = false
+		pr.upstreamReader = bytes.NewReader(b)
+		return
+	}
+	resp.Header.Set(headers.NameContentType, t.OutputContentType)
+	resp.Header.Set(headers.NameContentLength, strconv.Itoa(len(out)))
+	resp.ContentLength = int64(len(out))
+	pr.upstreamReader = bytes.NewReader(out)
+}
+
 func (pr *proxyRequest) writeResponseBody() {
 	if pr.upstreamReader == nil || pr.responseWriter == nil {
 		return
 	}
-	io.Copy(pr.responseWriter, pr.upstreamReader)
+	if _, err := io.Copy(pr.responseWriter, pr.upstreamReader); err != nil && pr.writeToCache {
+		// the response was interrupted, so the captured body is incomplete and must not be cached
+		pr.Logger.Error("error streaming response, object will not be cached",
+			tl.Pairs{"cacheKey": pr.key, "detail": err.Error()})
+		pr.writeToCache = false
+	}
 }
 
 func (pr *proxyRequest) determineCacheability() {
@@ -412,6 +612,11 @@ func (pr *proxyRequest) determineCacheability() {
 		return
 	}
 
+	if pr.isPartialResponse && !rsc.OriginConfig.CacheByteRanges {
+		pr.writeToCache = false
+		return
+	}
+
 	if pr.revalidation == RevalStatusLocal {
 
 		tpc := pr.cachingPolicy.Clone()
@@ -443,9 +648,56 @@ func (pr *proxyRequest) determineCacheability() {
 }
 
 func (pr *proxyRequest) store() error {
+	cc, ttl, ok := pr.prepareStore()
+	if !ok {
+		return nil
+	}
+	return WriteCache(pr.upstreamRequest.Context(), cc, pr.key, pr.cacheDocument, ttl,
+		request.GetResources(pr.Request).OriginConfig.CompressableTypes)
+}
+
+// storeAsync writes the cache document to the cache in a background goroutine, and returns false
+// if the origin has no async cache write slot available, in which case nothing is written. The
+// goroutine takes over the request's cache lock, and releases it once the write completes, so the
+// request can complete without waiting on the cache. A write in progress when the process exits is lost
+func (pr *proxyRequest) storeAsync() bool {
+	rsc := request.GetResources(pr.Request)
+	oc := rsc.OriginConfig
+	if !oc.AsyncCacheWriteSlots.TryAcquire() {
+		return false
+	}
+
+	cc, ttl, ok := pr.prepareStore()
+	if !ok {
+		oc.AsyncCacheWriteSlots.Release()
+		return true
+	}
+
+	ctx, key, d := pr.upstreamRequest.Context(), pr.key, pr.cacheDocument
+	lock, hasWriteLock, hasReadLock := pr.cacheLock, pr.hasWriteLock, pr.hasReadLock
+	pr.hasWriteLock, pr.hasReadLock = false, false
+
+	goTracked(oc, func() {
+		if err := WriteCache(ctx, cc, key, d, ttl, oc.CompressableTypes); err != nil {
+			pr.Logger.Error("error writing object to cache",
+				tl.Pairs{"originName": oc.Name, "cacheKey": key, "detail": err.Error()})
+		}
+		oc.AsyncCacheWriteSlots.Release()
+		if hasWriteLock {
+			lock.Release()
+		} else if hasReadLock {
+			lock.RRelease()
+		}
+	})
+	return true
+}
+
+// prepareStore readies the cache document to be written to the cache, and returns the cache client
+// and TTL to write it with, or false if the document should not be written
+func (pr *proxyRequest) prepareStore() (cache.Cache, time.Duration, bool) {
 
 	if !pr.writeToCache || pr.cacheDocument == nil {
-		return nil
+		return nil, 0, false
 	}
 
 	d := pr.cacheDocument
@@ -470,13 +722,18 @@ func (pr *proxyRequest) store() error {
 		rf = 1
 	}
 
-	d.CachingPolicy = pr.cachingPolicy
-	err := WriteCache(pr.upstreamRequest.Context(), rsc.CacheClient, pr.key, d,
-		pr.cachingPolicy.TTL(rf, oc.MaxTTL), oc.CompressableTypes)
-	if err != nil {
-		return err
+	cc := rsc.CacheClient
+	if pr.cachingPolicy.IsNegativeCache {
+		if nc := negativeCacheClient(rsc); nc != cc {
+			// the primary cache is queried first, so any object it holds for the key
+			// would shadow the negative cache entry until it expired
+			cc.Remove(pr.key)
+			cc = nc
+		}
 	}
-	return nil
+
+	d.CachingPolicy = pr.cachingPolicy
+	return cc, pr.cachingPolicy.TTL(rf, oc.MaxTTL), true
 }
 
 func (pr *proxyRequest) updateContentLength() {
@@ -18,14 +18,19 @@ package engines
 
 import (
 	"bytes"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
 	"github.com/tricksterproxy/trickster/pkg/cache/status"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/ranges/byterange"
@@ -82,6 +87,26 @@ func TestStripConditionalHeaders(t *testing.T) {
 	}
 }
 
+func TestStripConditionalHeadersOnMiss(t *testing.T) {
+	oc := &oo.Options{ConditionalRequestPolicy: oo.ConditionalRequestPolicyForward}
+	r, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)
+	r = request.SetResources(r, request.NewResources(oc, nil, nil, nil, nil, nil, tl.ConsoleLogger("error")))
+	r.Header.Set(headers.NameIfNoneMatch, "test")
+	r.Header.Set(headers.NameIfModifiedSince, "Wed, 21 Oct 2015 07:28:00 GMT")
+	pr := proxyRequest{Request: r, upstreamRequest: r}
+
+	pr.stripConditionalHeadersOnMiss()
+	if v := r.Header.Get(headers.NameIfNoneMatch); v != "test" {
+		t.Errorf("expected header to be forwarded: %s", headers.NameIfNoneMatch)
+	}
+
+	oc.ConditionalRequestPolicy = oo.ConditionalRequestPolicyStripOnMiss
+	pr.stripConditionalHeadersOnMiss()
+	if r.Header.Get(headers.NameIfNoneMatch) != "" || r.Header.Get(headers.NameIfModifiedSince) != "" {
+		t.Error("expected conditional headers to be stripped")
+	}
+}
+
 func TestSetBodyWriter(t *testing.T) {
 
 	buff := make([]byte, 0)
@@ -121,6 +146,72 @@ func TestWriteResponseBody(t *testing.T) {
 	}
 }
 
+func TestWriteResponseBodyInterrupted(t *testing.T) {
+
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(oo.NewOptions(), nil, nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+
+	pr := newProxyRequest(r, &bytes.Buffer{})
+	pr.writeToCache = true
+	pr.upstreamReader = iotest.TimeoutReader(strings.NewReader("partial"))
+	pr.writeResponseBody()
+	if pr.writeToCache {
+		t.Error("expected interrupted response to not be cached")
+	}
+}
+
+func TestBufferResponse(t *testing.T) {
+
+	oc := oo.NewOptions()
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(oc, nil, nil, nil, nil, nil, tl.ConsoleLogger("error"))))
+
+	newPR := func(cl int64) *proxyRequest {
+		pr := newProxyRequest(r, nil)
+		pr.writeToCache = true
+		pr.upstreamResponse = &http.Response{StatusCode: http.StatusOK, ContentLength: cl}
+		pr.upstreamReader = iotest.TimeoutReader(strings.NewReader("partial"))
+		return pr
+	}
+
+	// streaming threshold is disabled, so the response remains streamed
+	pr := newPR(10)
+	pr.bufferResponse()
+	if _, ok := pr.upstreamReader.(*bytes.Reader); ok {
+		t.Error("expected response to be streamed")
+	}
+
+	oc.StreamingThresholdBytes = 5
+
+	// the declared length exceeds the threshold, so the response remains streamed
+	pr = newPR(10)
+	pr.bufferResponse()
+	if _, ok := pr.upstreamReader.(*bytes.Reader); ok {
+		t.Error("expected response to be streamed")
+	}
+
+	// the response is buffered, and fails mid-read
+	pr = newPR(4)
+	pr.bufferResponse()
+	if pr.upstreamResponse.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected %d got %d", http.StatusBadGateway, pr.upstreamResponse.StatusCode)
+	}
+	if pr.writeToCache {
+		t.Error("expected failed response to not be cached")
+	}
+
+	// the response is buffered successfully
+	pr = newPR(4)
+	pr.upstreamReader = strings.NewReader("test")
+	pr.bufferResponse()
+	b, _ := ioutil.ReadAll(pr.upstreamReader)
+	if string(b) != "test" || pr.upstreamResponse.StatusCode != http.StatusOK {
+		t.Errorf("expected %s got %s", "test", string(b))
+	}
+}
+
 func TestDetermineCacheability(t *testing.T) {
 
 	conf, _, err := config.Load("trickster", "test",
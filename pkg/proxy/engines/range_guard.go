@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+// defaultRangeGuardResponseBody is the message returned to requests exceeding an origin's
+// MaxQueryRange when the origin does not provide a RangeGuardResponseBody
+const defaultRangeGuardResponseBody = "requested range of {requested_range} exceeds the maximum " +
+	"range of {max_range} allowed for this origin"
+
+// rangeGuardError is the JSON representation of a range guard response, which follows the
+// error format of the Prometheus HTTP API so that clients like Grafana display the message
+type rangeGuardError struct {
+	Status             string `json:"status"`
+	ErrorType          string `json:"errorType"`
+	Error              string `json:"error"`
+	MaxRangeSecs       int64  `json:"maxRangeSecs"`
+	RequestedRangeSecs int64  `json:"requestedRangeSecs"`
+}
+
+// exceedsMaxQueryRange returns true if the requested range is longer than the origin allows
+func exceedsMaxQueryRange(requested time.Duration, oc *oo.Options) bool {
+	return oc.MaxQueryRange > 0 && requested > oc.MaxQueryRange
+}
+
+// rangeGuardResponse returns the headers and body of the response to a request whose
+// requested range exceeds the origin's MaxQueryRange, per its RangeGuardResponseFormat
+func rangeGuardResponse(requested time.Duration, oc *oo.Options) (http.Header, []byte) {
+	msg := oc.RangeGuardResponseBody
+	if msg == "" {
+		msg = defaultRangeGuardResponseBody
+	}
+	msg = strings.NewReplacer("{max_range}", formatRange(oc.MaxQueryRange),
+		"{requested_range}", formatRange(requested)).Replace(msg)
+
+	if oc.RangeGuardResponseFormat != oo.RangeGuardResponseFormatJSON {
+		return http.Header{headers.NameContentType: []string{headers.ValueTextPlain}}, []byte(msg)
+	}
+	b, _ := json.Marshal(&rangeGuardError{
+		Status:             "error",
+		ErrorType:          "bad_data",
+		Error:              msg,
+		MaxRangeSecs:       int64(oc.MaxQueryRange.Seconds()),
+		RequestedRangeSecs: int64(requested.Seconds()),
+	})
+	return http.Header{headers.NameContentType: []string{headers.ValueApplicationJSON}}, b
+}
+
+// formatRange returns d in the largest whole unit of days, hours, minutes or seconds
+// (e.g., "7d" or "90m"), falling back to seconds for ranges with a fractional unit
+func formatRange(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	day := 24 * time.Hour
+	switch {
+	case d >= day && d%day == 0:
+		return strconv.FormatInt(int64(d/day), 10) + "d"
+	case d >= time.Hour && d%time.Hour == 0:
+		return strconv.FormatInt(int64(d/time.Hour), 10) + "h"
+	case d >= time.Minute && d%time.Minute == 0:
+		return strconv.FormatInt(int64(d/time.Minute), 10) + "m"
+	}
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+func TestExceedsMaxQueryRange(t *testing.T) {
+	oc := oo.NewOptions()
+	if exceedsMaxQueryRange(time.Hour*24*365, oc) {
+		t.Error("expected false")
+	}
+	oc.MaxQueryRange = time.Hour * 24
+	if exceedsMaxQueryRange(time.Hour*24, oc) {
+		t.Error("expected false")
+	}
+	if !exceedsMaxQueryRange(time.Hour*25, oc) {
+		t.Error("expected true")
+	}
+}
+
+func TestRangeGuardResponse(t *testing.T) {
+
+	oc := oo.NewOptions()
+	oc.MaxQueryRange = time.Hour * 24
+
+	h, b := rangeGuardResponse(time.Hour*24*7, oc)
+	if v := h.Get(headers.NameContentType); v != headers.ValueTextPlain {
+		t.Errorf("expected %s got %s", headers.ValueTextPlain, v)
+	}
+	expected := "requested range of 7d exceeds the maximum range of 1d allowed for this origin"
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, string(b))
+	}
+
+	oc.RangeGuardResponseBody = "queries are limited to {max_range} (requested {requested_range})"
+	_, b = rangeGuardResponse(time.Minute*90, oc)
+	expected = "queries are limited to 1d (requested 90m)"
+	if string(b) != expected {
+		t.Errorf("expected %s got %s", expected, string(b))
+	}
+
+	oc.RangeGuardResponseFormat = oo.RangeGuardResponseFormatJSON
+	h, b = rangeGuardResponse(time.Hour*36, oc)
+	if v := h.Get(headers.NameContentType); v != headers.ValueApplicationJSON {
+		t.Errorf("expected %s got %s", headers.ValueApplicationJSON, v)
+	}
+	e := &rangeGuardError{}
+	if err := json.Unmarshal(b, e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Status != "error" || e.ErrorType != "bad_data" {
+		t.Errorf("unexpected status %s %s", e.Status, e.ErrorType)
+	}
+	expected = "queries are limited to 1d (requested 36h)"
+	if e.Error != expected {
+		t.Errorf("expected %s got %s", expected, e.Error)
+	}
+	if e.MaxRangeSecs != 86400 || e.RequestedRangeSecs != 129600 {
+		t.Errorf("expected %d %d got %d %d", 86400, 129600, e.MaxRangeSecs, e.RequestedRangeSecs)
+	}
+}
+
+func TestFormatRange(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{time.Hour * 48, "2d"},
+		{time.Hour * 25, "25h"},
+		{time.Minute * 61, "61m"},
+		{time.Second * 61, "61s"},
+		{time.Millisecond * 1500, "1s"},
+		{0, "0s"},
+	}
+	for _, test := range tests {
+		if v := formatRange(test.d); v != test.expected {
+			t.Errorf("expected %s got %s", test.expected, v)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/signing"
+	"github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// isRetryableMethod returns true if upstream requests using the method may be retried
+func isRetryableMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// isRetryableStatus returns true if the upstream response status code is in the origin's
+// list of retryable status codes
+func isRetryableStatus(code int, oc *oo.Options) bool {
+	for _, c := range oc.RetryStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff returns the wait before the numbered retry (starting at 1), doubling the
+// origin's initial backoff for each earlier retry, up to its maximum backoff
+func retryBackoff(attempt int, oc *oo.Options) time.Duration {
+	b := oc.RetryInitialBackoff
+	for i := 1; i < attempt && b < oc.RetryMaxBackoff; i++ {
+		b *= 2
+	}
+	if b > oc.RetryMaxBackoff {
+		b = oc.RetryMaxBackoff
+	}
+	return b
+}
+
+// retryRequest returns a copy of r for a retry attempt, with its body reset, or nil if the
+// body cannot be reset
+func retryRequest(r *http.Request) *http.Request {
+	if r.Body == nil || r.Body == http.NoBody {
+		return r.Clone(r.Context())
+	}
+	if r.GetBody == nil {
+		return nil
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return nil
+	}
+	r2 := r.Clone(r.Context())
+	r2.Body = body
+	return r2
+}
+
+// poolRequest returns a shallow copy of r, which was prepared for the origin's first replica URL,
+// that is addressed to the replica selected by the origin's load balancing pool, and re-signed
+// for that replica when the origin signs its requests
+func poolRequest(r *http.Request, oc *oo.Options) *http.Request {
+	b := oc.Pool.Next()
+	if b == nil {
+		return r
+	}
+	r2 := r.WithContext(r.Context())
+	u := *r.URL
+	rebaseURL(&u, oc.PathPrefix, b.URL.Scheme, b.URL.Host, b.URL.Path)
+	r2.URL = &u
+	if oc.RequestSigning != nil {
+		// the signature headers are set on a copy, so they don't leak into later attempts
+		r2.Header = r.Header.Clone()
+		signing.SignRequest(r2, oc.RequestSigning, time.Now())
+	}
+	return r2
+}
+
+// cancelOnClose calls the cancel func of a retry attempt's context when the response body
+// is closed, so the attempt's deadline remains in effect while the body is read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// releaseOnClose releases an upstream request slot when the response body is read to its end or
+// closed, so the request counts against the origin's concurrency limit until its response is read
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (c *releaseOnClose) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if err != nil {
+		c.once.Do(c.release)
+	}
+	return n, err
+}
+
+func (c *releaseOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.once.Do(c.release)
+	return err
+}
+
+// doRequest sends a single request to the origin, or to the replica selected by its load
+// balancing pool. When the origin is configured with max_concurrent_upstream_requests, the
+// request first waits up to the origin's timeout for a slot, and holds it until the response
+// body is closed; if no slot becomes available, ErrUpstreamConcurrencyLimit is returned
+func doRequest(r *http.Request, oc *oo.Options) (*http.Response, error) {
+	s := oc.UpstreamRequestSlots
+	if s == nil {
+		return oc.HTTPClient.Do(poolRequest(r, oc))
+	}
+
+	ctx := r.Context()
+	if oc.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, oc.Timeout)
+		defer cancel()
+	}
+	if !s.Acquire(ctx) {
+		if err := r.Context().Err(); err != nil {
+			return nil, err
+		}
+		metrics.ProxyUpstreamRequestsRejected.WithLabelValues(oc.Name, oc.OriginType).Inc()
+		return nil, errors.ErrUpstreamConcurrencyLimit
+	}
+	inFlight := metrics.ProxyUpstreamRequestsInFlight.WithLabelValues(oc.Name, oc.OriginType)
+	inFlight.Inc()
+	release := func() {
+		s.Release()
+		inFlight.Dec()
+	}
+
+	resp, err := oc.HTTPClient.Do(poolRequest(r, oc))
+	if err != nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, err
+}
+
+// discardResponse drains and closes the body of a response that will be retried, so that
+// none of it is forwarded downstream and the connection can be reused
+func discardResponse(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+}
+
+// doWithRetry sends r to the origin per doRequest, retrying idempotent requests that fail with
+// a connection error or a retryable response status code, per the origin's retry options.
+// Retries happen before any part of the response is returned, wait an exponentially increasing
+// backoff, and stop once the next attempt could not begin within the origin's timeout_secs budget
+func doWithRetry(r *http.Request, oc *oo.Options, logger *log.Logger) (*http.Response, error) {
+
+	start := time.Now()
+	resp, err := doRequest(r, oc)
+	if oc.RetryMaxAttempts < 1 || !isRetryableMethod(r.Method) {
+		return resp, err
+	}
+
+	ctx := r.Context()
+	for attempt := 1; attempt <= oc.RetryMaxAttempts; attempt++ {
+		if err == nil && !isRetryableStatus(resp.StatusCode, oc) {
+			return resp, err
+		}
+		if ctx.Err() != nil {
+			// the downstream client has gone away
+			return resp, err
+		}
+		backoff := retryBackoff(attempt, oc)
+		remaining := oc.Timeout - time.Since(start) - backoff
+		if oc.Timeout > 0 && remaining <= 0 {
+			return resp, err
+		}
+		r2 := retryRequest(r)
+		if r2 == nil {
+			return resp, err
+		}
+
+		pairs := log.Pairs{"originName": oc.Name, "url": r.URL.String(),
+			"attempt": attempt, "backoffMS": backoff.Milliseconds()}
+		if err != nil {
+			pairs["detail"] = err.Error()
+		} else {
+			pairs["statusCode"] = resp.StatusCode
+		}
+		logger.Debug("retrying upstream request", pairs)
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return resp, err
+		case <-t.C:
+		}
+
+		discardResponse(resp)
+		metrics.ProxyUpstreamRetries.WithLabelValues(oc.Name, oc.OriginType).Inc()
+
+		var cancel context.CancelFunc = func() {}
+		if oc.Timeout > 0 {
+			var actx context.Context
+			actx, cancel = context.WithTimeout(ctx, remaining)
+			r2 = r2.WithContext(actx)
+		}
+		resp, err = doRequest(r2, oc)
+		if err != nil || resp.Body == nil {
+			cancel()
+		} else {
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+		}
+	}
+	return resp, err
+}
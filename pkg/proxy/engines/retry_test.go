@@ -0,0 +1,292 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/pool"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	so "github.com/tricksterproxy/trickster/pkg/proxy/signing/options"
+	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+)
+
+// newRetryTestServer returns a server that responds with failCode to the first failures
+// requests it receives, and with 200 OK thereafter
+func newRetryTestServer(failures int32, failCode int) (*httptest.Server, *int32) {
+	var count int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) <= failures {
+			w.WriteHeader(failCode)
+			w.Write([]byte("failed"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	return s, &count
+}
+
+func retryTestRequest(t *testing.T, method, url string, attempts int) *http.Request {
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", url, "-origin-type", "test", "-log-level", "debug"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	oc := conf.Origins["default"]
+	oc.HTTPClient = http.DefaultClient
+	oc.RetryMaxAttempts = attempts
+	oc.RetryInitialBackoff = time.Millisecond
+	oc.RetryMaxBackoff = 4 * time.Millisecond
+	pc := &po.Options{
+		Path:            "/",
+		RequestHeaders:  map[string]string{},
+		ResponseHeaders: map[string]string{},
+	}
+	r, _ := http.NewRequest(method, url, nil)
+	return r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(oc, pc, nil, nil, nil, tu.NewTestTracer(), testLogger)))
+}
+
+func TestDoProxyRetry(t *testing.T) {
+
+	s, count := newRetryTestServer(2, http.StatusServiceUnavailable)
+	defer s.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s.URL, 3)
+	w := httptest.NewRecorder()
+	DoProxy(w, r, true)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	if string(b) != "ok" {
+		t.Errorf("expected %s got %s", "ok", string(b))
+	}
+	if *count != 3 {
+		t.Errorf("expected %d got %d", 3, *count)
+	}
+}
+
+func TestDoProxyRetryExhausted(t *testing.T) {
+
+	s, count := newRetryTestServer(5, http.StatusBadGateway)
+	defer s.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s.URL, 2)
+	w := httptest.NewRecorder()
+	DoProxy(w, r, true)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected %d got %d", http.StatusBadGateway, resp.StatusCode)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	if string(b) != "failed" {
+		t.Errorf("expected %s got %s", "failed", string(b))
+	}
+	if *count != 3 {
+		t.Errorf("expected %d got %d", 3, *count)
+	}
+}
+
+func TestDoProxyRetryNonRetryable(t *testing.T) {
+
+	// POST requests and non-retryable status codes are not retried
+	s, count := newRetryTestServer(1, http.StatusServiceUnavailable)
+	defer s.Close()
+
+	r := retryTestRequest(t, http.MethodPost, s.URL, 3)
+	DoProxy(httptest.NewRecorder(), r, true)
+	if *count != 1 {
+		t.Errorf("expected %d got %d", 1, *count)
+	}
+
+	s2, count2 := newRetryTestServer(1, http.StatusInternalServerError)
+	defer s2.Close()
+
+	r = retryTestRequest(t, http.MethodGet, s2.URL, 3)
+	DoProxy(httptest.NewRecorder(), r, true)
+	if *count2 != 1 {
+		t.Errorf("expected %d got %d", 1, *count2)
+	}
+}
+
+func TestDoProxyRetryTimeoutBudget(t *testing.T) {
+
+	s, count := newRetryTestServer(5, http.StatusServiceUnavailable)
+	defer s.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s.URL, 5)
+	oc := request.GetResources(r).OriginConfig
+	oc.Timeout = 50 * time.Millisecond
+	oc.RetryInitialBackoff = 40 * time.Millisecond
+	oc.RetryMaxBackoff = 40 * time.Millisecond
+
+	w := httptest.NewRecorder()
+	DoProxy(w, r, true)
+	if w.Result().StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Result().StatusCode)
+	}
+	// the second retry would begin after the timeout budget, so only one is made
+	if *count != 2 {
+		t.Errorf("expected %d got %d", 2, *count)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+
+	r := retryTestRequest(t, http.MethodGet, "http://0/", 5)
+	oc := request.GetResources(r).OriginConfig
+	oc.RetryInitialBackoff = 100 * time.Millisecond
+	oc.RetryMaxBackoff = 500 * time.Millisecond
+
+	expected := []time.Duration{100, 200, 400, 500, 500}
+	for i, v := range expected {
+		if b := retryBackoff(i+1, oc); b != v*time.Millisecond {
+			t.Errorf("expected %d got %d", v*time.Millisecond, b)
+		}
+	}
+}
+
+func TestDoProxyPool(t *testing.T) {
+
+	s1, count1 := newRetryTestServer(100, http.StatusServiceUnavailable)
+	defer s1.Close()
+	s2, count2 := newRetryTestServer(0, http.StatusOK)
+	defer s2.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s1.URL, 1)
+	oc := request.GetResources(r).OriginConfig
+	u1, _ := url.Parse(s1.URL)
+	u2, _ := url.Parse(s2.URL)
+	oc.Pool = pool.New([]*url.URL{u1, u2}, pool.RoundRobin)
+
+	// each attempt is sent to the next replica, so each request fails on the first
+	// replica and is retried on the second
+	for i := 0; i < 4; i++ {
+		w := httptest.NewRecorder()
+		DoProxy(w, r, true)
+		if w.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected %d got %d", http.StatusOK, w.Result().StatusCode)
+		}
+	}
+	if *count1 != 4 || *count2 != 4 {
+		t.Errorf("expected [4 4] got [%d %d]", *count1, *count2)
+	}
+}
+
+func TestDoProxyPoolSigning(t *testing.T) {
+
+	sig := so.NewOptions()
+	sig.Secret = "trickster"
+	sig.SignedElements = append(sig.SignedElements, so.ElementHost)
+	if err := sig.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// each replica verifies that the request it receives is signed for its own host
+	var mismatches, requests int32
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		mac := hmac.New(sha256.New, []byte("trickster"))
+		mac.Write([]byte(strings.Join([]string{r.Method, r.URL.Path,
+			r.Header.Get(sig.TimestampHeaderName), r.Host}, "\n")))
+		if r.Header.Get(sig.HeaderName) != hex.EncodeToString(mac.Sum(nil)) {
+			atomic.AddInt32(&mismatches, 1)
+		}
+		w.Write([]byte("ok"))
+	})
+	s1 := httptest.NewServer(h)
+	defer s1.Close()
+	s2 := httptest.NewServer(h)
+	defer s2.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s1.URL+"/", 0)
+	oc := request.GetResources(r).OriginConfig
+	oc.RequestSigning = sig
+	u1, _ := url.Parse(s1.URL)
+	u2, _ := url.Parse(s2.URL)
+	oc.Pool = pool.New([]*url.URL{u1, u2}, pool.RoundRobin)
+
+	for i := 0; i < 4; i++ {
+		w := httptest.NewRecorder()
+		DoProxy(w, r, true)
+		if w.Result().StatusCode != http.StatusOK {
+			t.Errorf("expected %d got %d", http.StatusOK, w.Result().StatusCode)
+		}
+	}
+	if requests != 4 || mismatches != 0 {
+		t.Errorf("expected 4 requests with 0 signature mismatches got %d with %d",
+			requests, mismatches)
+	}
+}
+
+func TestDoProxyUpstreamConcurrencyLimit(t *testing.T) {
+
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	r := retryTestRequest(t, http.MethodGet, s.URL, 0)
+	oc := request.GetResources(r).OriginConfig
+	oc.Timeout = 100 * time.Millisecond
+	oc.UpstreamRequestSlots = oo.NewSlots(1)
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		DoProxy(w, r.Clone(r.Context()), true)
+		done <- w.Result().StatusCode
+	}()
+	for oc.UpstreamRequestSlots.InUse() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the only slot is held by the first request, so this one times out waiting for it
+	w := httptest.NewRecorder()
+	DoProxy(w, r, true)
+	if w.Result().StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, w.Result().StatusCode)
+	}
+
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, code)
+	}
+	if n := oc.UpstreamRequestSlots.InUse(); n != 0 {
+		t.Errorf("expected %d got %d", 0, n)
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// isUpgradeRequest returns true if r asks to upgrade its connection to another protocol, on
+// an upgradeable path, and the client connection can be handed over to the upstream
+func isUpgradeRequest(w io.Writer, r *http.Request) bool {
+	pc := request.GetResources(r).PathConfig
+	if pc == nil || !pc.Upgradeable || !headers.IsUpgrade(r.Header) {
+		return false
+	}
+	_, ok := w.(http.Hijacker)
+	return ok
+}
+
+// doUpgradeProxy sends the upgrade request r to the origin and, if the origin switches protocols,
+// tunnels the client connection to the upstream connection until either side closes it. The
+// origin's timeout applies only to the handshake, and nothing is cached
+func doUpgradeProxy(w io.Writer, r *http.Request) *http.Response {
+
+	rsc := request.GetResources(r)
+	oc := rsc.OriginConfig
+	pc := rsc.PathConfig
+
+	start := time.Now()
+
+	r = r.Clone(r.Context())
+	up := r.Header.Get(headers.NameUpgrade)
+	if oc.StripHopByHopHeaders {
+		headers.StripHopByHopHeaders(r.Header)
+	}
+	// the forwarding headers replace the client's hop-by-hop headers, so the upgrade is restored
+	headers.AddForwardingHeaders(r, oc.ForwardedHeaders)
+	r.Header.Set(headers.NameConnection, "Upgrade")
+	r.Header.Set(headers.NameUpgrade, up)
+	headers.UpdateHeaders(r.Header, pc.RequestHeaders)
+	r.RequestURI = ""
+	r.Host = ""
+
+	ctx := r.Context()
+	if oc.Timeout > 0 {
+		// canceling the context once the handshake completes does not close the upgraded connection
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, oc.Timeout)
+		defer cancel()
+	}
+
+	// the client's Timeout would otherwise apply for the lifetime of the upgraded connection
+	var rt http.RoundTripper = http.DefaultTransport
+	if oc.HTTPClient != nil && oc.HTTPClient.Transport != nil {
+		rt = oc.HTTPClient.Transport
+	}
+
+	resp, err := rt.RoundTrip(poolRequest(r, oc).WithContext(ctx))
+	if err != nil {
+		rsc.Logger.Error("error upgrading connection to origin",
+			log.Pairs{"url": r.URL.String(), "detail": err.Error()})
+		code := http.StatusBadGateway
+		if isTimeout(err) {
+			code = oc.TimeoutResponseCode
+		}
+		resp = &http.Response{StatusCode: code, Request: r, Header: make(http.Header)}
+		recordResults(r, "HTTPProxy", status.LookupStatusProxyError, resp.StatusCode,
+			r.URL.Path, "", time.Since(start).Seconds(), nil, resp.Header)
+		PrepareResponseWriter(w, resp.StatusCode, resp.Header)
+		return resp
+	}
+
+	headers.UpdateHeaders(resp.Header, pc.ResponseHeaders)
+
+	upstream, ok := resp.Body.(io.ReadWriteCloser)
+	if resp.StatusCode != http.StatusSwitchingProtocols || !ok {
+		// the origin declined the upgrade, so its response is passed through as usual
+		defer resp.Body.Close()
+		cacheStatusCode := setStatusHeader(resp.StatusCode, resp.Header)
+		if writer := PrepareResponseWriter(w, resp.StatusCode, resp.Header); writer != nil {
+			io.Copy(writer, resp.Body)
+		}
+		recordResults(r, "HTTPProxy", cacheStatusCode, resp.StatusCode,
+			r.URL.Path, "", time.Since(start).Seconds(), nil, resp.Header)
+		return resp
+	}
+	defer upstream.Close()
+
+	recordResults(r, "HTTPProxy", status.LookupStatusProxyOnly, resp.StatusCode,
+		r.URL.Path, "", time.Since(start).Seconds(), nil, resp.Header)
+
+	conn, brw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		rsc.Logger.Error("error hijacking client connection for upgrade",
+			log.Pairs{"url": r.URL.String(), "detail": err.Error()})
+		return resp
+	}
+	defer conn.Close()
+	// clear any deadlines set by the server for the request
+	conn.SetDeadline(time.Time{})
+
+	fmt.Fprintf(brw, "HTTP/1.1 %s\r\n", resp.Status)
+	resp.Header.Write(brw)
+	brw.WriteString("\r\n")
+	if err := brw.Flush(); err != nil {
+		return resp
+	}
+
+	g := metrics.ProxyUpgradedConnections.WithLabelValues(oc.Name, oc.OriginType)
+	g.Inc()
+	defer g.Dec()
+
+	// any bytes sent by the client after its request are buffered in brw. When either direction
+	// ends, both connections are closed, which ends the other
+	done := make(chan struct{}, 2)
+	goTracked(oc, func() {
+		io.Copy(upstream, brw)
+		done <- struct{}{}
+	})
+	goTracked(oc, func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	})
+	<-done
+	conn.Close()
+	upstream.Close()
+	<-done
+
+	return resp
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+)
+
+// newUpgradeTestServers returns an origin server that echoes the bytes sent over upgraded
+// connections, and a Trickster server that proxies to it with the provided path config
+func newUpgradeTestServers(t *testing.T, pc *po.Options) (*httptest.Server, *httptest.Server) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !headers.IsUpgrade(r.Header) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("not upgraded"))
+			return
+		}
+		conn, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " +
+			r.Header.Get(headers.NameUpgrade) + "\r\n\r\n")
+		brw.Flush()
+		io.Copy(conn, brw)
+	}))
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-origin-url", es.URL, "-origin-type", "test", "-log-level", "debug"})
+	if err != nil {
+		es.Close()
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	oc := conf.Origins["default"]
+	oc.HTTPClient = http.DefaultClient
+	oc.Timeout = 50 * time.Millisecond
+	u, _ := url.Parse(es.URL)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(tc.WithResources(r.Context(),
+			request.NewResources(oc, pc, nil, nil, nil, nil, testLogger)))
+		r.URL = urls.BuildUpstreamURL(r, u)
+		DoProxy(w, r, true)
+	}))
+
+	return es, ts
+}
+
+func TestDoUpgradeProxy(t *testing.T) {
+
+	pc := po.NewOptions()
+	pc.Upgradeable = true
+	es, ts := newUpgradeTestServers(t, pc)
+	defer es.Close()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	conn.Write([]byte("GET /stream HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\n" +
+		"Upgrade: websocket\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected %d got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+	if v := resp.Header.Get(headers.NameUpgrade); v != "websocket" {
+		t.Errorf("expected %s got %s", "websocket", v)
+	}
+
+	// the upgraded connection must outlive the origin's timeout
+	time.Sleep(100 * time.Millisecond)
+
+	for _, msg := range []string{"hello\n", "world\n"} {
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		l, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l != msg {
+			t.Errorf("expected %q got %q", msg, l)
+		}
+	}
+}
+
+func TestDoUpgradeProxyNotUpgradeable(t *testing.T) {
+
+	es, ts := newUpgradeTestServers(t, po.NewOptions())
+	defer es.Close()
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/stream", nil)
+	req.Header.Set(headers.NameConnection, "Upgrade")
+	req.Header.Set(headers.NameUpgrade, "websocket")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// without upgradeable, the request is proxied as usual
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDoUpgradeProxyOriginDown(t *testing.T) {
+
+	pc := po.NewOptions()
+	pc.Upgradeable = true
+	es, ts := newUpgradeTestServers(t, pc)
+	es.Close()
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/stream", nil)
+	req.Header.Set(headers.NameConnection, "Upgrade")
+	req.Header.Set(headers.NameUpgrade, "websocket")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusBadGateway)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (w *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
+
+func TestIsUpgradeRequest(t *testing.T) {
+
+	pc := po.NewOptions()
+	pc.Upgradeable = true
+	r := httptest.NewRequest(http.MethodGet, "http://127.0.0.1/", nil)
+	r.Header.Set(headers.NameConnection, "Upgrade")
+	r.Header.Set(headers.NameUpgrade, "websocket")
+	r = r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(nil, pc, nil, nil, nil, nil, testLogger)))
+
+	// a ResponseRecorder cannot be hijacked
+	if isUpgradeRequest(httptest.NewRecorder(), r) {
+		t.Error("expected false")
+	}
+	if !isUpgradeRequest(&hijackableRecorder{httptest.NewRecorder()}, r) {
+		t.Error("expected true")
+	}
+}
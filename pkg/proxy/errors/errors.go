@@ -66,6 +66,17 @@ var ErrDrainTimeout = errors.New("timed out draining")
 // ErrPCFContentLength indicates that a response's content length does not permit PCF
 var ErrPCFContentLength = errors.New("content length does not permit PCF")
 
+// ErrCircuitOpen indicates that an upstream request was not attempted because the origin's
+// circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// ErrUpstreamConcurrencyLimit indicates that an upstream request was not attempted because no
+// slot under the origin's max_concurrent_upstream_requests became available within its timeout
+var ErrUpstreamConcurrencyLimit = errors.New("upstream concurrency limit exceeded")
+
+// ErrRequestBodyTooLarge indicates that a request body exceeds the size the origin will buffer
+var ErrRequestBodyTooLarge = errors.New("request body too large")
+
 // MissingURLParam returns a Formatted Error
 func MissingURLParam(param string) error {
 	return fmt.Errorf("missing URL parameter: [%s]", param)
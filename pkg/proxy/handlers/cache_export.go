@@ -0,0 +1,203 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// CacheRecord is a cached object in a cache export, which is a stream of JSON-encoded
+// CacheRecords, one per line. The Value is the object as stored in the cache, so records
+// are portable between cache types
+type CacheRecord struct {
+	Key        string    `json:"key"`
+	Expiration time.Time `json:"expiration"`
+	Value      []byte    `json:"value"`
+}
+
+// CacheImportResult summarizes the records processed by a cache import
+type CacheImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"`
+	Failed   int `json:"failed"`
+}
+
+// CacheExportHandleFunc streams the contents of the cache named by the 'cache' query parameter
+// to the client as CacheRecords. The cache's index is copied when the export starts, and objects
+// are read from the cache as they are written, so that the export does not block other requests.
+// Objects that expire or are removed before they are read are not exported.
+func CacheExportHandleFunc(conf *config.Config,
+	caches map[string]cache.Cache) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		if !isAdminAuthorized(conf, r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		c, err := requestedCache(r, caches)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		ic, ok := c.(cache.IndexedCache)
+		if !ok {
+			http.Error(w, fmt.Sprintf("cache type [%s] does not support export",
+				c.Configuration().CacheType), http.StatusNotImplemented)
+			return
+		}
+
+		exps := ic.ObjectExpirations()
+		keys := make([]string, 0, len(exps))
+		for k := range exps {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationNDJSON)
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		f, _ := w.(http.Flusher)
+		for _, k := range keys {
+			if time.Now().After(exps[k]) {
+				continue
+			}
+			b, ls, err := c.Retrieve(k, false)
+			if err != nil || ls != status.LookupStatusHit {
+				continue
+			}
+			if err = enc.Encode(&CacheRecord{Key: k, Expiration: exps[k], Value: b}); err != nil {
+				return
+			}
+			if f != nil {
+				f.Flush()
+			}
+		}
+	}
+}
+
+// CacheImportHandleFunc stores the CacheRecords in the request body in the cache named by the
+// 'cache' query parameter, with the TTL remaining until their expiration. Expired records are
+// skipped. The response is the CacheImportResult, which is also returned, along with the error,
+// when the request body is not a valid cache export
+func CacheImportHandleFunc(conf *config.Config,
+	caches map[string]cache.Cache) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		if !isAdminAuthorized(conf, r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		c, err := requestedCache(r, caches)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		// the memory cache serves objects stored by reference, rather than serialized objects
+		if c.Configuration().CacheType == "memory" {
+			http.Error(w, "cache type [memory] does not support import", http.StatusNotImplemented)
+			return
+		}
+
+		res := &CacheImportResult{}
+		dec := json.NewDecoder(r.Body)
+		code := http.StatusOK
+		var derr error
+		for {
+			rec := &CacheRecord{}
+			if derr = dec.Decode(rec); derr == io.EOF {
+				derr = nil
+				break
+			}
+			if derr == nil && rec.Key == "" {
+				derr = fmt.Errorf("record %d has no key", res.Imported+res.Skipped+res.Failed+1)
+			}
+			if derr != nil {
+				code = http.StatusBadRequest
+				break
+			}
+			ttl := time.Until(rec.Expiration)
+			if ttl <= 0 {
+				res.Skipped++
+				continue
+			}
+			if err := c.Store(rec.Key, rec.Value, ttl); err != nil {
+				res.Failed++
+				continue
+			}
+			res.Imported++
+		}
+
+		out := struct {
+			*CacheImportResult
+			Error string `json:"error,omitempty"`
+		}{CacheImportResult: res}
+		if derr != nil {
+			out.Error = derr.Error()
+		}
+		b, _ := json.Marshal(out)
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		w.WriteHeader(code)
+		w.Write(b)
+	}
+}
+
+// requestedCache returns the cache named by the request's 'cache' query parameter
+func requestedCache(r *http.Request, caches map[string]cache.Cache) (cache.Cache, error) {
+	name := r.URL.Query().Get("cache")
+	if name == "" {
+		name = "default"
+	}
+	c, ok := caches[name]
+	if !ok || c == nil {
+		return nil, fmt.Errorf("cache [%s] not found", name)
+	}
+	return c, nil
+}
+
+// isAdminAuthorized returns true if the request provides the configured admin auth token
+// as a bearer token. No request is authorized when an admin auth token is not configured
+func isAdminAuthorized(conf *config.Config, r *http.Request) bool {
+	if conf == nil || conf.Main == nil || conf.Main.AdminAuthToken == "" {
+		return false
+	}
+	const prefix = "Bearer "
+	v := r.Header.Get(headers.NameAuthorization)
+	if !strings.HasPrefix(v, prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(v[len(prefix):]), []byte(conf.Main.AdminAuthToken)) == 1
+}
@@ -0,0 +1,200 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	co "github.com/tricksterproxy/trickster/pkg/cache/options"
+	"github.com/tricksterproxy/trickster/pkg/cache/registration"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+func newTestFilesystemCache(t *testing.T, name string) cache.Cache {
+	dir, err := ioutil.TempDir("", "trickster-export-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	o := co.NewOptions()
+	o.Name = name
+	o.CacheType = "filesystem"
+	o.Filesystem.CachePath = dir
+	return registration.NewCache(name, o, tl.ConsoleLogger("error"))
+}
+
+func TestCacheExportImport(t *testing.T) {
+
+	conf, _, err := config.Load("trickster-test", "test",
+		[]string{"-origin-url", "http://1.2.3.4", "-origin-type", "prometheus"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	conf.Main.AdminAuthToken = "test-token"
+
+	src := newTestFilesystemCache(t, "src")
+	dst := newTestFilesystemCache(t, "dst")
+	defer src.Close()
+	defer dst.Close()
+	caches := map[string]cache.Cache{"src": src, "dst": dst}
+
+	src.Store("key1", []byte("value1"), time.Hour)
+	src.Store("key2", []byte("value2"), time.Minute)
+
+	export := CacheExportHandleFunc(conf, caches)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://0/trickster/cache/export?cache=src", nil)
+	r.Header.Set(headers.NameAuthorization, "Bearer test-token")
+	export(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if v := w.Header().Get(headers.NameContentType); v != headers.ValueApplicationNDJSON {
+		t.Errorf("expected %s got %s", headers.ValueApplicationNDJSON, v)
+	}
+	var recs []*CacheRecord
+	s := bufio.NewScanner(bytes.NewReader(w.Body.Bytes()))
+	for s.Scan() {
+		rec := &CacheRecord{}
+		if err := json.Unmarshal(s.Bytes(), rec); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 2 || recs[0].Key != "key1" || string(recs[0].Value) != "value1" {
+		t.Fatalf("unexpected export %s", w.Body.String())
+	}
+
+	// an expired record is skipped on import
+	body := w.Body.String() + `{"key":"key3","expiration":"2000-01-01T00:00:00Z","value":"dmFsdWUz"}` + "\n"
+	imp := CacheImportHandleFunc(conf, caches)
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "http://0/trickster/cache/import?cache=dst",
+		strings.NewReader(body))
+	r.Header.Set(headers.NameAuthorization, "Bearer test-token")
+	imp(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	res := &CacheImportResult{}
+	json.Unmarshal(w.Body.Bytes(), res)
+	if res.Imported != 2 || res.Skipped != 1 || res.Failed != 0 {
+		t.Errorf("unexpected import result %s", w.Body.String())
+	}
+	b, _, err := dst.Retrieve("key1", false)
+	if err != nil || string(b) != "value1" {
+		t.Errorf("expected %s got %s", "value1", string(b))
+	}
+	if exp := dst.(cache.IndexedCache).ObjectExpirations()["key2"]; exp.After(recs[1].Expiration.Add(time.Second)) {
+		t.Errorf("expected remaining ttl to be preserved, got expiration %s", exp)
+	}
+
+	// an invalid export
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "http://0/trickster/cache/import?cache=dst",
+		strings.NewReader(`{"key":"key4","expiration":"2100-01-01T00:00:00Z","value":"dmFsdWU0"}`+"\nnot json"))
+	r.Header.Set(headers.NameAuthorization, "Bearer test-token")
+	imp(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"imported":1`) {
+		t.Errorf("unexpected import result %s", w.Body.String())
+	}
+}
+
+func TestCacheExportImportErrors(t *testing.T) {
+
+	conf, _, err := config.Load("trickster-test", "test",
+		[]string{"-origin-url", "http://1.2.3.4", "-origin-type", "prometheus"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+
+	export := CacheExportHandleFunc(conf, caches)
+	imp := CacheImportHandleFunc(conf, caches)
+
+	tests := []struct {
+		handler  func(http.ResponseWriter, *http.Request)
+		method   string
+		url      string
+		token    string
+		expected int
+	}{
+		// no admin auth token is configured
+		{export, http.MethodGet, "/?cache=default", "", http.StatusUnauthorized},
+		{export, http.MethodGet, "/?cache=default", "test-token", http.StatusUnauthorized},
+		{imp, http.MethodPost, "/?cache=default", "test-token", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "http://0"+test.url, strings.NewReader(""))
+		if test.token != "" {
+			r.Header.Set(headers.NameAuthorization, "Bearer "+test.token)
+		}
+		test.handler(w, r)
+		if w.Code != test.expected {
+			t.Errorf("expected %d got %d", test.expected, w.Code)
+		}
+	}
+
+	conf.Main.AdminAuthToken = "test-token"
+	tests = []struct {
+		handler  func(http.ResponseWriter, *http.Request)
+		method   string
+		url      string
+		token    string
+		expected int
+	}{
+		{export, http.MethodGet, "/?cache=default", "", http.StatusUnauthorized},
+		{export, http.MethodGet, "/?cache=default", "invalid", http.StatusUnauthorized},
+		{export, http.MethodPost, "/?cache=default", "test-token", http.StatusMethodNotAllowed},
+		{export, http.MethodGet, "/?cache=invalid", "test-token", http.StatusNotFound},
+		// the memory cache stores objects by reference
+		{export, http.MethodGet, "/?cache=default", "test-token", http.StatusNotImplemented},
+		{imp, http.MethodGet, "/?cache=default", "test-token", http.StatusMethodNotAllowed},
+		{imp, http.MethodPost, "/?cache=invalid", "test-token", http.StatusNotFound},
+		{imp, http.MethodPost, "/", "test-token", http.StatusNotImplemented},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "http://0"+test.url, strings.NewReader(""))
+		if test.token != "" {
+			r.Header.Set(headers.NameAuthorization, "Bearer "+test.token)
+		}
+		test.handler(w, r)
+		if w.Code != test.expected {
+			t.Errorf("expected %d got %d", test.expected, w.Code)
+		}
+	}
+}
@@ -23,12 +23,13 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 )
 
-// ConfigHandleFunc responds to the HTTP request with the running configuration
+// ConfigHandleFunc responds to the HTTP request with the running configuration,
+// followed by the phase timings of the load that produced it
 func ConfigHandleFunc(conf *config.Config) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
 		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(conf.String()))
+		w.Write([]byte(conf.String() + conf.LoadTimingsString()))
 	}
 }
@@ -19,6 +19,7 @@ package handlers
 import (
 	"io/ioutil"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/tricksterproxy/trickster/pkg/config"
@@ -57,4 +58,8 @@ func TestConfigHandler(t *testing.T) {
 		t.Errorf("response is not toml format")
 	}
 
+	if !strings.Contains(string(bodyBytes), "# config load phase timings") {
+		t.Errorf("missing load phase timings in response")
+	}
+
 }
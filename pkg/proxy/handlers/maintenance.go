@@ -0,0 +1,72 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// MaintenanceState describes whether an origin is in maintenance mode
+type MaintenanceState struct {
+	Origin          string `json:"origin"`
+	MaintenanceMode bool   `json:"maintenance_mode"`
+}
+
+// MaintenanceHandleFunc responds with the MaintenanceState of the origin named by the 'origin'
+// query parameter, or of the default origin. POST and PUT requests first place the origin in,
+// or remove it from, maintenance mode per the 'enabled' query parameter. The change is made in
+// memory only, and is replaced by the origin's maintenance_mode setting when the config is reloaded
+func MaintenanceHandleFunc(conf *config.Config) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		if !isAdminAuthorized(conf, r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		qp := r.URL.Query()
+		oc := findOrigin(conf, qp.Get("origin"))
+		if oc == nil {
+			http.Error(w, "origin not found", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut:
+			enabled, err := strconv.ParseBool(qp.Get("enabled"))
+			if err != nil {
+				http.Error(w, "invalid enabled value", http.StatusBadRequest)
+				return
+			}
+			oc.SetMaintenance(enabled)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		b, _ := json.Marshal(&MaintenanceState{Origin: oc.Name, MaintenanceMode: oc.InMaintenance()})
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
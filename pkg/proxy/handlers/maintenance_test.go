@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func TestMaintenanceHandleFunc(t *testing.T) {
+
+	conf, _, err := config.Load("trickster-test", "test",
+		[]string{"-origin-url", "http://1.2.3.4", "-origin-type", "prometheus"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	conf.Main.AdminAuthToken = "test-token"
+	f := MaintenanceHandleFunc(conf)
+
+	tests := []struct {
+		method, query, token string
+		expectedCode         int
+		expectedMode         bool
+	}{
+		{http.MethodPost, "?enabled=true", "wrong-token", http.StatusUnauthorized, false},
+		{http.MethodGet, "?origin=default", "test-token", http.StatusOK, false},
+		{http.MethodPost, "?origin=default&enabled=true", "test-token", http.StatusOK, true},
+		{http.MethodGet, "", "test-token", http.StatusOK, true},
+		{http.MethodPut, "?enabled=false", "test-token", http.StatusOK, false},
+		{http.MethodPost, "?enabled=maybe", "test-token", http.StatusBadRequest, false},
+		{http.MethodDelete, "", "test-token", http.StatusMethodNotAllowed, false},
+		{http.MethodGet, "?origin=missing", "test-token", http.StatusNotFound, false},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "http://0/trickster/maintenance"+test.query, nil)
+		r.Header.Set(headers.NameAuthorization, "Bearer "+test.token)
+		f(w, r)
+		if w.Code != test.expectedCode {
+			t.Errorf("%s %s: expected %d got %d", test.method, test.query, test.expectedCode, w.Code)
+		}
+		if v := conf.Origins["default"].InMaintenance(); v != test.expectedMode {
+			t.Errorf("%s %s: expected %t got %t", test.method, test.query, test.expectedMode, v)
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		ms := &MaintenanceState{}
+		if err := json.Unmarshal(w.Body.Bytes(), ms); err != nil {
+			t.Fatal(err)
+		}
+		if ms.Origin != "default" || ms.MaintenanceMode != test.expectedMode {
+			t.Errorf("%s %s: unexpected state %+v", test.method, test.query, ms)
+		}
+	}
+}
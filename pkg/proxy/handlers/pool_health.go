@@ -0,0 +1,82 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/pool"
+)
+
+// PoolHealth describes the health of an origin's replica URLs, and of the origin as a whole
+type PoolHealth struct {
+	Origin  string              `json:"origin"`
+	Healthy bool                `json:"healthy"`
+	Quorum  int                 `json:"quorum"`
+	URLs    []PoolBackendHealth `json:"urls"`
+}
+
+// PoolBackendHealth describes the health of one of an origin's replica URLs
+type PoolBackendHealth struct {
+	URL     string `json:"url"`
+	Healthy bool   `json:"healthy"`
+}
+
+// PoolHealthHandleFunc responds with the health of each of the origin's replica URLs, and of the
+// origin, which is healthy when at least its health_check_quorum of the URLs are healthy. The
+// response status is 200 when the origin is healthy, and 503 otherwise. When check is not nil,
+// as when active health checks are disabled, each URL is checked upon the request
+func PoolHealthHandleFunc(oc *oo.Options, check func(*pool.Backend) bool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		backends := oc.Pool.Backends()
+		if check != nil {
+			for _, b := range backends {
+				b.SetHealthy(check(b))
+			}
+		}
+
+		// the quorum is validated when the config is loaded
+		size, _ := oc.HealthCheckQuorum.Size(len(backends))
+		ph := &PoolHealth{Origin: oc.Name, Quorum: size,
+			URLs: make([]PoolBackendHealth, len(backends))}
+		var healthy int
+		for i, b := range backends {
+			ph.URLs[i] = PoolBackendHealth{URL: b.URL.String(), Healthy: b.Healthy()}
+			if ph.URLs[i].Healthy {
+				healthy++
+			}
+		}
+		ph.Healthy = size > 0 && healthy >= size
+
+		b, err := json.Marshal(ph)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		if ph.Healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(b)
+	}
+}
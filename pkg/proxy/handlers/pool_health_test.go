@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/pool"
+)
+
+func TestPoolHealthHandler(t *testing.T) {
+
+	u1, _ := url.Parse("http://1/")
+	u2, _ := url.Parse("http://2/")
+
+	oc := oo.NewOptions()
+	oc.Name = "test"
+	oc.Pool = pool.New([]*url.URL{u1, u2}, pool.RoundRobin)
+	oc.Pool.Backends()[1].SetHealthy(false)
+
+	tests := []struct {
+		quorum   pool.Quorum
+		check    func(*pool.Backend) bool
+		size     int
+		expected int
+	}{
+		{pool.QuorumAny, nil, 1, 200},
+		{pool.QuorumAll, nil, 2, 503},
+		{"2", func(*pool.Backend) bool { return true }, 2, 200},
+		{pool.QuorumAny, func(*pool.Backend) bool { return false }, 1, 503},
+	}
+
+	for i, test := range tests {
+		oc.HealthCheckQuorum = test.quorum
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://0/trickster/health/test", nil)
+		PoolHealthHandleFunc(oc, test.check)(w, r)
+		resp := w.Result()
+
+		if resp.StatusCode != test.expected {
+			t.Errorf("test %d: expected %d got %d", i, test.expected, resp.StatusCode)
+		}
+
+		ph := &PoolHealth{}
+		if err := json.NewDecoder(resp.Body).Decode(ph); err != nil {
+			t.Fatal(err)
+		}
+		if ph.Quorum != test.size {
+			t.Errorf("test %d: expected quorum %d got %d", i, test.size, ph.Quorum)
+		}
+		if ph.Healthy != (test.expected == 200) {
+			t.Errorf("test %d: expected healthy %t got %t", i, test.expected == 200, ph.Healthy)
+		}
+		if len(ph.URLs) != 2 || ph.URLs[0].URL != u1.String() {
+			t.Errorf("test %d: unexpected urls %v", i, ph.URLs)
+		}
+	}
+
+}
@@ -33,18 +33,34 @@ func ReloadHandleFunc(f reload.ReloaderFunc, conf *config.Config, wg *sync.WaitG
 	args []string) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if conf != nil {
-			conf.Main.ReloaderLock.Lock()
-			defer conf.Main.ReloaderLock.Unlock()
-			if conf.IsStale() {
-				log.Warn("configuration reload starting now", tl.Pairs{"source": "reloadEndpoint"})
-				err := f(conf, wg, log, caches, args, false)
-				if err == nil {
-					w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
-					w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
-					w.WriteHeader(http.StatusOK)
-					w.Write([]byte("configuration reloaded"))
-					return
+			var reloaded bool
+			queue := conf.ReloadConfig != nil && conf.ReloadConfig.QueueReloads
+			ran := reload.Serialize(queue, func() {
+				// a reload that completed while this one was queued has replaced conf,
+				// so this one is applied over the config that is now running
+				rc, rlog, rcaches := reload.Running(conf, log, caches)
+				rc.Main.ReloaderLock.Lock()
+				defer rc.Main.ReloaderLock.Unlock()
+				if rc.IsStale() {
+					rlog.Warn("configuration reload starting now", tl.Pairs{"source": "reloadEndpoint"})
+					reloaded = f(rc, wg, rlog, rcaches, args, false) == nil
 				}
+			})
+			if !ran {
+				log.Warn("configuration reload skipped: a reload is already in progress",
+					tl.Pairs{"source": "reloadEndpoint"})
+				w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
+				w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte("configuration NOT reloaded: a reload is already in progress"))
+				return
+			}
+			if reloaded {
+				w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
+				w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("configuration reloaded"))
+				return
 			}
 		}
 		w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
@@ -28,6 +28,7 @@ import (
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/config/reload"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 )
 
@@ -65,3 +66,50 @@ func TestReloadHandleFunc(t *testing.T) {
 	time.Sleep(time.Millisecond * 500)
 	f(w, r)
 }
+
+func TestReloadHandleFuncSuperseded(t *testing.T) {
+
+	var reloaded *config.Config
+	var f = func(c *config.Config, _ *sync.WaitGroup, _ *tl.Logger,
+		_ map[string]cache.Cache, _ []string, _ bool) error {
+		reloaded = c
+		return nil
+	}
+
+	testFile := fmt.Sprintf("trickster_test_config.%d.conf", time.Now().UnixNano())
+	tml, err := ioutil.ReadFile("../../../testdata/test.empty.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(testFile, tml, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(testFile)
+
+	running, _, err := config.Load("testing", "testing", []string{"-config", testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	running.ReloadConfig.RateLimitSecs = 0
+	running.ReloadConfig.DebounceMS = 0
+	log := tl.ConsoleLogger("info")
+	reload.SetRunning(running, log, nil)
+	defer reload.SetRunning(nil, nil, nil)
+
+	// the running config's file has changed since it was loaded
+	ts := time.Now().Add(time.Minute)
+	os.Chtimes(testFile, ts, ts)
+
+	// the handler holds the config that running replaced
+	superseded := config.NewConfig()
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest("GET", "/", nil)
+	ReloadHandleFunc(f, superseded, nil, log, nil, nil)(w, r)
+	if reloaded != running {
+		t.Error("expected the reload to be applied over the running config")
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "configuration reloaded" {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
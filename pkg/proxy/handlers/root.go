@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+// RootHandleFunc responds to an HTTP Request with the configured static root handler response
+func RootHandleFunc(conf *config.Config) func(http.ResponseWriter, *http.Request) {
+	var rc *config.RootHandlerResponseConfig
+	if conf != nil && conf.Frontend != nil {
+		rc = conf.Frontend.RootHandlerResponse
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rc == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set(headers.NameContentType, rc.ContentType)
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		w.WriteHeader(rc.StatusCode)
+		if r.Method != http.MethodHead {
+			w.Write([]byte(rc.Body))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func TestRootHandler(t *testing.T) {
+
+	conf, _, err := config.Load("trickster-test", "test",
+		[]string{"-origin-type", "reverseproxycache", "-origin-url", "http://0/"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://0/", nil)
+	RootHandleFunc(conf)(w, r)
+	if w.Result().StatusCode != http.StatusNotFound {
+		t.Errorf("expected %d got %d.", http.StatusNotFound, w.Result().StatusCode)
+	}
+
+	conf.Frontend.RootHandlerResponse = &config.RootHandlerResponseConfig{
+		StatusCode: http.StatusOK, Body: "trickster", ContentType: headers.ValueTextPlain}
+
+	w = httptest.NewRecorder()
+	RootHandleFunc(conf)(w, r)
+	resp := w.Result()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d.", http.StatusOK, resp.StatusCode)
+	}
+
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(bodyBytes) != "trickster" {
+		t.Errorf("expected 'trickster' got %s.", bodyBytes)
+	}
+
+	if resp.Header.Get(headers.NameContentType) != headers.ValueTextPlain {
+		t.Errorf("expected %s got %s.", headers.ValueTextPlain, resp.Header.Get(headers.NameContentType))
+	}
+
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+
+	"github.com/gorilla/mux"
+)
+
+// RouteMatch describes the origin path configuration that would handle a request
+type RouteMatch struct {
+	Origin            string `json:"origin"`
+	Method            string `json:"method"`
+	Path              string `json:"path"`
+	Matched           bool   `json:"matched"`
+	PathKey           string `json:"path_key,omitempty"`
+	MatchType         string `json:"match_type,omitempty"`
+	HandlerName       string `json:"handler,omitempty"`
+	TimeseriesTTLSecs int    `json:"timeseries_ttl_secs"`
+	MaxTTLSecs        int    `json:"max_ttl_secs"`
+}
+
+// RouteDebugHandleFunc responds to the HTTP request with the origin path configuration that
+// would handle the request described by the 'origin', 'method' and 'url' query parameters.
+// The request is only matched against the origin's compiled router and is never proxied.
+// Since it exposes the origins' path configurations, it requires the admin auth token.
+func RouteDebugHandleFunc(conf *config.Config) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+		if !isAdminAuthorized(conf, r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		qp := r.URL.Query()
+		oc := findOrigin(conf, qp.Get("origin"))
+		if oc == nil || oc.Router == nil {
+			http.Error(w, "origin not found", http.StatusNotFound)
+			return
+		}
+
+		u, err := url.Parse(qp.Get("url"))
+		if err != nil || u.Path == "" {
+			http.Error(w, "invalid url", http.StatusBadRequest)
+			return
+		}
+
+		method := strings.ToUpper(qp.Get("method"))
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		rm := matchRoute(oc, method, u)
+		b, err := json.Marshal(rm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	}
+}
+
+// findOrigin returns the named origin, or the default origin when name is empty
+func findOrigin(conf *config.Config, name string) *oo.Options {
+	if conf == nil {
+		return nil
+	}
+	if name != "" {
+		return conf.Origins[name]
+	}
+	for _, oc := range conf.Origins {
+		if oc.IsDefault {
+			return oc
+		}
+	}
+	return nil
+}
+
+// matchRoute runs the described request through the origin's router and returns the matching path
+func matchRoute(oc *oo.Options, method string, u *url.URL) *RouteMatch {
+	rm := &RouteMatch{
+		Origin:            oc.Name,
+		Method:            method,
+		Path:              u.Path,
+		TimeseriesTTLSecs: oc.TimeseriesTTLSecs,
+		MaxTTLSecs:        oc.MaxTTLSecs,
+	}
+
+	r := httptest.NewRequest(method, u.RequestURI(), nil)
+	var m mux.RouteMatch
+	if !oc.Router.Match(r, &m) || m.Route == nil {
+		return rm
+	}
+	tmpl, err := m.Route.GetPathTemplate()
+	if err != nil {
+		return rm
+	}
+
+	for k, p := range oc.Paths {
+		mp := oc.StripPathPrefix + p.Path
+		if (mp == tmpl || mp+"/" == tmpl) && hasMethod(p, method) {
+			rm.Matched = true
+			rm.PathKey = k
+			rm.MatchType = p.MatchType.String()
+			rm.HandlerName = p.HandlerName
+			break
+		}
+	}
+	return rm
+}
+
+func hasMethod(p *po.Options, method string) bool {
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+
+	"github.com/gorilla/mux"
+)
+
+func testRouteDebugConfig(handled *bool) *config.Config {
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { *handled = true })
+
+	oc := config.NewConfig().Origins["default"]
+	oc.Name = "default"
+	oc.IsDefault = true
+	oc.Router = mux.NewRouter()
+	oc.Router.Handle("/api/v1/query", h).Methods(http.MethodGet)
+	oc.Router.PathPrefix("/").Handler(h).Methods(http.MethodGet)
+
+	query := po.NewOptions()
+	query.Path = "/api/v1/query"
+	query.HandlerName = "query"
+	query.Methods = []string{http.MethodGet}
+	proxy := po.NewOptions()
+	proxy.Path = "/"
+	proxy.HandlerName = "proxy"
+	proxy.Methods = []string{http.MethodGet}
+	proxy.MatchType = matching.PathMatchTypePrefix
+	oc.Paths = map[string]*po.Options{"/api/v1/query-GET": query, "/-GET": proxy}
+
+	conf := config.NewConfig()
+	conf.Origins["default"] = oc
+	return conf
+}
+
+func TestRouteDebugHandler(t *testing.T) {
+
+	var handled bool
+	conf := testRouteDebugConfig(&handled)
+	h := RouteDebugHandleFunc(conf)
+
+	// requests are rejected until an admin auth token is configured and provided
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "http://0/trickster/routes?url=/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %d got %d", http.StatusUnauthorized, w.Code)
+	}
+	conf.Main.AdminAuthToken = "secret"
+
+	tests := []struct {
+		query   string
+		code    int
+		matched bool
+		pathKey string
+		handler string
+	}{
+		{"?url=/api/v1/query%3Fquery%3Dup", 200, true, "/api/v1/query-GET", "query"},
+		{"?origin=default&url=/some/other/path", 200, true, "/-GET", "proxy"},
+		{"?url=/api/v1/query&method=post", 200, false, "", ""},
+		{"?origin=missing&url=/", 404, false, "", ""},
+		{"?url=", 400, false, "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "http://0/trickster/routes"+test.query, nil)
+			r.Header.Set(headers.NameAuthorization, "Bearer secret")
+			h(w, r)
+			resp := w.Result()
+			if resp.StatusCode != test.code {
+				t.Fatalf("expected %d got %d", test.code, resp.StatusCode)
+			}
+			if test.code != 200 {
+				return
+			}
+			rm := &RouteMatch{}
+			if err := json.NewDecoder(resp.Body).Decode(rm); err != nil {
+				t.Fatal(err)
+			}
+			if rm.Matched != test.matched || rm.PathKey != test.pathKey || rm.HandlerName != test.handler {
+				t.Errorf("unexpected match %+v", rm)
+			}
+		})
+	}
+
+	if handled {
+		t.Error("route debug handler should not invoke the matched handler")
+	}
+}
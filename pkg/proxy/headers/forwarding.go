@@ -78,6 +78,20 @@ var HopHeaders = []string{
 	NameAcceptEncoding,
 }
 
+// HopByHopHeaders defines the list of hop-by-hop headers described in RFC 7230 Section 6.1,
+// including the non-standard, but widely used, Proxy-Connection and Keep-Alive headers
+var HopByHopHeaders = []string{
+	NameConnection,
+	NameProxyConnection,
+	NameKeepAlive,
+	NameProxyAuthenticate,
+	NameProxyAuthorization,
+	NameTe,
+	NameTrailer,
+	NameTransferEncoding,
+	NameUpgrade,
+}
+
 // ForwardingHeaders defines a list of headers that Proxies use to identify themselves in a request
 var ForwardingHeaders = []string{
 	NameXForwardedFor,
@@ -318,6 +332,24 @@ func StripClientHeaders(h http.Header) {
 	}
 }
 
+// StripHopByHopHeaders strips all hop-by-hop headers from the provided header set,
+// including any headers nominated as hop-by-hop by the Connection header
+func StripHopByHopHeaders(h http.Header) {
+	if h == nil {
+		return
+	}
+	for _, v := range h[NameConnection] {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				h.Del(k)
+			}
+		}
+	}
+	for _, k := range HopByHopHeaders {
+		h.Del(k)
+	}
+}
+
 // StripForwardingHeaders strips certain headers from the HTTP request to facililate acceleration
 func StripForwardingHeaders(h http.Header) {
 	for _, k := range ForwardingHeaders {
@@ -183,3 +183,27 @@ func TestFormatForwardedAddress(t *testing.T) {
 		t.Errorf("expected %s got %s", expected, s)
 	}
 }
+
+func TestStripHopByHopHeaders(t *testing.T) {
+
+	StripHopByHopHeaders(nil)
+
+	h := http.Header{
+		NameConnection:       []string{"close, X-Hop-One", "X-Hop-Two"},
+		NameKeepAlive:        []string{"timeout=5"},
+		NameTransferEncoding: []string{"chunked"},
+		NameUpgrade:          []string{"h2c"},
+		"X-Hop-One":          []string{"1"},
+		"X-Hop-Two":          []string{"2"},
+		NameContentType:      []string{"text/plain"},
+	}
+
+	StripHopByHopHeaders(h)
+
+	if len(h) != 1 {
+		t.Errorf("expected %d got %d: %v", 1, len(h), h)
+	}
+	if h.Get(NameContentType) != "text/plain" {
+		t.Errorf("expected %s got %s", "text/plain", h.Get(NameContentType))
+	}
+}
@@ -30,8 +30,12 @@ import (
 const (
 	// Common HTTP Header Values
 
+	// ValueApplicationFlux represents the HTTP Header Value of "application/vnd.flux"
+	ValueApplicationFlux = "application/vnd.flux"
 	// ValueApplicationJSON represents the HTTP Header Value of "application/json"
 	ValueApplicationJSON = "application/json"
+	// ValueApplicationNDJSON represents the HTTP Header Value of "application/x-ndjson"
+	ValueApplicationNDJSON = "application/x-ndjson"
 	// ValueMaxAge represents the HTTP Header Value of "max-age"
 	ValueMaxAge = "max-age"
 	// ValueMultipartFormData represents the HTTP Header Value of "multipart/form-data"
@@ -57,8 +61,15 @@ const (
 	// ValueXFormURLEncoded represents the HTTP Header Value of "application/x-www-form-urlencoded"
 	ValueXFormURLEncoded = "application/x-www-form-urlencoded"
 
+	// Value100Continue represents the HTTP Header Value of "100-continue"
+	Value100Continue = "100-continue"
 	// ValueMultipartByteRanges represents the HTTP Header prefix for a Multipart Byte Range response
 	ValueMultipartByteRanges = "multipart/byteranges; boundary="
+	// ValueWarningStale represents the HTTP Warning Header Value for a stale response
+	ValueWarningStale = `110 - "Response is Stale"`
+	// ValueWarningRevalidationFailed represents the HTTP Warning Header Value for a stale
+	// response served because it could not be revalidated
+	ValueWarningRevalidationFailed = `111 - "Revalidation Failed"`
 
 	// Common HTTP Header Names
 
@@ -76,16 +87,24 @@ const (
 	NameContentLength = "Content-Length"
 	// NameAuthorization represents the HTTP Header Name of "Authorization"
 	NameAuthorization = "Authorization"
+	// NameWWWAuthenticate represents the HTTP Header Name of "WWW-Authenticate"
+	NameWWWAuthenticate = "WWW-Authenticate"
 	// NameContentRange represents the HTTP Header Name of "Content-Range"
 	NameContentRange = "Content-Range"
 	// NameTricksterResult represents the HTTP Header Name of "X-Trickster-Result"
 	NameTricksterResult = "X-Trickster-Result"
+	// NameAge represents the HTTP Header Name of "Age"
+	NameAge = "Age"
+	// NameServerTiming represents the HTTP Header Name of "Server-Timing"
+	NameServerTiming = "Server-Timing"
 	// NameAcceptEncoding represents the HTTP Header Name of "Accept-Encoding"
 	NameAcceptEncoding = "Accept-Encoding"
 	// NameSetCookie represents the HTTP Header Name of "Set-Cookie"
 	NameSetCookie = "Set-Cookie"
 	// NameRange represents the HTTP Header Name of "Range"
 	NameRange = "Range"
+	// NameExpect represents the HTTP Header Name of "Expect"
+	NameExpect = "Expect"
 	// NameTransferEncoding represents the HTTP Header Name of "Transfer-Encoding"
 	NameTransferEncoding = "Transfer-Encoding"
 	// NameIfModifiedSince represents the HTTP Header Name of "If-Modified-Since"
@@ -122,6 +141,10 @@ const (
 	NameTrailer = "Trailer"
 	// NameUpgrade represents the HTTP Header Name of "Upgrade"
 	NameUpgrade = "Upgrade"
+	// NameVary represents the HTTP Header Name of "Vary"
+	NameVary = "Vary"
+	// NameWarning represents the HTTP Header Name of "Warning"
+	NameWarning = "Warning"
 )
 
 // Merge merges the source http.Header map into destination map.
@@ -191,6 +214,37 @@ func SetResultsHeader(headers http.Header, engine, status, ffstatus string, fetc
 
 }
 
+// GetResultsStatus returns the cache lookup status recorded in the X-Trickster-Result header
+func GetResultsStatus(headers http.Header) string {
+	if headers == nil {
+		return ""
+	}
+	for _, part := range strings.Split(headers.Get(NameTricksterResult), "; ") {
+		if strings.HasPrefix(part, "status=") {
+			return part[7:]
+		}
+	}
+	return ""
+}
+
+// IsUpgrade returns true if the headers request that the connection be upgraded to another
+// protocol, such as a WebSocket. Upgrades to h2c are excluded, since servers may ignore them
+// and respond over HTTP/1.1 as usual
+func IsUpgrade(h http.Header) bool {
+	up := strings.TrimSpace(h.Get(NameUpgrade))
+	if up == "" || strings.EqualFold(up, "h2c") {
+		return false
+	}
+	for _, v := range h[NameConnection] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // ExtractHeader returns the value for the provided header name, and a boolean indicating if the header was present
 func ExtractHeader(headers http.Header, header string) (string, bool) {
 	if Value, ok := headers[header]; ok {
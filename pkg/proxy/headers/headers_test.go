@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -140,6 +141,38 @@ func TestSetResultsHeader(t *testing.T) {
 	}
 }
 
+func TestGetResultsStatus(t *testing.T) {
+	h := http.Header{}
+	SetResultsHeader(h, "test-engine", "kmiss", "test-ffstatus", nil)
+	if s := GetResultsStatus(h); s != "kmiss" {
+		t.Errorf("expected %s got %s", "kmiss", s)
+	}
+	if s := GetResultsStatus(nil); s != "" {
+		t.Errorf("expected empty status got %s", s)
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	tests := []struct {
+		h        http.Header
+		expected bool
+	}{
+		{http.Header{}, false},
+		{http.Header{NameUpgrade: {"websocket"}}, false},
+		{http.Header{NameConnection: {"Upgrade"}}, false},
+		{http.Header{NameConnection: {"Upgrade"}, NameUpgrade: {"websocket"}}, true},
+		{http.Header{NameConnection: {"keep-alive, upgrade"}, NameUpgrade: {"websocket"}}, true},
+		{http.Header{NameConnection: {"Upgrade, HTTP2-Settings"}, NameUpgrade: {"h2c"}}, false},
+	}
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if v := IsUpgrade(test.h); v != test.expected {
+				t.Errorf("expected %t got %t", test.expected, v)
+			}
+		})
+	}
+}
+
 func TestSetResultsHeaderEmtpy(t *testing.T) {
 	h := http.Header{}
 	SetResultsHeader(h, "", "test-status", "test-ffstatus",
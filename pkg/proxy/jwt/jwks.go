@@ -0,0 +1,274 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+// ErrNoKeys indicates the key set could not be fetched, or provides no usable keys
+var ErrNoKeys = errors.New("no keys are available to verify the token")
+
+// minRefetchInterval is the least amount of time between fetches of the key set prompted by a
+// token signed with an unknown key, or that follow a failed fetch
+const minRefetchInterval = 30 * time.Second
+
+// maxKeySetBytes limits the size of the key set document that is read
+const maxKeySetBytes = 1 << 20
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+var curveHashes = map[string]crypto.Hash{
+	"P-256": crypto.SHA256,
+	"P-384": crypto.SHA384,
+	"P-521": crypto.SHA512,
+}
+
+// Validator validates tokens using the keys of a JSON Web Key Set, which is fetched when first
+// needed, and again once it is older than the refresh interval
+type Validator struct {
+	opts   Options
+	client *http.Client
+	logger *tl.Logger
+
+	mtx     sync.RWMutex
+	set     []*publicKey
+	fetched time.Time
+	// attempted is the time of the most recent fetch of the key set, successful or not
+	attempted time.Time
+	fetching  int32
+	fetchMtx  sync.Mutex
+}
+
+// publicKey is a signature verification key of the key set
+type publicKey struct {
+	kid string
+	kty string
+	alg string
+	key crypto.PublicKey
+}
+
+// jsonWebKey is a member of a JSON Web Key Set, per RFC 7517
+type jsonWebKey struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+}
+
+// NewValidator returns a Validator with the provided options
+func NewValidator(opts Options, logger *tl.Logger) *Validator {
+	return &Validator{
+		opts:   opts,
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger,
+	}
+}
+
+// keys returns the keys of the key set, fetching it if it has not been fetched, or is
+// due to be refreshed. When the key set has no key with the provided ID, it is fetched
+// again, at most once per minRefetchInterval, in case the signing keys were rotated
+func (v *Validator) keys(kid string) ([]*publicKey, error) {
+	v.mtx.RLock()
+	set, fetched, attempted := v.set, v.fetched, v.attempted
+	v.mtx.RUnlock()
+
+	if !v.refreshDue(set, fetched, attempted, kid, time.Now()) {
+		if set == nil {
+			return nil, ErrNoKeys
+		}
+		return set, nil
+	}
+
+	if set != nil {
+		// the current keys are used while another request refreshes the key set
+		if !atomic.CompareAndSwapInt32(&v.fetching, 0, 1) {
+			return set, nil
+		}
+		defer atomic.StoreInt32(&v.fetching, 0)
+	}
+	v.fetchMtx.Lock()
+	defer v.fetchMtx.Unlock()
+
+	v.mtx.RLock()
+	if !v.attempted.Equal(attempted) {
+		// the key set was fetched while this request waited for the lock
+		set = v.set
+		v.mtx.RUnlock()
+		if set == nil {
+			return nil, ErrNoKeys
+		}
+		return set, nil
+	}
+	v.mtx.RUnlock()
+
+	ns, err := v.fetch()
+	v.mtx.Lock()
+	v.attempted = time.Now()
+	if err == nil {
+		v.set, v.fetched = ns, v.attempted
+		set = ns
+	}
+	v.mtx.Unlock()
+	if err != nil {
+		v.logger.Warn("unable to fetch jwks", tl.Pairs{"url": v.opts.JWKSURL, "detail": err.Error()})
+		if set == nil {
+			return nil, ErrNoKeys
+		}
+	}
+	return set, nil
+}
+
+// refreshDue returns true if the key set should be fetched. Failed fetches are not retried
+// until minRefetchInterval has passed
+func (v *Validator) refreshDue(set []*publicKey, fetched, attempted time.Time,
+	kid string, now time.Time) bool {
+	if attempted.After(fetched) && now.Sub(attempted) < minRefetchInterval {
+		return false
+	}
+	if set == nil || now.Sub(fetched) >= v.opts.RefreshInterval {
+		return true
+	}
+	return kid != "" && !hasKeyID(set, kid) && now.Sub(fetched) >= minRefetchInterval
+}
+
+// fetch requests and parses the key set
+func (v *Validator) fetch() ([]*publicKey, error) {
+	resp, err := v.client.Get(v.opts.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxKeySetBytes))
+	if err != nil {
+		return nil, err
+	}
+	return parseKeySet(b)
+}
+
+// parseKeySet returns the signature verification keys of a JSON Web Key Set. Keys of
+// unsupported types, or for uses other than signatures, are skipped
+func parseKeySet(b []byte) ([]*publicKey, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	set := make([]*publicKey, 0, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key [%s]: %v", jwk.KeyID, err)
+		}
+		if key == nil {
+			continue
+		}
+		set = append(set, &publicKey{kid: jwk.KeyID, kty: jwk.KeyType, alg: jwk.Algorithm, key: key})
+	}
+	if len(set) == 0 {
+		return nil, errors.New("key set has no signature verification keys")
+	}
+	return set, nil
+}
+
+// publicKey returns the RSA or EC public key described by the JSON Web Key,
+// or nil for other key types
+func (jwk *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decodeInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		c, ok := curves[jwk.Curve]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Curve)
+		}
+		x, err := decodeInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !c.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func hasKeyID(set []*publicKey, kid string) bool {
+	for _, k := range set {
+		if k.kid == kid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package jwt validates JSON Web Tokens signed by the keys of a JSON Web Key Set
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	// the hash functions of the supported signing algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// ErrMalformedToken indicates the token is not a JWS Compact Serialization of a JSON claims set
+var ErrMalformedToken = errors.New("malformed token")
+
+// ErrUnsupportedAlgorithm indicates the token is signed with an algorithm that is not supported
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// ErrInvalidSignature indicates the token's signature is not verified by any key of the key set
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// ErrExpired indicates the token is expired, or does not provide an expiration time
+var ErrExpired = errors.New("token is expired")
+
+// ErrNotYetValid indicates the token's not before time has not yet passed
+var ErrNotYetValid = errors.New("token is not yet valid")
+
+// ErrInvalidIssuer indicates the token's issuer is not one of the accepted issuers
+var ErrInvalidIssuer = errors.New("invalid issuer")
+
+// ErrInvalidAudience indicates none of the token's audiences are accepted
+var ErrInvalidAudience = errors.New("invalid audience")
+
+// ErrInvalidClaim indicates a required claim is missing from the token, or has the wrong value
+var ErrInvalidClaim = errors.New("invalid claim")
+
+// Claims is the decoded claims set of a token
+type Claims map[string]interface{}
+
+// Options is a collection of configurations for the validation of tokens
+type Options struct {
+	// JWKSURL is the URL of the JSON Web Key Set whose keys sign the tokens
+	JWKSURL string
+	// RefreshInterval is how long the key set is used before it is fetched again
+	RefreshInterval time.Duration
+	// Leeway is the clock skew tolerated when checking the token's expiration and not before times
+	Leeway time.Duration
+	// Issuers is the list of accepted token issuers. When empty, any issuer is accepted
+	Issuers []string
+	// Audiences is the list of accepted token audiences. When empty, any audience is accepted
+	Audiences []string
+	// RequiredClaims is a map of the claims each token must provide to their required values.
+	// Claims mapped to an empty value must be present, but may have any value
+	RequiredClaims map[string]string
+	// ClaimHeaders is a map of claims to the names of the request headers that pass their
+	// values upstream
+	ClaimHeaders map[string]string
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type algorithm struct {
+	hash crypto.Hash
+	// kty is the type of key that verifies signatures of the algorithm
+	kty    string
+	verify func(key crypto.PublicKey, hash crypto.Hash, digest, sig []byte) bool
+}
+
+var algorithms = map[string]algorithm{
+	"RS256": {crypto.SHA256, "RSA", verifyPKCS1v15},
+	"RS384": {crypto.SHA384, "RSA", verifyPKCS1v15},
+	"RS512": {crypto.SHA512, "RSA", verifyPKCS1v15},
+	"PS256": {crypto.SHA256, "RSA", verifyPSS},
+	"PS384": {crypto.SHA384, "RSA", verifyPSS},
+	"PS512": {crypto.SHA512, "RSA", verifyPSS},
+	"ES256": {crypto.SHA256, "EC", verifyECDSA},
+	"ES384": {crypto.SHA384, "EC", verifyECDSA},
+	"ES512": {crypto.SHA512, "EC", verifyECDSA},
+}
+
+// Validate verifies the token's signature with the key set, and returns its claims if they
+// are currently valid and satisfy the Validator's issuers, audiences and required claims
+func (v *Validator) Validate(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, ErrMalformedToken
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil || claims == nil {
+		return nil, ErrMalformedToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	alg, ok := algorithms[h.Algorithm]
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	hf := alg.hash.New()
+	hf.Write([]byte(parts[0] + "." + parts[1]))
+	digest := hf.Sum(nil)
+	keys, err := v.keys(h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	verified := false
+	for _, k := range keys {
+		if k.kty == alg.kty && (k.alg == "" || k.alg == h.Algorithm) &&
+			(h.KeyID == "" || k.kid == h.KeyID) && alg.verify(k.key, alg.hash, digest, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidSignature
+	}
+
+	if err = v.validateClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validateClaims checks the registered claims of the token as of the provided time,
+// followed by its required claims
+func (v *Validator) validateClaims(claims Claims, now time.Time) error {
+	exp, ok := claims.numericDate("exp")
+	if !ok || !now.Before(exp.Add(v.opts.Leeway)) {
+		return ErrExpired
+	}
+	if _, present := claims["nbf"]; present {
+		nbf, ok := claims.numericDate("nbf")
+		if !ok || now.Add(v.opts.Leeway).Before(nbf) {
+			return ErrNotYetValid
+		}
+	}
+	if len(v.opts.Issuers) > 0 && !claims.matchesAny("iss", v.opts.Issuers) {
+		return ErrInvalidIssuer
+	}
+	if len(v.opts.Audiences) > 0 && !claims.matchesAny("aud", v.opts.Audiences) {
+		return ErrInvalidAudience
+	}
+	for k, want := range v.opts.RequiredClaims {
+		if _, present := claims[k]; !present || (want != "" && !claims.matchesAny(k, []string{want})) {
+			return fmt.Errorf("%w: %s", ErrInvalidClaim, k)
+		}
+	}
+	return nil
+}
+
+// SetClaimHeaders sets the claim headers of the validated token's claims on h. Any claim
+// headers already in h are removed, so that clients cannot provide them
+func (v *Validator) SetClaimHeaders(h http.Header, claims Claims) {
+	for k, name := range v.opts.ClaimHeaders {
+		h.Del(name)
+		if s, ok := claims.Value(k); ok {
+			h.Set(name, s)
+		}
+	}
+}
+
+// Value returns the string representation of the named claim, and false if it is not present.
+// Strings are returned as is, lists are joined with commas, and other values are JSON-encoded
+func (c Claims) Value(name string) (string, bool) {
+	v, ok := c[name]
+	if !ok || v == nil {
+		return "", false
+	}
+	if l, ok := v.([]interface{}); ok {
+		vals := make([]string, 0, len(l))
+		for _, e := range l {
+			if s, ok := scalarString(e); ok {
+				vals = append(vals, s)
+			}
+		}
+		return strings.Join(vals, ","), true
+	}
+	if s, ok := scalarString(v); ok {
+		return s, true
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// matchesAny returns true if the named claim, or any member of it when it is a list,
+// is one of the provided values
+func (c Claims) matchesAny(name string, vals []string) bool {
+	var members []interface{}
+	switch t := c[name].(type) {
+	case nil:
+		return false
+	case []interface{}:
+		members = t
+	default:
+		members = []interface{}{t}
+	}
+	for _, m := range members {
+		s, ok := scalarString(m)
+		if !ok {
+			continue
+		}
+		for _, v := range vals {
+			if s == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// numericDate returns the time of the named claim, which must be a number of seconds since the epoch
+func (c Claims) numericDate(name string) (time.Time, bool) {
+	n, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true
+}
+
+func scalarString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case json.Number:
+		return t.String(), true
+	case bool:
+		return strconv.FormatBool(t), true
+	}
+	return "", false
+}
+
+// decodeSegment decodes a base64url-encoded JSON segment of a token into v
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+func verifyPKCS1v15(key crypto.PublicKey, hash crypto.Hash, digest, sig []byte) bool {
+	pk, ok := key.(*rsa.PublicKey)
+	return ok && rsa.VerifyPKCS1v15(pk, hash, digest, sig) == nil
+}
+
+func verifyPSS(key crypto.PublicKey, hash crypto.Hash, digest, sig []byte) bool {
+	pk, ok := key.(*rsa.PublicKey)
+	return ok && rsa.VerifyPSS(pk, hash, digest, sig,
+		&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+}
+
+// verifyECDSA verifies a signature of the concatenated R and S values, each of the byte size of
+// the key's curve. The curve must match the algorithm's hash, per RFC 7518 section 3.4
+func verifyECDSA(key crypto.PublicKey, hash crypto.Hash, digest, sig []byte) bool {
+	pk, ok := key.(*ecdsa.PublicKey)
+	if !ok || curveHashes[pk.Curve.Params().Name] != hash {
+		return false
+	}
+	size := (pk.Curve.Params().BitSize + 7) / 8
+	if len(sig) != 2*size {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:size])
+	s := new(big.Int).SetBytes(sig[size:])
+	return ecdsa.Verify(pk, digest, r, s)
+}
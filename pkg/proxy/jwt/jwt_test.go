@@ -0,0 +1,304 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+var testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+var testECKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func testKeySet() []byte {
+	b, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kid": "rsa1", "kty": "RSA", "use": "sig", "n": b64(testRSAKey.N.Bytes()),
+			"e": b64(big.NewInt(int64(testRSAKey.E)).Bytes())},
+		{"kid": "ec1", "kty": "EC", "crv": "P-256", "x": b64(testECKey.X.Bytes()),
+			"y": b64(testECKey.Y.Bytes())},
+		{"kid": "enc1", "kty": "RSA", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		{"kid": "oct1", "kty": "oct", "k": "c2VjcmV0"},
+	}})
+	return b
+}
+
+func sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	h, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	c, _ := json.Marshal(claims)
+	input := b64(h) + "." + b64(c)
+	var sig []byte
+	var err error
+	switch alg {
+	case "RS256":
+		d := crypto.SHA256.New()
+		d.Write([]byte(input))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, testRSAKey, crypto.SHA256, d.Sum(nil))
+	case "PS384":
+		d := crypto.SHA384.New()
+		d.Write([]byte(input))
+		sig, err = rsa.SignPSS(rand.Reader, testRSAKey, crypto.SHA384, d.Sum(nil),
+			&rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		d := crypto.SHA256.New()
+		d.Write([]byte(input))
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, testECKey, d.Sum(nil))
+		if err == nil {
+			sig = make([]byte, 64)
+			rb, sb := r.Bytes(), s.Bytes()
+			copy(sig[32-len(rb):32], rb)
+			copy(sig[64-len(sb):], sb)
+		}
+	default:
+		sig = []byte("signature")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + b64(sig)
+}
+
+func newTestJWKS(status int, body []byte) (*httptest.Server, *int32) {
+	var fetches int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	return ts, &fetches
+}
+
+func TestValidate(t *testing.T) {
+
+	ts, _ := newTestJWKS(http.StatusOK, testKeySet())
+	defer ts.Close()
+
+	v := NewValidator(Options{
+		JWKSURL:         ts.URL,
+		RefreshInterval: time.Minute,
+		Issuers:         []string{"https://issuer.example.com/"},
+		Audiences:       []string{"trickster"},
+		RequiredClaims:  map[string]string{"tenant": "", "scope": "read"},
+	}, tl.ConsoleLogger("error"))
+
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{"iss": "https://issuer.example.com/", "aud": []string{"other", "trickster"},
+			"exp": exp, "tenant": "acme", "scope": []string{"write", "read"}}
+	}
+	with := func(k string, val interface{}) map[string]interface{} {
+		c := valid()
+		if val == nil {
+			delete(c, k)
+		} else {
+			c[k] = val
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		{"rs256", sign(t, "RS256", "rsa1", valid()), nil},
+		{"ps384", sign(t, "PS384", "rsa1", valid()), nil},
+		{"es256", sign(t, "ES256", "ec1", valid()), nil},
+		{"no kid", sign(t, "ES256", "", valid()), nil},
+		{"wrong key type", sign(t, "ES256", "rsa1", valid()), ErrInvalidSignature},
+		{"unknown kid", sign(t, "RS256", "rsa2", valid()), ErrInvalidSignature},
+		{"none", sign(t, "none", "", valid()), ErrUnsupportedAlgorithm},
+		{"hs256", sign(t, "HS256", "oct1", valid()), ErrUnsupportedAlgorithm},
+		{"malformed", "abc.def", ErrMalformedToken},
+		{"expired", sign(t, "RS256", "rsa1", with("exp", time.Now().Add(-time.Minute).Unix())), ErrExpired},
+		{"no exp", sign(t, "RS256", "rsa1", with("exp", nil)), ErrExpired},
+		{"not yet valid", sign(t, "RS256", "rsa1", with("nbf", time.Now().Add(time.Hour).Unix())), ErrNotYetValid},
+		{"nbf passed", sign(t, "RS256", "rsa1", with("nbf", time.Now().Add(-time.Hour).Unix())), nil},
+		{"issuer", sign(t, "RS256", "rsa1", with("iss", "https://other.example.com/")), ErrInvalidIssuer},
+		{"audience", sign(t, "RS256", "rsa1", with("aud", "other")), ErrInvalidAudience},
+		{"audience string", sign(t, "RS256", "rsa1", with("aud", "trickster")), nil},
+		{"missing claim", sign(t, "RS256", "rsa1", with("tenant", nil)), ErrInvalidClaim},
+		{"claim value", sign(t, "RS256", "rsa1", with("scope", "write")), ErrInvalidClaim},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claims, err := v.Validate(test.token)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v got %v", test.err, err)
+			}
+			if test.err == nil && claims["tenant"] != "acme" {
+				t.Errorf("expected claim tenant %s got %v", "acme", claims["tenant"])
+			}
+		})
+	}
+}
+
+func TestValidateTamperedSignature(t *testing.T) {
+	ts, _ := newTestJWKS(http.StatusOK, testKeySet())
+	defer ts.Close()
+	v := NewValidator(Options{JWKSURL: ts.URL, RefreshInterval: time.Minute}, tl.ConsoleLogger("error"))
+	token := sign(t, "RS256", "rsa1", map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})
+	other := sign(t, "RS256", "rsa1", map[string]interface{}{"exp": time.Now().Add(2 * time.Hour).Unix()})
+	parts, otherParts := strings.Split(token, "."), strings.Split(other, ".")
+	if _, err := v.Validate(parts[0] + "." + otherParts[1] + "." + parts[2]); err != ErrInvalidSignature {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, err)
+	}
+}
+
+func TestValidateLeeway(t *testing.T) {
+	ts, _ := newTestJWKS(http.StatusOK, testKeySet())
+	defer ts.Close()
+	v := NewValidator(Options{JWKSURL: ts.URL, RefreshInterval: time.Minute, Leeway: time.Minute},
+		tl.ConsoleLogger("error"))
+	token := sign(t, "RS256", "rsa1", map[string]interface{}{"exp": time.Now().Add(-30 * time.Second).Unix()})
+	if _, err := v.Validate(token); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidatorKeySetRefresh(t *testing.T) {
+
+	ts, fetches := newTestJWKS(http.StatusOK, testKeySet())
+	defer ts.Close()
+	v := NewValidator(Options{JWKSURL: ts.URL, RefreshInterval: time.Minute}, tl.ConsoleLogger("error"))
+	claims := map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()}
+
+	for i := 0; i < 3; i++ {
+		if _, err := v.Validate(sign(t, "RS256", "rsa1", claims)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("expected %d fetches got %d", 1, n)
+	}
+
+	// a token signed by an unknown key does not refetch a recently fetched key set
+	v.Validate(sign(t, "RS256", "rsa2", claims))
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("expected %d fetches got %d", 1, n)
+	}
+
+	v.mtx.Lock()
+	v.fetched = v.fetched.Add(-minRefetchInterval)
+	v.attempted = v.fetched
+	v.mtx.Unlock()
+	v.Validate(sign(t, "RS256", "rsa2", claims))
+	if n := atomic.LoadInt32(fetches); n != 2 {
+		t.Errorf("expected %d fetches got %d", 2, n)
+	}
+
+	v.mtx.Lock()
+	v.fetched = v.fetched.Add(-time.Minute)
+	v.attempted = v.fetched
+	v.mtx.Unlock()
+	if _, err := v.Validate(sign(t, "RS256", "rsa1", claims)); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(fetches); n != 3 {
+		t.Errorf("expected %d fetches got %d", 3, n)
+	}
+}
+
+func TestValidatorFetchFailure(t *testing.T) {
+
+	ts, fetches := newTestJWKS(http.StatusInternalServerError, nil)
+	defer ts.Close()
+	v := NewValidator(Options{JWKSURL: ts.URL, RefreshInterval: time.Minute}, tl.ConsoleLogger("error"))
+	token := sign(t, "RS256", "rsa1", map[string]interface{}{"exp": time.Now().Add(time.Hour).Unix()})
+
+	for i := 0; i < 2; i++ {
+		if _, err := v.Validate(token); err != ErrNoKeys {
+			t.Errorf("expected error %v got %v", ErrNoKeys, err)
+		}
+	}
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("expected %d fetches got %d", 1, n)
+	}
+
+	// the previous key set is used when a refresh fails
+	set, _ := parseKeySet(testKeySet())
+	v.mtx.Lock()
+	v.set, v.fetched, v.attempted = set, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour)
+	v.mtx.Unlock()
+	if _, err := v.Validate(token); err != nil {
+		t.Error(err)
+	}
+	if n := atomic.LoadInt32(fetches); n != 2 {
+		t.Errorf("expected %d fetches got %d", 2, n)
+	}
+}
+
+func TestParseKeySet(t *testing.T) {
+	tests := []string{
+		`{"keys":[`,
+		`{"keys":[]}`,
+		`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`,
+		`{"keys":[{"kty":"RSA","n":"","e":"AQAB"}]}`,
+		`{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		`{"keys":[{"kty":"EC","crv":"P-192","x":"AQ","y":"AQ"}]}`,
+	}
+	for _, test := range tests {
+		if _, err := parseKeySet([]byte(test)); err == nil {
+			t.Errorf("expected error for key set %s", test)
+		}
+	}
+}
+
+func TestClaimsValue(t *testing.T) {
+	var c Claims
+	decodeSegment(b64([]byte(`{"sub":"user1","groups":["a","b"],"admin":true,"n":42,`+
+		`"org":{"id":7},"empty":null}`)), &c)
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"sub", "user1", true},
+		{"groups", "a,b", true},
+		{"admin", "true", true},
+		{"n", "42", true},
+		{"org", `{"id":7}`, true},
+		{"empty", "", false},
+		{"missing", "", false},
+	}
+	for _, test := range tests {
+		v, ok := c.Value(test.name)
+		if v != test.value || ok != test.ok {
+			t.Errorf("expected %s %t for claim %s got %s %t", test.value, test.ok, test.name, v, ok)
+		}
+	}
+}
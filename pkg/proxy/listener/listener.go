@@ -32,9 +32,10 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+	"github.com/tricksterproxy/trickster/pkg/util/middleware"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"golang.org/x/net/netutil"
-
-	"github.com/gorilla/handlers"
 )
 
 // Listener is the Trickster net.Listener implmementation
@@ -167,6 +168,22 @@ func (lg *ListenerGroup) Get(name string) *Listener {
 func (lg *ListenerGroup) StartListener(listenerName, address string, port int, connectionsLimit int,
 	tlsConfig *tls.Config, router http.Handler, wg *sync.WaitGroup, tracers tracing.Tracers,
 	exitOnError bool, drainTimeout time.Duration, log *tl.Logger) error {
+	return lg.startListener(listenerName, address, port, connectionsLimit, tlsConfig, router,
+		wg, tracers, exitOnError, drainTimeout, false, log)
+}
+
+// StartH2CListener starts a new plaintext HTTP listener that also serves HTTP/2 without TLS (h2c),
+// and adds it to the listener group
+func (lg *ListenerGroup) StartH2CListener(listenerName, address string, port int, connectionsLimit int,
+	router http.Handler, wg *sync.WaitGroup, tracers tracing.Tracers,
+	exitOnError bool, drainTimeout time.Duration, log *tl.Logger) error {
+	return lg.startListener(listenerName, address, port, connectionsLimit, nil, router,
+		wg, tracers, exitOnError, drainTimeout, true, log)
+}
+
+func (lg *ListenerGroup) startListener(listenerName, address string, port int, connectionsLimit int,
+	tlsConfig *tls.Config, router http.Handler, wg *sync.WaitGroup, tracers tracing.Tracers,
+	exitOnError bool, drainTimeout time.Duration, enableH2C bool, log *tl.Logger) error {
 	if wg != nil {
 		defer wg.Done()
 	}
@@ -207,7 +224,7 @@ func (lg *ListenerGroup) StartListener(listenerName, address string, port int, c
 
 	if tlsConfig != nil {
 		svr := &http.Server{
-			Handler:   handlers.CompressHandler(l.routeSwapper),
+			Handler:   middleware.Compress(l.routeSwapper),
 			TLSConfig: tlsConfig,
 		}
 		l.server = svr
@@ -221,8 +238,12 @@ func (lg *ListenerGroup) StartListener(listenerName, address string, port int, c
 		return err
 	}
 
+	var h http.Handler = middleware.Compress(l.routeSwapper)
+	if enableH2C {
+		h = h2c.NewHandler(h, &http2.Server{})
+	}
 	svr := &http.Server{
-		Handler: handlers.CompressHandler(l.routeSwapper),
+		Handler: h,
 	}
 	l.server = svr
 	err = svr.Serve(l)
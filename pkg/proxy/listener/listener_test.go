@@ -38,6 +38,8 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/stdout"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
 	tlstest "github.com/tricksterproxy/trickster/pkg/util/testing/tls"
+
+	"golang.org/x/net/http2"
 )
 
 func testListener() net.Listener {
@@ -298,3 +300,39 @@ func TestUpdateRouters(t *testing.T) {
 		t.Error("expected non-nil handler")
 	}
 }
+
+func TestStartH2CListener(t *testing.T) {
+
+	testLG := NewListenerGroup()
+	router := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+	go testLG.StartH2CListener("h2cListener", "127.0.0.1", 0, 20, router,
+		nil, nil, false, 0, tl.ConsoleLogger("info"))
+
+	var l *Listener
+	for i := 0; i < 30 && l == nil; i++ {
+		time.Sleep(time.Millisecond * 10)
+		l = testLG.Get("h2cListener")
+	}
+	if l == nil {
+		t.Fatal("expected h2c listener")
+	}
+	defer l.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err := client.Get("http://" + l.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	if string(b) != "HTTP/2.0" {
+		t.Errorf("expected %s got %s", "HTTP/2.0", string(b))
+	}
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+)
+
+// defaultFrom is the start of the range when a render request has no from parameter
+const defaultFrom = "-24h"
+
+// defaultStep is the step of render requests whose targets do not bucket their datapoints
+// into fixed intervals. As Graphite does not accept a step, and the resolution of a response
+// is determined by the storage schema of its metrics, this is the most common resolution
+const defaultStep = time.Minute
+
+// absoluteTimeLayouts are the layouts of the absolute time references accepted by Graphite
+var absoluteTimeLayouts = []string{"15:04_20060102", "20060102"}
+
+// reIntervalFunc matches calls of functions that bucket datapoints into fixed intervals, and
+// captures the interval, which is the first quoted argument following the series argument
+var reIntervalFunc = regexp.MustCompile(`(?i)\b(?:summarize|hitcount)\(.*?,\s*["']([^"']*)["']`)
+
+// reFromAlignedFunc matches calls of functions whose intervals are aligned to the start of the
+// requested range, rather than to the epoch, so their results cannot be partially fetched
+var reFromAlignedFunc = regexp.MustCompile(`(?i)\bsmartSummarize\(|\b(?:summarize|hitcount)\(.*,\s*true\s*\)`)
+
+// parseTime returns the time represented by a Graphite time expression, relative to now. The
+// expression may be "now", an epoch time in seconds, an absolute time of the form HH:MM_YYYYMMDD
+// or YYYYMMDD in the provided location, or an offset (e.g., "-1h"), which may follow "now"
+// or an absolute time. An empty expression represents now
+func parseTime(s string, now time.Time, loc *time.Location) (time.Time, error) {
+
+	// Graphite times have a resolution of seconds
+	now = time.Unix(now.Unix(), 0)
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "now" {
+		return now, nil
+	}
+
+	if isDigits(s) && !isDate(s) {
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, invalidTime(s)
+		}
+		return time.Unix(i, 0), nil
+	}
+
+	ref, offset := s, ""
+	if i := strings.IndexAny(s, "+-"); i >= 0 {
+		ref, offset = s[:i], s[i:]
+	}
+
+	t := now
+	if ref != "" && ref != "now" {
+		var err error
+		if t, err = parseAbsoluteTime(ref, loc); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if offset != "" {
+		d, err := parseOffset(offset)
+		if err != nil {
+			return time.Time{}, err
+		}
+		t = t.Add(d)
+	}
+	return time.Unix(t.Unix(), 0), nil
+}
+
+// invalidTime returns an error indicating the Graphite time expression is invalid
+func invalidTime(s string) error {
+	return fmt.Errorf("unable to parse time: %s", s)
+}
+
+// parseAbsoluteTime returns the time represented by an absolute Graphite time reference
+func parseAbsoluteTime(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range absoluteTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, invalidTime(s)
+}
+
+// parseOffset returns the duration of a Graphite time offset, like "-1h" or "+30min", which
+// may combine several values and units (e.g., "-1d12h")
+func parseOffset(s string) (time.Duration, error) {
+
+	in := s
+	var sign time.Duration = 1
+	if strings.HasPrefix(s, "-") {
+		sign = -1
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	if s == "" {
+		return errors.ParseDuration(in)
+	}
+
+	var d time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return errors.ParseDuration(in)
+		}
+		v, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return errors.ParseDuration(in)
+		}
+		s = s[i:]
+		j := strings.IndexFunc(s, func(r rune) bool { return r >= '0' && r <= '9' })
+		if j < 0 {
+			j = len(s)
+		}
+		u, ok := parseUnit(s[:j])
+		if !ok {
+			return errors.ParseDuration(in)
+		}
+		d += time.Duration(v) * u
+		s = s[j:]
+	}
+	return sign * d, nil
+}
+
+// parseUnit returns the duration of a Graphite time unit, which, as with Graphite, is
+// recognized by its prefix (e.g., "s", "sec" and "seconds" are all seconds)
+func parseUnit(s string) (time.Duration, bool) {
+	switch {
+	case s == "":
+		return 0, false
+	case strings.HasPrefix(s, "s"):
+		return time.Second, true
+	case strings.HasPrefix(s, "min"):
+		return time.Minute, true
+	case strings.HasPrefix(s, "h"):
+		return time.Hour, true
+	case strings.HasPrefix(s, "d"):
+		return 24 * time.Hour, true
+	case strings.HasPrefix(s, "w"):
+		return 7 * 24 * time.Hour, true
+	case strings.HasPrefix(s, "mon"):
+		return 30 * 24 * time.Hour, true
+	case strings.HasPrefix(s, "m"):
+		return time.Minute, true
+	case strings.HasPrefix(s, "y"):
+		return 365 * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// targetsStep returns the step of a render request, which is the largest interval of the
+// functions in its targets that bucket datapoints into fixed intervals, or the defaultStep
+func targetsStep(targets []string) (time.Duration, error) {
+	var step time.Duration
+	for _, t := range targets {
+		if reFromAlignedFunc.MatchString(t) {
+			return 0, errors.ErrStepParse
+		}
+		for _, m := range reIntervalFunc.FindAllStringSubmatch(t, -1) {
+			d, err := parseOffset(strings.ToLower(m[1]))
+			if err != nil || d <= 0 {
+				return 0, errors.ErrStepParse
+			}
+			if d > step {
+				step = d
+			}
+		}
+	}
+	if step == 0 {
+		step = defaultStep
+	}
+	return step, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// isDate returns true if the string of digits is a YYYYMMDD date, which Graphite
+// prefers over an epoch time of the same digits
+func isDate(s string) bool {
+	if len(s) != 8 {
+		return false
+	}
+	y, _ := strconv.Atoi(s[:4])
+	m, _ := strconv.Atoi(s[4:6])
+	d, _ := strconv.Atoi(s[6:])
+	return y > 1900 && m < 13 && d < 32
+}
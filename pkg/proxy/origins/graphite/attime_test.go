@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+)
+
+func TestParseTime(t *testing.T) {
+
+	now := time.Unix(1577836800, 0)
+
+	tests := []struct {
+		s        string
+		expected int64
+		err      bool
+	}{
+		{"", 1577836800, false},
+		{"now", 1577836800, false},
+		{"NOW", 1577836800, false},
+		{"1577833200", 1577833200, false},
+		{"-1h", 1577833200, false},
+		{"now-1h", 1577833200, false},
+		{"+30min", 1577838600, false},
+		{"-1d12h", 1577707200, false},
+		{"-2weeks", 1576627200, false},
+		{"-1mon", 1575244800, false},
+		{"20191231", 1577750400, false},
+		{"12:00_20191231", 1577793600, false},
+		{"20191231+6h", 1577772000, false},
+		{"noon", 0, true},
+		{"-1x", 0, true},
+		{"-", 0, true},
+	}
+
+	for _, test := range tests {
+		tm, err := parseTime(test.s, now, time.UTC)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error", test.s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.s, err)
+			continue
+		}
+		if tm.Unix() != test.expected {
+			t.Errorf("%s: expected %d got %d", test.s, test.expected, tm.Unix())
+		}
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+
+	tests := []struct {
+		s        string
+		expected time.Duration
+	}{
+		{"10s", 10 * time.Second},
+		{"-5min", -5 * time.Minute},
+		{"5m", 5 * time.Minute},
+		{"1h30min", 90 * time.Minute},
+		{"+2days", 48 * time.Hour},
+		{"1y", 365 * 24 * time.Hour},
+	}
+
+	for _, test := range tests {
+		d, err := parseOffset(test.s)
+		if err != nil {
+			t.Errorf("%s: %v", test.s, err)
+			continue
+		}
+		if d != test.expected {
+			t.Errorf("%s: expected %s got %s", test.s, test.expected, d)
+		}
+	}
+
+	for _, s := range []string{"", "+", "h", "1", "1h2", "1.5h"} {
+		if _, err := parseOffset(s); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestTargetsStep(t *testing.T) {
+
+	tests := []struct {
+		targets  []string
+		expected time.Duration
+		err      error
+	}{
+		{[]string{"a.b.c"}, defaultStep, nil},
+		{[]string{`summarize(a.b.c, "1h", "sum")`}, time.Hour, nil},
+		{[]string{`hitcount(a.b.c, '10min')`, `summarize(a.b, "30s")`}, 10 * time.Minute, nil},
+		{[]string{`alias(summarize(a.b.c, "1d"), "daily")`}, 24 * time.Hour, nil},
+		{[]string{`smartSummarize(a.b.c, "1h")`}, 0, errors.ErrStepParse},
+		{[]string{`summarize(a.b.c, "1h", "sum", true)`}, 0, errors.ErrStepParse},
+		{[]string{`summarize(a.b.c, "hourly")`}, 0, errors.ErrStepParse},
+	}
+
+	for _, test := range tests {
+		step, err := targetsStep(test.targets)
+		if err != test.err {
+			t.Errorf("%v: expected %v got %v", test.targets, test.err, err)
+			continue
+		}
+		if step != test.expected {
+			t.Errorf("%v: expected %s got %s", test.targets, test.expected, step)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package graphite provides the Graphite origin type
+package graphite
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/proxy"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+var _ origins.Client = (*Client)(nil)
+
+// Client Implements the Proxy Client Interface
+type Client struct {
+	name               string
+	config             *oo.Options
+	cache              cache.Cache
+	webClient          *http.Client
+	handlers           map[string]http.Handler
+	handlersRegistered bool
+	baseUpstreamURL    *url.URL
+	healthURL          *url.URL
+	healthMethod       string
+	healthHeaders      http.Header
+	router             http.Handler
+}
+
+// NewClient returns a new Client Instance
+func NewClient(name string, oc *oo.Options, router http.Handler,
+	cache cache.Cache) (origins.Client, error) {
+	c, err := proxy.NewHTTPClient(oc)
+	bur := urls.FromParts(oc.Scheme, oc.Host, oc.PathPrefix, "", "")
+	// explicitly disable Fast Forward for this client
+	oc.FastForwardDisable = true
+	return &Client{name: name, config: oc, router: router, cache: cache,
+		baseUpstreamURL: bur, webClient: c}, err
+}
+
+// Configuration returns the upstream Configuration for this Client
+func (c *Client) Configuration() *oo.Options {
+	return c.config
+}
+
+// HTTPClient returns the HTTP Transport the client is using
+func (c *Client) HTTPClient() *http.Client {
+	return c.webClient
+}
+
+// Cache returns and handle to the Cache instance used by the Client
+func (c *Client) Cache() cache.Cache {
+	return c.cache
+}
+
+// Name returns the name of the upstream Configuration proxied by the Client
+func (c *Client) Name() string {
+	return c.name
+}
+
+// SetCache sets the Cache object the client will use for caching origin content
+func (c *Client) SetCache(cc cache.Cache) {
+	c.cache = cc
+}
+
+// Router returns the http.Handler that handles request routing for this Client
+func (c *Client) Router() http.Handler {
+	return c.router
+}
+
+// ParseTimeRangeQuery parses the key parts of a TimeRangeQuery from the inbound HTTP Request.
+// The from and until parameters are resolved into an absolute Extent, so that requests for
+// relative ranges (e.g., from=-1h) share cache entries, and the targets are joined into the
+// Statement. Requests for output formats other than json and raw are not time range queries
+func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery, error) {
+
+	v, _, _ := params.GetRequestValues(r)
+
+	targets := v[upTarget]
+	if len(targets) == 0 {
+		return nil, errors.MissingURLParam(upTarget)
+	}
+
+	if !isCacheableFormat(v.Get(upFormat)) || v.Get(upJSONP) != "" {
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+
+	loc := time.UTC
+	if tz := v.Get(upTZ); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, err
+		}
+		loc = l
+	}
+
+	now := time.Now()
+	from := v.Get(upFrom)
+	if from == "" {
+		from = defaultFrom
+	}
+	start, err := parseTime(from, now, loc)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseTime(v.Get(upUntil), now, loc)
+	if err != nil {
+		return nil, err
+	}
+	if !end.After(start) {
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+
+	step, err := targetsStep(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	trq := &timeseries.TimeRangeQuery{
+		Statement: strings.Join(targets, "\n"),
+		Extent:    timeseries.Extent{Start: start, End: end},
+		Step:      step,
+	}
+
+	// the template includes only the parameters that identify the query's results, with
+	// its targets combined into one value, so that each is represented in the cache key
+	qt := url.Values{upTarget: {trq.Statement}, upFormat: {v.Get(upFormat)}}
+	if p := v.Get(upNoNullPoints); p != "" {
+		qt.Set(upNoNullPoints, p)
+	}
+	trq.TemplateURL = urls.Clone(r.URL)
+	trq.TemplateURL.RawQuery = qt.Encode()
+
+	return trq, nil
+}
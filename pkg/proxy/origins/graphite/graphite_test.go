@@ -0,0 +1,228 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	cr "github.com/tricksterproxy/trickster/pkg/cache/registration"
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+)
+
+func TestGraphiteClientInterfacing(t *testing.T) {
+
+	// this test ensures the client will properly conform to the
+	// Client and TimeseriesClient interfaces
+
+	c := &Client{name: "test"}
+	var oc origins.Client = c
+	var tc origins.TimeseriesClient = c
+
+	if oc.Name() != "test" {
+		t.Errorf("expected %s got %s", "test", oc.Name())
+	}
+
+	if tc.Name() != "test" {
+		t.Errorf("expected %s got %s", "test", tc.Name())
+	}
+}
+
+func TestNewClient(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-type", "graphite", "-origin-url", "http://1"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := cr.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer cr.CloseCaches(caches)
+	cache, ok := caches["default"]
+	if !ok {
+		t.Errorf("Could not find default configuration")
+	}
+
+	oc := &oo.Options{OriginType: "TEST_CLIENT"}
+	c, err := NewClient("default", oc, nil, cache)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if c.Name() != "default" {
+		t.Errorf("expected %s got %s", "default", c.Name())
+	}
+
+	if c.Cache().Configuration().CacheType != "memory" {
+		t.Errorf("expected %s got %s", "memory", c.Cache().Configuration().CacheType)
+	}
+
+	if c.Configuration().OriginType != "TEST_CLIENT" {
+		t.Errorf("expected %s got %s", "TEST_CLIENT", c.Configuration().OriginType)
+	}
+}
+
+func TestConfiguration(t *testing.T) {
+	oc := &oo.Options{OriginType: "TEST"}
+	client := Client{config: oc}
+	c := client.Configuration()
+	if c.OriginType != "TEST" {
+		t.Errorf("expected %s got %s", "TEST", c.OriginType)
+	}
+}
+
+func TestCache(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test", []string{"-origin-type", "graphite", "-origin-url", "http://1"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := cr.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer cr.CloseCaches(caches)
+	cache, ok := caches["default"]
+	if !ok {
+		t.Errorf("Could not find default configuration")
+	}
+	client := Client{cache: cache}
+	c := client.Cache()
+
+	if c.Configuration().CacheType != "memory" {
+		t.Errorf("expected %s got %s", "memory", c.Configuration().CacheType)
+	}
+}
+
+func TestName(t *testing.T) {
+
+	client := Client{name: "TEST"}
+	c := client.Name()
+	if c != "TEST" {
+		t.Errorf("expected %s got %s", "TEST", c)
+	}
+
+}
+
+func TestRouter(t *testing.T) {
+	client := Client{name: "TEST"}
+	r := client.Router()
+	if r != nil {
+		t.Error("expected nil router")
+	}
+}
+
+func TestHTTPClient(t *testing.T) {
+	oc := &oo.Options{OriginType: "TEST"}
+
+	client, err := NewClient("test", oc, nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if client.HTTPClient() == nil {
+		t.Errorf("missing http client")
+	}
+}
+
+func TestSetCache(t *testing.T) {
+	c, err := NewClient("test", oo.NewOptions(), nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	c.SetCache(nil)
+	if c.Cache() != nil {
+		t.Errorf("expected nil cache for client named %s", "test")
+	}
+}
+
+func TestParseTimeRangeQuery(t *testing.T) {
+
+	client := &Client{}
+	v := url.Values{upTarget: {"summarize(a.b.*, '10min')", "c.d"}, upFormat: {formatJSON},
+		upFrom: {"-6h"}, upMaxDataPoints: {"1000"}}
+	r, _ := http.NewRequest(http.MethodGet, "http://blah.com/render?"+v.Encode(), nil)
+
+	trq, err := client.ParseTimeRangeQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq.Step != 10*time.Minute {
+		t.Errorf("expected %s got %s", 10*time.Minute, trq.Step)
+	}
+	if d := trq.Extent.End.Sub(trq.Extent.Start); d != 6*time.Hour {
+		t.Errorf("expected %s got %s", 6*time.Hour, d)
+	}
+	if trq.Statement != "summarize(a.b.*, '10min')\nc.d" {
+		t.Errorf("unexpected statement %s", trq.Statement)
+	}
+	qt := trq.TemplateURL.Query()
+	if len(qt) != 2 || qt.Get(upTarget) != trq.Statement || qt.Get(upFormat) != formatJSON {
+		t.Errorf("unexpected template params %v", qt)
+	}
+
+	// form-encoded POST requests are parsed the same
+	r, _ = http.NewRequest(http.MethodPost, "http://blah.com/render", strings.NewReader(v.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	trq2, err := client.ParseTimeRangeQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq2.Statement != trq.Statement || trq2.Step != trq.Step {
+		t.Errorf("unexpected time range query %s", trq2.String())
+	}
+
+	// absolute times are interpreted in the tz location
+	v = url.Values{upTarget: {"a.b"}, upFormat: {formatRaw}, upFrom: {"00:00_20200101"},
+		upUntil: {"20200102"}, upTZ: {"America/New_York"}}
+	r, _ = http.NewRequest(http.MethodGet, "http://blah.com/render?"+v.Encode(), nil)
+	trq, err = client.ParseTimeRangeQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq.Step != defaultStep {
+		t.Errorf("expected %s got %s", defaultStep, trq.Step)
+	}
+	if trq.Extent.Start.Unix() != 1577854800 || trq.Extent.End.Unix() != 1577941200 {
+		t.Errorf("unexpected extent %s", trq.Extent.String())
+	}
+
+	tests := []struct {
+		v   url.Values
+		err error
+	}{
+		{url.Values{upFormat: {formatJSON}}, errors.MissingURLParam(upTarget)},
+		{url.Values{upTarget: {"a.b"}}, errors.ErrNotTimeRangeQuery},
+		{url.Values{upTarget: {"a.b"}, upFormat: {"png"}}, errors.ErrNotTimeRangeQuery},
+		{url.Values{upTarget: {"a.b"}, upFormat: {formatJSON}, upJSONP: {"cb"}}, errors.ErrNotTimeRangeQuery},
+		{url.Values{upTarget: {"a.b"}, upFormat: {formatJSON}, upFrom: {"now"}, upUntil: {"-1h"}},
+			errors.ErrNotTimeRangeQuery},
+		{url.Values{upTarget: {"a.b"}, upFormat: {formatJSON}, upFrom: {"noon"}}, invalidTime("noon")},
+		{url.Values{upTarget: {"smartSummarize(a.b, '1h')"}, upFormat: {formatJSON}}, errors.ErrStepParse},
+	}
+	for _, test := range tests {
+		r, _ = http.NewRequest(http.MethodGet, "http://blah.com/render?"+test.v.Encode(), nil)
+		_, err = client.ParseTimeRangeQuery(r)
+		if err == nil || err.Error() != test.err.Error() {
+			t.Errorf("%v: expected %v got %v", test.v, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+)
+
+func TestRenderHandler(t *testing.T) {
+
+	client := &Client{name: "test"}
+	ts, w, r, hc, err := tu.NewTestInstance("", client.DefaultPathConfigs, 200, testJSON,
+		map[string]string{"Content-Type": "application/json"}, "graphite",
+		"/render?target=a.b&target=sumSeries(c.%7Bd,e%7D)&format=json&from=1577836740&until=1577836920",
+		"debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	ctx := r.Context()
+	rsc := request.GetResources(r)
+	rsc.OriginClient = client
+	client.config = rsc.OriginConfig
+	client.webClient = hc
+	client.config.HTTPClient = hc
+	client.baseUpstreamURL, _ = url.Parse(ts.URL)
+	// the test data is older than the oldest timestamp retained by the default eviction method
+	client.config.TimeseriesEvictionMethod = evictionmethods.EvictionMethodLRU
+
+	client.RenderHandler(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != 200 {
+		t.Errorf("expected 200 got %d.", resp.StatusCode)
+	}
+	if h := resp.Header.Get("X-Trickster-Result"); !strings.Contains(h, "engine=DeltaProxyCache") {
+		t.Errorf("expected delta proxy cache result, got %s", h)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != testJSON {
+		t.Errorf("expected %s got %s", testJSON, string(b))
+	}
+
+	// other output formats are proxied
+	r, _ = http.NewRequest(http.MethodGet, ts.URL+"/render?target=a.b&format=png", nil)
+	r = r.WithContext(ctx)
+	w = httptest.NewRecorder()
+	client.RenderHandler(w, r)
+	resp = w.Result()
+	if h := resp.Header.Get("X-Trickster-Result"); !strings.Contains(h, "engine=HTTPProxy") {
+		t.Errorf("expected proxy result, got %s", h)
+	}
+}
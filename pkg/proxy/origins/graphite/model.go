@@ -0,0 +1,247 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/sort/times"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// ErrInvalidRawSeries indicates a raw format render response line is malformed
+var ErrInvalidRawSeries = errors.New("invalid raw format series")
+
+// rawNull is the value of a null datapoint in the raw render format
+const rawNull = "None"
+
+// SeriesEnvelope represents a response object from the Graphite Render API
+type SeriesEnvelope struct {
+	Series       []*Series             `json:"series"`
+	Format       string                `json:"format"`
+	StepDuration time.Duration         `json:"step,omitempty"`
+	ExtentList   timeseries.ExtentList `json:"extents,omitempty"`
+
+	timestamps map[time.Time]bool // tracks unique timestamps in the series data
+	tslist     times.Times
+	isSorted   bool // tracks if the series data is currently sorted
+	isCounted  bool // tracks if timestamps slice is up-to-date
+
+	updateLock sync.Mutex
+}
+
+// Series represents a single series in a Graphite Render API response
+type Series struct {
+	Target     string            `json:"target"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Datapoints []Datapoint       `json:"datapoints"`
+	// StepSecs is the interval of the series' datapoints, as provided by the raw format
+	StepSecs int64 `json:"step,omitempty"`
+}
+
+// jsonSeries is the representation of a Series in the JSON render format
+type jsonSeries struct {
+	Target     string            `json:"target"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Datapoints []Datapoint       `json:"datapoints"`
+}
+
+// Datapoint represents a single datapoint of a Graphite series. A nil Value is a null datapoint
+type Datapoint struct {
+	Value     *float64
+	Timestamp int64
+}
+
+// MarshalJSON encodes the Datapoint as a [value, timestamp] tuple
+func (d Datapoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{d.Value, d.Timestamp})
+}
+
+// UnmarshalJSON decodes a [value, timestamp] tuple into the Datapoint
+func (d *Datapoint) UnmarshalJSON(b []byte) error {
+	var v []*json.Number
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if len(v) != 2 || v[1] == nil {
+		return fmt.Errorf("invalid graphite datapoint: %s", string(b))
+	}
+	ts, err := v[1].Float64()
+	if err != nil {
+		return err
+	}
+	d.Timestamp = int64(ts)
+	d.Value = nil
+	if v[0] != nil {
+		f, err := v[0].Float64()
+		if err != nil {
+			return err
+		}
+		d.Value = &f
+	}
+	return nil
+}
+
+// Time returns the timestamp of the Datapoint
+func (d Datapoint) Time() time.Time {
+	return time.Unix(d.Timestamp, 0)
+}
+
+// MarshalTimeseries converts a Timeseries into a JSON blob for Cache Storage when it has
+// extents, and otherwise into the render format of the original response for the client
+func (c Client) MarshalTimeseries(ts timeseries.Timeseries) ([]byte, error) {
+	se, ok := ts.(*SeriesEnvelope)
+	if !ok || len(se.ExtentList) > 0 {
+		return json.Marshal(ts)
+	}
+	if se.Format == formatRaw {
+		return se.marshalRaw(), nil
+	}
+	out := make([]jsonSeries, len(se.Series))
+	for i, s := range se.Series {
+		out[i] = jsonSeries{Target: s.Target, Tags: s.Tags, Datapoints: s.Datapoints}
+		if out[i].Datapoints == nil {
+			out[i].Datapoints = []Datapoint{}
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalTimeseries converts a cached JSON blob, or a json or raw format render
+// response, into a Timeseries
+func (c Client) UnmarshalTimeseries(data []byte) (timeseries.Timeseries, error) {
+	b := bytes.TrimSpace(data)
+	se := &SeriesEnvelope{}
+	switch {
+	case len(b) > 0 && b[0] == '{':
+		err := json.Unmarshal(b, se)
+		return se, err
+	case len(b) > 0 && b[0] == '[':
+		se.Format = formatJSON
+		err := json.Unmarshal(b, &se.Series)
+		return se, err
+	}
+	se.Format = formatRaw
+	err := se.unmarshalRaw(b)
+	return se, err
+}
+
+// unmarshalRaw decodes a raw format render response, made up of one line per series formatted
+// as target,start,end,step|value1,value2,...
+func (se *SeriesEnvelope) unmarshalRaw(b []byte) error {
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndex(line, "|")
+		if i < 0 {
+			return ErrInvalidRawSeries
+		}
+		// targets may contain commas, so the header is split from its end
+		header, values := line[:i], line[i+1:]
+		parts := make([]int64, 3)
+		for j := 2; j >= 0; j-- {
+			k := strings.LastIndex(header, ",")
+			if k < 0 {
+				return ErrInvalidRawSeries
+			}
+			v, err := strconv.ParseInt(header[k+1:], 10, 64)
+			if err != nil {
+				return ErrInvalidRawSeries
+			}
+			parts[j] = v
+			header = header[:k]
+		}
+		start, step := parts[0], parts[2]
+		if step <= 0 {
+			return ErrInvalidRawSeries
+		}
+		s := &Series{Target: header, StepSecs: step}
+		if values != "" {
+			vals := strings.Split(values, ",")
+			s.Datapoints = make([]Datapoint, len(vals))
+			for j, v := range vals {
+				s.Datapoints[j].Timestamp = start + int64(j)*step
+				if v == rawNull {
+					continue
+				}
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil {
+					return ErrInvalidRawSeries
+				}
+				s.Datapoints[j].Value = &f
+			}
+		}
+		se.Series = append(se.Series, s)
+	}
+	return nil
+}
+
+// marshalRaw encodes the Timeseries in the raw render format. Datapoints missing from
+// a series' interval grid are written as null values
+func (se *SeriesEnvelope) marshalRaw() []byte {
+	var buf bytes.Buffer
+	for _, s := range se.Series {
+		step := s.StepSecs
+		if step <= 0 {
+			step = int64(se.StepDuration.Seconds())
+		}
+		if step <= 0 {
+			step = int64(defaultStep.Seconds())
+		}
+		var start, end int64
+		if len(s.Datapoints) > 0 {
+			start = s.Datapoints[0].Timestamp
+			end = s.Datapoints[len(s.Datapoints)-1].Timestamp + step
+		}
+		fmt.Fprintf(&buf, "%s,%d,%d,%d|", s.Target, start, end, step)
+		j := 0
+		for ts := start; ts < end; ts += step {
+			if ts > start {
+				buf.WriteByte(',')
+			}
+			for j < len(s.Datapoints) && s.Datapoints[j].Timestamp < ts {
+				j++
+			}
+			if j < len(s.Datapoints) && s.Datapoints[j].Timestamp == ts && s.Datapoints[j].Value != nil {
+				buf.WriteString(formatRawValue(*s.Datapoints[j].Value))
+				continue
+			}
+			buf.WriteString(rawNull)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// formatRawValue formats a raw format value as Graphite does, with integral values
+// including a decimal (e.g., "1.0")
+func formatRawValue(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.ContainsAny(s, ".NI") {
+		s += ".0"
+	}
+	return s
+}
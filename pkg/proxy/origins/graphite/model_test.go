@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+const testJSON = `[{"target":"a.b","tags":{"name":"a.b"},"datapoints":[[1,1577836800],[null,1577836860],` +
+	`[2.5,1577836920]]},{"target":"sumSeries(c.{d,e})","datapoints":[[3,1577836800]]}]`
+
+const testRaw = "a.b,1577836800,1577836980,60|1.0,None,2.5\n" +
+	"sumSeries(c.{d,e}),1577836800,1577836860,60|3.0\n"
+
+func TestUnmarshalTimeseriesJSON(t *testing.T) {
+
+	client := &Client{}
+	ts, err := client.UnmarshalTimeseries([]byte(testJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	se := ts.(*SeriesEnvelope)
+	if se.Format != formatJSON {
+		t.Errorf("expected %s got %s", formatJSON, se.Format)
+	}
+	if len(se.Series) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(se.Series))
+	}
+	s := se.Series[0]
+	if s.Target != "a.b" || s.Tags["name"] != "a.b" || len(s.Datapoints) != 3 {
+		t.Errorf("unexpected series %v", s)
+	}
+	if s.Datapoints[1].Value != nil || *s.Datapoints[2].Value != 2.5 || s.Datapoints[2].Timestamp != 1577836920 {
+		t.Errorf("unexpected datapoints %v", s.Datapoints)
+	}
+
+	b, err := client.MarshalTimeseries(se)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != testJSON {
+		t.Errorf("expected %s got %s", testJSON, string(b))
+	}
+
+	if _, err = client.UnmarshalTimeseries([]byte(`[{"target":"a","datapoints":[[1]]}]`)); err == nil {
+		t.Error("expected error for invalid datapoint")
+	}
+}
+
+func TestUnmarshalTimeseriesRaw(t *testing.T) {
+
+	client := &Client{}
+	ts, err := client.UnmarshalTimeseries([]byte(testRaw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	se := ts.(*SeriesEnvelope)
+	if se.Format != formatRaw {
+		t.Errorf("expected %s got %s", formatRaw, se.Format)
+	}
+	if len(se.Series) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(se.Series))
+	}
+	s := se.Series[1]
+	if s.Target != "sumSeries(c.{d,e})" || s.StepSecs != 60 || len(s.Datapoints) != 1 ||
+		*s.Datapoints[0].Value != 3 {
+		t.Errorf("unexpected series %v", s)
+	}
+
+	b, err := client.MarshalTimeseries(se)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != testRaw {
+		t.Errorf("expected %s got %s", testRaw, string(b))
+	}
+
+	// datapoints missing from a merged series are marshaled as nulls
+	se.Series[0].Datapoints = append(se.Series[0].Datapoints[:1], se.Series[0].Datapoints[2])
+	b, _ = client.MarshalTimeseries(se)
+	if !strings.HasPrefix(string(b), "a.b,1577836800,1577836980,60|1.0,None,2.5\n") {
+		t.Errorf("unexpected raw output %s", string(b))
+	}
+
+	for _, raw := range []string{"a.b|1.0", "a.b,1,2,x|1.0", "a.b,1,2,0|1.0", "a.b,1,2,60|x"} {
+		if _, err = client.UnmarshalTimeseries([]byte(raw)); err != ErrInvalidRawSeries {
+			t.Errorf("%s: expected %v got %v", raw, ErrInvalidRawSeries, err)
+		}
+	}
+}
+
+func TestMarshalTimeseriesCache(t *testing.T) {
+
+	client := &Client{}
+	ts, _ := client.UnmarshalTimeseries([]byte(testRaw))
+	ts.SetStep(time.Minute)
+	ts.SetExtents(timeseries.ExtentList{{Start: time.Unix(1577836800, 0), End: time.Unix(1577836920, 0)}})
+
+	b, err := client.MarshalTimeseries(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), `{"series":`) {
+		t.Errorf("unexpected cache document %s", string(b))
+	}
+
+	ts2, err := client.UnmarshalTimeseries(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	se := ts2.(*SeriesEnvelope)
+	if se.Format != formatRaw || se.StepDuration != time.Minute || len(se.ExtentList) != 1 ||
+		se.Series[0].StepSecs != 60 || se.ValueCount() != 4 {
+		t.Errorf("unexpected timeseries %v", se)
+	}
+}
+
+func TestFormatRawValue(t *testing.T) {
+	tests := map[float64]string{1: "1.0", 2.5: "2.5", -3: "-3.0", 0.125: "0.125"}
+	for f, expected := range tests {
+		if v := formatRawValue(f); v != expected {
+			t.Errorf("expected %s got %s", expected, v)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/sort/times"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// SetExtents overwrites a Timeseries's known extents with the provided extent list
+func (se *SeriesEnvelope) SetExtents(extents timeseries.ExtentList) {
+	el := make(timeseries.ExtentList, len(extents))
+	copy(el, extents)
+	se.ExtentList = el
+	se.isCounted = false
+}
+
+// Extents returns the Timeseries's ExentList
+func (se *SeriesEnvelope) Extents() timeseries.ExtentList {
+	return se.ExtentList
+}
+
+// ValueCount returns the count of all datapoints across all series in the Timeseries
+func (se *SeriesEnvelope) ValueCount() int {
+	c := 0
+	for _, s := range se.Series {
+		c += len(s.Datapoints)
+	}
+	return c
+}
+
+// TimestampCount returns the count unique timestampes in across all series in the Timeseries
+func (se *SeriesEnvelope) TimestampCount() int {
+	se.updateTimestamps()
+	return len(se.timestamps)
+}
+
+func (se *SeriesEnvelope) updateTimestamps() {
+	if se.isCounted {
+		return
+	}
+	m := make(map[time.Time]bool)
+	for _, s := range se.Series {
+		for _, d := range s.Datapoints {
+			m[d.Time()] = true
+		}
+	}
+	se.timestamps = m
+	se.tslist = times.FromMap(m)
+	se.isCounted = true
+}
+
+// SeriesCount returns the count of all series in the Timeseries
+func (se *SeriesEnvelope) SeriesCount() int {
+	return len(se.Series)
+}
+
+// Step returns the step for the Timeseries
+func (se *SeriesEnvelope) Step() time.Duration {
+	return se.StepDuration
+}
+
+// SetStep sets the step for the Timeseries
+func (se *SeriesEnvelope) SetStep(step time.Duration) {
+	se.StepDuration = step
+}
+
+// seriesKeys returns the identity of each series, which is its target and its occurrence
+// among the series having the same target, since a render request may repeat a target
+func seriesKeys(series []*Series) []string {
+	keys := make([]string, len(series))
+	counts := make(map[string]int, len(series))
+	for i, s := range series {
+		keys[i] = s.Target + "." + strconv.Itoa(counts[s.Target])
+		counts[s.Target]++
+	}
+	return keys
+}
+
+// Merge merges the provided Timeseries list into the base Timeseries
+// (in the order provided) and optionally sorts the merged Timeseries
+func (se *SeriesEnvelope) Merge(sort bool, collection ...timeseries.Timeseries) {
+
+	se.updateLock.Lock()
+	defer se.updateLock.Unlock()
+
+	series := make(map[string]*Series, len(se.Series))
+	for i, k := range seriesKeys(se.Series) {
+		series[k] = se.Series[i]
+	}
+
+	for _, ts := range collection {
+		if ts == nil {
+			continue
+		}
+		se2 := ts.(*SeriesEnvelope)
+		for i, k := range seriesKeys(se2.Series) {
+			s := se2.Series[i]
+			if es, ok := series[k]; ok {
+				es.Datapoints = append(es.Datapoints, s.Datapoints...)
+				continue
+			}
+			ns := s.clone()
+			series[k] = ns
+			se.Series = append(se.Series, ns)
+		}
+		se.ExtentList = append(se.ExtentList, se2.ExtentList...)
+		if se.Format == "" {
+			se.Format = se2.Format
+		}
+	}
+
+	se.ExtentList = se.ExtentList.Compress(se.StepDuration)
+	se.isSorted = false
+	se.isCounted = false
+	if sort {
+		se.Sort()
+	}
+}
+
+// Clone returns a perfect copy of the base Timeseries
+func (se *SeriesEnvelope) Clone() timeseries.Timeseries {
+	se.updateLock.Lock()
+	defer se.updateLock.Unlock()
+	clone := &SeriesEnvelope{
+		Series:       make([]*Series, len(se.Series)),
+		Format:       se.Format,
+		StepDuration: se.StepDuration,
+		ExtentList:   se.ExtentList.Clone(),
+		timestamps:   make(map[time.Time]bool, len(se.timestamps)),
+		tslist:       make(times.Times, len(se.tslist)),
+		isCounted:    se.isCounted,
+		isSorted:     se.isSorted,
+	}
+	for k, v := range se.timestamps {
+		clone.timestamps[k] = v
+	}
+	copy(clone.tslist, se.tslist)
+	for i, s := range se.Series {
+		clone.Series[i] = s.clone()
+	}
+	return clone
+}
+
+func (s *Series) clone() *Series {
+	ns := &Series{
+		Target:     s.Target,
+		StepSecs:   s.StepSecs,
+		Datapoints: make([]Datapoint, len(s.Datapoints)),
+	}
+	if s.Tags != nil {
+		ns.Tags = make(map[string]string, len(s.Tags))
+		for k, v := range s.Tags {
+			ns.Tags[k] = v
+		}
+	}
+	for i, d := range s.Datapoints {
+		ns.Datapoints[i].Timestamp = d.Timestamp
+		if d.Value != nil {
+			v := *d.Value
+			ns.Datapoints[i].Value = &v
+		}
+	}
+	return ns
+}
+
+// CropToSize reduces the number of elements in the Timeseries to the provided count, by evicting elements
+// using a least-recently-used methodology. The time parameter limits the upper extent to the provided time,
+// in order to support backfill tolerance
+func (se *SeriesEnvelope) CropToSize(sz int, t time.Time, lur timeseries.Extent) {
+
+	se.isCounted = false
+	se.isSorted = false
+	x := len(se.ExtentList)
+	// The Series has no extents, so no need to do anything
+	if x < 1 {
+		se.Series = []*Series{}
+		se.ExtentList = timeseries.ExtentList{}
+		return
+	}
+
+	// Crop to the Backfill Tolerance Value if needed
+	if se.ExtentList[x-1].End.After(t) {
+		se.CropToRange(timeseries.Extent{Start: se.ExtentList[0].Start, End: t})
+	}
+
+	tc := se.TimestampCount()
+	if len(se.Series) == 0 || tc <= sz {
+		return
+	}
+
+	el := timeseries.ExtentListLRU(se.ExtentList).UpdateLastUsed(lur, se.StepDuration)
+	sort.Sort(el)
+
+	rc := tc - sz // # of required timestamps we must delete to meet the rentention policy
+	removals := make(map[time.Time]bool)
+	done := false
+	var ok bool
+
+	for _, x := range el {
+		for ts := x.Start; !x.End.Before(ts) && !done; ts = ts.Add(se.StepDuration) {
+			if _, ok = se.timestamps[ts]; ok {
+				removals[ts] = true
+				done = len(removals) >= rc
+			}
+		}
+		if done {
+			break
+		}
+	}
+
+	for _, s := range se.Series {
+		tmp := s.Datapoints[:0]
+		for _, d := range s.Datapoints {
+			if !removals[d.Time()] {
+				tmp = append(tmp, d)
+			}
+		}
+		s.Datapoints = tmp
+	}
+	se.removeEmptySeries()
+
+	tl := times.FromMap(removals)
+	sort.Sort(tl)
+	for _, t := range tl {
+		for i, e := range el {
+			if e.StartsAt(t) {
+				el[i].Start = e.Start.Add(se.StepDuration)
+			}
+		}
+	}
+
+	se.ExtentList = timeseries.ExtentList(el).Compress(se.StepDuration)
+	se.Sort()
+}
+
+// CropToRange reduces the Timeseries down to timestamps contained within the provided Extents (inclusive).
+func (se *SeriesEnvelope) CropToRange(e timeseries.Extent) {
+	se.isCounted = false
+	x := len(se.ExtentList)
+	// The Series has no extents, or its extents are entirely outside of the crop range,
+	// so return an empty set
+	if x < 1 || se.ExtentList.OutsideOf(e) {
+		se.Series = []*Series{}
+		se.ExtentList = timeseries.ExtentList{}
+		return
+	}
+
+	for _, s := range se.Series {
+		tmp := s.Datapoints[:0]
+		for _, d := range s.Datapoints {
+			if t := d.Time(); !t.Before(e.Start) && !t.After(e.End) {
+				tmp = append(tmp, d)
+			}
+		}
+		s.Datapoints = tmp
+	}
+	se.removeEmptySeries()
+	se.ExtentList = se.ExtentList.Crop(e)
+}
+
+// removeEmptySeries removes the series having no datapoints from the Timeseries
+func (se *SeriesEnvelope) removeEmptySeries() {
+	tmp := se.Series[:0]
+	for _, s := range se.Series {
+		if len(s.Datapoints) > 0 {
+			tmp = append(tmp, s)
+		}
+	}
+	se.Series = tmp
+}
+
+// Sort sorts all datapoints in each series chronologically by their timestamp, and removes
+// datapoints having duplicate timestamps, preferring the first datapoint having a value. This
+// retains the cached value of the datapoint that precedes each merged delta, which Graphite
+// may return as null, since functions like perSecond() cannot calculate it
+func (se *SeriesEnvelope) Sort() {
+
+	if se.isSorted || len(se.Series) == 0 {
+		return
+	}
+
+	tsm := map[time.Time]bool{}
+	for _, s := range se.Series {
+		m := make(map[int64]Datapoint, len(s.Datapoints))
+		keys := make([]int64, 0, len(s.Datapoints))
+		for _, d := range s.Datapoints {
+			if ed, ok := m[d.Timestamp]; ok {
+				if ed.Value == nil && d.Value != nil {
+					m[d.Timestamp] = d
+				}
+				continue
+			}
+			keys = append(keys, d.Timestamp)
+			m[d.Timestamp] = d
+			tsm[d.Time()] = true
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		dps := make([]Datapoint, 0, len(keys))
+		for _, k := range keys {
+			dps = append(dps, m[k])
+		}
+		s.Datapoints = dps
+	}
+
+	sort.Sort(se.ExtentList)
+
+	se.timestamps = tsm
+	se.tslist = times.FromMap(tsm)
+	se.isCounted = true
+	se.isSorted = true
+}
+
+// Size returns the approximate memory utilization in bytes of the timeseries
+func (se *SeriesEnvelope) Size() int {
+	c := 24 + // .stepDuration
+		len(se.Format) +
+		se.ExtentList.Size() + // time.Time (24) * 3
+		(25 * len(se.timestamps)) + // time.Time (24) + bool(1)
+		(24 * len(se.tslist)) + // time.Time (24)
+		2 // .isSorted + .isCounted
+	for _, s := range se.Series {
+		c += len(s.Target) + 8 + // .StepSecs
+			(24 * len(s.Datapoints)) // *float64 (8) + float64 (8) + int64 (8)
+		for k, v := range s.Tags {
+			c += len(k) + len(v)
+		}
+	}
+	return c
+}
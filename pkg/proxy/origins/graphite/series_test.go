@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func testValue(f float64) *float64 {
+	return &f
+}
+
+func testEnvelope(start, end int64, targets ...string) *SeriesEnvelope {
+	se := &SeriesEnvelope{Format: formatJSON, StepDuration: time.Minute,
+		ExtentList: timeseries.ExtentList{{Start: time.Unix(start, 0), End: time.Unix(end, 0)}}}
+	for _, target := range targets {
+		s := &Series{Target: target}
+		for ts := start; ts <= end; ts += 60 {
+			s.Datapoints = append(s.Datapoints, Datapoint{Value: testValue(float64(ts)), Timestamp: ts})
+		}
+		se.Series = append(se.Series, s)
+	}
+	return se
+}
+
+func TestMerge(t *testing.T) {
+
+	se := testEnvelope(1577836800, 1577837040, "a", "b")
+	se2 := testEnvelope(1577837100, 1577837220, "b", "c")
+	// the datapoint preceding the merged range is null, as perSecond() would produce
+	se2.Series[0].Datapoints = append([]Datapoint{{Timestamp: 1577837040}}, se2.Series[0].Datapoints...)
+
+	se.Merge(true, se2, nil)
+
+	if len(se.Series) != 3 {
+		t.Fatalf("expected %d got %d", 3, len(se.Series))
+	}
+	if len(se.ExtentList) != 1 || se.ExtentList[0].End.Unix() != 1577837220 {
+		t.Errorf("unexpected extents %s", se.ExtentList.String())
+	}
+	b := se.Series[1]
+	if b.Target != "b" || len(b.Datapoints) != 8 {
+		t.Fatalf("unexpected series %v", b)
+	}
+	if b.Datapoints[4].Value == nil || *b.Datapoints[4].Value != 1577837040 {
+		t.Errorf("expected the cached value to be retained, got %v", b.Datapoints[4])
+	}
+	for i := 1; i < len(b.Datapoints); i++ {
+		if b.Datapoints[i].Timestamp <= b.Datapoints[i-1].Timestamp {
+			t.Errorf("expected sorted datapoints, got %v", b.Datapoints)
+		}
+	}
+	if se.Series[2].Target != "c" || se.TimestampCount() != 8 {
+		t.Errorf("unexpected series %v", se.Series[2])
+	}
+}
+
+func TestMergeRepeatedTargets(t *testing.T) {
+
+	se := testEnvelope(1577836800, 1577836860, "a", "a")
+	se.Series[1].Datapoints[0].Value = testValue(-1)
+	se2 := testEnvelope(1577836920, 1577836980, "a", "a")
+	se2.Series[1].Datapoints[0].Value = testValue(-2)
+
+	se.Merge(true, se2)
+	if len(se.Series) != 2 || len(se.Series[0].Datapoints) != 4 || len(se.Series[1].Datapoints) != 4 {
+		t.Fatalf("unexpected series %v", se.Series)
+	}
+	if *se.Series[1].Datapoints[2].Value != -2 || *se.Series[0].Datapoints[2].Value != 1577836920 {
+		t.Errorf("expected repeated targets to merge in order, got %v", se.Series)
+	}
+}
+
+func TestClone(t *testing.T) {
+
+	se := testEnvelope(1577836800, 1577836980, "a")
+	se.Series[0].Tags = map[string]string{"name": "a"}
+	se.Sort()
+
+	clone := se.Clone().(*SeriesEnvelope)
+	*clone.Series[0].Datapoints[0].Value = 0
+	clone.Series[0].Tags["name"] = "b"
+
+	if *se.Series[0].Datapoints[0].Value != 1577836800 || se.Series[0].Tags["name"] != "a" {
+		t.Error("expected clone to be independent of the original")
+	}
+	if clone.Format != se.Format || clone.StepDuration != se.StepDuration ||
+		clone.TimestampCount() != se.TimestampCount() || len(clone.ExtentList) != 1 {
+		t.Errorf("unexpected clone %v", clone)
+	}
+}
+
+func TestCropToRange(t *testing.T) {
+
+	se := testEnvelope(1577836800, 1577837340, "a", "b")
+	se.Series[1].Datapoints = se.Series[1].Datapoints[:2]
+
+	se.CropToRange(timeseries.Extent{Start: time.Unix(1577837040, 0), End: time.Unix(1577837100, 0)})
+	if len(se.Series) != 1 || len(se.Series[0].Datapoints) != 2 {
+		t.Fatalf("unexpected series %v", se.Series)
+	}
+	if se.ValueCount() != 2 || se.ExtentList[0].Start.Unix() != 1577837040 {
+		t.Errorf("unexpected timeseries %v", se)
+	}
+
+	se.CropToRange(timeseries.Extent{Start: time.Unix(1577840000, 0), End: time.Unix(1577841000, 0)})
+	if len(se.Series) != 0 || len(se.ExtentList) != 0 {
+		t.Errorf("expected empty timeseries, got %v", se)
+	}
+}
+
+func TestCropToSize(t *testing.T) {
+
+	se := testEnvelope(1577836800, 1577837340, "a", "b")
+	now := time.Unix(1577840000, 0)
+
+	se.CropToSize(20, now, se.ExtentList[0])
+	if se.TimestampCount() != 10 {
+		t.Errorf("expected %d got %d", 10, se.TimestampCount())
+	}
+
+	se.CropToSize(4, now, se.ExtentList[0])
+	if se.TimestampCount() != 4 || se.ValueCount() != 8 {
+		t.Errorf("expected %d got %d", 4, se.TimestampCount())
+	}
+	if se.ExtentList[0].Start.Unix() != 1577837160 || se.Series[0].Datapoints[0].Timestamp != 1577837160 {
+		t.Errorf("expected oldest datapoints to be removed, got %s", se.ExtentList.String())
+	}
+
+	// datapoints after the backfill tolerance time are removed
+	se.CropToSize(10, time.Unix(1577837220, 0), se.ExtentList[0])
+	if se.TimestampCount() != 2 {
+		t.Errorf("expected %d got %d", 2, se.TimestampCount())
+	}
+
+	se.ExtentList = nil
+	se.CropToSize(10, now, timeseries.Extent{})
+	if len(se.Series) != 0 {
+		t.Errorf("expected empty timeseries, got %v", se)
+	}
+}
+
+func TestSize(t *testing.T) {
+	se := testEnvelope(1577836800, 1577836980, "a")
+	if se.Size() <= 0 {
+		t.Error("expected positive size")
+	}
+	if se.SeriesCount() != 1 || se.Step() != time.Minute {
+		t.Errorf("unexpected timeseries %v", se)
+	}
+	se.SetStep(time.Hour)
+	if se.Step() != time.Hour {
+		t.Errorf("expected %s got %s", time.Hour, se.Step())
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// Common URL Parameter Names
+const (
+	upTarget        = "target"
+	upFrom          = "from"
+	upUntil         = "until"
+	upFormat        = "format"
+	upTZ            = "tz"
+	upJSONP         = "jsonp"
+	upNoNullPoints  = "noNullPoints"
+	upMaxDataPoints = "maxDataPoints"
+)
+
+// Common Graphite API Paths
+const (
+	mnRender  = "/render"
+	mnMetrics = "/metrics/"
+)
+
+// Render API output formats that are cached as timeseries
+const (
+	formatJSON = "json"
+	formatRaw  = "raw"
+)
+
+// isCacheableFormat returns true if the render output format is handled as a timeseries
+func isCacheableFormat(format string) bool {
+	return format == formatJSON || format == formatRaw
+}
+
+// SetExtent will change the upstream request query to use the provided Extent
+func (c *Client) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery, extent *timeseries.Extent) {
+
+	if extent == nil || r == nil || trq == nil {
+		return
+	}
+
+	v, _, _ := params.GetRequestValues(r)
+	// Graphite returns the datapoints after from, through until. from is moved back by a step, so
+	// the response includes the datapoint at the start of the extent, as well as the one prior,
+	// which functions like perSecond() need in order to calculate the extent's first datapoint
+	v.Set(upFrom, strconv.FormatInt(extent.Start.Add(-trq.Step).Unix()-1, 10))
+	v.Set(upUntil, strconv.FormatInt(extent.End.Unix(), 10))
+	// maxDataPoints consolidates datapoints according to the requested range, which would
+	// make the resolution of cached data depend on the range of the delta that fetched it
+	v.Del(upMaxDataPoints)
+	params.SetRequestValues(r, v)
+}
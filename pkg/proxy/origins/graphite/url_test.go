@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package graphite
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestSetExtent(t *testing.T) {
+
+	client := &Client{}
+	v := url.Values{upTarget: {"a.b"}, upFormat: {formatJSON}, upFrom: {"-1h"}, upMaxDataPoints: {"100"}}
+	r, _ := http.NewRequest(http.MethodGet, "http://blah.com/render?"+v.Encode(), nil)
+	trq := &timeseries.TimeRangeQuery{Step: time.Minute}
+	e := &timeseries.Extent{Start: time.Unix(1577833200, 0), End: time.Unix(1577836800, 0)}
+
+	client.SetExtent(r, trq, e)
+	q := r.URL.Query()
+	if q.Get(upFrom) != "1577833139" {
+		t.Errorf("expected %s got %s", "1577833139", q.Get(upFrom))
+	}
+	if q.Get(upUntil) != "1577836800" {
+		t.Errorf("expected %s got %s", "1577836800", q.Get(upUntil))
+	}
+	if _, ok := q[upMaxDataPoints]; ok {
+		t.Errorf("expected %s to be removed", upMaxDataPoints)
+	}
+	if q.Get(upTarget) != "a.b" {
+		t.Errorf("expected %s got %s", "a.b", q.Get(upTarget))
+	}
+
+	// form-encoded POST requests have their bodies updated
+	r, _ = http.NewRequest(http.MethodPost, "http://blah.com/render", strings.NewReader(v.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client.SetExtent(r, trq, e)
+	b, _ := ioutil.ReadAll(r.Body)
+	if r.ContentLength != int64(len(b)) {
+		t.Errorf("expected %d got %d", len(b), r.ContentLength)
+	}
+	q, _ = url.ParseQuery(string(b))
+	if q.Get(upFrom) != "1577833139" || q.Get(upUntil) != "1577836800" {
+		t.Errorf("unexpected body %s", string(b))
+	}
+
+	// nil extents are ignored
+	client.SetExtent(r, trq, nil)
+}
+
+func TestIsCacheableFormat(t *testing.T) {
+	for _, f := range []string{formatJSON, formatRaw} {
+		if !isCacheableFormat(f) {
+			t.Errorf("expected true for %s", f)
+		}
+	}
+	for _, f := range []string{"", "png", "csv", "pickle"} {
+		if isCacheableFormat(f) {
+			t.Errorf("expected false for %s", f)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package grpcpassthrough provides the gRPC Passthrough Origin Type, which proxies
+// HTTP/2 gRPC requests, including bidirectional streams, to the origin without caching
+package grpcpassthrough
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/proxy"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+
+	"golang.org/x/net/http2"
+)
+
+var _ origins.Client = (*Client)(nil)
+
+// Client Implements the Proxy Client Interface
+type Client struct {
+	name               string
+	config             *oo.Options
+	cache              cache.Cache
+	webClient          *http.Client
+	handlers           map[string]http.Handler
+	handlersRegistered bool
+	baseUpstreamURL    *url.URL
+	router             http.Handler
+	reverseProxy       *httputil.ReverseProxy
+}
+
+// NewClient returns a new Client Instance
+func NewClient(name string, oc *oo.Options, router http.Handler,
+	cache cache.Cache) (origins.Client, error) {
+	c, err := proxy.NewHTTPClient(oc)
+	if err != nil {
+		return nil, err
+	}
+	bur := urls.FromParts(oc.Scheme, oc.Host, oc.PathPrefix, "", "")
+	client := &Client{name: name, config: oc, router: router, cache: cache,
+		webClient: c, baseUpstreamURL: bur}
+	if c != nil {
+		transport := c.Transport
+		if oc.Scheme == "http" {
+			// an http origin is reached with cleartext HTTP/2 (h2c) using prior knowledge
+			transport = newH2CTransport(oc)
+		} else if t, ok := c.Transport.(*http.Transport); ok {
+			// gRPC requires HTTP/2 to the origin, which is negotiated over TLS
+			t.ForceAttemptHTTP2 = true
+		}
+		client.reverseProxy = &httputil.ReverseProxy{
+			Director: client.direct,
+			// responses are flushed immediately so that server streams are not buffered
+			FlushInterval: -1,
+			Transport:     transport,
+		}
+	}
+	return client, nil
+}
+
+// newH2CTransport returns an HTTP/2 transport that connects to the origin without TLS
+func newH2CTransport(oc *oo.Options) http.RoundTripper {
+	return &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.DialTimeout(network, addr, oc.Timeout)
+		},
+	}
+}
+
+// direct rewrites the inbound request for delivery to the origin
+func (c *Client) direct(r *http.Request) {
+	r.URL = urls.BuildUpstreamURL(r, c.baseUpstreamURL)
+	r.Host = c.baseUpstreamURL.Host
+}
+
+// Configuration returns the upstream Configuration for this Client
+func (c *Client) Configuration() *oo.Options {
+	return c.config
+}
+
+// HTTPClient returns the HTTP Transport the client is using
+func (c *Client) HTTPClient() *http.Client {
+	return c.webClient
+}
+
+// Cache returns and handle to the Cache instance used by the Client
+func (c *Client) Cache() cache.Cache {
+	return c.cache
+}
+
+// Name returns the name of the upstream Configuration proxied by the Client
+func (c *Client) Name() string {
+	return c.name
+}
+
+// SetCache sets the Cache object the client will use when caching origin content
+func (c *Client) SetCache(cc cache.Cache) {
+	c.cache = cc
+}
+
+// Router returns the http.Handler that handles request routing for this Client
+func (c *Client) Router() http.Handler {
+	return c.router
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcpassthrough
+
+import (
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+func TestGRPCPassthroughClientInterfacing(t *testing.T) {
+
+	// this test ensures the client will properly conform to the
+	// Client interface
+
+	c := &Client{name: "test"}
+	var oc origins.Client = c
+
+	if oc.Name() != "test" {
+		t.Errorf("expected %s got %s", "test", oc.Name())
+	}
+
+}
+
+func TestNewClient(t *testing.T) {
+	c, err := NewClient("test", oo.NewOptions(), nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if c == nil {
+		t.Fatalf("expected client named %s", "test")
+	}
+	if c.HTTPClient() == nil {
+		t.Errorf("expected HTTPClient for gRPC Passthrough client named %s", "test")
+	}
+	if c.Configuration() == nil {
+		t.Error("expected non-nil configuration")
+	}
+	c.SetCache(nil)
+	if c.Cache() != nil {
+		t.Error("expected nil cache")
+	}
+	if c.Router() != nil {
+		t.Error("expected nil router")
+	}
+}
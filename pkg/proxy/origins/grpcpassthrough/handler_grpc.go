@@ -0,0 +1,36 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcpassthrough
+
+import (
+	"net/http"
+)
+
+// GRPCHandler proxies the inbound gRPC request to the configured origin, streaming
+// the request and response bodies and passing through any trailers
+func (c *Client) GRPCHandler(w http.ResponseWriter, r *http.Request) {
+	if c.reverseProxy == nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	if r.ProtoMajor != 2 {
+		// gRPC is only defined over HTTP/2
+		w.WriteHeader(http.StatusHTTPVersionNotSupported)
+		return
+	}
+	c.reverseProxy.ServeHTTP(w, r)
+}
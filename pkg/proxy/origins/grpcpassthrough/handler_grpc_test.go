@@ -0,0 +1,129 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcpassthrough
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestGRPCHandler(t *testing.T) {
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	oc := oo.NewOptions()
+	oc.Scheme = u.Scheme
+	oc.Host = u.Host
+	oc.TLS = &to.Options{InsecureSkipVerify: true}
+
+	c, err := NewClient("test", oc, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := c.(*Client)
+
+	// HTTP/1.x requests are rejected
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://0/pkg.Service/Method", strings.NewReader("data"))
+	client.GRPCHandler(w, r)
+	if w.Code != http.StatusHTTPVersionNotSupported {
+		t.Errorf("expected %d got %d", http.StatusHTTPVersionNotSupported, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "http://0/pkg.Service/Method", strings.NewReader("data"))
+	r.ProtoMajor, r.ProtoMinor, r.Proto = 2, 0, "HTTP/2.0"
+	r.Header.Set("Content-Type", "application/grpc")
+	r.Header.Set("Te", "trailers")
+	client.GRPCHandler(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	if string(b) != "data" {
+		t.Errorf("expected %s got %s", "data", string(b))
+	}
+	if v := resp.Trailer.Get("Grpc-Status"); v != "0" {
+		t.Errorf("expected %s got %s", "0", v)
+	}
+
+	// a client without a reverse proxy responds with a bad gateway
+	w = httptest.NewRecorder()
+	(&Client{}).GRPCHandler(w, r)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("expected %d got %d", http.StatusBadGateway, w.Code)
+	}
+}
+
+func TestGRPCHandlerH2C(t *testing.T) {
+
+	ts := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusHTTPVersionNotSupported)
+			return
+		}
+		w.Header().Set("Content-Type", "application/grpc")
+		w.WriteHeader(http.StatusOK)
+	}), &http2.Server{}))
+	defer ts.Close()
+
+	u, _ := url.Parse(ts.URL)
+	oc := oo.NewOptions()
+	oc.Scheme = u.Scheme
+	oc.Host = u.Host
+
+	c, err := NewClient("test", oc, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://0/pkg.Service/Method", strings.NewReader("data"))
+	r.ProtoMajor, r.ProtoMinor, r.Proto = 2, 0, "HTTP/2.0"
+	r.Header.Set("Content-Type", "application/grpc")
+	c.(*Client).GRPCHandler(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+}
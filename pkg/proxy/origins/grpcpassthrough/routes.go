@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcpassthrough
+
+import (
+	"net/http"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+)
+
+func (c *Client) registerHandlers() {
+	c.handlersRegistered = true
+	c.handlers = make(map[string]http.Handler)
+	// This is the registry of handlers that Trickster supports for gRPC Passthrough,
+	// and are able to be referenced by name (map key) in Config Files
+	c.handlers["grpc"] = http.HandlerFunc(c.GRPCHandler)
+}
+
+// Handlers returns a map of the HTTP Handlers the client has registered
+func (c *Client) Handlers() map[string]http.Handler {
+	if !c.handlersRegistered {
+		c.registerHandlers()
+	}
+	return c.handlers
+}
+
+// DefaultPathConfigs returns the default PathConfigs for the given OriginType
+func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
+	// gRPC calls are always made via POST
+	paths := map[string]*po.Options{
+		"/-" + http.MethodPost: {
+			Path:          "/",
+			HandlerName:   "grpc",
+			Methods:       []string{http.MethodPost},
+			MatchType:     matching.PathMatchTypePrefix,
+			MatchTypeName: "prefix",
+			// the metrics decorator's response writer does not support flushing, which streams require
+			NoMetrics: true,
+		},
+	}
+	return paths
+}
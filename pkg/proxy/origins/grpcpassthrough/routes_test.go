@@ -0,0 +1,43 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grpcpassthrough
+
+import (
+	"net/http"
+	"testing"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+func TestRegisterHandlers(t *testing.T) {
+	c, _ := NewClient("test", oo.NewOptions(), nil, nil)
+	if _, ok := c.Handlers()["grpc"]; !ok {
+		t.Errorf("expected to find handler named: %s", "grpc")
+	}
+}
+
+func TestDefaultPathConfigs(t *testing.T) {
+	c, _ := NewClient("test", oo.NewOptions(), nil, nil)
+	dpc := c.DefaultPathConfigs(oo.NewOptions())
+	p, ok := dpc["/-"+http.MethodPost]
+	if !ok {
+		t.Fatalf("expected to find path named: %s", "/-"+http.MethodPost)
+	}
+	if p.HandlerName != "grpc" {
+		t.Errorf("expected %s got %s", "grpc", p.HandlerName)
+	}
+}
@@ -0,0 +1,250 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/sort/times"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	str "github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// ErrFluxTimeColumnMissing indicates a table in a Flux query result has no _time column
+var ErrFluxTimeColumnMissing = errors.New("flux table has no _time column")
+
+// Flux annotated CSV column names
+const (
+	fcResult = "result"
+	fcTable  = "table"
+	fcStart  = "_start"
+	fcStop   = "_stop"
+	fcTime   = "_time"
+	fcValue  = "_value"
+	fcError  = "error"
+)
+
+// fluxCachePrefix is the prefix of a FluxEnvelope that has been marshaled for cache storage,
+// which distinguishes it from an InfluxDB 1.x response and from annotated CSV
+var fluxCachePrefix = []byte(`{"tables":`)
+
+// FluxEnvelope represents a response to a Flux query from the InfluxDB 2.x HTTP API,
+// which is formatted as annotated CSV
+type FluxEnvelope struct {
+	Tables       []*FluxTable          `json:"tables"`
+	StepDuration time.Duration         `json:"step,omitempty"`
+	ExtentList   timeseries.ExtentList `json:"extents,omitempty"`
+
+	timestamps map[time.Time]bool // tracks unique timestamps in the table data
+	tslist     times.Times
+	isSorted   bool // tracks if the table data is currently sorted
+	isCounted  bool // tracks if timestamps slice is up-to-date
+
+	updateLock sync.Mutex
+}
+
+// FluxTable represents a table in a Flux query result
+type FluxTable struct {
+	// Annotations are the annotation rows (e.g., #datatype, #group and #default) of the table
+	Annotations [][]string `json:"annotations,omitempty"`
+	// Columns is the header row of the table
+	Columns []string `json:"columns"`
+	// Rows are the data rows of the table
+	Rows [][]string `json:"rows"`
+}
+
+// unmarshalFlux converts an annotated CSV Flux query result into a FluxEnvelope. Each run of
+// rows sharing a value in the table column is a table, and tables are separated by the empty
+// line, annotations and header row that precede a change in schema
+func unmarshalFlux(data []byte) (*FluxEnvelope, error) {
+	fe := &FluxEnvelope{}
+	blocks := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n\n")
+	for _, block := range blocks {
+		if strings.TrimSpace(block) == "" {
+			continue
+		}
+		cr := csv.NewReader(strings.NewReader(block))
+		cr.FieldsPerRecord = -1
+		records, err := cr.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+
+		var annotations [][]string
+		var columns []string
+		var t *FluxTable
+		ti, id := -1, ""
+		for _, rec := range records {
+			if columns == nil {
+				if len(rec) > 0 && strings.HasPrefix(rec[0], "#") {
+					annotations = append(annotations, rec)
+					continue
+				}
+				columns = rec
+				if str.IndexOfString(columns, fcTime) < 0 {
+					if str.IndexOfString(columns, fcError) >= 0 {
+						return nil, fluxError(columns, records)
+					}
+					return nil, ErrFluxTimeColumnMissing
+				}
+				ti = str.IndexOfString(columns, fcTable)
+				continue
+			}
+			if t == nil || (ti >= 0 && ti < len(rec) && rec[ti] != id) {
+				t = &FluxTable{Annotations: annotations, Columns: columns}
+				fe.Tables = append(fe.Tables, t)
+				if ti >= 0 && ti < len(rec) {
+					id = rec[ti]
+				}
+			}
+			t.Rows = append(t.Rows, rec)
+		}
+	}
+	return fe, nil
+}
+
+// fluxError returns the error reported by the error table of a Flux query result
+func fluxError(columns []string, records [][]string) error {
+	i := str.IndexOfString(columns, fcError)
+	for _, rec := range records {
+		if len(rec) > i && !strings.HasPrefix(rec[0], "#") && rec[i] != fcError && rec[i] != "" {
+			return errors.New(rec[i])
+		}
+	}
+	return errors.New("flux query error")
+}
+
+// marshalCSV converts the FluxEnvelope into an annotated CSV Flux query result. Tables are
+// renumbered in order within each result, and empty tables are omitted
+func (fe *FluxEnvelope) marshalCSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.UseCRLF = true
+
+	ids := make(map[string]int)
+	var prev *FluxTable
+	for _, t := range fe.Tables {
+		if len(t.Rows) == 0 {
+			continue
+		}
+		if prev == nil || !reflect.DeepEqual(prev.Columns, t.Columns) ||
+			!reflect.DeepEqual(prev.Annotations, t.Annotations) {
+			if prev != nil {
+				w.Flush()
+				buf.WriteString("\r\n")
+			}
+			w.WriteAll(t.Annotations)
+			w.Write(t.Columns)
+		}
+		r := t.result()
+		id := strconv.Itoa(ids[r])
+		ids[r]++
+		ti := str.IndexOfString(t.Columns, fcTable)
+		for _, row := range t.Rows {
+			if ti >= 0 && ti < len(row) {
+				nr := make([]string, len(row))
+				copy(nr, row)
+				nr[ti] = id
+				row = nr
+			}
+			w.Write(row)
+		}
+		prev = t
+	}
+	w.Flush()
+	if prev != nil {
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes(), w.Error()
+}
+
+// result returns the name of the result the table belongs to
+func (t *FluxTable) result() string {
+	i := str.IndexOfString(t.Columns, fcResult)
+	if i < 0 {
+		return ""
+	}
+	if len(t.Rows) > 0 && i < len(t.Rows[0]) && t.Rows[0][i] != "" {
+		return t.Rows[0][i]
+	}
+	for _, a := range t.Annotations {
+		if a[0] == "#default" && i < len(a) {
+			return a[i]
+		}
+	}
+	return ""
+}
+
+// groupColumns returns the indexes of the table's group key columns, which are provided by the
+// #group annotation, or are otherwise its tag, measurement and field columns
+func (t *FluxTable) groupColumns() []int {
+	var out []int
+	for _, a := range t.Annotations {
+		if a[0] != "#group" {
+			continue
+		}
+		for i := 1; i < len(a) && i < len(t.Columns); i++ {
+			if a[i] == "true" {
+				out = append(out, i)
+			}
+		}
+		return out
+	}
+	for i, c := range t.Columns {
+		switch c {
+		case "", fcResult, fcTable, fcStart, fcStop, fcTime, fcValue:
+			continue
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+// key returns the identity of the table, which is its result, its columns and the values of its
+// group key, except for _start and _stop, which are the bounds of each fetched range
+func (t *FluxTable) key() string {
+	parts := []string{t.result(), strings.Join(t.Columns, ",")}
+	if len(t.Rows) > 0 {
+		for _, i := range t.groupColumns() {
+			if c := t.Columns[i]; c == fcStart || c == fcStop || i >= len(t.Rows[0]) {
+				continue
+			}
+			parts = append(parts, t.Columns[i]+"="+t.Rows[0][i])
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// rowTime returns the timestamp of the row in the provided _time column. It is returned in the
+// local time zone, like the times in Extents, so that they can be compared as map keys
+func rowTime(row []string, ti int) (time.Time, bool) {
+	if ti < 0 || ti >= len(row) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, row[ti])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, t.UnixNano()), true
+}
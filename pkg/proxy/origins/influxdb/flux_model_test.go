@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"strings"
+	"testing"
+)
+
+const testFluxCSV = "#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string\r\n" +
+	"#group,false,false,true,true,false,false,true,true\r\n" +
+	"#default,_result,,,,,,,\r\n" +
+	",result,table,_start,_stop,_time,_value,_field,host\r\n" +
+	",,0,2020-01-01T00:00:00Z,2020-01-01T00:03:00Z,2020-01-01T00:01:00Z,1.5,usage,a\r\n" +
+	",,0,2020-01-01T00:00:00Z,2020-01-01T00:03:00Z,2020-01-01T00:02:00Z,2.5,usage,a\r\n" +
+	",,1,2020-01-01T00:00:00Z,2020-01-01T00:03:00Z,2020-01-01T00:01:00Z,3.5,usage,b\r\n" +
+	"\r\n" +
+	"#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,long,string,string\r\n" +
+	"#group,false,false,true,true,false,false,true,true\r\n" +
+	"#default,_result,,,,,,,\r\n" +
+	",result,table,_start,_stop,_time,_value,_field,host\r\n" +
+	",,2,2020-01-01T00:00:00Z,2020-01-01T00:03:00Z,2020-01-01T00:01:00Z,7,count,a\r\n" +
+	"\r\n"
+
+func TestUnmarshalFlux(t *testing.T) {
+
+	fe, err := unmarshalFlux([]byte(testFluxCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fe.Tables) != 3 {
+		t.Fatalf("expected %d got %d", 3, len(fe.Tables))
+	}
+	if len(fe.Tables[0].Rows) != 2 || len(fe.Tables[1].Rows) != 1 || len(fe.Tables[2].Rows) != 1 {
+		t.Error("unexpected table rows")
+	}
+	if len(fe.Tables[2].Annotations) != 3 || fe.Tables[2].Annotations[0][6] != "long" {
+		t.Error("unexpected table annotations")
+	}
+	if r := fe.Tables[0].result(); r != "_result" {
+		t.Errorf("expected %s got %s", "_result", r)
+	}
+
+	b, err := fe.marshalCSV()
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != testFluxCSV {
+		t.Errorf("expected %s got %s", testFluxCSV, string(b))
+	}
+
+	_, err = unmarshalFlux([]byte("#datatype,string,string\r\n#group,true,true\r\n#default,,\r\n" +
+		",error,reference\r\n,failed to execute query,897\r\n\r\n"))
+	if err == nil || err.Error() != "failed to execute query" {
+		t.Errorf("expected error for error table, got %v", err)
+	}
+
+	_, err = unmarshalFlux([]byte(",result,table,_value\r\n,_result,0,1\r\n\r\n"))
+	if err != ErrFluxTimeColumnMissing {
+		t.Errorf("expected %v got %v", ErrFluxTimeColumnMissing, err)
+	}
+}
+
+func TestMarshalFluxTableNumbering(t *testing.T) {
+
+	fe, err := unmarshalFlux([]byte(testFluxCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// removing the first table renumbers the others
+	fe.Tables = fe.Tables[1:]
+	b, err := fe.marshalCSV()
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(string(b), ",,0,2020-01-01T00:00:00Z,2020-01-01T00:03:00Z,2020-01-01T00:01:00Z,3.5") ||
+		!strings.Contains(string(b), ",,1,2020-01-01T00:00:00Z,2020-01-01T00:03:00Z,2020-01-01T00:01:00Z,7") {
+		t.Errorf("unexpected table numbering: %s", string(b))
+	}
+}
+
+func TestFluxTableKey(t *testing.T) {
+
+	fe, err := unmarshalFlux([]byte(testFluxCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "_result;,result,table,_start,_stop,_time,_value,_field,host;_field=usage;host=a"
+	if k := fe.Tables[0].key(); k != expected {
+		t.Errorf("expected %s got %s", expected, k)
+	}
+
+	// without a #group annotation, the tag, measurement and field columns are the group key
+	fe.Tables[0].Annotations = nil
+	expected = ";,result,table,_start,_stop,_time,_value,_field,host;_field=usage;host=a"
+	if k := fe.Tables[0].key(); k != expected {
+		t.Errorf("expected %s got %s", expected, k)
+	}
+}
+
+func TestMarshalUnmarshalFluxTimeseries(t *testing.T) {
+
+	client := &Client{}
+	ts, err := client.UnmarshalTimeseries([]byte(testFluxCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fe, ok := ts.(*FluxEnvelope)
+	if !ok {
+		t.Fatal("expected FluxEnvelope")
+	}
+
+	fe.SetExtents(testFluxExtents)
+	b, err := client.MarshalTimeseries(fe)
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.HasPrefix(string(b), string(fluxCachePrefix)) {
+		t.Errorf("expected cache format, got %s", string(b))
+	}
+
+	ts, err = client.UnmarshalTimeseries(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.ValueCount() != 4 || len(ts.Extents()) != 1 {
+		t.Errorf("unexpected timeseries %v", ts)
+	}
+
+	ts.SetExtents(nil)
+	b, err = client.MarshalTimeseries(ts)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != testFluxCSV {
+		t.Errorf("expected %s got %s", testFluxCSV, string(b))
+	}
+}
@@ -0,0 +1,346 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/sort/times"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	str "github.com/tricksterproxy/trickster/pkg/util/strings"
+)
+
+// SetExtents overwrites a Timeseries's known extents with the provided extent list
+func (fe *FluxEnvelope) SetExtents(extents timeseries.ExtentList) {
+	el := make(timeseries.ExtentList, len(extents))
+	copy(el, extents)
+	fe.ExtentList = el
+	fe.isCounted = false
+}
+
+// Extents returns the Timeseries's ExentList
+func (fe *FluxEnvelope) Extents() timeseries.ExtentList {
+	return fe.ExtentList
+}
+
+// ValueCount returns the count of all rows across all tables in the Timeseries
+func (fe *FluxEnvelope) ValueCount() int {
+	c := 0
+	for _, t := range fe.Tables {
+		c += len(t.Rows)
+	}
+	return c
+}
+
+// TimestampCount returns the count unique timestampes in across all tables in the Timeseries
+func (fe *FluxEnvelope) TimestampCount() int {
+	if fe.timestamps == nil {
+		fe.timestamps = make(map[time.Time]bool)
+	}
+	fe.updateTimestamps()
+	return len(fe.timestamps)
+}
+
+func (fe *FluxEnvelope) updateTimestamps() {
+	if fe.isCounted {
+		return
+	}
+	m := make(map[time.Time]bool)
+	for _, t := range fe.Tables {
+		ti := str.IndexOfString(t.Columns, fcTime)
+		for _, row := range t.Rows {
+			if ts, ok := rowTime(row, ti); ok {
+				m[ts] = true
+			}
+		}
+	}
+	fe.timestamps = m
+	fe.tslist = times.FromMap(m)
+	fe.isCounted = true
+}
+
+// SeriesCount returns the count of all tables in the Timeseries
+func (fe *FluxEnvelope) SeriesCount() int {
+	return len(fe.Tables)
+}
+
+// Step returns the step for the Timeseries
+func (fe *FluxEnvelope) Step() time.Duration {
+	return fe.StepDuration
+}
+
+// SetStep sets the step for the Timeseries
+func (fe *FluxEnvelope) SetStep(step time.Duration) {
+	fe.StepDuration = step
+}
+
+// Merge merges the provided Timeseries list into the base Timeseries
+// (in the order provided) and optionally sorts the merged Timeseries
+func (fe *FluxEnvelope) Merge(sort bool, collection ...timeseries.Timeseries) {
+
+	fe.updateLock.Lock()
+	defer fe.updateLock.Unlock()
+
+	tables := make(map[string]*FluxTable, len(fe.Tables))
+	for _, t := range fe.Tables {
+		tables[t.key()] = t
+	}
+
+	for _, ts := range collection {
+		if ts == nil {
+			continue
+		}
+		fe2 := ts.(*FluxEnvelope)
+		for _, t := range fe2.Tables {
+			k := t.key()
+			if et, ok := tables[k]; ok {
+				et.Rows = append(et.Rows, t.Rows...)
+				continue
+			}
+			nt := t.clone()
+			tables[k] = nt
+			fe.Tables = append(fe.Tables, nt)
+		}
+		fe.ExtentList = append(fe.ExtentList, fe2.ExtentList...)
+	}
+
+	fe.ExtentList = fe.ExtentList.Compress(fe.StepDuration)
+	fe.isSorted = false
+	fe.isCounted = false
+	if sort {
+		fe.Sort()
+	}
+}
+
+// Clone returns a perfect copy of the base Timeseries
+func (fe *FluxEnvelope) Clone() timeseries.Timeseries {
+	fe.updateLock.Lock()
+	defer fe.updateLock.Unlock()
+	clone := &FluxEnvelope{
+		Tables:       make([]*FluxTable, len(fe.Tables)),
+		StepDuration: fe.StepDuration,
+		ExtentList:   fe.ExtentList.Clone(),
+		timestamps:   make(map[time.Time]bool, len(fe.timestamps)),
+		tslist:       make(times.Times, len(fe.tslist)),
+		isCounted:    fe.isCounted,
+		isSorted:     fe.isSorted,
+	}
+	for k, v := range fe.timestamps {
+		clone.timestamps[k] = v
+	}
+	copy(clone.tslist, fe.tslist)
+	for i, t := range fe.Tables {
+		clone.Tables[i] = t.clone()
+	}
+	return clone
+}
+
+func (t *FluxTable) clone() *FluxTable {
+	nt := &FluxTable{
+		Annotations: make([][]string, len(t.Annotations)),
+		Columns:     str.CloneList(t.Columns),
+		Rows:        make([][]string, len(t.Rows)),
+	}
+	for i, a := range t.Annotations {
+		nt.Annotations[i] = str.CloneList(a)
+	}
+	for i, row := range t.Rows {
+		nt.Rows[i] = str.CloneList(row)
+	}
+	return nt
+}
+
+// CropToSize reduces the number of elements in the Timeseries to the provided count, by evicting elements
+// using a least-recently-used methodology. The time parameter limits the upper extent to the provided time,
+// in order to support backfill tolerance
+func (fe *FluxEnvelope) CropToSize(sz int, t time.Time, lur timeseries.Extent) {
+
+	fe.isCounted = false
+	fe.isSorted = false
+	x := len(fe.ExtentList)
+	// The Series has no extents, so no need to do anything
+	if x < 1 {
+		fe.Tables = []*FluxTable{}
+		fe.ExtentList = timeseries.ExtentList{}
+		return
+	}
+
+	// Crop to the Backfill Tolerance Value if needed
+	if fe.ExtentList[x-1].End.After(t) {
+		fe.CropToRange(timeseries.Extent{Start: fe.ExtentList[0].Start, End: t})
+	}
+
+	tc := fe.TimestampCount()
+	if len(fe.Tables) == 0 || tc <= sz {
+		return
+	}
+
+	el := timeseries.ExtentListLRU(fe.ExtentList).UpdateLastUsed(lur, fe.StepDuration)
+	sort.Sort(el)
+
+	rc := tc - sz // # of required timestamps we must delete to meet the rentention policy
+	removals := make(map[time.Time]bool)
+	done := false
+	var ok bool
+
+	for _, x := range el {
+		for ts := x.Start; !x.End.Before(ts) && !done; ts = ts.Add(fe.StepDuration) {
+			if _, ok = fe.timestamps[ts]; ok {
+				removals[ts] = true
+				done = len(removals) >= rc
+			}
+		}
+		if done {
+			break
+		}
+	}
+
+	for _, tbl := range fe.Tables {
+		ti := str.IndexOfString(tbl.Columns, fcTime)
+		tmp := tbl.Rows[:0]
+		for _, row := range tbl.Rows {
+			if ts, ok := rowTime(row, ti); ok && !removals[ts] {
+				tmp = append(tmp, row)
+			}
+		}
+		tbl.Rows = tmp
+	}
+	fe.removeEmptyTables()
+
+	tl := times.FromMap(removals)
+	sort.Sort(tl)
+	for _, t := range tl {
+		for i, e := range el {
+			if e.StartsAt(t) {
+				el[i].Start = e.Start.Add(fe.StepDuration)
+			}
+		}
+	}
+
+	fe.ExtentList = timeseries.ExtentList(el).Compress(fe.StepDuration)
+	fe.Sort()
+}
+
+// CropToRange reduces the Timeseries down to timestamps contained within the provided Extents (inclusive).
+func (fe *FluxEnvelope) CropToRange(e timeseries.Extent) {
+	fe.isCounted = false
+	x := len(fe.ExtentList)
+	// The Series has no extents, or its extents are entirely outside of the crop range,
+	// so return an empty set
+	if x < 1 || fe.ExtentList.OutsideOf(e) {
+		fe.Tables = []*FluxTable{}
+		fe.ExtentList = timeseries.ExtentList{}
+		return
+	}
+
+	// if the series extent is entirely inside the extent of the crop range, simply adjust down its ExtentList
+	if fe.ExtentList.InsideOf(e) {
+		fe.removeEmptyTables()
+		fe.ExtentList = fe.ExtentList.Crop(e)
+		return
+	}
+
+	for _, t := range fe.Tables {
+		ti := str.IndexOfString(t.Columns, fcTime)
+		tmp := t.Rows[:0]
+		for _, row := range t.Rows {
+			if ts, ok := rowTime(row, ti); ok && !ts.Before(e.Start) && !ts.After(e.End) {
+				tmp = append(tmp, row)
+			}
+		}
+		t.Rows = tmp
+	}
+	fe.removeEmptyTables()
+	fe.ExtentList = fe.ExtentList.Crop(e)
+}
+
+// removeEmptyTables removes the tables having no rows from the Timeseries
+func (fe *FluxEnvelope) removeEmptyTables() {
+	tmp := fe.Tables[:0]
+	for _, t := range fe.Tables {
+		if len(t.Rows) > 0 {
+			tmp = append(tmp, t)
+		}
+	}
+	fe.Tables = tmp
+}
+
+// Sort sorts all rows in each table chronologically by their timestamp, and removes rows
+// having duplicate or unparseable timestamps
+func (fe *FluxEnvelope) Sort() {
+
+	if fe.isSorted || len(fe.Tables) == 0 {
+		return
+	}
+
+	tsm := map[time.Time]bool{}
+	for _, t := range fe.Tables {
+		ti := str.IndexOfString(t.Columns, fcTime)
+		m := make(map[time.Time][]string, len(t.Rows))
+		keys := make(times.Times, 0, len(t.Rows))
+		for _, row := range t.Rows {
+			ts, ok := rowTime(row, ti)
+			if !ok {
+				continue
+			}
+			if _, ok = m[ts]; !ok {
+				keys = append(keys, ts)
+				m[ts] = row
+			}
+			tsm[ts] = true
+		}
+		sort.Sort(keys)
+		rows := make([][]string, 0, len(keys))
+		for _, k := range keys {
+			rows = append(rows, m[k])
+		}
+		t.Rows = rows
+	}
+
+	sort.Sort(fe.ExtentList)
+
+	fe.timestamps = tsm
+	fe.tslist = times.FromMap(tsm)
+	fe.isCounted = true
+	fe.isSorted = true
+}
+
+// Size returns the approximate memory utilization in bytes of the timeseries
+func (fe *FluxEnvelope) Size() int {
+	c := 24 + // .stepDuration
+		fe.ExtentList.Size() + // time.Time (24) * 3
+		(25 * len(fe.timestamps)) + // time.Time (24) + bool(1)
+		(24 * len(fe.tslist)) + // time.Time (24)
+		2 // .isSorted + .isCounted
+	for _, t := range fe.Tables {
+		for _, a := range t.Annotations {
+			for _, v := range a {
+				c += len(v)
+			}
+		}
+		for _, v := range t.Columns {
+			c += len(v)
+		}
+		for _, row := range t.Rows {
+			for _, v := range row {
+				c += len(v)
+			}
+		}
+	}
+	return c
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+var testFluxExtents = timeseries.ExtentList{{Start: time.Unix(1577836860, 0), End: time.Unix(1577836920, 0)}}
+
+func testFluxEnvelope(t *testing.T) *FluxEnvelope {
+	fe, err := unmarshalFlux([]byte(testFluxCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fe.SetExtents(testFluxExtents)
+	fe.SetStep(time.Minute)
+	return fe
+}
+
+func TestFluxMerge(t *testing.T) {
+
+	fe := testFluxEnvelope(t)
+	fe2 := testFluxEnvelope(t)
+	// shift the second envelope forward by 2 minutes, so table b gains a row and table a is unchanged
+	for _, tbl := range fe2.Tables {
+		for _, row := range tbl.Rows {
+			ts, _ := rowTime(row, 5)
+			row[5] = ts.Add(2 * time.Minute).UTC().Format(time.RFC3339)
+			row[3], row[4] = "2020-01-01T00:02:00Z", "2020-01-01T00:05:00Z"
+		}
+	}
+	fe2.SetExtents(timeseries.ExtentList{{Start: time.Unix(1577836980, 0), End: time.Unix(1577837040, 0)}})
+
+	fe.Merge(true, fe2)
+
+	if len(fe.Tables) != 3 {
+		t.Fatalf("expected %d got %d", 3, len(fe.Tables))
+	}
+	if fe.ValueCount() != 8 {
+		t.Errorf("expected %d got %d", 8, fe.ValueCount())
+	}
+	if fe.TimestampCount() != 4 {
+		t.Errorf("expected %d got %d", 4, fe.TimestampCount())
+	}
+	if len(fe.ExtentList) != 1 || fe.ExtentList[0].End.Unix() != 1577837040 {
+		t.Errorf("unexpected extents %s", fe.ExtentList.String())
+	}
+	if fe.Tables[0].Rows[3][5] != "2020-01-01T00:04:00Z" {
+		t.Errorf("expected %s got %s", "2020-01-01T00:04:00Z", fe.Tables[0].Rows[3][5])
+	}
+}
+
+func TestFluxSort(t *testing.T) {
+
+	fe := testFluxEnvelope(t)
+	rows := fe.Tables[0].Rows
+	fe.Tables[0].Rows = [][]string{rows[1], rows[0], rows[1]}
+	fe.Sort()
+	if len(fe.Tables[0].Rows) != 2 || fe.Tables[0].Rows[0][5] != "2020-01-01T00:01:00Z" {
+		t.Errorf("unexpected rows %v", fe.Tables[0].Rows)
+	}
+	if fe.TimestampCount() != 2 {
+		t.Errorf("expected %d got %d", 2, fe.TimestampCount())
+	}
+}
+
+func TestFluxClone(t *testing.T) {
+
+	fe := testFluxEnvelope(t)
+	c := fe.Clone().(*FluxEnvelope)
+	c.Tables[0].Rows[0][6] = "99"
+	if fe.Tables[0].Rows[0][6] == "99" {
+		t.Error("expected clone to be independent of the original")
+	}
+	if c.ValueCount() != fe.ValueCount() || c.Step() != fe.Step() || len(c.Extents()) != 1 {
+		t.Error("expected clone to match the original")
+	}
+}
+
+func TestFluxCropToRange(t *testing.T) {
+
+	fe := testFluxEnvelope(t)
+	fe.CropToRange(timeseries.Extent{Start: time.Unix(1577836920, 0), End: time.Unix(1577837040, 0)})
+	// only table a has a row at 00:02
+	if len(fe.Tables) != 1 || fe.ValueCount() != 1 {
+		t.Errorf("unexpected tables %v", fe.Tables)
+	}
+	if len(fe.ExtentList) != 1 || fe.ExtentList[0].Start.Unix() != 1577836920 {
+		t.Errorf("unexpected extents %s", fe.ExtentList.String())
+	}
+
+	fe = testFluxEnvelope(t)
+	fe.CropToRange(timeseries.Extent{Start: time.Unix(1577840000, 0), End: time.Unix(1577850000, 0)})
+	if len(fe.Tables) != 0 || len(fe.ExtentList) != 0 {
+		t.Error("expected empty timeseries")
+	}
+
+	fe = testFluxEnvelope(t)
+	fe.CropToRange(timeseries.Extent{Start: time.Unix(1577836800, 0), End: time.Unix(1577837040, 0)})
+	if fe.ValueCount() != 4 {
+		t.Errorf("expected %d got %d", 4, fe.ValueCount())
+	}
+}
+
+func TestFluxCropToSize(t *testing.T) {
+
+	fe := testFluxEnvelope(t)
+	now := time.Unix(1577840000, 0)
+	fe.CropToSize(1, now, testFluxExtents[0])
+	if fe.TimestampCount() != 1 || fe.ValueCount() != 1 {
+		t.Errorf("expected a single timestamp, got %d", fe.TimestampCount())
+	}
+	if len(fe.ExtentList) != 1 || fe.ExtentList[0].Start.Unix() != 1577836920 {
+		t.Errorf("unexpected extents %s", fe.ExtentList.String())
+	}
+
+	fe = testFluxEnvelope(t)
+	fe.SetExtents(nil)
+	fe.CropToSize(1, now, testFluxExtents[0])
+	if len(fe.Tables) != 0 {
+		t.Error("expected empty timeseries")
+	}
+}
+
+func TestFluxSize(t *testing.T) {
+	fe := testFluxEnvelope(t)
+	if fe.Size() <= 0 {
+		t.Errorf("expected positive size, got %d", fe.Size())
+	}
+	if fe.SeriesCount() != 3 {
+		t.Errorf("expected %d got %d", 3, fe.SeriesCount())
+	}
+}
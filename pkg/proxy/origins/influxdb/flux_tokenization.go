@@ -0,0 +1,172 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/timeconv"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	"github.com/tricksterproxy/trickster/pkg/util/regexp/matching"
+)
+
+// This file handles tokenization of time ranges within Flux queries
+// for cache key hashing and delta proxy caching.
+
+// Tokens for String Interpolation
+const (
+	tkFluxRange = "<$FLUX_RANGE_TOKEN$>"
+)
+
+var reFluxRange, reFluxRangeCall, reFluxWindow, reFluxTimeSrcStart *regexp.Regexp
+
+func init() {
+
+	// Regexp for extracting the time range from a Flux query. searches for something like:
+	// range(start: -1h, stop: now())
+	reFluxRange = regexp.MustCompile(`\brange\(\s*start\s*:\s*(?P<start>now\(\)|[^\s,()]+)\s*` +
+		`(,\s*stop\s*:\s*(?P<stop>now\(\)|[^\s,()]+)\s*)?\)`)
+
+	// Regexp for counting the range() calls in a Flux query, including those reFluxRange does not match
+	reFluxRangeCall = regexp.MustCompile(`\brange\(`)
+
+	// Regexp for extracting the step from a Flux query. searches for something like:
+	// aggregateWindow(every: 1m, fn: mean)
+	reFluxWindow = regexp.MustCompile(`\baggregateWindow\([^)]*?\bevery\s*:\s*(?P<step>[^\s,()]+)`)
+
+	// Regexp for determining if aggregateWindow() timestamps each window with its start time
+	reFluxTimeSrcStart = regexp.MustCompile(`\baggregateWindow\([^)]*?\btimeSrc\s*:\s*"_start"`)
+}
+
+// interpolateFluxRange returns the tokenized Flux query with its range set to the provided Extent.
+// aggregateWindow() timestamps each window with its stop time unless its timeSrc is "_start", so the
+// range is widened by one step, on the side that makes the windows' timestamps match the Extent
+func interpolateFluxRange(template string, extent *timeseries.Extent, step time.Duration) string {
+	start, stop := extent.Start, extent.End
+	if reFluxTimeSrcStart.MatchString(template) {
+		stop = stop.Add(step)
+	} else {
+		start = start.Add(-step)
+	}
+	return strings.Replace(template, tkFluxRange, fmt.Sprintf("range(start: %s, stop: %s)",
+		start.UTC().Format(time.RFC3339Nano), stop.UTC().Format(time.RFC3339Nano)), -1)
+}
+
+// getFluxQueryParts returns the Flux query with its range() call tokenized, and the time range
+// it requested. Relative times are resolved against now. Queries must have exactly one range() call
+func getFluxQueryParts(query string, now time.Time) (string, timeseries.Extent, error) {
+
+	var e timeseries.Extent
+	if len(reFluxRangeCall.FindAllStringIndex(query, 2)) != 1 {
+		return "", e, errors.ErrNotTimeRangeQuery
+	}
+
+	m := matching.GetNamedMatches(reFluxRange, query, nil)
+	start, ok := m["start"]
+	if !ok {
+		return "", e, errors.ErrNotTimeRangeQuery
+	}
+
+	var err error
+	if e.Start, err = parseFluxTime(start, now); err != nil {
+		return "", e, err
+	}
+	e.End = now
+	if stop, ok := m["stop"]; ok {
+		if e.End, err = parseFluxTime(stop, now); err != nil {
+			return "", e, err
+		}
+	}
+
+	loc := reFluxRange.FindStringIndex(query)
+	return query[:loc[0]] + tkFluxRange + query[loc[1]:], e, nil
+}
+
+// getFluxStep returns the window period of the Flux query's aggregateWindow() call
+func getFluxStep(query string) (time.Duration, error) {
+	step, found := matching.GetNamedMatch("step", reFluxWindow, query)
+	if !found {
+		return 0, errors.ErrStepParse
+	}
+	d, err := parseFluxDuration(step)
+	if err != nil || d <= 0 {
+		return 0, errors.ErrStepParse
+	}
+	return d, nil
+}
+
+// parseFluxTime returns the time represented by a Flux range() argument, which may be now(),
+// a duration relative to now, an RFC3339 timestamp or date, or an integer of Unix epoch seconds
+func parseFluxTime(v string, now time.Time) (time.Time, error) {
+	if v == "now()" {
+		return now, nil
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(i, 0), nil
+	}
+	if d, err := parseFluxDuration(v); err == nil {
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.ErrNotTimeRangeQuery
+}
+
+// parseFluxDuration returns the time.Duration of a Flux duration literal, which may be negative
+// and may have multiple magnitude and unit pairs (e.g., -1h30m). Calendar months are not supported
+func parseFluxDuration(v string) (time.Duration, error) {
+	s := strings.TrimPrefix(v, "-")
+	if s == "" {
+		return errors.ParseDuration(v)
+	}
+	var d time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsDigit(r) })
+		if i <= 0 {
+			return errors.ParseDuration(v)
+		}
+		n, err := strconv.ParseInt(s[:i], 10, 64)
+		if err != nil {
+			return errors.ParseDuration(v)
+		}
+		s = s[i:]
+		j := strings.IndexFunc(s, unicode.IsDigit)
+		if j < 0 {
+			j = len(s)
+		}
+		p, err := timeconv.ParseDurationParts(n, s[:j])
+		if err != nil {
+			return errors.ParseDuration(v)
+		}
+		d += p
+		s = s[j:]
+	}
+	if strings.HasPrefix(v, "-") {
+		d = -d
+	}
+	return d, nil
+}
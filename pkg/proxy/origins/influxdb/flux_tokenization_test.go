@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+const testFluxQuery = `from(bucket: "telegraf")
+  |> range(start: -6h, stop: now())
+  |> filter(fn: (r) => r._measurement == "cpu")
+  |> aggregateWindow(every: 1m, fn: mean)`
+
+func TestGetFluxQueryParts(t *testing.T) {
+
+	now := time.Unix(1577836800, 0)
+
+	s, e, err := getFluxQueryParts(testFluxQuery, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `from(bucket: "telegraf")
+  |> ` + tkFluxRange + `
+  |> filter(fn: (r) => r._measurement == "cpu")
+  |> aggregateWindow(every: 1m, fn: mean)`
+	if s != expected {
+		t.Errorf("expected %s got %s", expected, s)
+	}
+	if !e.Start.Equal(now.Add(-6*time.Hour)) || !e.End.Equal(now) {
+		t.Errorf("unexpected extent %s", e.String())
+	}
+
+	tests := []struct {
+		query string
+		start int64
+		end   int64
+	}{
+		{`range(start: -1h30m)`, 1577831400, 1577836800},
+		{`range(start: 2019-12-31T23:00:00Z, stop: 2020-01-01T00:00:00Z)`, 1577833200, 1577836800},
+		{`range(start:1577833200, stop:1577836800)`, 1577833200, 1577836800},
+		{`range(start: 2019-12-31)`, 1577750400, 1577836800},
+	}
+	for _, test := range tests {
+		_, e, err := getFluxQueryParts(test.query, now)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if e.Start.Unix() != test.start || e.End.Unix() != test.end {
+			t.Errorf("%s: expected %d-%d got %d-%d", test.query, test.start, test.end,
+				e.Start.Unix(), e.End.Unix())
+		}
+	}
+
+	for _, q := range []string{
+		`from(bucket: "telegraf")`,
+		`range(start: v.timeRangeStart, stop: v.timeRangeStop)`,
+		`union(tables: [a |> range(start: -1h), b |> range(start: -2h)])`,
+	} {
+		if _, _, err := getFluxQueryParts(q, now); err != errors.ErrNotTimeRangeQuery {
+			t.Errorf("%s: expected %v got %v", q, errors.ErrNotTimeRangeQuery, err)
+		}
+	}
+}
+
+func TestGetFluxStep(t *testing.T) {
+
+	d, err := getFluxStep(testFluxQuery)
+	if err != nil {
+		t.Error(err)
+	}
+	if d != time.Minute {
+		t.Errorf("expected %s got %s", time.Minute, d)
+	}
+
+	for _, q := range []string{
+		`range(start: -1h)`,
+		`aggregateWindow(every: v.windowPeriod, fn: mean)`,
+		`aggregateWindow(every: 1mo, fn: mean)`,
+	} {
+		if _, err := getFluxStep(q); err != errors.ErrStepParse {
+			t.Errorf("%s: expected %v got %v", q, errors.ErrStepParse, err)
+		}
+	}
+}
+
+func TestParseFluxDuration(t *testing.T) {
+
+	tests := []struct {
+		in       string
+		expected time.Duration
+		err      bool
+	}{
+		{"15s", 15 * time.Second, false},
+		{"-1h30m", -90 * time.Minute, false},
+		{"2d", 48 * time.Hour, false},
+		{"1mo", 0, true},
+		{"-", 0, true},
+		{"h", 0, true},
+	}
+	for _, test := range tests {
+		d, err := parseFluxDuration(test.in)
+		if (err != nil) != test.err {
+			t.Errorf("%s: unexpected error result %v", test.in, err)
+		}
+		if d != test.expected {
+			t.Errorf("%s: expected %s got %s", test.in, test.expected, d)
+		}
+	}
+}
+
+func TestInterpolateFluxRange(t *testing.T) {
+
+	e := &timeseries.Extent{Start: time.Unix(1577833200, 0), End: time.Unix(1577836800, 0)}
+
+	s := interpolateFluxRange(`|> `+tkFluxRange+` |> aggregateWindow(every: 1m, fn: mean)`, e, time.Minute)
+	expected := `|> range(start: 2019-12-31T22:59:00Z, stop: 2020-01-01T00:00:00Z) ` +
+		`|> aggregateWindow(every: 1m, fn: mean)`
+	if s != expected {
+		t.Errorf("expected %s got %s", expected, s)
+	}
+
+	s = interpolateFluxRange(`|> `+tkFluxRange+` |> aggregateWindow(every: 1m, fn: mean, timeSrc: "_start")`,
+		e, time.Minute)
+	expected = `|> range(start: 2019-12-31T23:00:00Z, stop: 2020-01-01T00:01:00Z) ` +
+		`|> aggregateWindow(every: 1m, fn: mean, timeSrc: "_start")`
+	if s != expected {
+		t.Errorf("expected %s got %s", expected, s)
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/engines"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/urls"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+// FluxHandler handles Flux queries to the InfluxDB 2.x query API and processes them through
+// the delta proxy cache
+func (c *Client) FluxHandler(w http.ResponseWriter, r *http.Request) {
+	r.URL = urls.BuildUpstreamURL(r, c.baseUpstreamURL)
+	engines.DeltaProxyCacheRequest(w, r)
+}
+
+// parseFluxTimeRangeQuery parses the key parts of a TimeRangeQuery from an inbound Flux query
+// request, which has a JSON body, or a raw Flux body with a Content-Type of application/vnd.flux
+func parseFluxTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery, error) {
+
+	if r.Method != http.MethodPost || r.Body == nil {
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.ParseRequestBody(err)
+	}
+
+	doc := make(map[string]interface{})
+	mt, _, _ := mime.ParseMediaType(r.Header.Get(headers.NameContentType))
+	switch mt {
+	case headers.ValueApplicationJSON:
+		if err = json.Unmarshal(b, &doc); err != nil {
+			return nil, errors.ParseRequestBody(err)
+		}
+	case headers.ValueApplicationFlux:
+		doc["query"] = string(b)
+	default:
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+
+	q, _ := doc["query"].(string)
+	if q == "" {
+		return nil, errors.MissingRequestParam("query")
+	}
+	if t, ok := doc["type"].(string); ok && t != "flux" {
+		return nil, errors.ErrNotTimeRangeQuery
+	}
+	// the response must be comma-delimited and have header rows to be parsed as a timeseries
+	if d, ok := doc["dialect"].(map[string]interface{}); ok {
+		if v, ok := d["delimiter"].(string); ok && v != "," {
+			return nil, errors.ErrNotTimeRangeQuery
+		}
+		if v, ok := d["header"].(bool); ok && !v {
+			return nil, errors.ErrNotTimeRangeQuery
+		}
+	}
+
+	// relative times are resolved against the request's now value, when provided. It is then
+	// removed from the template, since the tokenized query no longer depends on it
+	now := time.Now()
+	if v, ok := doc["now"].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			now = t
+		}
+		delete(doc, "now")
+	}
+
+	trq := &timeseries.TimeRangeQuery{}
+	if trq.Step, err = getFluxStep(q); err != nil {
+		return nil, err
+	}
+	if trq.Statement, trq.Extent, err = getFluxQueryParts(q, now); err != nil {
+		return nil, err
+	}
+	doc["query"] = trq.Statement
+
+	// the tokenized request body is carried in the TemplateURL, so it is factored into the cache key
+	// and is available to SetExtent, since the upstream request bodies cannot be re-read
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err = enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	trq.TemplateURL = urls.Clone(r.URL)
+	qt := trq.TemplateURL.Query()
+	qt.Set(upFluxRequest, strings.TrimSpace(buf.String()))
+	trq.TemplateURL.RawQuery = qt.Encode()
+
+	return trq, nil
+}
+
+// setFluxExtent sets the body of the upstream Flux request to the tokenized request body, with
+// its range set to the provided Extent
+func setFluxExtent(r *http.Request, trq *timeseries.TimeRangeQuery, extent *timeseries.Extent) {
+	if trq.TemplateURL == nil {
+		return
+	}
+	body := []byte(interpolateFluxRange(trq.TemplateURL.Query().Get(upFluxRequest), extent, trq.Step))
+	r.Header = r.Header.Clone()
+	r.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+	r.ContentLength = int64(len(body))
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+}
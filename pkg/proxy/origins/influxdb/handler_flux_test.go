@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/request"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+	tu "github.com/tricksterproxy/trickster/pkg/util/testing"
+)
+
+func testFluxRequest(contentType, body string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "http://blah.com/api/v2/query?org=example",
+		bytes.NewBufferString(body))
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("Authorization", "Token abc123")
+	return r
+}
+
+func TestParseFluxTimeRangeQuery(t *testing.T) {
+
+	client := &Client{}
+	body, _ := json.Marshal(map[string]interface{}{"query": testFluxQuery, "type": "flux",
+		"now": "2020-01-01T00:00:00Z", "dialect": map[string]interface{}{"annotations": []string{"datatype"}}})
+
+	r := testFluxRequest("application/json", string(body))
+	trq, err := client.ParseTimeRangeQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq.Step != time.Minute {
+		t.Errorf("expected %s got %s", time.Minute, trq.Step)
+	}
+	if trq.Extent.End.Unix() != 1577836800 || trq.Extent.End.Sub(trq.Extent.Start) != 6*time.Hour {
+		t.Errorf("unexpected extent %s", trq.Extent.String())
+	}
+	if !strings.Contains(trq.Statement, tkFluxRange) {
+		t.Errorf("expected tokenized statement, got %s", trq.Statement)
+	}
+	qt := trq.TemplateURL.Query()
+	if qt.Get(upOrg) != "example" {
+		t.Errorf("expected %s got %s", "example", qt.Get(upOrg))
+	}
+	tb := qt.Get(upFluxRequest)
+	if !strings.Contains(tb, tkFluxRange) || strings.Contains(tb, `"now"`) ||
+		!strings.Contains(tb, `"dialect":{"annotations":["datatype"]}`) {
+		t.Errorf("unexpected template body %s", tb)
+	}
+	// the body remains readable for proxying
+	if b, _ := ioutil.ReadAll(r.Body); string(b) != string(body) {
+		t.Errorf("expected %s got %s", string(body), string(b))
+	}
+
+	// a raw flux body produces the same template body as its JSON equivalent
+	trq2, err := client.ParseTimeRangeQuery(testFluxRequest("application/vnd.flux", testFluxQuery))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq2.Statement != trq.Statement {
+		t.Errorf("expected %s got %s", trq.Statement, trq2.Statement)
+	}
+
+	tests := []struct {
+		contentType string
+		body        string
+		err         error
+	}{
+		{"text/plain", testFluxQuery, errors.ErrNotTimeRangeQuery},
+		{"application/json", `{"type":"flux"}`, errors.MissingRequestParam("query")},
+		{"application/json", `{"query":"x","type":"influxql"}`, errors.ErrNotTimeRangeQuery},
+		{"application/json", `{"query":"x","dialect":{"delimiter":";"}}`, errors.ErrNotTimeRangeQuery},
+		{"application/json", `{"query":"x","dialect":{"header":false}}`, errors.ErrNotTimeRangeQuery},
+		{"application/vnd.flux", `from(bucket: "a") |> range(start: -1h)`, errors.ErrStepParse},
+	}
+	for _, test := range tests {
+		_, err := client.ParseTimeRangeQuery(testFluxRequest(test.contentType, test.body))
+		if err == nil || err.Error() != test.err.Error() {
+			t.Errorf("%s: expected %v got %v", test.body, test.err, err)
+		}
+	}
+
+	r, _ = http.NewRequest(http.MethodGet, "http://blah.com/api/v2/query", nil)
+	if _, err = client.ParseTimeRangeQuery(r); err != errors.ErrNotTimeRangeQuery {
+		t.Errorf("expected %v got %v", errors.ErrNotTimeRangeQuery, err)
+	}
+}
+
+func TestSetFluxExtent(t *testing.T) {
+
+	client := &Client{}
+	r := testFluxRequest("application/vnd.flux", testFluxQuery)
+	trq, err := client.ParseTimeRangeQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &timeseries.Extent{Start: time.Unix(1577833200, 0), End: time.Unix(1577836800, 0)}
+	client.SetExtent(r, trq, e)
+
+	if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected %s got %s", "application/json", ct)
+	}
+	b, _ := ioutil.ReadAll(r.Body)
+	if r.ContentLength != int64(len(b)) {
+		t.Errorf("expected %d got %d", len(b), r.ContentLength)
+	}
+	doc := make(map[string]interface{})
+	if err = json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if q, _ := doc["query"].(string); !strings.Contains(q,
+		"range(start: 2019-12-31T22:59:00Z, stop: 2020-01-01T00:00:00Z)") {
+		t.Errorf("unexpected query %s", q)
+	}
+}
+
+func TestFluxHandler(t *testing.T) {
+
+	client := &Client{name: "test"}
+	ts, w, r, hc, err := tu.NewTestInstance("", client.DefaultPathConfigs, 200, testFluxCSV,
+		map[string]string{"Content-Type": "text/csv; charset=utf-8"}, "influxdb", "/api/v2/query", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	rsc := request.GetResources(r)
+	rsc.OriginClient = client
+	client.config = rsc.OriginConfig
+	client.webClient = hc
+	client.config.HTTPClient = hc
+	client.baseUpstreamURL, _ = url.Parse(ts.URL)
+
+	r.Method = http.MethodPost
+	r.Header.Set("Content-Type", "application/vnd.flux")
+	r.Body = ioutil.NopCloser(bytes.NewBufferString(testFluxQuery))
+
+	client.FluxHandler(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != 200 {
+		t.Errorf("expected 200 got %d.", resp.StatusCode)
+	}
+	if h := resp.Header.Get("X-Trickster-Result"); !strings.Contains(h, "engine=DeltaProxyCache") {
+		t.Errorf("expected delta proxy cache result, got %s", h)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	if string(b) != testFluxCSV {
+		t.Errorf("expected %s got %s", testFluxCSV, string(b))
+	}
+}
@@ -49,6 +49,10 @@ func (c *Client) QueryHandler(w http.ResponseWriter, r *http.Request) {
 // ParseTimeRangeQuery parses the key parts of a TimeRangeQuery from the inbound HTTP Request
 func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery, error) {
 
+	if isFluxPath(r.URL.Path) {
+		return parseFluxTimeRangeQuery(r)
+	}
+
 	trq := &timeseries.TimeRangeQuery{Extent: timeseries.Extent{}}
 
 	v, _, _ := params.GetRequestValues(r)
@@ -17,6 +17,7 @@
 package influxdb
 
 import (
+	"bytes"
 	"encoding/json"
 	"sync"
 	"time"
@@ -49,14 +50,26 @@ type Result struct {
 	Err         string       `json:"error,omitempty"`
 }
 
-// MarshalTimeseries converts a Timeseries into a JSON blob
+// MarshalTimeseries converts a Timeseries into a JSON blob. Flux results are converted into JSON
+// when they have extents, for Cache Storage, and otherwise into annotated CSV for the client response
 func (c Client) MarshalTimeseries(ts timeseries.Timeseries) ([]byte, error) {
+	if fe, ok := ts.(*FluxEnvelope); ok && len(fe.ExtentList) == 0 {
+		return fe.marshalCSV()
+	}
 	// Marshal the Envelope back to a json object for Cache Storage
 	return json.Marshal(ts)
 }
 
-// UnmarshalTimeseries converts a JSON blob into a Timeseries
+// UnmarshalTimeseries converts a JSON blob, or an annotated CSV Flux result, into a Timeseries
 func (c Client) UnmarshalTimeseries(data []byte) (timeseries.Timeseries, error) {
+	if bytes.HasPrefix(data, fluxCachePrefix) {
+		fe := &FluxEnvelope{}
+		err := json.Unmarshal(data, fe)
+		return fe, err
+	}
+	if b := bytes.TrimSpace(data); len(b) > 0 && b[0] != '{' {
+		return unmarshalFlux(data)
+	}
 	se := &SeriesEnvelope{}
 	err := json.Unmarshal(data, se)
 	return se, err
@@ -31,6 +31,7 @@ func (c *Client) registerHandlers() {
 	// and are able to be referenced by name (map key) in Config Files
 	c.handlers["health"] = http.HandlerFunc(c.HealthHandler)
 	c.handlers["query"] = http.HandlerFunc(c.QueryHandler)
+	c.handlers["flux"] = http.HandlerFunc(c.FluxHandler)
 	c.handlers["proxy"] = http.HandlerFunc(c.ProxyHandler)
 }
 
@@ -69,6 +70,15 @@ func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
 			MatchTypeName:   "exact",
 			MatchType:       matching.PathMatchTypeExact,
 		},
+		"/" + mnFluxQuery: {
+			Path:            "/" + mnFluxQuery,
+			HandlerName:     "flux",
+			Methods:         []string{http.MethodPost},
+			CacheKeyParams:  []string{upOrg, upOrgID, upFluxRequest},
+			CacheKeyHeaders: []string{},
+			MatchTypeName:   "exact",
+			MatchType:       matching.PathMatchTypeExact,
+		},
 		"/": {
 			Path:          "/",
 			HandlerName:   "proxy",
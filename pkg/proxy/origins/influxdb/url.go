@@ -18,6 +18,7 @@ package influxdb
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/params"
 	"github.com/tricksterproxy/trickster/pkg/timeseries"
@@ -25,17 +26,31 @@ import (
 
 // Upstream Endpoints
 const (
-	mnQuery = "query"
+	mnQuery     = "query"
+	mnFluxQuery = "api/v2/query"
 )
 
 // Common URL Parameter Names
 const (
 	upQuery = "q"
 	upDB    = "db"
+	upOrg   = "org"
+	upOrgID = "orgID"
+	// upFluxRequest is the TemplateURL parameter carrying the tokenized body of a Flux request
+	upFluxRequest = "flux"
 )
 
+// isFluxPath returns true if the path is the InfluxDB 2.x Flux query endpoint
+func isFluxPath(path string) bool {
+	return strings.HasSuffix(path, "/"+mnFluxQuery)
+}
+
 // SetExtent will change the upstream request query to use the provided Extent
 func (c Client) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery, extent *timeseries.Extent) {
+	if isFluxPath(r.URL.Path) {
+		setFluxExtent(r, trq, extent)
+		return
+	}
 	v, _, _ := params.GetRequestValues(r)
 	// the TemplateURL in the TimeRangeQuery will always have URL Query Params, even for POSTs
 	// For POST, ParseTimeRangeQuery extracts the params from the original request body and
@@ -17,15 +17,22 @@
 package options
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/evictionmethods"
 	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+	ao "github.com/tricksterproxy/trickster/pkg/proxy/acl/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/breaker"
 	rule "github.com/tricksterproxy/trickster/pkg/proxy/origins/rule/options"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/pool"
+	"github.com/tricksterproxy/trickster/pkg/proxy/queryshape"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	so "github.com/tricksterproxy/trickster/pkg/proxy/signing/options"
 	to "github.com/tricksterproxy/trickster/pkg/proxy/tls/options"
 
 	"github.com/gorilla/mux"
@@ -33,6 +40,108 @@ import (
 
 var restrictedOriginNames = map[string]bool{"frontend": true}
 
+// Trailing Slash Policies indicate how request paths with a trailing slash are handled
+const (
+	// TrailingSlashPolicyStrict routes paths with and without a trailing slash as distinct paths
+	TrailingSlashPolicyStrict = "strict"
+	// TrailingSlashPolicyIgnore removes the trailing slash before routing and cache key derivation
+	TrailingSlashPolicyIgnore = "ignore"
+	// TrailingSlashPolicyRedirect redirects paths with a trailing slash to the path without it
+	TrailingSlashPolicyRedirect = "redirect"
+)
+
+// Duplicate Param Policies indicate which values of a repeated query parameter are used
+const (
+	// DuplicateParamPolicyAll uses every value of a repeated query parameter
+	DuplicateParamPolicyAll = "all"
+	// DuplicateParamPolicyFirst uses only the first value of a repeated query parameter
+	DuplicateParamPolicyFirst = "first"
+	// DuplicateParamPolicyLast uses only the last value of a repeated query parameter
+	DuplicateParamPolicyLast = "last"
+)
+
+// Byte Range Reassembly Policies indicate how newly-fetched byte ranges are combined with cached ranges
+const (
+	// ByteRangeReassemblyPolicyMerge merges fetched ranges into the cached ranges, reassembling
+	// the full object body once all of its ranges have been cached
+	ByteRangeReassemblyPolicyMerge = "merge"
+	// ByteRangeReassemblyPolicyReplace replaces any cached ranges with the most recently fetched ranges
+	ByteRangeReassemblyPolicyReplace = "replace"
+)
+
+// Handle100Continue modes indicate how requests with an Expect: 100-continue header are proxied
+const (
+	// Handle100ContinueForward proxies the Expect header to the origin
+	Handle100ContinueForward = "forward"
+	// Handle100ContinueRespond answers the client's 100-continue locally and buffers the request
+	// body before proxying the request to the origin without the Expect header
+	Handle100ContinueRespond = "respond"
+	// Handle100ContinueStrip removes the Expect header before proxying the request to the origin
+	Handle100ContinueStrip = "strip"
+)
+
+// Cache Key Components Policies indicate how requests exceeding MaxCacheKeyComponents are handled
+const (
+	// CacheKeyComponentsPolicyReject proxies the request without caching it
+	CacheKeyComponentsPolicyReject = "reject"
+	// CacheKeyComponentsPolicyTruncate derives the cache key from the first MaxCacheKeyComponents
+	// params and headers, in sorted order
+	CacheKeyComponentsPolicyTruncate = "truncate"
+)
+
+// Conditional Request Policies indicate how client conditional headers are handled when the
+// requested object is not in the cache
+const (
+	// ConditionalRequestPolicyForward proxies the client's conditional headers to the origin
+	ConditionalRequestPolicyForward = "forward"
+	// ConditionalRequestPolicyStripOnMiss removes the client's conditional headers, so that the
+	// origin returns a full response
+	ConditionalRequestPolicyStripOnMiss = "strip-on-miss"
+)
+
+// MaxResponseDataPointsPolicyReject responds to timeseries requests whose responses exceed
+// MaxResponseDataPoints with a 400 Bad Request. The other MaxResponseDataPointsPolicy values
+// are the names of the timeseries.DownsampleMethods used to reduce the response
+const MaxResponseDataPointsPolicyReject = "reject"
+
+// Range Guard Response Formats indicate how the response to a timeseries request whose
+// range exceeds MaxQueryRangeSecs is formatted
+const (
+	// RangeGuardResponseFormatText responds with a text/plain message
+	RangeGuardResponseFormatText = "text"
+	// RangeGuardResponseFormatJSON responds with a JSON error object, in the style of the
+	// Prometheus HTTP API, that includes the maximum and requested ranges in seconds
+	RangeGuardResponseFormatJSON = "json"
+)
+
+// Cache Compression codecs indicate how response bodies are compressed when written to the cache
+const (
+	// CacheCompressionNone stores response bodies uncompressed
+	CacheCompressionNone = "none"
+	// CacheCompressionGzip compresses response bodies with gzip
+	CacheCompressionGzip = "gzip"
+	// CacheCompressionZstd compresses response bodies with zstd
+	CacheCompressionZstd = "zstd"
+)
+
+// Cache Key Encodings indicate how the digest of a derived cache key is encoded
+const (
+	// CacheKeyEncodingHex encodes cache key digests as lowercase hexadecimal
+	CacheKeyEncodingHex = "hex"
+	// CacheKeyEncodingBase64URL encodes cache key digests as unpadded URL-safe base64
+	CacheKeyEncodingBase64URL = "base64url"
+	// CacheKeyEncodingBase32 encodes cache key digests as unpadded standard base32
+	CacheKeyEncodingBase32 = "base32"
+)
+
+// Collapsed Waiters Policies indicate how requests exceeding MaxCollapsedWaiters are handled
+const (
+	// CollapsedWaitersPolicyProxy proxies the excess request to the origin independently
+	CollapsedWaitersPolicyProxy = "proxy"
+	// CollapsedWaitersPolicyReject responds to the excess request with a 503 Service Unavailable
+	CollapsedWaitersPolicyReject = "reject"
+)
+
 // Options is a collection of configurations for Origins proxied by Trickster
 type Options struct {
 
@@ -45,16 +154,74 @@ type Options struct {
 	// OriginURL provides the base upstream URL for all proxied requests to this origin.
 	// it can be as simple as http://example.com or as complex as https://example.com:8443/path/prefix
 	OriginURL string `toml:"origin_url"`
+	// OriginURLs provides the base upstream URLs of replicas of the origin, which requests are
+	// distributed across per LoadBalancing. It may be used instead of OriginURL
+	OriginURLs []string `toml:"origin_urls"`
+	// LoadBalancing is the policy for selecting one of OriginURLs for each upstream request
+	// ('round_robin' or 'random')
+	LoadBalancing string `toml:"load_balancing"`
 	// TimeoutSecs defines how long the HTTP request will wait for a response before timing out
 	TimeoutSecs int64 `toml:"timeout_secs"`
+	// TimeoutResponseCode is the HTTP status code returned downstream when the upstream request times out
+	TimeoutResponseCode int `toml:"timeout_response_code"`
+	// TimeoutResponseBody is the response body returned downstream when the upstream request times out
+	TimeoutResponseBody string `toml:"timeout_response_body"`
+	// TimeoutResponseContentType is the Content-Type of TimeoutResponseBody
+	TimeoutResponseContentType string `toml:"timeout_response_content_type"`
+	// RetryMaxAttempts is the maximum number of times an idempotent upstream request is retried
+	// after a connection error or a RetryStatusCodes response. A value of 0 disables retries
+	RetryMaxAttempts int `toml:"retry_max_attempts"`
+	// RetryInitialBackoffMS is the time to wait before the first retry, in milliseconds.
+	// The wait doubles with each subsequent retry, up to RetryMaxBackoffMS
+	RetryInitialBackoffMS int `toml:"retry_initial_backoff_ms"`
+	// RetryMaxBackoffMS is the maximum time to wait between retries, in milliseconds
+	RetryMaxBackoffMS int `toml:"retry_max_backoff_ms"`
+	// RetryStatusCodes is the list of upstream response status codes that are retried
+	RetryStatusCodes []int `toml:"retry_status_codes"`
+	// FailoverOrigins is the ordered list of names of origins that idempotent upstream requests
+	// are reissued against when this origin fails with a connection error or a 5xx response
+	FailoverOrigins []string `toml:"failover_origins"`
+	// LastResortResponse, when set, is the static response served in place of a 5xx response to the
+	// client, which occurs only once the cache, retries and failover origins have all failed to serve it
+	LastResortResponse *LastResortResponseOptions `toml:"last_resort_response"`
+	// BreakerErrorThreshold is the number of consecutive upstream failures after which the origin's
+	// circuit breaker opens and requests fail immediately. It is disabled when unset (0)
+	BreakerErrorThreshold int `toml:"breaker_error_threshold,omitzero"`
+	// BreakerOpenDurationSecs is how long the circuit breaker remains open before allowing probe requests
+	BreakerOpenDurationSecs int `toml:"breaker_open_duration_secs"`
+	// BreakerHalfOpenRequests is the number of probe requests that must succeed to close the circuit breaker
+	BreakerHalfOpenRequests int `toml:"breaker_half_open_requests"`
 	// KeepAliveTimeoutSecs defines how long an open keep-alive HTTP connection remains idle before closing
 	KeepAliveTimeoutSecs int64 `toml:"keep_alive_timeout_secs"`
 	// MaxIdleConns defines maximum number of open keep-alive connections to maintain
 	MaxIdleConns int `toml:"max_idle_conns"`
+	// MaxConcurrentTLSHandshakes limits the number of TLS handshakes that may be in progress
+	// to the origin at the same time. A value of 0 means unlimited
+	MaxConcurrentTLSHandshakes int `toml:"max_concurrent_tls_handshakes"`
+	// EnableHTTP2Upstream, when true, negotiates HTTP/2 with the origin during the TLS handshake,
+	// falling back to HTTP/1.1 when the origin does not support it
+	EnableHTTP2Upstream bool `toml:"enable_http2_upstream"`
+	// MaxConcurrentUpstreamRequests limits the number of requests that may be in flight to the origin
+	// at the same time. Excess requests wait up to TimeoutSecs for a slot. A value of 0 means unlimited
+	MaxConcurrentUpstreamRequests int `toml:"max_concurrent_upstream_requests"`
 	// CacheName provides the name of the configured cache where the origin client will store it's cache data
 	CacheName string `toml:"cache_name"`
 	// CacheKeyPrefix defines the cache key prefix the origin will use when writing objects to the cache
 	CacheKeyPrefix string `toml:"cache_key_prefix"`
+	// CacheKeyEncoding is the encoding of the digest of derived cache keys ('hex', 'base64url' or 'base32')
+	CacheKeyEncoding string `toml:"cache_key_encoding"`
+	// AsyncCacheWrite, when true, causes cacheable object responses to be written to the cache in
+	// the background after they are sent to the client, rather than before the response completes
+	AsyncCacheWrite bool `toml:"async_cache_write"`
+	// MaxAsyncCacheWrites limits the number of background cache writes that may be in progress for
+	// the origin at once. When the limit is reached, responses are written to the cache before completing
+	MaxAsyncCacheWrites int `toml:"max_async_cache_writes"`
+	// MaxCacheWritesPerSec limits the rate at which the origin writes objects to the cache. Responses
+	// that would exceed the rate are served to the client without being cached. A value of 0 means unlimited
+	MaxCacheWritesPerSec int `toml:"max_cache_writes_per_sec"`
+	// SharedCacheNamespace, when set, is used as the cache key prefix for the origin, so that all origins
+	// configured with the same namespace share cache entries for identical requests
+	SharedCacheNamespace string `toml:"shared_cache_namespace"`
 	// HealthCheckUpstreamPath provides the URL path for the upstream health check
 	HealthCheckUpstreamPath string `toml:"health_check_upstream_path"`
 	// HealthCheckVerb provides the HTTP verb to use when making an upstream health check
@@ -63,10 +230,18 @@ type Options struct {
 	HealthCheckQuery string `toml:"health_check_query"`
 	// HealthCheckHeaders provides the HTTP Headers to apply when making an upstream health check
 	HealthCheckHeaders map[string]string `toml:"health_check_headers"`
+	// HealthCheckIntervalSecs is how often each of OriginURLs is health checked. Replicas failing
+	// the health check are not selected until they pass. A value of 0 disables the health checks
+	HealthCheckIntervalSecs int `toml:"health_check_interval_secs"`
+	// HealthCheckQuorum is how many of OriginURLs must be healthy for the origin to be reported as
+	// healthy by its health handler: 'all', 'any' (default) or an integer no greater than their count
+	HealthCheckQuorum pool.Quorum `toml:"health_check_quorum"`
 	// Object Proxy Cache and Delta Proxy Cache Configurations
 	// TimeseriesRetentionFactor limits the maximum the number of chronological
 	// timestamps worth of data to store in cache for each query
 	TimeseriesRetentionFactor int `toml:"timeseries_retention_factor"`
+	// ValueRetentionFactor is the deprecated name of TimeseriesRetentionFactor
+	ValueRetentionFactor int `toml:"value_retention_factor,omitempty"`
 	// TimeseriesEvictionMethodName specifies which methodology ("oldest", "lru") is used to identify
 	//timeseries to evict from a full cache object
 	TimeseriesEvictionMethodName string `toml:"timeseries_eviction_method"`
@@ -78,6 +253,16 @@ type Options struct {
 	Paths map[string]*po.Options `toml:"paths"`
 	// NegativeCacheName provides the name of the Negative Cache Config to be used by this Origin
 	NegativeCacheName string `toml:"negative_cache_name"`
+	// NegativeCacheBackendName provides the name of an optional Cache Config to be used for storing
+	// negatively-cached responses. When empty, they are stored in the cache named by CacheName
+	NegativeCacheBackendName string `toml:"negative_cache_backend_name"`
+	// NegativeCacheRevalidate, when true, revalidates negatively-cached objects against the upstream
+	// in the background as they are served, and removes them from the cache once the upstream
+	// returns a successful response, rather than serving the cached error until its TTL expires
+	NegativeCacheRevalidate bool `toml:"negative_cache_revalidate"`
+	// NegativeCacheRevalidateIntervalMS is the minimum time between background revalidations of
+	// the same negatively-cached object, in milliseconds
+	NegativeCacheRevalidateIntervalMS int `toml:"negative_cache_revalidate_interval_ms"`
 	// TimeseriesTTLSecs specifies the cache TTL of timeseries objects
 	TimeseriesTTLSecs int `toml:"timeseries_ttl_secs"`
 	// TimeseriesTTLSecs specifies the cache TTL of fast forward data
@@ -87,11 +272,27 @@ type Options struct {
 	// RevalidationFactor specifies how many times to multiply the object freshness lifetime
 	// by to calculate an absolute cache TTL
 	RevalidationFactor float64 `toml:"revalidation_factor"`
+	// TTLAsRangeFraction, when greater than 0, sets the cache TTL of timeseries objects to the
+	// duration of the requested range multiplied by this value, capped by MaxTTLSecs
+	TTLAsRangeFraction float64 `toml:"ttl_as_range_fraction"`
+	// TTLAsRangeFractionMinSecs is the minimum TTL used when TTLAsRangeFraction is in effect
+	TTLAsRangeFractionMinSecs int `toml:"ttl_as_range_fraction_min_secs"`
 	// MaxObjectSizeBytes specifies the max objectsize to be accepted for any given cache object
 	MaxObjectSizeBytes int `toml:"max_object_size_bytes"`
+	// StreamingThresholdBytes, when greater than 0, is the largest declared Content-Length of an upstream
+	// response that is fully buffered before it is delivered downstream. Larger responses, and those without
+	// a declared Content-Length, are streamed to the client while being captured for the cache
+	StreamingThresholdBytes int `toml:"streaming_threshold_bytes"`
 	// CompressableTypeList specifies the HTTP Object Content Types that will be compressed internally
 	// when stored in the Trickster cache
 	CompressableTypeList []string `toml:"compressable_types"`
+	// CacheCompression is the codec used to compress response bodies before they are written to
+	// the cache: 'none' (default), 'gzip' or 'zstd'. Cached bodies are decompressed upon retrieval
+	// per the codec recorded with the object, regardless of the current setting
+	CacheCompression string `toml:"cache_compression"`
+	// BrotliQuality is the brotli quality level, from 0 (fastest) to 11 (smallest), used to
+	// compress responses to clients that accept the br encoding
+	BrotliQuality int `toml:"brotli_quality"`
 	// TracingConfigName provides the name of the Tracing Config to be used by this Origin
 	TracingConfigName string `toml:"tracing_name"`
 	// RuleName provides the name of the rule config to be used by this origin.
@@ -100,9 +301,31 @@ type Options struct {
 	// ReqRewriterName is the name of a configured Rewriter that will modify the request prior to
 	// processing by the origin client
 	ReqRewriterName string `toml:"req_rewriter_name"`
+	// CacheIdentityRewriterName is the name of a configured Rewriter that is applied to a copy of the
+	// request, whose resulting method and canonicalized URL are used to derive the cache key. When set, it
+	// takes precedence over the cache_key_params, cache_key_headers and cache_key_form_fields of all paths
+	CacheIdentityRewriterName string `toml:"cache_identity_rewriter_name"`
+	// CacheValidationRewriterName is the name of a configured Rewriter that is applied, upon a cache
+	// hit, to a copy of the request bearing the cached object's headers. When the Rewriter would modify
+	// the copy, or fails on a missing reference, the object is invalid and is fetched anew
+	CacheValidationRewriterName string `toml:"cache_validation_rewriter_name"`
+	// CoalescingKeyRewriterName is the name of a configured Rewriter that is applied to a copy of the
+	// request, whose resulting method, canonicalized URL, body and Authorization header are used to derive
+	// the key by which concurrent requests share a progressive collapsed forwarding fetch. When empty,
+	// requests are coalesced by their cache key
+	CoalescingKeyRewriterName string `toml:"coalescing_key_rewriter_name"`
 
 	// TLS is the TLS Configuration for the Frontend and Backend
 	TLS *to.Options `toml:"tls"`
+	// TrailingSlashPolicy indicates how request paths with a trailing slash are handled:
+	// 'strict' routes them as distinct paths, 'ignore' removes the trailing slash before routing
+	// and cache key derivation, and 'redirect' responds with a 308 redirect to the path without it
+	TrailingSlashPolicy string `toml:"trailing_slash_policy"`
+	// DuplicateParamPolicy indicates which values of a repeated URL query parameter or form field are
+	// used for both cache key derivation and the upstream request: 'all', 'first' or 'last'
+	DuplicateParamPolicy string `toml:"duplicate_param_policy"`
+	// RequestSigning is the configuration for signing upstream requests with an HMAC
+	RequestSigning *so.Options `toml:"request_signing"`
 
 	// ForwardedHeaders indicates the class of 'Forwarded' header to attach to upstream requests
 	ForwardedHeaders string `toml:"forwarded_headers"`
@@ -113,8 +336,31 @@ type Options struct {
 	FastForwardDisable bool `toml:"fast_forward_disable"`
 	// PathRoutingDisabled, when true, will bypass /originName/path route registrations
 	PathRoutingDisabled bool `toml:"path_routing_disabled"`
+	// StripPathPrefix is a path prefix under which the origin's paths are served to clients, and which is
+	// removed from the request path before proxying (e.g., '/prometheus' routes '/prometheus/api/v1/query'
+	// to the upstream's '/api/v1/query'). Requests without the prefix are not routed to the origin's paths
+	StripPathPrefix string `toml:"strip_path_prefix"`
+	// StripPathPrefixFromCacheKey, when true, derives cache keys from the path after StripPathPrefix
+	// is removed, rather than from the path as requested by the client
+	StripPathPrefixFromCacheKey bool `toml:"strip_path_prefix_from_cache_key"`
 	// RequireTLS, when true, indicates this Origin Config's paths must only be registered with the TLS Router
 	RequireTLS bool `toml:"require_tls"`
+	// FrontendNames is the list of frontends whose listeners serve this origin. 'default' refers to the
+	// main [frontend]. When empty, the origin is served by all frontends
+	FrontendNames []string `toml:"frontend_names"`
+	// JWTAuthDisabled, when true, serves this origin without the JWT validation of any frontend
+	// with a jwt_auth config
+	JWTAuthDisabled bool `toml:"jwt_auth_disabled"`
+	// ACL, when set, restricts the client addresses from which this origin accepts requests,
+	// in addition to the acl of the frontend that accepted them
+	ACL *ao.Options `toml:"acl"`
+	// DefaultPathMethods is the list of HTTP methods routed for any configured path that does not
+	// specify its own methods. When empty, such paths are routed for GET and HEAD
+	DefaultPathMethods []string `toml:"default_path_methods"`
+	// CacheableMethods is the list of HTTP methods eligible for caching. Requests using any other method
+	// are proxied without caching, even when routed to a caching handler. When empty, any method routed
+	// to a caching handler is eligible
+	CacheableMethods []string `toml:"cacheable_methods"`
 	// MultipartRangesDisabled, when true, indicates that if a downstream client requests multiple ranges
 	// in a single request, Trickster will instead request and return a 200 OK with the full object body
 	MultipartRangesDisabled bool `toml:"multipart_ranges_disabled"`
@@ -123,6 +369,118 @@ type Options struct {
 	// expects a multipart response	// this optimizes Trickster to request as few bytes as possible when
 	// fronting origins that only support single range requests
 	DearticulateUpstreamRanges bool `toml:"dearticulate_upstream_ranges"`
+	// CacheByteRanges, when false, indicates that partial content (206) responses from the origin
+	// are not written to the cache. Range requests are still served from fully-cached objects
+	CacheByteRanges bool `toml:"cache_byte_ranges"`
+	// ByteRangeReassemblyPolicy indicates how newly-fetched byte ranges are combined with the cached
+	// ranges of an object: 'merge' (default) or 'replace'
+	ByteRangeReassemblyPolicy string `toml:"byte_range_reassembly_policy"`
+	// EmitAgeHeader, when true, indicates that Trickster will attach an Age header to responses
+	// served from cache, conveying how long the object has resided in the cache
+	EmitAgeHeader bool `toml:"emit_age_header"`
+	// EmitStaleWarning, when true, indicates that Trickster will attach a Warning header to responses
+	// served from stale cached data, per RFC 7234
+	EmitStaleWarning bool `toml:"emit_stale_warning"`
+	// EmitServerTiming, when true, indicates that Trickster will append a Server-Timing header to
+	// responses, conveying the durations of the cache lookup and upstream fetch
+	EmitServerTiming bool `toml:"emit_server_timing"`
+	// DownstreamCacheControl, when set, replaces the Cache-Control header of responses to clients,
+	// independently of the upstream Cache-Control header that Trickster uses for its own caching
+	DownstreamCacheControl string `toml:"downstream_cache_control"`
+	// HonorClientMaxAge, when true, indicates that a Cache-Control max-age directive provided by the client
+	// is honored, such that cached objects older than the client's max-age are treated as stale.
+	// Since it allows cache busting, it is only honored for clients permitted by ClientMaxAgeACL
+	HonorClientMaxAge bool `toml:"honor_client_max_age"`
+	// ClientMaxAgeACL restricts the clients whose max-age directive is honored when HonorClientMaxAge
+	// is true, and must allow at least one network
+	ClientMaxAgeACL *ao.Options `toml:"client_max_age_acl"`
+	// StripHopByHopHeaders, when true, removes all RFC 7230 hop-by-hop headers, plus any headers
+	// nominated in the Connection header, from upstream requests and downstream responses
+	StripHopByHopHeaders bool `toml:"strip_hop_by_hop_headers"`
+	// DefaultUpstreamContentType is the Content-Type applied to upstream responses that do not include one,
+	// before they are cached and served to the client
+	DefaultUpstreamContentType string `toml:"default_upstream_content_type"`
+	// SniffContentType, when true, detects the Content-Type of upstream responses that do not include one
+	// from the first 512 bytes of the response body, using DefaultUpstreamContentType, when set, for any
+	// body that is not recognized
+	SniffContentType bool `toml:"sniff_content_type"`
+	// Handle100Continue indicates how requests with an Expect: 100-continue header are proxied:
+	// 'forward' (default), 'respond' or 'strip'
+	Handle100Continue string `toml:"handle_100_continue"`
+	// Max100ContinueBodyBytes is the largest request body buffered when Handle100Continue is 'respond'.
+	// Requests with larger bodies are answered with a 413
+	Max100ContinueBodyBytes int `toml:"max_100_continue_body_bytes"`
+	// IncludeHostInCacheKey, when true, includes the Host requested by the client in the cache key,
+	// so that the same path requested under different hostnames is cached separately
+	IncludeHostInCacheKey bool `toml:"include_host_in_cache_key"`
+	// IncludeSchemeInCacheKey, when true, includes the scheme (http or https) requested by the client
+	// in the cache key
+	IncludeSchemeInCacheKey bool `toml:"include_scheme_in_cache_key"`
+	// MaxCacheKeyComponents, when greater than 0, limits the combined number of params and headers
+	// that participate in a request's cache key
+	MaxCacheKeyComponents int `toml:"max_cache_key_components"`
+	// CacheKeyComponentsPolicy indicates how requests exceeding MaxCacheKeyComponents are handled:
+	// 'reject' (default) or 'truncate'
+	CacheKeyComponentsPolicy string `toml:"cache_key_components_policy"`
+	// MaxDistinctQueryShapes, when greater than 0, limits the number of distinct timeseries query
+	// shapes (queries with their literals and ranges stripped) that are cached. Queries of new shapes
+	// beyond the limit are proxied without caching until the least recently used shape goes idle
+	MaxDistinctQueryShapes int `toml:"max_distinct_query_shapes"`
+	// CanonicalizeCacheKeyHeaders, when true, normalizes the casing of header names before they are
+	// included in the cache key, so that differently-cased request headers produce the same key
+	CanonicalizeCacheKeyHeaders bool `toml:"canonicalize_cache_key_headers"`
+	// CacheKeyHeaders is the list of request headers included in the cache key of every path of the
+	// origin, in addition to each path's cache_key_headers, unless the path replaces them
+	CacheKeyHeaders []string `toml:"cache_key_headers"`
+	// CacheKeyCookies is the list of request cookies whose values are included in the cache key of every
+	// path of the origin, in addition to each path's cache_key_cookies
+	CacheKeyCookies []string `toml:"cache_key_cookies"`
+	// CacheKeyFromAuthHash, when true, includes a SHA-256 hash of the client's identity header in
+	// the cache key instead of the header's raw value, so that each identity is cached separately
+	CacheKeyFromAuthHash bool `toml:"cache_key_from_auth_hash"`
+	// CacheKeyAuthHeader is the name of the header identifying the client when CacheKeyFromAuthHash
+	// is true. The default is Authorization
+	CacheKeyAuthHeader string `toml:"cache_key_auth_header"`
+	// MaxCollapsedWaiters, when greater than 0, limits the number of requests that may concurrently
+	// wait on the collapsed-forwarding fetch of the same object
+	MaxCollapsedWaiters int `toml:"max_collapsed_waiters"`
+	// CollapsedWaitersPolicy indicates how requests exceeding MaxCollapsedWaiters are handled:
+	// 'proxy' (default) or 'reject'
+	CollapsedWaitersPolicy string `toml:"collapsed_waiters_policy"`
+	// CollapsedForwardingTimeoutMS, when greater than 0, limits how long a request waits on another
+	// request's collapsed-forwarding fetch of the same object before abandoning the collapse
+	CollapsedForwardingTimeoutMS int `toml:"collapsed_forwarding_timeout_ms"`
+	// CollapsedForwardingTimeoutPolicy indicates how requests exceeding CollapsedForwardingTimeoutMS
+	// are handled: 'proxy' (default) or 'reject'
+	CollapsedForwardingTimeoutPolicy string `toml:"collapsed_forwarding_timeout_policy"`
+	// IdempotencyKeyHeader, when set, is the name of a request header (e.g., Idempotency-Key) whose value
+	// deduplicates POST, PUT, PATCH and DELETE requests: the first response for a given key is cached
+	// for IdempotencyWindowSecs and replayed for retries of the request, without proxying them upstream
+	IdempotencyKeyHeader string `toml:"idempotency_key_header"`
+	// IdempotencyWindowSecs is how long the response to a request carrying an IdempotencyKeyHeader
+	// is replayed for retries of the request
+	IdempotencyWindowSecs int `toml:"idempotency_window_secs"`
+	// MaxResponseDataPoints, when greater than 0, limits the number of values, across all series, in the
+	// timeseries responses returned to clients. Larger responses are handled per MaxResponseDataPointsPolicy
+	MaxResponseDataPoints int `toml:"max_response_data_points"`
+	// MaxResponseDataPointsPolicy indicates how responses exceeding MaxResponseDataPoints are handled:
+	// 'lttb' (default) or 'average' to downsample each series, or 'reject'
+	MaxResponseDataPointsPolicy string `toml:"max_response_data_points_policy"`
+	// MaxQueryRangeSecs, when greater than 0, is the longest time range a timeseries request may
+	// query. Requests for longer ranges are rejected with a 400 Bad Request
+	MaxQueryRangeSecs int `toml:"max_query_range_secs"`
+	// RangeGuardResponseBody is the message returned to requests exceeding MaxQueryRangeSecs. It may
+	// include the {max_range} and {requested_range} placeholders. A default message is used if empty
+	RangeGuardResponseBody string `toml:"range_guard_response_body"`
+	// RangeGuardResponseFormat indicates how responses to requests exceeding MaxQueryRangeSecs are
+	// formatted: 'text' (default) or 'json'
+	RangeGuardResponseFormat string `toml:"range_guard_response_format"`
+	// ConditionalRequestPolicy indicates how client conditional headers (e.g., If-None-Match) are
+	// handled when the requested object is not in the cache: 'forward' (default) or 'strip-on-miss'
+	ConditionalRequestPolicy string `toml:"conditional_request_policy"`
+	// MaintenanceMode, when true, responds to all proxied requests for the origin with a
+	// 503 Service Unavailable, without contacting the upstream. Health checks are unaffected
+	MaintenanceMode bool `toml:"maintenance_mode"`
 
 	// Synthesized Configurations
 	// These configurations are parsed versions of those defined above, and are what Trickster uses internally
@@ -133,6 +491,14 @@ type Options struct {
 	Router *mux.Router `toml:"-"`
 	// Timeout is the time.Duration representation of TimeoutSecs
 	Timeout time.Duration `toml:"-"`
+	// CollapsedForwardingTimeout is the time.Duration representation of CollapsedForwardingTimeoutMS
+	CollapsedForwardingTimeout time.Duration `toml:"-"`
+	// RetryInitialBackoff is the time.Duration representation of RetryInitialBackoffMS
+	RetryInitialBackoff time.Duration `toml:"-"`
+	// NegativeCacheRevalidateInterval is the time.Duration representation of NegativeCacheRevalidateIntervalMS
+	NegativeCacheRevalidateInterval time.Duration `toml:"-"`
+	// RetryMaxBackoff is the time.Duration representation of RetryMaxBackoffMS
+	RetryMaxBackoff time.Duration `toml:"-"`
 	// BackfillTolerance is the time.Duration representation of BackfillToleranceSecs
 	BackfillTolerance time.Duration `toml:"-"`
 	// ValueRetention is the time.Duration representation of ValueRetentionSecs
@@ -160,6 +526,12 @@ type Options struct {
 	FastForwardPath *po.Options `toml:"-"`
 	// MaxTTL is the parsed value of MaxTTLSecs
 	MaxTTL time.Duration `toml:"-"`
+	// TTLAsRangeFractionMin is the parsed value of TTLAsRangeFractionMinSecs
+	TTLAsRangeFractionMin time.Duration `toml:"-"`
+	// IdempotencyWindow is the parsed value of IdempotencyWindowSecs
+	IdempotencyWindow time.Duration `toml:"-"`
+	// MaxQueryRange is the parsed value of MaxQueryRangeSecs
+	MaxQueryRange time.Duration `toml:"-"`
 	// HTTPClient is the Client used by trickster to communicate with this origin
 	HTTPClient *http.Client `toml:"-"`
 	// CompressableTypes is the map version of CompressableTypeList for fast lookup
@@ -168,58 +540,282 @@ type Options struct {
 	RuleOptions *rule.Options `toml:"-"`
 	// ReqRewriter is the rewriter handler as indicated by RuleName
 	ReqRewriter rewriter.RewriteInstructions
+	// CacheIdentityRewriter is the rewriter as indicated by CacheIdentityRewriterName
+	CacheIdentityRewriter rewriter.RewriteInstructions `toml:"-"`
+	// CacheValidationRewriter is the rewriter as indicated by CacheValidationRewriterName
+	CacheValidationRewriter rewriter.RewriteInstructions `toml:"-"`
+	// CoalescingKeyRewriter is the rewriter as indicated by CoalescingKeyRewriterName
+	CoalescingKeyRewriter rewriter.RewriteInstructions `toml:"-"`
+	// Pool is the load-balanced pool of replicas as indicated by OriginURLs
+	Pool *pool.Pool `toml:"-"`
+	// ClaimCacheKeyHeaders is the list of JWT claim headers, set on the origin's requests by the
+	// frontends serving it, that are included in the cache key of every path of the origin
+	ClaimCacheKeyHeaders []string `toml:"-"`
+	// Breaker is the origin's circuit breaker, when BreakerErrorThreshold is greater than 0
+	Breaker *breaker.Breaker `toml:"-"`
+	// QueryShapes tracks the distinct query shapes cached by the origin, when MaxDistinctQueryShapes is greater than 0
+	QueryShapes *queryshape.Tracker `toml:"-"`
+	// AsyncCacheWriteSlots is the semaphore bounding the origin's background cache writes to MaxAsyncCacheWrites
+	AsyncCacheWriteSlots *Slots `toml:"-"`
+	// CacheWriteLimiter is the rate limiter bounding the origin's cache writes to MaxCacheWritesPerSec
+	CacheWriteLimiter *RateLimiter `toml:"-"`
+	// UpstreamRequestSlots is the semaphore bounding the origin's in-flight upstream requests to
+	// MaxConcurrentUpstreamRequests
+	UpstreamRequestSlots *Slots `toml:"-"`
+	// FailoverOriginConfigs is the list of references to the Origin Options as indicated by FailoverOrigins
+	FailoverOriginConfigs []*Options `toml:"-"`
+
+	// maintenance is 1 when the origin is in maintenance mode. It is initialized from
+	// MaintenanceMode and may be changed at runtime, until the config is reloaded
+	maintenance int32
+}
+
+// Slots is a semaphore that limits the number of concurrent operations
+type Slots struct {
+	c chan struct{}
+}
+
+// NewSlots returns a new Slots with the provided capacity
+func NewSlots(capacity int) *Slots {
+	return &Slots{c: make(chan struct{}, capacity)}
+}
+
+// TryAcquire acquires a slot without blocking, and returns false if none is available
+func (s *Slots) TryAcquire() bool {
+	if s == nil {
+		return false
+	}
+	select {
+	case s.c <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Acquire waits for a slot until ctx is done, and returns false if none was acquired
+func (s *Slots) Acquire(ctx context.Context) bool {
+	if s == nil {
+		return false
+	}
+	select {
+	case s.c <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Release releases a slot acquired with TryAcquire or Acquire
+func (s *Slots) Release() {
+	<-s.c
+}
+
+// InUse returns the number of acquired slots
+func (s *Slots) InUse() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.c)
+}
+
+// LastResortResponseOptions is a collection of configurations for the static response served in place
+// of an origin's failed responses
+type LastResortResponseOptions struct {
+	// Code is the HTTP status code of the response; default is 200
+	Code int `toml:"code"`
+	// Body is the body of the response
+	Body string `toml:"body"`
+	// Headers is a map of the headers of the response
+	Headers map[string]string `toml:"headers"`
+}
+
+// Clone returns an exact copy of a LastResortResponseOptions
+func (lr *LastResortResponseOptions) Clone() *LastResortResponseOptions {
+	if lr == nil {
+		return nil
+	}
+	lr2 := *lr
+	if lr.Headers != nil {
+		lr2.Headers = make(map[string]string, len(lr.Headers))
+		for k, v := range lr.Headers {
+			lr2.Headers[k] = v
+		}
+	}
+	return &lr2
 }
 
 // NewOptions will return a pointer to an OriginConfig with the default configuration settings
 func NewOptions() *Options {
 	return &Options{
-		BackfillTolerance:            d.DefaultBackfillToleranceSecs,
-		BackfillToleranceSecs:        d.DefaultBackfillToleranceSecs,
-		CacheKeyPrefix:               "",
-		CacheName:                    d.DefaultOriginCacheName,
-		CompressableTypeList:         d.DefaultCompressableTypes(),
-		FastForwardTTL:               d.DefaultFastForwardTTLSecs * time.Second,
-		FastForwardTTLSecs:           d.DefaultFastForwardTTLSecs,
-		ForwardedHeaders:             d.DefaultForwardedHeaders,
-		HealthCheckHeaders:           make(map[string]string),
-		HealthCheckQuery:             d.DefaultHealthCheckQuery,
-		HealthCheckUpstreamPath:      d.DefaultHealthCheckPath,
-		HealthCheckVerb:              d.DefaultHealthCheckVerb,
-		KeepAliveTimeoutSecs:         d.DefaultKeepAliveTimeoutSecs,
-		MaxIdleConns:                 d.DefaultMaxIdleConns,
-		MaxObjectSizeBytes:           d.DefaultMaxObjectSizeBytes,
-		MaxTTL:                       d.DefaultMaxTTLSecs * time.Second,
-		MaxTTLSecs:                   d.DefaultMaxTTLSecs,
-		NegativeCache:                make(map[int]time.Duration),
-		NegativeCacheName:            d.DefaultOriginNegativeCacheName,
-		Paths:                        make(map[string]*po.Options),
-		RevalidationFactor:           d.DefaultRevalidationFactor,
-		TLS:                          &to.Options{},
-		Timeout:                      time.Second * d.DefaultOriginTimeoutSecs,
-		TimeoutSecs:                  d.DefaultOriginTimeoutSecs,
-		TimeseriesEvictionMethod:     d.DefaultOriginTEM,
-		TimeseriesEvictionMethodName: d.DefaultOriginTEMName,
-		TimeseriesRetention:          d.DefaultOriginTRF,
-		TimeseriesRetentionFactor:    d.DefaultOriginTRF,
-		TimeseriesTTL:                d.DefaultTimeseriesTTLSecs * time.Second,
-		TimeseriesTTLSecs:            d.DefaultTimeseriesTTLSecs,
-		TracingConfigName:            d.DefaultTracingConfigName,
+		BackfillTolerance:                 d.DefaultBackfillToleranceSecs,
+		ByteRangeReassemblyPolicy:         d.DefaultByteRangeReassemblyPolicy,
+		BreakerOpenDurationSecs:           d.DefaultBreakerOpenDurationSecs,
+		BreakerHalfOpenRequests:           d.DefaultBreakerHalfOpenRequests,
+		CacheByteRanges:                   d.DefaultCacheByteRanges,
+		BackfillToleranceSecs:             d.DefaultBackfillToleranceSecs,
+		CacheKeyPrefix:                    "",
+		CacheKeyEncoding:                  d.DefaultCacheKeyEncoding,
+		MaxAsyncCacheWrites:               d.DefaultMaxAsyncCacheWrites,
+		CacheName:                         d.DefaultOriginCacheName,
+		CompressableTypeList:              d.DefaultCompressableTypes(),
+		CacheCompression:                  d.DefaultCacheCompression,
+		BrotliQuality:                     d.DefaultBrotliQuality,
+		EmitAgeHeader:                     d.DefaultEmitAgeHeader,
+		EmitStaleWarning:                  d.DefaultEmitStaleWarning,
+		StripHopByHopHeaders:              d.DefaultStripHopByHopHeaders,
+		Handle100Continue:                 d.DefaultHandle100Continue,
+		Max100ContinueBodyBytes:           d.DefaultMax100ContinueBodyBytes,
+		CacheKeyComponentsPolicy:          d.DefaultCacheKeyComponentsPolicy,
+		CanonicalizeCacheKeyHeaders:       d.DefaultCanonicalizeCacheKeyHeaders,
+		CacheKeyAuthHeader:                d.DefaultCacheKeyAuthHeader,
+		CollapsedWaitersPolicy:            d.DefaultCollapsedWaitersPolicy,
+		CollapsedForwardingTimeoutPolicy:  d.DefaultCollapsedForwardingTimeoutPolicy,
+		ConditionalRequestPolicy:          d.DefaultConditionalRequestPolicy,
+		MaxResponseDataPointsPolicy:       d.DefaultMaxResponseDataPointsPolicy,
+		RangeGuardResponseFormat:          d.DefaultRangeGuardResponseFormat,
+		FastForwardTTL:                    d.DefaultFastForwardTTLSecs * time.Second,
+		FastForwardTTLSecs:                d.DefaultFastForwardTTLSecs,
+		ForwardedHeaders:                  d.DefaultForwardedHeaders,
+		TrailingSlashPolicy:               d.DefaultTrailingSlashPolicy,
+		DuplicateParamPolicy:              d.DefaultDuplicateParamPolicy,
+		HealthCheckHeaders:                make(map[string]string),
+		HealthCheckIntervalSecs:           d.DefaultHealthCheckIntervalSecs,
+		HealthCheckQuorum:                 d.DefaultHealthCheckQuorum,
+		LoadBalancing:                     d.DefaultLoadBalancing,
+		IdempotencyWindow:                 d.DefaultIdempotencyWindowSecs * time.Second,
+		IdempotencyWindowSecs:             d.DefaultIdempotencyWindowSecs,
+		HealthCheckQuery:                  d.DefaultHealthCheckQuery,
+		HealthCheckUpstreamPath:           d.DefaultHealthCheckPath,
+		HealthCheckVerb:                   d.DefaultHealthCheckVerb,
+		KeepAliveTimeoutSecs:              d.DefaultKeepAliveTimeoutSecs,
+		MaxIdleConns:                      d.DefaultMaxIdleConns,
+		MaxObjectSizeBytes:                d.DefaultMaxObjectSizeBytes,
+		MaxTTL:                            d.DefaultMaxTTLSecs * time.Second,
+		MaxTTLSecs:                        d.DefaultMaxTTLSecs,
+		NegativeCache:                     make(map[int]time.Duration),
+		NegativeCacheName:                 d.DefaultOriginNegativeCacheName,
+		Paths:                             make(map[string]*po.Options),
+		RetryInitialBackoff:               d.DefaultRetryInitialBackoffMS * time.Millisecond,
+		RetryInitialBackoffMS:             d.DefaultRetryInitialBackoffMS,
+		NegativeCacheRevalidateInterval:   d.DefaultNegativeCacheRevalidateIntervalMS * time.Millisecond,
+		NegativeCacheRevalidateIntervalMS: d.DefaultNegativeCacheRevalidateIntervalMS,
+		RetryMaxBackoff:                   d.DefaultRetryMaxBackoffMS * time.Millisecond,
+		RetryMaxBackoffMS:                 d.DefaultRetryMaxBackoffMS,
+		RetryStatusCodes:                  d.DefaultRetryStatusCodes(),
+		RevalidationFactor:                d.DefaultRevalidationFactor,
+		TLS:                               &to.Options{},
+		Timeout:                           time.Second * d.DefaultOriginTimeoutSecs,
+		TimeoutSecs:                       d.DefaultOriginTimeoutSecs,
+		TimeoutResponseCode:               d.DefaultTimeoutResponseCode,
+		TimeoutResponseContentType:        d.DefaultTimeoutResponseContentType,
+		TimeseriesEvictionMethod:          d.DefaultOriginTEM,
+		TimeseriesEvictionMethodName:      d.DefaultOriginTEMName,
+		TimeseriesRetention:               d.DefaultOriginTRF,
+		TimeseriesRetentionFactor:         d.DefaultOriginTRF,
+		TimeseriesTTL:                     d.DefaultTimeseriesTTLSecs * time.Second,
+		TimeseriesTTLSecs:                 d.DefaultTimeseriesTTLSecs,
+		TTLAsRangeFractionMin:             d.DefaultTTLAsRangeFractionMinSecs * time.Second,
+		TTLAsRangeFractionMinSecs:         d.DefaultTTLAsRangeFractionMinSecs,
+		TracingConfigName:                 d.DefaultTracingConfigName,
 	}
 }
 
+// InMaintenance returns true if the origin is in maintenance mode
+func (oc *Options) InMaintenance() bool {
+	return atomic.LoadInt32(&oc.maintenance) == 1
+}
+
+// SetMaintenance places the origin in, or removes it from, maintenance mode. The change
+// lasts until the config is reloaded, which reapplies MaintenanceMode
+func (oc *Options) SetMaintenance(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&oc.maintenance, v)
+}
+
 // Clone returns an exact copy of an *origins.Options
 func (oc *Options) Clone() *Options {
 
 	o := &Options{}
 	o.DearticulateUpstreamRanges = oc.DearticulateUpstreamRanges
+	o.CacheByteRanges = oc.CacheByteRanges
+	o.ByteRangeReassemblyPolicy = oc.ByteRangeReassemblyPolicy
 	o.BackfillTolerance = oc.BackfillTolerance
 	o.BackfillToleranceSecs = oc.BackfillToleranceSecs
 	o.CacheName = oc.CacheName
 	o.CacheKeyPrefix = oc.CacheKeyPrefix
+	o.CacheKeyEncoding = oc.CacheKeyEncoding
+	o.AsyncCacheWrite = oc.AsyncCacheWrite
+	o.MaxAsyncCacheWrites = oc.MaxAsyncCacheWrites
+	o.MaxCacheWritesPerSec = oc.MaxCacheWritesPerSec
+	o.CacheWriteLimiter = oc.CacheWriteLimiter
+	o.AsyncCacheWriteSlots = oc.AsyncCacheWriteSlots
+	o.SharedCacheNamespace = oc.SharedCacheNamespace
+	o.NegativeCacheBackendName = oc.NegativeCacheBackendName
+	o.NegativeCacheRevalidate = oc.NegativeCacheRevalidate
+	o.NegativeCacheRevalidateIntervalMS = oc.NegativeCacheRevalidateIntervalMS
+	o.NegativeCacheRevalidateInterval = oc.NegativeCacheRevalidateInterval
+	o.EmitAgeHeader = oc.EmitAgeHeader
+	o.EmitStaleWarning = oc.EmitStaleWarning
+	o.DownstreamCacheControl = oc.DownstreamCacheControl
+	o.EmitServerTiming = oc.EmitServerTiming
+	o.HonorClientMaxAge = oc.HonorClientMaxAge
+	o.StripHopByHopHeaders = oc.StripHopByHopHeaders
+	o.DefaultUpstreamContentType = oc.DefaultUpstreamContentType
+	o.SniffContentType = oc.SniffContentType
+	o.Handle100Continue = oc.Handle100Continue
+	o.Max100ContinueBodyBytes = oc.Max100ContinueBodyBytes
+	o.IncludeHostInCacheKey = oc.IncludeHostInCacheKey
+	o.IncludeSchemeInCacheKey = oc.IncludeSchemeInCacheKey
+	o.MaxCacheKeyComponents = oc.MaxCacheKeyComponents
+	o.MaxDistinctQueryShapes = oc.MaxDistinctQueryShapes
+	o.QueryShapes = oc.QueryShapes
+	o.CacheKeyComponentsPolicy = oc.CacheKeyComponentsPolicy
+	o.CanonicalizeCacheKeyHeaders = oc.CanonicalizeCacheKeyHeaders
+	if oc.CacheKeyHeaders != nil {
+		o.CacheKeyHeaders = make([]string, len(oc.CacheKeyHeaders))
+		copy(o.CacheKeyHeaders, oc.CacheKeyHeaders)
+	}
+	if oc.CacheKeyCookies != nil {
+		o.CacheKeyCookies = make([]string, len(oc.CacheKeyCookies))
+		copy(o.CacheKeyCookies, oc.CacheKeyCookies)
+	}
+	if oc.ClaimCacheKeyHeaders != nil {
+		o.ClaimCacheKeyHeaders = make([]string, len(oc.ClaimCacheKeyHeaders))
+		copy(o.ClaimCacheKeyHeaders, oc.ClaimCacheKeyHeaders)
+	}
+	o.CacheKeyFromAuthHash = oc.CacheKeyFromAuthHash
+	o.CacheKeyAuthHeader = oc.CacheKeyAuthHeader
+	o.MaxCollapsedWaiters = oc.MaxCollapsedWaiters
+	o.CollapsedWaitersPolicy = oc.CollapsedWaitersPolicy
+	o.CollapsedForwardingTimeoutMS = oc.CollapsedForwardingTimeoutMS
+	o.CollapsedForwardingTimeout = oc.CollapsedForwardingTimeout
+	o.CollapsedForwardingTimeoutPolicy = oc.CollapsedForwardingTimeoutPolicy
+	o.ConditionalRequestPolicy = oc.ConditionalRequestPolicy
+	o.MaintenanceMode = oc.MaintenanceMode
+	o.maintenance = atomic.LoadInt32(&oc.maintenance)
+	o.CacheCompression = oc.CacheCompression
+	o.BrotliQuality = oc.BrotliQuality
+	o.MaxResponseDataPoints = oc.MaxResponseDataPoints
+	o.MaxResponseDataPointsPolicy = oc.MaxResponseDataPointsPolicy
+	o.MaxQueryRangeSecs = oc.MaxQueryRangeSecs
+	o.MaxQueryRange = oc.MaxQueryRange
+	o.RangeGuardResponseBody = oc.RangeGuardResponseBody
+	o.RangeGuardResponseFormat = oc.RangeGuardResponseFormat
+	o.IdempotencyKeyHeader = oc.IdempotencyKeyHeader
+	o.IdempotencyWindowSecs = oc.IdempotencyWindowSecs
+	o.IdempotencyWindow = oc.IdempotencyWindow
 	o.FastForwardDisable = oc.FastForwardDisable
 	o.FastForwardTTL = oc.FastForwardTTL
 	o.FastForwardTTLSecs = oc.FastForwardTTLSecs
 	o.ForwardedHeaders = oc.ForwardedHeaders
+	o.TrailingSlashPolicy = oc.TrailingSlashPolicy
+	o.DuplicateParamPolicy = oc.DuplicateParamPolicy
+	o.StripPathPrefix = oc.StripPathPrefix
+	o.StripPathPrefixFromCacheKey = oc.StripPathPrefixFromCacheKey
 	o.HealthCheckUpstreamPath = oc.HealthCheckUpstreamPath
 	o.HealthCheckVerb = oc.HealthCheckVerb
 	o.HealthCheckQuery = oc.HealthCheckQuery
@@ -228,19 +824,69 @@ func (oc *Options) Clone() *Options {
 	o.IsDefault = oc.IsDefault
 	o.KeepAliveTimeoutSecs = oc.KeepAliveTimeoutSecs
 	o.MaxIdleConns = oc.MaxIdleConns
+	o.MaxConcurrentTLSHandshakes = oc.MaxConcurrentTLSHandshakes
+	o.EnableHTTP2Upstream = oc.EnableHTTP2Upstream
+	o.MaxConcurrentUpstreamRequests = oc.MaxConcurrentUpstreamRequests
+	o.UpstreamRequestSlots = oc.UpstreamRequestSlots
 	o.MaxTTLSecs = oc.MaxTTLSecs
 	o.MaxTTL = oc.MaxTTL
 	o.MaxObjectSizeBytes = oc.MaxObjectSizeBytes
+	o.StreamingThresholdBytes = oc.StreamingThresholdBytes
 	o.MultipartRangesDisabled = oc.MultipartRangesDisabled
+	o.JWTAuthDisabled = oc.JWTAuthDisabled
+	o.ACL = oc.ACL.Clone()
+	o.ClientMaxAgeACL = oc.ClientMaxAgeACL.Clone()
 	o.OriginType = oc.OriginType
 	o.OriginURL = oc.OriginURL
+	if oc.OriginURLs != nil {
+		o.OriginURLs = make([]string, len(oc.OriginURLs))
+		copy(o.OriginURLs, oc.OriginURLs)
+	}
+	o.LoadBalancing = oc.LoadBalancing
+	o.HealthCheckIntervalSecs = oc.HealthCheckIntervalSecs
+	o.HealthCheckQuorum = oc.HealthCheckQuorum
+	o.Pool = oc.Pool
+	o.BreakerErrorThreshold = oc.BreakerErrorThreshold
+	o.BreakerOpenDurationSecs = oc.BreakerOpenDurationSecs
+	o.BreakerHalfOpenRequests = oc.BreakerHalfOpenRequests
+	o.Breaker = oc.Breaker
 	o.PathPrefix = oc.PathPrefix
 	o.ReqRewriterName = oc.ReqRewriterName
+	o.CacheIdentityRewriterName = oc.CacheIdentityRewriterName
+	o.CacheIdentityRewriter = oc.CacheIdentityRewriter
+	o.CacheValidationRewriterName = oc.CacheValidationRewriterName
+	o.CacheValidationRewriter = oc.CacheValidationRewriter
+	o.CoalescingKeyRewriterName = oc.CoalescingKeyRewriterName
+	o.CoalescingKeyRewriter = oc.CoalescingKeyRewriter
 	o.RevalidationFactor = oc.RevalidationFactor
+	o.TTLAsRangeFraction = oc.TTLAsRangeFraction
+	o.TTLAsRangeFractionMin = oc.TTLAsRangeFractionMin
+	o.TTLAsRangeFractionMinSecs = oc.TTLAsRangeFractionMinSecs
 	o.RuleName = oc.RuleName
 	o.Scheme = oc.Scheme
 	o.Timeout = oc.Timeout
 	o.TimeoutSecs = oc.TimeoutSecs
+	o.TimeoutResponseCode = oc.TimeoutResponseCode
+	o.TimeoutResponseBody = oc.TimeoutResponseBody
+	o.TimeoutResponseContentType = oc.TimeoutResponseContentType
+	o.RetryMaxAttempts = oc.RetryMaxAttempts
+	o.RetryInitialBackoff = oc.RetryInitialBackoff
+	o.RetryInitialBackoffMS = oc.RetryInitialBackoffMS
+	o.RetryMaxBackoff = oc.RetryMaxBackoff
+	o.RetryMaxBackoffMS = oc.RetryMaxBackoffMS
+	if oc.RetryStatusCodes != nil {
+		o.RetryStatusCodes = make([]int, len(oc.RetryStatusCodes))
+		copy(o.RetryStatusCodes, oc.RetryStatusCodes)
+	}
+	o.LastResortResponse = oc.LastResortResponse.Clone()
+	if oc.FailoverOrigins != nil {
+		o.FailoverOrigins = make([]string, len(oc.FailoverOrigins))
+		copy(o.FailoverOrigins, oc.FailoverOrigins)
+	}
+	if oc.FailoverOriginConfigs != nil {
+		o.FailoverOriginConfigs = make([]*Options, len(oc.FailoverOriginConfigs))
+		copy(o.FailoverOriginConfigs, oc.FailoverOriginConfigs)
+	}
 	o.TimeseriesRetention = oc.TimeseriesRetention
 	o.TimeseriesRetentionFactor = oc.TimeseriesRetentionFactor
 	o.TimeseriesEvictionMethodName = oc.TimeseriesEvictionMethodName
@@ -295,8 +941,26 @@ func (oc *Options) Clone() *Options {
 	if oc.TLS != nil {
 		o.TLS = oc.TLS.Clone()
 	}
+	if oc.RequestSigning != nil {
+		o.RequestSigning = oc.RequestSigning.Clone()
+	}
 	o.RequireTLS = oc.RequireTLS
 
+	if oc.FrontendNames != nil {
+		o.FrontendNames = make([]string, len(oc.FrontendNames))
+		copy(o.FrontendNames, oc.FrontendNames)
+	}
+
+	if oc.DefaultPathMethods != nil {
+		o.DefaultPathMethods = make([]string, len(oc.DefaultPathMethods))
+		copy(o.DefaultPathMethods, oc.DefaultPathMethods)
+	}
+
+	if oc.CacheableMethods != nil {
+		o.CacheableMethods = make([]string, len(oc.CacheableMethods))
+		copy(o.CacheableMethods, oc.CacheableMethods)
+	}
+
 	if oc.FastForwardPath != nil {
 		o.FastForwardPath = oc.FastForwardPath.Clone()
 	}
@@ -308,6 +972,19 @@ func (oc *Options) Clone() *Options {
 	return o
 }
 
+// IsCacheableMethod returns true if requests using the method are eligible for caching
+func (oc *Options) IsCacheableMethod(method string) bool {
+	if len(oc.CacheableMethods) == 0 {
+		return true
+	}
+	for _, m := range oc.CacheableMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateOriginName ensures the origin name is permitted against the dictionary of
 // restricted words
 func ValidateOriginName(name string) error {
@@ -17,6 +17,7 @@
 package options
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
@@ -49,6 +50,24 @@ func TestClone(t *testing.T) {
 
 }
 
+func TestIsCacheableMethod(t *testing.T) {
+	o := NewOptions()
+	if !o.IsCacheableMethod(http.MethodPost) {
+		t.Error("expected any method to be cacheable when cacheable_methods is empty")
+	}
+	o.CacheableMethods = []string{http.MethodGet, http.MethodPost}
+	if !o.IsCacheableMethod(http.MethodPost) {
+		t.Errorf("expected %s to be cacheable", http.MethodPost)
+	}
+	if o.IsCacheableMethod(http.MethodDelete) {
+		t.Errorf("expected %s to be uncacheable", http.MethodDelete)
+	}
+	o2 := o.Clone()
+	if len(o2.CacheableMethods) != 2 {
+		t.Errorf("expected %d got %d", 2, len(o2.CacheableMethods))
+	}
+}
+
 func TestValidateOriginName(t *testing.T) {
 
 	err := ValidateOriginName("test")
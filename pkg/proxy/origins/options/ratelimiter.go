@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that limits the rate of an operation to a number of operations per
+// second, permitting bursts of up to one second's worth of operations
+type RateLimiter struct {
+	mtx    sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter returns a new RateLimiter permitting the provided number of operations per second
+func NewRateLimiter(perSec int) *RateLimiter {
+	return &RateLimiter{rate: float64(perSec), tokens: float64(perSec), last: time.Now(), now: time.Now}
+}
+
+// Allow consumes a token and returns true if one is available, and otherwise returns false.
+// A nil RateLimiter allows all operations
+func (l *RateLimiter) Allow() bool {
+	if l == nil {
+		return true
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
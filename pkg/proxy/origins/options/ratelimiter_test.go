@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+
+	var l *RateLimiter
+	if !l.Allow() {
+		t.Error("expected nil limiter to allow")
+	}
+
+	now := time.Unix(1577836800, 0)
+	l = NewRateLimiter(2)
+	l.last = now
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !l.Allow() {
+			t.Errorf("expected allow for operation %d", i)
+		}
+	}
+	if l.Allow() {
+		t.Error("expected operation exceeding the burst to be denied")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if !l.Allow() {
+		t.Error("expected allow after a token was replenished")
+	}
+	if l.Allow() {
+		t.Error("expected deny after the replenished token was consumed")
+	}
+
+	// tokens do not accumulate beyond one second's worth
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		l.Allow()
+	}
+	if l.Allow() {
+		t.Error("expected operation exceeding the burst to be denied")
+	}
+}
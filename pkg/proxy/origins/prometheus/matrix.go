@@ -420,3 +420,49 @@ func (me *MatrixEnvelope) Size() int {
 	wg.Wait()
 	return int(c)
 }
+
+// Downsample reduces each series in the Timeseries to at most the provided number of values
+func (me *MatrixEnvelope) Downsample(sz int, method timeseries.DownsampleMethod) {
+	for _, s := range me.Data.Result {
+		if len(s.Values) <= sz {
+			continue
+		}
+		if method == timeseries.DownsampleAverage {
+			s.Values = averageSamples(s.Values, sz)
+			continue
+		}
+		x := make([]float64, len(s.Values))
+		y := make([]float64, len(s.Values))
+		for i, v := range s.Values {
+			x[i] = float64(v.Timestamp)
+			y[i] = float64(v.Value)
+		}
+		idx := timeseries.LTTB(x, y, sz)
+		vals := make([]model.SamplePair, len(idx))
+		for i, j := range idx {
+			vals[i] = s.Values[j]
+		}
+		s.Values = vals
+	}
+	me.isCounted = false
+}
+
+// averageSamples returns sz samples, each averaging a bucket of consecutive values
+// and having the timestamp of the first value in the bucket
+func averageSamples(vals []model.SamplePair, sz int) []model.SamplePair {
+	starts := timeseries.AverageBuckets(len(vals), sz)
+	out := make([]model.SamplePair, len(starts))
+	for i, start := range starts {
+		end := len(vals)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		var sum model.SampleValue
+		for _, v := range vals[start:end] {
+			sum += v.Value
+		}
+		out[i] = model.SamplePair{Timestamp: vals[start].Timestamp,
+			Value: sum / model.SampleValue(end-start)}
+	}
+	return out
+}
@@ -1707,3 +1707,40 @@ func TestSize(t *testing.T) {
 		t.Errorf("expected %d got %d", expected, i)
 	}
 }
+
+func TestDownsample(t *testing.T) {
+
+	newEnvelope := func() *MatrixEnvelope {
+		s := &model.SampleStream{Metric: model.Metric{"__name__": "a"}}
+		for i := 0; i < 10; i++ {
+			s.Values = append(s.Values, model.SamplePair{Timestamp: model.Time(i * 1000),
+				Value: model.SampleValue(i)})
+		}
+		short := &model.SampleStream{Metric: model.Metric{"__name__": "b"},
+			Values: []model.SamplePair{{Timestamp: 0, Value: 1}}}
+		return &MatrixEnvelope{Data: MatrixData{ResultType: "matrix", Result: model.Matrix{s, short}}}
+	}
+
+	me := newEnvelope()
+	me.Downsample(4, timeseries.DownsampleLTTB)
+	if me.ValueCount() != 5 {
+		t.Errorf("expected %d got %d", 5, me.ValueCount())
+	}
+	vals := me.Data.Result[0].Values
+	if vals[0].Timestamp != 0 || vals[3].Timestamp != 9000 {
+		t.Errorf("expected first and last values to be kept, got %v", vals)
+	}
+
+	me = newEnvelope()
+	me.Downsample(5, timeseries.DownsampleAverage)
+	vals = me.Data.Result[0].Values
+	if len(vals) != 5 {
+		t.Fatalf("expected %d got %d", 5, len(vals))
+	}
+	if vals[1].Timestamp != 2000 || vals[1].Value != 2.5 {
+		t.Errorf("expected %d %f got %d %f", 2000, 2.5, vals[1].Timestamp, vals[1].Value)
+	}
+	if len(me.Data.Result[1].Values) != 1 {
+		t.Errorf("expected %d got %d", 1, len(me.Data.Result[1].Values))
+	}
+}
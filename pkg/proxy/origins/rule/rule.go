@@ -75,7 +75,9 @@ func (r *rule) EvaluateOpArg(hr *http.Request) (http.Handler, *http.Request, err
 
 	// if this case includes ingress rewriter instructions, execute those now
 	if len(r.ingressReqRewriter) > 0 {
-		r.ingressReqRewriter.Execute(hr)
+		if err := r.ingressReqRewriter.Execute(hr); err != nil {
+			return badRequestHandler, hr, nil
+		}
 	}
 
 	var h http.Handler = r.defaultRouter
@@ -89,7 +91,9 @@ func (r *rule) EvaluateOpArg(hr *http.Request) (http.Handler, *http.Request, err
 
 		// if this case includes rewriter instructions, execute those now
 		if len(c.rewriter) > 0 {
-			c.rewriter.Execute(hr)
+			if err := c.rewriter.Execute(hr); err != nil {
+				return badRequestHandler, hr, nil
+			}
 		}
 
 		// if it's a redirect response, set the appropriate context
@@ -100,12 +104,16 @@ func (r *rule) EvaluateOpArg(hr *http.Request) (http.Handler, *http.Request, err
 	}
 
 	if !nonDefault && r.defaultRewriter != nil {
-		r.defaultRewriter.Execute(hr)
+		if err := r.defaultRewriter.Execute(hr); err != nil {
+			return badRequestHandler, hr, nil
+		}
 	}
 
 	// if this case includes egress rewriter instructions, execute those now
 	if len(r.egressReqRewriter) > 0 {
-		r.egressReqRewriter.Execute(hr)
+		if err := r.egressReqRewriter.Execute(hr); err != nil {
+			return badRequestHandler, hr, nil
+		}
 	}
 
 	if !nonDefault && r.defaultRedirectCode > 0 {
@@ -131,7 +139,9 @@ func (r *rule) EvaluateCaseArg(hr *http.Request) (http.Handler, *http.Request, e
 
 	// if this case includes ingress rewriter instructions, execute those now
 	if len(r.ingressReqRewriter) > 0 {
-		r.ingressReqRewriter.Execute(hr)
+		if err := r.ingressReqRewriter.Execute(hr); err != nil {
+			return badRequestHandler, hr, nil
+		}
 	}
 
 	var h http.Handler = r.defaultRouter
@@ -150,7 +160,9 @@ func (r *rule) EvaluateCaseArg(hr *http.Request) (http.Handler, *http.Request, e
 
 			// if this case includes rewriter instructions, execute those now
 			if len(c.rewriter) > 0 {
-				c.rewriter.Execute(hr)
+				if err := c.rewriter.Execute(hr); err != nil {
+					return badRequestHandler, hr, nil
+				}
 			}
 
 			// if it's a redirect response, set the appropriate context
@@ -162,12 +174,16 @@ func (r *rule) EvaluateCaseArg(hr *http.Request) (http.Handler, *http.Request, e
 	}
 
 	if !nonDefault && r.defaultRewriter != nil {
-		r.defaultRewriter.Execute(hr)
+		if err := r.defaultRewriter.Execute(hr); err != nil {
+			return badRequestHandler, hr, nil
+		}
 	}
 
 	// if this case includes egress rewriter instructions, execute those now
 	if len(r.egressReqRewriter) > 0 {
-		r.egressReqRewriter.Execute(hr)
+		if err := r.egressReqRewriter.Execute(hr); err != nil {
+			return badRequestHandler, hr, nil
+		}
 	}
 
 	if !nonDefault && r.defaultRedirectCode > 0 {
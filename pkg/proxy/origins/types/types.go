@@ -34,6 +34,12 @@ const (
 	OriginTypeIronDB
 	// OriginTypeClickHouse represents the ClickHouse origin type
 	OriginTypeClickHouse
+	// OriginTypeGRPCPassthrough represents the uncached gRPC Passthrough origin type
+	OriginTypeGRPCPassthrough
+	// OriginTypeVictoriaMetrics represents the VictoriaMetrics origin type
+	OriginTypeVictoriaMetrics
+	// OriginTypeGraphite represents the Graphite origin type
+	OriginTypeGraphite
 )
 
 // Names is a map of OriginTypes keyed by string name
@@ -45,6 +51,9 @@ var Names = map[string]OriginType{
 	"influxdb":          OriginTypeInfluxDB,
 	"irondb":            OriginTypeIronDB,
 	"clickhouse":        OriginTypeClickHouse,
+	"grpc_passthrough":  OriginTypeGRPCPassthrough,
+	"victoriametrics":   OriginTypeVictoriaMetrics,
+	"graphite":          OriginTypeGraphite,
 }
 
 // Values is a map of OriginTypes valued by string name
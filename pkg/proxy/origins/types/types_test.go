@@ -53,6 +53,8 @@ func TestIsValidOriginType(t *testing.T) {
 		{"invalid", false},
 		{"influxdb", true},
 		{"irondb", true},
+		{"victoriametrics", true},
+		{"graphite", true},
 	}
 
 	for i, test := range tests {
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package victoriametrics
+
+import (
+	"net/http"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins/prometheus"
+	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+)
+
+// DefaultPathConfigs returns the default PathConfigs for the given OriginType
+func (c *Client) DefaultPathConfigs(oc *oo.Options) map[string]*po.Options {
+
+	paths := c.Client.DefaultPathConfigs(oc)
+
+	// VictoriaMetrics-specific parameters that change query results must vary the cache key
+	paths[prometheus.APIPath+mnQueryRange].CacheKeyParams = []string{upQuery, upStep,
+		upExtraLabel, upExtraFilters, upRoundDigits}
+	paths[prometheus.APIPath+mnQuery].CacheKeyParams = []string{upQuery, upTime,
+		upExtraLabel, upExtraFilters, upRoundDigits}
+	paths[prometheus.APIPath+mnSeries].CacheKeyParams = []string{upMatch, upStart, upEnd,
+		upExtraLabel, upExtraFilters}
+
+	// exports of raw samples, and the status of currently-running queries, are not cached
+	paths[prometheus.APIPath+mnExport] = &po.Options{
+		Path:          prometheus.APIPath + mnExport,
+		HandlerName:   "proxy",
+		Methods:       []string{http.MethodGet, http.MethodPost},
+		MatchType:     matching.PathMatchTypePrefix,
+		MatchTypeName: "prefix",
+	}
+	for _, p := range []string{mnActiveQueries, mnTopQueries} {
+		paths[prometheus.APIPath+p] = &po.Options{
+			Path:          prometheus.APIPath + p,
+			HandlerName:   "proxy",
+			Methods:       []string{http.MethodGet},
+			MatchType:     matching.PathMatchTypeExact,
+			MatchTypeName: "exact",
+		}
+	}
+
+	oc.FastForwardPath = paths[prometheus.APIPath+mnQuery].Clone()
+
+	return paths
+
+}
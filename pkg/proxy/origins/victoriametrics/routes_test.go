@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package victoriametrics
+
+import (
+	"testing"
+
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins/prometheus"
+)
+
+func TestDefaultPathConfigs(t *testing.T) {
+
+	oc := oo.NewOptions()
+	c, err := NewClient("test", oc, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dpc := c.(*Client).DefaultPathConfigs(oc)
+
+	const expectedLen = 16
+	if len(dpc) != expectedLen {
+		t.Errorf("expected ordered length to be: %d got %d", expectedLen, len(dpc))
+	}
+
+	if p, ok := dpc[prometheus.APIPath+mnExport]; !ok || p.HandlerName != "proxy" {
+		t.Errorf("expected proxy path for %s", prometheus.APIPath+mnExport)
+	}
+
+	p := dpc[prometheus.APIPath+mnQueryRange]
+	if len(p.CacheKeyParams) != 5 || p.CacheKeyParams[2] != upExtraLabel {
+		t.Errorf("unexpected cache key params %v", p.CacheKeyParams)
+	}
+
+	if oc.FastForwardPath == nil || oc.FastForwardPath.Path != prometheus.APIPath+mnQuery {
+		t.Error("expected fast forward path")
+	}
+}
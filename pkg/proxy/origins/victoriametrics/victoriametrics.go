@@ -0,0 +1,191 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package victoriametrics provides the VictoriaMetrics Origin Type
+package victoriametrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/cache"
+	"github.com/tricksterproxy/trickster/pkg/proxy/errors"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins/prometheus"
+	"github.com/tricksterproxy/trickster/pkg/proxy/params"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+var _ origins.Client = (*Client)(nil)
+
+// VictoriaMetrics API
+const (
+	mnQueryRange    = "query_range"
+	mnQuery         = "query"
+	mnSeries        = "series"
+	mnExport        = "export"
+	mnActiveQueries = "status/active_queries"
+	mnTopQueries    = "status/top_queries"
+)
+
+// Common URL Parameter Names
+const (
+	upQuery        = "query"
+	upStart        = "start"
+	upEnd          = "end"
+	upStep         = "step"
+	upTime         = "time"
+	upMatch        = "match[]"
+	upExtraLabel   = "extra_label"
+	upExtraFilters = "extra_filters[]"
+	upRoundDigits  = "round_digits"
+)
+
+// defaultStep is the step VictoriaMetrics uses for range queries that do not provide one
+const defaultStep = 5 * time.Minute
+
+// Client Implements Proxy Client Interface. VictoriaMetrics implements the Prometheus
+// HTTP API, so the Client uses the Prometheus Client's handlers and timeseries
+// marshaling, but parses queries and registers default paths for VictoriaMetrics
+type Client struct {
+	*prometheus.Client
+}
+
+// NewClient returns a new Client Instance
+func NewClient(name string, oc *oo.Options, router http.Handler,
+	cache cache.Cache) (origins.Client, error) {
+	pc, err := prometheus.NewClient(name, oc, router, cache)
+	return &Client{Client: pc.(*prometheus.Client)}, err
+}
+
+// parseTime converts a query time URL parameter to time.Time
+func parseTime(s string) (time.Time, error) {
+	if t, err := strconv.ParseFloat(s, 64); err == nil {
+		s, ns := math.Modf(t)
+		ns = math.Round(ns*1000) / 1000
+		return time.Unix(int64(s), int64(ns*float64(time.Second))), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q to a valid timestamp", s)
+}
+
+// durationUnits are the units supported by MetricsQL durations
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  24 * 7 * time.Hour,
+	"y":  24 * 365 * time.Hour,
+}
+
+// parseDuration parses VictoriaMetrics step parameters, which can be float64 seconds or
+// MetricsQL durations, which may be fractional or combine several units, like 1.5h or 1h30m
+func parseDuration(input string) (time.Duration, error) {
+	if v, err := strconv.ParseFloat(input, 64); err == nil {
+		// assume v is in seconds
+		return time.Duration(v * float64(time.Second)), nil
+	}
+	if input == "" {
+		return errors.ParseDuration(input)
+	}
+	var d float64
+	for s := input; s != ""; {
+		i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if i <= 0 {
+			return errors.ParseDuration(input)
+		}
+		v, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return errors.ParseDuration(input)
+		}
+		s = s[i:]
+		j := strings.IndexFunc(s, func(r rune) bool { return (r >= '0' && r <= '9') || r == '.' })
+		if j < 0 {
+			j = len(s)
+		}
+		u, ok := durationUnits[s[:j]]
+		if !ok {
+			return errors.ParseDuration(input)
+		}
+		d += v * float64(u)
+		s = s[j:]
+	}
+	return time.Duration(d), nil
+}
+
+// ParseTimeRangeQuery parses the key parts of a TimeRangeQuery from the inbound HTTP Request.
+// As with VictoriaMetrics, the end time defaults to now, and the step defaults to 5m
+func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery, error) {
+
+	trq := &timeseries.TimeRangeQuery{Extent: timeseries.Extent{}}
+
+	qp, _, _ := params.GetRequestValues(r)
+
+	trq.Statement = qp.Get(upQuery)
+	if trq.Statement == "" {
+		return nil, errors.MissingURLParam(upQuery)
+	}
+
+	if p := qp.Get(upStart); p != "" {
+		t, err := parseTime(p)
+		if err != nil {
+			return nil, err
+		}
+		trq.Extent.Start = t
+	} else {
+		return nil, errors.MissingURLParam(upStart)
+	}
+
+	if p := qp.Get(upEnd); p != "" {
+		t, err := parseTime(p)
+		if err != nil {
+			return nil, err
+		}
+		trq.Extent.End = t
+	} else {
+		trq.Extent.End = time.Now()
+	}
+
+	if p := qp.Get(upStep); p != "" {
+		step, err := parseDuration(p)
+		if err != nil {
+			return nil, err
+		}
+		trq.Step = step
+	} else {
+		trq.Step = defaultStep
+	}
+
+	if strings.Contains(trq.Statement, " offset ") {
+		trq.IsOffset = true
+		trq.FastForwardDisable = true
+	}
+
+	if strings.Contains(trq.Statement, timeseries.FastForwardUserDisableFlag) {
+		trq.FastForwardDisable = true
+	}
+
+	return trq, nil
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package victoriametrics
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/timeseries"
+)
+
+func TestVictoriaMetricsClientInterfacing(t *testing.T) {
+
+	// this test ensures the client will properly conform to the
+	// Client and TimeseriesClient interfaces
+
+	c, err := NewClient("test", &oo.Options{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tc origins.TimeseriesClient = c.(*Client)
+
+	if tc.Name() != "test" {
+		t.Errorf("expected %s got %s", "test", tc.Name())
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected time.Duration
+		err      bool
+	}{
+		{"15", 15 * time.Second, false},
+		{"0.5", 500 * time.Millisecond, false},
+		{"5m", 5 * time.Minute, false},
+		{"500ms", 500 * time.Millisecond, false},
+		{"1.5h", 90 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{"1d", 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"", 0, true},
+		{"5x", 0, true},
+		{"m", 0, true},
+		{"5m3", 0, true},
+		{"1..5m", 0, true},
+	}
+
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			d, err := parseDuration(test.input)
+			if (err != nil) != test.err {
+				t.Errorf("expected error %t got %v", test.err, err)
+			}
+			if d != test.expected {
+				t.Errorf("expected %s got %s", test.expected, d)
+			}
+		})
+	}
+}
+
+func TestParseTimeRangeQuery(t *testing.T) {
+
+	now := time.Now()
+	req := &http.Request{URL: &url.URL{
+		Scheme: "https",
+		Host:   "blah.com",
+		Path:   "/",
+		RawQuery: url.Values(map[string][]string{
+			"query": {`rate(up[5i]) offset 1h ` + timeseries.FastForwardUserDisableFlag},
+			"start": {strconv.Itoa(int(now.Add(time.Duration(-6) * time.Hour).Unix()))},
+			"end":   {strconv.Itoa(int(now.Unix()))},
+			"step":  {"1m30s"},
+		}).Encode(),
+	}}
+	client := &Client{}
+	res, err := client.ParseTimeRangeQuery(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Step != 90*time.Second {
+		t.Errorf("expected %s got %s", 90*time.Second, res.Step)
+	}
+	if int(res.Extent.End.Sub(res.Extent.Start).Hours()) != 6 {
+		t.Errorf("expected 6 got %d", int(res.Extent.End.Sub(res.Extent.Start).Hours()))
+	}
+	if !res.IsOffset || !res.FastForwardDisable {
+		t.Errorf("expected true got %t/%t", res.IsOffset, res.FastForwardDisable)
+	}
+
+	// end and step are optional
+	req.URL.RawQuery = url.Values(map[string][]string{
+		"query": {`up`},
+		"start": {strconv.Itoa(int(now.Add(time.Duration(-6) * time.Hour).Unix()))},
+	}).Encode()
+	res, err = client.ParseTimeRangeQuery(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Step != defaultStep {
+		t.Errorf("expected %s got %s", defaultStep, res.Step)
+	}
+	if res.Extent.End.Before(now) {
+		t.Errorf("expected end time of now got %s", res.Extent.End)
+	}
+}
+
+func TestParseTimeRangeQueryErrors(t *testing.T) {
+
+	start := strconv.Itoa(int(time.Now().Add(time.Duration(-6) * time.Hour).Unix()))
+	tests := []url.Values{
+		{"start": {start}},
+		{"query": {"up"}},
+		{"query": {"up"}, "start": {"x"}},
+		{"query": {"up"}, "start": {start}, "end": {"x"}},
+		{"query": {"up"}, "start": {start}, "step": {"1q"}},
+	}
+
+	client := &Client{}
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			req := &http.Request{URL: &url.URL{Scheme: "https", Host: "blah.com", Path: "/",
+				RawQuery: test.Encode()}}
+			if _, err := client.ParseTimeRangeQuery(req); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
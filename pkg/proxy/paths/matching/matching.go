@@ -26,12 +26,15 @@ const (
 	PathMatchTypeExact = PathMatchType(iota)
 	// PathMatchTypePrefix indicates the router will map the Path by prefix against incoming requests
 	PathMatchTypePrefix
+	// PathMatchTypeRegex indicates the router will map the Path by regular expression against incoming requests
+	PathMatchTypeRegex
 )
 
 // Names is a map of PathMatchTypes keyed by string name
 var Names = map[string]PathMatchType{
 	"exact":  PathMatchTypeExact,
 	"prefix": PathMatchTypePrefix,
+	"regex":  PathMatchTypeRegex,
 }
 
 // Values is a map of PathMatchTypes valued by string name
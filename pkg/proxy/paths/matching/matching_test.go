@@ -22,6 +22,7 @@ func TestPMTString(t *testing.T) {
 
 	t1 := PathMatchTypeExact
 	t2 := PathMatchTypePrefix
+	t4 := PathMatchTypeRegex
 
 	var t3 PathMatchType = 3
 
@@ -33,6 +34,10 @@ func TestPMTString(t *testing.T) {
 		t.Errorf("expected %s got %s", "prefix", t2.String())
 	}
 
+	if t4.String() != "regex" {
+		t.Errorf("expected %s got %s", "regex", t4.String())
+	}
+
 	if t3.String() != "3" {
 		t.Errorf("expected %s got %s", "3", t3.String())
 	}
@@ -18,12 +18,14 @@ package options
 
 import (
 	"net/http"
+	"regexp"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/key"
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
+	"github.com/tricksterproxy/trickster/pkg/proxy/transform"
 	"github.com/tricksterproxy/trickster/pkg/util/strings"
 	ts "github.com/tricksterproxy/trickster/pkg/util/strings"
 )
@@ -32,8 +34,13 @@ import (
 type Options struct {
 	// Path indicates the HTTP Request's URL PATH to which this configuration applies
 	Path string `toml:"path"`
-	// MatchTypeName indicates the type of path match the router will apply to the path ('exact' or 'prefix')
+	// MatchTypeName indicates the type of path match the router will apply to the path ('exact', 'prefix'
+	// or 'regex'). With 'regex', Path is a regular expression that must match the entire request path
 	MatchTypeName string `toml:"match_type"`
+	// MatchPriority orders the evaluation of the origin's paths against incoming requests, from highest
+	// to lowest priority. Paths of equal priority are evaluated from most to least specific (longest path
+	// first). The default is 0, so a path with a priority of 1 or more is evaluated before all other paths
+	MatchPriority int `toml:"match_priority"`
 	// HandlerName provides the name of the HTTP handler to use
 	HandlerName string `toml:"handler"`
 	// Methods provides the list of permitted HTTP request methods for this Path
@@ -43,6 +50,9 @@ type Options struct {
 	CacheKeyParams []string `toml:"cache_key_params"`
 	// CacheKeyHeaders provides the list of http request headers to be included in the hash for each request's cache key
 	CacheKeyHeaders []string `toml:"cache_key_headers"`
+	// CacheKeyCookies provides the list of http request cookies whose values are included in the hash for
+	// each request's cache key. Cookies that are not listed, such as session cookies, do not affect the key
+	CacheKeyCookies []string `toml:"cache_key_cookies"`
 	// CacheKeyFormFields provides the list of http request body fields to be included
 	// in the hash for each request's cache key
 	CacheKeyFormFields []string `toml:"cache_key_form_fields"`
@@ -61,6 +71,9 @@ type Options struct {
 	// ReqRewriterName is the name of a configured Rewriter that will modify the request prior to
 	// processing by the origin client
 	ReqRewriterName string `toml:"req_rewriter_name"`
+	// ResponseTransform is the name of a built-in converter (e.g., 'xml2json') that is applied to
+	// upstream responses for this path before they are cached and returned to the client
+	ResponseTransform string `toml:"response_transform"`
 
 	// Handler is the HTTP Handler represented by the Path's HandlerName
 	Handler http.Handler `toml:"-"`
@@ -68,6 +81,8 @@ type Options struct {
 	ResponseBodyBytes []byte `toml:"-"`
 	// MatchType is the PathMatchType representation of MatchTypeName
 	MatchType matching.PathMatchType `toml:"-"`
+	// PathRegexp is the compiled representation of Path when the MatchType is regex
+	PathRegexp *regexp.Regexp `toml:"-"`
 	// CollapsedForwardingType is the typed representation of CollapsedForwardingName
 	CollapsedForwardingType forwarding.CollapsedForwardingType `toml:"-"`
 	// KeyHasher points to an optional function that hashes the cacheKey with a custom algorithm
@@ -78,9 +93,20 @@ type Options struct {
 	Custom []string `toml:"-"`
 	// ReqRewriter is the rewriter handler as indicated by RuleName
 	ReqRewriter rewriter.RewriteInstructions
+	// ResponseTransformer is the converter as indicated by ResponseTransform
+	ResponseTransformer *transform.Transformer `toml:"-"`
 
 	// NoMetrics, when set to true, disables metrics decoration for the path
 	NoMetrics bool `toml:"no_metrics"`
+	// CacheDisabled, when set to true, proxies all requests to the path without reading from or
+	// writing to the cache, regardless of the path's handler or the origin's TTLs
+	CacheDisabled bool `toml:"cache_disabled"`
+	// ReplaceCacheKeyHeaders, when set to true, uses only the path's CacheKeyHeaders in the cache key,
+	// rather than adding them to the origin's cache_key_headers
+	ReplaceCacheKeyHeaders bool `toml:"replace_cache_key_headers"`
+	// Upgradeable, when set to true, tunnels requests to the path that ask to upgrade the connection
+	// (e.g., to a WebSocket) to the origin, rather than rejecting them
+	Upgradeable bool `toml:"upgradeable"`
 	// HasCustomResponseBody is a boolean indicating if the response body is custom
 	// this flag allows an empty string response to be configured as a return value
 	HasCustomResponseBody bool `toml:"-"`
@@ -98,6 +124,7 @@ func NewOptions() *Options {
 		CollapsedForwardingType: forwarding.CFTypeBasic,
 		CacheKeyParams:          make([]string, 0),
 		CacheKeyHeaders:         make([]string, 0),
+		CacheKeyCookies:         make([]string, 0),
 		CacheKeyFormFields:      make([]string, 0),
 		Custom:                  make([]string, 0),
 		RequestHeaders:          make(map[string]string),
@@ -114,22 +141,30 @@ func (o *Options) Clone() *Options {
 		//		OriginConfig:            o.OriginConfig,
 		MatchTypeName:           o.MatchTypeName,
 		MatchType:               o.MatchType,
+		PathRegexp:              o.PathRegexp,
+		MatchPriority:           o.MatchPriority,
 		HandlerName:             o.HandlerName,
 		Handler:                 o.Handler,
 		RequestHeaders:          ts.CloneMap(o.RequestHeaders),
 		RequestParams:           ts.CloneMap(o.RequestParams),
 		ReqRewriter:             o.ReqRewriter,
 		ReqRewriterName:         o.ReqRewriterName,
+		ResponseTransform:       o.ResponseTransform,
+		ResponseTransformer:     o.ResponseTransformer,
 		ResponseHeaders:         ts.CloneMap(o.ResponseHeaders),
 		ResponseBody:            o.ResponseBody,
 		ResponseBodyBytes:       o.ResponseBodyBytes,
 		CollapsedForwardingName: o.CollapsedForwardingName,
 		CollapsedForwardingType: o.CollapsedForwardingType,
 		NoMetrics:               o.NoMetrics,
+		CacheDisabled:           o.CacheDisabled,
+		ReplaceCacheKeyHeaders:  o.ReplaceCacheKeyHeaders,
+		Upgradeable:             o.Upgradeable,
 		HasCustomResponseBody:   o.HasCustomResponseBody,
 		Methods:                 make([]string, len(o.Methods)),
 		CacheKeyParams:          make([]string, len(o.CacheKeyParams)),
 		CacheKeyHeaders:         make([]string, len(o.CacheKeyHeaders)),
+		CacheKeyCookies:         make([]string, len(o.CacheKeyCookies)),
 		CacheKeyFormFields:      make([]string, len(o.CacheKeyFormFields)),
 		Custom:                  make([]string, len(o.Custom)),
 		KeyHasher:               o.KeyHasher,
@@ -137,6 +172,7 @@ func (o *Options) Clone() *Options {
 	copy(c.Methods, o.Methods)
 	copy(c.CacheKeyParams, o.CacheKeyParams)
 	copy(c.CacheKeyHeaders, o.CacheKeyHeaders)
+	copy(c.CacheKeyCookies, o.CacheKeyCookies)
 	copy(c.CacheKeyFormFields, o.CacheKeyFormFields)
 	copy(c.Custom, o.Custom)
 	return c
@@ -152,9 +188,13 @@ func (o *Options) Merge(o2 *Options) {
 		switch c {
 		case "path":
 			o.Path = o2.Path
+			o.PathRegexp = o2.PathRegexp
 		case "match_type":
 			o.MatchType = o2.MatchType
 			o.MatchTypeName = o2.MatchTypeName
+			o.PathRegexp = o2.PathRegexp
+		case "match_priority":
+			o.MatchPriority = o2.MatchPriority
 		case "handler":
 			o.HandlerName = o2.HandlerName
 			o.Handler = o2.Handler
@@ -164,6 +204,10 @@ func (o *Options) Merge(o2 *Options) {
 			o.CacheKeyParams = o2.CacheKeyParams
 		case "cache_key_headers":
 			o.CacheKeyHeaders = o2.CacheKeyHeaders
+		case "cache_key_cookies":
+			o.CacheKeyCookies = o2.CacheKeyCookies
+		case "replace_cache_key_headers":
+			o.ReplaceCacheKeyHeaders = o2.ReplaceCacheKeyHeaders
 		case "cache_key_form_fields":
 			o.CacheKeyFormFields = o2.CacheKeyFormFields
 		case "request_headers":
@@ -180,13 +224,53 @@ func (o *Options) Merge(o2 *Options) {
 			o.ResponseBodyBytes = o2.ResponseBodyBytes
 		case "no_metrics":
 			o.NoMetrics = o2.NoMetrics
+		case "cache_disabled":
+			o.CacheDisabled = o2.CacheDisabled
+		case "upgradeable":
+			o.Upgradeable = o2.Upgradeable
 		case "collapsed_forwarding":
 			o.CollapsedForwardingName = o2.CollapsedForwardingName
 			o.CollapsedForwardingType = o2.CollapsedForwardingType
 		case "req_rewriter_name":
 			o.ReqRewriterName = o2.ReqRewriterName
 			o.ReqRewriter = o2.ReqRewriter
+		case "response_transform":
+			o.ResponseTransform = o2.ResponseTransform
+			o.ResponseTransformer = o2.ResponseTransformer
 		}
 	}
 	o.Custom = strings.Unique(o.Custom)
 }
+
+// InheritCacheKeyHeaders adds the origin's cache key headers to the path's CacheKeyHeaders,
+// ahead of the path's own headers, unless ReplaceCacheKeyHeaders is true
+func (o *Options) InheritCacheKeyHeaders(originHeaders []string) {
+	if o.ReplaceCacheKeyHeaders || len(originHeaders) == 0 {
+		return
+	}
+	h := make([]string, 0, len(originHeaders)+len(o.CacheKeyHeaders))
+	h = append(h, originHeaders...)
+	o.CacheKeyHeaders = strings.Unique(append(h, o.CacheKeyHeaders...))
+}
+
+// AppendCacheKeyHeaders adds the provided headers to the path's CacheKeyHeaders, regardless of
+// ReplaceCacheKeyHeaders, for headers that must always partition the cache
+func (o *Options) AppendCacheKeyHeaders(h []string) {
+	if len(h) == 0 {
+		return
+	}
+	h2 := make([]string, 0, len(o.CacheKeyHeaders)+len(h))
+	h2 = append(h2, o.CacheKeyHeaders...)
+	o.CacheKeyHeaders = strings.Unique(append(h2, h...))
+}
+
+// InheritCacheKeyCookies adds the origin's cache key cookies to the path's CacheKeyCookies,
+// ahead of the path's own cookies
+func (o *Options) InheritCacheKeyCookies(originCookies []string) {
+	if len(originCookies) == 0 {
+		return
+	}
+	c := make([]string, 0, len(originCookies)+len(o.CacheKeyCookies))
+	c = append(c, originCookies...)
+	o.CacheKeyCookies = strings.Unique(append(c, o.CacheKeyCookies...))
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/forwarding"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
+	"github.com/tricksterproxy/trickster/pkg/util/strings"
 )
 
 func TestNewOptions(t *testing.T) {
@@ -53,6 +54,48 @@ func TestPathClone(t *testing.T) {
 
 }
 
+func TestInheritCacheKeyHeaders(t *testing.T) {
+
+	pc := NewOptions()
+	pc.CacheKeyHeaders = []string{"X-Path", "X-Tenant"}
+	pc.InheritCacheKeyHeaders([]string{"X-Tenant", "X-Region"})
+	expected := []string{"X-Tenant", "X-Region", "X-Path"}
+	if !strings.Equal(pc.CacheKeyHeaders, expected) {
+		t.Errorf("expected %v got %v", expected, pc.CacheKeyHeaders)
+	}
+
+	pc = NewOptions()
+	pc.CacheKeyHeaders = []string{"X-Path"}
+	pc.ReplaceCacheKeyHeaders = true
+	pc.InheritCacheKeyHeaders([]string{"X-Tenant"})
+	if !strings.Equal(pc.CacheKeyHeaders, []string{"X-Path"}) {
+		t.Errorf("expected %v got %v", []string{"X-Path"}, pc.CacheKeyHeaders)
+	}
+}
+
+func TestAppendCacheKeyHeaders(t *testing.T) {
+
+	pc := NewOptions()
+	pc.CacheKeyHeaders = []string{"X-Path", "X-Tenant"}
+	pc.ReplaceCacheKeyHeaders = true
+	pc.AppendCacheKeyHeaders([]string{"X-Tenant", "X-Org"})
+	expected := []string{"X-Path", "X-Tenant", "X-Org"}
+	if !strings.Equal(pc.CacheKeyHeaders, expected) {
+		t.Errorf("expected %v got %v", expected, pc.CacheKeyHeaders)
+	}
+}
+
+func TestInheritCacheKeyCookies(t *testing.T) {
+
+	pc := NewOptions()
+	pc.CacheKeyCookies = []string{"theme", "locale"}
+	pc.InheritCacheKeyCookies([]string{"locale", "region"})
+	expected := []string{"locale", "region", "theme"}
+	if !strings.Equal(pc.CacheKeyCookies, expected) {
+		t.Errorf("expected %v got %v", expected, pc.CacheKeyCookies)
+	}
+}
+
 func TestPathMerge(t *testing.T) {
 
 	pc := NewOptions()
@@ -61,7 +104,8 @@ func TestPathMerge(t *testing.T) {
 	pc2.Custom = []string{"path", "match_type", "handler", "methods",
 		"cache_key_params", "cache_key_headers", "cache_key_form_fields",
 		"request_headers", "request_params", "response_headers",
-		"response_code", "response_body", "no_metrics", "collapsed_forwarding"}
+		"response_code", "response_body", "no_metrics", "collapsed_forwarding", "cache_disabled",
+		"upgradeable"}
 
 	expectedPath := "testPath"
 	expectedHandlerName := "testHandler"
@@ -79,6 +123,8 @@ func TestPathMerge(t *testing.T) {
 	pc2.ResponseCode = 404
 	pc2.ResponseBody = "trickster"
 	pc2.NoMetrics = true
+	pc2.CacheDisabled = true
+	pc2.Upgradeable = true
 	pc2.CollapsedForwardingName = "progressive"
 	pc2.CollapsedForwardingType = forwarding.CFTypeProgressive
 
@@ -136,6 +182,14 @@ func TestPathMerge(t *testing.T) {
 		t.Errorf("expected %t got %t", true, pc.NoMetrics)
 	}
 
+	if !pc.CacheDisabled {
+		t.Errorf("expected %t got %t", true, pc.CacheDisabled)
+	}
+
+	if !pc.Upgradeable {
+		t.Errorf("expected %t got %t", true, pc.Upgradeable)
+	}
+
 	if pc.CollapsedForwardingName != "progressive" ||
 		pc.CollapsedForwardingType != forwarding.CFTypeProgressive {
 		t.Errorf("expected %s got %s", "progressive", pc.CollapsedForwardingName)
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package pool provides load balancing across the replica upstream URLs of an origin
+package pool
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Load Balancing policies indicate how a Pool selects the Backend for each request
+const (
+	// RoundRobin selects each healthy Backend in turn
+	RoundRobin = "round_robin"
+	// Random selects a healthy Backend at random
+	Random = "random"
+)
+
+// Health Check Quorums indicate how many of a Pool's Backends must be healthy for the Pool to be
+// healthy, when the quorum is not an integer
+const (
+	// QuorumAll requires every Backend to be healthy
+	QuorumAll = "all"
+	// QuorumAny requires at least one Backend to be healthy
+	QuorumAny = "any"
+)
+
+// Quorum is the number of a Pool's Backends that must be healthy for the Pool to be healthy:
+// QuorumAll, QuorumAny or an integer. In TOML, it may be provided as a string or an integer
+type Quorum string
+
+// UnmarshalTOML decodes a Quorum provided as a TOML string or integer
+func (q *Quorum) UnmarshalTOML(v interface{}) error {
+	switch t := v.(type) {
+	case string:
+		*q = Quorum(t)
+	case int64:
+		*q = Quorum(strconv.FormatInt(t, 10))
+	default:
+		return fmt.Errorf("invalid health check quorum [%v]", v)
+	}
+	return nil
+}
+
+// Size returns the number of n Backends that must be healthy to satisfy the Quorum. An error is
+// returned unless the Quorum is QuorumAll, QuorumAny or an integer between 1 and n
+func (q Quorum) Size(n int) (int, error) {
+	switch q {
+	case QuorumAll:
+		return n, nil
+	case QuorumAny:
+		return 1, nil
+	}
+	i, err := strconv.Atoi(string(q))
+	if err != nil || i < 1 || i > n {
+		return 0, fmt.Errorf("invalid health check quorum [%s] for %d urls", q, n)
+	}
+	return i, nil
+}
+
+// Backend is a replica upstream of an origin
+type Backend struct {
+	// URL is the base upstream URL of the Backend
+	URL *url.URL
+	// unhealthy is 1 when the Backend failed its most recent health check
+	unhealthy int32
+}
+
+// Healthy returns true unless the Backend failed its most recent health check
+func (b *Backend) Healthy() bool {
+	return atomic.LoadInt32(&b.unhealthy) == 0
+}
+
+// SetHealthy records the result of a health check of the Backend
+func (b *Backend) SetHealthy(healthy bool) {
+	var v int32
+	if !healthy {
+		v = 1
+	}
+	atomic.StoreInt32(&b.unhealthy, v)
+}
+
+// Pool selects a Backend for each request to an origin with replica upstream URLs
+type Pool struct {
+	backends []*Backend
+	policy   string
+	next     uint32
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New returns a Pool of Backends for the provided URLs, using the provided Load Balancing policy
+func New(urls []*url.URL, policy string) *Pool {
+	p := &Pool{backends: make([]*Backend, len(urls)), policy: policy, stop: make(chan struct{})}
+	for i, u := range urls {
+		p.backends[i] = &Backend{URL: u}
+	}
+	return p
+}
+
+// Backends returns the Pool's Backends
+func (p *Pool) Backends() []*Backend {
+	return p.backends
+}
+
+// Next returns the Backend to use for the next request. Unhealthy Backends are skipped
+// until they recover, unless no Backends are healthy, in which case all are eligible
+func (p *Pool) Next() *Backend {
+	if p == nil || len(p.backends) == 0 {
+		return nil
+	}
+	candidates := make([]*Backend, 0, len(p.backends))
+	for _, b := range p.backends {
+		if b.Healthy() {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.backends
+	}
+	if p.policy == Random {
+		return candidates[rand.Intn(len(candidates))]
+	}
+	n := atomic.AddUint32(&p.next, 1) - 1
+	return candidates[int(n%uint32(len(candidates)))]
+}
+
+// StartHealthChecks runs check against each Backend at the provided interval, recording
+// the results, until Stop is called
+func (p *Pool) StartHealthChecks(interval time.Duration, check func(*Backend) bool) {
+	if p == nil || interval <= 0 || check == nil {
+		return
+	}
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			for _, b := range p.backends {
+				b.SetHealthy(check(b))
+			}
+			select {
+			case <-p.stop:
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+// Stop ends the Pool's health checks
+func (p *Pool) Stop() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+// HTTPCheck returns a health check that requests the provided path and query from each Backend
+// using the provided client, method and headers. A Backend is healthy when it responds with a
+// status code below 500
+func HTTPCheck(client *http.Client, method, path, query string, h http.Header) func(*Backend) bool {
+	return func(b *Backend) bool {
+		u := *b.URL
+		u.Path += path
+		u.RawQuery = query
+		req, err := http.NewRequest(method, u.String(), nil)
+		if err != nil {
+			return false
+		}
+		if h != nil {
+			req.Header = h.Clone()
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return false
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode < http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pool
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func testURLs(t *testing.T, s ...string) []*url.URL {
+	out := make([]*url.URL, len(s))
+	for i, v := range s {
+		u, err := url.Parse(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[i] = u
+	}
+	return out
+}
+
+func TestNextRoundRobin(t *testing.T) {
+
+	p := New(testURLs(t, "http://1", "http://2", "http://3"), RoundRobin)
+	expected := []string{"1", "2", "3", "1", "2"}
+	for _, e := range expected {
+		if h := p.Next().URL.Host; h != e {
+			t.Errorf("expected %s got %s", e, h)
+		}
+	}
+
+	// unhealthy backends are skipped until they recover
+	p.Backends()[1].SetHealthy(false)
+	for i := 0; i < 4; i++ {
+		if h := p.Next().URL.Host; h == "2" {
+			t.Errorf("expected unhealthy backend %s to be skipped", h)
+		}
+	}
+	p.Backends()[1].SetHealthy(true)
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		seen[p.Next().URL.Host] = true
+	}
+	if !seen["2"] {
+		t.Error("expected recovered backend to be selected")
+	}
+
+	// when no backends are healthy, all are eligible
+	for _, b := range p.Backends() {
+		b.SetHealthy(false)
+	}
+	if p.Next() == nil {
+		t.Error("expected a backend")
+	}
+}
+
+func TestNextRandom(t *testing.T) {
+
+	p := New(testURLs(t, "http://1", "http://2"), Random)
+	p.Backends()[0].SetHealthy(false)
+	for i := 0; i < 10; i++ {
+		if h := p.Next().URL.Host; h != "2" {
+			t.Errorf("expected %s got %s", "2", h)
+		}
+	}
+
+	var np *Pool
+	if np.Next() != nil {
+		t.Error("expected nil backend")
+	}
+}
+
+func TestHealthChecks(t *testing.T) {
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" || r.URL.RawQuery != "q=1" || r.Header.Get("X-Test") != "test" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	p := New(testURLs(t, up.URL, down.URL, "http://127.0.0.1:64389"), RoundRobin)
+	check := HTTPCheck(http.DefaultClient, http.MethodGet, "/health", "q=1",
+		http.Header{"X-Test": []string{"test"}})
+	p.StartHealthChecks(time.Hour, check)
+	defer p.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Backends()[1].Healthy() || p.Backends()[2].Healthy() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for health checks")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !p.Backends()[0].Healthy() {
+		t.Error("expected healthy backend")
+	}
+	for i := 0; i < 3; i++ {
+		if h := p.Next().URL.String(); h != up.URL {
+			t.Errorf("expected %s got %s", up.URL, h)
+		}
+	}
+	p.Stop()
+}
+
+func TestQuorumSize(t *testing.T) {
+
+	tests := []struct {
+		quorum   Quorum
+		expected int
+		valid    bool
+	}{
+		{QuorumAll, 3, true},
+		{QuorumAny, 1, true},
+		{"2", 2, true},
+		{"0", 0, false},
+		{"4", 0, false},
+		{"most", 0, false},
+	}
+
+	for _, test := range tests {
+		n, err := test.quorum.Size(3)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %t got %v", test.quorum, test.valid, err)
+		}
+		if n != test.expected {
+			t.Errorf("%s: expected %d got %d", test.quorum, test.expected, n)
+		}
+	}
+}
+
+func TestQuorumUnmarshalTOML(t *testing.T) {
+
+	var q Quorum
+	if err := q.UnmarshalTOML(int64(2)); err != nil || q != "2" {
+		t.Errorf("expected %s got %s %v", "2", q, err)
+	}
+	if err := q.UnmarshalTOML("all"); err != nil || q != QuorumAll {
+		t.Errorf("expected %s got %s %v", QuorumAll, q, err)
+	}
+	if err := q.UnmarshalTOML(true); err == nil {
+		t.Error("expected error for invalid quorum")
+	}
+}
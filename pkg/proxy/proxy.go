@@ -18,6 +18,7 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
@@ -27,6 +28,9 @@ import (
 	"time"
 
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // NewHTTPClient returns an HTTP client configured to the specifications of the
@@ -40,7 +44,10 @@ func NewHTTPClient(oc *oo.Options) (*http.Client, error) {
 	var TLSConfig *tls.Config
 
 	if oc.TLS != nil {
-		TLSConfig = &tls.Config{InsecureSkipVerify: oc.TLS.InsecureSkipVerify}
+		TLSConfig = &tls.Config{
+			InsecureSkipVerify: oc.TLS.InsecureSkipVerify,
+			ServerName:         oc.TLS.ServerName,
+		}
 
 		if oc.TLS.ClientCertPath != "" && oc.TLS.ClientKeyPath != "" {
 			// load client cert
@@ -77,17 +84,112 @@ func NewHTTPClient(oc *oo.Options) (*http.Client, error) {
 		}
 	}
 
+	if oc.EnableHTTP2Upstream {
+		if TLSConfig == nil {
+			TLSConfig = &tls.Config{}
+		}
+		// offer h2 via ALPN, so the upstream may choose it over HTTP/1.1. This applies to
+		// connections made by the limited TLS dialer as well
+		TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+
+	keepAlive := time.Duration(oc.KeepAliveTimeoutSecs) * time.Second
+	dialer := &net.Dialer{KeepAlive: keepAlive}
+	transport := &http.Transport{
+		Dial:                dialer.Dial,
+		MaxIdleConns:        oc.MaxIdleConns,
+		MaxIdleConnsPerHost: oc.MaxIdleConns,
+		TLSClientConfig:     TLSConfig,
+	}
+	if oc.EnableHTTP2Upstream {
+		// HTTP/2 is otherwise disabled for transports with a custom dialer or TLS config
+		transport.ForceAttemptHTTP2 = true
+		// an h2 connection multiplexes concurrent requests rather than being returned to the idle
+		// pool after each one, so it is closed once it has been idle for the keep-alive timeout
+		transport.IdleConnTimeout = keepAlive
+	}
+	if oc.MaxConcurrentTLSHandshakes > 0 {
+		transport.DialTLSContext = limitedTLSDialer(dialer.DialContext, TLSConfig,
+			make(chan struct{}, oc.MaxConcurrentTLSHandshakes),
+			metrics.ProxyTLSHandshakes.WithLabelValues(oc.Name, oc.OriginType), oc.Timeout)
+	}
+
 	return &http.Client{
 		Timeout: oc.Timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
-		Transport: &http.Transport{
-			Dial:                (&net.Dialer{KeepAlive: time.Duration(oc.KeepAliveTimeoutSecs) * time.Second}).Dial,
-			MaxIdleConns:        oc.MaxIdleConns,
-			MaxIdleConnsPerHost: oc.MaxIdleConns,
-			TLSClientConfig:     TLSConfig,
-		},
+		Transport: transport,
 	}, nil
 
 }
+
+// limitedTLSDialer returns a DialTLSContext function that establishes TLS connections using dial
+// and cfg, while allowing no more handshakes to be in progress at once than the capacity of sem.
+// Each handshake must complete within timeout (when non-zero) and is abandoned if ctx is canceled,
+// so that a stalled upstream cannot hold a handshake slot indefinitely
+func limitedTLSDialer(dial func(context.Context, string, string) (net.Conn, error), cfg *tls.Config,
+	sem chan struct{}, g prometheus.Gauge,
+	timeout time.Duration) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		g.Inc()
+		defer func() {
+			g.Dec()
+			<-sem
+		}()
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		var c *tls.Config
+		if cfg != nil {
+			c = cfg.Clone()
+		} else {
+			c = &tls.Config{}
+		}
+		if c.ServerName == "" {
+			if host, _, err := net.SplitHostPort(addr); err == nil {
+				c.ServerName = host
+			} else {
+				c.ServerName = addr
+			}
+		}
+
+		if timeout > 0 {
+			conn.SetDeadline(time.Now().Add(timeout))
+		}
+		// interrupt the handshake if the dial is canceled before it completes
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.SetDeadline(time.Now())
+			case <-done:
+			}
+		}()
+
+		tc := tls.Client(conn, c)
+		err = tc.Handshake()
+		close(done)
+		if err != nil {
+			conn.Close()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		// clear the handshake deadline, including one set by a cancellation that raced its completion
+		if err = conn.SetDeadline(time.Time{}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tc, nil
+	}
+}
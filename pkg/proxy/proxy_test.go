@@ -17,10 +17,19 @@
 package proxy
 
 import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	tlstest "github.com/tricksterproxy/trickster/pkg/util/testing/tls"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func TestNewHTTPClient(t *testing.T) {
@@ -52,6 +61,17 @@ func TestNewHTTPClient(t *testing.T) {
 		t.Error(err)
 	}
 
+	// test server name override
+	oc.TLS.ServerName = "origin.example.com"
+	c, err = NewHTTPClient(oc)
+	if err != nil {
+		t.Error(err)
+	}
+	if v := c.Transport.(*http.Transport).TLSClientConfig.ServerName; v != oc.TLS.ServerName {
+		t.Errorf("expected %s got %s", oc.TLS.ServerName, v)
+	}
+	oc.TLS.ServerName = ""
+
 	// test good originconfig, 1 good CA
 	oc.TLS.CertificateAuthorityPaths = []string{caFile}
 	_, err = NewHTTPClient(oc)
@@ -98,3 +118,174 @@ func TestNewHTTPClient(t *testing.T) {
 		t.Errorf("failed to find any PEM data in key input for file %s", oc.TLS.ClientKeyPath)
 	}
 }
+
+func TestNewHTTPClientMaxConcurrentTLSHandshakes(t *testing.T) {
+
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	oc := oo.NewOptions()
+	oc.Name = "test"
+	oc.OriginType = "test"
+	oc.MaxConcurrentTLSHandshakes = 1
+	oc.TLS.InsecureSkipVerify = true
+
+	c, err := NewHTTPClient(oc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Transport.(*http.Transport).DialTLSContext == nil {
+		t.Fatal("expected limited tls dialer")
+	}
+	resp, err := c.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestNewHTTPClientEnableHTTP2Upstream(t *testing.T) {
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+
+	tests := []struct {
+		enabled        bool
+		maxHandshakes  int
+		expectedProto  int
+		expectedIdleTO time.Duration
+	}{
+		{false, 0, 1, 0},
+		{true, 0, 2, 300 * time.Second},
+		{true, 1, 2, 300 * time.Second},
+	}
+
+	for _, test := range tests {
+		oc := oo.NewOptions()
+		oc.Name = "test"
+		oc.OriginType = "test"
+		oc.EnableHTTP2Upstream = test.enabled
+		oc.MaxConcurrentTLSHandshakes = test.maxHandshakes
+		oc.KeepAliveTimeoutSecs = 300
+		oc.TLS.InsecureSkipVerify = true
+
+		c, err := NewHTTPClient(oc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := c.Transport.(*http.Transport).IdleConnTimeout; v != test.expectedIdleTO {
+			t.Errorf("expected %s got %s", test.expectedIdleTO, v)
+		}
+		resp, err := c.Get(s.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.ProtoMajor != test.expectedProto {
+			t.Errorf("enabled %t, max handshakes %d: expected HTTP/%d got %s",
+				test.enabled, test.maxHandshakes, test.expectedProto, resp.Proto)
+		}
+	}
+}
+
+func TestLimitedTLSDialer(t *testing.T) {
+
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	sem := make(chan struct{}, 1)
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test"})
+	dial := limitedTLSDialer((&net.Dialer{}).DialContext, &tls.Config{InsecureSkipVerify: true}, sem, g,
+		5*time.Second)
+	addr := strings.TrimPrefix(s.URL, "https://")
+
+	// occupy the only handshake slot, so the dial must wait for its release
+	sem <- struct{}{}
+	done := make(chan error)
+	go func() {
+		conn, err := dial(context.Background(), "tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected dial to wait for a handshake slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-sem
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected dial to complete")
+	}
+	if len(sem) != 0 {
+		t.Error("expected handshake slot to be released")
+	}
+
+	// a failed dial releases its slot
+	if _, err := dial(context.Background(), "tcp", "127.0.0.1:0"); err == nil {
+		t.Error("expected dial error")
+	}
+	if len(sem) != 0 {
+		t.Error("expected handshake slot to be released")
+	}
+
+	// a dial canceled while waiting for a slot returns without taking one
+	sem <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	_, err := dial(ctx, "tcp", addr)
+	cancel()
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+	<-sem
+
+	// an upstream that never completes the handshake does not hold the slot
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	dial = limitedTLSDialer((&net.Dialer{}).DialContext, &tls.Config{InsecureSkipVerify: true}, sem, g,
+		50*time.Millisecond)
+	if _, err := dial(context.Background(), "tcp", l.Addr().String()); err == nil {
+		t.Error("expected handshake timeout")
+	}
+	if len(sem) != 0 {
+		t.Error("expected handshake slot to be released")
+	}
+
+	dial = limitedTLSDialer((&net.Dialer{}).DialContext, &tls.Config{InsecureSkipVerify: true}, sem, g, 0)
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := dial(ctx, "tcp", l.Addr().String()); err != context.Canceled {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+	if len(sem) != 0 {
+		t.Error("expected handshake slot to be released")
+	}
+}
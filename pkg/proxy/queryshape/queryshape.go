@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package queryshape bounds the number of distinct timeseries query shapes an origin caches
+package queryshape
+
+import (
+	"container/list"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// literals matches quoted strings, and numbers and durations that are not part of an identifier
+var literals = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|'(?:[^'\\\\]|\\\\.)*'|`[^`]*`" +
+	`|\b\d+(?:\.\d+)?(?:[eE][+-]?\d+)?(?:ms|[smhdwy])*\b`)
+
+// Normalize returns the shape of a timeseries query statement, which is the statement with
+// its string, numeric and duration literals replaced by placeholders and its whitespace
+// collapsed, so that queries differing only in label values, thresholds or ranges share a shape
+func Normalize(statement string) string {
+	return strings.Join(strings.Fields(literals.ReplaceAllString(statement, "?")), " ")
+}
+
+// Tracker tracks the distinct query shapes cached by an origin, up to a maximum, in order of use
+type Tracker struct {
+	max  int
+	idle time.Duration
+
+	mtx    sync.Mutex
+	lru    *list.List
+	shapes map[string]*list.Element
+	now    func() time.Time
+}
+
+type entry struct {
+	shape    string
+	lastSeen time.Time
+}
+
+// New returns a Tracker that admits up to max distinct shapes. When the Tracker is full, the
+// least recently used shape is evicted to admit a new one once it has not been used for idle
+func New(max int, idle time.Duration) *Tracker {
+	return &Tracker{max: max, idle: idle, lru: list.New(),
+		shapes: make(map[string]*list.Element), now: time.Now}
+}
+
+// Admit returns true if responses to queries of the shape may be cached, which is the case when
+// the shape is already tracked, or can be tracked without exceeding the maximum. A nil Tracker
+// admits all shapes
+func (t *Tracker) Admit(shape string) bool {
+	if t == nil {
+		return true
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	now := t.now()
+	if el, ok := t.shapes[shape]; ok {
+		el.Value.(*entry).lastSeen = now
+		t.lru.MoveToFront(el)
+		return true
+	}
+	if t.lru.Len() >= t.max {
+		oldest := t.lru.Back()
+		if oldest == nil || now.Sub(oldest.Value.(*entry).lastSeen) < t.idle {
+			return false
+		}
+		delete(t.shapes, oldest.Value.(*entry).shape)
+		t.lru.Remove(oldest)
+	}
+	t.shapes[shape] = t.lru.PushFront(&entry{shape: shape, lastSeen: now})
+	return true
+}
+
+// Len returns the number of distinct shapes being tracked
+func (t *Tracker) Len() int {
+	if t == nil {
+		return 0
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.lru.Len()
+}
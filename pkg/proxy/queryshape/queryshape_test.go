@@ -0,0 +1,74 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package queryshape
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		statement, expected string
+	}{
+		{`sum(rate(http_requests_total{job="api", code=~'5..'}[5m])) > 0.5`,
+			`sum(rate(http_requests_total{job=?, code=~?}[?])) > ?`},
+		{"SELECT  mean(\"value\") FROM cpu WHERE host = 'a' AND time > now() - 1h GROUP BY time(10s)",
+			"SELECT mean(?) FROM cpu WHERE host = ? AND time > now() - ? GROUP BY time(?)"},
+		{`node_load1 offset 1d`, `node_load1 offset ?`},
+	}
+	for _, test := range tests {
+		if s := Normalize(test.statement); s != test.expected {
+			t.Errorf("expected %s got %s", test.expected, s)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+
+	now := time.Unix(1577836800, 0)
+	tr := New(2, time.Minute)
+	tr.now = func() time.Time { return now }
+
+	if !tr.Admit("a") || !tr.Admit("b") {
+		t.Fatal("expected shapes to be admitted")
+	}
+	if tr.Admit("c") {
+		t.Error("expected shape beyond the limit to be refused")
+	}
+	now = now.Add(30 * time.Second)
+	if !tr.Admit("a") {
+		t.Error("expected tracked shape to be admitted")
+	}
+
+	// b is now the least recently used shape, and is evicted once idle
+	now = now.Add(31 * time.Second)
+	if !tr.Admit("c") {
+		t.Error("expected shape to be admitted after evicting an idle shape")
+	}
+	if tr.Len() != 2 {
+		t.Errorf("expected %d got %d", 2, tr.Len())
+	}
+	if tr.Admit("b") {
+		t.Error("expected evicted shape to be refused")
+	}
+
+	var nt *Tracker
+	if !nt.Admit("a") || nt.Len() != 0 {
+		t.Error("expected nil tracker to admit all shapes")
+	}
+}
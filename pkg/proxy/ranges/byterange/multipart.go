@@ -34,8 +34,8 @@ import (
 
 // MultipartByteRange represents one part of a list of multipart byte ranges
 type MultipartByteRange struct {
-	Range   Range  `msg:"range"`
-	Content []byte `msg:"content"`
+	Range   Range  `msg:"range" json:"range"`
+	Content []byte `msg:"content" json:"content"`
 }
 
 // MultipartByteRanges is a list of type MultipartByteRange
@@ -31,8 +31,8 @@ import (
 
 // Range represents the start and end for a byte range object
 type Range struct {
-	Start int64 `msg:"start"`
-	End   int64 `msg:"end"`
+	Start int64 `msg:"start" json:"start"`
+	End   int64 `msg:"end" json:"end"`
 }
 
 // Ranges represents a slice of type Range
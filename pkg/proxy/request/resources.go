@@ -34,31 +34,33 @@ import (
 // Resources is a collection of resources a Trickster request would need to fulfill the client request
 // This is stored in the client request's context for use by request handers.
 type Resources struct {
-	OriginConfig      *oo.Options
-	PathConfig        *po.Options
-	CacheConfig       *co.Options
-	NoLock            bool
-	CacheClient       cache.Cache
-	OriginClient      origins.Client
-	AlternateCacheTTL time.Duration
-	TimeRangeQuery    *timeseries.TimeRangeQuery
-	Tracer            *tracing.Tracer
-	Logger            *tl.Logger
+	OriginConfig        *oo.Options
+	PathConfig          *po.Options
+	CacheConfig         *co.Options
+	NoLock              bool
+	CacheClient         cache.Cache
+	NegativeCacheClient cache.Cache
+	OriginClient        origins.Client
+	AlternateCacheTTL   time.Duration
+	TimeRangeQuery      *timeseries.TimeRangeQuery
+	Tracer              *tracing.Tracer
+	Logger              *tl.Logger
 }
 
 // Clone returns an exact copy of the subject Resources collection
 func (r Resources) Clone() *Resources {
 	return &Resources{
-		OriginConfig:      r.OriginConfig,
-		PathConfig:        r.PathConfig,
-		CacheConfig:       r.CacheConfig,
-		NoLock:            r.NoLock,
-		CacheClient:       r.CacheClient,
-		OriginClient:      r.OriginClient,
-		AlternateCacheTTL: r.AlternateCacheTTL,
-		TimeRangeQuery:    r.TimeRangeQuery,
-		Tracer:            r.Tracer,
-		Logger:            r.Logger,
+		OriginConfig:        r.OriginConfig,
+		PathConfig:          r.PathConfig,
+		CacheConfig:         r.CacheConfig,
+		NoLock:              r.NoLock,
+		CacheClient:         r.CacheClient,
+		NegativeCacheClient: r.NegativeCacheClient,
+		OriginClient:        r.OriginClient,
+		AlternateCacheTTL:   r.AlternateCacheTTL,
+		TimeRangeQuery:      r.TimeRangeQuery,
+		Tracer:              r.Tracer,
+		Logger:              r.Logger,
 	}
 }
 
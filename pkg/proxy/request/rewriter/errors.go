@@ -20,3 +20,5 @@ import "errors"
 
 var errBadParams = errors.New("invalid parameters provided to rewrite instruction")
 var errBadDepthParse = errors.New("unable to parse depth value")
+var errBadMethod = errors.New("invalid http method provided to rewrite instruction")
+var errMissingReference = errors.New("rewrite instruction references a missing request value")
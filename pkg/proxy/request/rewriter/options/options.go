@@ -22,11 +22,14 @@ type RewriteList [][]string
 // Options is a collection of Options pertaining to Request Rewriter Instructions
 type Options struct {
 	Instructions RewriteList `toml:"instructions"`
+	// OnMissingReference is the policy for executing an instruction that references a
+	// request header, parameter or path part that is missing: "skip" (default), "empty" or "error"
+	OnMissingReference string `toml:"on_missing_reference"`
 }
 
 // Clone returns an exact copy of the subject *Options
 func (o *Options) Clone() *Options {
-	o2 := &Options{}
+	o2 := &Options{OnMissingReference: o.OnMissingReference}
 	if len(o.Instructions) > 0 {
 		o2.Instructions = o.Instructions.Clone()
 	}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rewriter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/tls/clientcert"
+)
+
+// missingReferencePolicy determines how an instruction is executed when a reference
+// in its values is not present in the request
+type missingReferencePolicy int
+
+const (
+	// missingReferenceSkip leaves the target of the instruction unchanged
+	missingReferenceSkip = missingReferencePolicy(iota)
+	// missingReferenceEmpty substitutes an empty string for the missing reference
+	missingReferenceEmpty
+	// missingReferenceError fails the request with a 400 Bad Request
+	missingReferenceError
+)
+
+// missingReferencePolicies is a map of missing reference policies keyed by name
+var missingReferencePolicies = map[string]missingReferencePolicy{
+	"skip":  missingReferenceSkip,
+	"empty": missingReferenceEmpty,
+	"error": missingReferenceError,
+}
+
+// rwiReferenced wraps an instruction whose values include references to parts of the
+// request, which are resolved against the request each time the instruction is executed
+type rwiReferenced struct {
+	rewriteInstruction
+	policy missingReferencePolicy
+}
+
+// Execute executes the instruction, with its references resolved against the request
+func (ri *rwiReferenced) Execute(r *http.Request) {
+	ri.execute(r)
+}
+
+// execute executes the instruction, with its references resolved against the request,
+// and returns errMissingReference if a reference is missing and the policy is error
+func (ri *rwiReferenced) execute(r *http.Request) error {
+	missing := false
+	instr := ri.resolve(func(s string) string {
+		v, ok := resolveReferences(s, r)
+		if !ok {
+			missing = true
+		}
+		return v
+	})
+	if missing {
+		switch ri.policy {
+		case missingReferenceSkip:
+			return nil
+		case missingReferenceError:
+			return errMissingReference
+		}
+	}
+	instr.Execute(r)
+	return nil
+}
+
+// resolveReferences returns s with each ${header:Name}, ${param:name}, ${path:N}, ${match:N}
+// and ${client_cert:cn|san} reference replaced by the value of the request header, the URL
+// query parameter, the zero-indexed part of the URL path, the group, by index or name, captured
+// by the regex path config that matched the request, or the Common Name or comma-separated
+// Subject Alternative Names of the verified client certificate. Missing references are replaced
+// with an empty string and cause false to be returned. Other tokens are left as-is.
+func resolveReferences(s string, r *http.Request) (string, bool) {
+	if !checkTokens(s) {
+		return s, true
+	}
+	var sb strings.Builder
+	ok := true
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(s[i:], "}")
+		if j < 0 {
+			break
+		}
+		j += i
+		sb.WriteString(s[:i])
+		v, isRef, found := lookupReference(s[i+2:j], r)
+		switch {
+		case !isRef:
+			sb.WriteString(s[i : j+1])
+		case !found:
+			ok = false
+		default:
+			sb.WriteString(v)
+		}
+		s = s[j+1:]
+	}
+	sb.WriteString(s)
+	return sb.String(), ok
+}
+
+// lookupReference returns the value of the reference, whether it is a reference,
+// and whether it was found in the request
+func lookupReference(ref string, r *http.Request) (string, bool, bool) {
+	i := strings.Index(ref, ":")
+	if i < 1 || i == len(ref)-1 {
+		return "", false, false
+	}
+	name := ref[i+1:]
+	switch ref[:i] {
+	case "header":
+		if r == nil {
+			return "", true, false
+		}
+		vals, ok := r.Header[http.CanonicalHeaderKey(name)]
+		if !ok || len(vals) == 0 {
+			return "", true, false
+		}
+		return vals[0], true, true
+	case "param":
+		if r == nil || r.URL == nil {
+			return "", true, false
+		}
+		vals, ok := r.URL.Query()[name]
+		if !ok || len(vals) == 0 {
+			return "", true, false
+		}
+		return vals[0], true, true
+	case "path":
+		n, err := strconv.Atoi(name)
+		if err != nil || n < 0 {
+			return "", false, false
+		}
+		if r == nil || r.URL == nil {
+			return "", true, false
+		}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if n >= len(parts) || parts[n] == "" {
+			return "", true, false
+		}
+		return parts[n], true, true
+	case "match":
+		if r == nil {
+			return "", true, false
+		}
+		v, ok := context.PathMatch(r.Context())[name]
+		if !ok {
+			return "", true, false
+		}
+		return v, true, true
+	case "client_cert":
+		var v string
+		var ok bool
+		switch name {
+		case "cn":
+			v, ok = clientcert.CommonName(r)
+		case "san":
+			var sans []string
+			sans, ok = clientcert.SANs(r)
+			v = strings.Join(sans, ",")
+		default:
+			return "", false, false
+		}
+		if !ok || v == "" {
+			return "", true, false
+		}
+		return v, true, true
+	}
+	return "", false, false
+}
@@ -29,6 +29,9 @@ type rewriteInstruction interface {
 	Parse([]string) error
 	Execute(r *http.Request)
 	HasTokens() bool
+	// resolve returns a copy of the instruction with the values that may contain
+	// tokens passed through f
+	resolve(f func(string) string) rewriteInstruction
 }
 
 // RewriteInstructions is a list of type []rewriteInstruction
@@ -49,6 +52,7 @@ var rewriters = map[string]func() rewriteInstruction{
 	"params-set":       func() rewriteInstruction { return &rwiBasicSetter{} },
 	"params-replace":   func() rewriteInstruction { return &rwiBasicReplacer{} },
 	"method-set":       func() rewriteInstruction { return &rwiBasicSetter{} },
+	"method-transform": func() rewriteInstruction { return &rwiMethodTransformer{} },
 	"host-set":         func() rewriteInstruction { return &rwiBasicSetter{} },
 	"host-replace":     func() rewriteInstruction { return &rwiBasicReplacer{} },
 	"hostname-set":     func() rewriteInstruction { return &rwiBasicSetter{} },
@@ -172,11 +176,20 @@ func (ris RewriteInstructions) String() string {
 	return "[" + strings.Join(l, ",") + "]"
 }
 
-// Execute executes the Rewriter Instructions on the provided HTTP Request
-func (ris RewriteInstructions) Execute(r *http.Request) {
+// Execute executes the Rewriter Instructions on the provided HTTP Request. An error is
+// returned, and the remaining instructions are not executed, if an instruction references
+// a part of the request that is missing and its rewriter's on_missing_reference is error
+func (ris RewriteInstructions) Execute(r *http.Request) error {
 	for _, instr := range ris {
+		if ri, ok := instr.(*rwiReferenced); ok {
+			if err := ri.execute(r); err != nil {
+				return err
+			}
+			continue
+		}
 		instr.Execute(r)
 	}
+	return nil
 }
 
 func checkTokens(input string) bool {
@@ -224,6 +237,12 @@ func (ri *rwiKeyBasedSetter) HasTokens() bool {
 	return ri.hasTokens
 }
 
+func (ri *rwiKeyBasedSetter) resolve(f func(string) string) rewriteInstruction {
+	ri2 := *ri
+	ri2.value = f(ri.value)
+	return &ri2
+}
+
 type rwiKeyBasedAppender struct {
 	key, value string
 	hasTokens  bool
@@ -332,6 +351,12 @@ func (ri *rwiKeyBasedAppender) HasTokens() bool {
 	return ri.hasTokens
 }
 
+func (ri *rwiKeyBasedAppender) resolve(f func(string) string) rewriteInstruction {
+	ri2 := *ri
+	ri2.value = f(ri.value)
+	return &ri2
+}
+
 type rwiKeyBasedReplacer struct {
 	key, search, replacement string
 	depth                    int
@@ -400,6 +425,14 @@ func (ri *rwiKeyBasedReplacer) HasTokens() bool {
 	return ri.hasTokens
 }
 
+func (ri *rwiKeyBasedReplacer) resolve(f func(string) string) rewriteInstruction {
+	ri2 := *ri
+	ri2.key = f(ri.key)
+	ri2.search = f(ri.search)
+	ri2.replacement = f(ri.replacement)
+	return &ri2
+}
+
 type rwiKeyBasedDeleter struct {
 	key, value string
 	hasTokens  bool
@@ -480,6 +513,13 @@ func (ri *rwiKeyBasedDeleter) HasTokens() bool {
 	return ri.hasTokens
 }
 
+func (ri *rwiKeyBasedDeleter) resolve(f func(string) string) rewriteInstruction {
+	ri2 := *ri
+	ri2.key = f(ri.key)
+	ri2.value = f(ri.value)
+	return &ri2
+}
+
 type rwiPathSetter struct {
 	value     string
 	depth     int
@@ -515,6 +555,12 @@ func (ri *rwiPathSetter) HasTokens() bool {
 	return ri.hasTokens
 }
 
+func (ri *rwiPathSetter) resolve(f func(string) string) rewriteInstruction {
+	ri2 := *ri
+	ri2.value = f(ri.value)
+	return &ri2
+}
+
 func (ri *rwiPathSetter) Execute(r *http.Request) {
 	if ri.depth > -1 {
 		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/")
@@ -573,6 +619,13 @@ func (ri *rwiPathReplacer) HasTokens() bool {
 	return ri.hasTokens
 }
 
+func (ri *rwiPathReplacer) resolve(f func(string) string) rewriteInstruction {
+	ri2 := *ri
+	ri2.search = f(ri.search)
+	ri2.replacement = f(ri.replacement)
+	return &ri2
+}
+
 type rwiBasicSetter struct {
 	value     string
 	setter    scalarSetFunc
@@ -608,6 +661,12 @@ func (ri *rwiBasicSetter) HasTokens() bool {
 	return ri.hasTokens
 }
 
+func (ri *rwiBasicSetter) resolve(f func(string) string) rewriteInstruction {
+	ri2 := *ri
+	ri2.value = f(ri.value)
+	return &ri2
+}
+
 type rwiBasicReplacer struct {
 	search, replacement string
 	depth               int
@@ -660,6 +719,13 @@ func (ri *rwiBasicReplacer) HasTokens() bool {
 	return ri.hasTokens
 }
 
+func (ri *rwiBasicReplacer) resolve(f func(string) string) rewriteInstruction {
+	ri2 := *ri
+	ri2.search = f(ri.search)
+	ri2.replacement = f(ri.replacement)
+	return &ri2
+}
+
 type rwiPortDeleter struct {
 }
 
@@ -684,3 +750,72 @@ func (ri *rwiPortDeleter) Execute(r *http.Request) {
 func (ri *rwiPortDeleter) HasTokens() bool {
 	return false
 }
+
+func (ri *rwiPortDeleter) resolve(f func(string) string) rewriteInstruction {
+	return ri
+}
+
+type rwiMethodTransformer struct {
+	from, to string
+}
+
+func (ri *rwiMethodTransformer) String() string {
+	return fmt.Sprintf(`{"type":"methodTransformer","from":"%s","to":"%s"}`, ri.from, ri.to)
+}
+
+func (ri *rwiMethodTransformer) Parse(parts []string) error {
+	if len(parts) != 4 {
+		return errBadParams
+	}
+	ri.from = strings.ToUpper(parts[2])
+	ri.to = strings.ToUpper(parts[3])
+	if !isMethodToken(ri.from) || !isMethodToken(ri.to) {
+		return errBadMethod
+	}
+	return nil
+}
+
+func (ri *rwiMethodTransformer) Execute(r *http.Request) {
+	if r != nil && r.Method == ri.from {
+		r.Method = ri.to
+	}
+}
+
+func (ri *rwiMethodTransformer) HasTokens() bool {
+	return false
+}
+
+func (ri *rwiMethodTransformer) resolve(f func(string) string) rewriteInstruction {
+	return ri
+}
+
+// isUnsafe returns true if the transformer changes a safe HTTP method
+// into one that modifies or deletes the target resource
+func (ri *rwiMethodTransformer) isUnsafe() bool {
+	switch ri.from {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+	default:
+		return false
+	}
+	switch ri.to {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isMethodToken returns true if s is a non-empty RFC 7230 token, as required of HTTP method names
+func isMethodToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -453,6 +453,50 @@ func TestMiscRequestSetters(t *testing.T) {
 	}
 }
 
+func TestMethodTransformer(t *testing.T) {
+
+	ri := &rwiMethodTransformer{}
+	if err := ri.Parse([]string{"method", "transform", "get"}); err != errBadParams {
+		t.Errorf("expected %v got %v", errBadParams, err)
+	}
+	if err := ri.Parse([]string{"method", "transform", "GET", "P OST"}); err != errBadMethod {
+		t.Errorf("expected %v got %v", errBadMethod, err)
+	}
+	if err := ri.Parse([]string{"method", "transform", "get", "post"}); err != nil {
+		t.Error(err)
+	}
+	if ri.HasTokens() {
+		t.Error("expected false")
+	}
+	if ri.String() != `{"type":"methodTransformer","from":"GET","to":"POST"}` {
+		t.Errorf("unexpected string %s", ri.String())
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, testURLRaw, nil)
+	ri.Execute(r)
+	if r.Method != http.MethodPost {
+		t.Errorf("expected %s got %s", http.MethodPost, r.Method)
+	}
+
+	r, _ = http.NewRequest(http.MethodPut, testURLRaw, nil)
+	ri.Execute(r)
+	if r.Method != http.MethodPut {
+		t.Errorf("expected %s got %s", http.MethodPut, r.Method)
+	}
+
+	if ri.isUnsafe() {
+		t.Error("expected GET to POST to be safe")
+	}
+	ri = &rwiMethodTransformer{from: http.MethodGet, to: http.MethodDelete}
+	if !ri.isUnsafe() {
+		t.Error("expected GET to DELETE to be unsafe")
+	}
+	ri = &rwiMethodTransformer{from: http.MethodPost, to: http.MethodDelete}
+	if ri.isUnsafe() {
+		t.Error("expected POST to DELETE to be safe")
+	}
+}
+
 func reqString(r *http.Request) string {
 
 	if r == nil || r.URL == nil {
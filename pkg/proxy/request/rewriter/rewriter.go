@@ -18,7 +18,9 @@ package rewriter
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter/options"
 )
@@ -38,6 +40,19 @@ func ProcessConfigs(rwl map[string]*options.Options) (map[string]RewriteInstruct
 		if err != nil {
 			return nil, err
 		}
+		policy := missingReferenceSkip
+		if v.OnMissingReference != "" {
+			var ok bool
+			if policy, ok = missingReferencePolicies[strings.ToLower(v.OnMissingReference)]; !ok {
+				return nil, fmt.Errorf("invalid on_missing_reference [%s] provided in rewriter config [%s]",
+					v.OnMissingReference, k)
+			}
+		}
+		for i, instr := range ri {
+			if instr.HasTokens() {
+				ri[i] = &rwiReferenced{rewriteInstruction: instr, policy: policy}
+			}
+		}
 		crw[k] = ri
 	}
 	return crw, nil
@@ -63,11 +78,27 @@ func parseRewriteList(rl options.RewriteList) (RewriteInstructions, error) {
 	return fri, nil
 }
 
+// UnsafeMethodTransforms returns a description of each method transformation in the
+// instructions that turns a safe HTTP method into one that modifies or deletes the target
+// resource (e.g., GET to DELETE), so that they can be reported as configuration warnings
+func UnsafeMethodTransforms(ri RewriteInstructions) []string {
+	var out []string
+	for _, instr := range ri {
+		if mt, ok := instr.(*rwiMethodTransformer); ok && mt.isUnsafe() {
+			out = append(out, fmt.Sprintf("%s to %s", mt.from, mt.to))
+		}
+	}
+	return out
+}
+
 // Rewrite returns a handler that executes the Rewriter and passes
 // the request to the next Handler
 func Rewrite(ri RewriteInstructions, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ri.Execute(r)
+		if err := ri.Execute(r); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
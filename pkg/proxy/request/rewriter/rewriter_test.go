@@ -17,9 +17,14 @@
 package rewriter
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter/options"
 
 	"testing"
@@ -55,3 +60,121 @@ func TestProcessConfig(t *testing.T) {
 	}
 
 }
+
+func TestUnsafeMethodTransforms(t *testing.T) {
+	ri, err := parseRewriteList(options.RewriteList{
+		[]string{"method", "transform", "GET", "POST"},
+		[]string{"method", "transform", "HEAD", "DELETE"},
+		[]string{"method", "set", "DELETE"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := UnsafeMethodTransforms(ri)
+	if len(u) != 1 || u[0] != "HEAD to DELETE" {
+		t.Errorf("expected %s got %v", "HEAD to DELETE", u)
+	}
+}
+
+func TestProcessConfigsOnMissingReference(t *testing.T) {
+
+	rl := options.RewriteList{
+		[]string{"header", "set", "X-Tenant", "${header:X-Org}-${path:0}"},
+		[]string{"param", "set", "tenant", "${param:org}"},
+	}
+
+	o := &options.Options{Instructions: rl, OnMissingReference: "invalid"}
+	_, err := ProcessConfigs(map[string]*options.Options{"test": o})
+	if err == nil || !strings.Contains(err.Error(), "invalid on_missing_reference") {
+		t.Errorf("expected invalid on_missing_reference error, got %v", err)
+	}
+
+	tests := []struct {
+		policy, query      string
+		expectedCode       int
+		expectedHeader     string
+		expectedHasHeader  bool
+		expectedParamValue string
+	}{
+		{"", "org=acme", http.StatusOK, "", false, "acme"},
+		{"skip", "org=acme", http.StatusOK, "", false, "acme"},
+		{"empty", "org=acme", http.StatusOK, "-api", true, "acme"},
+		{"empty", "", http.StatusOK, "-api", true, ""},
+		{"error", "", http.StatusBadRequest, "", false, ""},
+	}
+
+	for i, test := range tests {
+		o := &options.Options{Instructions: rl, OnMissingReference: test.policy}
+		ri, err := ProcessConfigs(map[string]*options.Options{"test": o})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r2 *http.Request
+		h := Rewrite(ri["test"], http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r2 = r
+		}))
+		r, _ := http.NewRequest("GET", "http://example.com/api/v1?"+test.query, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != test.expectedCode {
+			t.Errorf("test %d: expected %d got %d", i, test.expectedCode, w.Code)
+		}
+		if test.expectedCode != http.StatusOK {
+			if r2 != nil {
+				t.Errorf("test %d: expected request not to be handled", i)
+			}
+			continue
+		}
+		if _, ok := r2.Header["X-Tenant"]; ok != test.expectedHasHeader {
+			t.Errorf("test %d: expected header presence %t got %t", i, test.expectedHasHeader, ok)
+		}
+		if v := r2.Header.Get("X-Tenant"); v != test.expectedHeader {
+			t.Errorf("test %d: expected %s got %s", i, test.expectedHeader, v)
+		}
+		if v := r2.URL.Query().Get("tenant"); v != test.expectedParamValue {
+			t.Errorf("test %d: expected %s got %s", i, test.expectedParamValue, v)
+		}
+	}
+}
+
+func TestResolveReferences(t *testing.T) {
+
+	r, _ := http.NewRequest("GET", "http://example.com/api/v1/query?org=acme&empty=", nil)
+	r.Header.Set("X-Org", "trickster")
+	r = r.WithContext(tc.WithPathMatch(r.Context(), map[string]string{"1": "v1", "version": "v1"}))
+
+	tests := []struct {
+		input, expected string
+		ok              bool
+	}{
+		{"static", "static", true},
+		{"${header:x-org}", "trickster", true},
+		{"${param:org}/${path:2}", "acme/query", true},
+		{"${param:empty}", "", true},
+		{"${trickster}", "${trickster}", true},
+		{"${path:x}", "${path:x}", true},
+		{"a${header:X-Missing}b", "ab", false},
+		{"${param:missing}", "", false},
+		{"${path:3}", "", false},
+		{"${match:1}/${match:version}", "v1/v1", true},
+		{"${match:2}", "", false},
+		{"${client_cert:cn}", "", false},
+		{"${client_cert:other}", "${client_cert:other}", true},
+	}
+
+	for _, test := range tests {
+		v, ok := resolveReferences(test.input, r)
+		if v != test.expected || ok != test.ok {
+			t.Errorf("%s: expected %s %t got %s %t", test.input, test.expected, test.ok, v, ok)
+		}
+	}
+
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{
+		Subject:  pkix.Name{CommonName: "client.example.com"},
+		DNSNames: []string{"client.example.com", "alt.example.com"},
+	}}}}
+	if v, ok := resolveReferences("${client_cert:cn}|${client_cert:san}", r); !ok ||
+		v != "client.example.com|client.example.com,alt.example.com" {
+		t.Errorf("unexpected client cert references %s %t", v, ok)
+	}
+}
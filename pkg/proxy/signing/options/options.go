@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	d "github.com/tricksterproxy/trickster/pkg/config/defaults"
+)
+
+// ElementHeaderPrefix is the prefix of a signed element that references a request header (e.g., 'header:Content-Type')
+const ElementHeaderPrefix = "header:"
+
+// Supported signed elements
+const (
+	ElementMethod    = "method"
+	ElementPath      = "path"
+	ElementQuery     = "query"
+	ElementHost      = "host"
+	ElementTimestamp = "timestamp"
+)
+
+// Algorithms is the list of supported HMAC hashing algorithms
+var Algorithms = map[string]bool{"sha1": true, "sha256": true, "sha512": true}
+
+// Options is a collection of configurations for signing upstream requests with an HMAC
+type Options struct {
+	// Algorithm is the hashing algorithm used to compute the HMAC: 'sha1', 'sha256' or 'sha512'
+	Algorithm string `toml:"algorithm"`
+	// Secret is the shared secret used to compute the HMAC. SecretFile is preferred
+	Secret string `toml:"secret"`
+	// SecretFile is the path to a file containing the shared secret, and takes precedence over Secret
+	SecretFile string `toml:"secret_file"`
+	// HeaderName is the name of the request header in which the signature is provided
+	HeaderName string `toml:"header_name"`
+	// TimestampHeaderName is the name of the request header in which the signing timestamp is provided
+	TimestampHeaderName string `toml:"timestamp_header_name"`
+	// SignedElements is the ordered list of request elements included in the signature. Options are
+	// 'method', 'path', 'query', 'host', 'timestamp' and 'header:<Header-Name>'
+	SignedElements []string `toml:"signed_elements"`
+
+	// Key is the loaded secret used to compute the HMAC
+	Key []byte `toml:"-"`
+}
+
+// NewOptions will return a *Options with the default settings
+func NewOptions() *Options {
+	return &Options{
+		Algorithm:           d.DefaultSigningAlgorithm,
+		HeaderName:          d.DefaultSigningHeaderName,
+		TimestampHeaderName: d.DefaultSigningTimestampHeaderName,
+		SignedElements:      []string{ElementMethod, ElementPath, ElementTimestamp},
+	}
+}
+
+// Clone returns an exact copy of the subject *Options
+func (o *Options) Clone() *Options {
+
+	var se []string
+	if o.SignedElements != nil {
+		se = make([]string, len(o.SignedElements))
+		copy(se, o.SignedElements)
+	}
+
+	var key []byte
+	if o.Key != nil {
+		key = make([]byte, len(o.Key))
+		copy(key, o.Key)
+	}
+
+	return &Options{
+		Algorithm:           o.Algorithm,
+		Secret:              o.Secret,
+		SecretFile:          o.SecretFile,
+		HeaderName:          o.HeaderName,
+		TimestampHeaderName: o.TimestampHeaderName,
+		SignedElements:      se,
+		Key:                 key,
+	}
+}
+
+// Validate verifies the Options, normalizes their values and loads the signing secret
+func (o *Options) Validate() error {
+
+	o.Algorithm = strings.ToLower(o.Algorithm)
+	if _, ok := Algorithms[o.Algorithm]; !ok {
+		return fmt.Errorf("invalid request signing algorithm: %s", o.Algorithm)
+	}
+
+	if o.HeaderName == "" {
+		return errors.New("request signing header_name must not be empty")
+	}
+
+	if len(o.SignedElements) == 0 {
+		return errors.New("request signing signed_elements must not be empty")
+	}
+
+	for i, e := range o.SignedElements {
+		le := strings.ToLower(e)
+		switch {
+		case le == ElementMethod, le == ElementPath, le == ElementQuery, le == ElementHost:
+			o.SignedElements[i] = le
+		case le == ElementTimestamp:
+			if o.TimestampHeaderName == "" {
+				return errors.New("request signing timestamp_header_name must not be empty " +
+					"when the timestamp is a signed element")
+			}
+			o.SignedElements[i] = le
+		case strings.HasPrefix(le, ElementHeaderPrefix) && len(le) > len(ElementHeaderPrefix):
+			o.SignedElements[i] = ElementHeaderPrefix + e[len(ElementHeaderPrefix):]
+		default:
+			return fmt.Errorf("invalid request signing element: %s", e)
+		}
+	}
+
+	if o.SecretFile != "" {
+		b, err := ioutil.ReadFile(o.SecretFile)
+		if err != nil {
+			return fmt.Errorf("could not read request signing secret file: %s", err.Error())
+		}
+		o.Key = []byte(strings.TrimSpace(string(b)))
+	} else {
+		o.Key = []byte(o.Secret)
+	}
+
+	if len(o.Key) == 0 {
+		return errors.New("request signing secret must not be empty")
+	}
+
+	return nil
+}
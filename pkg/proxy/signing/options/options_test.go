@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+
+	o := NewOptions()
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for empty secret")
+	}
+
+	f, err := ioutil.TempFile("", "trickster-signing-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("from-file\n")
+	f.Close()
+
+	o.Secret = "inline"
+	o.SecretFile = f.Name()
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	if string(o.Key) != "from-file" {
+		t.Errorf("expected %s got %s", "from-file", string(o.Key))
+	}
+
+	o.SecretFile = f.Name() + ".invalid"
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for invalid secret file")
+	}
+
+	o.SecretFile = ""
+	o.Algorithm = "md5"
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for invalid algorithm")
+	}
+
+	o.Algorithm = "SHA512"
+	o.SignedElements = []string{"Method", "header:X-Test", "body"}
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for invalid element")
+	}
+
+	o.SignedElements = []string{"Method", "header:X-Test"}
+	if err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+	if o.SignedElements[0] != "method" || o.SignedElements[1] != "header:X-Test" {
+		t.Errorf("unexpected signed elements %v", o.SignedElements)
+	}
+
+	o.SignedElements = nil
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for empty signed elements")
+	}
+
+	o.SignedElements = []string{"timestamp"}
+	o.TimestampHeaderName = ""
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for empty timestamp header name")
+	}
+
+	o.TimestampHeaderName = "X-Timestamp"
+	o.HeaderName = ""
+	if err := o.Validate(); err == nil {
+		t.Error("expected error for empty header name")
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := NewOptions()
+	o.Key = []byte("trickster")
+	o2 := o.Clone()
+	if string(o2.Key) != "trickster" || len(o2.SignedElements) != len(o.SignedElements) {
+		t.Error("clone mismatch")
+	}
+}
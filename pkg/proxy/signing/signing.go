@@ -0,0 +1,79 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package signing computes HMAC signatures for upstream requests
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/signing/options"
+)
+
+// SignRequest computes the HMAC signature over the configured elements of the request,
+// and sets the signature (and timestamp, when signed) headers on the request
+func SignRequest(r *http.Request, o *options.Options, now time.Time) {
+
+	if r == nil || o == nil || len(o.Key) == 0 {
+		return
+	}
+
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	parts := make([]string, len(o.SignedElements))
+	for i, e := range o.SignedElements {
+		switch e {
+		case options.ElementMethod:
+			parts[i] = r.Method
+		case options.ElementPath:
+			parts[i] = r.URL.Path
+		case options.ElementQuery:
+			parts[i] = r.URL.RawQuery
+		case options.ElementHost:
+			parts[i] = r.URL.Host
+		case options.ElementTimestamp:
+			parts[i] = ts
+			r.Header.Set(o.TimestampHeaderName, ts)
+		default:
+			if strings.HasPrefix(e, options.ElementHeaderPrefix) {
+				parts[i] = r.Header.Get(e[len(options.ElementHeaderPrefix):])
+			}
+		}
+	}
+
+	mac := hmac.New(hashFunc(o.Algorithm), o.Key)
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	r.Header.Set(o.HeaderName, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func hashFunc(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "sha1":
+		return sha1.New
+	case "sha512":
+		return sha512.New
+	}
+	return sha256.New
+}
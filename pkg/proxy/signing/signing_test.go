@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/signing/options"
+)
+
+func TestSignRequest(t *testing.T) {
+
+	o := options.NewOptions()
+	o.Secret = "trickster"
+	o.SignedElements = append(o.SignedElements, "query", "header:X-Test")
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "http://0/api/v1/query?q=up", nil)
+	r.Header.Set("X-Test", "value")
+	now := time.Unix(1577836800, 0)
+
+	SignRequest(r, o, now)
+
+	if v := r.Header.Get(o.TimestampHeaderName); v != "1577836800" {
+		t.Errorf("expected %s got %s", "1577836800", v)
+	}
+
+	mac := hmac.New(sha256.New, []byte("trickster"))
+	mac.Write([]byte("GET\n/api/v1/query\n1577836800\nq=up\nvalue"))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if v := r.Header.Get(o.HeaderName); v != expected {
+		t.Errorf("expected %s got %s", expected, v)
+	}
+
+	// a nil options should not sign
+	r = httptest.NewRequest("GET", "http://0/", nil)
+	SignRequest(r, nil, now)
+	if v := r.Header.Get(o.HeaderName); v != "" {
+		t.Errorf("expected empty signature got %s", v)
+	}
+}
+
+func TestHashFunc(t *testing.T) {
+	for _, a := range []string{"sha1", "sha256", "sha512"} {
+		if hashFunc(a)() == nil {
+			t.Errorf("expected hash for %s", a)
+		}
+	}
+}
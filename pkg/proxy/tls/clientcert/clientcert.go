@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package clientcert provides the identity of verified TLS client certificates
+package clientcert
+
+import (
+	"crypto/x509"
+	"net/http"
+)
+
+// Verified returns the leaf certificate that the client of the request presented and the
+// listener verified, or nil if the request was not authenticated by a client certificate
+func Verified(r *http.Request) *x509.Certificate {
+	if r == nil || r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// CommonName returns the Subject Common Name of the request's verified client certificate
+func CommonName(r *http.Request) (string, bool) {
+	c := Verified(r)
+	if c == nil {
+		return "", false
+	}
+	return c.Subject.CommonName, true
+}
+
+// SANs returns the Subject Alternative Names of the request's verified client certificate
+func SANs(r *http.Request) ([]string, bool) {
+	c := Verified(r)
+	if c == nil {
+		return nil, false
+	}
+	return AltNames(c), true
+}
+
+// AltNames returns the Subject Alternative Names of the certificate, with its DNS names,
+// followed by its email addresses, IP addresses and URIs
+func AltNames(c *x509.Certificate) []string {
+	out := make([]string, 0, len(c.DNSNames)+len(c.EmailAddresses)+len(c.IPAddresses)+len(c.URIs))
+	out = append(out, c.DNSNames...)
+	out = append(out, c.EmailAddresses...)
+	for _, ip := range c.IPAddresses {
+		out = append(out, ip.String())
+	}
+	for _, u := range c.URIs {
+		out = append(out, u.String())
+	}
+	return out
+}
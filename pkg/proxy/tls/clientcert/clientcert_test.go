@@ -0,0 +1,66 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clientcert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestVerified(t *testing.T) {
+
+	if Verified(nil) != nil {
+		t.Error("expected nil certificate")
+	}
+
+	r := httptest.NewRequest("GET", "https://127.0.0.1/", nil)
+	if _, ok := CommonName(r); ok {
+		t.Error("expected no common name for a request without a client certificate")
+	}
+	if _, ok := SANs(r); ok {
+		t.Error("expected no sans for a request without a client certificate")
+	}
+
+	// presented certificates that were not verified do not identify the client
+	c := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "client"},
+		DNSNames:       []string{"client.example.com"},
+		EmailAddresses: []string{"client@example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		URIs:           []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/client"}},
+	}
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{c}}
+	if Verified(r) != nil {
+		t.Error("expected nil certificate")
+	}
+
+	r.TLS.VerifiedChains = [][]*x509.Certificate{{c}}
+	if cn, ok := CommonName(r); !ok || cn != "client" {
+		t.Errorf("expected %s got %s", "client", cn)
+	}
+	sans, ok := SANs(r)
+	const expected = "client.example.com,client@example.com,10.0.0.1,spiffe://example.com/client"
+	if !ok || strings.Join(sans, ",") != expected {
+		t.Errorf("expected %s got %s", expected, strings.Join(sans, ","))
+	}
+}
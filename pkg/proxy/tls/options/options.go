@@ -18,6 +18,9 @@ package options
 
 import (
 	"io/ioutil"
+	"net"
+	"regexp"
+	gostrings "strings"
 
 	"github.com/tricksterproxy/trickster/pkg/util/strings"
 )
@@ -35,6 +38,9 @@ type Options struct {
 	// InsecureSkipVerify indicates that the HTTPS Client in Trickster should bypass
 	// hostname verification for the origin's certificate when proxying requests
 	InsecureSkipVerify bool `toml:"insecure_skip_verify"`
+	// ServerName overrides the hostname sent to the origin via SNI, and against which the origin's
+	// certificate is verified, when it differs from the host in the origin_url (e.g., an IP address)
+	ServerName string `toml:"server_name"`
 	// CertificateAuthorities provides a list of custom Certificate Authorities for the upstream origin
 	// which are considered in addition to any system CA's by the Trickster HTTPS Client
 	CertificateAuthorityPaths []string `toml:"certificate_authority_paths"`
@@ -42,6 +48,11 @@ type Options struct {
 	ClientCertPath string `toml:"client_cert_path"`
 	// ClientKeyPath provides the path to the Client Key when using Mutual Authorization
 	ClientKeyPath string `toml:"client_key_path"`
+	// PasswordFile is the path to a file containing a password for TLS authentication mechanisms
+	// that require one, which is read into Password when the config is loaded or reloaded
+	PasswordFile string `toml:"password_file"`
+	// Password is the contents of PasswordFile
+	Password string `toml:"-"`
 }
 
 // NewOptions will return a *Options with the default settings
@@ -66,9 +77,12 @@ func (o *Options) Clone() *Options {
 		PrivateKeyPath:            o.PrivateKeyPath,
 		ServeTLS:                  o.ServeTLS,
 		InsecureSkipVerify:        o.InsecureSkipVerify,
+		ServerName:                o.ServerName,
 		CertificateAuthorityPaths: caps,
 		ClientCertPath:            o.ClientCertPath,
 		ClientKeyPath:             o.ClientKeyPath,
+		PasswordFile:              o.PasswordFile,
+		Password:                  o.Password,
 	}
 }
 
@@ -77,11 +91,31 @@ func (o *Options) Equal(o2 *Options) bool {
 	return o.FullChainCertPath == o2.FullChainCertPath &&
 		o.PrivateKeyPath == o2.PrivateKeyPath &&
 		o.InsecureSkipVerify == o2.InsecureSkipVerify &&
+		o.ServerName == o2.ServerName &&
 		strings.Equal(o.CertificateAuthorityPaths, o2.CertificateAuthorityPaths) &&
 		o.ClientCertPath == o2.ClientCertPath &&
-		o.ClientKeyPath == o2.ClientKeyPath
+		o.ClientKeyPath == o2.ClientKeyPath &&
+		o.PasswordFile == o2.PasswordFile
 }
 
+// ValidServerName returns true if the provided name is a plausible DNS hostname for use in SNI.
+// IP addresses are not permitted in SNI, and so are not valid server names
+func ValidServerName(name string) bool {
+	if name == "" || len(name) > 253 || net.ParseIP(name) != nil {
+		return false
+	}
+	for _, label := range gostrings.Split(gostrings.TrimSuffix(name, "."), ".") {
+		if !serverNameLabel.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// serverNameLabel matches a single DNS label of up to 63 letters, digits or hyphens,
+// which does not start or end with a hyphen
+var serverNameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
 // Validate returns true if the TLS Options are validated
 func (o *Options) Validate() (bool, error) {
 
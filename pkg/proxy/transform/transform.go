@@ -0,0 +1,78 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package transform provides the built-in converters that can be applied
+// to upstream response bodies on a per-path basis
+package transform
+
+import (
+	"mime"
+	"strings"
+)
+
+// Func converts a response body into a different format
+type Func func([]byte) ([]byte, error)
+
+// Transformer describes a built-in response body converter
+type Transformer struct {
+	// Name is the name used to reference the Transformer in path configs
+	Name string
+	// InputContentTypes is the list of upstream media types the Transformer converts
+	InputContentTypes []string
+	// InputContentTypeSuffixes is the list of structured syntax suffixes (e.g., "+xml")
+	// of upstream media types the Transformer converts
+	InputContentTypeSuffixes []string
+	// OutputContentType is the Content-Type of the converted response
+	OutputContentType string
+	// Transform converts the response body
+	Transform Func
+}
+
+// Transformers is a map of the built-in Transformers keyed by name
+var Transformers = map[string]*Transformer{
+	"xml2json": {
+		Name:                     "xml2json",
+		InputContentTypes:        []string{"application/xml", "text/xml"},
+		InputContentTypeSuffixes: []string{"+xml"},
+		OutputContentType:        "application/json",
+		Transform:                XMLToJSON,
+	},
+}
+
+// Get returns the named Transformer, and false if the name is not a built-in Transformer
+func Get(name string) (*Transformer, bool) {
+	t, ok := Transformers[strings.ToLower(name)]
+	return t, ok
+}
+
+// Accepts returns true if the Transformer converts responses of the provided Content-Type
+func (t *Transformer) Accepts(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, v := range t.InputContentTypes {
+		if mt == v {
+			return true
+		}
+	}
+	for _, v := range t.InputContentTypeSuffixes {
+		if strings.HasSuffix(mt, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	if _, ok := Get("XML2JSON"); !ok {
+		t.Error("expected xml2json transformer")
+	}
+	if _, ok := Get("invalid"); ok {
+		t.Error("expected no transformer")
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	tr, _ := Get("xml2json")
+	tests := []struct {
+		contentType string
+		expected    bool
+	}{
+		{"application/xml", true},
+		{"text/xml; charset=utf-8", true},
+		{"application/atom+xml", true},
+		{"application/json", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		if v := tr.Accepts(test.contentType); v != test.expected {
+			t.Errorf("expected %t got %t for %s", test.expected, v, test.contentType)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNoRootElement indicates the XML document has no root element
+var ErrNoRootElement = errors.New("xml document has no root element")
+
+// xmlNode is an element of a decoded XML document
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     strings.Builder
+}
+
+// XMLToJSON converts an XML document into JSON. The root element becomes the only key of
+// the JSON object. Attributes are represented as keys prefixed with "@", child elements as
+// keys, or as arrays when an element name repeats, and text content as a string, or under
+// the "#text" key when the element also has attributes or children. Namespace prefixes are
+// not included in the converted key names, and namespace declarations are omitted.
+func XMLToJSON(b []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &xmlNode{name: t.Name.Local, attrs: t.Attr}
+			if len(stack) > 0 {
+				p := stack[len(stack)-1]
+				p.children = append(p.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, ErrNoRootElement
+	}
+	return json.Marshal(map[string]interface{}{root.name: root.value()})
+}
+
+// value returns the JSON-encodable representation of the node
+func (n *xmlNode) value() interface{} {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.children) == 0 && !n.hasAttrs() {
+		return text
+	}
+	m := make(map[string]interface{}, len(n.attrs)+len(n.children)+1)
+	for _, a := range n.attrs {
+		if isNamespaceDecl(a) {
+			continue
+		}
+		m["@"+a.Name.Local] = a.Value
+	}
+	for _, c := range n.children {
+		v := c.value()
+		switch e := m[c.name].(type) {
+		case nil:
+			m[c.name] = v
+		case []interface{}:
+			m[c.name] = append(e, v)
+		default:
+			m[c.name] = []interface{}{e, v}
+		}
+	}
+	if text != "" {
+		m["#text"] = text
+	}
+	return m
+}
+
+// hasAttrs returns true if the node has any attributes other than namespace declarations
+func (n *xmlNode) hasAttrs() bool {
+	for _, a := range n.attrs {
+		if !isNamespaceDecl(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNamespaceDecl returns true if the attribute is an xmlns namespace declaration
+func isNamespaceDecl(a xml.Attr) bool {
+	return a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns")
+}
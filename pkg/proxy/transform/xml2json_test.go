@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package transform
+
+import "testing"
+
+func TestXMLToJSON(t *testing.T) {
+
+	tests := []struct {
+		input, expected string
+		expectErr       bool
+	}{
+		{`<a>text</a>`, `{"a":"text"}`, false},
+		{`<?xml version="1.0"?><a id="1"><b>x</b><b>y</b><c/></a>`,
+			`{"a":{"@id":"1","b":["x","y"],"c":""}}`, false},
+		{`<a x="1">text</a>`, `{"a":{"#text":"text","@x":"1"}}`, false},
+		{`<ns:a xmlns:ns="urn:x"><ns:b>1</ns:b></ns:a>`, `{"a":{"b":"1"}}`, false},
+		{`<a xmlns="urn:x">1</a>`, `{"a":"1"}`, false},
+		{``, ``, true},
+		{`<a><b></a>`, ``, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			b, err := XMLToJSON([]byte(test.input))
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error %t got %v", test.expectErr, err)
+			}
+			if string(b) != test.expected {
+				t.Errorf("expected %s got %s", test.expected, string(b))
+			}
+		})
+	}
+}
@@ -21,14 +21,20 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/pprof"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/tricksterproxy/trickster/pkg/cache"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	th "github.com/tricksterproxy/trickster/pkg/proxy/handlers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/clickhouse"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins/graphite"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins/grpcpassthrough"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/influxdb"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/irondb"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
@@ -36,8 +42,10 @@ import (
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/reverseproxycache"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/rule"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/types"
+	"github.com/tricksterproxy/trickster/pkg/proxy/origins/victoriametrics"
 	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
+	"github.com/tricksterproxy/trickster/pkg/proxy/pool"
 	"github.com/tricksterproxy/trickster/pkg/proxy/request/rewriter"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
@@ -171,6 +179,15 @@ func registerOriginRoutes(router *mux.Router, conf *config.Config, k string,
 		return nil, fmt.Errorf("could not find cache named [%s]", o.CacheName)
 	}
 
+	// negatively-cached responses are stored in the primary cache unless a separate one is configured
+	nc := c
+	if o.NegativeCacheBackendName != "" {
+		nc, ok = caches[o.NegativeCacheBackendName]
+		if !ok {
+			return nil, fmt.Errorf("could not find negative cache backend named [%s]", o.NegativeCacheBackendName)
+		}
+	}
+
 	if !dryRun {
 		log.Info("registering route paths", tl.Pairs{"originName": k,
 			"originType": o.OriginType, "upstreamHost": o.Host})
@@ -185,8 +202,14 @@ func registerOriginRoutes(router *mux.Router, conf *config.Config, k string,
 		client, err = irondb.NewClient(k, o, mux.NewRouter(), c)
 	case "clickhouse":
 		client, err = clickhouse.NewClient(k, o, mux.NewRouter(), c)
+	case "victoriametrics":
+		client, err = victoriametrics.NewClient(k, o, mux.NewRouter(), c)
+	case "graphite":
+		client, err = graphite.NewClient(k, o, mux.NewRouter(), c)
 	case "rpc", "reverseproxycache":
 		client, err = reverseproxycache.NewClient(k, o, mux.NewRouter(), c)
+	case "grpc_passthrough":
+		client, err = grpcpassthrough.NewClient(k, o, mux.NewRouter(), c)
 	case "rule":
 		client, err = rule.NewClient(k, o, mux.NewRouter(), clients)
 	}
@@ -197,8 +220,9 @@ func registerOriginRoutes(router *mux.Router, conf *config.Config, k string,
 	if client != nil && !dryRun {
 		o.HTTPClient = client.HTTPClient()
 		clients[k] = client
+		startPoolHealthChecks(o)
 		defaultPaths := client.DefaultPathConfigs(o)
-		registerPathRoutes(router, client.Handlers(), client, o, c, defaultPaths,
+		registerPathRoutes(router, client.Handlers(), client, o, c, nc, defaultPaths,
 			tracers, conf.Main.HealthHandlerPath, log)
 	}
 	return clients, nil
@@ -208,7 +232,7 @@ func registerOriginRoutes(router *mux.Router, conf *config.Config, k string,
 // merge it with any path data in the provided originconfig, and then register
 // the path routes to the appropriate handler from the provided handlers map
 func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
-	client origins.Client, oo *oo.Options, c cache.Cache,
+	client origins.Client, oo *oo.Options, c, nc cache.Cache,
 	defaultPaths map[string]*po.Options, tracers tracing.Tracers,
 	healthHandlerPath string, log *tl.Logger) {
 
@@ -232,7 +256,9 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 			h = middleware.Trace(tr, h)
 		}
 		// add Origin, Cache, and Path Configs to the HTTP Request's context
-		h = middleware.WithResourcesContext(client, oo, c, po, tr, log, h)
+		h = middleware.WithResourcesContext(client, oo, c, nc, po, tr, log, h)
+		// apply the origin's duplicate query parameter policy to the request as it is cached and proxied
+		h = middleware.DuplicateParams(oo.DuplicateParamPolicy, h)
 		// attach any request rewriters
 		if len(oo.ReqRewriter) > 0 {
 			h = rewriter.Rewrite(oo.ReqRewriter, h)
@@ -240,15 +266,57 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		if len(po.ReqRewriter) > 0 {
 			h = rewriter.Rewrite(po.ReqRewriter, h)
 		}
+		// make any groups captured by a regex path available to the rewriters
+		if po.MatchType == matching.PathMatchTypeRegex {
+			h = middleware.PathMatch(po.PathRegexp, h)
+		}
+		// replace 5xx responses with the origin's last resort response
+		h = middleware.LastResortResponse(oo, h)
+		// respond on behalf of the origin while it is in maintenance mode
+		h = middleware.Maintenance(oo, h)
+		// reject connection upgrades, unless they are tunneled to the origin by the path
+		if !po.Upgradeable {
+			h = middleware.RejectUpgrades(h)
+		}
+		// compress responses to the client per the origin's compressable_types
+		h = middleware.ResponseCompression(oo, h)
+		// set the Cache-Control header of responses to the client
+		if oo.DownstreamCacheControl != "" {
+			h = middleware.DownstreamCacheControl(oo.DownstreamCacheControl, h)
+		}
 		// decorate frontend prometheus metrics
 		if !po.NoMetrics {
 			h = middleware.Decorate(oo.Name, oo.OriginType, po.Path, h)
 		}
+		// remove the origin's mount prefix before the request is rewritten and proxied
+		if oo.StripPathPrefix != "" {
+			h = middleware.StripPathPrefix(oo.StripPathPrefix, h)
+		}
+		// apply the origin's trailing slash policy
+		h = middleware.TrailingSlash(oo.TrailingSlashPolicy, h)
+		// validate the bearer token of requests accepted by a frontend with a jwt_auth config
+		h = middleware.JWTAuth(oo, h)
+		// reject clients not permitted by the origin's acl
+		h = middleware.ACL(oo.ACL, h)
+		// restrict the origin to the frontends it is bound to
+		h = middleware.FrontendFilter(oo.FrontendNames, h)
 		return h
 	}
 
-	// now we'll go ahead and register the health handler
-	if h, ok := handlers["health"]; ok &&
+	// now we'll go ahead and register the health handler. for an origin with replica urls, it
+	// reports the health of each replica, and of the origin per its health_check_quorum
+	if oo.Pool != nil && healthHandlerPath != "" {
+		hp := strings.Replace(healthHandlerPath+"/"+oo.Name, "//", "/", -1)
+		log.Debug("registering pool health handler path",
+			tl.Pairs{"path": hp, "originName": oo.Name, "quorum": oo.HealthCheckQuorum})
+		// without active health checks, the replicas are checked upon each health request
+		var check func(*pool.Backend) bool
+		if oo.HealthCheckIntervalSecs <= 0 && oo.HTTPClient != nil {
+			check = poolHealthCheck(oo)
+		}
+		router.PathPrefix(hp).HandlerFunc(th.PoolHealthHandleFunc(oo, check)).
+			Methods(methods.CacheableHTTPMethods()...)
+	} else if h, ok := handlers["health"]; ok &&
 		oo.HealthCheckUpstreamPath != "" && oo.HealthCheckVerb != "" && healthHandlerPath != "" {
 		hp := strings.Replace(healthHandlerPath+"/"+oo.Name, "//", "/", -1)
 		log.Debug("registering health handler path",
@@ -256,7 +324,7 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 				"upstreamPath": oo.HealthCheckUpstreamPath,
 				"upstreamVerb": oo.HealthCheckVerb})
 		router.PathPrefix(hp).
-			Handler(middleware.WithResourcesContext(client, oo, nil, nil, tr, log, h)).
+			Handler(middleware.WithResourcesContext(client, oo, nil, nil, nil, tr, log, h)).
 			Methods(methods.CacheableHTTPMethods()...)
 	}
 
@@ -288,6 +356,9 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 	plist := make([]string, 0, len(pathsWithVerbs))
 	deletes := make([]string, 0, len(pathsWithVerbs))
 	for k, p := range pathsWithVerbs {
+		p.InheritCacheKeyHeaders(oo.CacheKeyHeaders)
+		p.AppendCacheKeyHeaders(oo.ClaimCacheKeyHeaders)
+		p.InheritCacheKeyCookies(oo.CacheKeyCookies)
 		if h, ok := handlers[p.HandlerName]; ok && h != nil {
 			p.Handler = h
 			plist = append(plist, k)
@@ -301,11 +372,16 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		delete(pathsWithVerbs, p)
 	}
 
-	sort.Sort(ByLen(plist))
-	for i := len(plist)/2 - 1; i >= 0; i-- {
-		opp := len(plist) - 1 - i
-		plist[i], plist[opp] = plist[opp], plist[i]
-	}
+	// paths are evaluated longest first, with regex paths ranked by their literal prefix
+	sort.Strings(plist)
+	sort.SliceStable(plist, func(i, j int) bool {
+		return matchLen(plist[i], pathsWithVerbs[plist[i]]) > matchLen(plist[j], pathsWithVerbs[plist[j]])
+	})
+	// paths with a higher match priority are registered, and so evaluated, first, while
+	// paths of equal priority retain their longest-first order
+	sort.SliceStable(plist, func(i, j int) bool {
+		return pathsWithVerbs[plist[i]].MatchPriority > pathsWithVerbs[plist[j]].MatchPriority
+	})
 
 	or := client.Router().(*mux.Router)
 
@@ -313,7 +389,9 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		p := pathsWithVerbs[v]
 
 		pathPrefix := "/" + oo.Name
-		handledPath := pathPrefix + p.Path
+		// mountedPath is the path as requested by clients, including any prefix to be stripped
+		mountedPath := oo.StripPathPrefix + p.Path
+		handledPath := pathPrefix + mountedPath
 
 		log.Debug("registering origin handler path",
 			tl.Pairs{"originName": oo.Name, "path": v, "handlerName": p.HandlerName,
@@ -330,24 +408,41 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 				// Case where we path match by prefix
 				// Host Header Routing
 				for _, h := range oo.Hosts {
-					router.PathPrefix(p.Path).Handler(decorate(p)).Methods(p.Methods...).Host(h)
+					router.PathPrefix(mountedPath).Handler(decorate(p)).Methods(p.Methods...).Host(h)
 				}
 				if !oo.PathRoutingDisabled {
 					// Path Routing
 					router.PathPrefix(handledPath).Handler(middleware.StripPathPrefix(pathPrefix, decorate(p))).Methods(p.Methods...)
 				}
-				or.PathPrefix(p.Path).Handler(decorate(p)).Methods(p.Methods...)
-			default:
-				// default to exact match
+				or.PathPrefix(mountedPath).Handler(decorate(p)).Methods(p.Methods...)
+			case matching.PathMatchTypeRegex:
+				// Case where we path match by regular expression
 				// Host Header Routing
 				for _, h := range oo.Hosts {
-					router.Handle(p.Path, decorate(p)).Methods(p.Methods...).Host(h)
+					router.MatcherFunc(regexPathMatcher(p.PathRegexp, oo.StripPathPrefix, oo.TrailingSlashPolicy)).
+						Handler(decorate(p)).Methods(p.Methods...).Host(h)
 				}
 				if !oo.PathRoutingDisabled {
 					// Path Routing
-					router.Handle(handledPath, middleware.StripPathPrefix(pathPrefix, decorate(p))).Methods(p.Methods...)
+					router.MatcherFunc(regexPathMatcher(p.PathRegexp, pathPrefix+oo.StripPathPrefix,
+						oo.TrailingSlashPolicy)).
+						Handler(middleware.StripPathPrefix(pathPrefix, decorate(p))).Methods(p.Methods...)
+				}
+				or.MatcherFunc(regexPathMatcher(p.PathRegexp, oo.StripPathPrefix, oo.TrailingSlashPolicy)).
+					Handler(decorate(p)).Methods(p.Methods...)
+			default:
+				// default to exact match
+				for _, ep := range exactMatchPaths(mountedPath, oo.TrailingSlashPolicy) {
+					// Host Header Routing
+					for _, h := range oo.Hosts {
+						router.Handle(ep, decorate(p)).Methods(p.Methods...).Host(h)
+					}
+					if !oo.PathRoutingDisabled {
+						// Path Routing
+						router.Handle(pathPrefix+ep, middleware.StripPathPrefix(pathPrefix, decorate(p))).Methods(p.Methods...)
+					}
+					or.Handle(ep, decorate(p)).Methods(p.Methods...)
 				}
-				or.Handle(p.Path, decorate(p)).Methods(p.Methods...)
 			}
 		}
 	}
@@ -356,6 +451,7 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 		log.Info("registering default origin handler paths", tl.Pairs{"originName": oo.Name})
 		for _, v := range plist {
 			p := pathsWithVerbs[v]
+			mountedPath := oo.StripPathPrefix + p.Path
 			if p.Handler != nil && len(p.Methods) > 0 {
 				log.Debug("registering default origin handler paths",
 					tl.Pairs{"originName": oo.Name, "path": p.Path, "handlerName": p.HandlerName,
@@ -363,12 +459,19 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 				switch p.MatchType {
 				case matching.PathMatchTypePrefix:
 					// Case where we path match by prefix
-					router.PathPrefix(p.Path).Handler(decorate(p)).Methods(p.Methods...)
+					router.PathPrefix(mountedPath).Handler(decorate(p)).Methods(p.Methods...)
+				case matching.PathMatchTypeRegex:
+					// Case where we path match by regular expression
+					router.MatcherFunc(regexPathMatcher(p.PathRegexp, oo.StripPathPrefix, oo.TrailingSlashPolicy)).
+						Handler(decorate(p)).Methods(p.Methods...)
+					continue
 				default:
 					// default to exact match
-					router.Handle(p.Path, decorate(p)).Methods(p.Methods...)
+					for _, ep := range exactMatchPaths(mountedPath, oo.TrailingSlashPolicy) {
+						router.Handle(ep, decorate(p)).Methods(p.Methods...)
+					}
 				}
-				router.Handle(p.Path, decorate(p)).Methods(p.Methods...)
+				router.Handle(mountedPath, decorate(p)).Methods(p.Methods...)
 			}
 		}
 	}
@@ -376,6 +479,52 @@ func registerPathRoutes(router *mux.Router, handlers map[string]http.Handler,
 	oo.Paths = pathsWithVerbs
 }
 
+// exactMatchPaths returns the paths to register for an exact match path. When the trailing slash policy
+// is not strict, the path is also registered with a trailing slash, so the policy can be applied to it
+func exactMatchPaths(path, policy string) []string {
+	if policy == oo.TrailingSlashPolicyStrict || policy == "" || strings.HasSuffix(path, "/") {
+		return []string{path}
+	}
+	return []string{path, path + "/"}
+}
+
+// regexPathMatcher returns a route matcher for a regex path. The request path must begin with
+// the provided prefix, and the remainder is evaluated against the compiled pattern. When the
+// trailing slash policy is not strict, any trailing slash is disregarded, so the policy can be
+// applied to the request
+func regexPathMatcher(re *regexp.Regexp, prefix, policy string) mux.MatcherFunc {
+	return func(r *http.Request, _ *mux.RouteMatch) bool {
+		if re == nil || r.URL == nil || !strings.HasPrefix(r.URL.Path, prefix) {
+			return false
+		}
+		path := r.URL.Path[len(prefix):]
+		if !strings.HasPrefix(path, "/") {
+			return false
+		}
+		if (policy == oo.TrailingSlashPolicyIgnore || policy == oo.TrailingSlashPolicyRedirect) &&
+			len(path) > 1 {
+			if path = strings.TrimRight(path, "/"); path == "" {
+				path = "/"
+			}
+		}
+		return re.MatchString(path)
+	}
+}
+
+// matchLen returns the length by which the path keyed by k is ranked for evaluation. A regex
+// path is ranked by the length of the literal prefix of its pattern rather than the pattern
+// itself, so that a broad pattern is not evaluated ahead of a more specific path
+func matchLen(k string, p *po.Options) int {
+	if p.MatchType == matching.PathMatchTypeRegex && strings.HasPrefix(k, p.Path) {
+		// the literal prefix is only reported for patterns that are not anchored
+		if re, err := regexp.Compile(p.Path); err == nil {
+			prefix, _ := re.LiteralPrefix()
+			return len(prefix) + len(k) - len(p.Path)
+		}
+	}
+	return len(k)
+}
+
 // ByLen allows sorting of a string slice by string length
 type ByLen []string
 
@@ -390,3 +539,32 @@ func (a ByLen) Less(i, j int) bool {
 func (a ByLen) Swap(i, j int) {
 	a[i], a[j] = a[j], a[i]
 }
+
+// startPoolHealthChecks begins health checking the replicas of an origin configured with
+// origin_urls, using the origin's health check settings, so unhealthy replicas are skipped
+func startPoolHealthChecks(o *oo.Options) {
+	if o.Pool == nil || o.HealthCheckIntervalSecs <= 0 || o.HTTPClient == nil {
+		return
+	}
+	o.Pool.StartHealthChecks(time.Duration(o.HealthCheckIntervalSecs)*time.Second, poolHealthCheck(o))
+}
+
+// poolHealthCheck returns the health check of an origin's replicas per its health check settings
+func poolHealthCheck(o *oo.Options) func(*pool.Backend) bool {
+	path, method, query := o.HealthCheckUpstreamPath, o.HealthCheckVerb, o.HealthCheckQuery
+	if path == "-" {
+		path = "/"
+	}
+	if method == "-" {
+		method = http.MethodGet
+	}
+	if query == "-" {
+		query = ""
+	}
+	var h http.Header
+	if len(o.HealthCheckHeaders) > 0 {
+		h = http.Header{}
+		headers.UpdateHeaders(h, o.HealthCheckHeaders)
+	}
+	return pool.HTTPCheck(o.HTTPClient, method, path, query, h)
+}
@@ -19,22 +19,29 @@ package routing
 import (
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/tricksterproxy/trickster/pkg/cache/registration"
 	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins"
 	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/reverseproxycache"
 	"github.com/tricksterproxy/trickster/pkg/proxy/origins/rule"
+	"github.com/tricksterproxy/trickster/pkg/proxy/paths/matching"
 	po "github.com/tricksterproxy/trickster/pkg/proxy/paths/options"
 	"github.com/tricksterproxy/trickster/pkg/tracing"
 	"github.com/tricksterproxy/trickster/pkg/tracing/exporters/zipkin"
 	to "github.com/tricksterproxy/trickster/pkg/tracing/options"
 	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/middleware"
 	tlstest "github.com/tricksterproxy/trickster/pkg/util/testing/tls"
 
+	"github.com/andybalholm/brotli"
 	"github.com/gorilla/mux"
 )
 
@@ -186,6 +193,48 @@ func TestRegisterProxyRoutesClickHouse(t *testing.T) {
 
 }
 
+func TestRegisterProxyRoutesVictoriaMetrics(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", "http://1", "-origin-type", "victoriametrics"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	proxyClients, err := RegisterProxyRoutes(conf, mux.NewRouter(), caches, nil, tl.ConsoleLogger("info"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(proxyClients) == 0 {
+		t.Errorf("expected %d got %d", 1, 0)
+	}
+
+}
+
+func TestRegisterProxyRoutesGraphite(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", "http://1", "-origin-type", "graphite"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	proxyClients, err := RegisterProxyRoutes(conf, mux.NewRouter(), caches, nil, tl.ConsoleLogger("info"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(proxyClients) == 0 {
+		t.Errorf("expected %d got %d", 1, 0)
+	}
+
+}
+
 func TestRegisterProxyRoutesIRONdb(t *testing.T) {
 
 	conf, _, err := config.Load("trickster", "test",
@@ -345,7 +394,7 @@ func TestRegisterMultipleOriginsPlusDefault(t *testing.T) {
 
 func TestRegisterPathRoutes(t *testing.T) {
 	p := map[string]*po.Options{"test": {}}
-	registerPathRoutes(nil, nil, nil, nil, nil, p, nil, "", nil)
+	registerPathRoutes(nil, nil, nil, nil, nil, nil, p, nil, "", nil)
 
 	conf, _, err := config.Load("trickster", "test",
 		[]string{"-log-level", "debug", "-origin-url", "http://1", "-origin-type", "rpc"})
@@ -357,8 +406,664 @@ func TestRegisterPathRoutes(t *testing.T) {
 	rpc, _ := reverseproxycache.NewClient("test", oo, mux.NewRouter(), nil)
 	dpc := rpc.DefaultPathConfigs(oo)
 	dpc["/-GET-HEAD"].Methods = nil
-	registerPathRoutes(nil, nil, rpc, oo, nil, dpc, nil, "", tl.ConsoleLogger("INFO"))
+	registerPathRoutes(nil, nil, rpc, oo, nil, nil, dpc, nil, "", tl.ConsoleLogger("INFO"))
+
+}
+
+func TestRegisterPathRoutesCacheKeyHeaders(t *testing.T) {
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", "http://1", "-origin-type", "rpc"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	oo := conf.Origins["default"]
+	oo.CacheKeyHeaders = []string{"X-Tenant"}
+	p := po.NewOptions()
+	p.CacheKeyHeaders = []string{"X-Api-Version"}
+	p.ReplaceCacheKeyHeaders = true
+	p.Custom = []string{"cache_key_headers", "replace_cache_key_headers"}
+	oo.Paths = map[string]*po.Options{"/-GET-HEAD": p}
+
+	rpc, _ := reverseproxycache.NewClient("test", oo, mux.NewRouter(), nil)
+	dpc := rpc.DefaultPathConfigs(oo)
+	registerPathRoutes(mux.NewRouter(), rpc.Handlers(), rpc, oo, nil, nil, dpc, nil, "",
+		tl.ConsoleLogger("error"))
+
+	// the configured path replaces the origin's headers
+	if v := dpc["/-GET-HEAD"].CacheKeyHeaders; len(v) != 1 || v[0] != "X-Api-Version" {
+		t.Errorf("expected %v got %v", p.CacheKeyHeaders, v)
+	}
+	// and the other default path inherits them
+	for k, pc := range dpc {
+		if k == "/-GET-HEAD" {
+			continue
+		}
+		if v := pc.CacheKeyHeaders; len(v) != 1 || v[0] != "X-Tenant" {
+			t.Errorf("expected %v got %v", oo.CacheKeyHeaders, v)
+		}
+	}
+}
+
+func TestRegisterProxyRoutesTrailingSlashRedirect(t *testing.T) {
+
+	log := tl.ConsoleLogger("info")
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", "http://1", "-origin-type", "prometheus"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	conf.Origins["default"].TrailingSlashPolicy = oo.TrailingSlashPolicyRedirect
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, log, false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, u := range []string{"http://0/api/v1/query_range/?query=up", "http://0/default/api/v1/query_range/?query=up"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, u, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusPermanentRedirect {
+			t.Errorf("expected %d got %d", http.StatusPermanentRedirect, w.Code)
+		}
+		if l := w.Header().Get("Location"); !strings.HasSuffix(l, "/api/v1/query_range?query=up") {
+			t.Errorf("unexpected redirect location %s", l)
+		}
+	}
+}
+
+func TestRegisterProxyRoutesStripPathPrefix(t *testing.T) {
+
+	var upstreamPath string
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer es.Close()
+
+	log := tl.ConsoleLogger("info")
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", es.URL, "-origin-type", "rpc"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	conf.Origins["default"].StripPathPrefix = "/prometheus"
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, log, false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, u := range []string{"http://0/prometheus/query", "http://0/default/prometheus/query"} {
+		upstreamPath = ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, u, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+		}
+		if upstreamPath != "/query" {
+			t.Errorf("expected upstream path %s got %s", "/query", upstreamPath)
+		}
+	}
+
+	// requests without the prefix are not routed to the origin
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://0/query", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestRegisterProxyRoutesDownstreamCacheControl(t *testing.T) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameCacheControl, "max-age=60")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("test"))
+	}))
+	defer es.Close()
+
+	log := tl.ConsoleLogger("info")
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", es.URL, "-origin-type", "reverseproxycache"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	conf.Origins["default"].DownstreamCacheControl = "no-store"
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, log, false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// the client's Cache-Control is replaced, while Trickster still caches per the origin's header
+	for i, expected := range []string{"status=kmiss", "status=hit"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://0/downstream-cache-control", nil)
+		router.ServeHTTP(w, r)
+		if v := w.Header().Get(headers.NameCacheControl); v != "no-store" {
+			t.Errorf("expected %s got %s", "no-store", v)
+		}
+		if v := w.Header().Get(headers.NameTricksterResult); !strings.Contains(v, expected) {
+			t.Errorf("request %d: expected %s got %s", i, expected, v)
+		}
+	}
+}
+
+func TestRegisterProxyRoutesFrontendNames(t *testing.T) {
+
+	log := tl.ConsoleLogger("info")
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", "http://1", "-origin-type", "prometheus"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	conf.Origins["default"].FrontendNames = []string{"internal"}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, log, false)
+	if err != nil {
+		t.Error(err)
+	}
 
+	// the main frontend does not serve the origin
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://0/default/api/v1/query_range/?query=up", nil)
+	router.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected %d got %d", http.StatusNotFound, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	middleware.WithFrontendName("internal", router).ServeHTTP(w, r)
+	if w.Code == http.StatusNotFound {
+		t.Error("expected origin to be served by the internal frontend")
+	}
+}
+
+func TestRegisterProxyRoutesRegexPath(t *testing.T) {
+
+	var upstreamPath, tenant string
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath = r.URL.Path
+		tenant = r.Header.Get("X-Tenant")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer es.Close()
+
+	f, err := ioutil.TempFile("", "trickster-regex-path-*.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`
+[request_rewriters]
+  [request_rewriters.tenant]
+  instructions = [
+    ['header', 'set', 'X-Tenant', '${match:tenant}'],
+  ]
+
+[origins]
+  [origins.default]
+  origin_type = 'rpc'
+  origin_url = '` + es.URL + `'
+    [origins.default.paths]
+      [origins.default.paths.tenant]
+      path = '/api/v1/tenants/(?P<tenant>[^/]+)/query'
+      match_type = 'regex'
+      req_rewriter_name = 'tenant'
+      handler = 'proxy'
+`)
+	f.Close()
+
+	conf, _, err := config.Load("trickster", "test", []string{"-config", f.Name()})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("info"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, u := range []string{"http://0/api/v1/tenants/acme/query", "http://0/default/api/v1/tenants/acme/query"} {
+		upstreamPath, tenant = "", ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, u, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+		}
+		if upstreamPath != "/api/v1/tenants/acme/query" {
+			t.Errorf("expected upstream path %s got %s", "/api/v1/tenants/acme/query", upstreamPath)
+		}
+		if tenant != "acme" {
+			t.Errorf("expected tenant %s got %s", "acme", tenant)
+		}
+	}
+
+	// the pattern must match the entire path, so this is handled by the default '/' path
+	tenant = ""
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://0/api/v1/tenants/acme/query/extra", nil)
+	router.ServeHTTP(w, r)
+	if tenant != "" {
+		t.Errorf("expected no tenant got %s", tenant)
+	}
+}
+
+func TestRegisterProxyRoutesMatchPriority(t *testing.T) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer es.Close()
+
+	for _, test := range []struct {
+		priority, expected int
+	}{
+		// the catch-all path's methods make it the longer path, so it is evaluated first
+		{0, http.StatusOK},
+		{10, http.StatusNoContent},
+	} {
+		conf, _, err := config.Load("trickster", "test",
+			[]string{"-log-level", "debug", "-origin-url", es.URL, "-origin-type", "rpc"})
+		if err != nil {
+			t.Fatalf("Could not load configuration: %s", err.Error())
+		}
+		oc := conf.Origins["default"]
+
+		p1 := po.NewOptions()
+		p1.Path = "/"
+		p1.MatchType = matching.PathMatchTypePrefix
+		p1.Methods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut}
+		p1.Custom = []string{"path", "match_type", "methods"}
+
+		p2 := po.NewOptions()
+		p2.Path = "/metrics"
+		p2.Methods = []string{http.MethodGet}
+		p2.HandlerName = "localresponse"
+		p2.ResponseCode = http.StatusNoContent
+		p2.MatchPriority = test.priority
+		p2.Custom = []string{"path", "methods", "handler", "response_code", "match_priority"}
+
+		oc.Paths = map[string]*po.Options{"/-GET-HEAD-POST-PUT": p1, "/metrics-GET": p2}
+
+		caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+		router := mux.NewRouter()
+		_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("error"), false)
+		if err != nil {
+			t.Error(err)
+		}
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://0/metrics", nil)
+		router.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("priority %d: expected %d got %d", test.priority, test.expected, w.Code)
+		}
+		registration.CloseCaches(caches)
+	}
+}
+
+func TestRegisterProxyRoutesRegexPathSpecificity(t *testing.T) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", es.URL, "-origin-type", "rpc"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	oc := conf.Origins["default"]
+
+	// the broad pattern is longer than the exact path, but has a shorter literal prefix
+	p1 := po.NewOptions()
+	p1.Path = "/api/v1/.*.*.*.*.*.*.*.*.*.*"
+	p1.MatchType = matching.PathMatchTypeRegex
+	p1.PathRegexp = regexp.MustCompile("^(?:" + p1.Path + ")$")
+	p1.Methods = []string{http.MethodGet}
+	p1.HandlerName = "localresponse"
+	p1.ResponseCode = http.StatusNoContent
+	p1.Custom = []string{"path", "match_type", "methods", "handler", "response_code"}
+
+	p2 := po.NewOptions()
+	p2.Path = "/api/v1/query"
+	p2.Methods = []string{http.MethodGet}
+	p2.HandlerName = "proxy"
+	p2.Custom = []string{"path", "methods", "handler"}
+
+	oc.Paths = map[string]*po.Options{p1.Path + "-GET": p1, p2.Path + "-GET": p2}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for u, expected := range map[string]int{
+		"http://0/api/v1/query": http.StatusOK,
+		"http://0/api/v1/other": http.StatusNoContent,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, u, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != expected {
+			t.Errorf("%s: expected %d got %d", u, expected, w.Code)
+		}
+	}
+}
+
+func TestMatchLen(t *testing.T) {
+
+	p := po.NewOptions()
+	p.Path = "/api/v[0-9]+/query"
+	p.MatchType = matching.PathMatchTypeRegex
+	if v := matchLen(p.Path+"-GET", p); v != len("/api/v-GET") {
+		t.Errorf("expected %d got %d", len("/api/v-GET"), v)
+	}
+
+	p.MatchType = matching.PathMatchTypeExact
+	if v := matchLen(p.Path+"-GET", p); v != len(p.Path+"-GET") {
+		t.Errorf("expected %d got %d", len(p.Path+"-GET"), v)
+	}
+}
+
+func TestRegisterProxyRoutesUpgradeable(t *testing.T) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer es.Close()
+
+	for _, test := range []struct {
+		upgradeable bool
+		expected    int
+	}{
+		// the origin does not switch protocols, so its response is passed through
+		{true, http.StatusOK},
+		{false, http.StatusBadRequest},
+	} {
+		conf, _, err := config.Load("trickster", "test",
+			[]string{"-log-level", "debug", "-origin-url", es.URL, "-origin-type", "rpc"})
+		if err != nil {
+			t.Fatalf("Could not load configuration: %s", err.Error())
+		}
+		oc := conf.Origins["default"]
+
+		p := po.NewOptions()
+		p.Path = "/stream"
+		p.HandlerName = "proxy"
+		p.Methods = []string{http.MethodGet}
+		p.Upgradeable = test.upgradeable
+		p.Custom = []string{"path", "handler", "methods", "upgradeable"}
+		oc.Paths = map[string]*po.Options{"/stream-GET": p}
+
+		caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+		router := mux.NewRouter()
+		_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("error"), false)
+		if err != nil {
+			t.Error(err)
+		}
+
+		ts := httptest.NewServer(router)
+		r, _ := http.NewRequest(http.MethodGet, ts.URL+"/stream", nil)
+		r.Header.Set(headers.NameConnection, "Upgrade")
+		r.Header.Set(headers.NameUpgrade, "websocket")
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.expected {
+			t.Errorf("upgradeable %t: expected %d got %d", test.upgradeable, test.expected, resp.StatusCode)
+		}
+		ts.Close()
+		registration.CloseCaches(caches)
+	}
+}
+
+func TestRegisterProxyRoutesMaintenance(t *testing.T) {
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", es.URL, "-origin-type", "rpc"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	oc := conf.Origins["default"]
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// maintenance mode is applied to the registered routes as it changes
+	for _, test := range []struct {
+		maintenance bool
+		expected    int
+	}{
+		{false, http.StatusOK},
+		{true, http.StatusServiceUnavailable},
+		{false, http.StatusOK},
+	} {
+		oc.SetMaintenance(test.maintenance)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://0/", nil)
+		router.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("maintenance %t: expected %d got %d", test.maintenance, test.expected, w.Code)
+		}
+	}
+}
+
+func TestRegisterProxyRoutesLastResortResponse(t *testing.T) {
+
+	status := http.StatusServiceUnavailable
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Upstream", "test")
+		w.WriteHeader(status)
+		w.Write([]byte("upstream"))
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", es.URL, "-origin-type", "reverseproxycache"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	conf.Origins["default"].LastResortResponse = &oo.LastResortResponseOptions{Code: http.StatusOK,
+		Body: "[]", Headers: map[string]string{headers.NameContentType: headers.ValueApplicationJSON}}
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		path, body   string
+		status, code int
+	}{
+		// the failed response is replaced, and is not cached in place of the upstream's response
+		{"/last-resort", "[]", http.StatusServiceUnavailable, http.StatusOK},
+		{"/last-resort", "upstream", http.StatusOK, http.StatusOK},
+		{"/missing", "", http.StatusOK, http.StatusNotFound},
+	}
+
+	for i, test := range tests {
+		status = test.status
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://0"+test.path, nil)
+		router.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("test %d: expected %d got %d", i, test.code, w.Code)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("test %d: expected %s got %s", i, test.body, w.Body.String())
+		}
+		if test.body != "[]" {
+			continue
+		}
+		if v := w.Header().Get(headers.NameContentType); v != headers.ValueApplicationJSON {
+			t.Errorf("test %d: expected %s got %s", i, headers.ValueApplicationJSON, v)
+		}
+		if v := w.Header().Get("X-Upstream"); v != "" {
+			t.Errorf("test %d: unexpected upstream header %s", i, v)
+		}
+	}
+}
+
+func TestRegisterProxyRoutesCompression(t *testing.T) {
+
+	const body = `{"status":"success","data":{"resultType":"matrix","result":[]}}`
+
+	es := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image":
+			w.Header().Set(headers.NameContentType, "image/png")
+		case "/no-transform":
+			w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+			w.Header().Set(headers.NameCacheControl, headers.ValueNoTransform)
+		default:
+			w.Header().Set(headers.NameContentType, headers.ValueApplicationJSON)
+		}
+		w.Write([]byte(body))
+	}))
+	defer es.Close()
+
+	conf, _, err := config.Load("trickster", "test",
+		[]string{"-log-level", "debug", "-origin-url", es.URL, "-origin-type", "rpc"})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	conf.Origins["default"].BrotliQuality = 11
+
+	caches := registration.LoadCachesFromConfig(conf, tl.ConsoleLogger("error"))
+	defer registration.CloseCaches(caches)
+	router := mux.NewRouter()
+	_, err = RegisterProxyRoutes(conf, router, caches, nil, tl.ConsoleLogger("error"), false)
+	if err != nil {
+		t.Error(err)
+	}
+	h := middleware.Compress(router)
+
+	tests := []struct {
+		path, acceptEncoding, expected string
+	}{
+		{"/", "br", "br"},
+		{"/", "gzip, deflate, br", "br"},
+		{"/", "gzip, br;q=0.5", "gzip"},
+		{"/", "*;q=0.1, deflate", "deflate"},
+		{"/", "br;q=0, gzip;q=0", ""},
+		{"/", "", ""},
+		{"/image", "br", ""},
+		{"/no-transform", "br", ""},
+		// a range request is answered with partial content
+		{"/partial", "br", ""},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://0"+test.path, nil)
+		if test.acceptEncoding != "" {
+			r.Header.Set(headers.NameAcceptEncoding, test.acceptEncoding)
+		}
+		if test.path == "/partial" {
+			r.Header.Set(headers.NameRange, "bytes=0-9")
+		}
+		h.ServeHTTP(w, r)
+		if v := w.Header().Get(headers.NameContentEncoding); v != test.expected {
+			t.Errorf("%s %q: expected encoding %q got %q", test.path, test.acceptEncoding, test.expected, v)
+		}
+	}
+
+	// the brotli-encoded body decodes to the upstream response
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://0/", nil)
+	r.Header.Set(headers.NameAcceptEncoding, "br")
+	h.ServeHTTP(w, r)
+	if v := w.Header().Get(headers.NameVary); v != headers.NameAcceptEncoding {
+		t.Errorf("expected %s got %s", headers.NameAcceptEncoding, v)
+	}
+	b, err := ioutil.ReadAll(brotli.NewReader(w.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != body {
+		t.Errorf("expected %s got %s", body, string(b))
+	}
+}
+
+func TestRegexPathMatcher(t *testing.T) {
+
+	re := regexp.MustCompile("^(?:/api/[^/]+/query)$")
+	tests := []struct {
+		prefix, policy, path string
+		expected             bool
+	}{
+		{"", "", "/api/v1/query", true},
+		{"", "", "/api/v1/query/", false},
+		{"", oo.TrailingSlashPolicyIgnore, "/api/v1/query/", true},
+		{"/default", "", "/default/api/v1/query", true},
+		{"/default", "", "/api/v1/query", false},
+		{"/default", "", "/defaultapi/v1/query", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://0"+test.path, nil)
+		if v := regexPathMatcher(re, test.prefix, test.policy)(r, nil); v != test.expected {
+			t.Errorf("%s%s: expected %t got %t", test.prefix, test.path, test.expected, v)
+		}
+	}
+}
+
+func TestExactMatchPaths(t *testing.T) {
+	if p := exactMatchPaths("/test", oo.TrailingSlashPolicyStrict); len(p) != 1 {
+		t.Errorf("expected %d got %d", 1, len(p))
+	}
+	if p := exactMatchPaths("/test/", oo.TrailingSlashPolicyIgnore); len(p) != 1 {
+		t.Errorf("expected %d got %d", 1, len(p))
+	}
+	if p := exactMatchPaths("/test", oo.TrailingSlashPolicyIgnore); len(p) != 2 || p[1] != "/test/" {
+		t.Errorf("unexpected paths %v", p)
+	}
 }
 
 func TestValidateRuleClients(t *testing.T) {
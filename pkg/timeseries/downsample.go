@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import "math"
+
+// DownsampleMethod enumerates the methods for reducing the number of values in a series
+type DownsampleMethod int
+
+const (
+	// DownsampleLTTB selects the values that best preserve the visual shape of the series,
+	// using the Largest-Triangle-Three-Buckets algorithm
+	DownsampleLTTB = DownsampleMethod(iota)
+	// DownsampleAverage replaces each bucket of consecutive values with their average
+	DownsampleAverage
+)
+
+// DownsampleMethodNames is a map of DownsampleMethods keyed by name
+var DownsampleMethodNames = map[string]DownsampleMethod{
+	"lttb":    DownsampleLTTB,
+	"average": DownsampleAverage,
+}
+
+// Downsampler is implemented by Timeseries that can reduce the number of values in their series
+type Downsampler interface {
+	// Downsample should reduce each Series in the Timeseries to at most the provided number
+	// of values, using the provided method
+	Downsample(int, DownsampleMethod)
+}
+
+// LTTB returns the indexes of the threshold points, of those with the provided x and y values,
+// that are selected by the Largest-Triangle-Three-Buckets algorithm. The first and last points
+// are always selected. If there are no more than threshold points, or threshold is less than 3,
+// all indexes, or the first and last indexes, respectively, are returned.
+func LTTB(x, y []float64, threshold int) []int {
+	n := len(x)
+	if threshold >= n || n <= 2 {
+		out := make([]int, n)
+		for i := range out {
+			out[i] = i
+		}
+		return out
+	}
+	if threshold < 3 {
+		return []int{0, n - 1}
+	}
+
+	out := make([]int, 0, threshold)
+	out = append(out, 0)
+
+	// the points between the first and last are divided into threshold-2 buckets
+	every := float64(n-2) / float64(threshold-2)
+	a := 0
+	for i := 0; i < threshold-2; i++ {
+
+		// the average of the next bucket is the third point of the triangle
+		nextStart := int(float64(i+1)*every) + 1
+		nextEnd := int(float64(i+2)*every) + 1
+		if nextEnd > n {
+			nextEnd = n
+		}
+		if nextStart >= nextEnd {
+			nextStart = nextEnd - 1
+		}
+		var avgX, avgY float64
+		for j := nextStart; j < nextEnd; j++ {
+			avgX += x[j]
+			avgY += finite(y[j])
+		}
+		avgX /= float64(nextEnd - nextStart)
+		avgY /= float64(nextEnd - nextStart)
+
+		start := int(float64(i)*every) + 1
+		end := int(float64(i+1)*every) + 1
+		maxArea := -1.0
+		selected := start
+		for j := start; j < end; j++ {
+			area := math.Abs((x[a]-avgX)*(finite(y[j])-finite(y[a])) -
+				(x[a]-x[j])*(avgY-finite(y[a])))
+			if area > maxArea {
+				maxArea = area
+				selected = j
+			}
+		}
+		out = append(out, selected)
+		a = selected
+	}
+
+	return append(out, n-1)
+}
+
+// AverageBuckets returns the starting index of each of the threshold buckets of consecutive
+// points into which n points are evenly divided for averaging. If n is no more than threshold,
+// each point is its own bucket.
+func AverageBuckets(n, threshold int) []int {
+	if threshold >= n {
+		threshold = n
+	}
+	if threshold < 1 {
+		return nil
+	}
+	out := make([]int, threshold)
+	for i := range out {
+		out[i] = i * n / threshold
+	}
+	return out
+}
+
+// finite returns v, or 0 if v is NaN or infinite, so that non-finite values do not
+// prevent LTTB from comparing triangle areas
+func finite(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeseries
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLTTB(t *testing.T) {
+
+	x := []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	y := []float64{0, 0, 10, 0, 0, 0, -10, 0, 0, 0}
+
+	// the peaks are preserved
+	idx := LTTB(x, y, 4)
+	if !reflect.DeepEqual(idx, []int{0, 2, 6, 9}) {
+		t.Errorf("expected %v got %v", []int{0, 2, 6, 9}, idx)
+	}
+
+	if idx = LTTB(x, y, 10); len(idx) != 10 {
+		t.Errorf("expected %d got %d", 10, len(idx))
+	}
+
+	if idx = LTTB(x, y, 2); !reflect.DeepEqual(idx, []int{0, 9}) {
+		t.Errorf("expected %v got %v", []int{0, 9}, idx)
+	}
+
+	y[4] = math.NaN()
+	if idx = LTTB(x, y, 5); len(idx) != 5 {
+		t.Errorf("expected %d got %d", 5, len(idx))
+	}
+}
+
+func TestAverageBuckets(t *testing.T) {
+
+	tests := []struct {
+		n, threshold int
+		expected     []int
+	}{
+		{10, 5, []int{0, 2, 4, 6, 8}},
+		{10, 3, []int{0, 3, 6}},
+		{3, 5, []int{0, 1, 2}},
+		{3, 0, nil},
+	}
+
+	for _, test := range tests {
+		if v := AverageBuckets(test.n, test.threshold); !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("expected %v got %v", test.expected, v)
+		}
+	}
+}
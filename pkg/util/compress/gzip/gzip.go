@@ -23,6 +23,19 @@ import (
 	"io/ioutil"
 )
 
+// Deflate returns the gzip-deflated version of the byte slice
+func Deflate(in []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	if _, err := gw.Write(in); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 // Inflate returns the inflated version of a gzip-deflated byte slice
 func Inflate(in []byte) ([]byte, error) {
 	gr, err := gzip.NewReader(bytes.NewReader(in))
@@ -41,3 +41,18 @@ func TestInflate(t *testing.T) {
 	}
 
 }
+
+func TestDeflate(t *testing.T) {
+	const expected = "this is the inflated text string"
+	c, err := Deflate([]byte(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := Inflate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(u) != expected {
+		t.Errorf(`got "%s" expected "%s"`, string(u), expected)
+	}
+}
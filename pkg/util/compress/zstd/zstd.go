@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package zstd provides zstd compression capabilities for byte slices
+package zstd
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder and decoder compress and decompress byte slices without a dictionary.
+// EncodeAll and DecodeAll are safe for concurrent use
+var encoder, _ = zstd.NewWriter(nil)
+var decoder, _ = zstd.NewReader(nil)
+
+// Deflate returns the zstd-compressed version of the byte slice
+func Deflate(in []byte) []byte {
+	return encoder.EncodeAll(in, nil)
+}
+
+// Inflate returns the inflated version of a zstd-compressed byte slice
+func Inflate(in []byte) ([]byte, error) {
+	return decoder.DecodeAll(in, nil)
+}
+
+// Dictionary compresses and decompresses byte slices using a shared, pre-trained zstd dictionary
+type Dictionary struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// dictionaries holds the Dictionary created for each distinct trained dictionary. A decoder runs
+// goroutines until it is closed, and may be in use by requests of the previous config during a
+// reload, so each dictionary's Dictionary is created once and shared by every config that uses it
+var dictionaries = make(map[[sha256.Size]byte]*Dictionary)
+var dictionariesLock sync.Mutex
+
+// NewDictionary returns a Dictionary for the provided trained zstd dictionary,
+// or an error if the dictionary is not valid
+func NewDictionary(dict []byte) (*Dictionary, error) {
+	sum := sha256.Sum256(dict)
+	dictionariesLock.Lock()
+	defer dictionariesLock.Unlock()
+	if d, ok := dictionaries[sum]; ok {
+		return d, nil
+	}
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderDict(dict))
+	if err != nil {
+		return nil, err
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dict))
+	if err != nil {
+		enc.Close()
+		return nil, err
+	}
+	d := &Dictionary{encoder: enc, decoder: dec}
+	dictionaries[sum] = d
+	return d, nil
+}
+
+// Deflate returns the dictionary-compressed version of the byte slice
+func (d *Dictionary) Deflate(in []byte) []byte {
+	return d.encoder.EncodeAll(in, nil)
+}
+
+// Inflate returns the inflated version of a dictionary-compressed byte slice
+func (d *Dictionary) Inflate(in []byte) ([]byte, error) {
+	return d.decoder.DecodeAll(in, nil)
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package zstd
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestDictionary(t *testing.T) {
+
+	_, err := NewDictionary([]byte("not a dictionary"))
+	if err == nil {
+		t.Error("expected error for invalid dictionary")
+	}
+
+	b, err := ioutil.ReadFile("../../../../testdata/zstd_test.dict")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDictionary(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same dictionary is shared rather than recreated, such as on a config reload
+	if d2, _ := NewDictionary(b); d2 != d {
+		t.Error("expected the existing dictionary to be reused")
+	}
+
+	const expected = `{"status":"success","data":{"resultType":"matrix","result":[{"metric":` +
+		`{"__name__":"up","instance":"localhost:9090","job":"prometheus"},"values":[[1577836800,"1"]]}]}}`
+	c := d.Deflate([]byte(expected))
+	if len(c) >= len(expected) {
+		t.Errorf("expected compressed size less than %d got %d", len(expected), len(c))
+	}
+
+	u, err := d.Inflate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(u) != expected {
+		t.Errorf(`got "%s" expected "%s"`, string(u), expected)
+	}
+
+	_, err = d.Inflate([]byte("invalid"))
+	if err == nil {
+		t.Error("expected error for invalid compressed data")
+	}
+}
+
+func TestDeflate(t *testing.T) {
+	const expected = "this is the inflated text string, this is the inflated text string"
+	c := Deflate([]byte(expected))
+	u, err := Inflate(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(u) != expected {
+		t.Errorf(`got "%s" expected "%s"`, string(u), expected)
+	}
+	_, err = Inflate([]byte("invalid"))
+	if err == nil {
+		t.Error("expected error for invalid compressed data")
+	}
+}
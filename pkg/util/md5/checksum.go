@@ -22,6 +22,9 @@ import (
 	"fmt"
 )
 
+// ChecksumLength is the length of the hex string returned by Checksum
+const ChecksumLength = 32
+
 // Checksum returns the calculated hex string version of the md5 checksum for the input string
 func Checksum(input string) string {
 	return fmt.Sprintf("%x", md5.Sum([]byte(input)))
@@ -36,6 +36,8 @@ const (
 // Default histogram buckets used by trickster
 var (
 	defaultBuckets = []float64{0.05, 0.1, 0.5, 1, 5, 10, 20}
+	configBuckets  = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+	ttlBuckets     = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 21600, 86400}
 )
 
 // BuildInfo is a Gauge representing the Trickster binary build information of the running server instance
@@ -44,6 +46,12 @@ var BuildInfo *prometheus.GaugeVec
 // LastReloadSuccessful gauge will be set to 1 if Trickster's last config reload succeeded else 0
 var LastReloadSuccessful prometheus.Gauge
 
+// ReloadsSkipped counter is the number of config reloads skipped because one was already in progress
+var ReloadsSkipped prometheus.Counter
+
+// ConfigLoadPhaseDuration is a histogram of the time spent in each phase of loading the configuration
+var ConfigLoadPhaseDuration *prometheus.HistogramVec
+
 // LastReloadSuccessfulTimestamp gauge is the epoch time of the most recent successful config load
 var LastReloadSuccessfulTimestamp prometheus.Gauge
 
@@ -56,12 +64,24 @@ var FrontendRequestDuration *prometheus.HistogramVec
 // FrontendRequestWrittenBytes is a Counter of bytes written for front end requests
 var FrontendRequestWrittenBytes *prometheus.CounterVec
 
+// FrontendAuthFailures is a Counter of front end requests rejected by HTTP Basic Authentication
+var FrontendAuthFailures *prometheus.CounterVec
+
 // ProxyRequestStatus is a Counter of downstream client requests handled by Trickster
 var ProxyRequestStatus *prometheus.CounterVec
 
 // ProxyRequestElements is a Counter of data points in the timeseries returned to the requesting client
 var ProxyRequestElements *prometheus.CounterVec
 
+// ProxyByteRangeRequests is a Counter of downstream client byte range requests handled by the object proxy cache
+var ProxyByteRangeRequests *prometheus.CounterVec
+
+// ProxyRequestBytes is a Counter of request body bytes read from downstream clients
+var ProxyRequestBytes *prometheus.CounterVec
+
+// ProxyResponseBytes is a Counter of response bytes written to downstream clients, by cache result
+var ProxyResponseBytes *prometheus.CounterVec
+
 // ProxyRequestDuration is a Histogram of time required in seconds to proxy a given Prometheus query
 var ProxyRequestDuration *prometheus.HistogramVec
 
@@ -74,6 +94,9 @@ var CacheByteOperations *prometheus.CounterVec
 // CacheEvents is a Counter of events performed on a Trickster cache
 var CacheEvents *prometheus.CounterVec
 
+// CacheEvictions is a Counter of objects evicted from a Trickster cache to maintain its maximum size
+var CacheEvictions *prometheus.CounterVec
+
 // CacheObjects is a Gauge representing the number of objects in a Trickster cache
 var CacheObjects *prometheus.GaugeVec
 
@@ -86,6 +109,70 @@ var CacheMaxObjects *prometheus.GaugeVec
 // CacheMaxBytes is a Gauge for the Trickster cache's Max Object Threshold for triggering an eviction exercise
 var CacheMaxBytes *prometheus.GaugeVec
 
+// ProxyActiveGoroutines is a Gauge representing the number of goroutines spawned by an origin's proxy handlers
+// that are currently running
+var ProxyActiveGoroutines *prometheus.GaugeVec
+
+// ProxyCollapsedWaiters is a Gauge representing the number of requests currently waiting on an origin's
+// collapsed-forwarding fetches
+var ProxyCollapsedWaiters *prometheus.GaugeVec
+
+// ProxyDistinctQueryShapes is a Gauge representing the number of distinct timeseries query shapes
+// cached by an origin that is configured with max_distinct_query_shapes
+var ProxyDistinctQueryShapes *prometheus.GaugeVec
+
+// ProxyCollapsedTimeouts is a Counter representing the number of requests that abandoned waiting on an
+// origin's collapsed-forwarding fetches after exceeding collapsed_forwarding_timeout_ms
+var ProxyCollapsedTimeouts *prometheus.CounterVec
+
+// ProxyIdempotentReplays is a Counter representing the number of requests to an origin that were
+// answered with the cached response to an earlier request carrying the same idempotency key
+var ProxyIdempotentReplays *prometheus.CounterVec
+
+// ProxyLimitedResponses is a Counter representing the number of timeseries responses to an origin's
+// clients that exceeded max_response_data_points, and were downsampled or rejected
+var ProxyLimitedResponses *prometheus.CounterVec
+
+// ProxyUpstreamRetries is a Counter representing the number of upstream requests to an origin
+// that were retried after a connection error or a retryable response status code
+var ProxyUpstreamRetries *prometheus.CounterVec
+
+// ProxyFailoverResponses is a Counter representing the number of requests to an origin that failed
+// over to its failover_origins, labeled by the origin that ultimately served the response
+var ProxyFailoverResponses *prometheus.CounterVec
+
+// ProxyLastResortResponses is a Counter representing the number of failed responses from an origin
+// that were replaced with its last_resort_response
+var ProxyLastResortResponses *prometheus.CounterVec
+
+// ProxyUpstreamRequestsInFlight is a Gauge representing the number of upstream requests in flight
+// to an origin that is configured with max_concurrent_upstream_requests
+var ProxyUpstreamRequestsInFlight *prometheus.GaugeVec
+
+// ProxyUpstreamRequestsRejected is a Counter representing the number of requests to an origin that
+// were rejected because its max_concurrent_upstream_requests limit was exceeded for timeout_secs
+var ProxyUpstreamRequestsRejected *prometheus.CounterVec
+
+// ProxyCacheWritesThrottled is a Counter representing the number of cache writes by an origin that
+// were skipped because its max_cache_writes_per_sec limit was exceeded
+var ProxyCacheWritesThrottled *prometheus.CounterVec
+
+// ProxyAppliedTTL is a Histogram of the TTLs, in seconds, with which an origin's objects are
+// written to the cache, after any range-based scaling and clamping
+var ProxyAppliedTTL *prometheus.HistogramVec
+
+// ProxyBreakerState is a Gauge representing the state of an origin's circuit breaker,
+// where 0 is closed, 1 is open and 2 is half-open
+var ProxyBreakerState *prometheus.GaugeVec
+
+// ProxyTLSHandshakes is a Gauge representing the number of TLS handshakes in progress to an origin
+// that is configured with max_concurrent_tls_handshakes
+var ProxyTLSHandshakes *prometheus.GaugeVec
+
+// ProxyUpgradedConnections is a Gauge representing the number of upgraded connections (e.g.,
+// WebSockets) currently tunneled to an origin
+var ProxyUpgradedConnections *prometheus.GaugeVec
+
 // ProxyMaxConnections is a Gauge representing the max number of active concurrent connections in the server
 var ProxyMaxConnections prometheus.Gauge
 
@@ -135,6 +222,26 @@ func init() {
 		},
 	)
 
+	ReloadsSkipped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: configSubsystem,
+			Name:      "reloads_skipped_total",
+			Help:      "Count of configuration reloads skipped because a reload was already in progress.",
+		},
+	)
+
+	ConfigLoadPhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: configSubsystem,
+			Name:      "load_phase_duration_seconds",
+			Help:      "Time required to complete each phase of loading the configuration.",
+			Buckets:   configBuckets,
+		},
+		[]string{"phase"},
+	)
+
 	FrontendRequestStatus = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: metricNamespace,
@@ -165,6 +272,15 @@ func init() {
 		},
 		[]string{"origin_name", "origin_type", "method", "path", "http_status"})
 
+	FrontendAuthFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: frontendSubsystem,
+			Name:      "auth_failures_total",
+			Help:      "Count of front end requests rejected by HTTP Basic Authentication",
+		},
+		[]string{"frontend", "reason"})
+
 	ProxyRequestStatus = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: metricNamespace,
@@ -185,6 +301,36 @@ func init() {
 		[]string{"origin_name", "origin_type", "cache_status", "path"},
 	)
 
+	ProxyByteRangeRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "byte_range_requests_total",
+			Help:      "Count of downstream client byte range requests handled by the Object Proxy Cache.",
+		},
+		[]string{"origin_name", "origin_type", "cache_status"},
+	)
+
+	ProxyRequestBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "request_bytes_total",
+			Help:      "Count of request body bytes read from downstream clients.",
+		},
+		[]string{"origin_name"},
+	)
+
+	ProxyResponseBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "response_bytes_total",
+			Help:      "Count of response bytes written to downstream clients, by cache result.",
+		},
+		[]string{"origin_name", "cache_result"},
+	)
+
 	ProxyRequestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: metricNamespace,
@@ -196,6 +342,167 @@ func init() {
 		[]string{"origin_name", "origin_type", "method", "status", "http_status", "path"},
 	)
 
+	ProxyActiveGoroutines = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "active_goroutines",
+			Help:      "Number of running goroutines spawned by Trickster's proxy handlers for an origin.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyCollapsedWaiters = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "collapsed_waiters",
+			Help:      "Number of requests waiting on collapsed-forwarding fetches for an origin.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyDistinctQueryShapes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "distinct_query_shapes",
+			Help:      "Number of distinct timeseries query shapes cached by an origin.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyIdempotentReplays = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "idempotent_replays_total",
+			Help:      "Count of requests answered with the cached response for their idempotency key.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyCollapsedTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "collapsed_timeouts_total",
+			Help:      "Count of requests that timed out waiting on collapsed-forwarding fetches for an origin.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyLimitedResponses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "limited_responses_total",
+			Help:      "Count of timeseries responses that exceeded an origin's max_response_data_points.",
+		},
+		[]string{"origin_name", "origin_type", "action"},
+	)
+
+	ProxyUpstreamRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_retries_total",
+			Help:      "Count of upstream requests that were retried by origin.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyFailoverResponses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "failover_responses_total",
+			Help:      "Count of upstream requests that failed over to a failover origin, by serving origin.",
+		},
+		[]string{"origin_name", "origin_type", "served_by"},
+	)
+
+	ProxyLastResortResponses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "last_resort_responses_total",
+			Help:      "Count of failed responses that were replaced with the origin's last resort response.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyUpstreamRequestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_requests_in_flight",
+			Help:      "Number of upstream requests in flight to an origin.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyUpstreamRequestsRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_requests_rejected_total",
+			Help:      "Count of requests rejected because an origin's upstream concurrency limit was exceeded.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyCacheWritesThrottled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "cache_writes_throttled_total",
+			Help:      "Count of cache writes skipped because an origin's cache write rate limit was exceeded.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyAppliedTTL = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "applied_ttl_seconds",
+			Help:      "TTLs in seconds with which an origin's objects are written to the cache.",
+			Buckets:   ttlBuckets,
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "breaker_state",
+			Help:      "State of an origin's circuit breaker (0 closed, 1 open, 2 half-open).",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyTLSHandshakes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "tls_handshakes",
+			Help:      "Number of TLS handshakes in progress to an origin.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
+	ProxyUpgradedConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upgraded_connections",
+			Help:      "Number of upgraded connections currently tunneled to an origin.",
+		},
+		[]string{"origin_name", "origin_type"},
+	)
+
 	ProxyMaxConnections = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Namespace: metricNamespace,
@@ -279,6 +586,16 @@ func init() {
 		[]string{"cache_name", "cache_type", "event", "reason"},
 	)
 
+	CacheEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: cacheSubsystem,
+			Name:      "evictions_total",
+			Help:      "Count of objects evicted from a Trickster cache to maintain its maximum size.",
+		},
+		[]string{"cache_name", "cache_type", "policy"},
+	)
+
 	CacheObjects = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: metricNamespace,
@@ -321,11 +638,31 @@ func init() {
 
 	// Register Metrics
 	prometheus.MustRegister(FrontendRequestStatus)
+	prometheus.MustRegister(FrontendAuthFailures)
 	prometheus.MustRegister(FrontendRequestDuration)
 	prometheus.MustRegister(FrontendRequestWrittenBytes)
 	prometheus.MustRegister(ProxyRequestStatus)
 	prometheus.MustRegister(ProxyRequestElements)
+	prometheus.MustRegister(ProxyByteRangeRequests)
+	prometheus.MustRegister(ProxyRequestBytes)
+	prometheus.MustRegister(ProxyResponseBytes)
 	prometheus.MustRegister(ProxyRequestDuration)
+	prometheus.MustRegister(ProxyActiveGoroutines)
+	prometheus.MustRegister(ProxyCollapsedWaiters)
+	prometheus.MustRegister(ProxyDistinctQueryShapes)
+	prometheus.MustRegister(ProxyCollapsedTimeouts)
+	prometheus.MustRegister(ProxyIdempotentReplays)
+	prometheus.MustRegister(ProxyLimitedResponses)
+	prometheus.MustRegister(ProxyUpstreamRetries)
+	prometheus.MustRegister(ProxyFailoverResponses)
+	prometheus.MustRegister(ProxyLastResortResponses)
+	prometheus.MustRegister(ProxyUpstreamRequestsInFlight)
+	prometheus.MustRegister(ProxyUpstreamRequestsRejected)
+	prometheus.MustRegister(ProxyCacheWritesThrottled)
+	prometheus.MustRegister(ProxyAppliedTTL)
+	prometheus.MustRegister(ProxyBreakerState)
+	prometheus.MustRegister(ProxyTLSHandshakes)
+	prometheus.MustRegister(ProxyUpgradedConnections)
 	prometheus.MustRegister(ProxyMaxConnections)
 	prometheus.MustRegister(ProxyActiveConnections)
 	prometheus.MustRegister(ProxyConnectionRequested)
@@ -335,12 +672,15 @@ func init() {
 	prometheus.MustRegister(CacheObjectOperations)
 	prometheus.MustRegister(CacheByteOperations)
 	prometheus.MustRegister(CacheEvents)
+	prometheus.MustRegister(CacheEvictions)
 	prometheus.MustRegister(CacheObjects)
 	prometheus.MustRegister(CacheBytes)
 	prometheus.MustRegister(CacheMaxObjects)
 	prometheus.MustRegister(CacheMaxBytes)
 	prometheus.MustRegister(BuildInfo)
 	prometheus.MustRegister(LastReloadSuccessful)
+	prometheus.MustRegister(ReloadsSkipped)
+	prometheus.MustRegister(ConfigLoadPhaseDuration)
 	prometheus.MustRegister(LastReloadSuccessfulTimestamp)
 }
 
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	ao "github.com/tricksterproxy/trickster/pkg/proxy/acl/options"
+)
+
+func TestACL(t *testing.T) {
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if h := ACL(nil, next); h == nil {
+		t.Error("expected handler")
+	}
+
+	o := &ao.Options{Allow: []string{"10.0.0.0/8"}}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	h := ACL(o, next)
+
+	tests := []struct {
+		remoteAddr string
+		expected   int
+	}{
+		{"10.0.0.1:4000", http.StatusOK},
+		{"192.168.0.1:4000", http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://127.0.0.1/", nil)
+		r.RemoteAddr = test.remoteAddr
+		h.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("expected %d got %d", test.expected, w.Code)
+		}
+	}
+}
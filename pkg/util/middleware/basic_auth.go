@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BasicAuth requires HTTP Basic Authentication of requests to the frontend, other than to its
+// public paths, and responds to requests that fail it with a 401 Unauthorized
+func BasicAuth(ba *config.BasicAuthConfig, frontend string, next http.Handler) http.Handler {
+	if ba == nil {
+		return next
+	}
+	challenge := `Basic realm="` + ba.Realm + `", charset="UTF-8"`
+	// passwords of unknown users are compared against a dummy hash, so that the response
+	// time does not reveal which users exist
+	dummy := dummyHash(ba.Credentials)
+	// bcrypt is slow by design, so a digest of each user's verified password is kept to
+	// authenticate their subsequent requests. Only valid passwords are kept, one per user
+	var verified sync.Map
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublicPath(r.URL.Path, ba.PublicPaths) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		reason := "missing"
+		if user, pass, ok := r.BasicAuth(); ok {
+			reason = "invalid"
+			if h, ok := ba.Credentials[user]; ok {
+				sum := sha256.Sum256([]byte(pass))
+				if v, ok := verified.Load(user); ok && subtle.ConstantTimeCompare(v.([]byte), sum[:]) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+				if bcrypt.CompareHashAndPassword(h, []byte(pass)) == nil {
+					verified.Store(user, sum[:])
+					next.ServeHTTP(w, r)
+					return
+				}
+			} else {
+				bcrypt.CompareHashAndPassword(dummy, []byte(pass))
+			}
+		}
+		metrics.FrontendAuthFailures.WithLabelValues(frontend, reason).Inc()
+		w.Header().Set(headers.NameWWWAuthenticate, challenge)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// dummyHash returns a bcrypt hash of a random password, with the highest cost of the
+// credentials, so that comparing a password against it takes as long as against theirs
+func dummyHash(credentials map[string][]byte) []byte {
+	cost := bcrypt.MinCost
+	for _, h := range credentials {
+		if c, err := bcrypt.Cost(h); err == nil && c > cost {
+			cost = c
+		}
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	h, _ := bcrypt.GenerateFromPassword(b, cost)
+	return h
+}
+
+// isPublicPath returns true if the path is one of the public paths, or is beneath
+// one of them that ends in a slash
+func isPublicPath(path string, public []string) bool {
+	for _, p := range public {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBasicAuth(t *testing.T) {
+
+	h, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost)
+	ba := &config.BasicAuthConfig{
+		Realm:       "test",
+		Credentials: map[string][]byte{"user": h},
+		PublicPaths: []string{"/trickster/ping", "/trickster/health/"},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if BasicAuth(nil, "test", next) == nil {
+		t.Error("expected unauthenticated handler")
+	}
+
+	h2 := BasicAuth(ba, "test-basic-auth", next)
+
+	tests := []struct {
+		path, user, pass string
+		expected         int
+	}{
+		{"/", "user", "pass", http.StatusOK},
+		{"/", "user", "pass", http.StatusOK},
+		{"/", "user", "wrong", http.StatusUnauthorized},
+		{"/", "other", "pass", http.StatusUnauthorized},
+		{"/", "", "", http.StatusUnauthorized},
+		{"/trickster/ping", "", "", http.StatusOK},
+		{"/trickster/ping/x", "", "", http.StatusUnauthorized},
+		{"/trickster/health/default", "", "", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://0"+test.path, nil)
+		if test.user != "" {
+			r.SetBasicAuth(test.user, test.pass)
+		}
+		h2.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("%s %s: expected %d got %d", test.path, test.user, test.expected, w.Code)
+		}
+		if w.Code == http.StatusUnauthorized {
+			if v := w.Header().Get(headers.NameWWWAuthenticate); v != `Basic realm="test", charset="UTF-8"` {
+				t.Errorf("unexpected %s header %s", headers.NameWWWAuthenticate, v)
+			}
+		}
+	}
+
+	m := &dto.Metric{}
+	metrics.FrontendAuthFailures.WithLabelValues("test-basic-auth", "invalid").Write(m)
+	if v := m.GetCounter().GetValue(); v != 2 {
+		t.Errorf("expected %d got %f", 2, v)
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+
+	h, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.MinCost+1)
+	for _, test := range []struct {
+		credentials map[string][]byte
+		expected    int
+	}{
+		{nil, bcrypt.MinCost},
+		{map[string][]byte{"user": h, "invalid": []byte("x")}, bcrypt.MinCost + 1},
+	} {
+		if c, err := bcrypt.Cost(dummyHash(test.credentials)); err != nil || c != test.expected {
+			t.Errorf("expected %d got %d (%v)", test.expected, c, err)
+		}
+	}
+}
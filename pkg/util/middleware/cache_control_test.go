@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+)
+
+func TestDownstreamCacheControl(t *testing.T) {
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(headers.NameCacheControl, "max-age=60")
+		w.Write([]byte("test"))
+	})
+
+	if h := DownstreamCacheControl("", next); h == nil {
+		t.Error("expected handler")
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://0/", nil)
+	DownstreamCacheControl(headers.ValueNoStore, next).ServeHTTP(w, r)
+	if v := w.Result().Header.Get(headers.NameCacheControl); v != headers.ValueNoStore {
+		t.Errorf("expected %s got %s", headers.ValueNoStore, v)
+	}
+}
+
+func TestDownstreamCacheControlFlush(t *testing.T) {
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("expected http.Flusher")
+		}
+		// a streamed response is flushed before any of its body is written
+		f.Flush()
+		w.Write([]byte("test"))
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "http://0/", nil)
+	DownstreamCacheControl(headers.ValueNoStore, next).ServeHTTP(w, r)
+	if !w.Flushed {
+		t.Error("expected response to be flushed")
+	}
+	if v := w.Result().Header.Get(headers.NameCacheControl); v != headers.ValueNoStore {
+		t.Errorf("expected %s got %s", headers.ValueNoStore, v)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/config/defaults"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	encodingBrotli  = "br"
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// encodingPreference is the order in which supported encodings are chosen when
+// the client accepts several of them equally
+var encodingPreference = []string{encodingBrotli, encodingGzip, encodingDeflate}
+
+// compressOptions determine which responses are compressed, and how. They are attached to the
+// request context by Compress, and replaced for origin requests by ResponseCompression
+type compressOptions struct {
+	// types is the set of compressed media types. When nil, all types are compressed
+	types         map[string]bool
+	brotliQuality int
+}
+
+type compressOptionsKey struct{}
+
+// Compress compresses responses with brotli, gzip or deflate, per the client's Accept-Encoding.
+// Responses for origin paths are compressed per the origin's ResponseCompression options, and
+// all other responses are compressed regardless of their content type. Responses that are
+// already content-encoded, marked no-transform, or partial content, are not compressed
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get(headers.NameAcceptEncoding))
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// the response is compressed here, so the upstream is not asked to compress it
+		r.Header.Del(headers.NameAcceptEncoding)
+		opts := &compressOptions{brotliQuality: defaults.DefaultBrotliQuality}
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, opts: opts}
+		defer cw.close()
+		next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), compressOptionsKey{}, opts)))
+	})
+}
+
+// ResponseCompression limits the compression of responses by Compress to the origin's
+// compressable_types, and sets the brotli quality level used to compress them
+func ResponseCompression(oc *oo.Options, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts, ok := r.Context().Value(compressOptionsKey{}).(*compressOptions); ok {
+			opts.types = oc.CompressableTypes
+			if opts.types == nil {
+				opts.types = map[string]bool{}
+			}
+			opts.brotliQuality = oc.BrotliQuality
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// negotiateEncoding returns the supported encoding with the highest quality value in the
+// Accept-Encoding header, or an empty string if the client accepts none of them
+func negotiateEncoding(accept string) string {
+	if accept == "" {
+		return ""
+	}
+	q := make(map[string]float64)
+	star := -1.0
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		v := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if f, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					v = f
+				}
+			}
+		}
+		if name == "*" {
+			star = v
+			continue
+		}
+		q[name] = v
+	}
+	var best string
+	var bestQ float64
+	for _, e := range encodingPreference {
+		v, ok := q[e]
+		if !ok {
+			v = star
+		}
+		if v > bestQ {
+			best, bestQ = e, v
+		}
+	}
+	return best
+}
+
+// compressWriter decides whether to compress the response when its headers are written,
+// once its status, content type and encoding are known
+type compressWriter struct {
+	http.ResponseWriter
+
+	encoding    string
+	opts        *compressOptions
+	enc         io.WriteCloser
+	wroteHeader bool
+}
+
+func (w *compressWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.compressible(statusCode) {
+			h := w.Header()
+			h.Set(headers.NameContentEncoding, w.encoding)
+			h.Add(headers.NameVary, headers.NameAcceptEncoding)
+			h.Del(headers.NameContentLength)
+			w.enc = newEncoder(w.ResponseWriter, w.encoding, w.opts.brotliQuality)
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		if w.Header().Get(headers.NameContentType) == "" {
+			w.Header().Set(headers.NameContentType, http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush writes any data buffered by the encoder to the client
+func (w *compressWriter) Flush() {
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the client connection over to the caller, as when a request is upgraded to
+// another protocol
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hj.Hijack()
+}
+
+func (w *compressWriter) close() {
+	if w.enc != nil {
+		w.enc.Close()
+	}
+}
+
+// compressible returns true if a response with the status code and the current headers
+// should be compressed
+func (w *compressWriter) compressible(statusCode int) bool {
+	if statusCode < http.StatusOK || statusCode == http.StatusNoContent ||
+		statusCode == http.StatusNotModified || statusCode == http.StatusPartialContent {
+		return false
+	}
+	h := w.Header()
+	// the byte ranges of a partial response refer to the uncompressed representation
+	if h.Get(headers.NameContentRange) != "" {
+		return false
+	}
+	if ce := h.Get(headers.NameContentEncoding); ce != "" && ce != "identity" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(h.Get(headers.NameCacheControl)), headers.ValueNoTransform) {
+		return false
+	}
+	if w.opts.types == nil {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(h.Get(headers.NameContentType))
+	return err == nil && w.opts.types[mt]
+}
+
+// newEncoder returns a writer that compresses to w with the encoding
+func newEncoder(w io.Writer, encoding string, brotliQuality int) io.WriteCloser {
+	switch encoding {
+	case encodingBrotli:
+		return brotli.NewWriterLevel(w, brotliQuality)
+	case encodingGzip:
+		return gzip.NewWriter(w)
+	}
+	fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return fw
+}
@@ -31,7 +31,7 @@ import (
 
 // WithResourcesContext ...
 func WithResourcesContext(client origins.Client, oc *oo.Options,
-	c cache.Cache, p *po.Options, t *tracing.Tracer,
+	c, nc cache.Cache, p *po.Options, t *tracing.Tracer,
 	l *tl.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var resources *request.Resources
@@ -39,6 +39,7 @@ func WithResourcesContext(client origins.Client, oc *oo.Options,
 			resources = request.NewResources(oc, p, nil, nil, client, t, l)
 		} else {
 			resources = request.NewResources(oc, p, c.Configuration(), c, client, t, l)
+			resources.NegativeCacheClient = nc
 		}
 		next.ServeHTTP(w, r.WithContext(context.WithResources(r.Context(), resources)))
 	})
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	tc "github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/jwt"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+type jwtValidatorKey struct{}
+
+// WithJWTAuth attaches a JWT Validator for the frontend's jwt_auth config to the context of its
+// requests, which JWTAuth uses to validate the requests to each origin that has not disabled it
+func WithJWTAuth(ja *config.JWTAuthConfig, log *tl.Logger, next http.Handler) http.Handler {
+	if ja == nil {
+		return next
+	}
+	v := jwt.NewValidator(jwt.Options{
+		JWKSURL:         ja.JWKSURL,
+		RefreshInterval: ja.JWKSRefreshInterval,
+		Leeway:          ja.Leeway,
+		Issuers:         ja.Issuers,
+		Audiences:       ja.Audiences,
+		RequiredClaims:  ja.RequiredClaims,
+		ClaimHeaders:    ja.ClaimHeaders,
+	}, log)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jwtValidatorKey{}, v)))
+	})
+}
+
+// JWTAuth requires a valid bearer token on requests to the origin that were accepted by a
+// frontend with a jwt_auth config, unless the origin has disabled it, and responds to requests
+// that fail validation with a 401 Unauthorized. The claim headers of valid tokens are set on
+// the request before it is proxied
+func JWTAuth(oc *oo.Options, next http.Handler) http.Handler {
+	if oc.JWTAuthDisabled {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, ok := r.Context().Value(jwtValidatorKey{}).(*jwt.Validator)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		reason, challenge := "missing", "Bearer"
+		if token, ok := bearerToken(r); ok {
+			claims, err := v.Validate(token)
+			if err == nil {
+				v.SetClaimHeaders(r.Header, claims)
+				next.ServeHTTP(w, r)
+				return
+			}
+			reason, challenge = "invalid", `Bearer error="invalid_token"`
+			if err == jwt.ErrExpired {
+				reason = "expired"
+			}
+		}
+		metrics.FrontendAuthFailures.WithLabelValues(tc.FrontendName(r.Context()), reason).Inc()
+		w.Header().Set(headers.NameWWWAuthenticate, challenge)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	})
+}
+
+// bearerToken returns the token of the request's bearer Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "bearer "
+	h := r.Header.Get(headers.NameAuthorization)
+	if len(h) <= len(prefix) || !strings.EqualFold(h[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(h[len(prefix):])
+	return token, token != ""
+}
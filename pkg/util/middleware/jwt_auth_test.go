@@ -0,0 +1,139 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tricksterproxy/trickster/pkg/config"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	tl "github.com/tricksterproxy/trickster/pkg/util/log"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func testJWT(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	h, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test"})
+	c, _ := json.Marshal(claims)
+	input := enc(h) + "." + enc(c)
+	d := crypto.SHA256.New()
+	d.Write([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, d.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + enc(sig)
+}
+
+func TestJWTAuth(t *testing.T) {
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{{"kid": "test",
+		"kty": "RSA", "n": enc(key.N.Bytes()), "e": enc(big.NewInt(int64(key.E)).Bytes())}}})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jwks)
+	}))
+	defer ts.Close()
+
+	ja := &config.JWTAuthConfig{
+		JWKSURL:             ts.URL,
+		JWKSRefreshInterval: time.Minute,
+		Audiences:           []string{"trickster"},
+		ClaimHeaders:        map[string]string{"tenant": "X-Tenant-Id"},
+	}
+
+	var tenant string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Tenant-Id")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	if WithJWTAuth(nil, tl.ConsoleLogger("error"), next) == nil {
+		t.Error("expected unauthenticated handler")
+	}
+
+	h := WithFrontendName("test-jwt-auth", WithJWTAuth(ja, tl.ConsoleLogger("error"),
+		JWTAuth(&oo.Options{}, next)))
+	disabled := WithJWTAuth(ja, tl.ConsoleLogger("error"), JWTAuth(&oo.Options{JWTAuthDisabled: true}, next))
+
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := testJWT(t, key, map[string]interface{}{"aud": "trickster", "exp": exp, "tenant": "acme"})
+	expired := testJWT(t, key, map[string]interface{}{"aud": "trickster", "exp": exp - 7200})
+	wrongAud := testJWT(t, key, map[string]interface{}{"aud": "other", "exp": exp})
+
+	tests := []struct {
+		handler       http.Handler
+		authorization string
+		expected      int
+		challenge     string
+		tenant        string
+	}{
+		{h, "Bearer " + valid, http.StatusOK, "", "acme"},
+		{h, "bearer " + valid, http.StatusOK, "", "acme"},
+		{h, "Bearer " + expired, http.StatusUnauthorized, `Bearer error="invalid_token"`, ""},
+		{h, "Bearer " + wrongAud, http.StatusUnauthorized, `Bearer error="invalid_token"`, ""},
+		{h, "Bearer ", http.StatusUnauthorized, "Bearer", ""},
+		{h, "Basic dXNlcjpwYXNz", http.StatusUnauthorized, "Bearer", ""},
+		{h, "", http.StatusUnauthorized, "Bearer", ""},
+		{disabled, "", http.StatusOK, "", "spoofed"},
+		{JWTAuth(&oo.Options{}, next), "", http.StatusOK, "", "spoofed"},
+	}
+
+	for i, test := range tests {
+		tenant = ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://0/", nil)
+		r.Header.Set("X-Tenant-Id", "spoofed")
+		if test.authorization != "" {
+			r.Header.Set(headers.NameAuthorization, test.authorization)
+		}
+		test.handler.ServeHTTP(w, r)
+		if w.Code != test.expected {
+			t.Errorf("test %d: expected %d got %d", i, test.expected, w.Code)
+		}
+		if v := w.Header().Get(headers.NameWWWAuthenticate); v != test.challenge {
+			t.Errorf("test %d: expected %s header %s got %s", i, headers.NameWWWAuthenticate, test.challenge, v)
+		}
+		if tenant != test.tenant {
+			t.Errorf("test %d: expected tenant %s got %s", i, test.tenant, tenant)
+		}
+	}
+
+	for reason, expected := range map[string]float64{"missing": 3, "invalid": 1, "expired": 1} {
+		m := &dto.Metric{}
+		metrics.FrontendAuthFailures.WithLabelValues("test-jwt-auth", reason).Write(m)
+		if v := m.GetCounter().GetValue(); v != expected {
+			t.Errorf("%s: expected %f got %f", reason, expected, v)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+	"github.com/tricksterproxy/trickster/pkg/util/metrics"
+)
+
+// LastResortResponse serves the origin's last_resort_response in place of any 5xx response to
+// the client. Since it replaces the final response, it applies only after the origin has
+// exhausted its cached objects, retries and failover origins
+func LastResortResponse(oc *oo.Options, next http.Handler) http.Handler {
+	if oc.LastResortResponse == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&lastResortWriter{ResponseWriter: w, oc: oc}, r)
+	})
+}
+
+// lastResortWriter discards a 5xx response, including any headers already set for it,
+// and writes the last resort response instead
+type lastResortWriter struct {
+	http.ResponseWriter
+
+	oc          *oo.Options
+	wroteHeader bool
+	replaced    bool
+}
+
+func (w *lastResortWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if statusCode < http.StatusInternalServerError {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.replaced = true
+	lr := w.oc.LastResortResponse
+	h := w.Header()
+	for k := range h {
+		delete(h, k)
+	}
+	// the response is not to be cached downstream, so that it does not outlive the outage
+	h.Set(headers.NameCacheControl, headers.ValueNoStore)
+	for k, v := range lr.Headers {
+		h.Set(k, v)
+	}
+	h.Set(headers.NameContentLength, strconv.Itoa(len(lr.Body)))
+	metrics.ProxyLastResortResponses.WithLabelValues(w.oc.Name, w.oc.OriginType).Inc()
+	w.ResponseWriter.WriteHeader(lr.Code)
+	io.WriteString(w.ResponseWriter, lr.Body)
+}
+
+func (w *lastResortWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends any buffered response data to the client, as when a response is streamed
+func (w *lastResortWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the client connection over to the caller, as when a request is upgraded to
+// another protocol
+func (w *lastResortWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hj.Hijack()
+}
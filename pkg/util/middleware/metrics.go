@@ -17,15 +17,20 @@
 package middleware
 
 import (
+	"bufio"
+	"io"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/tricksterproxy/trickster/pkg/cache/status"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
 	"github.com/tricksterproxy/trickster/pkg/util/metrics"
 )
 
 // Decorate decorates a function in such a way that it captures both the
 // returned status and the time used to execute a request from the front end
-// perspective
+// perspective, along with the request and response bytes transferred by the proxy
 func Decorate(originName, originType, path string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		observer := &responseObserver{
@@ -34,6 +39,12 @@ func Decorate(originName, originType, path string, next http.Handler) http.Handl
 			0,
 		}
 
+		var body *bodyObserver
+		if r.Body != nil && r.Body != http.NoBody {
+			body = &bodyObserver{ReadCloser: r.Body}
+			r.Body = body
+		}
+
 		n := time.Now()
 		next.ServeHTTP(observer, r)
 
@@ -43,9 +54,28 @@ func Decorate(originName, originType, path string, next http.Handler) http.Handl
 			r.Method, path, observer.status).Inc()
 		metrics.FrontendRequestWrittenBytes.WithLabelValues(originName, originType,
 			r.Method, path, observer.status).Add(observer.bytesWritten)
+
+		if body != nil && body.bytesRead > 0 {
+			metrics.ProxyRequestBytes.WithLabelValues(originName).Add(body.bytesRead)
+		}
+		metrics.ProxyResponseBytes.WithLabelValues(originName,
+			status.CacheResult(headers.GetResultsStatus(observer.Header()))).Add(observer.bytesWritten)
 	})
 }
 
+// bodyObserver counts the bytes read from a request body
+type bodyObserver struct {
+	io.ReadCloser
+
+	bytesRead float64
+}
+
+func (b *bodyObserver) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytesRead += float64(n)
+	return n, err
+}
+
 type responseObserver struct {
 	http.ResponseWriter
 
@@ -69,6 +99,20 @@ func (w *responseObserver) WriteHeader(statusCode int) {
 	}
 }
 
+// Hijack hands the client connection over to the caller, as when a request is upgraded to
+// another protocol, which is recorded as a 1xx response
+func (w *responseObserver) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hj.Hijack()
+	if err == nil {
+		w.status = "1xx"
+	}
+	return conn, rw, err
+}
+
 func (w *responseObserver) Write(b []byte) (int, error) {
 	bytesWritten, err := w.ResponseWriter.Write(b)
 
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	"github.com/tricksterproxy/trickster/pkg/proxy/methods"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+// DuplicateParams applies the provided Duplicate Param Policy to incoming HTTP Requests. With
+// 'first' or 'last', only the first or last occurrence of each repeated URL query parameter, or
+// form field of a form-encoded request body, is kept, so that the cache key and the upstream
+// request use the same value
+func DuplicateParams(policy string, next http.Handler) http.Handler {
+
+	if policy != oo.DuplicateParamPolicyFirst && policy != oo.DuplicateParamPolicyLast {
+		return next
+	}
+	last := policy == oo.DuplicateParamPolicyLast
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.URL != nil && r.URL.RawQuery != "" {
+			r.URL.RawQuery = dedupeQuery(r.URL.RawQuery, last)
+		}
+		if r.Body != nil && methods.HasBody(r.Method) && isFormEncoded(r.Header) {
+			b, err := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			b = []byte(dedupeQuery(string(b), last))
+			r.ContentLength = int64(len(b))
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isFormEncoded returns true if the headers describe a form-encoded request body
+func isFormEncoded(h http.Header) bool {
+	mt, _, err := mime.ParseMediaType(h.Get(headers.NameContentType))
+	return err == nil && mt == headers.ValueXFormURLEncoded
+}
+
+// dedupeQuery returns the raw query with only the first, or last, occurrence of each parameter.
+// The retained parameters keep their original order and encoding
+func dedupeQuery(rawQuery string, last bool) string {
+	parts := strings.Split(rawQuery, "&")
+	names := make([]string, len(parts))
+	keep := make(map[string]int, len(parts))
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		name := part
+		if j := strings.Index(part, "="); j >= 0 {
+			name = part[:j]
+		}
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		names[i] = name
+		if _, ok := keep[name]; !ok || last {
+			keep[name] = i
+		}
+	}
+	if len(keep) == len(parts) {
+		return rawQuery
+	}
+	out := make([]string, 0, len(keep))
+	for i, part := range parts {
+		if part != "" && keep[names[i]] == i {
+			out = append(out, part)
+		}
+	}
+	return strings.Join(out, "&")
+}
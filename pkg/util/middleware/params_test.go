@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 Comcast Cable Communications Management, LLC
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
+)
+
+func TestDuplicateParams(t *testing.T) {
+
+	var rawQuery string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+	})
+
+	tests := []struct {
+		policy, query, expected string
+	}{
+		{oo.DuplicateParamPolicyAll, "q=a&q=b", "q=a&q=b"},
+		{"", "q=a&q=b", "q=a&q=b"},
+		{oo.DuplicateParamPolicyFirst, "q=a&step=15&q=b", "q=a&step=15"},
+		{oo.DuplicateParamPolicyLast, "q=a&step=15&q=b", "step=15&q=b"},
+		{oo.DuplicateParamPolicyFirst, "q=a%26b&step=15", "q=a%26b&step=15"},
+		{oo.DuplicateParamPolicyFirst, "q%5B%5D=a&q[]=b&flag&flag", "q%5B%5D=a&flag"},
+		{oo.DuplicateParamPolicyLast, "q=a&&q=b&", "q=b"},
+		{oo.DuplicateParamPolicyLast, "", ""},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://127.0.0.1/?"+test.query, nil)
+		DuplicateParams(test.policy, next).ServeHTTP(httptest.NewRecorder(), r)
+		if rawQuery != test.expected {
+			t.Errorf("%s %s: expected %s got %s", test.policy, test.query, test.expected, rawQuery)
+		}
+	}
+}
+
+func TestDuplicateParamsFormBody(t *testing.T) {
+
+	var body string
+	var contentLength int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body = string(b)
+		contentLength = r.ContentLength
+	})
+
+	tests := []struct {
+		policy, contentType, body, expected string
+	}{
+		{oo.DuplicateParamPolicyFirst, headers.ValueXFormURLEncoded, "q=a&step=15&q=b", "q=a&step=15"},
+		{oo.DuplicateParamPolicyLast, headers.ValueXFormURLEncoded + "; charset=utf-8",
+			"q=a&step=15&q=b", "step=15&q=b"},
+		{oo.DuplicateParamPolicyAll, headers.ValueXFormURLEncoded, "q=a&q=b", "q=a&q=b"},
+		{oo.DuplicateParamPolicyFirst, headers.ValueApplicationJSON, "q=a&q=b", "q=a&q=b"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "http://127.0.0.1/", strings.NewReader(test.body))
+		r.Header.Set(headers.NameContentType, test.contentType)
+		DuplicateParams(test.policy, next).ServeHTTP(httptest.NewRecorder(), r)
+		if body != test.expected {
+			t.Errorf("%s %s: expected %s got %s", test.policy, test.body, test.expected, body)
+		}
+		if contentLength != int64(len(test.expected)) {
+			t.Errorf("%s %s: expected %d got %d", test.policy, test.body, len(test.expected), contentLength)
+		}
+	}
+}
@@ -20,7 +20,14 @@ package middleware
 
 import (
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+
+	"github.com/tricksterproxy/trickster/pkg/proxy/context"
+	"github.com/tricksterproxy/trickster/pkg/proxy/headers"
+	oo "github.com/tricksterproxy/trickster/pkg/proxy/origins/options"
 )
 
 // StripPathPrefix removes the provided prefix from incoming HTTP Requests URLs
@@ -44,3 +51,92 @@ func StripPathPrefix(prefix string, next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// TrailingSlash applies the provided Trailing Slash Policy to incoming HTTP Requests.
+// With 'ignore', any trailing slash is removed from the request path before it is handled,
+// and with 'redirect', the client is redirected to the request path without the trailing slash
+func TrailingSlash(policy string, next http.Handler) http.Handler {
+
+	if policy != oo.TrailingSlashPolicyIgnore && policy != oo.TrailingSlashPolicyRedirect {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r == nil || r.URL == nil || len(r.URL.Path) < 2 || !strings.HasSuffix(r.URL.Path, "/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if policy == oo.TrailingSlashPolicyIgnore {
+			r.URL.Path = strings.TrimRight(r.URL.Path, "/")
+			if r.URL.Path == "" {
+				r.URL.Path = "/"
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		// the redirect must reflect the URL as requested by the client, which
+		// may differ from r.URL if a path prefix was stripped during routing
+		u := r.URL
+		if r.RequestURI != "" {
+			if ru, err := url.ParseRequestURI(r.RequestURI); err == nil {
+				u = ru
+			}
+		}
+		loc := strings.TrimRight(u.Path, "/")
+		if loc == "" {
+			loc = "/"
+		}
+		if u.RawQuery != "" {
+			loc += "?" + u.RawQuery
+		}
+		http.Redirect(w, r, loc, http.StatusPermanentRedirect)
+	})
+}
+
+// PathMatch attaches the groups captured by the provided regex path pattern from the request
+// path to the request context, keyed by both their index and any name, so they are available
+// to request rewriters. Groups that do not participate in the match are omitted
+func PathMatch(re *regexp.Regexp, next http.Handler) http.Handler {
+
+	if re == nil {
+		return next
+	}
+
+	names := re.SubexpNames()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r == nil || r.URL == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		m := re.FindStringSubmatchIndex(r.URL.Path)
+		if m == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		groups := make(map[string]string, len(names)*2)
+		for i, name := range names {
+			if m[2*i] < 0 {
+				continue
+			}
+			v := r.URL.Path[m[2*i]:m[2*i+1]]
+			groups[strconv.Itoa(i)] = v
+			if name != "" {
+				groups[name] = v
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithPathMatch(r.Context(), groups)))
+	})
+}
+
+// RejectUpgrades responds with a 400 Bad Request to requests that ask to upgrade the connection
+// to another protocol, for paths that are not configured as upgradeable
+func RejectUpgrades(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r != nil && headers.IsUpgrade(r.Header) {
+			http.Error(w, "connection upgrades are not permitted for this path", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}